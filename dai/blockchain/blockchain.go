@@ -14,6 +14,8 @@
 package blockchain
 
 import (
+	xdbchain "github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+
 	pbCom "github.com/PaddlePaddle/PaddleDTX/dai/protos/common"
 	pbTask "github.com/PaddlePaddle/PaddleDTX/dai/protos/task"
 )
@@ -43,6 +45,9 @@ const (
 
 	/* Define the maximum number of task list query */
 	TaskListMaxNum = 100
+
+	/* Define the name of events emitted once a task is published or its status changes, payload is TaskEvent */
+	EventTask = "TaskStatus"
 )
 
 // VlAlgorithmListName the mapping of vertical algorithm name and value
@@ -113,6 +118,27 @@ type FLTask *pbTask.FLTask
 
 type FLTasks []*pbTask.FLTask
 
+// TaskEvent is the payload of task events
+type TaskEvent struct {
+	TaskID    string   `json:"taskID"`
+	Status    string   `json:"status"`
+	Requester []byte   `json:"requester"`
+	Executors [][]byte `json:"executors"`
+}
+
+// NewTaskEvent makes the payload of the task's event
+func NewTaskEvent(t FLTask) TaskEvent {
+	e := TaskEvent{
+		TaskID:    t.TaskID,
+		Status:    t.Status,
+		Requester: t.Requester,
+	}
+	for _, ds := range t.DataSets {
+		e.Executors = append(e.Executors, ds.Executor)
+	}
+	return e
+}
+
 // PublishFLTaskOptions contains parameters for publishing tasks
 type PublishFLTaskOptions struct {
 	FLTask    FLTask `json:"fLTask"`
@@ -134,6 +160,17 @@ type ListFLTaskOptions struct {
 	TimeStart  int64  `json:"timeStart"` // task publish time period, only task published after TimeStart and before TimeEnd will be listed
 	TimeEnd    int64  `json:"timeEnd"`
 	Limit      int64  `json:"limit"` // limit number of tasks in list request, default 'all'
+	Cursor     string `json:"cursor"` // continue the list after the last task of the previous page, see NextTaskCursor
+}
+
+// NextTaskCursor returns the cursor to list tasks after the page, empty if the page is not full
+// so that no tasks are left
+func NextTaskCursor(tasks FLTasks, limit int64) string {
+	if limit <= 0 || int64(len(tasks)) < limit || len(tasks) == 0 {
+		return ""
+	}
+	t := tasks[len(tasks)-1]
+	return xdbchain.Cursor{ID: t.TaskID, Time: t.PublishTime, Owner: t.Requester}.String()
 }
 
 // FLTaskConfirmOptions contains parameters for confirming task
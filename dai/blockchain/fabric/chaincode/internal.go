@@ -14,10 +14,17 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
+	"strings"
+
+	xdbchain "github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
 
 	"github.com/PaddlePaddle/PaddleDTX/dai/blockchain"
+	pbTask "github.com/PaddlePaddle/PaddleDTX/dai/protos/task"
 )
 
 const (
@@ -70,6 +77,34 @@ func packFlTaskFilter(rPubkey, ePubkey []byte) (string, []string) {
 	}
 }
 
+// packFlTaskIndexByCursor pack the list index of the task at the cursor, in the list filtered by packFlTaskFilter
+func packFlTaskIndexByCursor(rPubkey, ePubkey []byte, c xdbchain.Cursor) string {
+	task := blockchain.FLTask(&pbTask.FLTask{TaskID: c.ID, Requester: c.Owner, PublishTime: c.Time})
+	if len(rPubkey) > 0 && len(ePubkey) > 0 {
+		return packRequesterExecutorTaskIndex(ePubkey, task)
+	} else if len(rPubkey) > 0 {
+		return packFlTaskListIndex(task)
+	}
+	return packExecutorTaskListIndex(ePubkey, task)
+}
+
+// packCursorIndex returns the list index of the task at the cursor, which is built by index,
+// lists filtered by prefix and attr are continued by skipping indexes up to it. It's empty if the cursor is empty
+func packCursorIndex(prefix string, attr []string, cursor string, index func(xdbchain.Cursor) string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	c, err := xdbchain.ParseCursor(cursor)
+	if err != nil {
+		return "", err
+	}
+	key := index(c)
+	if !strings.HasPrefix(key, createCompositeKey(prefix, attr)) {
+		return "", errorx.New(errorx.ErrCodeParam, "invalid cursor, not in the list")
+	}
+	return key, nil
+}
+
 // packNodeIndex pack index-id contract key for saving executor node
 func packNodeIndex(nodeID []byte) string {
 	return createCompositeKey(prefixNodeIndex, []string{fmt.Sprintf("%x", nodeID)})
@@ -98,3 +133,15 @@ func createCompositeKey(objectType string, attributes []string) string {
 	}
 	return ck
 }
+
+// emitTaskEvent sets the event of the task's status for watchers
+func emitTaskEvent(stub shim.ChaincodeStubInterface, t blockchain.FLTask) error {
+	s, err := json.Marshal(blockchain.NewTaskEvent(t))
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal task event")
+	}
+	if err := stub.SetEvent(blockchain.EventTask, s); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to set task event")
+	}
+	return nil
+}
@@ -51,12 +51,16 @@ func (x *Xdata) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 		return x.NodeOffline(stub, args)
 	case "NodeOnline":
 		return x.NodeOnline(stub, args)
+	case "NodeDrain":
+		return x.NodeDrain(stub, args)
 	case "Heartbeat":
 		return x.Heartbeat(stub, args)
 	case "GetHeartbeatNum":
 		return x.GetHeartbeatNum(stub, args)
 	case "ListNodesExpireSlice":
 		return x.ListNodesExpireSlice(stub, args)
+	case "ListNodesDeletedSlice":
+		return x.ListNodesDeletedSlice(stub, args)
 	case "GetSliceMigrateRecords":
 		return x.GetSliceMigrateRecords(stub, args)
 	case "PublishFile":
@@ -65,6 +69,8 @@ func (x *Xdata) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 		return x.AddFileNs(stub, args)
 	case "UpdateNsReplica":
 		return x.UpdateNsReplica(stub, args)
+	case "UpdateNsLifecycle":
+		return x.UpdateNsLifecycle(stub, args)
 	case "UpdateFilePublicSliceMeta":
 		return x.UpdateFilePublicSliceMeta(stub, args)
 	case "GetFileByName":
@@ -73,10 +79,14 @@ func (x *Xdata) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 		return x.GetFileByID(stub, args)
 	case "UpdateFileExpireTime":
 		return x.UpdateFileExpireTime(stub, args)
+	case "DeleteFile":
+		return x.DeleteFile(stub, args)
 	case "SliceMigrateRecord":
 		return x.SliceMigrateRecord(stub, args)
 	case "ListFiles":
 		return x.ListFiles(stub, args)
+	case "ListFileVersions":
+		return x.ListFileVersions(stub, args)
 	case "ListExpiredFiles":
 		return x.ListExpiredFiles(stub, args)
 	case "ListFileNs":
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
 	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
+	xdbchain "github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	pb "github.com/hyperledger/fabric/protos/peer"
@@ -89,6 +90,10 @@ func (x *Xdata) PublishTask(stub shim.ChaincodeStubInterface, args []string) pb.
 				"fail to put requester and executor listIndex-fltask on fabric: %s", resp.Message).Error())
 		}
 	}
+	// emit the event for watchers
+	if err := emitTaskEvent(stub, t); err != nil {
+		return shim.Error(err.Error())
+	}
 	return shim.Success([]byte("added"))
 }
 
@@ -109,6 +114,12 @@ func (x *Xdata) ListTask(stub shim.ChaincodeStubInterface, args []string) pb.Res
 
 	// get fltasks by list_prefix
 	prefix, attr := packFlTaskFilter(opt.PubKey, opt.ExecPubKey)
+	cursorKey, err := packCursorIndex(prefix, attr, opt.Cursor, func(c xdbchain.Cursor) string {
+		return packFlTaskIndexByCursor(opt.PubKey, opt.ExecPubKey, c)
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
 	iterator, err := stub.GetStateByPartialCompositeKey(prefix, attr)
 
 	// defer iter.Close()
@@ -121,6 +132,10 @@ func (x *Xdata) ListTask(stub shim.ChaincodeStubInterface, args []string) pb.Res
 		if err != nil {
 			return shim.Error(err.Error())
 		}
+		// skip indexes up to the cursor, which are listed in previous pages
+		if queryResponse.Key <= cursorKey {
+			continue
+		}
 		if opt.Limit > 0 && int64(len(tasks)) >= opt.Limit {
 			break
 		}
@@ -247,6 +262,10 @@ func (x *Xdata) setTaskConfirmStatus(stub shim.ChaincodeStubInterface, args []st
 		return shim.Error(errorx.New(errorx.ErrCodeWriteBlockchain,
 			"fail to confirm index-flTask on fabric: %s", resp.Message).Error())
 	}
+	// emit the event for watchers
+	if err := emitTaskEvent(stub, t); err != nil {
+		return shim.Error(err.Error())
+	}
 	return shim.Success([]byte("OK"))
 }
 
@@ -293,6 +312,10 @@ func (x *Xdata) StartTask(stub shim.ChaincodeStubInterface, args []string) pb.Re
 		return shim.Error(errorx.New(errorx.ErrCodeWriteBlockchain,
 			"fail to confirm index-flTask on fabric: %s", resp.Message).Error())
 	}
+	// emit the event for watchers
+	if err := emitTaskEvent(stub, t); err != nil {
+		return shim.Error(err.Error())
+	}
 	return shim.Success([]byte("OK"))
 }
 
@@ -372,6 +395,10 @@ func (x *Xdata) setTaskExecuteStatus(stub shim.ChaincodeStubInterface, args []st
 		return shim.Error(errorx.New(errorx.ErrCodeWriteBlockchain,
 			"fail to set task execute status on fabric: %s", resp.Message).Error())
 	}
+	// emit the event for watchers
+	if err := emitTaskEvent(stub, t); err != nil {
+		return shim.Error(err.Error())
+	}
 	return shim.Success([]byte("OK"))
 }
 
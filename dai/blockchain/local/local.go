@@ -0,0 +1,46 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	xdblocal "github.com/PaddlePaddle/PaddleDTX/xdb/blockchain/local"
+	xchainblockchain "github.com/PaddlePaddle/PaddleDTX/xdb/blockchain/xchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+
+	"github.com/PaddlePaddle/PaddleDTX/dai/blockchain/xchain"
+	"github.com/PaddlePaddle/PaddleDTX/dai/blockchain/xchain/contract/core"
+	"github.com/PaddlePaddle/PaddleDTX/dai/config"
+)
+
+// chainName is the name of the local blockchain
+const chainName = "local"
+
+// New creates a blockchain client running the DAI contract in process, without any blockchain network.
+// It's for development and tests, the task state machine and signature checks are the same as the xchain contract,
+// and executors, requesters and xdb nodes configured with the same leveldb root on a machine share the chain.
+func New(conf *config.LocalChainConf) (*xchain.XChain, error) {
+	if conf == nil {
+		return nil, errorx.New(errorx.ErrCodeConfig, "missing local blockchain configuration")
+	}
+	contract, err := xdblocal.NewContract(conf.LeveldbRoot, new(core.Xdata))
+	if err != nil {
+		return nil, errorx.Wrap(err, "failed to create local contract")
+	}
+	return &xchain.XChain{
+		XChain: xchainblockchain.XChain{
+			ChainName: chainName,
+			Contract:  contract,
+		},
+	}, nil
+}
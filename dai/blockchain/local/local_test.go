@@ -0,0 +1,162 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	util "github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/strings"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/dai/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/dai/config"
+	pbTask "github.com/PaddlePaddle/PaddleDTX/dai/protos/task"
+)
+
+func sign(t *testing.T, privkey ecdsa.PrivateKey, opt interface{}) []byte {
+	msg, err := util.GetSigMessage(opt)
+	require.NoError(t, err)
+	sig, err := ecdsa.Sign(privkey, hash.HashUsingSha256([]byte(msg)))
+	require.NoError(t, err)
+	return sig[:]
+}
+
+func TestLocalChain(t *testing.T) {
+	root, err := ioutil.TempDir("", "localchain")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	chain, err := New(&config.LocalChainConf{LeveldbRoot: root})
+	require.NoError(t, err)
+	defer chain.Close()
+
+	requesterKey, requester, err := ecdsa.GenerateKeyPair()
+	require.NoError(t, err)
+	executorKey, executor, err := ecdsa.GenerateKeyPair()
+	require.NoError(t, err)
+
+	// executor nodes are registered with their signatures
+	nodeOpt := blockchain.AddNodeOptions{
+		Node: blockchain.ExecutorNode{
+			ID:      executor[:],
+			Name:    "executor1",
+			Address: "127.0.0.1:8184",
+			RegTime: time.Now().UnixNano(),
+		},
+	}
+	nodeOpt.Signature = sign(t, executorKey, nodeOpt)
+	require.NoError(t, chain.RegisterExecutorNode(&nodeOpt))
+	nodes, err := chain.ListExecutorNodes()
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	require.Equal(t, "executor1", nodes[0].Name)
+
+	task := &pbTask.FLTask{
+		TaskID:      "task1",
+		Name:        "train",
+		Requester:   requester[:],
+		PublishTime: time.Now().UnixNano(),
+		DataSets: []*pbTask.DataForTask{
+			{Owner: executor[:], Executor: executor[:], DataID: "file1"},
+		},
+	}
+	pubOpt := blockchain.PublishFLTaskOptions{FLTask: task}
+	pubOpt.Signature = sign(t, executorKey, task)
+	require.True(t, errorx.Is(chain.PublishTask(&pubOpt), errorx.ErrCodeBadSignature))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := chain.Watch(ctx, blockchain.EventTask)
+	require.NoError(t, err)
+	pubOpt.Signature = sign(t, requesterKey, task)
+	require.NoError(t, chain.PublishTask(&pubOpt))
+
+	// executors are notified of tasks published
+	e := <-events
+	var te blockchain.TaskEvent
+	require.NoError(t, json.Unmarshal(e.Payload, &te))
+	require.Equal(t, "task1", te.TaskID)
+	require.Equal(t, blockchain.TaskConfirming, te.Status)
+	require.Equal(t, [][]byte{executor[:]}, te.Executors)
+
+	got, err := chain.GetTaskById("task1")
+	require.NoError(t, err)
+	require.Equal(t, blockchain.TaskConfirming, got.Status)
+
+	// tasks can't be started before confirmed by executors
+	startOpt := blockchain.StartFLTaskOptions{TaskID: "task1"}
+	startOpt.Signature = sign(t, requesterKey, startOpt)
+	require.True(t, errorx.Is(chain.StartTask(&startOpt), errorx.ErrCodeParam))
+
+	// only executors of the task confirm it, and the file must exist
+	confirmOpt := blockchain.FLTaskConfirmOptions{
+		Pubkey:      requester[:],
+		TaskID:      "task1",
+		CurrentTime: time.Now().UnixNano(),
+	}
+	confirmOpt.Signature = sign(t, requesterKey, confirmOpt)
+	require.True(t, errorx.Is(chain.ConfirmTask(&confirmOpt), errorx.ErrCodeParam))
+	confirmOpt.Pubkey = executor[:]
+	confirmOpt.Signature = sign(t, executorKey, confirmOpt)
+	require.True(t, errorx.Is(chain.ConfirmTask(&confirmOpt), errorx.ErrCodeParam))
+
+	// the status is kept as the file is not found when rejecting either
+	confirmOpt.RejectReason = "no such file"
+	confirmOpt.Signature = sign(t, executorKey, confirmOpt)
+	require.True(t, errorx.Is(chain.RejectTask(&confirmOpt), errorx.ErrCodeParam))
+
+	tasks, err := chain.ListTask(&blockchain.ListFLTaskOptions{
+		ExecPubKey: executor[:],
+		TimeEnd:    time.Now().UnixNano(),
+	})
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	require.Equal(t, blockchain.TaskConfirming, tasks[0].Status)
+
+	// tasks are listed page by page with cursors, the latest first
+	task2 := &pbTask.FLTask{
+		TaskID:      "task2",
+		Name:        "predict",
+		Requester:   requester[:],
+		PublishTime: task.PublishTime + 1,
+		DataSets:    task.DataSets,
+	}
+	pubOpt = blockchain.PublishFLTaskOptions{FLTask: task2}
+	pubOpt.Signature = sign(t, requesterKey, task2)
+	require.NoError(t, chain.PublishTask(&pubOpt))
+	listOpt := blockchain.ListFLTaskOptions{PubKey: requester[:], Limit: 1}
+	var ids []string
+	for pages := 0; ; pages++ {
+		require.True(t, pages < 3)
+		tasks, err := chain.ListTask(&listOpt)
+		require.NoError(t, err)
+		for _, t := range tasks {
+			ids = append(ids, t.TaskID)
+		}
+		if listOpt.Cursor = blockchain.NextTaskCursor(tasks, listOpt.Limit); listOpt.Cursor == "" {
+			break
+		}
+	}
+	require.Equal(t, []string{"task2", "task1"}, ids)
+	listOpt.Cursor = "not a cursor"
+	_, err = chain.ListTask(&listOpt)
+	require.True(t, errorx.Is(err, errorx.ErrCodeParam))
+}
@@ -11,13 +11,19 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package main
+package core
 
 import (
 	"fmt"
 	"math"
+	"strings"
+
+	xdbchain "github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	"github.com/xuperchain/xuperchain/core/contractsdk/go/code"
 
 	"github.com/PaddlePaddle/PaddleDTX/dai/blockchain"
+	pbTask "github.com/PaddlePaddle/PaddleDTX/dai/protos/task"
 )
 
 const (
@@ -68,6 +74,35 @@ func packFlTaskFilter(rPubkey, ePubkey []byte) string {
 	return filter
 }
 
+// packFlTaskIndexByCursor pack the list index of the task at the cursor, in the list filtered by packFlTaskFilter
+func packFlTaskIndexByCursor(rPubkey, ePubkey []byte, c xdbchain.Cursor) string {
+	task := blockchain.FLTask(&pbTask.FLTask{TaskID: c.ID, Requester: c.Owner, PublishTime: c.Time})
+	if len(rPubkey) > 0 && len(ePubkey) > 0 {
+		return packRequesterExecutorTaskIndex(ePubkey, task)
+	} else if len(rPubkey) > 0 {
+		return packFlTaskListIndex(task)
+	}
+	return packExecutorTaskListIndex(ePubkey, task)
+}
+
+// listRange returns the range of list indexes with the prefix, starting after the index of the task at the cursor,
+// so that lists are continued from where the previous page ends
+func listRange(prefix, cursor string, index func(xdbchain.Cursor) string) ([]byte, []byte, error) {
+	start, limit := code.PrefixRange([]byte(prefix))
+	if cursor == "" {
+		return start, limit, nil
+	}
+	c, err := xdbchain.ParseCursor(cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := index(c)
+	if !strings.HasPrefix(key, prefix) {
+		return nil, nil, errorx.New(errorx.ErrCodeParam, "invalid cursor, not in the list")
+	}
+	return append([]byte(key), 0), limit, nil
+}
+
 // packNodeIndex pack index-id contract key for saving executor node
 func packNodeIndex(nodeID []byte) string {
 	return fmt.Sprintf("%s/%x", prefixNodeIndex, nodeID)
@@ -87,3 +122,11 @@ func packNodeNameIndex(name string) string {
 func packNodeListIndex(node blockchain.ExecutorNode) string {
 	return fmt.Sprintf("%s/%d/%x", prefixNodeListIndex, subByInt64Max(node.RegTime), node.ID)
 }
+
+// emitTaskEvent emits the event of the task's status for watchers
+func emitTaskEvent(ctx code.Context, t blockchain.FLTask) error {
+	if err := ctx.EmitJSONEvent(blockchain.EventTask, blockchain.NewTaskEvent(t)); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to emit task event")
+	}
+	return nil
+}
@@ -11,7 +11,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package main
+package core
 
 import (
 	"bytes"
@@ -19,6 +19,7 @@ import (
 
 	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
 	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
+	xdbchain "github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
 	"github.com/xuperchain/xuperchain/core/contractsdk/go/code"
 
@@ -90,6 +91,10 @@ func (x *Xdata) PublishTask(ctx code.Context) code.Response {
 				"fail to put requester and executor listIndex-fltask on xchain"))
 		}
 	}
+	// emit the event for watchers
+	if err := emitTaskEvent(ctx, t); err != nil {
+		return code.Error(err)
+	}
 	return code.OK([]byte("added"))
 }
 
@@ -109,9 +114,15 @@ func (x *Xdata) ListTask(ctx code.Context) code.Response {
 
 	var tasks blockchain.FLTasks
 
-	// get fltasks by list_prefix
+	// get fltasks by list_prefix, after the cursor
 	prefix := packFlTaskFilter(opt.PubKey, opt.ExecPubKey)
-	iter := ctx.NewIterator(code.PrefixRange([]byte(prefix)))
+	start, limit, err := listRange(prefix, opt.Cursor, func(c xdbchain.Cursor) string {
+		return packFlTaskIndexByCursor(opt.PubKey, opt.ExecPubKey, c)
+	})
+	if err != nil {
+		return code.Error(err)
+	}
+	iter := ctx.NewIterator(start, limit)
 	defer iter.Close()
 	for iter.Next() {
 		if opt.Limit > 0 && int64(len(tasks)) >= opt.Limit {
@@ -237,6 +248,10 @@ func (x *Xdata) setTaskConfirmStatus(ctx code.Context, isConfirm bool) code.Resp
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain,
 			"fail to confirm index-flTask on xchain"))
 	}
+	// emit the event for watchers
+	if err := emitTaskEvent(ctx, t); err != nil {
+		return code.Error(err)
+	}
 	return code.OK([]byte("OK"))
 }
 
@@ -281,6 +296,10 @@ func (x *Xdata) StartTask(ctx code.Context) code.Response {
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain,
 			"fail to start index-flTask on xchain"))
 	}
+	// emit the event for watchers
+	if err := emitTaskEvent(ctx, t); err != nil {
+		return code.Error(err)
+	}
 	return code.OK([]byte("OK"))
 }
 
@@ -359,6 +378,10 @@ func (x *Xdata) setTaskExecuteStatus(ctx code.Context, isFinish bool) code.Respo
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain,
 			"fail to set task execute status on xchain"))
 	}
+	// emit the event for watchers
+	if err := emitTaskEvent(ctx, t); err != nil {
+		return code.Error(err)
+	}
 	return code.OK([]byte("OK"))
 }
 
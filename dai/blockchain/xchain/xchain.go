@@ -46,6 +46,10 @@ func New(conf *config.XchainConf) (*XChain, error) {
 
 // Close closes client
 func (x *XChain) Close() {
+	// the contract running in process has no connection
+	if x.XChain.Contract != nil {
+		return
+	}
 	if err := x.XChain.XchainClient.XchainConn.Close(); err != nil {
 		logger.WithError(err).Error("failed to close xchain client")
 	}
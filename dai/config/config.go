@@ -42,6 +42,7 @@ type ExecutorConf struct {
 	Mpc             *ExecutorMpcConf
 	Storage         *ExecutorStorageConf // model storage and prediction results storage
 	Blockchain      *ExecutorBlockchainConf
+	Index           *ExecutorIndexConf // local index of tasks, disabled if nil
 }
 
 // HttpServerConf defines the configuration required to start the executor node's httpserver
@@ -100,6 +101,7 @@ type ExecutorBlockchainConf struct {
 	Type   string
 	Xchain *XchainConf
 	Fabric *FabricConf
+	Local  *LocalChainConf
 }
 
 type XchainConf struct {
@@ -118,6 +120,18 @@ type FabricConf struct {
 	OrgName    string
 }
 
+// ExecutorIndexConf is the configuration of the local index of tasks the executor involves,
+// which is synced from blockchain into LeveldbRoot every SyncInterval minutes, and searched and paged by cursors
+type ExecutorIndexConf struct {
+	LeveldbRoot  string
+	SyncInterval int64
+}
+
+// LocalChainConf is the configuration of the local blockchain, which runs the contract in process on leveldb
+type LocalChainConf struct {
+	LeveldbRoot string
+}
+
 // Log defines the storage path of the logs generated by the executor node at runtime
 type Log struct {
 	Level string
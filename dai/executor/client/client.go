@@ -65,6 +65,14 @@ func (c *Client) GetTaskById(ctx context.Context, id string) (*pbTask.FLTask, er
 // limit is the maximum number of tasks to response
 func (c *Client) ListTask(ctx context.Context, rPubkeyStr, ePubkeyStr, status string, start, end,
 	limit int64) (ts *pbTask.FLTasks, err error) {
+	return c.SearchTask(ctx, rPubkeyStr, ePubkeyStr, status, "", start, end, limit, "")
+}
+
+// SearchTask searches tasks by keywords found in task name or description, tasks are paged by cursor,
+// which is returned by the previous page as ts.Next.
+// Keywords are supported only if the executor's local index is enabled and ePubkeyStr is the executor's
+func (c *Client) SearchTask(ctx context.Context, rPubkeyStr, ePubkeyStr, status, keywords string, start, end,
+	limit int64, cursor string) (ts *pbTask.FLTasks, err error) {
 	if c.conn != nil {
 		defer c.conn.Close()
 	}
@@ -86,6 +94,8 @@ func (c *Client) ListTask(ctx context.Context, rPubkeyStr, ePubkeyStr, status st
 		TimeEnd:   end,
 		Status:    status,
 		Limit:     limit,
+		Keywords:  keywords,
+		Cursor:    cursor,
 	}
 
 	ts, err = c.executorClient.ListTask(ctx, in)
@@ -27,9 +27,11 @@ import (
 )
 
 var (
-	status  string
-	rPubkey string
-	ePubkey string
+	status   string
+	rPubkey  string
+	ePubkey  string
+	keywords string
+	cursor   string
 )
 
 // listTasksCmd lists tasks from blockchain with specific participant public key and task status
@@ -69,7 +71,8 @@ var listTasksCmd = &cobra.Command{
 			ePubkey = strings.TrimSpace(string(pubkeyBytes))
 		}
 
-		tasks, err := client.ListTask(context.Background(), rPubkey, ePubkey, status, startTime, endTime.UnixNano(), limit)
+		tasks, err := client.SearchTask(context.Background(), rPubkey, ePubkey, status, keywords, startTime, endTime.UnixNano(),
+			limit, cursor)
 		if err != nil {
 			fmt.Printf("ListTask failed：%v\n", err)
 			return
@@ -82,6 +85,9 @@ var listTasksCmd = &cobra.Command{
 		}
 
 		fmt.Printf("taskNum : %d\n\n", len(tasks.FLTasks))
+		if tasks.Next != "" {
+			fmt.Printf("more tasks listed with --cursor %s\n", tasks.Next)
+		}
 	},
 }
 
@@ -94,5 +100,7 @@ func init() {
 	listTasksCmd.Flags().StringVarP(&start, "start", "s", "", "start of time range during which tasks were published, example '2021-06-10 12:00:00'")
 	listTasksCmd.Flags().StringVarP(&end, "end", "e", time.Unix(0, time.Now().UnixNano()).Format(timeTemplate), "end of time range during which tasks were published, example '2021-06-10 12:00:00'")
 	listTasksCmd.Flags().Int64VarP(&limit, "limit", "l", blockchain.TaskListMaxNum, "limit of number for listing tasks")
+	listTasksCmd.Flags().StringVarP(&keywords, "keywords", "k", "", "words found in task name or description, requires the executor's local index")
+	listTasksCmd.Flags().StringVar(&cursor, "cursor", "", "cursor printed by the previous list to get the next page")
 	listTasksCmd.Flags().StringVar(&status, "status", "", "status of task, such as Confirming, Ready, ToProcess, Processing, Finished, Failed, default for all types of status")
 }
@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/json"
 	"io/ioutil"
+	"time"
 
 	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
 	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
@@ -30,6 +31,7 @@ import (
 	vl_common "github.com/PaddlePaddle/PaddleDTX/dai/crypto/vl/common"
 	"github.com/PaddlePaddle/PaddleDTX/dai/errcodes"
 	"github.com/PaddlePaddle/PaddleDTX/dai/executor/handler"
+	"github.com/PaddlePaddle/PaddleDTX/dai/executor/index"
 	"github.com/PaddlePaddle/PaddleDTX/dai/executor/monitor"
 	"github.com/PaddlePaddle/PaddleDTX/dai/mpc/cluster"
 	pbCom "github.com/PaddlePaddle/PaddleDTX/dai/protos/common"
@@ -46,12 +48,15 @@ var (
 //  storage is the handler for results storage, which includes trained model and prediction result storage
 //  mpcHandler is the handler for mpc task execution, which includes task preparation, task execution, results storage...
 //  monitor is the handler for task monitoring, that is, monitoring tasks to be executed
+//  index is the local index of tasks the node involves, synced every indexInterval, nil if not enabled
 type Engine struct {
-	chain      handler.Blockchain
-	node       handler.Node
-	storage    handler.FileStorage
-	mpcHandler handler.MpcHandler
-	monitor    *monitor.TaskMonitor
+	chain         handler.Blockchain
+	node          handler.Node
+	storage       handler.FileStorage
+	mpcHandler    handler.MpcHandler
+	monitor       *monitor.TaskMonitor
+	index         *index.Index
+	indexInterval time.Duration
 }
 
 // NewEngine initiates Engine by executor node configuration
@@ -72,6 +77,10 @@ func (e *Engine) Start(ctx context.Context) error {
 	// then starts Multi-Party Computation for each task
 	e.monitor.StartTaskLoopRequest(ctx)
 
+	// sync tasks into the local index to list them without contract queries
+	if e.index != nil {
+		go e.syncIndex(ctx)
+	}
 	return nil
 }
 
@@ -80,8 +89,21 @@ func (e *Engine) GetMpcService() *cluster.Service {
 	return e.mpcHandler.GetMpcClusterService()
 }
 
-// ListTask lists tasks from blockchain by requester or executor's Public Key
+// ListTask lists tasks from blockchain by requester or executor's Public Key.
+// Tasks local node involves are listed from the local index once synced, which are searched by keywords
+// and paged by cursors, keywords and cursors are not supported without the index
 func (e *Engine) ListTask(ctx context.Context, in *pbTask.ListTaskRequest) (*pbTask.FLTasks, error) {
+	if resp, ok, err := e.listIndexedTasks(in); ok || err != nil {
+		if err != nil {
+			return &pbTask.FLTasks{}, errorx.Wrap(err, "failed list task from index")
+		}
+		return resp, nil
+	}
+	if in.Keywords != "" {
+		return &pbTask.FLTasks{}, errorx.New(errorx.ErrCodeParam,
+			"keywords are only supported by the local index of the executor's tasks, which is not enabled or synced")
+	}
+
 	listOptions := &blockchain.ListFLTaskOptions{
 		PubKey:     in.PubKey,
 		ExecPubKey: in.EPubKey,
@@ -89,6 +111,7 @@ func (e *Engine) ListTask(ctx context.Context, in *pbTask.ListTaskRequest) (*pbT
 		TimeStart:  in.TimeStart,
 		TimeEnd:    in.TimeEnd,
 		Limit:      in.Limit,
+		Cursor:     in.Cursor,
 	}
 	// invoke contract to list tasks
 	fts, err := e.chain.ListTask(listOptions)
@@ -96,7 +119,7 @@ func (e *Engine) ListTask(ctx context.Context, in *pbTask.ListTaskRequest) (*pbT
 		return &pbTask.FLTasks{}, errorx.Wrap(err, "failed list task")
 	}
 	// traverse tasks
-	resp := &pbTask.FLTasks{}
+	resp := &pbTask.FLTasks{Next: blockchain.NextTaskCursor(fts, listOptions.Limit)}
 	for _, ft := range fts {
 		resp.FLTasks = append(resp.FLTasks, ft)
 	}
@@ -260,4 +283,7 @@ func (e *Engine) Close() {
 	if e.chain != nil {
 		e.chain.Close()
 	}
+	if e.index != nil {
+		e.index.Close()
+	}
 }
@@ -0,0 +1,148 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	xdbchain "github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/sirupsen/logrus"
+
+	"github.com/PaddlePaddle/PaddleDTX/dai/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/dai/executor/index"
+	pbTask "github.com/PaddlePaddle/PaddleDTX/dai/protos/task"
+)
+
+// DefaultIndexSyncInterval is the default interval to sync tasks into the local index
+const DefaultIndexSyncInterval = time.Minute * 10
+
+// listIndexedTasks lists tasks from the local index if they're involved by local node and have been synced,
+// returns false if the index is not able to answer the request, or the list is continued from a cursor
+// returned by the contract
+func (e *Engine) listIndexedTasks(in *pbTask.ListTaskRequest) (*pbTask.FLTasks, bool, error) {
+	if e.index == nil || !bytes.Equal(in.EPubKey, e.node.ID) {
+		return nil, false, nil
+	}
+	if _, err := xdbchain.ParseCursor(in.Cursor); in.Cursor != "" && err == nil {
+		return nil, false, nil
+	}
+	synced, err := e.index.Synced()
+	if err != nil || synced == 0 {
+		return nil, false, err
+	}
+	tasks, next, err := e.index.Tasks(index.TaskQuery{
+		Requester: in.PubKey,
+		Status:    in.Status,
+		Keywords:  in.Keywords,
+		TimeStart: in.TimeStart,
+		TimeEnd:   in.TimeEnd,
+		Cursor:    in.Cursor,
+		Limit:     in.Limit,
+	})
+	if err != nil {
+		return nil, true, err
+	}
+	return &pbTask.FLTasks{FLTasks: tasks, Next: next}, true, nil
+}
+
+// syncIndex synchronizes tasks local node involves into the local index every interval,
+// and updates tasks by their events in between
+func (e *Engine) syncIndex(ctx context.Context) {
+	l := logger.WithField("runner", "index sync loop")
+	defer l.Info("index sync stopped")
+
+	events := xdbchain.Watch(ctx, e.chain, blockchain.EventTask)
+	ticker := time.NewTicker(e.indexInterval)
+	defer ticker.Stop()
+
+	e.syncTasks(ctx, l)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.syncTasks(ctx, l)
+		case ev := <-events:
+			e.indexTaskEvent(ev, l)
+		}
+	}
+}
+
+// syncTasks lists all tasks local node involves from blockchain page by page into the local index,
+// and records the time of synchronization once they're listed completely
+func (e *Engine) syncTasks(ctx context.Context, l *logrus.Entry) {
+	start := time.Now().UnixNano()
+	opt := blockchain.ListFLTaskOptions{
+		ExecPubKey: e.node.ID,
+		TimeEnd:    start,
+		Limit:      blockchain.TaskListMaxNum,
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		tasks, err := e.chain.ListTask(&opt)
+		if err != nil {
+			l.WithError(err).Warn("failed to sync tasks into index")
+			return
+		}
+		for _, t := range tasks {
+			if err := e.index.PutTask(t); err != nil {
+				l.WithError(err).WithField("taskID", t.TaskID).Warn("failed to index task")
+				return
+			}
+		}
+		if opt.Cursor = blockchain.NextTaskCursor(tasks, opt.Limit); opt.Cursor == "" {
+			break
+		}
+	}
+	if err := e.index.SetSynced(start); err != nil {
+		l.WithError(err).Warn("failed to record index sync time")
+	}
+}
+
+// indexTaskEvent updates the task in the local index once its status changes
+func (e *Engine) indexTaskEvent(ev xdbchain.Event, l *logrus.Entry) {
+	if ev.Name != blockchain.EventTask {
+		return
+	}
+	var te blockchain.TaskEvent
+	if err := json.Unmarshal(ev.Payload, &te); err != nil {
+		l.WithError(err).Warn("failed to unmarshal task event")
+		return
+	}
+	involved := false
+	for _, executor := range te.Executors {
+		if bytes.Equal(executor, e.node.ID) {
+			involved = true
+			break
+		}
+	}
+	if !involved {
+		return
+	}
+	t, err := e.chain.GetTaskById(te.TaskID)
+	if err != nil {
+		l.WithError(err).WithField("taskID", te.TaskID).Warn("failed to get task to index")
+		return
+	}
+	if err := e.index.PutTask(t); err != nil {
+		l.WithError(err).WithField("taskID", te.TaskID).Warn("failed to index task")
+	}
+}
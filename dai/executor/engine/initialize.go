@@ -22,9 +22,11 @@ import (
 	"github.com/PaddlePaddle/PaddleDTX/xdb/peer"
 
 	"github.com/PaddlePaddle/PaddleDTX/dai/blockchain/fabric"
+	localblockchain "github.com/PaddlePaddle/PaddleDTX/dai/blockchain/local"
 	"github.com/PaddlePaddle/PaddleDTX/dai/blockchain/xchain"
 	"github.com/PaddlePaddle/PaddleDTX/dai/config"
 	"github.com/PaddlePaddle/PaddleDTX/dai/executor/handler"
+	"github.com/PaddlePaddle/PaddleDTX/dai/executor/index"
 	"github.com/PaddlePaddle/PaddleDTX/dai/executor/monitor"
 	"github.com/PaddlePaddle/PaddleDTX/dai/executor/storage/local"
 	"github.com/PaddlePaddle/PaddleDTX/dai/executor/storage/xuperdb"
@@ -77,14 +79,21 @@ func initEngine(conf *config.ExecutorConf) (e *Engine, err error) {
 	if err != nil {
 		return e, err
 	}
+	// get the local index of tasks if enabled
+	taskIndex, indexInterval, err := newIndex(conf.Index)
+	if err != nil {
+		return e, err
+	}
 	logger.Info("initiate engine successfully")
 
 	return &Engine{
-		node:       node,
-		chain:      chain,
-		storage:    storage,
-		mpcHandler: mpcHandler,
-		monitor:    taskMonitor,
+		node:          node,
+		chain:         chain,
+		storage:       storage,
+		mpcHandler:    mpcHandler,
+		monitor:       taskMonitor,
+		index:         taskIndex,
+		indexInterval: indexInterval,
 	}, nil
 }
 
@@ -95,6 +104,8 @@ func newBlockchain(conf *config.ExecutorBlockchainConf) (b handler.Blockchain, e
 		b, err = xchain.New(conf.Xchain)
 	case "fabric":
 		b, err = fabric.New(conf.Fabric)
+	case "local":
+		b, err = localblockchain.New(conf.Local)
 	default:
 		return b, errorx.New(errorx.ErrCodeConfig, "invalid blockchain type: %s", conf.Type)
 	}
@@ -270,3 +281,19 @@ func newMonitor(fileDownloadType string, privateKey ecdsa.PrivateKey, chain hand
 		MpcHandler: mpcHandler,
 	}, nil
 }
+
+// newIndex opens the local index of tasks and returns the interval to sync it, the index is nil if not configured
+func newIndex(conf *config.ExecutorIndexConf) (*index.Index, time.Duration, error) {
+	if conf == nil {
+		return nil, 0, nil
+	}
+	i, err := index.New(conf)
+	if err != nil {
+		return nil, 0, err
+	}
+	interval := time.Duration(conf.SyncInterval) * time.Minute
+	if interval == 0 {
+		interval = DefaultIndexSyncInterval
+	}
+	return i, interval, nil
+}
@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/cjqpker/slidewindow"
-	"github.com/sirupsen/logrus"
 
 	"github.com/PaddlePaddle/PaddleDTX/crypto/core/aes"
 	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
@@ -19,6 +18,8 @@ import (
 	"github.com/PaddlePaddle/PaddleDTX/dai/executor/storage/xuperdb"
 	xdbchain "github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/common"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/compressor"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/http"
@@ -152,9 +153,10 @@ func (f *FileDownload) getDecryptAuthKey(authKey []byte) (firKey aes.AESKey, sec
 // 1. parameters check
 // 2. read file info from the blockchain
 // 3. decrypt the file's struct to get slice's order
-// 4. download slices from the storage node, if request fails, pull slices from other storage nodes
+// 4. download slices from the storage node, if request fails, pull slices from other storage nodes,
+//    missing data slices of erasure coded files are rebuilt from parity shards of the same stripe
 // 5. slices decryption and combination
-// 6. decrypt the combined slices to get the original file
+// 6. decrypt the combined slices to get the original file, and decompress it if compressed
 func (f *FileDownload) recoverFile(ctx context.Context, chain Blockchain, file xdbchain.File,
 	firstKey aes.AESKey, secKey map[string]map[string]aes.AESKey) (io.ReadCloser, error) {
 	ctx, cancel := context.WithCancel(ctx)
@@ -185,90 +187,56 @@ func (f *FileDownload) recoverFile(ctx context.Context, chain Blockchain, file x
 		return nil, err
 	}
 
+	// erasure coded files are read stripe by stripe, each slice is a stripe by itself otherwise
+	stripes := common.GroupStripes(fs, file.ErasureCoded())
+
 	// use sliding window
 	sw := slidewindow.SlideWindow{
-		Total:       uint64(len(fs)),
+		Total:       uint64(len(stripes)),
 		Concurrency: defaultConcurrency,
 	}
 
 	sw.Init = func(ctx context.Context, s *slidewindow.Session) error {
 		return nil
 	}
-	// get the mapping of slice-storageNodes
-	slicesPool := makeSlicesPool4Read(file.Slices)
+	// get the mapping of slice-storageNodes, referenced slices are read from their source files
+	slicesPool := common.MakeSlicesPool4Read(common.ResolveSliceRefs(chain, file.Slices, logger))
+	pull := func(ctx context.Context, slice xdbchain.PrivateSliceMeta) ([]byte, error) {
+		return f.pullSlice(ctx, file.ID, slice, slicesPool[slice.SliceID], nodesMap, secKey)
+	}
 	sw.Task = func(ctx context.Context, s *slidewindow.Session) error {
-		slice := fs[int(s.Index())]
-		// get the list of storage nodes where the slice stored
-		targetPool, ok := slicesPool[slice.SliceID]
-		if !ok {
-			return errorx.Internal(nil, "bad file structure")
-		}
-		for _, target := range targetPool {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-			}
+		stripe := stripes[int(s.Index())]
 
-			node, exist := nodesMap[string(target.NodeID)]
-			if !exist || !node.Online {
-				logger.WithField("node_id", string(target.NodeID)).Warn("abnormal node")
-				continue
-			}
-			// pull slice
-			r, err := f.pull(ctx, target.ID, target.StorIndex, file.ID, node.Address)
-			if err != nil {
-				logger.WithFields(logrus.Fields{
-					"slice_id":    target.ID,
-					"file_id":     file.ID,
-					"target_node": string(node.ID),
-				}).WithError(err).Warn("failed to pull slice")
-				continue
-			}
-			defer r.Close()
-
-			// read slice and check slice hash
-			cipherText, err := ioutil.ReadAll(r)
-			if err != nil {
-				logger.WithError(err).Warn("failed to read slice from target node")
-				continue
-			}
-			if len(cipherText) != int(target.Length) {
-				logger.WithFields(logrus.Fields{"expected": target.Length, "got": len(cipherText)}).
-					Warn("invalid slice length.")
-				continue
-			}
-			hGot := hash.HashUsingSha256(cipherText)
-			if !bytes.Equal(hGot, target.CipherHash) {
-				logger.WithFields(logrus.Fields{"expected": target.CipherHash, "got": hGot}).
-					Warn("invalid slice hash.")
-				continue
+		if !file.ErasureCoded() {
+			slice := stripe[0]
+			if _, ok := slicesPool[slice.SliceID]; !ok {
+				return errorx.Internal(nil, "bad file structure")
 			}
-
-			// decrypt the encrypted slice
-			plainText, err := f.recover(secKey[target.ID][string(node.ID)], cipherText)
+			plainText, err := pull(ctx, slice)
 			if err != nil {
-				logger.WithError(err).Error("failed to decrypt slice")
-				continue
+				return err
 			}
-
-			// trim 0 at the end of file
-			if s.Index() != sw.Total-1 {
-				s.Set("data", plainText)
-			} else {
-				s.Set("data", bytes.TrimRight(plainText, string([]byte{0})))
+			// slices of files in convergent format are decrypted one by one
+			if file.CipherFormat == encryptor.FormatConvergentGCM {
+				if plainText, err = encryptor.ConvergentOpen(plainText, slice.Key, slice.PlainHash); err != nil {
+					return errorx.Wrap(err, "failed to decrypt slice %s", slice.SliceID)
+				}
 			}
-
-			break
+			s.Set("data", [][]byte{plainText})
+			return nil
 		}
 
-		if _, exist := s.Get("data"); !exist {
-			return errorx.New(errorx.ErrCodeNotFound, "failed to pull slice %s", slice.SliceID)
+		plainTexts, err := common.ReadStripe(ctx, stripe, pull, logger)
+		if err != nil {
+			return err
 		}
-
+		s.Set("data", plainTexts)
 		return nil
 	}
 
+	// zero padding at the end of the last slice or stripe is dropped,
+	// so that only the ciphertext of the file is written
+	remain := cipherLength(file)
 	reader, writer := io.Pipe()
 	sw.Done = func(ctx context.Context, s *slidewindow.Session) error {
 		data, exist := s.Get("data")
@@ -276,12 +244,18 @@ func (f *FileDownload) recoverFile(ctx context.Context, chain Blockchain, file x
 			return errorx.New(errorx.ErrCodeNotFound, "failed to find data")
 		}
 
-		if _, err := writer.Write(data.([]byte)); err != nil {
-			return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write")
+		for _, d := range data.([][]byte) {
+			if uint64(len(d)) > remain {
+				d = d[:remain]
+			}
+			if _, err := writer.Write(d); err != nil {
+				return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write")
+			}
+			remain -= uint64(len(d))
 		}
 
 		// exit on success
-		if s.Index() == uint64(len(fs)-1) {
+		if s.Index() == uint64(len(stripes)-1) {
 			writer.Close()
 		}
 		return nil
@@ -294,6 +268,29 @@ func (f *FileDownload) recoverFile(ctx context.Context, chain Blockchain, file x
 		}
 	}()
 
+	// slices are decrypted already in convergent format
+	if file.CipherFormat == encryptor.FormatConvergentGCM {
+		return reader, nil
+	}
+
+	// decrypt recovered file as a stream, closing the returned reader stops pulling slices
+	if file.CipherFormat == encryptor.FormatChunkedGCM && compressor.Enabled(file.Compression) {
+		decompressor, err := compressor.Decompress(file.Compression,
+			encryptor.NewStreamDecrypter(firstKey, reader, file.CompressedLength, common.StreamSalts(file)))
+		if err != nil {
+			reader.Close()
+			return nil, err
+		}
+		return &decompressedReader{ReadCloser: decompressor, pipe: reader}, nil
+	}
+	if file.CipherFormat == encryptor.FormatChunkedGCM {
+		plainText := encryptor.NewStreamDecrypter(firstKey, reader, file.Length, common.StreamSalts(file))
+		return struct {
+			io.Reader
+			io.Closer
+		}{plainText, reader}, nil
+	}
+
 	// decrypt recovered file
 	fileCipherText, err := ioutil.ReadAll(reader)
 	if err != nil {
@@ -306,6 +303,49 @@ func (f *FileDownload) recoverFile(ctx context.Context, chain Blockchain, file x
 	return ioutil.NopCloser(bytes.NewReader(plainText)), nil
 }
 
+// cipherLength returns the length of the file ciphertext combined by slices,
+// the ciphertext is 16 bytes more than the original text in legacy format
+func cipherLength(file xdbchain.File) uint64 {
+	switch file.CipherFormat {
+	case encryptor.FormatChunkedGCM:
+		length := file.Length
+		if compressor.Enabled(file.Compression) {
+			length = file.CompressedLength
+		}
+		_, end := encryptor.StreamCipherRange(length, encryptor.DefaultChunkSize, 0, length)
+		return end
+	case encryptor.FormatConvergentGCM:
+		return file.Length
+	}
+	return file.Length + 16
+}
+
+// pullSlice pulls a slice from one of the storage nodes in targetPool and decrypts it
+func (f *FileDownload) pullSlice(ctx context.Context, fileID string, slice xdbchain.PrivateSliceMeta,
+	targetPool []xdbchain.PublicSliceMeta, nodesMap map[string]xdbchain.Node,
+	secKey map[string]map[string]aes.AESKey) ([]byte, error) {
+	fetch := func(ctx context.Context, target xdbchain.PublicSliceMeta, node *xdbchain.Node) (io.ReadCloser, error) {
+		return f.pull(ctx, target.ID, target.StorIndex, fileID, node.Address)
+	}
+	decrypt := func(target xdbchain.PublicSliceMeta, cipherText []byte) ([]byte, error) {
+		return f.recover(secKey[target.ID][string(target.NodeID)], cipherText)
+	}
+	return common.PullSlice(ctx, fileID, slice.SliceID, targetPool, nodesMap, fetch, decrypt, logger)
+}
+
+// decompressedReader reads plaintext of a compressed file,
+// Close stops pulling slices before releasing the decompressor blocked in reading
+type decompressedReader struct {
+	io.ReadCloser
+	pipe *io.PipeReader
+}
+
+func (d *decompressedReader) Close() error {
+	err := d.pipe.Close()
+	d.ReadCloser.Close()
+	return err
+}
+
 // pull used pull slices from storage nodes
 func (f *FileDownload) pull(ctx context.Context, id, storIndex, fileId, nodeAddress string) (io.ReadCloser, error) {
 	// Add signature
@@ -361,19 +401,3 @@ func (f *FileDownload) recover(aesKey aes.AESKey, ciphertext []byte) ([]byte, er
 
 	return plaintext, nil
 }
-
-// makeSlicesPool4Read return the mapping of storage nodes where the slice is located
-// key is sliceID, value is the list of slices meta
-func makeSlicesPool4Read(srs []xdbchain.PublicSliceMeta) map[string][]xdbchain.PublicSliceMeta {
-	slicesPool := make(map[string][]xdbchain.PublicSliceMeta)
-	for _, s := range srs {
-		var ss []xdbchain.PublicSliceMeta
-		if v, exist := slicesPool[s.ID]; exist {
-			ss = v
-		}
-		ss = append(ss, s)
-		slicesPool[s.ID] = ss
-	}
-
-	return slicesPool
-}
@@ -0,0 +1,155 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/kvindex"
+
+	"github.com/PaddlePaddle/PaddleDTX/dai/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/dai/config"
+	pbTask "github.com/PaddlePaddle/PaddleDTX/dai/protos/task"
+)
+
+const (
+	dbName = "indexDB"
+
+	kindTask = "task"
+
+	syncedName = "synced"
+)
+
+// TaskQuery is the query of tasks in the local index, Requester and Status are optional.
+// Keywords are words found in the task name or description, case-insensitive.
+// Tasks are sorted by publish time, in descending order unless Asc,
+// TimeStart and TimeEnd are inclusive and zero TimeEnd is unbounded
+type TaskQuery struct {
+	Requester []byte
+	Status    string
+	Keywords  string
+	Asc       bool
+
+	TimeStart int64
+	TimeEnd   int64
+
+	Cursor string
+	Limit  int64
+}
+
+// Index indexes tasks the executor involves in levelDB, which are synced from blockchain,
+// so that they can be searched and paged by cursors without contract queries
+type Index struct {
+	store *kvindex.Store
+}
+
+// New creates an Index by index configuration
+func New(conf *config.ExecutorIndexConf) (*Index, error) {
+	if len(conf.LeveldbRoot) == 0 {
+		return nil, errorx.New(errorx.ErrCodeConfig, "missing config: leveldbRoot")
+	}
+	if conf.SyncInterval < 0 {
+		return nil, errorx.New(errorx.ErrCodeConfig, "invalid index config, negative syncInterval")
+	}
+	store, err := kvindex.Open(filepath.Join(conf.LeveldbRoot, dbName))
+	if err != nil {
+		return nil, err
+	}
+	return &Index{store: store}, nil
+}
+
+// PutTask adds a task or updates it in the index
+func (i *Index) PutTask(t *pbTask.FLTask) error {
+	value := kvindex.Int(t.PublishTime)
+	return i.store.Put(kindTask, t.TaskID, t,
+		kvindex.Key{Sort: taskSort(nil), Value: value},
+		kvindex.Key{Sort: taskSort(t.Requester), Value: value},
+	)
+}
+
+// Tasks returns tasks matched by q and the cursor of the next page, which is empty if no tasks are left
+func (i *Index) Tasks(q TaskQuery) (blockchain.FLTasks, string, error) {
+	keywords := strings.Fields(strings.ToLower(q.Keywords))
+	kq := kvindex.Query{
+		Kind:   kindTask,
+		Sort:   taskSort(q.Requester),
+		Desc:   !q.Asc,
+		Cursor: q.Cursor,
+		Limit:  int(q.Limit),
+	}
+	if q.TimeStart > 0 {
+		kq.Min = kvindex.Int(q.TimeStart)
+	}
+	if q.TimeEnd > 0 {
+		kq.Max = kvindex.Int(q.TimeEnd)
+	}
+	kq.Match = func(value []byte) (bool, error) {
+		var t pbTask.FLTask
+		if err := json.Unmarshal(value, &t); err != nil {
+			return false, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal task")
+		}
+		return (q.Status == "" || t.Status == q.Status) && matchKeywords(&t, keywords), nil
+	}
+
+	values, next, err := i.store.Query(kq)
+	if err != nil {
+		return nil, "", err
+	}
+	tasks := make(blockchain.FLTasks, 0, len(values))
+	for _, v := range values {
+		t := new(pbTask.FLTask)
+		if err := json.Unmarshal(v, t); err != nil {
+			return nil, "", errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal task")
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, next, nil
+}
+
+// Synced returns the time tasks were last synced completely, zero if never
+func (i *Index) Synced() (int64, error) {
+	var synced int64
+	_, err := i.store.GetMeta(syncedName, &synced)
+	return synced, err
+}
+
+// SetSynced records the time tasks were synced completely
+func (i *Index) SetSynced(synced int64) error {
+	return i.store.PutMeta(syncedName, synced)
+}
+
+// Close closes levelDB
+func (i *Index) Close() {
+	i.store.Close()
+}
+
+// taskSort returns the name of the sort of tasks published by the requester, empty for all requesters
+func taskSort(requester []byte) string {
+	return fmt.Sprintf("%x", requester)
+}
+
+// matchKeywords checks if all keywords are found in the task name or description
+func matchKeywords(t *pbTask.FLTask, keywords []string) bool {
+	text := strings.ToLower(t.Name + "\n" + t.Description)
+	for _, w := range keywords {
+		if !strings.Contains(text, w) {
+			return false
+		}
+	}
+	return true
+}
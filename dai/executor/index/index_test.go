@@ -0,0 +1,93 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/dai/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/dai/config"
+	pbCom "github.com/PaddlePaddle/PaddleDTX/dai/protos/common"
+	pbTask "github.com/PaddlePaddle/PaddleDTX/dai/protos/task"
+)
+
+func TestTasks(t *testing.T) {
+	root, err := ioutil.TempDir("", "index")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	i, err := New(&config.ExecutorIndexConf{LeveldbRoot: root})
+	require.NoError(t, err)
+	defer i.Close()
+
+	r1, r2 := []byte("r1"), []byte("r2")
+	for _, task := range []*pbTask.FLTask{
+		{TaskID: "t1", Name: "Train Boston", Description: "linear regression", Requester: r1,
+			Status: blockchain.TaskFinished, PublishTime: 1, AlgoParam: &pbCom.TaskParams{TaskType: pbCom.TaskType_LEARN}},
+		{TaskID: "t2", Name: "predict boston", Requester: r1, Status: blockchain.TaskConfirming, PublishTime: 2},
+		{TaskID: "t3", Name: "train iris", Description: "logistic regression", Requester: r2,
+			Status: blockchain.TaskConfirming, PublishTime: 3},
+	} {
+		require.NoError(t, i.PutTask(task))
+	}
+
+	ids := func(q TaskQuery) ([]string, string) {
+		list, next, err := i.Tasks(q)
+		require.NoError(t, err)
+		var ids []string
+		for _, task := range list {
+			ids = append(ids, task.TaskID)
+		}
+		return ids, next
+	}
+	list, _ := ids(TaskQuery{})
+	require.Equal(t, []string{"t3", "t2", "t1"}, list)
+	list, _ = ids(TaskQuery{Requester: r1, Asc: true})
+	require.Equal(t, []string{"t1", "t2"}, list)
+	list, _ = ids(TaskQuery{Status: blockchain.TaskConfirming})
+	require.Equal(t, []string{"t3", "t2"}, list)
+	list, _ = ids(TaskQuery{Keywords: "BOSTON train"})
+	require.Equal(t, []string{"t1"}, list)
+	list, _ = ids(TaskQuery{Keywords: "regression", TimeEnd: 2})
+	require.Equal(t, []string{"t1"}, list)
+
+	// pages
+	list, next := ids(TaskQuery{Limit: 2})
+	require.Equal(t, []string{"t3", "t2"}, list)
+	list, next = ids(TaskQuery{Limit: 2, Cursor: next})
+	require.Equal(t, []string{"t1"}, list)
+	require.Empty(t, next)
+
+	// tasks keep their parameters, and status updated
+	tasks, _, err := i.Tasks(TaskQuery{Requester: r1, TimeStart: 1, TimeEnd: 1})
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	require.Equal(t, pbCom.TaskType_LEARN, tasks[0].AlgoParam.TaskType)
+	require.NoError(t, i.PutTask(&pbTask.FLTask{TaskID: "t2", Name: "predict boston", Requester: r1,
+		Status: blockchain.TaskFailed, PublishTime: 2}))
+	list, _ = ids(TaskQuery{Status: blockchain.TaskConfirming})
+	require.Equal(t, []string{"t3"}, list)
+
+	synced, err := i.Synced()
+	require.NoError(t, err)
+	require.Zero(t, synced)
+	require.NoError(t, i.SetSynced(5))
+	synced, err = i.Synced()
+	require.NoError(t, err)
+	require.Equal(t, int64(5), synced)
+}
@@ -16,6 +16,7 @@ package monitor
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -36,7 +37,8 @@ import (
 // loopRequest checks blockchain every some seconds to find tasks ready to execute,
 // then starts Multi-Party Computation for each task.
 // And checks tasks in execution pool if they're expired,
-// then stops expired tasks
+// then stops expired tasks.
+// If the blockchain supports events, tasks are also confirmed and started once their status changes
 func (t *TaskMonitor) loopRequest(ctx context.Context) {
 	logger.Info("task loop start")
 
@@ -48,10 +50,16 @@ func (t *TaskMonitor) loopRequest(ctx context.Context) {
 
 	defer logger.Info("task loop stopped")
 
+	// react to tasks and authorization applications involving local node, and poll for those missed
+	events := xdbchain.Watch(ctx, t.Blockchain, blockchain.EventTask, xdbchain.EventFileAuth)
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case e := <-events:
+			t.reactToEvent(e)
+			continue
 		case <-ticker.C:
 		}
 
@@ -73,6 +81,54 @@ func (t *TaskMonitor) loopRequest(ctx context.Context) {
 	}
 }
 
+// reactToEvent confirms tasks once they're published or authorization applications are approved or rejected,
+// and starts tasks once requesters start them
+func (t *TaskMonitor) reactToEvent(e xdbchain.Event) {
+	switch e.Name {
+	case blockchain.EventTask:
+		var te blockchain.TaskEvent
+		if err := json.Unmarshal(e.Payload, &te); err != nil {
+			logger.WithError(err).Warn("failed to unmarshal task event")
+			return
+		}
+		if !t.executes(te.Executors) {
+			return
+		}
+		switch te.Status {
+		case blockchain.TaskConfirming:
+			if err := t.getUnconfirmedTaskAndConfirm(); err != nil {
+				logger.WithError(err).Errorf("failed to confirm the task published, taskID: %s", te.TaskID)
+			}
+		case blockchain.TaskToProcess:
+			if err := t.getToProcessTaskAndStart(); err != nil {
+				logger.WithError(err).Errorf("failed to start the task, taskID: %s", te.TaskID)
+			}
+		}
+	case xdbchain.EventFileAuth:
+		var fe xdbchain.FileAuthEvent
+		if err := json.Unmarshal(e.Payload, &fe); err != nil {
+			logger.WithError(err).Warn("failed to unmarshal file authorization event")
+			return
+		}
+		if !bytes.Equal(fe.Applier, t.PublicKey[:]) || fe.Status == xdbchain.FileAuthUnapproved {
+			return
+		}
+		if err := t.getUnconfirmedTaskAndConfirm(); err != nil {
+			logger.WithError(err).Errorf("failed to confirm tasks after the file authorization application %s", fe.Status)
+		}
+	}
+}
+
+// executes checks whether local node is one of the executors
+func (t *TaskMonitor) executes(executors [][]byte) bool {
+	for _, executor := range executors {
+		if bytes.Equal(executor, t.PublicKey[:]) {
+			return true
+		}
+	}
+	return false
+}
+
 // getUnconfirmedTaskAndConfirm query confirming tasks that need to be confirmed by the
 // executor node from chain, check whether the executor node has permission to use the sample file,
 // if not, publishes a file authorization application, otherwise confrims or rejects the task
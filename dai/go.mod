@@ -19,6 +19,7 @@ require (
 	github.com/sirupsen/logrus v1.8.1
 	github.com/spf13/cobra v1.1.3
 	github.com/spf13/viper v1.7.1
+	github.com/stretchr/testify v1.7.0
 	github.com/xuperchain/xuperchain v0.0.0-20210208123615-2d08ff11de3e
 	golang.org/x/net v0.0.0-20210917221730-978cfadd31cf
 	google.golang.org/genproto v0.0.0-20211208223120-3a66f561d7aa
@@ -28,3 +29,5 @@ require (
 )
 
 replace github.com/go-kit/kit => github.com/go-kit/kit v0.8.0
+
+replace github.com/PaddlePaddle/PaddleDTX/xdb => ../xdb
@@ -130,6 +130,8 @@ type ListTaskRequest struct {
 	TimeStart            int64    `protobuf:"varint,4,opt,name=timeStart,proto3" json:"timeStart,omitempty"`
 	TimeEnd              int64    `protobuf:"varint,5,opt,name=timeEnd,proto3" json:"timeEnd,omitempty"`
 	Limit                int64    `protobuf:"varint,6,opt,name=limit,proto3" json:"limit,omitempty"`
+	Keywords             string   `protobuf:"bytes,7,opt,name=keywords,proto3" json:"keywords,omitempty"`
+	Cursor               string   `protobuf:"bytes,8,opt,name=cursor,proto3" json:"cursor,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -202,6 +204,20 @@ func (m *ListTaskRequest) GetLimit() int64 {
 	return 0
 }
 
+func (m *ListTaskRequest) GetKeywords() string {
+	if m != nil {
+		return m.Keywords
+	}
+	return ""
+}
+
+func (m *ListTaskRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
 // DataForTask is a message received from Executor
 type DataForTask struct {
 	Owner                []byte   `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
@@ -429,6 +445,7 @@ func (m *FLTask) GetEndTime() int64 {
 // FLTasks is list of FLTasks received from Executor
 type FLTasks struct {
 	FLTasks              []*FLTask `protobuf:"bytes,1,rep,name=fLTasks,proto3" json:"fLTasks,omitempty"`
+	Next                 string    `protobuf:"bytes,2,opt,name=next,proto3" json:"next,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
 	XXX_unrecognized     []byte    `json:"-"`
 	XXX_sizecache        int32     `json:"-"`
@@ -466,6 +483,13 @@ func (m *FLTasks) GetFLTasks() []*FLTask {
 	return nil
 }
 
+func (m *FLTasks) GetNext() string {
+	if m != nil {
+		return m.Next
+	}
+	return ""
+}
+
 // GetTaskRequest is message sent to Executor server to get a task
 type GetTaskRequest struct {
 	TaskID               string   `protobuf:"bytes,1,opt,name=taskID,proto3" json:"taskID,omitempty"`
@@ -568,54 +592,55 @@ func init() {
 func init() { proto.RegisterFile("task/task.proto", fileDescriptor_8e8f2b86464a95fe) }
 
 var fileDescriptor_8e8f2b86464a95fe = []byte{
-	// 742 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0xdf, 0x6e, 0xd3, 0x3e,
-	0x14, 0x56, 0xba, 0xae, 0x7f, 0x4e, 0xbb, 0xdf, 0xf6, 0x33, 0x1b, 0x44, 0x65, 0x9a, 0xaa, 0x5c,
-	0x4c, 0x15, 0x12, 0x0b, 0xeb, 0xee, 0xb8, 0x63, 0x94, 0x4d, 0x83, 0x81, 0xaa, 0xac, 0x17, 0x08,
-	0x6e, 0x70, 0x9b, 0xb3, 0xcc, 0x2c, 0x89, 0x83, 0xed, 0x00, 0xbd, 0xe5, 0x15, 0x78, 0x0a, 0xde,
-	0x80, 0xf7, 0xe0, 0x15, 0xb8, 0xe5, 0x19, 0x40, 0xb6, 0x93, 0x36, 0x9d, 0x86, 0xc4, 0x4d, 0x9b,
-	0xef, 0x1c, 0xfb, 0xf3, 0x77, 0xce, 0xf9, 0x6c, 0xd8, 0x54, 0x54, 0x5e, 0xfb, 0xfa, 0xe7, 0x20,
-	0x13, 0x5c, 0x71, 0x52, 0xd7, 0xdf, 0xbd, 0x3b, 0x33, 0x9e, 0x24, 0x3c, 0xf5, 0xed, 0x9f, 0x4d,
-	0xf5, 0x76, 0x23, 0xce, 0xa3, 0x18, 0x7d, 0x9a, 0x31, 0x9f, 0xa6, 0x29, 0x57, 0x54, 0x31, 0x9e,
-	0x4a, 0x9b, 0xf5, 0xde, 0x42, 0x67, 0x42, 0xe5, 0x75, 0x80, 0x1f, 0x72, 0x94, 0x8a, 0xdc, 0x85,
-	0x46, 0x96, 0x4f, 0x5f, 0xe0, 0xdc, 0x75, 0xfa, 0xce, 0xa0, 0x1b, 0x14, 0x48, 0xc7, 0xf5, 0x09,
-	0x67, 0x23, 0xb7, 0xd6, 0x77, 0x06, 0xed, 0xa0, 0x40, 0x64, 0x17, 0xda, 0x92, 0x45, 0x29, 0x55,
-	0xb9, 0x40, 0xb7, 0x6e, 0xb6, 0x2c, 0x03, 0xde, 0x3e, 0x74, 0x2d, 0xb9, 0xcc, 0x78, 0x2a, 0xf1,
-	0x6f, 0x2c, 0xde, 0x37, 0x07, 0x36, 0xcf, 0x99, 0x54, 0xff, 0xa2, 0xc4, 0x85, 0x26, 0x8e, 0x6d,
-	0xa2, 0x66, 0x12, 0x25, 0xd4, 0x3b, 0xa4, 0xa2, 0x2a, 0x97, 0xee, 0x9a, 0x65, 0xb7, 0x48, 0x6b,
-	0x54, 0x2c, 0xc1, 0x0b, 0x45, 0x85, 0x32, 0x1a, 0xd7, 0x82, 0x65, 0x40, 0xf3, 0x69, 0xf0, 0x2c,
-	0x0d, 0xdd, 0x75, 0x93, 0x2b, 0x21, 0xd9, 0x86, 0xf5, 0x98, 0x25, 0x4c, 0xb9, 0x0d, 0x13, 0xb7,
-	0xc0, 0xfb, 0xe5, 0x40, 0x67, 0x44, 0x15, 0x3d, 0xe1, 0x42, 0xcb, 0xd5, 0xab, 0xf8, 0xa7, 0x14,
-	0x45, 0x21, 0xd3, 0x02, 0xd2, 0x83, 0x16, 0x7e, 0xc6, 0x59, 0xae, 0xb8, 0x28, 0x64, 0x2e, 0xb0,
-	0xd6, 0x19, 0x52, 0x45, 0xcf, 0x46, 0xa5, 0x4e, 0x8b, 0xf4, 0x9e, 0x4c, 0xb2, 0x73, 0x3a, 0xc5,
-	0xd8, 0xc8, 0x6c, 0x07, 0x0b, 0x4c, 0xfa, 0xd0, 0x99, 0xf1, 0xf4, 0x92, 0x89, 0x04, 0xc3, 0x27,
-	0xaa, 0x50, 0x5a, 0x0d, 0x91, 0x3d, 0x00, 0x81, 0xef, 0x71, 0xa6, 0xcc, 0x02, 0x2b, 0xb9, 0x12,
-	0xd1, 0x75, 0xd2, 0x30, 0x14, 0x28, 0xa5, 0xdb, 0x34, 0xe4, 0x25, 0xd4, 0xfd, 0x61, 0x72, 0x42,
-	0xa3, 0xb1, 0xee, 0x4f, 0xab, 0xef, 0x0c, 0x5a, 0xc1, 0x32, 0xe0, 0xfd, 0xae, 0x41, 0xe3, 0xe4,
-	0xdc, 0x94, 0xba, 0x1c, 0x9f, 0xb3, 0x62, 0x02, 0x02, 0xf5, 0x94, 0x26, 0x58, 0x0c, 0xd5, 0x7c,
-	0x6b, 0xc1, 0x21, 0xca, 0x99, 0x60, 0x99, 0x76, 0x5b, 0x51, 0x69, 0x35, 0xa4, 0x8f, 0x15, 0x76,
-	0xd6, 0x28, 0x4a, 0xeb, 0x2c, 0x02, 0xe4, 0x21, 0xb4, 0x74, 0x5b, 0x2e, 0x50, 0x49, 0x77, 0xbd,
-	0xbf, 0x36, 0xe8, 0x0c, 0xff, 0x3f, 0x30, 0x7e, 0xaf, 0xf4, 0x3e, 0x58, 0x2c, 0x21, 0x8f, 0xa0,
-	0x4d, 0xe3, 0x88, 0x8f, 0xa9, 0xa0, 0x89, 0x29, 0xbe, 0x33, 0x24, 0x07, 0xc5, 0x35, 0xd0, 0x4b,
-	0x4d, 0x42, 0x06, 0xcb, 0x45, 0x15, 0xb7, 0x34, 0x57, 0xdc, 0xb2, 0x07, 0x80, 0x42, 0xbc, 0x44,
-	0x29, 0x69, 0x84, 0xa6, 0x1d, 0xed, 0xa0, 0x12, 0xd1, 0xfb, 0x04, 0xca, 0x3c, 0x56, 0x6e, 0xdb,
-	0xee, 0xb3, 0x48, 0x17, 0x9c, 0xe5, 0xd3, 0x98, 0xc9, 0xab, 0x09, 0x4b, 0xd0, 0x05, 0x3b, 0xa1,
-	0x4a, 0xc8, 0xdc, 0x15, 0x6d, 0x39, 0x93, 0xef, 0x58, 0x1f, 0x2e, 0x02, 0xc6, 0xd7, 0x69, 0x68,
-	0x72, 0x5d, 0xeb, 0xc3, 0x02, 0x7a, 0x87, 0xd0, 0xb4, 0x03, 0x90, 0x64, 0x1f, 0x9a, 0x97, 0xf6,
-	0xd3, 0x75, 0x4c, 0x53, 0xba, 0xb6, 0x29, 0x36, 0x1f, 0x94, 0x49, 0x6f, 0x00, 0xff, 0x9d, 0xe2,
-	0xcd, 0xeb, 0x74, 0xdb, 0xec, 0xbc, 0xa7, 0xb0, 0x39, 0x16, 0x18, 0xb2, 0x99, 0xba, 0xe5, 0x96,
-	0xae, 0x8e, 0xd9, 0x85, 0x66, 0x46, 0xe7, 0x31, 0xa7, 0x61, 0x79, 0xf3, 0x0a, 0x38, 0xfc, 0x5e,
-	0x83, 0xba, 0x71, 0xc8, 0x73, 0x68, 0x95, 0xf7, 0x98, 0xec, 0x58, 0x69, 0x37, 0xee, 0x75, 0x6f,
-	0xa3, 0xaa, 0x58, 0x7a, 0xee, 0x97, 0x1f, 0x3f, 0xbf, 0xd6, 0x88, 0xb7, 0xe1, 0x7f, 0x3c, 0x34,
-	0x0f, 0x9a, 0x1f, 0x33, 0xa9, 0x1e, 0x3b, 0x0f, 0xc8, 0x2b, 0xe8, 0x14, 0x35, 0x1c, 0xcf, 0xcf,
-	0x42, 0xb2, 0x6d, 0xf7, 0xad, 0x96, 0xd5, 0x5b, 0xa9, 0xdf, 0xbb, 0x6f, 0xc8, 0x76, 0xbc, 0xad,
-	0x05, 0x59, 0x84, 0x6a, 0x3a, 0x67, 0xa1, 0xe6, 0x7b, 0x07, 0x5b, 0xa7, 0xa8, 0x96, 0xc5, 0xea,
-	0xa1, 0x15, 0x9e, 0xaa, 0x32, 0x16, 0xb2, 0x6f, 0x34, 0xc5, 0xf3, 0x0c, 0xf5, 0xae, 0x77, 0x6f,
-	0x41, 0x9d, 0xd9, 0x15, 0x02, 0xa5, 0x3e, 0x45, 0x9f, 0x30, 0x84, 0xb6, 0x79, 0x53, 0x4c, 0xf9,
-	0xb7, 0x50, 0x93, 0x6a, 0xc8, 0xf2, 0x1e, 0x1f, 0xbd, 0x39, 0x8c, 0x98, 0xba, 0xca, 0xa7, 0xda,
-	0xad, 0xfe, 0x98, 0x86, 0x61, 0x8c, 0xf6, 0xb7, 0x00, 0xa3, 0xc9, 0x6b, 0x3f, 0xa4, 0xcc, 0x37,
-	0xcf, 0xb5, 0x34, 0x47, 0x4f, 0x1b, 0x06, 0x1c, 0xfd, 0x09, 0x00, 0x00, 0xff, 0xff, 0xb8, 0xf7,
-	0x49, 0x52, 0x07, 0x06, 0x00, 0x00,
+	// 768 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0xcd, 0x6e, 0xdb, 0x46,
+	0x10, 0x06, 0x65, 0x59, 0x3f, 0x2b, 0xb9, 0x76, 0xb7, 0x76, 0x4b, 0xa8, 0x86, 0x21, 0xf0, 0x60,
+	0x08, 0x05, 0x6a, 0xd6, 0xf2, 0xad, 0xb7, 0xba, 0xb2, 0x0d, 0xb7, 0x6e, 0x21, 0xd0, 0x3a, 0x04,
+	0xc9, 0x25, 0x2b, 0x71, 0x4c, 0x6f, 0x4c, 0x72, 0x99, 0xdd, 0x65, 0x6c, 0x5d, 0xf3, 0x0a, 0x79,
+	0x99, 0xbc, 0x47, 0xce, 0xb9, 0xe5, 0x9a, 0x67, 0x48, 0x30, 0xbb, 0x24, 0x45, 0x19, 0x0e, 0x90,
+	0x8b, 0xc4, 0x6f, 0x66, 0xf7, 0xdb, 0xf9, 0x66, 0xbe, 0x5d, 0xb2, 0xad, 0x99, 0xba, 0xf3, 0xf1,
+	0xe7, 0x28, 0x93, 0x42, 0x0b, 0xda, 0xc4, 0xef, 0xc1, 0x4f, 0x0b, 0x91, 0x24, 0x22, 0xf5, 0xed,
+	0x9f, 0x4d, 0x0d, 0xf6, 0x23, 0x21, 0xa2, 0x18, 0x7c, 0x96, 0x71, 0x9f, 0xa5, 0xa9, 0xd0, 0x4c,
+	0x73, 0x91, 0x2a, 0x9b, 0xf5, 0x5e, 0x90, 0xde, 0x8c, 0xa9, 0xbb, 0x00, 0x5e, 0xe7, 0xa0, 0x34,
+	0xfd, 0x99, 0xb4, 0xb2, 0x7c, 0xfe, 0x2f, 0x2c, 0x5d, 0x67, 0xe8, 0x8c, 0xfa, 0x41, 0x81, 0x30,
+	0x8e, 0x27, 0x5c, 0x4e, 0xdc, 0xc6, 0xd0, 0x19, 0x75, 0x83, 0x02, 0xd1, 0x7d, 0xd2, 0x55, 0x3c,
+	0x4a, 0x99, 0xce, 0x25, 0xb8, 0x4d, 0xb3, 0x65, 0x15, 0xf0, 0x0e, 0x49, 0xdf, 0x92, 0xab, 0x4c,
+	0xa4, 0x0a, 0xbe, 0xc5, 0xe2, 0x7d, 0x74, 0xc8, 0xf6, 0x15, 0x57, 0xfa, 0x7b, 0x2a, 0x71, 0x49,
+	0x1b, 0xa6, 0x36, 0xd1, 0x30, 0x89, 0x12, 0xe2, 0x0e, 0xa5, 0x99, 0xce, 0x95, 0xbb, 0x61, 0xd9,
+	0x2d, 0xc2, 0x1a, 0x35, 0x4f, 0xe0, 0x5a, 0x33, 0xa9, 0x4d, 0x8d, 0x1b, 0xc1, 0x2a, 0x80, 0x7c,
+	0x08, 0xce, 0xd2, 0xd0, 0xdd, 0x34, 0xb9, 0x12, 0xd2, 0x5d, 0xb2, 0x19, 0xf3, 0x84, 0x6b, 0xb7,
+	0x65, 0xe2, 0x16, 0xd0, 0x01, 0xe9, 0xdc, 0xc1, 0xf2, 0x5e, 0xc8, 0x50, 0xb9, 0x6d, 0x73, 0x4e,
+	0x85, 0xb1, 0x82, 0x45, 0x2e, 0x95, 0x90, 0x6e, 0xc7, 0x56, 0x60, 0x91, 0xf7, 0xd9, 0x21, 0xbd,
+	0x09, 0xd3, 0xec, 0x5c, 0x48, 0x94, 0x88, 0xcc, 0xe2, 0x3e, 0x05, 0x59, 0x48, 0xb3, 0x00, 0x99,
+	0xe1, 0x01, 0x16, 0xb9, 0x16, 0xb2, 0x90, 0x56, 0x61, 0x64, 0x0e, 0x99, 0x66, 0x97, 0x93, 0x52,
+	0x9b, 0x45, 0xb8, 0x27, 0x53, 0xfc, 0x8a, 0xcd, 0x21, 0x36, 0xd2, 0xba, 0x41, 0x85, 0xe9, 0x90,
+	0xf4, 0x16, 0x22, 0xbd, 0xe1, 0x32, 0x81, 0xf0, 0x2f, 0x5d, 0xa8, 0xab, 0x87, 0xe8, 0x01, 0x21,
+	0x12, 0x5e, 0xc1, 0x42, 0x9b, 0x05, 0x56, 0x66, 0x2d, 0x82, 0xbd, 0x61, 0x61, 0x28, 0x41, 0x95,
+	0x52, 0x4b, 0x88, 0x3d, 0xe5, 0x6a, 0xc6, 0xa2, 0x29, 0xf6, 0x14, 0xc5, 0x76, 0x82, 0x55, 0xc0,
+	0xfb, 0xd2, 0x20, 0xad, 0xf3, 0x2b, 0x23, 0x75, 0x35, 0x72, 0x67, 0xcd, 0x38, 0x94, 0x34, 0x53,
+	0x96, 0x40, 0x61, 0x04, 0xf3, 0x8d, 0x05, 0x87, 0xa0, 0x16, 0x92, 0x67, 0xe8, 0xd0, 0x42, 0x69,
+	0x3d, 0x84, 0xc7, 0x4a, 0xeb, 0x0f, 0x90, 0xa5, 0xdd, 0xaa, 0x00, 0xfd, 0x9d, 0x74, 0xb0, 0x2d,
+	0xd7, 0xa0, 0x95, 0xbb, 0x39, 0xdc, 0x18, 0xf5, 0xc6, 0x3f, 0x1e, 0x99, 0x3b, 0x52, 0xeb, 0x7d,
+	0x50, 0x2d, 0xa1, 0x7f, 0x90, 0x2e, 0x8b, 0x23, 0x31, 0x65, 0x92, 0x25, 0x46, 0x7c, 0x6f, 0x4c,
+	0x8f, 0x8a, 0xab, 0x83, 0x4b, 0x4d, 0x42, 0x05, 0xab, 0x45, 0x35, 0x87, 0xb5, 0xd7, 0x1c, 0x76,
+	0x40, 0x08, 0x48, 0xf9, 0x1f, 0x28, 0xc5, 0x22, 0x28, 0x66, 0x5f, 0x8b, 0xe0, 0x3e, 0x09, 0x2a,
+	0x8f, 0xb5, 0xdb, 0xb5, 0xfb, 0x2c, 0x42, 0xc1, 0x59, 0x3e, 0x8f, 0xb9, 0xba, 0x9d, 0xf1, 0x04,
+	0x5c, 0x62, 0x27, 0x54, 0x0b, 0x99, 0xfb, 0x85, 0x36, 0x35, 0xf9, 0x9e, 0xf5, 0x6e, 0x15, 0x30,
+	0x77, 0x21, 0x0d, 0x4d, 0xae, 0x6f, 0xbd, 0x5b, 0x40, 0xef, 0x8c, 0xb4, 0xed, 0x00, 0x14, 0x3d,
+	0x24, 0xed, 0x1b, 0xfb, 0xe9, 0x3a, 0xa6, 0x29, 0x7d, 0xdb, 0x14, 0x9b, 0x0f, 0xca, 0xa4, 0x99,
+	0x08, 0x3c, 0xe8, 0x6a, 0x22, 0xf0, 0xa0, 0xbd, 0x11, 0xf9, 0xe1, 0x02, 0x1e, 0x5f, 0xcb, 0xa7,
+	0xe6, 0xe9, 0xfd, 0x4d, 0xb6, 0xa7, 0x12, 0x42, 0xbe, 0xd0, 0x4f, 0xdc, 0xf6, 0xf5, 0xd1, 0xbb,
+	0xa4, 0x9d, 0xb1, 0x65, 0x2c, 0x58, 0x58, 0xde, 0xe0, 0x02, 0x8e, 0xdf, 0x37, 0x48, 0xd3, 0xb8,
+	0xe6, 0x1f, 0xd2, 0x29, 0xdf, 0x03, 0xba, 0x67, 0xcb, 0x7d, 0xf4, 0x3e, 0x0c, 0xb6, 0xea, 0x2a,
+	0x94, 0xe7, 0xbe, 0xfd, 0xf0, 0xe9, 0x5d, 0x83, 0xfe, 0xe9, 0xfc, 0xe6, 0x6d, 0xf9, 0x6f, 0x8e,
+	0xcd, 0xdb, 0xe8, 0xc7, 0x5c, 0x69, 0xfa, 0x3f, 0xe9, 0x15, 0x1a, 0x4e, 0x97, 0x97, 0x21, 0xdd,
+	0xb5, 0xfb, 0xd6, 0x65, 0x0d, 0xd6, 0x7a, 0xe2, 0xfd, 0x6a, 0xc8, 0xf6, 0x90, 0x6c, 0xa7, 0x22,
+	0x8b, 0x40, 0xcf, 0x97, 0x3c, 0xa4, 0x2f, 0xc9, 0xce, 0x05, 0xe8, 0x95, 0x58, 0x1c, 0x64, 0xe1,
+	0xb3, 0x3a, 0x63, 0x51, 0xf6, 0xa3, 0xa6, 0x78, 0x9e, 0xa1, 0xde, 0x47, 0xea, 0x5f, 0x2a, 0xea,
+	0xcc, 0x2e, 0x92, 0xa0, 0xf0, 0x14, 0x3a, 0x26, 0x5d, 0xf3, 0x36, 0x19, 0xf9, 0x4f, 0x50, 0xd3,
+	0x7a, 0xc8, 0xf2, 0x9e, 0x9e, 0x3c, 0x3f, 0x8e, 0xb8, 0xbe, 0xcd, 0xe7, 0xe8, 0x60, 0x7f, 0xca,
+	0xc2, 0x30, 0x06, 0xfb, 0x5b, 0x80, 0xc9, 0xec, 0x99, 0x1f, 0x32, 0xee, 0x9b, 0x67, 0x5f, 0x99,
+	0x73, 0xe7, 0x2d, 0x03, 0x4e, 0xbe, 0x0e, 0x00, 0x1b, 0xa9, 0xaf, 0x53, 0x4f, 0x06, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
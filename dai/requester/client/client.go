@@ -30,6 +30,7 @@ import (
 
 	"github.com/PaddlePaddle/PaddleDTX/dai/blockchain"
 	fabricblockchain "github.com/PaddlePaddle/PaddleDTX/dai/blockchain/fabric"
+	localblockchain "github.com/PaddlePaddle/PaddleDTX/dai/blockchain/local"
 	xchainblockchain "github.com/PaddlePaddle/PaddleDTX/dai/blockchain/xchain"
 	"github.com/PaddlePaddle/PaddleDTX/dai/config"
 	"github.com/PaddlePaddle/PaddleDTX/dai/crypto/vl/common/csv"
@@ -69,6 +70,8 @@ func newChainClient(conf *config.ExecutorBlockchainConf) (b Blockchain, err erro
 		b, err = xchainblockchain.New(conf.Xchain)
 	case "fabric":
 		b, err = fabricblockchain.New(conf.Fabric)
+	case "local":
+		b, err = localblockchain.New(conf.Local)
 	default:
 		return b, errorx.New(errorx.ErrCodeConfig, "invalid blockchain type: %s", conf.Type)
 	}
@@ -266,13 +269,21 @@ func (c *Client) GetTaskById(id string) (t blockchain.FLTask, err error) {
 // limit is the maximum number of tasks to response
 func (c *Client) ListTask(rPubkeyStr, ePubkeyStr, status string, start, end,
 	limit int64) (tasks blockchain.FLTasks, err error) {
+	tasks, _, err = c.ListTaskPage(rPubkeyStr, ePubkeyStr, status, start, end, limit, "")
+	return
+}
+
+// ListTaskPage lists a page of tasks as ListTask does, continued from the cursor returned by the previous page,
+// next is the cursor of the next page, which is empty if no tasks are left
+func (c *Client) ListTaskPage(rPubkeyStr, ePubkeyStr, status string, start, end,
+	limit int64, cursor string) (tasks blockchain.FLTasks, next string, err error) {
 	rPubkey, err := hex.DecodeString(rPubkeyStr)
 	if err != nil {
-		return tasks, errorx.Wrap(err, "failed to decode requester public key")
+		return tasks, next, errorx.Wrap(err, "failed to decode requester public key")
 	}
 	ePubkey, err := hex.DecodeString(ePubkeyStr)
 	if err != nil {
-		return tasks, errorx.Wrap(err, "failed to decode executor public key")
+		return tasks, next, errorx.Wrap(err, "failed to decode executor public key")
 	}
 	tasks, err = c.chainClient.ListTask(&blockchain.ListFLTaskOptions{
 		PubKey:     rPubkey[:],
@@ -281,8 +292,12 @@ func (c *Client) ListTask(rPubkeyStr, ePubkeyStr, status string, start, end,
 		TimeEnd:    end,
 		Status:     status,
 		Limit:      limit,
+		Cursor:     cursor,
 	})
-	return
+	if err != nil {
+		return tasks, next, err
+	}
+	return tasks, blockchain.NextTaskCursor(tasks, limit), nil
 }
 
 // StartTask starts task by taskID, only task with 'Ready' status can be started
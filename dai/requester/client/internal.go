@@ -29,7 +29,7 @@ func checkConfig(configPath string) error {
 	cliConf := config.GetCliConf()
 
 	blockchainType := cliConf.Type
-	if blockchainType != "xchain" && blockchainType != "fabric" {
+	if blockchainType != "xchain" && blockchainType != "fabric" && blockchainType != "local" {
 		return errorx.New(errorx.ErrCodeConfig, "invalid blockchain type: %s", blockchainType)
 	}
 	return nil
@@ -32,6 +32,8 @@ var (
 	end     string
 	status  string
 	limit   int64
+	cursor  string
+	all     bool
 )
 
 // listTasksCmd lists tasks from blockchain
@@ -72,18 +74,29 @@ var listTasksCmd = &cobra.Command{
 			rPubkey = strings.TrimSpace(string(pubkeyBytes))
 		}
 
-		tasks, err := client.ListTask(rPubkey, ePubkey, status, startTime, endTime.UnixNano(), limit)
-		if err != nil {
-			fmt.Printf("ListTask failed：%v\n", err)
-			return
-		}
-		for _, task := range tasks {
-			ptime := time.Unix(0, task.PublishTime).Format(timeTemplate)
-			fmt.Printf("TaskID: %s\nTaskType: %s\nTaskName: %s\nDescription: %s\nTaskStatus: %s\nPublishTime: %s\n\n",
-				task.TaskID, task.AlgoParam.TaskType, task.Name, task.Description, task.Status, ptime)
+		num := 0
+		for {
+			tasks, next, err := client.ListTaskPage(rPubkey, ePubkey, status, startTime, endTime.UnixNano(), limit, cursor)
+			if err != nil {
+				fmt.Printf("ListTask failed：%v\n", err)
+				return
+			}
+			for _, task := range tasks {
+				ptime := time.Unix(0, task.PublishTime).Format(timeTemplate)
+				fmt.Printf("TaskID: %s\nTaskType: %s\nTaskName: %s\nDescription: %s\nTaskStatus: %s\nPublishTime: %s\n\n",
+					task.TaskID, task.AlgoParam.TaskType, task.Name, task.Description, task.Status, ptime)
+			}
+			num += len(tasks)
+			// only the first page is listed unless all
+			if cursor = next; !all || cursor == "" {
+				break
+			}
 		}
 
-		fmt.Printf("taskNum : %d\n\n", len(tasks))
+		fmt.Printf("taskNum : %d\n\n", num)
+		if cursor != "" {
+			fmt.Printf("more tasks listed with --cursor %s\n", cursor)
+		}
 	},
 }
 
@@ -96,6 +109,8 @@ func init() {
 	listTasksCmd.Flags().StringVarP(&start, "st", "s", "", "start of time range during which tasks were published, example '2021-06-10 12:00:00'")
 	listTasksCmd.Flags().StringVarP(&end, "et", "e", time.Unix(0, time.Now().UnixNano()).Format(timeTemplate), "end of time range during which tasks were published, example '2021-06-10 12:00:00'")
 	listTasksCmd.Flags().Int64VarP(&limit, "limit", "l", blockchain.TaskListMaxNum, "maximum of tasks can be queried")
+	listTasksCmd.Flags().StringVar(&cursor, "cursor", "", "cursor printed by the previous list to get the next page")
+	listTasksCmd.Flags().BoolVar(&all, "all", false, "list all pages of tasks")
 	listTasksCmd.Flags().StringVar(&status, "status", "", "status of task, such as Confirming, Ready, ToProcess, Processing, Finished, Failed, default for all types of status")
 
 }
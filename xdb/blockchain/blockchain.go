@@ -29,6 +29,7 @@ const (
 	FileAuthUnapproved = "Unapproved" // the applier published file's authorization application and the authorizer has not yet approved
 	FileAuthApproved   = "Approved"   // the authorizer approved applier's authorization application
 	FileAuthRejected   = "Rejected"   // the authorizer rejected applier's authorization application
+	FileAuthInvalid    = "Invalid"    // the file was deleted before the authorizer approved the application
 )
 
 // define variables about node health
@@ -75,12 +76,41 @@ type PublicSliceMeta struct {
 
 	// for pairing based challenge
 	SliceIdx int `json:"sliceIdx"` // slice index stored on this node, like 1,2,3...
+
+	// for erasure coded files
+	Stripe int `json:"stripe,omitempty"` // index of the stripe the shard belongs to
+
+	// for deduplicated slices, ID of the file which stored the slice, empty if the slice is stored by the file itself
+	SourceFileID string `json:"sourceFileID,omitempty"`
+
+	// ID of the master key the replica is encrypted with, empty for the legacy key
+	KeyID string `json:"keyID,omitempty"`
+}
+
+// Referenced returns true if the slice is stored by another file and referenced by deduplication,
+// such slices are challenged and migrated by the source file
+func (s PublicSliceMeta) Referenced() bool {
+	return s.SourceFileID != ""
+}
+
+// KeyFileID returns ID of the file whose keys the slice is encrypted with
+func (s PublicSliceMeta) KeyFileID(fileID string) string {
+	if s.Referenced() {
+		return s.SourceFileID
+	}
+	return fileID
 }
 
 // PrivateSliceMeta private, description of the order of original slices
 type PrivateSliceMeta struct {
-	SliceID   string `json:"sliceID"`   // slice ID
-	PlainHash []byte `json:"plainHash"` // hash of plain text
+	SliceID   string `json:"sliceID"`          // slice ID
+	PlainHash []byte `json:"plainHash"`        // hash of plain text
+	Length    uint64 `json:"length,omitempty"` // length of plain text, not recorded by early versions
+	Key       []byte `json:"key,omitempty"`    // key sealing the plain text, for files in convergent format
+
+	// for erasure coded files
+	Stripe int  `json:"stripe,omitempty"` // index of the stripe the shard belongs to
+	Parity bool `json:"parity,omitempty"` // whether the shard is a parity shard
 }
 
 type FileStructure []PrivateSliceMeta
@@ -112,10 +142,56 @@ type File struct {
 	RandU     []byte `json:"randU"`
 	RandV     []byte `json:"randV"`
 
+	// for erasure coded files, copied from namespace when the file is published
+	DataShards   int `json:"dataShards,omitempty"`   // number of data shards per stripe
+	ParityShards int `json:"parityShards,omitempty"` // number of parity shards per stripe
+
+	// format of file ciphertext, empty for the whole file sealed by AES-GCM at once,
+	// slices of files in convergent format may be shared with other files of the same namespace
+	CipherFormat string `json:"cipherFormat,omitempty"`
+
+	// algorithm compressing file plaintext before encryption, empty for files not compressed.
+	// Length is the length of the original plaintext, and CompressedLength is the length after compression
+	Compression      string `json:"compression,omitempty"`
+	CompressedLength uint64 `json:"compressedLength,omitempty"`
+
+	// for files in chunked format uploaded in parts, PartSalts are salts of the parts of PartSize bytes
+	// of plaintext, which the keys to seal chunks of the parts are derived from
+	PartSize  uint64   `json:"partSize,omitempty"`
+	PartSalts [][]byte `json:"partSalts,omitempty"`
+
+	// files published with the same name in a namespace are versions of it, the latest one is got by name.
+	// Version starts from 1 and is assigned on chain, PrevID is the ID of the previous version.
+	// Files published before versioning have no version, and are taken as version 1
+	Version int64  `json:"version,omitempty"`
+	PrevID  string `json:"prevID,omitempty"`
+
+	// ID of the master key the file content and Structure are encrypted with, empty for the legacy key.
+	// Replicas of slices are encrypted again by their own keys, which are rotated by re-encryption.
+	// Once the file is rekeyed, the key of content and Structure is sealed by the master key of KeyID as WrappedKey
+	KeyID      string `json:"keyID,omitempty"`
+	WrappedKey []byte `json:"wrappedKey,omitempty"`
+
 	// extension
 	Ext []byte `json:"ext"`
 }
 
+// ErasureCoded returns true if the file slices are stored as erasure coded shards rather than replicas
+func (f File) ErasureCoded() bool {
+	return f.ParityShards > 0
+}
+
+// OwnSlices returns the slices stored by the file itself, excluding the ones referenced from other files
+func (f File) OwnSlices() []PublicSliceMeta {
+	slices := make([]PublicSliceMeta, 0, len(f.Slices))
+	for _, s := range f.Slices {
+		if !s.Referenced() {
+			slices = append(slices, s)
+		}
+	}
+	return slices
+}
+
 type FileH struct {
 	File   File   `json:"file"`
 	Health string `json:"health"`
@@ -159,6 +235,18 @@ type ListFileOptions struct {
 	TimeEnd     int64 `json:"timeEnd"`
 	CurrentTime int64 `json:"currentTime"`
 	Limit       int64 `json:"limit"` // file number limit
+
+	Cursor string `json:"cursor"` // continue the list after the last file of the previous page, see Cursor
+}
+
+// ListFileVersionsOptions lists versions of the file with the name, from the latest to the earliest
+type ListFileVersionsOptions struct {
+	Owner     []byte `json:"owner"`     // file owner
+	Namespace string `json:"namespace"` // file namespace
+	Name      string `json:"name"`      // file name
+
+	CurrentTime int64 `json:"currentTime"`
+	Limit       int64 `json:"limit"` // version number limit
 }
 
 type ListChallengeOptions struct {
@@ -170,6 +258,8 @@ type ListChallengeOptions struct {
 	TimeStart int64 `json:"timeStart"` // challenge time period
 	TimeEnd   int64 `json:"timeEnd"`
 	Limit     int64 `json:"limit"` // challenge limit
+
+	Cursor string `json:"cursor"` // continue the list after the last challenge of the previous page, see Cursor
 }
 
 // ChallengeRequestOptions used for dataOwner nodes to add challenge request on chain
@@ -223,6 +313,20 @@ type Node struct {
 	Online   bool   `json:"online"`   // whether node is online or offline
 	RegTime  int64  `json:"regTime"`  // node register time
 	UpdateAt int64  `json:"updateAt"` // node recent update time
+
+	// whether node is draining, no new slices are placed on a draining node, and its slices are migrated
+	// to other nodes by dataOwners, it's set offline once empty and stops draining when set online again
+	Draining bool `json:"draining,omitempty"`
+
+	// capacity of the node's storage reported by heartbeats in bytes, zero if unknown
+	Capacity  uint64 `json:"capacity,omitempty"`
+	FreeSpace uint64 `json:"freeSpace,omitempty"`
+
+	// labels registered with the node, describing the failure domains it lives in.
+	// Zone is unique in a region and Rack is unique in a zone, empty if unknown
+	Region string `json:"region,omitempty"`
+	Zone   string `json:"zone,omitempty"`
+	Rack   string `json:"rack,omitempty"`
 }
 
 type NodeH struct {
@@ -238,7 +342,7 @@ type AddNodeOptions struct {
 	Signature []byte `json:"signature"`
 }
 
-// NodeOperateOptions used to online or offline storage node
+// NodeOperateOptions used to online, offline or drain storage node
 type NodeOperateOptions struct {
 	NodeID    []byte `json:"nodeID"`
 	Nonce     int64  `json:"nonce"`
@@ -250,6 +354,8 @@ type NodeHeartBeatOptions struct {
 	NodeID        []byte `json:"nodeID"`
 	CurrentTime   int64  `json:"currentTime"`
 	BeginningTime int64  `json:"beginningTime"`
+	Capacity      uint64 `json:"capacity,omitempty"`  // total bytes of the node's storage, zero if unknown
+	FreeSpace     uint64 `json:"freeSpace,omitempty"` // free bytes of the node's storage
 	Signature     []byte `json:"signature"`
 }
 
@@ -261,13 +367,36 @@ type UpdateExptimeOptions struct {
 	Signature     []byte `json:"signature"`
 }
 
+// DeleteFileOptions used by file owner to delete a file before it expires
+type DeleteFileOptions struct {
+	FileID      string `json:"fileID"`
+	CurrentTime int64  `json:"currentTime"`
+	Signature   []byte `json:"signature"`
+}
+
+// FileTombstone is left on chain when a file is deleted by its owner,
+// slices of the file are removed by storage nodes once they observe the deletion
+type FileTombstone struct {
+	FileID     string `json:"fileID"`
+	Owner      []byte `json:"owner"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	DeleteTime int64  `json:"deleteTime"`
+}
+
 // UpdateFilePSMOptions used to update the slice public info on chain
-// when the dataOwner migrates slice from bad storage node to good storage node
+// when the dataOwner migrates slice from bad storage node to good storage node, or re-encrypts slices
 type UpdateFilePSMOptions struct {
-	FileID    string            `json:"fileID"`
-	Owner     []byte            `json:"owner"`
-	Slices    []PublicSliceMeta `json:"slices"`
-	Signature []byte            `json:"signature"`
+	FileID string            `json:"fileID"`
+	Owner  []byte            `json:"owner"`
+	Slices []PublicSliceMeta `json:"slices"`
+
+	// for files rekeyed, the key of file content and structure sealed by the master key of KeyID,
+	// the key of the file is not updated if WrappedKey is empty
+	KeyID      string `json:"keyID,omitempty"`
+	WrappedKey []byte `json:"wrappedKey,omitempty"`
+
+	Signature []byte `json:"signature"`
 }
 
 // UpdateNsReplicaOptions used to update the replica on the blockchain
@@ -279,6 +408,16 @@ type UpdateNsReplicaOptions struct {
 	Signature   []byte `json:"signature"`
 }
 
+// UpdateNsLifecycleOptions used to update the lifecycle policy of namespace on the blockchain,
+// the policy is removed if Lifecycle is nil
+type UpdateNsLifecycleOptions struct {
+	Owner       []byte           `json:"owner"`
+	Name        string           `json:"name"`
+	Lifecycle   *LifecyclePolicy `json:"lifecycle"`
+	CurrentTime int64            `json:"currentTime"`
+	Signature   []byte           `json:"signature"`
+}
+
 // SliceMigrateOptions used to record migrate info for storage node
 type SliceMigrateOptions struct {
 	NodeID      []byte `json:"nodeID"`
@@ -313,6 +452,75 @@ type Namespace struct {
 	FileTotalNum int64  `json:"fileTotalNum"`
 	CreateTime   int64  `json:"createTime"`
 	UpdateTime   int64  `json:"updateTime"`
+
+	// Reed-Solomon redundancy mode, each stripe of DataShards slices is stored with ParityShards
+	// parity shards on distinct nodes instead of Replica full copies
+	DataShards   int `json:"dataShards,omitempty"`
+	ParityShards int `json:"parityShards,omitempty"`
+
+	// algorithm compressing files written into the namespace, files are not compressed if empty
+	Compression string `json:"compression,omitempty"`
+
+	// placement policy of replicas, or shards of a stripe for erasure coded namespace, nil if not restricted
+	Placement *PlacementPolicy `json:"placement,omitempty"`
+
+	// lifecycle policy of files under the namespace, nil if files are only expired by their expire time
+	Lifecycle *LifecyclePolicy `json:"lifecycle,omitempty"`
+
+	// ID of the master key current when the namespace is created, empty for the legacy key.
+	// Slices of files in convergent format are keyed by a secret derived from it, so that they are
+	// deduplicated across key rotation
+	KeyID string `json:"keyID,omitempty"`
+}
+
+// ErasureCoded returns true if files under the namespace are erasure coded
+func (n Namespace) ErasureCoded() bool {
+	return n.ParityShards > 0
+}
+
+// LifecyclePolicy rules the expiry of files under a namespace, durations are in nanoseconds.
+// Files uploaded without expire time expire after DefaultTTL, files used by DAI tasks in progress
+// are renewed by DefaultTTL before they expire if AutoRenew, and expired files are deleted
+// once expired for GracePeriod, which never exceeds FileRetainPeriod, if it's positive
+type LifecyclePolicy struct {
+	DefaultTTL  int64 `json:"defaultTTL,omitempty"`
+	AutoRenew   bool  `json:"autoRenew,omitempty"`
+	GracePeriod int64 `json:"gracePeriod,omitempty"`
+}
+
+// Valid returns true if durations of the policy are valid, files are renewed by DefaultTTL if AutoRenew
+func (p LifecyclePolicy) Valid() bool {
+	if p.DefaultTTL < 0 || p.GracePeriod < 0 || p.GracePeriod > FileRetainPeriod.Nanoseconds() {
+		return false
+	}
+	return !p.AutoRenew || p.DefaultTTL > 0
+}
+
+// PlacementPolicy restricts storage nodes the replicas of a slice are placed on
+type PlacementPolicy struct {
+	MinZones int      `json:"minZones,omitempty"` // replicas are spread across at least MinZones zones
+	Regions  []string `json:"regions,omitempty"`  // replicas are only placed in these regions, any region if empty
+}
+
+// Allows returns true if the node is in a region allowed by the policy, a nil policy allows any node
+func (p *PlacementPolicy) Allows(n Node) bool {
+	if p == nil || len(p.Regions) == 0 {
+		return true
+	}
+	for _, r := range p.Regions {
+		if r == n.Region {
+			return true
+		}
+	}
+	return false
+}
+
+// ZonesRequired returns the number of zones replicas must be spread across, zero if not restricted
+func (p *PlacementPolicy) ZonesRequired() int {
+	if p == nil {
+		return 0
+	}
+	return p.MinZones
 }
 
 // NamespaceH used to list file's information under namespace
@@ -400,4 +608,57 @@ type ListFileAuthOptions struct {
 	TimeStart  int64  `json:"timeStart"`
 	TimeEnd    int64  `json:"timeEnd"`
 	Limit      int64  `json:"limit"` // limit number of applications in list request
+
+	Cursor string `json:"cursor"` // continue the list after the last application of the previous page, see Cursor
+}
+
+// define statuses of DAI tasks in progress, files used by them are renewed if the namespace is auto-renewed
+const (
+	TaskConfirming = "Confirming" // waiting for Executors to confirm
+	TaskReady      = "Ready"      // has been confirmed by all Executors, and ready to start
+	TaskToProcess  = "ToProcess"  // has started, and waiting to be precessed
+	TaskProcessing = "Processing" // under process, that's during training or predicting
+)
+
+// Task is a DAI task published on the contract shared with XuperDB, only the fields XuperDB concerns
+type Task struct {
+	TaskID   string        `json:"taskID"`
+	Status   string        `json:"status"`
+	DataSets []TaskDataSet `json:"dataSets"`
+}
+
+// TaskDataSet is a file used by DAI task
+type TaskDataSet struct {
+	Owner  []byte `json:"owner"`
+	DataID string `json:"dataID"` // file ID
+}
+
+// InProgress returns true if the task is not finished, failed or rejected yet
+func (t Task) InProgress() bool {
+	switch t.Status {
+	case TaskConfirming, TaskReady, TaskToProcess, TaskProcessing:
+		return true
+	}
+	return false
+}
+
+// ListTaskOptions used to list DAI tasks an executor involved
+type ListTaskOptions struct {
+	ExecPubKey []byte `json:"exePubKey"`
+}
+
+// TaskFiles returns IDs of the files of owner used by the tasks in progress
+func TaskFiles(tasks []Task, owner []byte) map[string]struct{} {
+	files := make(map[string]struct{})
+	for _, t := range tasks {
+		if !t.InProgress() {
+			continue
+		}
+		for _, d := range t.DataSets {
+			if string(d.Owner) == string(owner) {
+				files[d.DataID] = struct{}{}
+			}
+		}
+	}
+	return files
 }
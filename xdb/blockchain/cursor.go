@@ -0,0 +1,86 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blockchain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// Cursor is the position of the last item of a page listed from contracts. It holds the fields of the item
+// which its list indexes are built from, so that contracts continue the list from the index next to the item,
+// even if the item has been removed. Cursors are encoded into opaque strings for clients
+type Cursor struct {
+	ID        string `json:"id,omitempty"`
+	Time      int64  `json:"t,omitempty"`  // publish time of files and tasks, challenge time, or creation time of applications
+	Namespace string `json:"ns,omitempty"` // file namespace
+	Name      string `json:"n,omitempty"`  // file name
+	Owner     []byte `json:"o,omitempty"`  // file owner, applier of file authorization applications, or task requester
+	Target    []byte `json:"tg,omitempty"` // storage node challenged, or authorizer of file authorization applications
+}
+
+// String encodes the cursor into an opaque string
+func (c Cursor) String() string {
+	s, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(s)
+}
+
+// ParseCursor decodes the cursor encoded by Cursor.String
+func ParseCursor(s string) (Cursor, error) {
+	var c Cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errorx.NewCode(err, errorx.ErrCodeParam, "invalid cursor")
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, errorx.NewCode(err, errorx.ErrCodeParam, "invalid cursor")
+	}
+	return c, nil
+}
+
+// NextFileCursor returns the cursor to list files after the page, empty if the page is not full
+// so that no files are left
+func NextFileCursor(files []File, limit int64) string {
+	return nextCursor(len(files), limit, func() Cursor {
+		f := files[len(files)-1]
+		return Cursor{ID: f.ID, Time: f.PublishTime, Namespace: f.Namespace, Name: f.Name, Owner: f.Owner}
+	})
+}
+
+// NextChallengeCursor returns the cursor to list challenges after the page, empty if the page is not full
+func NextChallengeCursor(challenges []Challenge, limit int64) string {
+	return nextCursor(len(challenges), limit, func() Cursor {
+		c := challenges[len(challenges)-1]
+		return Cursor{ID: c.ID, Time: c.ChallengeTime, Owner: c.FileOwner, Target: c.TargetNode}
+	})
+}
+
+// NextFileAuthCursor returns the cursor to list file authorization applications after the page,
+// empty if the page is not full
+func NextFileAuthCursor(fas FileAuthApplications, limit int64) string {
+	return nextCursor(len(fas), limit, func() Cursor {
+		fa := fas[len(fas)-1]
+		return Cursor{ID: fa.ID, Time: fa.CreateTime, Owner: fa.Applier, Target: fa.Authorizer}
+	})
+}
+
+// nextCursor returns the cursor of the last of n items listed, lists without limit are never paged
+func nextCursor(n int, limit int64, last func() Cursor) string {
+	if limit <= 0 || int64(n) < limit || n == 0 {
+		return ""
+	}
+	return last().String()
+}
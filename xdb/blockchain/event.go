@@ -0,0 +1,102 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blockchain
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// define names of events emitted by the contract, at most one event is emitted by a transaction
+const (
+	EventChallengeRequest = "ChallengeRequest"    // a challenge is requested, payload is ChallengeEvent
+	EventChallengeAnswer  = "ChallengeAnswer"     // a challenge is answered, proved or failed, payload is ChallengeEvent
+	EventFileAuth         = "FileAuthApplication" // an authorization application is published, approved or rejected, payload is FileAuthEvent
+)
+
+// watchRetryInterval is the interval to subscribe to events again once the subscription fails or breaks
+const watchRetryInterval = time.Minute
+
+var logger = logrus.WithField("module", "blockchain")
+
+// Event is an event emitted by the contract, payload is json encoded
+type Event struct {
+	Name    string
+	Payload []byte
+}
+
+// ChallengeEvent is the payload of challenge events
+type ChallengeEvent struct {
+	ID         string `json:"id"`
+	FileOwner  []byte `json:"fileOwner"`
+	TargetNode []byte `json:"targetNode"`
+	Status     string `json:"status"`
+}
+
+// FileAuthEvent is the payload of authorization application events
+type FileAuthEvent struct {
+	ID         string `json:"id"`
+	FileID     string `json:"fileID"`
+	Applier    []byte `json:"applier"`
+	Authorizer []byte `json:"authorizer"`
+	Status     string `json:"status"`
+}
+
+// ChainWatcher subscribes to events emitted by the contract.
+// Events named names are sent to the returned channel, which is closed when ctx is done or the subscription breaks
+type ChainWatcher interface {
+	Watch(ctx context.Context, names ...string) (<-chan Event, error)
+}
+
+// Watch subscribes to events named names if chain is a ChainWatcher, and subscribes again a minute later
+// once the subscription fails or breaks, events are sent to the returned channel until ctx is done.
+// It returns nil if chain is not a ChainWatcher, receiving from which blocks forever,
+// so that monitors selecting on it along with their tickers fall back to polling
+func Watch(ctx context.Context, chain interface{}, names ...string) <-chan Event {
+	w, ok := chain.(ChainWatcher)
+	if !ok {
+		logger.WithField("events", names).Info("events are not supported by the blockchain, poll it instead")
+		return nil
+	}
+
+	out := make(chan Event)
+	go func() {
+		for {
+			events, err := w.Watch(ctx, names...)
+			if err != nil {
+				logger.WithError(err).WithField("events", names).Warn("failed to watch events, poll the blockchain until retry")
+			} else {
+				for e := range events {
+					select {
+					case out <- e:
+					case <-ctx.Done():
+						return
+					}
+				}
+				if ctx.Err() == nil {
+					logger.WithField("events", names).Warn("event subscription broken, poll the blockchain until retry")
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(watchRetryInterval):
+			}
+		}
+	}()
+	return out
+}
@@ -50,6 +50,12 @@ func (x *Xdata) ListChallengeRequests(stub shim.ChaincodeStubInterface, args []s
 
 	// pack prefix
 	prefix, attr := packChallengeFilter(opt.FileOwner, opt.TargetNode)
+	cursorKey, err := packCursorIndex(prefix, attr, opt.Cursor, func(c blockchain.Cursor) string {
+		return packChallengeIndexByCursor(opt.FileOwner, opt.TargetNode, c)
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
 	// get iter by prefix
 	iterator, err := stub.GetStateByPartialCompositeKey(prefix, attr)
 	if err != nil {
@@ -63,6 +69,10 @@ func (x *Xdata) ListChallengeRequests(stub shim.ChaincodeStubInterface, args []s
 		if err != nil {
 			return shim.Error(err.Error())
 		}
+		// skip indexes up to the cursor, which are listed in previous pages
+		if queryResponse.Key <= cursorKey {
+			continue
+		}
 
 		if opt.Limit > 0 && int64(len(cs)) >= opt.Limit {
 			break
@@ -176,6 +186,15 @@ func (x *Xdata) ChallengeRequest(stub shim.ChaincodeStubInterface, args []string
 		return shim.Error(errorx.New(errorx.ErrCodeWriteBlockchain,
 			"failed to set index4Target-ChallengeID on chain: %s", resp.Message).Error())
 	}
+	// emit the event for watchers
+	if err := emitEvent(stub, blockchain.EventChallengeRequest, blockchain.ChallengeEvent{
+		ID:         c.ID,
+		FileOwner:  c.FileOwner,
+		TargetNode: c.TargetNode,
+		Status:     c.Status,
+	}); err != nil {
+		return shim.Error(err.Error())
+	}
 
 	return shim.Success([]byte("requested"))
 }
@@ -280,6 +299,15 @@ func (x *Xdata) ChallengeAnswer(stub shim.ChaincodeStubInterface, args []string)
 		return shim.Error(errorx.New(errorx.ErrCodeWriteBlockchain,
 			"failed to update ChallengeID-Challenge on chain: %s", resp.Message).Error())
 	}
+	// emit the event for watchers
+	if err := emitEvent(stub, blockchain.EventChallengeAnswer, blockchain.ChallengeEvent{
+		ID:         c.ID,
+		FileOwner:  c.FileOwner,
+		TargetNode: c.TargetNode,
+		Status:     c.Status,
+	}); err != nil {
+		return shim.Error(err.Error())
+	}
 
 	if c.Status == blockchain.ChallengeProved {
 		return shim.Success([]byte("answered"))
@@ -55,6 +55,11 @@ func (x *Xdata) PublishFileAuthApplication(stub shim.ChaincodeStubInterface, arg
 		return shim.Error(err.Error())
 	}
 
+	// deleted files can not be authorized
+	if resp := x.GetValue(stub, []string{packFileTombstoneIndex(fa.FileID)}); len(resp.Payload) != 0 {
+		return shim.Error(errorx.New(errorx.ErrCodeNotFound, "file already deleted").Error())
+	}
+
 	fa.Status = blockchain.FileAuthUnapproved
 	// marshal fileAuthApplication
 	s, err := json.Marshal(fa)
@@ -95,6 +100,16 @@ func (x *Xdata) PublishFileAuthApplication(stub shim.ChaincodeStubInterface, arg
 		return shim.Error(errorx.New(errorx.ErrCodeWriteBlockchain,
 			"failed to set index_fileauth_list_applier_authorizer on chain: %s", resp.Message).Error())
 	}
+	// emit the event for watchers
+	if err := emitEvent(stub, blockchain.EventFileAuth, blockchain.FileAuthEvent{
+		ID:         fa.ID,
+		FileID:     fa.FileID,
+		Applier:    fa.Applier,
+		Authorizer: fa.Authorizer,
+		Status:     fa.Status,
+	}); err != nil {
+		return shim.Error(err.Error())
+	}
 	return shim.Success([]byte("OK"))
 }
 
@@ -161,10 +176,61 @@ func (x *Xdata) setFileAuthConfirmStatus(stub shim.ChaincodeStubInterface, args
 		return shim.Error(errorx.New(errorx.ErrCodeWriteBlockchain,
 			"failed to confirm index_fileauth on chain: %s", resp.Message).Error())
 	}
+	// emit the event for watchers
+	if err := emitEvent(stub, blockchain.EventFileAuth, blockchain.FileAuthEvent{
+		ID:         fa.ID,
+		FileID:     fa.FileID,
+		Applier:    fa.Applier,
+		Authorizer: fa.Authorizer,
+		Status:     fa.Status,
+	}); err != nil {
+		return shim.Error(err.Error())
+	}
 
 	return shim.Success([]byte("OK"))
 }
 
+// invalidateFileAuths sets the unapproved authorization applications of a deleted file as Invalid
+func (x *Xdata) invalidateFileAuths(stub shim.ChaincodeStubInterface, f blockchain.File, ctime int64) error {
+	prefix, attr := packFileAuthFilter(nil, f.Owner)
+	iterator, err := stub.GetStateByPartialCompositeKey(prefix, attr)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate file authorization applications")
+	}
+	var fas []blockchain.FileAuthApplication
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			iterator.Close()
+			return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate file authorization applications")
+		}
+		fa, err := x.getFileAuthByID(stub, string(queryResponse.Value))
+		if err != nil {
+			iterator.Close()
+			return err
+		}
+		if fa.FileID == f.ID && fa.Status == blockchain.FileAuthUnapproved {
+			fas = append(fas, fa)
+		}
+	}
+	iterator.Close()
+
+	for _, fa := range fas {
+		fa.Status = blockchain.FileAuthInvalid
+		fa.RejectReason = "file deleted"
+		fa.ApprovalTime = ctime
+		s, err := json.Marshal(fa)
+		if err != nil {
+			return errorx.NewCode(err, errorx.ErrCodeInternal, "fail to marshal FileAuthApplication")
+		}
+		if resp := x.SetValue(stub, []string{packFileAuthIndex(fa.ID), string(s)}); resp.Status == shim.ERROR {
+			return errorx.New(errorx.ErrCodeWriteBlockchain,
+				"fail to invalidate index_fileauth on chain: %s", resp.Message)
+		}
+	}
+	return nil
+}
+
 // getFileAuthByID query file's authorization application by authID
 func (x *Xdata) getFileAuthByID(stub shim.ChaincodeStubInterface, authID string) (fa blockchain.FileAuthApplication, err error) {
 	index := packFileAuthIndex(authID)
@@ -195,6 +261,12 @@ func (x *Xdata) ListFileAuthApplications(stub shim.ChaincodeStubInterface, args
 	}
 	prefix, attr := packFileAuthFilter(opt.Applier, opt.Authorizer)
 
+	cursorKey, err := packCursorIndex(prefix, attr, opt.Cursor, func(c blockchain.Cursor) string {
+		return packFileAuthIndexByCursor(opt.Applier, opt.Authorizer, c)
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
 	// get iter by prefix
 	iterator, err := stub.GetStateByPartialCompositeKey(prefix, attr)
 	if err != nil {
@@ -212,6 +284,10 @@ func (x *Xdata) ListFileAuthApplications(stub shim.ChaincodeStubInterface, args
 		if err != nil {
 			return shim.Error(err.Error())
 		}
+		// skip indexes up to the cursor, which are listed in previous pages
+		if queryResponse.Key <= cursorKey {
+			continue
+		}
 
 		fa, err := x.getFileAuthByID(stub, string(queryResponse.Value))
 		if err != nil {
@@ -75,19 +75,18 @@ func (x *Xdata) PublishFile(stub shim.ChaincodeStubInterface, args []string) pb.
 		return shim.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
 			"failed to unmarshal namespace").Error())
 	}
+	// the redundancy mode of file must match its namespace
+	if f.DataShards != ns.DataShards || f.ParityShards != ns.ParityShards {
+		return shim.Error(errorx.New(errorx.ErrCodeParam, "file shards mismatch namespace").Error())
+	}
 
-	// if there's a expired file with the same name in user's storage, overwrite it with the new one
-	filenameIndex := packFileNameIndex(f.Owner, f.Namespace, f.Name)
-
-	if resp := x.GetValue(stub, []string{filenameIndex}); len(resp.Payload) != 0 {
-		fc, err := x.getFileByID(stub, string(resp.Payload))
-		if err != nil {
-			return shim.Error(err.Error())
-		}
-		if fc.ExpireTime+blockchain.FileRetainPeriod.Nanoseconds() > opt.File.PublishTime {
-			return shim.Error(errorx.New(errorx.ErrCodeAlreadyExists, "duplicated file name").Error())
-		}
+	// if there's already a file with the same name in user's storage, the new one becomes its next version
+	version, prevID, err := x.getLatestFileVersion(stub, f.Owner, f.Namespace, f.Name)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
+	f.Version = version + 1
+	f.PrevID = prevID
 
 	// marshal file
 	s, err := json.Marshal(f)
@@ -100,11 +99,18 @@ func (x *Xdata) PublishFile(stub shim.ChaincodeStubInterface, args []string) pb.
 		return shim.Error(errorx.New(errorx.ErrCodeWriteBlockchain,
 			"failed to set id-file on chain: %s", resp.Message).Error())
 	}
-	// set filenameIndex-id on chain
+	// set filenameIndex-id on chain, it always points to the latest version
+	filenameIndex := packFileNameIndex(f.Owner, f.Namespace, f.Name)
 	if resp := x.SetValue(stub, []string{filenameIndex, f.ID}); resp.Status == shim.ERROR {
 		return shim.Error(errorx.New(errorx.ErrCodeWriteBlockchain,
 			"failed to set index-id on chain: %s", resp.Message).Error())
 	}
+	// set fileVersionIndex-id on chain
+	fileVersionIndex := packFileVersionIndex(f.Owner, f.Namespace, f.Name, f.Version)
+	if resp := x.SetValue(stub, []string{fileVersionIndex, f.ID}); resp.Status == shim.ERROR {
+		return shim.Error(errorx.New(errorx.ErrCodeWriteBlockchain,
+			"failed to set versionIndex-id on chain: %s", resp.Message).Error())
+	}
 	// set filenameListIndex-id on chain
 	filenameListIndex := packFileListByOwnerIndex(f.Owner, f.Namespace, f.Name, f.PublishTime)
 	if resp := x.SetValue(stub, []string{filenameListIndex, f.ID}); resp.Status == shim.ERROR {
@@ -148,8 +154,40 @@ func (x *Xdata) PublishFile(stub shim.ChaincodeStubInterface, args []string) pb.
 				"failed to set index-id on chain: %s", resp.Message).Error())
 		}
 	}
+	if err := x.updateSliceRefs(stub, f, f.ExpireTime); err != nil {
+		return shim.Error(err.Error())
+	}
 
-	return shim.Success([]byte("Published"))
+	return shim.Success(s)
+}
+
+// getLatestFileVersion returns the latest version of the file name and its file ID, deleted versions included,
+// so that version numbers are never reused. 0 is returned if no file has the name.
+// A file published before versioning is taken as version 1, and its version index is set here
+func (x *Xdata) getLatestFileVersion(stub shim.ChaincodeStubInterface, owner []byte, ns, name string) (int64, string, error) {
+	prefix, attr := packFileVersionFilter(owner, ns, name)
+	iterator, err := stub.GetStateByPartialCompositeKey(prefix, attr)
+	if err != nil {
+		return 0, "", errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate file versions")
+	}
+	if iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		iterator.Close()
+		if err != nil {
+			return 0, "", errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate file versions")
+		}
+		return getFileVersion([]byte(queryResponse.Key)), string(queryResponse.Value), nil
+	}
+	iterator.Close()
+
+	resp := x.GetValue(stub, []string{packFileNameIndex(owner, ns, name)})
+	if len(resp.Payload) == 0 {
+		return 0, "", nil
+	}
+	if resp := x.SetValue(stub, []string{packFileVersionIndex(owner, ns, name, 1), string(resp.Payload)}); resp.Status == shim.ERROR {
+		return 0, "", errorx.New(errorx.ErrCodeWriteBlockchain, "failed to set versionIndex-id on chain: %s", resp.Message)
+	}
+	return 1, string(resp.Payload), nil
 }
 
 // AddFileNs adds file namespace
@@ -181,6 +219,12 @@ func (x *Xdata) AddFileNs(stub shim.ChaincodeStubInterface, args []string) pb.Re
 		return shim.Error(err.Error())
 	}
 
+	// erasure coded namespace stores each shard only once
+	if ns.DataShards < 0 || ns.ParityShards < 0 || (ns.DataShards > 0) != (ns.ParityShards > 0) ||
+		(ns.ParityShards > 0 && ns.Replica != 1) {
+		return shim.Error(errorx.New(errorx.ErrCodeParam, "bad param:shards").Error())
+	}
+
 	// judge if fileNsIndex exists
 	fileNsIndex := packFileNsIndex(ns.Owner, ns.Name)
 	if resp := x.GetValue(stub, []string{fileNsIndex}); len(resp.Payload) != 0 {
@@ -236,7 +280,7 @@ func (x *Xdata) UpdateNsReplica(stub shim.ChaincodeStubInterface, args []string)
 		return shim.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
 			"failed to unmarshal namespace").Error())
 	}
-	if n.Replica >= opt.Replica {
+	if n.ErasureCoded() || opt.Replica <= 0 || n.Replica == opt.Replica {
 		return shim.Error(errorx.New(errorx.ErrCodeParam, "bad param:replica").Error())
 	}
 
@@ -260,6 +304,64 @@ func (x *Xdata) UpdateNsReplica(stub shim.ChaincodeStubInterface, args []string)
 	return shim.Success([]byte("OK"))
 }
 
+// UpdateNsLifecycle updates lifecycle policy of file namespace
+func (x *Xdata) UpdateNsLifecycle(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) < 1 {
+		return shim.Error("invalid arguments. expecting UpdateNsLifecycleOptions")
+	}
+
+	// unmarshal opt
+	var opt blockchain.UpdateNsLifecycleOptions
+	if err := json.Unmarshal([]byte(args[0]), &opt); err != nil {
+		return shim.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal UpdateNsLifecycleOptions").Error())
+	}
+	// get the message to sign
+	msg, err := util.GetSigMessage(opt)
+	if err != nil {
+		return shim.Error(errorx.Internal(err, "failed to get the message to sign").Error())
+	}
+	// verify sig
+	if err := x.checkSign(opt.Signature, opt.Owner, []byte(msg)); err != nil {
+		return shim.Error(err.Error())
+	}
+	if opt.Lifecycle != nil && !opt.Lifecycle.Valid() {
+		return shim.Error(errorx.New(errorx.ErrCodeParam, "bad param:lifecycle").Error())
+	}
+
+	// get file ns
+	fileNsIndex := packFileNsIndex(opt.Owner, opt.Name)
+	resp := x.GetValue(stub, []string{fileNsIndex})
+	if len(resp.Payload) == 0 {
+		return shim.Error(errorx.New(errorx.ErrCodeNotFound,
+			"file namespace not found: %s", resp.Message).Error())
+	}
+	var n blockchain.Namespace
+	if err := json.Unmarshal(resp.Payload, &n); err != nil {
+		return shim.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal namespace").Error())
+	}
+
+	n.Lifecycle = opt.Lifecycle
+	n.UpdateTime = opt.CurrentTime
+	s, err := json.Marshal(n)
+	if err != nil {
+		return shim.Error(errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal Namespaces").Error())
+	}
+	// put index-ns on chain
+	if resp := x.SetValue(stub, []string{fileNsIndex, string(s)}); resp.Status == shim.ERROR {
+		return shim.Error(errorx.New(errorx.ErrCodeWriteBlockchain,
+			"failed to update nsindex-lifecycle on chain: %s", resp.Message).Error())
+	}
+	// put listIndex-ns on chain
+	nsListIndex := packFileNsListIndex(n.Owner, n.Name, n.CreateTime)
+	if resp := x.SetValue(stub, []string{nsListIndex, string(s)}); resp.Status == shim.ERROR {
+		return shim.Error(errorx.New(errorx.ErrCodeWriteBlockchain,
+			"failed to update nsListIndex-lifecycle on chain: %s", resp.Message).Error())
+	}
+	return shim.Success([]byte("OK"))
+}
+
 // UpdateFilePublicSliceMeta is used to update file public slice metas
 func (x *Xdata) UpdateFilePublicSliceMeta(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	if len(args) < 1 {
@@ -295,8 +397,12 @@ func (x *Xdata) UpdateFilePublicSliceMeta(stub shim.ChaincodeStubInterface, args
 		return shim.Error(errorx.New(errorx.ErrCodeNotAuthorized, "bad param, file owner is wrong").Error())
 	}
 
-	// update slices
+	// update slices, and the key of the file if it's rekeyed
 	f.Slices = opt.Slices
+	if len(opt.WrappedKey) != 0 {
+		f.KeyID = opt.KeyID
+		f.WrappedKey = opt.WrappedKey
+	}
 	nfs, err := json.Marshal(f)
 	if err != nil {
 		return shim.Error(errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal Namespaces").Error())
@@ -333,16 +439,29 @@ func (x *Xdata) GetFileByName(stub shim.ChaincodeStubInterface, args []string) p
 		return shim.Error(errorx.New(errorx.ErrCodeParam, "bad param, ns not found: %s", resp.Message).Error())
 	}
 
-	// pack fileNameIndex
+	// pack fileNameIndex, the latest version is got if version is not given
 	index := packFileNameIndex(owner, ns, name)
+	if len(args) > 4 {
+		version, err := strconv.ParseInt(args[4], 10, 64)
+		if err != nil || version < 0 {
+			return shim.Error(errorx.New(errorx.ErrCodeParam, "bad param:version").Error())
+		}
+		if version > 0 {
+			index = packFileVersionIndex(owner, ns, name, version)
+		}
+	}
 	//get id from index
 	resp := x.GetValue(stub, []string{index})
 	if len(resp.Payload) == 0 {
 		return shim.Error(errorx.New(errorx.ErrCodeNotFound, "fileID not found with name+ns: %s", resp.Message).Error())
 	}
 	// get file from id
-	resp = x.GetValue(stub, []string{string(resp.Payload)})
+	id := string(resp.Payload)
+	resp = x.GetValue(stub, []string{id})
 	if len(resp.Payload) == 0 {
+		if tresp := x.GetValue(stub, []string{packFileTombstoneIndex(id)}); len(tresp.Payload) != 0 {
+			return shim.Error(errorx.New(errorx.ErrCodeNotFound, "file already deleted").Error())
+		}
 		return shim.Error(errorx.New(errorx.ErrCodeNotFound, "file not found: %s", resp.Message).Error())
 	}
 
@@ -375,6 +494,9 @@ func (x *Xdata) GetFileByID(stub shim.ChaincodeStubInterface, args []string) pb.
 	//get file from id
 	resp := x.GetValue(stub, []string{id})
 	if len(resp.Payload) == 0 {
+		if tresp := x.GetValue(stub, []string{packFileTombstoneIndex(id)}); len(tresp.Payload) != 0 {
+			return shim.Error(errorx.New(errorx.ErrCodeNotFound, "file already deleted").Error())
+		}
 		return shim.Error(errorx.New(errorx.ErrCodeNotFound, "file not found: %s", resp.Message).Error())
 	}
 	var f blockchain.File
@@ -423,6 +545,9 @@ func (x *Xdata) UpdateFileExpireTime(stub shim.ChaincodeStubInterface, args []st
 		return shim.Error(err.Error())
 	}
 
+	if err := x.updateSliceRefs(stub, f, opt.NewExpireTime); err != nil {
+		return shim.Error(err.Error())
+	}
 	// marshal file
 	f.ExpireTime = opt.NewExpireTime
 	nf, err := json.Marshal(f)
@@ -437,6 +562,271 @@ func (x *Xdata) UpdateFileExpireTime(stub shim.ChaincodeStubInterface, args []st
 	return shim.Success(nf)
 }
 
+// DeleteFile deletes a file by its owner before it expires, a tombstone is left on chain.
+// Slices of the file are moved from node slice index to node deleted slice index,
+// so that storage nodes can remove them at once rather than after the file retain period.
+func (x *Xdata) DeleteFile(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) < 1 {
+		return shim.Error("invalid arguments. expecting DeleteFileOptions")
+	}
+
+	// unmarshal opt
+	var opt blockchain.DeleteFileOptions
+	if err := json.Unmarshal([]byte(args[0]), &opt); err != nil {
+		return shim.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal DeleteFileOptions").Error())
+	}
+	//get file from id
+	f, err := x.getFileByID(stub, opt.FileID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	// verify sig
+	msg, err := util.GetSigMessage(opt)
+	if err != nil {
+		return shim.Error(errorx.Internal(err, "failed to get the message to sign").Error())
+	}
+	if err := x.checkSign(opt.Signature, f.Owner, []byte(msg)); err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// put tombstone on chain
+	t, err := json.Marshal(blockchain.FileTombstone{
+		FileID:     f.ID,
+		Owner:      f.Owner,
+		Namespace:  f.Namespace,
+		Name:       f.Name,
+		DeleteTime: opt.CurrentTime,
+	})
+	if err != nil {
+		return shim.Error(errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal FileTombstone").Error())
+	}
+	if resp := x.SetValue(stub, []string{packFileTombstoneIndex(f.ID), string(t)}); resp.Status == shim.ERROR {
+		return shim.Error(errorx.New(errorx.ErrCodeWriteBlockchain,
+			"failed to set tombstone on chain: %s", resp.Message).Error())
+	}
+
+	// remove id-file and file indexes from chain
+	if err := stub.DelState(f.ID); err != nil {
+		return shim.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to delete id-file on chain").Error())
+	}
+	// if the latest version is deleted, the name points to the latest version left.
+	// Version index is kept, so that a deleted version is reported rather than taken by a new file
+	filenameIndex := packFileNameIndex(f.Owner, f.Namespace, f.Name)
+	if resp := x.GetValue(stub, []string{filenameIndex}); string(resp.Payload) == f.ID {
+		if err := x.resetLatestFileVersion(stub, f); err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	filenameListIndex := packFileListByOwnerIndex(f.Owner, f.Namespace, f.Name, f.PublishTime)
+	if err := stub.DelState(filenameListIndex); err != nil {
+		return shim.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to delete file listIndex-id on chain").Error())
+	}
+	fileListByNsIndex := packFileListByNsIndex(f.Owner, f.Namespace, f.Name, f.PublishTime)
+	if err := stub.DelState(fileListByNsIndex); err != nil {
+		return shim.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to delete fileListByNsIndex-id on chain").Error())
+	}
+
+	// move node-sliceID-expireTime to node-sliceID-deleteTime
+	nodeSlice := make(map[string][]string)
+	for _, slice := range f.Slices {
+		nodeSlice[string(slice.NodeID)] = append(nodeSlice[string(slice.NodeID)], slice.ID+":"+slice.StorIndex)
+	}
+	for nodeID, sliceL := range nodeSlice {
+		if err := x.deleteNodeSliceIndex(stub, nodeID, f.ID); err != nil {
+			return shim.Error(err.Error())
+		}
+		index := packNodeDeletedSliceIndex(nodeID, opt.CurrentTime, f.ID)
+		if resp := x.SetValue(stub, []string{index, strings.Join(sliceL, ",")}); resp.Status == shim.ERROR {
+			return shim.Error(errorx.New(errorx.ErrCodeWriteBlockchain,
+				"failed to set node deleted slice index on chain: %s", resp.Message).Error())
+		}
+	}
+	if err := x.updateSliceRefs(stub, f, 0); err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// update file num of fileNsIndex
+	fileNsIndex := packFileNsIndex(f.Owner, f.Namespace)
+	resp := x.GetValue(stub, []string{fileNsIndex})
+	if len(resp.Payload) == 0 {
+		return shim.Error(errorx.New(errorx.ErrCodeNotFound, "file namespace not found: %s", resp.Message).Error())
+	}
+	var ns blockchain.Namespace
+	if err := json.Unmarshal(resp.Payload, &ns); err != nil {
+		return shim.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal namespace").Error())
+	}
+	ns.FileTotalNum -= 1
+	ns.UpdateTime = opt.CurrentTime
+	nsf, err := json.Marshal(ns)
+	if err != nil {
+		return shim.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to marshal File namespace").Error())
+	}
+	if resp := x.SetValue(stub, []string{fileNsIndex, string(nsf)}); resp.Status == shim.ERROR {
+		return shim.Error(errorx.New(errorx.ErrCodeWriteBlockchain,
+			"failed to update index-ns on chain: %s", resp.Message).Error())
+	}
+	nsListIndex := packFileNsListIndex(ns.Owner, ns.Name, ns.CreateTime)
+	if resp := x.SetValue(stub, []string{nsListIndex, string(nsf)}); resp.Status == shim.ERROR {
+		return shim.Error(errorx.New(errorx.ErrCodeWriteBlockchain,
+			"failed to update listIndex-ns on chain: %s", resp.Message).Error())
+	}
+
+	// authorization applications not yet approved can never be approved
+	if err := x.invalidateFileAuths(stub, f, opt.CurrentTime); err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte("OK"))
+}
+
+// resetLatestFileVersion points the name of a deleted file to the latest version left,
+// the name index is removed if all versions are deleted
+func (x *Xdata) resetLatestFileVersion(stub shim.ChaincodeStubInterface, f blockchain.File) error {
+	filenameIndex := packFileNameIndex(f.Owner, f.Namespace, f.Name)
+	prefix, attr := packFileVersionFilter(f.Owner, f.Namespace, f.Name)
+	iterator, err := stub.GetStateByPartialCompositeKey(prefix, attr)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate file versions")
+	}
+	var latest string
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			iterator.Close()
+			return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate file versions")
+		}
+		// state deleted in the same transaction is still readable
+		id := string(queryResponse.Value)
+		if id == f.ID {
+			continue
+		}
+		if resp := x.GetValue(stub, []string{id}); len(resp.Payload) != 0 {
+			latest = id
+			break
+		}
+	}
+	iterator.Close()
+
+	if latest == "" {
+		if err := stub.DelState(filenameIndex); err != nil {
+			return errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to delete index-id on chain")
+		}
+		return nil
+	}
+	if resp := x.SetValue(stub, []string{filenameIndex, latest}); resp.Status == shim.ERROR {
+		return errorx.New(errorx.ErrCodeWriteBlockchain, "failed to set index-id on chain: %s", resp.Message)
+	}
+	return nil
+}
+
+// deleteNodeSliceIndex removes the node slice index of a file,
+// the index is looked up by file ID, as the expire time in index key may be updated
+func (x *Xdata) deleteNodeSliceIndex(stub shim.ChaincodeStubInterface, nodeID, fileID string) error {
+	prefix, attr := packNodeSliceFilter(nodeID)
+	iterator, err := stub.GetStateByPartialCompositeKey(prefix, attr)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate node slice index")
+	}
+	var keys []string
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			iterator.Close()
+			return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate node slice index")
+		}
+		if getNodeSliceKeyFileID([]byte(queryResponse.Key)) == fileID {
+			keys = append(keys, queryResponse.Key)
+		}
+	}
+	iterator.Close()
+
+	for _, key := range keys {
+		if err := stub.DelState(key); err != nil {
+			return errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to delete node slice index on chain")
+		}
+	}
+	return nil
+}
+
+// updateSliceRefs sets the expire time of the file in the reference index of its slices,
+// the file is removed from the index if expireTime is 0
+func (x *Xdata) updateSliceRefs(stub shim.ChaincodeStubInterface, f blockchain.File, expireTime int64) error {
+	seen := make(map[string]bool)
+	for _, slice := range f.Slices {
+		if seen[slice.ID] {
+			continue
+		}
+		seen[slice.ID] = true
+
+		refs, err := x.getSliceRefs(stub, slice.ID)
+		if err != nil {
+			return err
+		}
+		if expireTime > 0 {
+			refs[f.ID] = expireTime
+		} else {
+			delete(refs, f.ID)
+		}
+
+		index := packSliceRefIndex(slice.ID)
+		if len(refs) == 0 {
+			if err := stub.DelState(index); err != nil {
+				return errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to delete slice reference index on chain")
+			}
+			continue
+		}
+		r, err := json.Marshal(refs)
+		if err != nil {
+			return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal slice references")
+		}
+		if resp := x.SetValue(stub, []string{index, string(r)}); resp.Status == shim.ERROR {
+			return errorx.New(errorx.ErrCodeWriteBlockchain,
+				"failed to set slice reference index on chain: %s", resp.Message)
+		}
+	}
+	return nil
+}
+
+// getSliceRefs returns expire time of files referencing the slice by file ID,
+// it is empty for slices of files published before the reference index
+func (x *Xdata) getSliceRefs(stub shim.ChaincodeStubInterface, sliceID string) (map[string]int64, error) {
+	refs := make(map[string]int64)
+	resp := x.GetValue(stub, []string{packSliceRefIndex(sliceID)})
+	if len(resp.Payload) == 0 {
+		return refs, nil
+	}
+	if err := json.Unmarshal(resp.Payload, &refs); err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal slice references")
+	}
+	return refs, nil
+}
+
+// unreferencedSlices returns slices in the value of node slice index which are not referenced by any file
+// still alive, as told by alive with the expire time of the file
+func (x *Xdata) unreferencedSlices(stub shim.ChaincodeStubInterface, value string,
+	alive func(expireTime int64) bool) (string, error) {
+	var kept []string
+	for _, s := range strings.Split(value, ",") {
+		refs, err := x.getSliceRefs(stub, strings.SplitN(s, ":", 2)[0])
+		if err != nil {
+			return "", err
+		}
+		referenced := false
+		for _, expireTime := range refs {
+			if alive(expireTime) {
+				referenced = true
+				break
+			}
+		}
+		if !referenced {
+			kept = append(kept, s)
+		}
+	}
+	return strings.Join(kept, ","), nil
+}
+
 // SliceMigrateRecord is used by node to slice migration record
 func (x *Xdata) SliceMigrateRecord(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	if len(args) < 1 {
@@ -498,6 +888,12 @@ func (x *Xdata) ListFiles(stub shim.ChaincodeStubInterface, args []string) pb.Re
 
 	// pack prefix
 	prefix, attr := packFileNameFilter(opt.Owner, opt.Namespace)
+	cursorKey, err := packCursorIndex(prefix, attr, opt.Cursor, func(c blockchain.Cursor) string {
+		return packFileListIndexByCursor(opt.Owner, opt.Namespace, c)
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
 	// get iter by prefix
 	iterator, err := stub.GetStateByPartialCompositeKey(prefix, attr)
 	if err != nil {
@@ -512,6 +908,10 @@ func (x *Xdata) ListFiles(stub shim.ChaincodeStubInterface, args []string) pb.Re
 		if err != nil {
 			return shim.Error(err.Error())
 		}
+		// skip indexes up to the cursor, which are listed in previous pages
+		if queryResponse.Key <= cursorKey {
+			continue
+		}
 
 		if opt.Limit > 0 && int64(len(fs)) >= opt.Limit {
 			break
@@ -534,6 +934,62 @@ func (x *Xdata) ListFiles(stub shim.ChaincodeStubInterface, args []string) pb.Re
 	return shim.Success(s)
 }
 
+// ListFileVersions lists versions of the file with the name, from the latest to the earliest,
+// deleted and expired versions are excluded
+func (x *Xdata) ListFileVersions(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) < 1 {
+		return shim.Error("invalid arguments. expecting ListFileVersionsOptions")
+	}
+
+	// unmarshal opt
+	var opt blockchain.ListFileVersionsOptions
+	if err := json.Unmarshal([]byte(args[0]), &opt); err != nil {
+		return shim.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal ListFileVersionsOptions").Error())
+	}
+
+	prefix, attr := packFileVersionFilter(opt.Owner, opt.Namespace, opt.Name)
+	iterator, err := stub.GetStateByPartialCompositeKey(prefix, attr)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer iterator.Close()
+
+	var fs []blockchain.File
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		if opt.Limit > 0 && int64(len(fs)) >= opt.Limit {
+			break
+		}
+		f, err := x.getFileByID(stub, string(queryResponse.Value))
+		if err != nil {
+			if errorx.Is(err, errorx.ErrCodeNotFound) {
+				continue
+			}
+			return shim.Error(err.Error())
+		}
+		if f.ExpireTime <= opt.CurrentTime {
+			continue
+		}
+		// files published before versioning
+		if f.Version == 0 {
+			f.Version = getFileVersion([]byte(queryResponse.Key))
+		}
+		fs = append(fs, f)
+	}
+
+	s, err := json.Marshal(fs)
+	if err != nil {
+		return shim.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to marshal Files").Error())
+	}
+	return shim.Success(s)
+}
+
 // ListExpiredFiles lists expired but valid files
 func (x *Xdata) ListExpiredFiles(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	if len(args) < 1 {
@@ -549,6 +1005,12 @@ func (x *Xdata) ListExpiredFiles(stub shim.ChaincodeStubInterface, args []string
 
 	// pack prefix
 	prefix, attr := packFileNameFilter(opt.Owner, opt.Namespace)
+	cursorKey, err := packCursorIndex(prefix, attr, opt.Cursor, func(c blockchain.Cursor) string {
+		return packFileListIndexByCursor(opt.Owner, opt.Namespace, c)
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
 	// get iter by prefix
 	iterator, err := stub.GetStateByPartialCompositeKey(prefix, attr)
 	if err != nil {
@@ -563,6 +1025,10 @@ func (x *Xdata) ListExpiredFiles(stub shim.ChaincodeStubInterface, args []string
 		if err != nil {
 			return shim.Error(err.Error())
 		}
+		// skip indexes up to the cursor, which are listed in previous pages
+		if queryResponse.Key <= cursorKey {
+			continue
+		}
 
 		if opt.Limit > 0 && int64(len(fs)) >= opt.Limit {
 			break
@@ -666,7 +1132,7 @@ func (x *Xdata) GetNsByName(stub shim.ChaincodeStubInterface, args []string) pb.
 func (x *Xdata) getFileByID(stub shim.ChaincodeStubInterface, fileID string) (f blockchain.File, err error) {
 	resp := x.GetValue(stub, []string{fileID})
 	if len(resp.Payload) == 0 {
-		return f, errorx.NewCode(err, errorx.ErrCodeNotFound, "file[%s] not found", fileID)
+		return f, errorx.New(errorx.ErrCodeNotFound, "file[%s] not found: %s", fileID, resp.Message)
 	}
 	if err = json.Unmarshal(resp.Payload, &f); err != nil {
 		return f, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal File")
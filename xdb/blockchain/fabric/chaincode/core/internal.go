@@ -14,17 +14,21 @@
 package core
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"strconv"
 	"strings"
 
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+
 	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
 )
 
 const (
 	compositeKeyNamespace = "\x00"
-	minUnicodeRuneValue   = 0
+	minUnicodeRuneValue   = rune(0)
 
 	// Define the contract prefix key of file and file namespace operations
 	prefixFilenameIndex        = "index_fn"
@@ -32,6 +36,8 @@ const (
 	prefixFileListByNsIndex    = "index_fs_list"
 	prefixFileNsIndex          = "index_fns"
 	prefixFileNsListIndex      = "index_fns_list"
+	prefixFileTombstone        = "index_ftomb"
+	prefixFileVersionIndex     = "index_fv"
 	// Define the contract prefix key of file authorization application operations
 	prefixFileAuthIndex           = "index_fileauth"
 	prefixFileAuthApplierIndex    = "index_fa_applier"
@@ -47,7 +53,9 @@ const (
 	prefixNodeHeartbeatIndex    = "index_hbnode"
 	prefixNodeSliceMigrateIndex = "index_slicemigrate"
 	prefixNodeFileSlice         = "index_fslice"
+	prefixNodeDeletedSlice      = "index_fdslice"
 	prefixNodeNonceIndex        = "index_ndnonce"
+	prefixSliceRefIndex         = "index_sliceref"
 )
 
 func packNodeIndex(nodeID []byte) string {
@@ -88,6 +96,30 @@ func getNodeSliceFileID(key []byte) int64 {
 	return expireTime
 }
 
+// getNodeSliceKeyFileID returns the file ID in the key of node slice index
+func getNodeSliceKeyFileID(key []byte) string {
+	strArr := strings.Split(string(key), string(minUnicodeRuneValue))
+	if len(strArr) < 5 {
+		return ""
+	}
+	return strArr[4]
+}
+
+// packNodeDeletedSliceIndex used to list slices of files deleted by owners, by the time of deletion
+func packNodeDeletedSliceIndex(node string, deleteTime int64, fileID string) string {
+	attributes := []string{node, fmt.Sprintf("%d", deleteTime), fileID}
+	return createCompositeKey(prefixNodeDeletedSlice, attributes)
+}
+
+func packNodeDeletedSliceFilter(target string) (string, []string) {
+	return prefixNodeDeletedSlice, []string{target}
+}
+
+// packSliceRefIndex used to find files referencing a slice, a slice is shared by deduplicated files
+func packSliceRefIndex(sliceID string) string {
+	return createCompositeKey(prefixSliceRefIndex, []string{sliceID})
+}
+
 func packNodeSliceMigrateIndex(target string, ctime int64) string {
 	attributes := []string{target, fmt.Sprintf("%d", subByInt64Max(ctime))}
 	return createCompositeKey(prefixNodeSliceMigrateIndex, attributes)
@@ -122,6 +154,33 @@ func packFileListByNsIndex(owner []byte, ns, name string, pubTime int64) string
 	return createCompositeKey(prefixFileListByNsIndex, attributes)
 }
 
+// packFileVersionIndex used to get file by version, versions of a file are iterated from the latest
+func packFileVersionIndex(owner []byte, ns, name string, version int64) string {
+	attributes := []string{fmt.Sprintf("%x", owner), ns, name, fmt.Sprintf("%d", subByInt64Max(version))}
+	return createCompositeKey(prefixFileVersionIndex, attributes)
+}
+
+func packFileVersionFilter(owner []byte, ns, name string) (string, []string) {
+	return prefixFileVersionIndex, []string{fmt.Sprintf("%x", owner), ns, name}
+}
+
+// getFileVersion example: string(key) = \x00 index_fv 0 owner 0 ns 0 name 0 9223372036854775806 0
+func getFileVersion(key []byte) int64 {
+	strArr := strings.Split(string(key), string(minUnicodeRuneValue))
+	if len(strArr) < 6 {
+		return 0
+	}
+	version, err := strconv.ParseInt(strArr[5], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return subByInt64Max(version)
+}
+
+func packFileTombstoneIndex(fileID string) string {
+	return createCompositeKey(prefixFileTombstone, []string{fileID})
+}
+
 func packFileNsIndex(owner []byte, ns string) string {
 	attributes := []string{fmt.Sprintf("%x", owner), ns}
 	return createCompositeKey(prefixFileNsIndex, attributes)
@@ -156,6 +215,14 @@ func packFileNameFilter(owner []byte, ns string) (prefix string, attr []string)
 	return filter, attr
 }
 
+// packFileListIndexByCursor returns the list index of the file at the cursor, in the list filtered by packFileNameFilter
+func packFileListIndexByCursor(owner []byte, ns string, c blockchain.Cursor) string {
+	if len(owner) == 0 && len(ns) > 0 {
+		return packFileListByNsIndex(c.Owner, c.Namespace, c.Name, c.Time)
+	}
+	return packFileListByOwnerIndex(c.Owner, c.Namespace, c.Name, c.Time)
+}
+
 // packFileAuthIndex Define the contract key for file authorization application
 func packFileAuthIndex(id string) string {
 	return createCompositeKey(prefixFileAuthIndex, []string{id})
@@ -189,6 +256,16 @@ func packFileAuthFilter(applier, authorizer []byte) (string, []string) {
 	}
 }
 
+// packFileAuthIndexByCursor returns the list index of the application at the cursor, in the list filtered by packFileAuthFilter
+func packFileAuthIndexByCursor(applier, authorizer []byte, c blockchain.Cursor) string {
+	if len(applier) > 0 && len(authorizer) > 0 {
+		return packApplierAndAuthorizerIndex(c.Owner, c.Target, c.ID, c.Time)
+	} else if len(applier) > 0 {
+		return packFileAuthApplierIndex(c.Owner, c.ID, c.Time)
+	}
+	return packFileAuthAuthorizerIndex(c.Target, c.ID, c.Time)
+}
+
 func packChallengeIndex(id string) string {
 	return createCompositeKey(prefixChallenge, []string{id})
 }
@@ -221,6 +298,15 @@ func packChallengeFilter(owner, target []byte) (prefix string, attr []string) {
 	return prefix, attr
 }
 
+// packChallengeIndexByCursor returns the list index of the challenge at the cursor, in the list filtered by packChallengeFilter
+func packChallengeIndexByCursor(owner, target []byte, cur blockchain.Cursor) string {
+	c := &blockchain.Challenge{ID: cur.ID, FileOwner: cur.Owner, TargetNode: cur.Target, ChallengeTime: cur.Time}
+	if len(owner) == 0 && len(target) > 0 {
+		return packChallengeIndex4Target(c)
+	}
+	return packChallengeIndex4Owner(c)
+}
+
 func createCompositeKey(objectType string, attributes []string) string {
 	ck := compositeKeyNamespace + objectType + string(minUnicodeRuneValue)
 	for _, att := range attributes {
@@ -229,7 +315,37 @@ func createCompositeKey(objectType string, attributes []string) string {
 	return ck
 }
 
+// packCursorIndex returns the list index of the item at the cursor, which is built by index,
+// lists filtered by prefix and attr are continued by skipping indexes up to it. It's empty if the cursor is empty
+func packCursorIndex(prefix string, attr []string, cursor string, index func(blockchain.Cursor) string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	c, err := blockchain.ParseCursor(cursor)
+	if err != nil {
+		return "", err
+	}
+	key := index(c)
+	if !strings.HasPrefix(key, createCompositeKey(prefix, attr)) {
+		return "", errorx.New(errorx.ErrCodeParam, "invalid cursor, not in the list")
+	}
+	return key, nil
+}
+
 // return maxInt64 - N
 func subByInt64Max(n int64) int64 {
 	return math.MaxInt64 - n
 }
+
+// emitEvent sets an event with json encoded payload for watchers, see blockchain.ChainWatcher,
+// fabric keeps only the last event set by a transaction
+func emitEvent(stub shim.ChaincodeStubInterface, name string, payload interface{}) error {
+	s, err := json.Marshal(payload)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal event %s", name)
+	}
+	if err := stub.SetEvent(name, s); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to set event %s", name)
+	}
+	return nil
+}
@@ -135,7 +135,14 @@ func (x *Xdata) NodeOffline(stub shim.ChaincodeStubInterface, args []string) pb.
 	if len(args) < 1 {
 		return shim.Error("invalid arguments. expecting nodeID and signature")
 	}
-	return x.setNodeOnlineStatus(stub, args, false)
+	// a draining node is kept draining
+	return x.setNodeStatus(stub, args, func(node *blockchain.Node) (bool, error) {
+		if !node.Online {
+			return false, nil
+		}
+		node.Online = false
+		return true, nil
+	})
 }
 
 // NodeOnline args = {id, sig}
@@ -143,11 +150,38 @@ func (x *Xdata) NodeOnline(stub shim.ChaincodeStubInterface, args []string) pb.R
 	if len(args) < 1 {
 		return shim.Error("invalid arguments. expecting nodeID and signature")
 	}
-	return x.setNodeOnlineStatus(stub, args, true)
+	// a draining node stops draining
+	return x.setNodeStatus(stub, args, func(node *blockchain.Node) (bool, error) {
+		if node.Online && !node.Draining {
+			return false, nil
+		}
+		node.Online = true
+		node.Draining = false
+		return true, nil
+	})
+}
+
+// NodeDrain args = {id, sig}, starts draining an online node,
+// no new slices are placed on it and its slices are migrated to other nodes
+func (x *Xdata) NodeDrain(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) < 1 {
+		return shim.Error("invalid arguments. expecting nodeID and signature")
+	}
+	return x.setNodeStatus(stub, args, func(node *blockchain.Node) (bool, error) {
+		if !node.Online {
+			return false, errorx.New(errorx.ErrCodeParam, "node is offline")
+		}
+		if node.Draining {
+			return false, nil
+		}
+		node.Draining = true
+		return true, nil
+	})
 }
 
-// setNodeOnlineStatus sets node status
-func (x *Xdata) setNodeOnlineStatus(stub shim.ChaincodeStubInterface, args []string, online bool) pb.Response {
+// setNodeStatus sets node status by the function set, which returns true if the status is changed
+func (x *Xdata) setNodeStatus(stub shim.ChaincodeStubInterface, args []string,
+	set func(node *blockchain.Node) (bool, error)) pb.Response {
 	// unmarshal opt
 	var opt blockchain.NodeOperateOptions
 	if err := json.Unmarshal([]byte(args[0]), &opt); err != nil {
@@ -186,9 +220,11 @@ func (x *Xdata) setNodeOnlineStatus(stub shim.ChaincodeStubInterface, args []str
 			"failed to unmarshal node").Error())
 	}
 
-	if node.Online != online {
-		// change status
-		node.Online = online
+	changed, err := set(&node)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if changed {
 		// marshal new node
 		newn, err := json.Marshal(node)
 		if err != nil {
@@ -275,6 +311,11 @@ func (x *Xdata) Heartbeat(stub shim.ChaincodeStubInterface, args []string) pb.Re
 	}
 	// update node heartbeat time
 	node.UpdateAt = opt.CurrentTime
+	// update node capacity if reported
+	if opt.Capacity > 0 {
+		node.Capacity = opt.Capacity
+		node.FreeSpace = opt.FreeSpace
+	}
 	newNode, err := json.Marshal(node)
 	if err != nil {
 		return shim.Error(errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal node").Error())
@@ -351,22 +392,101 @@ func (x *Xdata) ListNodesExpireSlice(stub shim.ChaincodeStubInterface, args []st
 	}
 	defer iterator.Close()
 
+	// keys are ordered by expire time. A slice shared by deduplicated files
+	// is not expired while any file referencing it has not expired
 	var sl []string
 	for iterator.HasNext() {
+		if opt.Limit > 0 && int64(len(sl)) >= opt.Limit {
+			break
+		}
 		queryResponse, err := iterator.Next()
 		if err != nil {
 			return shim.Error(err.Error())
 		}
 
 		expireTime := getNodeSliceFileID([]byte(queryResponse.Key))
-		if (opt.Limit > 0 && int64(len(sl)) >= opt.Limit) || expireTime == 0 {
+		if expireTime == 0 || expireTime > opt.EndTime {
 			break
 		}
-		if expireTime < opt.StartTime || expireTime > opt.EndTime {
+		if expireTime < opt.StartTime {
 			continue
 		}
 
-		sl = append(sl, string(queryResponse.Value))
+		v, err := x.unreferencedSlices(stub, string(queryResponse.Value), func(t int64) bool {
+			return t > opt.EndTime
+		})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if v != "" {
+			sl = append(sl, v)
+		}
+	}
+
+	rs, err := json.Marshal(sl)
+	if err != nil {
+		return shim.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to marshal slices").Error())
+	}
+	return shim.Success(rs)
+}
+
+// ListNodesDeletedSlice lists slices of files deleted by owners from fabric, by the time of deletion
+func (x *Xdata) ListNodesDeletedSlice(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) < 1 {
+		return shim.Error("incorrect arguments. expecting ListNodeSliceOptions")
+	}
+
+	// unmarshal opt
+	var opt blockchain.ListNodeSliceOptions
+	if err := json.Unmarshal([]byte(args[0]), &opt); err != nil {
+		return shim.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal ListNodeSlice").Error())
+	}
+	pubkey, err := hex.DecodeString(string(opt.Target))
+	if err != nil {
+		return shim.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to decode nodeID").Error())
+	}
+	if len(pubkey) != ecdsa.PublicKeyLength ||
+		opt.StartTime < 0 || opt.EndTime <= 0 || opt.EndTime <= opt.StartTime {
+		return shim.Error(errorx.New(errorx.ErrCodeParam, "bad param").Error())
+	}
+
+	// keys are ordered by the time of deletion. A slice shared by deduplicated files
+	// is kept while any other file referencing it is not cleared
+	var sl []string
+	prefix, attr := packNodeDeletedSliceFilter(string(opt.Target))
+	iterator, err := stub.GetStateByPartialCompositeKey(prefix, attr)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer iterator.Close()
+	for iterator.HasNext() {
+		if opt.Limit > 0 && int64(len(sl)) >= opt.Limit {
+			break
+		}
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		deleteTime := getNodeSliceFileID([]byte(queryResponse.Key))
+		if deleteTime > opt.EndTime {
+			break
+		}
+		if deleteTime < opt.StartTime {
+			continue
+		}
+
+		v, err := x.unreferencedSlices(stub, string(queryResponse.Value), func(t int64) bool {
+			return t+blockchain.FileRetainPeriod.Nanoseconds() > opt.EndTime
+		})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if v != "" {
+			sl = append(sl, v)
+		}
 	}
 
 	rs, err := json.Marshal(sl)
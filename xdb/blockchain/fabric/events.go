@@ -0,0 +1,66 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fabric
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/event"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// Watch subscribes to events named names set by the chaincode in new blocks,
+// the user must be permitted to receive block events as payloads are not included in filtered blocks
+func (f *Fabric) Watch(ctx context.Context, names ...string) (<-chan blockchain.Event, error) {
+	client, err := event.New(f.channelProvider, event.WithBlockEvents())
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to create event client")
+	}
+
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+	filter := "^(" + strings.Join(quoted, "|") + ")$"
+	reg, ccEvents, err := client.RegisterChaincodeEvent(f.Config.ChaincodeID, filter)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeReadBlockchain, "failed to register chaincode events")
+	}
+
+	events := make(chan blockchain.Event)
+	go func() {
+		defer close(events)
+		defer client.Unregister(reg)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-ccEvents:
+				if !ok {
+					return
+				}
+				select {
+				case events <- blockchain.Event{Name: e.EventName, Payload: e.Payload}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
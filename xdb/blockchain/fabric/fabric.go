@@ -20,6 +20,7 @@ import (
 	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
 	"github.com/hyperledger/fabric-sdk-go/pkg/client/ledger"
 	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/retry"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/context"
 	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
 	"github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
 
@@ -41,6 +42,8 @@ type Fabric struct {
 	Sdk           *fabsdk.FabricSDK // Sdk is Fabric SDK instance
 	ChannelClient *channel.Client   // ChannelClient is client to request channel
 	ledgerClient  *ledger.Client    // ledgerClient is client to request ledger
+
+	channelProvider context.ChannelProvider // channelProvider is used to create event clients
 }
 
 // New new Fabric
@@ -93,6 +96,7 @@ func New(conf *conf.FabricConf) (*Fabric, error) {
 		return nil, errorx.Wrap(err, "failed to create channel client")
 	}
 	fabricDriver.ChannelClient = channelClient
+	fabricDriver.channelProvider = clientContext
 
 	// initiate ledgerClient
 	fabricDriver.ledgerClient, err = ledger.New(clientContext)
@@ -22,18 +22,23 @@ import (
 	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
 )
 
-// PublishFile publishes file onto fabric
-func (f *Fabric) PublishFile(opt *blockchain.PublishFileOptions) error {
+// PublishFile publishes file onto fabric, the file published is returned with its version
+func (f *Fabric) PublishFile(opt *blockchain.PublishFileOptions) (blockchain.File, error) {
+	var file blockchain.File
 	s, err := json.Marshal(*opt)
 	if err != nil {
-		return errorx.NewCode(err, errorx.ErrCodeInternal,
+		return file, errorx.NewCode(err, errorx.ErrCodeInternal,
 			"failed to marshal PublishFileOptions")
 	}
 
-	if _, err = f.InvokeContract([][]byte{s}, "PublishFile"); err != nil {
-		return err
+	resp, err := f.InvokeContract([][]byte{s}, "PublishFile")
+	if err != nil {
+		return file, err
 	}
-	return nil
+	if err = json.Unmarshal(resp, &file); err != nil {
+		return file, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal File")
+	}
+	return file, nil
 }
 
 // GetFileByName gets file by name from fabric
@@ -52,6 +57,23 @@ func (f *Fabric) GetFileByName(owner []byte, ns, name string) (blockchain.File,
 	return file, nil
 }
 
+// GetFileByVersion gets file by name and version from fabric
+func (f *Fabric) GetFileByVersion(owner []byte, ns, name string, version int64) (blockchain.File, error) {
+	var file blockchain.File
+
+	args := [][]byte{owner, []byte(ns), []byte(name), []byte(strconv.FormatInt(time.Now().UnixNano(), 10)),
+		[]byte(strconv.FormatInt(version, 10))}
+	s, err := f.QueryContract(args, "GetFileByName")
+	if err != nil {
+		return file, err
+	}
+	if err = json.Unmarshal(s, &file); err != nil {
+		return file, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal File")
+	}
+
+	return file, nil
+}
+
 // GetFileByID gets file by id from fabric
 func (f *Fabric) GetFileByID(id string) (blockchain.File, error) {
 	var file blockchain.File
@@ -87,6 +109,19 @@ func (f *Fabric) UpdateFileExpireTime(opt *blockchain.UpdateExptimeOptions) (blo
 	return file, nil
 }
 
+// DeleteFile deletes file by its owner and leaves a tombstone on chain
+func (f *Fabric) DeleteFile(opt *blockchain.DeleteFileOptions) error {
+	s, err := json.Marshal(*opt)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal DeleteFileOptions")
+	}
+
+	if _, err := f.InvokeContract([][]byte{s}, "DeleteFile"); err != nil {
+		return err
+	}
+	return nil
+}
+
 // AddFileNs adds file namespace
 func (f *Fabric) AddFileNs(opt *blockchain.AddNsOptions) error {
 	s, err := json.Marshal(*opt)
@@ -114,6 +149,19 @@ func (f *Fabric) UpdateNsReplica(opt *blockchain.UpdateNsReplicaOptions) error {
 	return nil
 }
 
+// UpdateNsLifecycle updates lifecycle policy of file namespace
+func (f *Fabric) UpdateNsLifecycle(opt *blockchain.UpdateNsLifecycleOptions) error {
+	s, err := json.Marshal(*opt)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal UpdateNsLifecycleOptions")
+	}
+
+	if _, err := f.InvokeContract([][]byte{s}, "UpdateNsLifecycle"); err != nil {
+		return err
+	}
+	return nil
+}
+
 // UpdateFilePublicSliceMeta is used to update file public slice metas
 func (f *Fabric) UpdateFilePublicSliceMeta(opt *blockchain.UpdateFilePSMOptions) error {
 	s, err := json.Marshal(*opt)
@@ -199,6 +247,27 @@ func (f *Fabric) ListFiles(opt *blockchain.ListFileOptions) ([]blockchain.File,
 	return fs, nil
 }
 
+// ListFileVersions lists versions of the file with the name from fabric, the latest first
+func (f *Fabric) ListFileVersions(opt *blockchain.ListFileVersionsOptions) ([]blockchain.File, error) {
+	var fs []blockchain.File
+
+	opts, err := json.Marshal(*opt)
+	if err != nil {
+		return fs, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal ListFileVersionsOptions")
+	}
+
+	s, err := f.QueryContract([][]byte{opts}, "ListFileVersions")
+	if err != nil {
+		return fs, err
+	}
+	if err = json.Unmarshal(s, &fs); err != nil {
+		return fs, errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal Files")
+	}
+
+	return fs, nil
+}
+
 // ListExpiredFiles lists expired but valid files
 // filter condition may include fileOwner, namespace, time period and items numb limit
 func (f *Fabric) ListExpiredFiles(opt *blockchain.ListFileOptions) ([]blockchain.File, error) {
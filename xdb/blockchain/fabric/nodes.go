@@ -65,18 +65,12 @@ func (f *Fabric) GetNode(id []byte) (node blockchain.Node, err error) {
 	return node, err
 }
 
-// setNodeOnlineStatus sets storage node status online/offline
-func (f *Fabric) setNodeOnlineStatus(opt *blockchain.NodeOperateOptions, online bool) error {
+// setNodeStatus sets storage node status online/offline/draining by contract method mName
+func (f *Fabric) setNodeStatus(opt *blockchain.NodeOperateOptions, mName string) error {
 	s, err := json.Marshal(*opt)
 	if err != nil {
 		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal NodeOperateOptions")
 	}
-	var mName string
-	if online {
-		mName = "NodeOnline"
-	} else {
-		mName = "NodeOffline"
-	}
 	if _, err := f.InvokeContract([][]byte{s}, mName); err != nil {
 		return err
 	}
@@ -85,12 +79,17 @@ func (f *Fabric) setNodeOnlineStatus(opt *blockchain.NodeOperateOptions, online
 
 // NodeOffline set node status on chain to offline
 func (f *Fabric) NodeOffline(opt *blockchain.NodeOperateOptions) error {
-	return f.setNodeOnlineStatus(opt, false)
+	return f.setNodeStatus(opt, "NodeOffline")
 }
 
 // NodeOnline set node status on chain to online
 func (f *Fabric) NodeOnline(opt *blockchain.NodeOperateOptions) error {
-	return f.setNodeOnlineStatus(opt, true)
+	return f.setNodeStatus(opt, "NodeOnline")
+}
+
+// NodeDrain set node status on chain to draining
+func (f *Fabric) NodeDrain(opt *blockchain.NodeOperateOptions) error {
+	return f.setNodeStatus(opt, "NodeDrain")
 }
 
 // Heartbeat updates heartbeat of storage node
@@ -155,10 +154,35 @@ func (f *Fabric) ListNodesExpireSlice(opt *blockchain.ListNodeSliceOptions) ([][
 		return sliceID2StorIndex, errorx.NewCode(err, errorx.ErrCodeInternal,
 			"failed to unmarshal Files")
 	}
-	// convert ['a,d','b,c'] to ['a','d','b','c']
-	arrs := strings.Join(sliceL, ",")
-	allSlices := strings.Split(arrs, ",")
+	return splitNodeSlices(sliceL), nil
+}
 
+// ListNodesDeletedSlice lists slices of files deleted by owners from fabric
+// returns a list of sliceID(first value of [2]string) and its StorageIndex(second value of [2]string)
+func (f *Fabric) ListNodesDeletedSlice(opt *blockchain.ListNodeSliceOptions) ([][2]string, error) {
+	var sliceL []string
+
+	opts, err := json.Marshal(*opt)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to marshal ListNodeSliceOptions")
+	}
+
+	s, err := f.QueryContract([][]byte{opts}, "ListNodesDeletedSlice")
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(s, &sliceL); err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal slices")
+	}
+	return splitNodeSlices(sliceL), nil
+}
+
+// splitNodeSlices converts ['a:1,d:2','b:3'] to [['a','1'],['d','2'],['b','3']]
+func splitNodeSlices(sliceL []string) [][2]string {
+	sliceID2StorIndex := make([][2]string, 0)
+	allSlices := strings.Split(strings.Join(sliceL, ","), ",")
 	for _, oneS := range allSlices {
 		si := strings.SplitN(oneS, ":", 2)
 		if len(si) < 2 {
@@ -167,7 +191,7 @@ func (f *Fabric) ListNodesExpireSlice(opt *blockchain.ListNodeSliceOptions) ([][
 
 		sliceID2StorIndex = append(sliceID2StorIndex, [2]string{si[0], si[1]})
 	}
-	return sliceID2StorIndex, nil
+	return sliceID2StorIndex
 }
 
 // GetNodeHealth gets storage node health status
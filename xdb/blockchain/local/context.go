@@ -0,0 +1,166 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/xuperchain/xuperchain/core/contractsdk/go/code"
+	"github.com/xuperchain/xuperchain/core/contractsdk/go/pb"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// reader reads states, it's a transaction for invocations and a snapshot for queries
+type reader interface {
+	Get(key []byte, ro *opt.ReadOptions) ([]byte, error)
+	NewIterator(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator
+}
+
+// writer writes states in a transaction, it's nil for queries
+type writer interface {
+	Put(key, value []byte, wo *opt.WriteOptions) error
+	Delete(key []byte, wo *opt.WriteOptions) error
+}
+
+// contractContext implements code.Context on leveldb for contract methods to run in process,
+// only arguments, states and events are supported, which are what the xdb contract depends on
+type contractContext struct {
+	args map[string][]byte
+	r    reader
+	w    writer
+
+	events []blockchain.Event // events emitted, published once the invocation is committed
+}
+
+func newContext(args map[string]string, r reader, w writer) *contractContext {
+	ctx := &contractContext{
+		args: make(map[string][]byte, len(args)),
+		r:    r,
+		w:    w,
+	}
+	for k, v := range args {
+		ctx.args[k] = []byte(v)
+	}
+	return ctx
+}
+
+func (c *contractContext) Args() map[string][]byte {
+	return c.args
+}
+
+func (c *contractContext) Caller() string {
+	return ""
+}
+
+func (c *contractContext) Initiator() string {
+	return ""
+}
+
+func (c *contractContext) AuthRequire() []string {
+	return nil
+}
+
+func (c *contractContext) PutObject(key []byte, value []byte) error {
+	if c.w == nil {
+		return errorx.New(errorx.ErrCodeInternal, "states are read-only in queries")
+	}
+	return c.w.Put(key, value, nil)
+}
+
+func (c *contractContext) GetObject(key []byte) ([]byte, error) {
+	return c.r.Get(key, nil)
+}
+
+func (c *contractContext) DeleteObject(key []byte) error {
+	if c.w == nil {
+		return errorx.New(errorx.ErrCodeInternal, "states are read-only in queries")
+	}
+	return c.w.Delete(key, nil)
+}
+
+func (c *contractContext) NewIterator(start, limit []byte) code.Iterator {
+	return &stateIterator{c.r.NewIterator(&util.Range{Start: start, Limit: limit}, nil)}
+}
+
+func (c *contractContext) QueryTx(txid string) (*pb.Transaction, error) {
+	return nil, errorx.New(errorx.ErrCodeInternal, "transactions are not supported in process")
+}
+
+func (c *contractContext) QueryBlock(blockid string) (*pb.Block, error) {
+	return nil, errorx.New(errorx.ErrCodeInternal, "blocks are not supported in process")
+}
+
+func (c *contractContext) Transfer(to string, amount *big.Int) error {
+	return errorx.New(errorx.ErrCodeInternal, "transfer is not supported in process")
+}
+
+func (c *contractContext) TransferAmount() (*big.Int, error) {
+	return new(big.Int), nil
+}
+
+func (c *contractContext) Call(module, contract, method string, args map[string][]byte) (*code.Response, error) {
+	return nil, errorx.New(errorx.ErrCodeInternal, "calling contracts is not supported in process")
+}
+
+func (c *contractContext) CrossQuery(uri string, args map[string][]byte) (*code.Response, error) {
+	return nil, errorx.New(errorx.ErrCodeInternal, "cross query is not supported in process")
+}
+
+func (c *contractContext) EmitEvent(name string, body []byte) error {
+	c.events = append(c.events, blockchain.Event{Name: name, Payload: body})
+	return nil
+}
+
+func (c *contractContext) EmitJSONEvent(name string, body interface{}) error {
+	s, err := json.Marshal(body)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal event body")
+	}
+	return c.EmitEvent(name, s)
+}
+
+func (c *contractContext) Logf(fmt string, args ...interface{}) {
+	logger.Debugf(fmt, args...)
+}
+
+// stateIterator implements code.Iterator, keys and values are copied as the contract may keep them
+type stateIterator struct {
+	iter iterator.Iterator
+}
+
+func (i *stateIterator) Key() []byte {
+	return append([]byte{}, i.iter.Key()...)
+}
+
+func (i *stateIterator) Value() []byte {
+	return append([]byte{}, i.iter.Value()...)
+}
+
+func (i *stateIterator) Next() bool {
+	return i.iter.Next()
+}
+
+func (i *stateIterator) Error() error {
+	return i.iter.Error()
+}
+
+func (i *stateIterator) Close() {
+	i.iter.Release()
+}
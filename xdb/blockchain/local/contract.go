@@ -0,0 +1,168 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/xuperchain/xuperchain/core/contractsdk/go/code"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+const (
+	dbName = "chainDB"
+
+	// lockTimeout is the time to wait for the leveldb locked by nodes in other processes
+	lockTimeout = time.Second * 10
+	// lockRetryInterval is the interval to retry opening the leveldb locked
+	lockRetryInterval = time.Millisecond * 10
+)
+
+var logger = logrus.WithField("module", "blockchain.local")
+
+// Contract runs a XuperChain native contract in process, and persists its states in leveldb.
+// Methods are called the way a XuperChain node calls them, states written by an invocation
+// are committed only if it succeeds, and those written by a query are discarded.
+// The leveldb is opened for each call, so that nodes in separate processes on a machine share the chain.
+type Contract struct {
+	root     string
+	contract code.Contract
+
+	lock sync.Mutex
+
+	watchers     map[*watcher]struct{} // watchers of events emitted by invocations in this process
+	watchersLock sync.Mutex
+}
+
+// NewContract creates an in-process contract whose states are saved in leveldb under root
+func NewContract(root string, contract code.Contract) (*Contract, error) {
+	if len(root) == 0 {
+		return nil, errorx.New(errorx.ErrCodeConfig, "missing leveldb-root")
+	}
+	c := &Contract{
+		root:     root,
+		contract: contract,
+		watchers: make(map[*watcher]struct{}),
+	}
+	// check the leveldb is available
+	if err := c.withDB(func(db *leveldb.DB) error { return nil }); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Invoke invokes the contract method, states are committed and events are published only if the method succeeds
+func (c *Contract) Invoke(args map[string]string, mName string) ([]byte, error) {
+	var body []byte
+	var events []blockchain.Event
+	err := c.withDB(func(db *leveldb.DB) error {
+		tx, err := db.OpenTransaction()
+		if err != nil {
+			return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to open transaction")
+		}
+		ctx := newContext(args, tx, tx)
+		if body, err = c.call(ctx, mName); err != nil {
+			tx.Discard()
+			return errorx.Wrap(err, "failed to invoke contract")
+		}
+		if err := tx.Commit(); err != nil {
+			return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to commit transaction")
+		}
+		events = ctx.events
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.publish(events)
+	return body, nil
+}
+
+// Query queries the contract method on a snapshot of states, states written are discarded
+func (c *Contract) Query(args map[string]string, mName string) ([]byte, error) {
+	var body []byte
+	err := c.withDB(func(db *leveldb.DB) error {
+		snap, err := db.GetSnapshot()
+		if err != nil {
+			return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to get snapshot")
+		}
+		defer snap.Release()
+
+		ctx := newContext(args, snap, nil)
+		if body, err = c.call(ctx, mName); err != nil {
+			return errorx.Wrap(err, "failed to query contract")
+		}
+		return nil
+	})
+	return body, err
+}
+
+// call calls the contract method by name like the XuperChain contract runner,
+// and parses errors of the errorx format from the response
+func (c *Contract) call(ctx *contractContext, mName string) (body []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errorx.New(errorx.ErrCodeInternal, "contract method %s panics: %v", mName, r)
+		}
+	}()
+
+	methodv := reflect.ValueOf(c.contract).MethodByName(strings.Title(mName))
+	if !methodv.IsValid() {
+		return nil, errorx.New(errorx.ErrCodeParam, "bad method %s", mName)
+	}
+	method, ok := methodv.Interface().(func(code.Context) code.Response)
+	if !ok {
+		return nil, errorx.New(errorx.ErrCodeParam, "bad method type %s", mName)
+	}
+	resp := method(ctx)
+	if code.IsStatusError(resp.Status) {
+		if errCode, message, ok := errorx.TryParseFromString(resp.Message); ok {
+			return nil, errorx.New(errCode, "%s", message)
+		}
+		return nil, errorx.New(errorx.ErrCodeInternal, "%s", resp.Message)
+	}
+	return resp.Body, nil
+}
+
+// withDB opens the leveldb, waiting for nodes in other processes to release it, and closes it once f returns
+func (c *Contract) withDB(f func(db *leveldb.DB) error) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	path := filepath.Join(c.root, dbName)
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		db, err := leveldb.OpenFile(path, nil)
+		if err == nil {
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.WithError(err).Warn("failed to close leveldb")
+				}
+			}()
+			return f(db)
+		}
+		if time.Now().After(deadline) {
+			return errorx.NewCode(err, errorx.ErrCodeInternal, "cannot open leveldb")
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
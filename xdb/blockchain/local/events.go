@@ -0,0 +1,73 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+)
+
+// watcherBufferSize is the number of events buffered for a watcher,
+// events are dropped if the watcher doesn't catch up, and are found by polling instead
+const watcherBufferSize = 64
+
+type watcher struct {
+	names  map[string]struct{}
+	events chan blockchain.Event
+}
+
+// Watch subscribes to events named names emitted by invocations in this process,
+// those by nodes in other processes sharing the leveldb are not observed
+func (c *Contract) Watch(ctx context.Context, names ...string) (<-chan blockchain.Event, error) {
+	w := &watcher{
+		names:  make(map[string]struct{}, len(names)),
+		events: make(chan blockchain.Event, watcherBufferSize),
+	}
+	for _, name := range names {
+		w.names[name] = struct{}{}
+	}
+
+	c.watchersLock.Lock()
+	c.watchers[w] = struct{}{}
+	c.watchersLock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		c.watchersLock.Lock()
+		defer c.watchersLock.Unlock()
+		delete(c.watchers, w)
+		close(w.events)
+	}()
+	return w.events, nil
+}
+
+// publish sends events to watchers without blocking invocations
+func (c *Contract) publish(events []blockchain.Event) {
+	c.watchersLock.Lock()
+	defer c.watchersLock.Unlock()
+
+	for _, e := range events {
+		for w := range c.watchers {
+			if _, ok := w.names[e.Name]; !ok {
+				continue
+			}
+			select {
+			case w.events <- e:
+			default:
+				logger.WithField("event", e.Name).Debug("watcher is busy, event dropped")
+			}
+		}
+	}
+}
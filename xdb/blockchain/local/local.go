@@ -0,0 +1,41 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain/xchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain/xchain/contract/core"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// chainName is the name of the local blockchain
+const chainName = "local"
+
+// New creates a blockchain client running the xdb contract in process, without any blockchain network.
+// It's for development and tests, validation and signature checks are the same as the xchain contract,
+// and nodes configured with the same leveldb root on a machine share the chain.
+func New(conf *config.LocalChainConf) (*xchain.XChain, error) {
+	if conf == nil {
+		return nil, errorx.New(errorx.ErrCodeConfig, "missing local blockchain configuration")
+	}
+	contract, err := NewContract(conf.LeveldbRoot, new(core.Xdata))
+	if err != nil {
+		return nil, errorx.Wrap(err, "failed to create local contract")
+	}
+	return &xchain.XChain{
+		ChainName: chainName,
+		Contract:  contract,
+	}, nil
+}
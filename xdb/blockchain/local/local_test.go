@@ -0,0 +1,372 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	util "github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/strings"
+)
+
+func sign(t *testing.T, privkey ecdsa.PrivateKey, opt interface{}) []byte {
+	msg, err := util.GetSigMessage(opt)
+	require.NoError(t, err)
+	sig, err := ecdsa.Sign(privkey, hash.HashUsingSha256([]byte(msg)))
+	require.NoError(t, err)
+	return sig[:]
+}
+
+func TestLocalChain(t *testing.T) {
+	root, err := ioutil.TempDir("", "localchain")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	_, err = New(&config.LocalChainConf{})
+	require.True(t, errorx.Is(err, errorx.ErrCodeConfig))
+
+	chain, err := New(&config.LocalChainConf{LeveldbRoot: root})
+	require.NoError(t, err)
+
+	privkey, pubkey, err := ecdsa.GenerateKeyPair()
+	require.NoError(t, err)
+	nodeID := []byte(pubkey.String())
+	now := time.Now().UnixNano()
+	opt := blockchain.AddNodeOptions{
+		Node: blockchain.Node{
+			ID:       nodeID,
+			Name:     "node1",
+			Address:  "127.0.0.1:8122",
+			Online:   true,
+			RegTime:  now,
+			UpdateAt: now,
+		},
+	}
+
+	// signatures are verified by the contract
+	opt.Signature = make([]byte, ecdsa.SignatureLength)
+	require.True(t, errorx.Is(chain.AddNode(&opt), errorx.ErrCodeBadSignature))
+	_, err = chain.GetNode(nodeID)
+	require.True(t, errorx.Is(err, errorx.ErrCodeNotFound))
+
+	opt.Signature = sign(t, privkey, opt)
+	require.NoError(t, chain.AddNode(&opt))
+	require.True(t, errorx.Is(chain.AddNode(&opt), errorx.ErrCodeAlreadyExists))
+
+	nodes, err := chain.ListNodes()
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	require.Equal(t, "node1", nodes[0].Name)
+	require.True(t, nodes[0].Online)
+
+	offlineOpt := blockchain.NodeOperateOptions{
+		NodeID: nodeID,
+		Nonce:  time.Now().UnixNano(),
+	}
+	offlineOpt.Signature = sign(t, privkey, offlineOpt)
+	require.NoError(t, chain.NodeOffline(&offlineOpt))
+
+	// states are persisted and shared by clients on the same leveldb
+	another, err := New(&config.LocalChainConf{LeveldbRoot: root})
+	require.NoError(t, err)
+	node, err := another.GetNode(nodeID)
+	require.NoError(t, err)
+	require.False(t, node.Online)
+
+	// unknown methods are rejected
+	_, err = chain.QueryContract(map[string]string{}, "NoSuchMethod")
+	require.True(t, errorx.Is(err, errorx.ErrCodeParam))
+}
+
+func TestWatch(t *testing.T) {
+	root, err := ioutil.TempDir("", "localchain")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	chain, err := New(&config.LocalChainConf{LeveldbRoot: root})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := chain.Watch(ctx, blockchain.EventChallengeRequest)
+	require.NoError(t, err)
+
+	privkey, pubkey, err := ecdsa.GenerateKeyPair()
+	require.NoError(t, err)
+	opt := blockchain.ChallengeRequestOptions{
+		ChallengeID:        "challenge1",
+		FileOwner:          pubkey[:],
+		TargetNode:         []byte("node1"),
+		FileID:             "file1",
+		ChallengeTime:      time.Now().UnixNano(),
+		ChallengeAlgorithm: types.MerkleChallengeAlgorithm,
+	}
+
+	// no events are published by failed invocations
+	opt.Signature = make([]byte, ecdsa.SignatureLength)
+	require.Error(t, chain.ChallengeRequest(&opt))
+	opt.Signature = sign(t, privkey, opt)
+	require.NoError(t, chain.ChallengeRequest(&opt))
+
+	e := <-events
+	require.Equal(t, blockchain.EventChallengeRequest, e.Name)
+	var ce blockchain.ChallengeEvent
+	require.NoError(t, json.Unmarshal(e.Payload, &ce))
+	require.Equal(t, "challenge1", ce.ID)
+	require.Equal(t, []byte("node1"), ce.TargetNode)
+	require.Equal(t, blockchain.ChallengeToProve, ce.Status)
+
+	// the channel is closed once ctx is done
+	cancel()
+	_, ok := <-events
+	require.False(t, ok)
+}
+
+func TestListByCursor(t *testing.T) {
+	root, err := ioutil.TempDir("", "localchain")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	chain, err := New(&config.LocalChainConf{LeveldbRoot: root})
+	require.NoError(t, err)
+
+	privkey, pubkey, err := ecdsa.GenerateKeyPair()
+	require.NoError(t, err)
+	for i, id := range []string{"c1", "c2", "c3", "c4", "c5"} {
+		opt := blockchain.ChallengeRequestOptions{
+			ChallengeID:        id,
+			FileOwner:          pubkey[:],
+			TargetNode:         []byte("node1"),
+			FileID:             "file1",
+			ChallengeTime:      int64(i + 1),
+			ChallengeAlgorithm: types.MerkleChallengeAlgorithm,
+		}
+		opt.Signature = sign(t, privkey, opt)
+		require.NoError(t, chain.ChallengeRequest(&opt))
+	}
+
+	// challenges are listed in descending order of time, page by page
+	opt := blockchain.ListChallengeOptions{
+		FileOwner:  pubkey[:],
+		TargetNode: []byte("node1"),
+		Status:     blockchain.ChallengeToProve,
+		TimeEnd:    10,
+		Limit:      2,
+	}
+	var ids []string
+	for pages := 0; ; pages++ {
+		require.True(t, pages < 3)
+		cs, err := chain.ListChallengeRequests(&opt)
+		require.NoError(t, err)
+		for _, c := range cs {
+			ids = append(ids, c.ID)
+		}
+		if opt.Cursor = blockchain.NextChallengeCursor(cs, opt.Limit); opt.Cursor == "" {
+			break
+		}
+	}
+	require.Equal(t, []string{"c5", "c4", "c3", "c2", "c1"}, ids)
+
+	// cursors out of the list are rejected
+	opt.TargetNode = []byte("node2")
+	opt.Cursor = blockchain.Cursor{ID: "c1", Time: 1, Owner: pubkey[:], Target: []byte("node1")}.String()
+	_, err = chain.ListChallengeRequests(&opt)
+	require.True(t, errorx.Is(err, errorx.ErrCodeParam))
+	opt.Cursor = "not a cursor"
+	_, err = chain.ListChallengeRequests(&opt)
+	require.True(t, errorx.Is(err, errorx.ErrCodeParam))
+}
+
+func TestSharedSlices(t *testing.T) {
+	root, err := ioutil.TempDir("", "localchain")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	chain, err := New(&config.LocalChainConf{LeveldbRoot: root})
+	require.NoError(t, err)
+
+	privkey, pubkey, err := ecdsa.GenerateKeyPair()
+	require.NoError(t, err)
+	_, nodePubkey, err := ecdsa.GenerateKeyPair()
+	require.NoError(t, err)
+	nodeID := []byte(nodePubkey.String())
+
+	nsOpt := blockchain.AddNsOptions{
+		Namespace: blockchain.Namespace{Name: "ns", Owner: pubkey[:], Replica: 1, CreateTime: 1},
+	}
+	nsOpt.Signature = sign(t, privkey, nsOpt)
+	require.NoError(t, chain.AddFileNs(&nsOpt))
+
+	publish := func(id string, expireTime int64, slices ...blockchain.PublicSliceMeta) {
+		for i := range slices {
+			slices[i].NodeID = nodeID
+			slices[i].StorIndex = "i" + slices[i].ID
+		}
+		opt := blockchain.PublishFileOptions{File: blockchain.File{
+			ID:          id,
+			Name:        id,
+			Namespace:   "ns",
+			Owner:       pubkey[:],
+			Slices:      slices,
+			PublishTime: 1,
+			ExpireTime:  expireTime,
+		}}
+		opt.Signature = sign(t, privkey, opt)
+		_, err := chain.PublishFile(&opt)
+		require.NoError(t, err)
+	}
+	// f2 references s1 stored by f1
+	publish("f1", 100, blockchain.PublicSliceMeta{ID: "s1"}, blockchain.PublicSliceMeta{ID: "s2"})
+	publish("f2", 200, blockchain.PublicSliceMeta{ID: "s1", SourceFileID: "f1"}, blockchain.PublicSliceMeta{ID: "s3"})
+	publish("f3", 120, blockchain.PublicSliceMeta{ID: "s4"})
+
+	listExpired := func(endTime, limit int64) [][2]string {
+		ss, err := chain.ListNodesExpireSlice(&blockchain.ListNodeSliceOptions{
+			Target: nodeID, EndTime: endTime, Limit: limit})
+		require.NoError(t, err)
+		return ss
+	}
+	// s1 is not expired while f2 referencing it is not
+	require.Equal(t, [][2]string{{"s2", "is2"}, {"s4", "is4"}}, listExpired(150, 0))
+	require.Equal(t, [][2]string{{"s2", "is2"}}, listExpired(150, 1))
+
+	// s1 is kept after f2 is deleted, as f1 storing it is not cleared yet
+	dopt := blockchain.DeleteFileOptions{FileID: "f2", CurrentTime: 130}
+	dopt.Signature = sign(t, privkey, dopt)
+	require.NoError(t, chain.DeleteFile(&dopt))
+	ss, err := chain.ListNodesDeletedSlice(&blockchain.ListNodeSliceOptions{Target: nodeID, EndTime: 140})
+	require.NoError(t, err)
+	require.Equal(t, [][2]string{{"s3", "is3"}}, ss)
+	require.Equal(t, [][2]string{{"s1", "is1"}, {"s2", "is2"}, {"s4", "is4"}}, listExpired(150, 0))
+
+	// extending the expire time of f1 keeps its slices
+	uopt := blockchain.UpdateExptimeOptions{FileID: "f1", NewExpireTime: 300, CurrentTime: 140}
+	uopt.Signature = sign(t, privkey, uopt)
+	_, err = chain.UpdateFileExpireTime(&uopt)
+	require.NoError(t, err)
+	require.Equal(t, [][2]string{{"s4", "is4"}}, listExpired(150, 0))
+}
+
+func TestUpdateFileKey(t *testing.T) {
+	root, err := ioutil.TempDir("", "localchain")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	chain, err := New(&config.LocalChainConf{LeveldbRoot: root})
+	require.NoError(t, err)
+	privkey, pubkey, err := ecdsa.GenerateKeyPair()
+	require.NoError(t, err)
+
+	nsOpt := blockchain.AddNsOptions{
+		Namespace: blockchain.Namespace{Name: "ns", Owner: pubkey[:], Replica: 1, CreateTime: 1},
+	}
+	nsOpt.Signature = sign(t, privkey, nsOpt)
+	require.NoError(t, chain.AddFileNs(&nsOpt))
+	slices := []blockchain.PublicSliceMeta{{ID: "s1", NodeID: []byte("n1"), StorIndex: "i1", KeyID: "2021"}}
+	opt := blockchain.PublishFileOptions{File: blockchain.File{
+		ID: "f1", Name: "f1", Namespace: "ns", Owner: pubkey[:], Slices: slices,
+		PublishTime: 1, ExpireTime: time.Now().Add(time.Hour).UnixNano(), KeyID: "2021",
+	}}
+	opt.Signature = sign(t, privkey, opt)
+	_, err = chain.PublishFile(&opt)
+	require.NoError(t, err)
+
+	update := func(opt blockchain.UpdateFilePSMOptions) blockchain.File {
+		opt.Signature = sign(t, privkey, opt)
+		require.NoError(t, chain.UpdateFilePublicSliceMeta(&opt))
+		f, err := chain.GetFileByID("f1")
+		require.NoError(t, err)
+		return f
+	}
+	// the key of the file is kept when only slices are updated
+	slices[0].KeyID = "2022"
+	f := update(blockchain.UpdateFilePSMOptions{FileID: "f1", Owner: pubkey[:], Slices: slices})
+	require.Equal(t, slices, f.Slices)
+	require.Equal(t, "2021", f.KeyID)
+	require.Empty(t, f.WrappedKey)
+
+	f = update(blockchain.UpdateFilePSMOptions{FileID: "f1", Owner: pubkey[:], Slices: slices,
+		KeyID: "2022", WrappedKey: []byte("wrapped")})
+	require.Equal(t, "2022", f.KeyID)
+	require.Equal(t, []byte("wrapped"), f.WrappedKey)
+}
+
+func TestFileVersionsOfPrefixedNames(t *testing.T) {
+	root, err := ioutil.TempDir("", "localchain")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	chain, err := New(&config.LocalChainConf{LeveldbRoot: root})
+	require.NoError(t, err)
+	privkey, pubkey, err := ecdsa.GenerateKeyPair()
+	require.NoError(t, err)
+
+	nsOpt := blockchain.AddNsOptions{
+		Namespace: blockchain.Namespace{Name: "ns", Owner: pubkey[:], Replica: 1, CreateTime: 1},
+	}
+	nsOpt.Signature = sign(t, privkey, nsOpt)
+	require.NoError(t, chain.AddFileNs(&nsOpt))
+	expireTime := time.Now().Add(time.Hour).UnixNano()
+	publish := func(id, name string) blockchain.File {
+		opt := blockchain.PublishFileOptions{File: blockchain.File{
+			ID: id, Name: name, Namespace: "ns", Owner: pubkey[:],
+			Slices:      []blockchain.PublicSliceMeta{{ID: "s-" + id, NodeID: []byte("n1"), StorIndex: "i-" + id}},
+			PublishTime: time.Now().UnixNano(), ExpireTime: expireTime,
+		}}
+		opt.Signature = sign(t, privkey, opt)
+		f, err := chain.PublishFile(&opt)
+		require.NoError(t, err)
+		return f
+	}
+
+	// versions of a name are not mixed with those of names it prefixes
+	require.Equal(t, int64(1), publish("f1", "a/b").Version)
+	require.Equal(t, int64(2), publish("f2", "a/b").Version)
+	f := publish("f3", "a")
+	require.Equal(t, int64(1), f.Version)
+	require.Empty(t, f.PrevID)
+	require.Equal(t, int64(2), publish("f4", "a").Version)
+
+	list := func(name string) []string {
+		files, err := chain.ListFileVersions(&blockchain.ListFileVersionsOptions{
+			Owner: pubkey[:], Namespace: "ns", Name: name, CurrentTime: time.Now().UnixNano(),
+		})
+		require.NoError(t, err)
+		var ids []string
+		for _, f := range files {
+			ids = append(ids, f.ID)
+		}
+		return ids
+	}
+	require.Equal(t, []string{"f4", "f3"}, list("a"))
+	require.Equal(t, []string{"f2", "f1"}, list("a/b"))
+
+	f, err = chain.GetFileByVersion(pubkey[:], "ns", "a", 1)
+	require.NoError(t, err)
+	require.Equal(t, "f3", f.ID)
+	f, err = chain.GetFileByVersion(pubkey[:], "ns", "a/b", 1)
+	require.NoError(t, err)
+	require.Equal(t, "f1", f.ID)
+}
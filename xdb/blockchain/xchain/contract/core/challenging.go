@@ -49,9 +49,15 @@ func (x *Xdata) ListChallengeRequests(ctx code.Context) code.Response {
 
 	// pack prefix
 	prefix := packChallengeFilter(opt.FileOwner, opt.TargetNode)
+	start, limit, err := listRange(prefix, opt.Cursor, func(c blockchain.Cursor) string {
+		return packChallengeIndexByCursor(opt.FileOwner, opt.TargetNode, c)
+	})
+	if err != nil {
+		return code.Error(err)
+	}
 
-	// get iter by prefix
-	iter := ctx.NewIterator(code.PrefixRange([]byte(prefix)))
+	// get iter by prefix, after the cursor
+	iter := ctx.NewIterator(start, limit)
 	defer iter.Close()
 
 	var cs []blockchain.Challenge
@@ -80,7 +86,7 @@ func (x *Xdata) ListChallengeRequests(ctx code.Context) code.Response {
 		cs = append(cs, c)
 	}
 
-	s, err := json.Marshal(cs)
+	s, err = json.Marshal(cs)
 	if err != nil {
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
 			"failed to marshal Challenges"))
@@ -168,6 +174,15 @@ func (x *Xdata) ChallengeRequest(ctx code.Context) code.Response {
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain,
 			"failed to set index4Target-ChallengeID on xchain"))
 	}
+	// emit the event for watchers
+	if err = emitEvent(ctx, blockchain.EventChallengeRequest, blockchain.ChallengeEvent{
+		ID:         c.ID,
+		FileOwner:  c.FileOwner,
+		TargetNode: c.TargetNode,
+		Status:     c.Status,
+	}); err != nil {
+		return code.Error(err)
+	}
 
 	return code.OK([]byte("requested"))
 }
@@ -275,6 +290,15 @@ func (x *Xdata) ChallengeAnswer(ctx code.Context) code.Response {
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain,
 			"failed to update ChallengeID-Challenge on xchain"))
 	}
+	// emit the event for watchers
+	if err = emitEvent(ctx, blockchain.EventChallengeAnswer, blockchain.ChallengeEvent{
+		ID:         c.ID,
+		FileOwner:  c.FileOwner,
+		TargetNode: c.TargetNode,
+		Status:     c.Status,
+	}); err != nil {
+		return code.Error(err)
+	}
 
 	if c.Status == blockchain.ChallengeProved {
 		return code.OK([]byte("answered"))
@@ -49,6 +49,11 @@ func (x *Xdata) PublishFileAuthApplication(ctx code.Context) code.Response {
 		return code.Error(err)
 	}
 
+	// deleted files can not be authorized
+	if _, err := ctx.GetObject([]byte(packFileTombstoneIndex(fa.FileID))); err == nil {
+		return code.Error(errorx.New(errorx.ErrCodeNotFound, "file already deleted"))
+	}
+
 	fa.Status = blockchain.FileAuthUnapproved
 	// marshal fileAuthApplication
 	s, err = json.Marshal(fa)
@@ -83,6 +88,16 @@ func (x *Xdata) PublishFileAuthApplication(ctx code.Context) code.Response {
 	if err := ctx.PutObject([]byte(authListIndex), []byte(fa.ID)); err != nil {
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to set index_fileauth_list_applier_authorizer on chain"))
 	}
+	// emit the event for watchers
+	if err := emitEvent(ctx, blockchain.EventFileAuth, blockchain.FileAuthEvent{
+		ID:         fa.ID,
+		FileID:     fa.FileID,
+		Applier:    fa.Applier,
+		Authorizer: fa.Authorizer,
+		Status:     fa.Status,
+	}); err != nil {
+		return code.Error(err)
+	}
 
 	return code.OK([]byte("OK"))
 }
@@ -148,9 +163,50 @@ func (x *Xdata) setFileAuthConfirmStatus(ctx code.Context, isConfirm bool) code.
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain,
 			"fail to confirm index_fileauth on xchain"))
 	}
+	// emit the event for watchers
+	if err := emitEvent(ctx, blockchain.EventFileAuth, blockchain.FileAuthEvent{
+		ID:         fa.ID,
+		FileID:     fa.FileID,
+		Applier:    fa.Applier,
+		Authorizer: fa.Authorizer,
+		Status:     fa.Status,
+	}); err != nil {
+		return code.Error(err)
+	}
 	return code.OK([]byte("OK"))
 }
 
+// invalidateFileAuths sets the unapproved authorization applications of a deleted file as Invalid
+func (x *Xdata) invalidateFileAuths(ctx code.Context, f blockchain.File, ctime int64) error {
+	iter := ctx.NewIterator(code.PrefixRange([]byte(packFileAuthFilter(nil, f.Owner))))
+	var fas []blockchain.FileAuthApplication
+	for iter.Next() {
+		fa, err := x.getFileAuthByID(ctx, string(iter.Value()))
+		if err != nil {
+			iter.Close()
+			return err
+		}
+		if fa.FileID == f.ID && fa.Status == blockchain.FileAuthUnapproved {
+			fas = append(fas, fa)
+		}
+	}
+	iter.Close()
+
+	for _, fa := range fas {
+		fa.Status = blockchain.FileAuthInvalid
+		fa.RejectReason = "file deleted"
+		fa.ApprovalTime = ctime
+		s, err := json.Marshal(fa)
+		if err != nil {
+			return errorx.NewCode(err, errorx.ErrCodeInternal, "fail to marshal FileAuthApplication")
+		}
+		if err := ctx.PutObject([]byte(packFileAuthIndex(fa.ID)), s); err != nil {
+			return errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "fail to invalidate index_fileauth on xchain")
+		}
+	}
+	return nil
+}
+
 // getFileAuthByID query file's authorization application by authID
 func (x *Xdata) getFileAuthByID(ctx code.Context, authID string) (fa blockchain.FileAuthApplication, err error) {
 	index := packFileAuthIndex(authID)
@@ -181,9 +237,15 @@ func (x *Xdata) ListFileAuthApplications(ctx code.Context) code.Response {
 			"failed to unmarshal ListFileAuthOptions"))
 	}
 	prefix := packFileAuthFilter(opt.Applier, opt.Authorizer)
+	start, limit, err := listRange(prefix, opt.Cursor, func(c blockchain.Cursor) string {
+		return packFileAuthIndexByCursor(opt.Applier, opt.Authorizer, c)
+	})
+	if err != nil {
+		return code.Error(err)
+	}
 
-	// get iter by prefix
-	iter := ctx.NewIterator(code.PrefixRange([]byte(prefix)))
+	// get iter by prefix, after the cursor
+	iter := ctx.NewIterator(start, limit)
 	defer iter.Close()
 
 	var fas blockchain.FileAuthApplications
@@ -208,7 +270,7 @@ func (x *Xdata) ListFileAuthApplications(ctx code.Context) code.Response {
 		fas = append(fas, &fa)
 	}
 
-	s, err := json.Marshal(fas)
+	s, err = json.Marshal(fas)
 	if err != nil {
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
 			"failed to marshal FileAuthApplications"))
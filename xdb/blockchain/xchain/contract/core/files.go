@@ -73,18 +73,18 @@ func (x *Xdata) PublishFile(ctx code.Context) code.Response {
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
 			"failed to unmarshal namespace"))
 	}
+	// the redundancy mode of file must match its namespace
+	if f.DataShards != ns.DataShards || f.ParityShards != ns.ParityShards {
+		return code.Error(errorx.New(errorx.ErrCodeParam, "file shards mismatch namespace"))
+	}
 
-	// judge if filenameIndex exists
-	filenameIndex := packFileNameIndex(f.Owner, f.Namespace, f.Name)
-	if id, err := ctx.GetObject([]byte(filenameIndex)); err == nil {
-		fc, err := x.getFileByID(ctx, id)
-		if err != nil {
-			return code.Error(err)
-		}
-		if fc.ExpireTime+blockchain.FileRetainPeriod.Nanoseconds() > opt.File.PublishTime {
-			return code.Error(errorx.New(errorx.ErrCodeAlreadyExists, "duplicated file name"))
-		}
+	// if there's already a file with the same name in user's storage, the new one becomes its next version
+	version, prevID, err := x.getLatestFileVersion(ctx, f.Owner, f.Namespace, f.Name)
+	if err != nil {
+		return code.Error(err)
 	}
+	f.Version = version + 1
+	f.PrevID = prevID
 
 	// marshal file
 	s, err = json.Marshal(f)
@@ -92,15 +92,20 @@ func (x *Xdata) PublishFile(ctx code.Context) code.Response {
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal File"))
 	}
 
-	// if there's already a file with the same name in user's storage, overwrite it with the new one directly
 	// set id-file on chain
 	if err := ctx.PutObject([]byte(f.ID), s); err != nil {
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to set id-file on chain"))
 	}
-	// set filenameIndex-id on chain
+	// set filenameIndex-id on chain, it always points to the latest version
+	filenameIndex := packFileNameIndex(f.Owner, f.Namespace, f.Name)
 	if err := ctx.PutObject([]byte(filenameIndex), []byte(f.ID)); err != nil {
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to set index-id on chain"))
 	}
+	// set fileVersionIndex-id on chain
+	fileVersionIndex := packFileVersionIndex(f.Owner, f.Namespace, f.Name, f.Version)
+	if err := ctx.PutObject([]byte(fileVersionIndex), []byte(f.ID)); err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to set versionIndex-id on chain"))
+	}
 	// set filenameListIndex-id on chain
 	filenameListIndex := packFileListByOwnerIndex(f.Owner, f.Namespace, f.Name, f.PublishTime)
 	if err := ctx.PutObject([]byte(filenameListIndex), []byte(f.ID)); err != nil {
@@ -140,8 +145,33 @@ func (x *Xdata) PublishFile(ctx code.Context) code.Response {
 			return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to set index-id on chain"))
 		}
 	}
+	if err := x.updateSliceRefs(ctx, f, f.ExpireTime); err != nil {
+		return code.Error(err)
+	}
 
-	return code.OK([]byte("Published"))
+	return code.OK(s)
+}
+
+// getLatestFileVersion returns the latest version of the file name and its file ID, deleted versions included,
+// so that version numbers are never reused. 0 is returned if no file has the name.
+// A file published before versioning is taken as version 1, and its version index is set here
+func (x *Xdata) getLatestFileVersion(ctx code.Context, owner []byte, ns, name string) (int64, string, error) {
+	iter := ctx.NewIterator(code.PrefixRange([]byte(packFileVersionFilter(owner, ns, name))))
+	if iter.Next() {
+		version, id := getFileVersion(iter.Key()), string(iter.Value())
+		iter.Close()
+		return version, id, nil
+	}
+	iter.Close()
+
+	id, err := ctx.GetObject([]byte(packFileNameIndex(owner, ns, name)))
+	if err != nil {
+		return 0, "", nil
+	}
+	if err := ctx.PutObject([]byte(packFileVersionIndex(owner, ns, name, 1)), id); err != nil {
+		return 0, "", errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to set versionIndex-id on chain")
+	}
+	return 1, string(id), nil
 }
 
 // AddFileNs adds file namespace
@@ -174,6 +204,12 @@ func (x *Xdata) AddFileNs(ctx code.Context) code.Response {
 		return code.Error(err)
 	}
 
+	// erasure coded namespace stores each shard only once
+	if ns.DataShards < 0 || ns.ParityShards < 0 || (ns.DataShards > 0) != (ns.ParityShards > 0) ||
+		(ns.ParityShards > 0 && ns.Replica != 1) {
+		return code.Error(errorx.New(errorx.ErrCodeParam, "bad param:shards"))
+	}
+
 	// judge if fileNsIndex exists
 	fileNsIndex := packFileNsIndex(ns.Owner, ns.Name)
 	// get file ns
@@ -227,7 +263,7 @@ func (x *Xdata) UpdateNsReplica(ctx code.Context) code.Response {
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
 			"failed to unmarshal namespace"))
 	}
-	if n.Replica >= opt.Replica {
+	if n.ErasureCoded() || opt.Replica <= 0 || n.Replica == opt.Replica {
 		return code.Error(errorx.New(errorx.ErrCodeParam, "bad param:replica"))
 	}
 
@@ -249,6 +285,61 @@ func (x *Xdata) UpdateNsReplica(ctx code.Context) code.Response {
 	return code.OK([]byte("OK"))
 }
 
+// UpdateNsLifecycle updates lifecycle policy of file namespace
+func (x *Xdata) UpdateNsLifecycle(ctx code.Context) code.Response {
+	// get opt
+	o, ok := ctx.Args()["opt"]
+	if !ok {
+		return code.Error(errorx.New(errorx.ErrCodeParam, "missing param:opt"))
+	}
+	// unmarshal opt
+	var opt blockchain.UpdateNsLifecycleOptions
+	if err := json.Unmarshal(o, &opt); err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal UpdateNsLifecycleOptions"))
+	}
+	// get the message to sign
+	msg, err := util.GetSigMessage(opt)
+	if err != nil {
+		return code.Error(errorx.Internal(err, "failed to get the message to sign"))
+	}
+	if err := x.checkSign(opt.Signature, opt.Owner, []byte(msg)); err != nil {
+		return code.Error(err)
+	}
+	if opt.Lifecycle != nil && !opt.Lifecycle.Valid() {
+		return code.Error(errorx.New(errorx.ErrCodeParam, "bad param:lifecycle"))
+	}
+
+	//get file ns
+	fileNsIndex := packFileNsIndex(opt.Owner, opt.Name)
+	nsr, err := ctx.GetObject([]byte(fileNsIndex))
+	if err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeNotFound, "file namespace not found"))
+	}
+	var n blockchain.Namespace
+	if err = json.Unmarshal(nsr, &n); err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal namespace"))
+	}
+
+	n.Lifecycle = opt.Lifecycle
+	n.UpdateTime = opt.CurrentTime
+	s, err := json.Marshal(n)
+	if err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal Namespaces"))
+	}
+	// put index-ns on chain
+	if err := ctx.PutObject([]byte(fileNsIndex), s); err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to update nsindex-lifecycle on chain"))
+	}
+	// put listIndex-ns on chain
+	nsListIndex := packFileNsListIndex(n.Owner, n.Name, n.CreateTime)
+	if err := ctx.PutObject([]byte(nsListIndex), s); err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to update nsListIndex-lifecycle on chain"))
+	}
+	return code.OK([]byte("OK"))
+}
+
 // UpdateFilePublicSliceMeta is used to update file public slice metas
 func (x *Xdata) UpdateFilePublicSliceMeta(ctx code.Context) code.Response {
 	// get opt
@@ -283,8 +374,12 @@ func (x *Xdata) UpdateFilePublicSliceMeta(ctx code.Context) code.Response {
 	if string(f.Owner) != string(opt.Owner) {
 		return code.Error(errorx.New(errorx.ErrCodeNotAuthorized, "bad param, file owner is wrong"))
 	}
-	// update slices
+	// update slices, and the key of the file if it's rekeyed
 	f.Slices = opt.Slices
+	if len(opt.WrappedKey) != 0 {
+		f.KeyID = opt.KeyID
+		f.WrappedKey = opt.WrappedKey
+	}
 	nfs, err := json.Marshal(f)
 	if err != nil {
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal Namespaces"))
@@ -322,8 +417,17 @@ func (x *Xdata) GetFileByName(ctx code.Context) code.Response {
 	if _, err := ctx.GetObject([]byte(fileNsIndex)); err != nil {
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeParam, "bad param, ns not found"))
 	}
-	// pack filenameindex
+	// pack filenameindex, the latest version is got if version is not given
 	index := packFileNameIndex(owner, string(ns), string(name))
+	if v, ok := ctx.Args()["version"]; ok {
+		version, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil || version < 0 {
+			return code.Error(errorx.New(errorx.ErrCodeParam, "bad param:version"))
+		}
+		if version > 0 {
+			index = packFileVersionIndex(owner, string(ns), string(name), version)
+		}
+	}
 	// get id from index
 	id, err := ctx.GetObject([]byte(index))
 	if err != nil {
@@ -332,6 +436,9 @@ func (x *Xdata) GetFileByName(ctx code.Context) code.Response {
 	// get file from id
 	s, err := ctx.GetObject(id)
 	if err != nil {
+		if _, terr := ctx.GetObject([]byte(packFileTombstoneIndex(string(id)))); terr == nil {
+			return code.Error(errorx.New(errorx.ErrCodeNotFound, "file already deleted"))
+		}
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeNotFound, "file not found"))
 	}
 
@@ -360,6 +467,9 @@ func (x *Xdata) GetFileByID(ctx code.Context) code.Response {
 	// get file from id
 	fs, err := ctx.GetObject(id)
 	if err != nil {
+		if _, terr := ctx.GetObject([]byte(packFileTombstoneIndex(string(id)))); terr == nil {
+			return code.Error(errorx.New(errorx.ErrCodeNotFound, "file already deleted"))
+		}
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeNotFound, "file not found"))
 	}
 	var f blockchain.File
@@ -408,6 +518,9 @@ func (x *Xdata) UpdateFileExpireTime(ctx code.Context) code.Response {
 		return code.Error(err)
 	}
 
+	if err := x.updateSliceRefs(ctx, f, opt.NewExpireTime); err != nil {
+		return code.Error(err)
+	}
 	// marshal file
 	f.ExpireTime = opt.NewExpireTime
 	nf, err := json.Marshal(f)
@@ -421,6 +534,247 @@ func (x *Xdata) UpdateFileExpireTime(ctx code.Context) code.Response {
 	return code.OK(nf)
 }
 
+// DeleteFile deletes a file by its owner before it expires, a tombstone is left on chain.
+// Slices of the file are moved from node slice index to node deleted slice index,
+// so that storage nodes can remove them at once rather than after the file retain period.
+func (x *Xdata) DeleteFile(ctx code.Context) code.Response {
+	// get DeleteFileOptions
+	s, ok := ctx.Args()["opt"]
+	if !ok {
+		return code.Error(errorx.New(errorx.ErrCodeParam, "missing param:opt"))
+	}
+	// unmarshal opt
+	var opt blockchain.DeleteFileOptions
+	if err := json.Unmarshal(s, &opt); err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal DeleteFileOptions"))
+	}
+	// get file from id
+	f, err := x.getFileByID(ctx, []byte(opt.FileID))
+	if err != nil {
+		return code.Error(err)
+	}
+	// verify sig
+	msg, err := util.GetSigMessage(opt)
+	if err != nil {
+		return code.Error(errorx.Internal(err, "failed to get the message to sign"))
+	}
+	if err := x.checkSign(opt.Signature, f.Owner, []byte(msg)); err != nil {
+		return code.Error(err)
+	}
+
+	// put tombstone on chain
+	t, err := json.Marshal(blockchain.FileTombstone{
+		FileID:     f.ID,
+		Owner:      f.Owner,
+		Namespace:  f.Namespace,
+		Name:       f.Name,
+		DeleteTime: opt.CurrentTime,
+	})
+	if err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal FileTombstone"))
+	}
+	if err := ctx.PutObject([]byte(packFileTombstoneIndex(f.ID)), t); err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to set tombstone on chain"))
+	}
+
+	// remove id-file and file indexes from chain
+	if err := ctx.DeleteObject([]byte(f.ID)); err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to delete id-file on chain"))
+	}
+	// if the latest version is deleted, the name points to the latest version left.
+	// Version index is kept, so that a deleted version is reported rather than taken by a new file
+	filenameIndex := packFileNameIndex(f.Owner, f.Namespace, f.Name)
+	if id, err := ctx.GetObject([]byte(filenameIndex)); err == nil && string(id) == f.ID {
+		if err := x.resetLatestFileVersion(ctx, f); err != nil {
+			return code.Error(err)
+		}
+	}
+	filenameListIndex := packFileListByOwnerIndex(f.Owner, f.Namespace, f.Name, f.PublishTime)
+	if err := ctx.DeleteObject([]byte(filenameListIndex)); err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to delete file listIndex-id on chain"))
+	}
+	fileListByNsIndex := packFileListByNsIndex(f.Owner, f.Namespace, f.Name, f.PublishTime)
+	if err := ctx.DeleteObject([]byte(fileListByNsIndex)); err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to delete file fileListByNsIndex-id on chain"))
+	}
+
+	// move node-sliceID-expireTime to node-sliceID-deleteTime
+	nodeSlice := make(map[string][]string)
+	for _, slice := range f.Slices {
+		nodeSlice[string(slice.NodeID)] = append(nodeSlice[string(slice.NodeID)], slice.ID+":"+slice.StorIndex)
+	}
+	for nodeID, sliceL := range nodeSlice {
+		if err := x.deleteNodeSliceIndex(ctx, nodeID, f.ID); err != nil {
+			return code.Error(err)
+		}
+		index := packNodeDeletedSliceIndex(nodeID, opt.CurrentTime, f.ID)
+		if err := ctx.PutObject([]byte(index), []byte(strings.Join(sliceL, ","))); err != nil {
+			return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to set node deleted slice index on chain"))
+		}
+	}
+
+	if err := x.updateSliceRefs(ctx, f, 0); err != nil {
+		return code.Error(err)
+	}
+
+	// update file num of fileNsIndex
+	fileNsIndex := packFileNsIndex(f.Owner, f.Namespace)
+	nsr, err := ctx.GetObject([]byte(fileNsIndex))
+	if err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeNotFound, "file namespace not found"))
+	}
+	var ns blockchain.Namespace
+	if err = json.Unmarshal(nsr, &ns); err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal namespace"))
+	}
+	ns.FileTotalNum -= 1
+	ns.UpdateTime = opt.CurrentTime
+	nsf, err := json.Marshal(ns)
+	if err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal File namespace"))
+	}
+	if err := ctx.PutObject([]byte(fileNsIndex), nsf); err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to update index-ns on chain"))
+	}
+	nsListIndex := packFileNsListIndex(ns.Owner, ns.Name, ns.CreateTime)
+	if err := ctx.PutObject([]byte(nsListIndex), nsf); err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to update listIndex-ns on chain"))
+	}
+
+	// authorization applications not yet approved can never be approved
+	if err := x.invalidateFileAuths(ctx, f, opt.CurrentTime); err != nil {
+		return code.Error(err)
+	}
+	return code.OK([]byte("OK"))
+}
+
+// resetLatestFileVersion points the name of a deleted file to the latest version left,
+// the name index is removed if all versions are deleted
+func (x *Xdata) resetLatestFileVersion(ctx code.Context, f blockchain.File) error {
+	filenameIndex := packFileNameIndex(f.Owner, f.Namespace, f.Name)
+	iter := ctx.NewIterator(code.PrefixRange([]byte(packFileVersionFilter(f.Owner, f.Namespace, f.Name))))
+	var latest []byte
+	for iter.Next() {
+		id := iter.Value()
+		if string(id) == f.ID {
+			continue
+		}
+		if _, err := ctx.GetObject(id); err == nil {
+			latest = append([]byte{}, id...)
+			break
+		}
+	}
+	iter.Close()
+
+	if latest == nil {
+		if err := ctx.DeleteObject([]byte(filenameIndex)); err != nil {
+			return errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to delete index-id on chain")
+		}
+		return nil
+	}
+	if err := ctx.PutObject([]byte(filenameIndex), latest); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to set index-id on chain")
+	}
+	return nil
+}
+
+// deleteNodeSliceIndex removes the node slice index of a file,
+// the index is looked up by file ID, as the expire time in index key may be updated
+func (x *Xdata) deleteNodeSliceIndex(ctx code.Context, nodeID, fileID string) error {
+	iter := ctx.NewIterator(code.PrefixRange([]byte(packNodeSliceFilter(nodeID))))
+	var keys [][]byte
+	for iter.Next() {
+		if id, _ := getNodeSliceFileID(iter.Key()); id == fileID {
+			keys = append(keys, append([]byte{}, iter.Key()...))
+		}
+	}
+	iter.Close()
+
+	for _, key := range keys {
+		if err := ctx.DeleteObject(key); err != nil {
+			return errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to delete node slice index on chain")
+		}
+	}
+	return nil
+}
+
+// updateSliceRefs sets the expire time of the file in the reference index of its slices,
+// the file is removed from the index if expireTime is 0
+func (x *Xdata) updateSliceRefs(ctx code.Context, f blockchain.File, expireTime int64) error {
+	seen := make(map[string]bool)
+	for _, slice := range f.Slices {
+		if seen[slice.ID] {
+			continue
+		}
+		seen[slice.ID] = true
+
+		refs, err := x.getSliceRefs(ctx, slice.ID)
+		if err != nil {
+			return err
+		}
+		if expireTime > 0 {
+			refs[f.ID] = expireTime
+		} else {
+			delete(refs, f.ID)
+		}
+
+		index := []byte(packSliceRefIndex(slice.ID))
+		if len(refs) == 0 {
+			if err := ctx.DeleteObject(index); err != nil {
+				return errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to delete slice reference index on chain")
+			}
+			continue
+		}
+		r, err := json.Marshal(refs)
+		if err != nil {
+			return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal slice references")
+		}
+		if err := ctx.PutObject(index, r); err != nil {
+			return errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to set slice reference index on chain")
+		}
+	}
+	return nil
+}
+
+// getSliceRefs returns expire time of files referencing the slice by file ID,
+// it is empty for slices of files published before the reference index
+func (x *Xdata) getSliceRefs(ctx code.Context, sliceID string) (map[string]int64, error) {
+	refs := make(map[string]int64)
+	r, err := ctx.GetObject([]byte(packSliceRefIndex(sliceID)))
+	if err != nil {
+		return refs, nil
+	}
+	if err := json.Unmarshal(r, &refs); err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal slice references")
+	}
+	return refs, nil
+}
+
+// unreferencedSlices returns slices in the value of node slice index which are not referenced by any file
+// still alive, as told by alive with the expire time of the file
+func (x *Xdata) unreferencedSlices(ctx code.Context, value string, alive func(expireTime int64) bool) (string, error) {
+	var kept []string
+	for _, s := range strings.Split(value, ",") {
+		refs, err := x.getSliceRefs(ctx, strings.SplitN(s, ":", 2)[0])
+		if err != nil {
+			return "", err
+		}
+		referenced := false
+		for _, expireTime := range refs {
+			if alive(expireTime) {
+				referenced = true
+				break
+			}
+		}
+		if !referenced {
+			kept = append(kept, s)
+		}
+	}
+	return strings.Join(kept, ","), nil
+}
+
 // SliceMigrateRecord is used by node to slice migration record
 func (x *Xdata) SliceMigrateRecord(ctx code.Context) code.Response {
 	// get SliceMigrateOptions
@@ -480,9 +834,15 @@ func (x *Xdata) ListFiles(ctx code.Context) code.Response {
 	}
 	// pack prefix
 	prefix := packFileNameFilter(opt.Owner, opt.Namespace)
+	start, limit, err := listRange(prefix, opt.Cursor, func(c blockchain.Cursor) string {
+		return packFileListIndexByCursor(opt.Owner, opt.Namespace, c)
+	})
+	if err != nil {
+		return code.Error(err)
+	}
 
-	// get iter by prefix
-	iter := ctx.NewIterator(code.PrefixRange([]byte(prefix)))
+	// get iter by prefix, after the cursor
+	iter := ctx.NewIterator(start, limit)
 	defer iter.Close()
 
 	// iterate iter
@@ -501,6 +861,54 @@ func (x *Xdata) ListFiles(ctx code.Context) code.Response {
 		fs = append(fs, f)
 	}
 
+	s, err = json.Marshal(fs)
+	if err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to marshal Files"))
+	}
+	return code.OK(s)
+}
+
+// ListFileVersions lists versions of the file with the name, from the latest to the earliest,
+// deleted and expired versions are excluded
+func (x *Xdata) ListFileVersions(ctx code.Context) code.Response {
+	// get opt
+	s, ok := ctx.Args()["opt"]
+	if !ok {
+		return code.Error(errorx.New(errorx.ErrCodeParam, "missing param:opt"))
+	}
+	// unmarshal opt
+	var opt blockchain.ListFileVersionsOptions
+	if err := json.Unmarshal(s, &opt); err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal ListFileVersionsOptions"))
+	}
+
+	iter := ctx.NewIterator(code.PrefixRange([]byte(packFileVersionFilter(opt.Owner, opt.Namespace, opt.Name))))
+	defer iter.Close()
+
+	var fs []blockchain.File
+	for iter.Next() {
+		if opt.Limit > 0 && int64(len(fs)) >= opt.Limit {
+			break
+		}
+		f, err := x.getFileByID(ctx, iter.Value())
+		if err != nil {
+			if errorx.Is(err, errorx.ErrCodeNotFound) {
+				continue
+			}
+			return code.Error(err)
+		}
+		if f.ExpireTime <= opt.CurrentTime {
+			continue
+		}
+		// files published before versioning
+		if f.Version == 0 {
+			f.Version = getFileVersion(iter.Key())
+		}
+		fs = append(fs, f)
+	}
+
 	s, err := json.Marshal(fs)
 	if err != nil {
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
@@ -524,9 +932,15 @@ func (x *Xdata) ListExpiredFiles(ctx code.Context) code.Response {
 	}
 	// pack prefix
 	prefix := packFileNameFilter(opt.Owner, opt.Namespace)
+	start, limit, err := listRange(prefix, opt.Cursor, func(c blockchain.Cursor) string {
+		return packFileListIndexByCursor(opt.Owner, opt.Namespace, c)
+	})
+	if err != nil {
+		return code.Error(err)
+	}
 
-	// get iter by prefix
-	iter := ctx.NewIterator(code.PrefixRange([]byte(prefix)))
+	// get iter by prefix, after the cursor
+	iter := ctx.NewIterator(start, limit)
 	defer iter.Close()
 
 	// iterate iter
@@ -548,7 +962,7 @@ func (x *Xdata) ListExpiredFiles(ctx code.Context) code.Response {
 		fs = append(fs, f)
 	}
 
-	s, err := json.Marshal(fs)
+	s, err = json.Marshal(fs)
 	if err != nil {
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
 			"failed to marshal Files"))
@@ -19,7 +19,10 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/xuperchain/xuperchain/core/contractsdk/go/code"
+
 	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
 )
 
 const (
@@ -29,6 +32,8 @@ const (
 	prefixFileListByNsIndex    = "index_fs_list"
 	prefixFileNsIndex          = "index_fns"
 	prefixFileNsListIndex      = "index_fns_list"
+	prefixFileTombstone        = "index_ftomb"
+	prefixFileVersionIndex     = "index_fv"
 	// Define the contract prefix key of file authorization application operations
 	prefixFileAuthIndex           = "index_fileauth"
 	prefixFileAuthApplierIndex    = "index_fa_applier"
@@ -44,7 +49,9 @@ const (
 	prefixNodeHeartbeatIndex    = "index_hbnode"
 	prefixNodeSliceMigrateIndex = "index_slicemigrate"
 	prefixNodeFileSlice         = "index_fslice"
+	prefixNodeDeletedSlice      = "index_fdslice"
 	prefixNodeNonceIndex        = "index_ndnonce"
+	prefixSliceRefIndex         = "index_sliceref"
 )
 
 func packNodeIndex(nodeID []byte) string {
@@ -71,6 +78,14 @@ func packNodeSliceFilter(target string) string {
 	return fmt.Sprintf("%s/%s/", prefixNodeFileSlice, target)
 }
 
+func packNodeDeletedSliceIndex(node string, deleteTime int64, fileID string) string {
+	return fmt.Sprintf("%s/%s/%d/%s", prefixNodeDeletedSlice, node, deleteTime, fileID)
+}
+
+func packNodeDeletedSliceFilter(target string) string {
+	return fmt.Sprintf("%s/%s/", prefixNodeDeletedSlice, target)
+}
+
 // getNodeSliceFileID return fileID and file's expireTime by contract key
 // Example: string(key) = index_fslice/nodeID/expireTime/fileID
 func getNodeSliceFileID(key []byte) (string, int64) {
@@ -82,6 +97,11 @@ func getNodeSliceFileID(key []byte) (string, int64) {
 	return strArr[len(strArr)-1], expireTime
 }
 
+// packSliceRefIndex used to find files referencing a slice, a slice is shared by deduplicated files
+func packSliceRefIndex(sliceID string) string {
+	return fmt.Sprintf("%s/%s", prefixSliceRefIndex, sliceID)
+}
+
 func packNodeSliceMigrateIndex(target string, ctime int64) string {
 	return fmt.Sprintf("%s/%s/%d", prefixNodeSliceMigrateIndex, target, subByInt64Max(ctime))
 }
@@ -114,6 +134,31 @@ func packFileListByNsIndex(owner []byte, ns, name string, pubTime int64) string
 	return fmt.Sprintf("%s/%s/%x/%d/%s", prefixFileListByNsIndex, ns, owner, subByInt64Max(pubTime), name)
 }
 
+// packFileVersionIndex used to get file by version, versions of a file are iterated from the latest.
+// ns and name are hex encoded, so that versions of a name are not mixed with those of names prefixed by it
+func packFileVersionIndex(owner []byte, ns, name string, version int64) string {
+	return fmt.Sprintf("%s%d", packFileVersionFilter(owner, ns, name), subByInt64Max(version))
+}
+
+func packFileVersionFilter(owner []byte, ns, name string) string {
+	return fmt.Sprintf("%s/%x/%x/%x/", prefixFileVersionIndex, owner, ns, name)
+}
+
+// getFileVersion return file version by contract key
+// Example: string(key) = index_fv/owner/hex(ns)/hex(name)/version
+func getFileVersion(key []byte) int64 {
+	strArr := strings.Split(string(key), "/")
+	version, err := strconv.ParseInt(strArr[len(strArr)-1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return subByInt64Max(version)
+}
+
+func packFileTombstoneIndex(fileID string) string {
+	return fmt.Sprintf("%s/%s", prefixFileTombstone, fileID)
+}
+
 func packFileNsIndex(owner []byte, ns string) string {
 	return fmt.Sprintf("%s/%x/%s", prefixFileNsIndex, owner, ns)
 }
@@ -145,6 +190,14 @@ func packFileNameFilter(owner []byte, ns string) string {
 	return filter
 }
 
+// packFileListIndexByCursor returns the list index of the file at the cursor, in the list filtered by packFileNameFilter
+func packFileListIndexByCursor(owner []byte, ns string, c blockchain.Cursor) string {
+	if len(owner) == 0 && len(ns) > 0 {
+		return packFileListByNsIndex(c.Owner, c.Namespace, c.Name, c.Time)
+	}
+	return packFileListByOwnerIndex(c.Owner, c.Namespace, c.Name, c.Time)
+}
+
 // packFileAuthIndex define the contract key for file authorization application
 func packFileAuthIndex(id string) string {
 	return fmt.Sprintf("%s/%s", prefixFileAuthIndex, id)
@@ -175,6 +228,16 @@ func packFileAuthFilter(applier, authorizer []byte) string {
 	}
 }
 
+// packFileAuthIndexByCursor returns the list index of the application at the cursor, in the list filtered by packFileAuthFilter
+func packFileAuthIndexByCursor(applier, authorizer []byte, c blockchain.Cursor) string {
+	if len(applier) > 0 && len(authorizer) > 0 {
+		return packApplierAndAuthorizerIndex(c.Owner, c.Target, c.ID, c.Time)
+	} else if len(applier) > 0 {
+		return packFileAuthApplierIndex(c.Owner, c.ID, c.Time)
+	}
+	return packFileAuthAuthorizerIndex(c.Target, c.ID, c.Time)
+}
+
 func packChallengeIndex(id string) string {
 	return fmt.Sprintf("%s/%s", prefixChallenge, id)
 }
@@ -207,7 +270,42 @@ func packChallengeFilter(owner, target []byte) string {
 	return prefix
 }
 
+// packChallengeIndexByCursor returns the list index of the challenge at the cursor, in the list filtered by packChallengeFilter
+func packChallengeIndexByCursor(owner, target []byte, cur blockchain.Cursor) string {
+	c := &blockchain.Challenge{ID: cur.ID, FileOwner: cur.Owner, TargetNode: cur.Target, ChallengeTime: cur.Time}
+	if len(owner) == 0 && len(target) > 0 {
+		return packChallengeIndex4Target(c)
+	}
+	return packChallengeIndex4Owner(c)
+}
+
+// listRange returns the range of list indexes with the prefix, starting after the index of the item at the cursor,
+// which is built by index, so that lists are continued from where the previous page ends
+func listRange(prefix, cursor string, index func(blockchain.Cursor) string) ([]byte, []byte, error) {
+	start, limit := code.PrefixRange([]byte(prefix))
+	if cursor == "" {
+		return start, limit, nil
+	}
+	c, err := blockchain.ParseCursor(cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := index(c)
+	if !strings.HasPrefix(key, prefix) {
+		return nil, nil, errorx.New(errorx.ErrCodeParam, "invalid cursor, not in the list")
+	}
+	return append([]byte(key), 0), limit, nil
+}
+
 // return maxInt64 - N
 func subByInt64Max(n int64) int64 {
 	return math.MaxInt64 - n
 }
+
+// emitEvent emits an event with json encoded payload for watchers, see blockchain.ChainWatcher
+func emitEvent(ctx code.Context, name string, payload interface{}) error {
+	if err := ctx.EmitJSONEvent(name, payload); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeWriteBlockchain, "failed to emit event %s", name)
+	}
+	return nil
+}
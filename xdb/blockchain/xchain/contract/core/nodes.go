@@ -123,18 +123,46 @@ func (x *Xdata) GetNode(ctx code.Context) code.Response {
 	return code.OK(s)
 }
 
-// NodeOffline gets node offline
+// NodeOffline gets node offline, a draining node is kept draining
 func (x *Xdata) NodeOffline(ctx code.Context) code.Response {
-	return x.setNodeOnlineStatus(ctx, false)
+	return x.setNodeStatus(ctx, func(node *blockchain.Node) (bool, error) {
+		if !node.Online {
+			return false, nil
+		}
+		node.Online = false
+		return true, nil
+	})
 }
 
-// NodeOnline gets node online
+// NodeOnline gets node online, and stops draining it
 func (x *Xdata) NodeOnline(ctx code.Context) code.Response {
-	return x.setNodeOnlineStatus(ctx, true)
+	return x.setNodeStatus(ctx, func(node *blockchain.Node) (bool, error) {
+		if node.Online && !node.Draining {
+			return false, nil
+		}
+		node.Online = true
+		node.Draining = false
+		return true, nil
+	})
+}
+
+// NodeDrain starts draining an online node, no new slices are placed on it
+// and its slices are migrated to other nodes
+func (x *Xdata) NodeDrain(ctx code.Context) code.Response {
+	return x.setNodeStatus(ctx, func(node *blockchain.Node) (bool, error) {
+		if !node.Online {
+			return false, errorx.New(errorx.ErrCodeParam, "node is offline")
+		}
+		if node.Draining {
+			return false, nil
+		}
+		node.Draining = true
+		return true, nil
+	})
 }
 
-// setNodeOnlineStatus sets node status
-func (x *Xdata) setNodeOnlineStatus(ctx code.Context, online bool) code.Response {
+// setNodeStatus sets node status by the function set, which returns true if the status is changed
+func (x *Xdata) setNodeStatus(ctx code.Context, set func(node *blockchain.Node) (bool, error)) code.Response {
 	s, ok := ctx.Args()["opt"]
 	if !ok {
 		return code.Error(errorx.New(errorx.ErrCodeParam, "missing param:opt"))
@@ -175,9 +203,11 @@ func (x *Xdata) setNodeOnlineStatus(ctx code.Context, online bool) code.Response
 			"failed to unmarshal node"))
 	}
 
-	if node.Online != online {
-		// change status
-		node.Online = online
+	changed, err := set(&node)
+	if err != nil {
+		return code.Error(err)
+	}
+	if changed {
 		// marshal new node
 		newn, err := json.Marshal(node)
 		if err != nil {
@@ -261,6 +291,11 @@ func (x *Xdata) Heartbeat(ctx code.Context) code.Response {
 
 	// update node heartbeat time
 	node.UpdateAt = opt.CurrentTime
+	// update node capacity if reported
+	if opt.Capacity > 0 {
+		node.Capacity = opt.Capacity
+		node.FreeSpace = opt.FreeSpace
+	}
 	newn, err := json.Marshal(node)
 	if err != nil {
 		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal node"))
@@ -334,18 +369,30 @@ func (x *Xdata) ListNodesExpireSlice(ctx code.Context) code.Response {
 	iter := ctx.NewIterator(code.PrefixRange([]byte(prefix)))
 	defer iter.Close()
 
-	// iterate iter
+	// iterate iter, keys are ordered by expire time. A slice shared by deduplicated files
+	// is not expired while any file referencing it has not expired
 	var sl []string
 	for iter.Next() {
+		if opt.Limit > 0 && int64(len(sl)) >= opt.Limit {
+			break
+		}
 		_, expireTime := getNodeSliceFileID(iter.Key())
-		if (opt.Limit > 0 && int64(len(sl)) >= opt.Limit) || expireTime == 0 {
+		if expireTime == 0 || expireTime > opt.EndTime {
 			break
 		}
-		if expireTime < opt.StartTime || expireTime > opt.EndTime {
+		if expireTime < opt.StartTime {
 			continue
 		}
 
-		sl = append(sl, string(iter.Value()))
+		v, err := x.unreferencedSlices(ctx, string(iter.Value()), func(t int64) bool {
+			return t > opt.EndTime
+		})
+		if err != nil {
+			return code.Error(err)
+		}
+		if v != "" {
+			sl = append(sl, v)
+		}
 	}
 	rs, err := json.Marshal(sl)
 	if err != nil {
@@ -355,6 +402,63 @@ func (x *Xdata) ListNodesExpireSlice(ctx code.Context) code.Response {
 	return code.OK(rs)
 }
 
+// ListNodesDeletedSlice lists slices of files deleted by owners from xchain, by the time of deletion
+func (x *Xdata) ListNodesDeletedSlice(ctx code.Context) code.Response {
+	// get opt
+	s, ok := ctx.Args()["opt"]
+	if !ok {
+		return code.Error(errorx.New(errorx.ErrCodeParam, "missing param:opt"))
+	}
+	// unmarshal opt
+	var opt blockchain.ListNodeSliceOptions
+	if err := json.Unmarshal(s, &opt); err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal ListNodeSlice"))
+	}
+	pubkey, err := hex.DecodeString(string(opt.Target))
+	if err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeParam, "wrong target node"))
+	}
+	if len(pubkey) != ecdsa.PublicKeyLength ||
+		opt.StartTime < 0 || opt.EndTime <= 0 || opt.EndTime <= opt.StartTime {
+		return code.Error(errorx.New(errorx.ErrCodeParam, "bad param"))
+	}
+
+	// keys are ordered by the time of deletion. A slice shared by deduplicated files
+	// is kept while any other file referencing it is not cleared
+	var sl []string
+	iter := ctx.NewIterator(code.PrefixRange([]byte(packNodeDeletedSliceFilter(string(opt.Target)))))
+	defer iter.Close()
+	for iter.Next() {
+		if opt.Limit > 0 && int64(len(sl)) >= opt.Limit {
+			break
+		}
+		_, deleteTime := getNodeSliceFileID(iter.Key())
+		if deleteTime > opt.EndTime {
+			break
+		}
+		if deleteTime < opt.StartTime {
+			continue
+		}
+
+		v, err := x.unreferencedSlices(ctx, string(iter.Value()), func(t int64) bool {
+			return t+blockchain.FileRetainPeriod.Nanoseconds() > opt.EndTime
+		})
+		if err != nil {
+			return code.Error(err)
+		}
+		if v != "" {
+			sl = append(sl, v)
+		}
+	}
+	rs, err := json.Marshal(sl)
+	if err != nil {
+		return code.Error(errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to marshal slices"))
+	}
+	return code.OK(rs)
+}
+
 // GetSliceMigrateRecords queries node slice migration records
 func (x *Xdata) GetSliceMigrateRecords(ctx code.Context) code.Response {
 	// get opt
@@ -0,0 +1,85 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xchain
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/xuperchain/xuper-sdk-go/pb"
+	"github.com/xuperchain/xuper-sdk-go/subscribe"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// Watch subscribes to events named names emitted by the contract in new blocks,
+// the contract runs in process is watched if it's a blockchain.ChainWatcher
+func (x *XChain) Watch(ctx context.Context, names ...string) (<-chan blockchain.Event, error) {
+	if x.Contract != nil {
+		if w, ok := x.Contract.(blockchain.ChainWatcher); ok {
+			return w.Watch(ctx, names...)
+		}
+		return nil, errorx.New(errorx.ErrCodeInternal, "events are not supported by the contract")
+	}
+
+	filter, err := subscribe.NewBlockFilter(x.ChainName, subscribe.WithContract(x.ContractName))
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to create block filter")
+	}
+	buf, err := proto.Marshal(filter)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal block filter")
+	}
+	// the stream is closed once ctx is done
+	stream, err := x.XchainClient.EventClient.Subscribe(ctx, &pb.SubscribeRequest{
+		Type:   pb.SubscribeType_BLOCK,
+		Filter: buf,
+	})
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeReadBlockchain, "failed to subscribe to block events")
+	}
+
+	watched := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		watched[name] = struct{}{}
+	}
+	events := make(chan blockchain.Event)
+	go func() {
+		defer close(events)
+		for {
+			e, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			var block pb.FilteredBlock
+			if err := proto.Unmarshal(e.Payload, &block); err != nil {
+				return
+			}
+			for _, tx := range block.Txs {
+				for _, ce := range tx.Events {
+					if _, ok := watched[ce.Name]; !ok || ce.Contract != x.ContractName {
+						continue
+					}
+					select {
+					case events <- blockchain.Event{Name: ce.Name, Payload: ce.Body}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	return events, nil
+}
@@ -22,21 +22,26 @@ import (
 	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
 )
 
-// PublishFile publishes file on xchain
-func (x *XChain) PublishFile(opt *blockchain.PublishFileOptions) error {
+// PublishFile publishes file on xchain, the file published is returned with its version
+func (x *XChain) PublishFile(opt *blockchain.PublishFileOptions) (blockchain.File, error) {
+	var file blockchain.File
 	s, err := json.Marshal(*opt)
 	if err != nil {
-		return errorx.NewCode(err, errorx.ErrCodeInternal,
+		return file, errorx.NewCode(err, errorx.ErrCodeInternal,
 			"failed to marshal PublishFileOptions")
 	}
 	args := map[string]string{
 		"opt": string(s),
 	}
 	mName := "PublishFile"
-	if _, err = x.InvokeContract(args, mName); err != nil {
-		return err
+	resp, err := x.InvokeContract(args, mName)
+	if err != nil {
+		return file, err
 	}
-	return nil
+	if err = json.Unmarshal(resp, &file); err != nil {
+		return file, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal File")
+	}
+	return file, nil
 }
 
 // GetFileByName gets file by name from xchain
@@ -61,6 +66,29 @@ func (x *XChain) GetFileByName(owner []byte, ns, name string) (blockchain.File,
 	return f, nil
 }
 
+// GetFileByVersion gets file by name and version from xchain
+func (x *XChain) GetFileByVersion(owner []byte, ns, name string, version int64) (blockchain.File, error) {
+	var f blockchain.File
+	args := map[string]string{
+		"owner":       string(owner),
+		"ns":          ns,
+		"name":        name,
+		"version":     strconv.FormatInt(version, 10),
+		"currentTime": strconv.FormatInt(time.Now().UnixNano(), 10),
+	}
+	mName := "GetFileByName"
+	s, err := x.QueryContract(args, mName)
+	if err != nil {
+		return f, err
+	}
+	if err = json.Unmarshal([]byte(s), &f); err != nil {
+		return f, errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal File")
+	}
+
+	return f, nil
+}
+
 // GetFileByID gets file by id from xchain
 func (x *XChain) GetFileByID(id string) (blockchain.File, error) {
 	var f blockchain.File
@@ -104,6 +132,22 @@ func (x *XChain) UpdateFileExpireTime(opt *blockchain.UpdateExptimeOptions) (blo
 	return file, nil
 }
 
+// DeleteFile deletes file by its owner and leaves a tombstone on chain
+func (x *XChain) DeleteFile(opt *blockchain.DeleteFileOptions) error {
+	s, err := json.Marshal(*opt)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal DeleteFileOptions")
+	}
+	args := map[string]string{
+		"opt": string(s),
+	}
+	mName := "DeleteFile"
+	if _, err := x.InvokeContract(args, mName); err != nil {
+		return err
+	}
+	return nil
+}
+
 // AddFileNs adds file namespace
 func (x *XChain) AddFileNs(opt *blockchain.AddNsOptions) error {
 	s, err := json.Marshal(*opt)
@@ -138,6 +182,23 @@ func (x *XChain) UpdateNsReplica(opt *blockchain.UpdateNsReplicaOptions) error {
 	return nil
 }
 
+// UpdateNsLifecycle updates lifecycle policy of file namespace
+func (x *XChain) UpdateNsLifecycle(opt *blockchain.UpdateNsLifecycleOptions) error {
+	s, err := json.Marshal(*opt)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to marshal UpdateNsLifecycleOptions")
+	}
+	args := map[string]string{
+		"opt": string(s),
+	}
+	mName := "UpdateNsLifecycle"
+	if _, err := x.InvokeContract(args, mName); err != nil {
+		return err
+	}
+	return nil
+}
+
 // UpdateFilePublicSliceMeta is used to update file public slice metas
 func (x *XChain) UpdateFilePublicSliceMeta(opt *blockchain.UpdateFilePSMOptions) error {
 	s, err := json.Marshal(*opt)
@@ -241,6 +302,30 @@ func (x *XChain) ListFiles(opt *blockchain.ListFileOptions) ([]blockchain.File,
 	return fs, nil
 }
 
+// ListFileVersions lists versions of the file with the name from xchain, the latest first
+func (x *XChain) ListFileVersions(opt *blockchain.ListFileVersionsOptions) ([]blockchain.File, error) {
+	var fs []blockchain.File
+
+	opts, err := json.Marshal(*opt)
+	if err != nil {
+		return fs, errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to marshal ListFileVersionsOptions")
+	}
+	args := map[string]string{
+		"opt": string(opts),
+	}
+	mName := "ListFileVersions"
+	s, err := x.QueryContract(args, mName)
+	if err != nil {
+		return fs, err
+	}
+	if err = json.Unmarshal(s, &fs); err != nil {
+		return fs, errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal Files")
+	}
+	return fs, nil
+}
+
 // ListExpiredFiles lists expired but valid files
 // filter condition may include fileOwner, namespace, time period and items numb limit
 func (x *XChain) ListExpiredFiles(opt *blockchain.ListFileOptions) ([]blockchain.File, error) {
@@ -74,8 +74,8 @@ func (x *XChain) GetNode(id []byte) (node blockchain.Node, err error) {
 	return node, err
 }
 
-// setNodeOnlineStatus sets storage node status online/offline
-func (x *XChain) setNodeOnlineStatus(opt *blockchain.NodeOperateOptions, online bool) error {
+// setNodeStatus sets storage node status online/offline/draining by contract method mName
+func (x *XChain) setNodeStatus(opt *blockchain.NodeOperateOptions, mName string) error {
 	s, err := json.Marshal(*opt)
 	if err != nil {
 		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal NodeOperateOptions")
@@ -83,12 +83,6 @@ func (x *XChain) setNodeOnlineStatus(opt *blockchain.NodeOperateOptions, online
 	args := map[string]string{
 		"opt": string(s),
 	}
-	var mName string
-	if online {
-		mName = "NodeOnline"
-	} else {
-		mName = "NodeOffline"
-	}
 	if _, err := x.InvokeContract(args, mName); err != nil {
 		return err
 	}
@@ -97,12 +91,17 @@ func (x *XChain) setNodeOnlineStatus(opt *blockchain.NodeOperateOptions, online
 
 // NodeOffline set node status on chain to offline
 func (x *XChain) NodeOffline(opt *blockchain.NodeOperateOptions) error {
-	return x.setNodeOnlineStatus(opt, false)
+	return x.setNodeStatus(opt, "NodeOffline")
 }
 
 // NodeOnline set node status on chain to online
 func (x *XChain) NodeOnline(opt *blockchain.NodeOperateOptions) error {
-	return x.setNodeOnlineStatus(opt, true)
+	return x.setNodeStatus(opt, "NodeOnline")
+}
+
+// NodeDrain set node status on chain to draining
+func (x *XChain) NodeDrain(opt *blockchain.NodeOperateOptions) error {
+	return x.setNodeStatus(opt, "NodeDrain")
 }
 
 // Heartbeat updates heartbeat of storage node
@@ -181,10 +180,38 @@ func (x *XChain) ListNodesExpireSlice(opt *blockchain.ListNodeSliceOptions) ([][
 		return sliceID2StorIndex, errorx.NewCode(err, errorx.ErrCodeInternal,
 			"failed to unmarshal Files")
 	}
-	// convert ['a,d','b,c'] to ['a','d','b','c']
-	arrs := strings.Join(sliceL, ",")
-	allSlices := strings.Split(arrs, ",")
+	return splitNodeSlices(sliceL), nil
+}
+
+// ListNodesDeletedSlice lists slices of files deleted by owners from xchain
+// returns a list of sliceID(first value of [2]string) and its StorageIndex(second value of [2]string)
+func (x *XChain) ListNodesDeletedSlice(opt *blockchain.ListNodeSliceOptions) ([][2]string, error) {
+	var sliceL []string
+
+	opts, err := json.Marshal(*opt)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to marshal ListNodeSliceOptions")
+	}
+	args := map[string]string{
+		"opt": string(opts),
+	}
+	mName := "ListNodesDeletedSlice"
+	s, err := x.QueryContract(args, mName)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(s, &sliceL); err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal slices")
+	}
+	return splitNodeSlices(sliceL), nil
+}
 
+// splitNodeSlices converts ['a:1,d:2','b:3'] to [['a','1'],['d','2'],['b','3']]
+func splitNodeSlices(sliceL []string) [][2]string {
+	sliceID2StorIndex := make([][2]string, 0)
+	allSlices := strings.Split(strings.Join(sliceL, ","), ",")
 	for _, oneS := range allSlices {
 		si := strings.SplitN(oneS, ":", 2)
 		if len(si) < 2 {
@@ -193,7 +220,7 @@ func (x *XChain) ListNodesExpireSlice(opt *blockchain.ListNodeSliceOptions) ([][
 
 		sliceID2StorIndex = append(sliceID2StorIndex, [2]string{si[0], si[1]})
 	}
-	return sliceID2StorIndex, nil
+	return sliceID2StorIndex
 }
 
 // GetNodeHealth gets storage node health status
@@ -0,0 +1,60 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xchain
+
+import (
+	"encoding/json"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// ListTaskFiles lists files of the owner used by DAI tasks in progress, which are published
+// on the contract shared with XuperDB in a DAI network, and listed by the executors involved
+func (x *XChain) ListTaskFiles(owner []byte) (map[string]struct{}, error) {
+	s, err := x.QueryContract(map[string]string{}, "ListExecutorNodes")
+	if err != nil {
+		return nil, err
+	}
+	var executors []struct {
+		ID []byte `json:"id"`
+	}
+	if err = json.Unmarshal(s, &executors); err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal,
+			"failed to unmarshal executor nodes")
+	}
+
+	var tasks []blockchain.Task
+	for _, e := range executors {
+		opt, err := json.Marshal(blockchain.ListTaskOptions{ExecPubKey: e.ID})
+		if err != nil {
+			return nil, errorx.NewCode(err, errorx.ErrCodeInternal,
+				"failed to marshal ListTaskOptions")
+		}
+		args := map[string]string{
+			"opt": string(opt),
+		}
+		s, err := x.QueryContract(args, "ListTask")
+		if err != nil {
+			return nil, err
+		}
+		var ts []blockchain.Task
+		if err = json.Unmarshal(s, &ts); err != nil {
+			return nil, errorx.NewCode(err, errorx.ErrCodeInternal,
+				"failed to unmarshal tasks")
+		}
+		tasks = append(tasks, ts...)
+	}
+	return blockchain.TaskFiles(tasks, owner), nil
+}
@@ -26,12 +26,20 @@ import (
 	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
 )
 
+// Contract invokes and queries contract methods in process instead of on a XuperChain network
+//  see more from blockchain.local
+type Contract interface {
+	Invoke(args map[string]string, mName string) ([]byte, error)
+	Query(args map[string]string, mName string) ([]byte, error)
+}
+
 type XChain struct {
 	ContractName    string              // ContractName is name of contract
 	ContractAccount string              // ContractAccount is a contract account
 	ChainName       string              // ChainName is name of blockchain
 	Account         *account.Account    // Account is the local account, and is also the client account to request blockchain
 	XchainClient    *xchain.XuperClient // XchainClient is the util used to connect and request blockchain
+	Contract        Contract            // Contract runs the contract in process if set, and XchainClient is not used
 }
 
 // New creates a XChain client which is used for connecting and requesting blockchain
@@ -74,6 +82,9 @@ func New(conf *config.XchainConf) (*XChain, error) {
 
 // InvokeContract invokes the contract
 func (x *XChain) InvokeContract(args map[string]string, mName string) ([]byte, error) {
+	if x.Contract != nil {
+		return x.Contract.Invoke(args, mName)
+	}
 	// initiate client for native contract
 	nativeContract := contract.InitNativeContractWithClient(
 		x.Account, x.ChainName, x.ContractName, x.ContractAccount, x.XchainClient)
@@ -103,6 +114,9 @@ func (x *XChain) InvokeContract(args map[string]string, mName string) ([]byte, e
 
 // QueryContract queries the contract
 func (x *XChain) QueryContract(args map[string]string, mName string) ([]byte, error) {
+	if x.Contract != nil {
+		return x.Contract.Query(args, mName)
+	}
 	// initiate client for native contract
 	nativeContract := contract.InitNativeContractWithClient(
 		x.Account, x.ChainName, x.ContractName, x.ContractAccount, x.XchainClient)
@@ -127,6 +141,9 @@ func (x *XChain) QueryContract(args map[string]string, mName string) ([]byte, er
 
 // GetRootAndLatestBlockIdInChain gets latest block height
 func (x *XChain) GetRootAndLatestBlockIdInChain() ([]byte, int64, error) {
+	if x.Contract != nil {
+		return nil, 0, errorx.New(errorx.ErrCodeInternal, "no block in process")
+	}
 	systemStatus, err := x.XchainClient.XchainClient.GetSystemStatus(context.Background(), &pb.CommonIn{})
 	if err != nil {
 		return nil, 0, err
@@ -19,6 +19,7 @@ import (
 	"net/url"
 	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
@@ -81,6 +82,9 @@ func (c *Client) Write(ctx context.Context, r io.Reader, opt WriteOptions) (
 		"ext":        opt.Extra,
 		"expireTime": strconv.FormatInt(opt.ExpireTime, 10),
 	}
+	if opt.Compression != "" {
+		reqParams["compression"] = opt.Compression
+	}
 	msg, err := util.GetSigMessage(reqParams)
 	if err != nil {
 		return servertypes.WriteResponse{}, errorx.Internal(err, "failed to get the message to sign")
@@ -114,6 +118,9 @@ func (c *Client) Read(ctx context.Context, opt ReadOptions) (io.ReadCloser, erro
 		"file_id":   opt.FileID,
 		"timestamp": strconv.FormatInt(time.Now().UnixNano(), 10),
 	}
+	if opt.Version > 0 {
+		reqParams["version"] = strconv.FormatInt(opt.Version, 10)
+	}
 	msg, err := util.GetSigMessage(reqParams)
 	if err != nil {
 		return nil, errorx.Internal(err, "failed to get the message to sign")
@@ -123,6 +130,13 @@ func (c *Client) Read(ctx context.Context, opt ReadOptions) (io.ReadCloser, erro
 		return nil, errorx.Wrap(err, "failed to sign")
 	}
 	reqParams["token"] = sig.String()
+	// range is not signed
+	if opt.Offset > 0 {
+		reqParams["offset"] = strconv.FormatUint(opt.Offset, 10)
+	}
+	if opt.Length > 0 {
+		reqParams["length"] = strconv.FormatUint(opt.Length, 10)
+	}
 
 	url := c.getRequestsUrl([]string{"file", "read"}, reqParams)
 	reader, err := httpkg.Get(ctx, url.String())
@@ -192,8 +206,38 @@ func (c *Client) GetNodeHealth(ctx context.Context, id string) (string, error) {
 	return status, nil
 }
 
-// setNodeOnlineStatus set storage node status online/offline
-func (c *Client) setNodeOnlineStatus(ctx context.Context, privateKey string, online bool) error {
+// GetSliceUsage get the storage used by a dataOwner node on the storage node and its quota
+func (c *Client) GetSliceUsage(ctx context.Context, owner string) (servertypes.SliceUsageResponse, error) {
+	var usage servertypes.SliceUsageResponse
+	url := c.getRequestsUrl([]string{"slice", "usage"}, map[string]string{"owner": owner})
+	if err := httpkg.GetResponse(ctx, url.String(), &usage); err != nil {
+		return usage, err
+	}
+	return usage, nil
+}
+
+// GetScrubReports get slices of a dataOwner node found corrupt or missing by scrubbing on the storage node
+func (c *Client) GetScrubReports(ctx context.Context, owner string) ([]servertypes.SliceScrubResponse, error) {
+	var reports []servertypes.SliceScrubResponse
+	url := c.getRequestsUrl([]string{"slice", "scrub"}, map[string]string{"owner": owner})
+	if err := httpkg.GetResponse(ctx, url.String(), &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// GetOrphanSlices get slices on the storage node not referenced by any file on blockchain
+func (c *Client) GetOrphanSlices(ctx context.Context) ([]servertypes.SliceOrphanResponse, error) {
+	var orphans []servertypes.SliceOrphanResponse
+	url := c.getRequestsUrl([]string{"slice", "orphans"}, nil)
+	if err := httpkg.GetResponse(ctx, url.String(), &orphans); err != nil {
+		return nil, err
+	}
+	return orphans, nil
+}
+
+// setNodeStatus set storage node status online/offline/draining by the operation op
+func (c *Client) setNodeStatus(ctx context.Context, privateKey string, op string) error {
 	private, err := ecdsa.DecodePrivateKeyFromString(privateKey)
 	if err != nil {
 		return err
@@ -213,12 +257,7 @@ func (c *Client) setNodeOnlineStatus(ctx context.Context, privateKey string, onl
 	}
 	reqParams["token"] = sig.String()
 
-	var url url.URL
-	if online {
-		url = c.getRequestsUrl([]string{"node", "online"}, reqParams)
-	} else {
-		url = c.getRequestsUrl([]string{"node", "offline"}, reqParams)
-	}
+	url := c.getRequestsUrl([]string{"node", op}, reqParams)
 	if _, err := httpkg.Post(ctx, url.String(), nil); err != nil {
 		return err
 	}
@@ -227,22 +266,29 @@ func (c *Client) setNodeOnlineStatus(ctx context.Context, privateKey string, onl
 
 // NodeOffline set storage node status offline
 func (c *Client) NodeOffline(ctx context.Context, privkey string) error {
-	return c.setNodeOnlineStatus(ctx, privkey, false)
+	return c.setNodeStatus(ctx, privkey, "offline")
 }
 
 // NodeOnline set storage node status online
 func (c *Client) NodeOnline(ctx context.Context, privkey string) error {
-	return c.setNodeOnlineStatus(ctx, privkey, true)
+	return c.setNodeStatus(ctx, privkey, "online")
+}
+
+// NodeDrain set storage node status draining, slices on the node are migrated to other nodes,
+// and the node is set offline once empty
+func (c *Client) NodeDrain(ctx context.Context, privkey string) error {
+	return c.setNodeStatus(ctx, privkey, "drain")
 }
 
-// ListFiles list unexpired files
-func (c *Client) ListFiles(ctx context.Context, opt ListFileOptions, isExpired bool) ([]blockchain.File, error) {
+// ListFiles list unexpired files, returns a page of files and the cursor of the next page
+func (c *Client) ListFiles(ctx context.Context, opt ListFileOptions, isExpired bool) (servertypes.FilePageResponse, error) {
 	reqParams := map[string]string{
-		"owner": opt.Owner,
-		"ns":    opt.Namespace,
-		"start": strconv.FormatInt(opt.TimeStart, 10),
-		"end":   strconv.FormatInt(opt.TimeEnd, 10),
-		"limit": strconv.FormatInt(opt.Limit, 10),
+		"owner":  opt.Owner,
+		"ns":     opt.Namespace,
+		"start":  strconv.FormatInt(opt.TimeStart, 10),
+		"end":    strconv.FormatInt(opt.TimeEnd, 10),
+		"limit":  strconv.FormatInt(opt.Limit, 10),
+		"cursor": opt.Cursor,
 	}
 	var url url.URL
 	if isExpired {
@@ -250,11 +296,44 @@ func (c *Client) ListFiles(ctx context.Context, opt ListFileOptions, isExpired b
 	} else {
 		url = c.getRequestsUrl([]string{"file", "list"}, reqParams)
 	}
-	var files []blockchain.File
-	if err := httpkg.GetResponse(ctx, url.String(), &files); err != nil {
-		return nil, err
+	var page servertypes.FilePageResponse
+	next, err := httpkg.GetPageResponse(ctx, url.String(), &page.Files)
+	if err != nil {
+		return page, err
 	}
-	return files, nil
+	page.Next = next
+	return page, nil
+}
+
+// SearchFiles search files in the local index of dataOwner node, returns a page of files
+// and the cursor of the next page
+func (c *Client) SearchFiles(ctx context.Context, opt SearchFileOptions) (servertypes.FilePageResponse, error) {
+	reqParams := map[string]string{
+		"owner":    opt.Owner,
+		"ns":       opt.Namespace,
+		"keywords": opt.Keywords,
+		"sort":     opt.SortBy,
+		"start":    strconv.FormatInt(opt.TimeStart, 10),
+		"end":      strconv.FormatInt(opt.TimeEnd, 10),
+		"ctime":    strconv.FormatInt(opt.CurrentTime, 10),
+		"cursor":   opt.Cursor,
+		"limit":    strconv.FormatInt(opt.Limit, 10),
+	}
+	if opt.Asc {
+		reqParams["order"] = "asc"
+	}
+	url := c.getRequestsUrl([]string{"file", "search"}, reqParams)
+	q := url.Query()
+	for k, v := range opt.Ext {
+		q.Add("ext", k+":"+v)
+	}
+	url.RawQuery = q.Encode()
+
+	var page servertypes.FilePageResponse
+	if err := httpkg.GetResponse(ctx, url.String(), &page); err != nil {
+		return page, err
+	}
+	return page, nil
 }
 
 // GetFileByID get file info by file id
@@ -277,6 +356,33 @@ func (c *Client) GetFileByName(ctx context.Context, owner, ns, name string) (blo
 	return hfile, nil
 }
 
+// GetFileByVersion get file info by file name, owner, namespace and version
+func (c *Client) GetFileByVersion(ctx context.Context, owner, ns, name string, version int64) (blockchain.FileH, error) {
+	var hfile blockchain.FileH
+	url := c.getRequestsUrl([]string{"file", "getbyname"}, map[string]string{"owner": owner, "ns": ns, "name": name,
+		"version": strconv.FormatInt(version, 10)})
+	if err := httpkg.GetResponse(ctx, url.String(), &hfile); err != nil {
+		return hfile, err
+	}
+	return hfile, nil
+}
+
+// ListFileVersions list unexpired versions of a file name, the latest first
+func (c *Client) ListFileVersions(ctx context.Context, opt ListFileVersionsOptions) ([]blockchain.File, error) {
+	reqParams := map[string]string{
+		"owner": opt.Owner,
+		"ns":    opt.Namespace,
+		"name":  opt.FileName,
+		"limit": strconv.FormatInt(opt.Limit, 10),
+	}
+	url := c.getRequestsUrl([]string{"file", "listversions"}, reqParams)
+	var files []blockchain.File
+	if err := httpkg.GetResponse(ctx, url.String(), &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
 // UpdateExpTimeByID update file expire time by file id
 func (c *Client) UpdateExpTimeByID(ctx context.Context, id, privateKey string, expireTime int64) error {
 	private, err := ecdsa.DecodePrivateKeyFromString(privateKey)
@@ -307,19 +413,63 @@ func (c *Client) UpdateExpTimeByID(ctx context.Context, id, privateKey string, e
 	return nil
 }
 
+// DeleteFileByID delete file by file id, only the owner of the file is allowed
+func (c *Client) DeleteFileByID(ctx context.Context, id, privateKey string) error {
+	private, err := ecdsa.DecodePrivateKeyFromString(privateKey)
+	if err != nil {
+		return err
+	}
+	reqParams := map[string]string{
+		"id":    id,
+		"user":  ecdsa.PublicKeyFromPrivateKey(private).String(),
+		"ctime": strconv.FormatInt(time.Now().UnixNano(), 10),
+	}
+	msg, err := util.GetSigMessage(reqParams)
+	if err != nil {
+		return errorx.Internal(err, "failed to get the message to sign")
+	}
+
+	sig, err := ecdsa.Sign(private, hash.HashUsingSha256([]byte(msg)))
+	if err != nil {
+		return errorx.Wrap(err, "failed to sign file deletion")
+	}
+	reqParams["token"] = sig.String()
+
+	url := c.getRequestsUrl([]string{"file", "delete"}, reqParams)
+	if _, err := httpkg.Post(ctx, url.String(), nil); err != nil {
+		return err
+	}
+	return nil
+}
+
 // AddFileNs add a file namespace
-func (c *Client) AddFileNs(ctx context.Context, owner, priKey, ns, des string, replica int) error {
-	private, err := ecdsa.DecodePrivateKeyFromString(priKey)
+func (c *Client) AddFileNs(ctx context.Context, opt AddNsOptions) error {
+	private, err := ecdsa.DecodePrivateKeyFromString(opt.PrivateKey)
 	if err != nil {
 		return err
 	}
 
 	reqParams := map[string]string{
-		"ns":      ns,
+		"ns":      opt.Namespace,
 		"user":    ecdsa.PublicKeyFromPrivateKey(private).String(),
-		"replica": strconv.Itoa(replica),
+		"replica": strconv.Itoa(opt.Replica),
 		"ctime":   strconv.FormatInt(time.Now().UnixNano(), 10),
-		"desc":    des,
+		"desc":    opt.Description,
+	}
+	// erasure coded namespace
+	if opt.DataShards > 0 || opt.ParityShards > 0 {
+		reqParams["dataShards"] = strconv.Itoa(opt.DataShards)
+		reqParams["parityShards"] = strconv.Itoa(opt.ParityShards)
+	}
+	if opt.Compression != "" {
+		reqParams["compression"] = opt.Compression
+	}
+	// placement policy
+	if opt.MinZones > 0 {
+		reqParams["minZones"] = strconv.Itoa(opt.MinZones)
+	}
+	if len(opt.Regions) > 0 {
+		reqParams["regions"] = strings.Join(opt.Regions, ",")
 	}
 	msg, err := util.GetSigMessage(reqParams)
 	if err != nil {
@@ -369,6 +519,40 @@ func (c *Client) UpdateFileNsReplica(ctx context.Context, priKey, ns string, rep
 	return nil
 }
 
+// UpdateFileNsLifecycle update namespace lifecycle policy, durations are in nanoseconds,
+// the policy is removed if none of the rules is set
+func (c *Client) UpdateFileNsLifecycle(ctx context.Context, priKey, ns string, defaultTTL int64, autoRenew bool,
+	gracePeriod int64) error {
+	private, err := ecdsa.DecodePrivateKeyFromString(priKey)
+	if err != nil {
+		return err
+	}
+	reqParams := map[string]string{
+		"ns":          ns,
+		"user":        ecdsa.PublicKeyFromPrivateKey(private).String(),
+		"defaultTTL":  strconv.FormatInt(defaultTTL, 10),
+		"autoRenew":   strconv.FormatBool(autoRenew),
+		"gracePeriod": strconv.FormatInt(gracePeriod, 10),
+		"ctime":       strconv.FormatInt(time.Now().UnixNano(), 10),
+	}
+	msg, err := util.GetSigMessage(reqParams)
+	if err != nil {
+		return errorx.Internal(err, "failed to get the message to sign")
+	}
+	// sign the message
+	sig, err := ecdsa.Sign(private, hash.HashUsingSha256([]byte(msg)))
+	if err != nil {
+		return errorx.Wrap(err, "failed to sign update ns lifecycle param")
+	}
+	reqParams["token"] = sig.String()
+
+	url := c.getRequestsUrl([]string{"file", "ulifecycle"}, reqParams)
+	if _, err := httpkg.Post(ctx, url.String(), nil); err != nil {
+		return err
+	}
+	return nil
+}
+
 // ListFileNs list file namespaces
 func (c *Client) ListFileNs(ctx context.Context, opt ListNsOptions) ([]blockchain.Namespace, error) {
 	reqParams := map[string]string{
@@ -446,8 +630,9 @@ func (c *Client) ConfirmOrRejectAuth(ctx context.Context, opt ConfirmAuthOptions
 	return nil
 }
 
-// ListFileAuths get the list of file authorization applications
-func (c *Client) ListFileAuths(ctx context.Context, opt ListFileAuthOptions) (blockchain.FileAuthApplications, error) {
+// ListFileAuths get the list of file authorization applications, returns a page of applications
+// and the cursor of the next page
+func (c *Client) ListFileAuths(ctx context.Context, opt ListFileAuthOptions) (servertypes.FileAuthPageResponse, error) {
 	reqParams := map[string]string{
 		"applierPubkey":    opt.Applier,
 		"authorizerPubkey": opt.Owner,
@@ -456,14 +641,17 @@ func (c *Client) ListFileAuths(ctx context.Context, opt ListFileAuthOptions) (bl
 		"start":            strconv.FormatInt(opt.TimeStart, 10),
 		"end":              strconv.FormatInt(opt.TimeEnd, 10),
 		"limit":            strconv.FormatInt(opt.Limit, 10),
+		"cursor":           opt.Cursor,
 	}
 
-	var fileAuths blockchain.FileAuthApplications
+	var page servertypes.FileAuthPageResponse
 	url := c.getRequestsUrl([]string{"file", "listauth"}, reqParams)
-	if err := httpkg.GetResponse(ctx, url.String(), &fileAuths); err != nil {
-		return nil, err
+	next, err := httpkg.GetPageResponse(ctx, url.String(), &page.FileAuths)
+	if err != nil {
+		return page, err
 	}
-	return fileAuths, nil
+	page.Next = next
+	return page, nil
 }
 
 // GetChallengeByID get challenge info by challenge id
@@ -476,15 +664,18 @@ func (c *Client) GetChallengeByID(ctx context.Context, id string) (blockchain.Ch
 	return challenge, nil
 }
 
-// GetChallenges get challenges with status "ToProve" or "Proved" or "Failed"
-func (c *Client) GetChallenges(ctx context.Context, opt GetChallengesOptions, status string) ([]blockchain.Challenge, error) {
+// GetChallenges get challenges with status "ToProve" or "Proved" or "Failed",
+// returns a page of challenges and the cursor of the next page
+func (c *Client) GetChallenges(ctx context.Context, opt GetChallengesOptions, status string) (
+	servertypes.ChallengePageResponse, error) {
 	reqParams := map[string]string{
-		"owner": opt.Owner,
-		"node":  opt.TargetNode,
-		"file":  opt.FileID,
-		"start": strconv.FormatInt(opt.TimeStart, 10),
-		"end":   strconv.FormatInt(opt.TimeEnd, 10),
-		"limit": strconv.FormatInt(int64(opt.Limit), 10),
+		"owner":  opt.Owner,
+		"node":   opt.TargetNode,
+		"file":   opt.FileID,
+		"start":  strconv.FormatInt(opt.TimeStart, 10),
+		"end":    strconv.FormatInt(opt.TimeEnd, 10),
+		"limit":  strconv.FormatInt(int64(opt.Limit), 10),
+		"cursor": opt.Cursor,
 	}
 	var url url.URL
 	switch status {
@@ -496,9 +687,36 @@ func (c *Client) GetChallenges(ctx context.Context, opt GetChallengesOptions, st
 		url = c.getRequestsUrl([]string{"challenge", "failed"}, reqParams)
 	}
 
-	var challenges []blockchain.Challenge
-	if err := httpkg.GetResponse(ctx, url.String(), &challenges); err != nil {
-		return challenges, err
+	var page servertypes.ChallengePageResponse
+	next, err := httpkg.GetPageResponse(ctx, url.String(), &page.Challenges)
+	if err != nil {
+		return page, err
+	}
+	page.Next = next
+	return page, nil
+}
+
+// SearchChallenges search challenges in the local index of dataOwner node, returns a page of challenges
+// and the cursor of the next page
+func (c *Client) SearchChallenges(ctx context.Context, opt SearchChallengesOptions) (servertypes.ChallengePageResponse, error) {
+	reqParams := map[string]string{
+		"owner":  opt.Owner,
+		"node":   opt.TargetNode,
+		"file":   opt.FileID,
+		"status": opt.Status,
+		"start":  strconv.FormatInt(opt.TimeStart, 10),
+		"end":    strconv.FormatInt(opt.TimeEnd, 10),
+		"cursor": opt.Cursor,
+		"limit":  strconv.FormatInt(opt.Limit, 10),
+	}
+	if opt.Asc {
+		reqParams["order"] = "asc"
+	}
+	url := c.getRequestsUrl([]string{"challenge", "search"}, reqParams)
+
+	var page servertypes.ChallengePageResponse
+	if err := httpkg.GetResponse(ctx, url.String(), &page); err != nil {
+		return page, err
 	}
-	return challenges, nil
+	return page, nil
 }
@@ -32,6 +32,17 @@ type WriteOptions struct {
 	ExpireTime  int64
 	Description string
 	Extra       string
+
+	// algorithm to compress the file with, the compression of namespace is used if empty,
+	// "none" disables compression. Compressed files can not be uploaded in parts
+	Compression string
+}
+
+// UploadOptions define the parameters required to upload a file in parts,
+// PartSize must be a multiple of 64KB, DefaultPartSize is used if it is 0
+type UploadOptions struct {
+	WriteOptions
+	PartSize uint64
 }
 
 // ReadOptions download files using FileID or Namespace+FileName
@@ -42,9 +53,42 @@ type ReadOptions struct {
 	FileName  string
 
 	FileID string
+
+	// version of the file to read by name, the latest version is read if 0
+	Version int64
+
+	// range of the file to read, the whole file is read by default, Length 0 means to the end of file
+	Offset uint64
+	Length uint64
+}
+
+// AddNsOptions define parameters to add a file namespace, files are erasure coded if DataShards or ParityShards
+// is set, compressed with Compression if it's not empty, and replicas of files are spread across at least
+// MinZones zones and only placed in Regions if not empty
+type AddNsOptions struct {
+	PrivateKey string
+
+	Namespace   string
+	Description string
+	Replica     int
+
+	DataShards   int
+	ParityShards int
+	Compression  string
+
+	MinZones int
+	Regions  []string
+}
+
+// ListFileVersionsOptions list versions of a file name
+type ListFileVersionsOptions struct {
+	Owner     string
+	Namespace string
+	FileName  string
+	Limit     int64
 }
 
-// ListFileOptions support paging query
+// ListFileOptions support paging query, Cursor is the cursor returned by the previous page
 type ListFileOptions struct {
 	Owner     string
 	Namespace string
@@ -52,6 +96,7 @@ type ListFileOptions struct {
 	TimeStart int64
 	TimeEnd   int64
 	Limit     int64
+	Cursor    string
 }
 
 // ListNsOptions support paging query
@@ -63,7 +108,8 @@ type ListNsOptions struct {
 	Limit     int64
 }
 
-// ListFileAuthOptions define parameters for authorizers or appliers to query the list of file authorization application
+// ListFileAuthOptions define parameters for authorizers or appliers to query the list of file authorization application,
+// Cursor is the cursor returned by the previous page
 type ListFileAuthOptions struct {
 	Owner     string
 	Applier   string
@@ -72,6 +118,7 @@ type ListFileAuthOptions struct {
 	TimeStart int64
 	TimeEnd   int64
 	Limit     int64
+	Cursor    string
 }
 
 // ConfirmAuthOptions define parameters for authorizers to confirm the file authorization application
@@ -83,7 +130,7 @@ type ConfirmAuthOptions struct {
 	Status       bool
 }
 
-// GetChallengesOptions support paging query
+// GetChallengesOptions support paging query, Cursor is the cursor returned by the previous page
 type GetChallengesOptions struct {
 	Owner      string
 	TargetNode string
@@ -92,4 +139,36 @@ type GetChallengesOptions struct {
 	TimeStart int64
 	TimeEnd   int64
 	Limit     int64
+	Cursor    string
+}
+
+// SearchFileOptions define parameters for searching files in the local index of dataOwner node,
+// Ext are values of top-level fields in file extension, SortBy is one of time, name, size and expire
+type SearchFileOptions struct {
+	Owner     string
+	Namespace string // optional, all namespaces by default
+	Keywords  string
+	Ext       map[string]string
+	SortBy    string
+	Asc       bool
+
+	TimeStart   int64
+	TimeEnd     int64
+	CurrentTime int64
+	Cursor      string
+	Limit       int64
+}
+
+// SearchChallengesOptions define parameters for searching challenges in the local index of dataOwner node
+type SearchChallengesOptions struct {
+	Owner      string
+	TargetNode string // optional, filter
+	FileID     string // optional, filter
+	Status     string // optional, filter
+	Asc        bool
+
+	TimeStart int64
+	TimeEnd   int64
+	Cursor    string
+	Limit     int64
 }
@@ -0,0 +1,228 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/compressor"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	httpkg "github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/http"
+	util "github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/strings"
+	servertypes "github.com/PaddlePaddle/PaddleDTX/xdb/server/types"
+)
+
+const (
+	// DefaultPartSize is the size of parts to upload a file in by default
+	DefaultPartSize = 64 << 20
+	// times to try uploading a part before giving up
+	uploadPartTries = 3
+)
+
+// Upload uploads a file of size bytes in parts, and publishes it when all parts are uploaded.
+// If an upload of the same file was interrupted, only the parts missing or changed are uploaded,
+// parts uploaded before are checked by hash.
+func (c *Client) Upload(ctx context.Context, r io.ReaderAt, size int64, opt UploadOptions) (
+	servertypes.WriteResponse, error) {
+
+	if opt.PartSize == 0 {
+		opt.PartSize = DefaultPartSize
+	}
+	session, err := c.InitiateUpload(ctx, opt, uint64(size))
+	if err != nil {
+		return servertypes.WriteResponse{}, err
+	}
+	uploaded := make(map[int][]byte)
+	for _, p := range session.Parts {
+		uploaded[p.Number] = p.PlainHash
+	}
+
+	for n := 1; n <= session.PartCount; n++ {
+		offset := int64(session.PartSize) * int64(n-1)
+		length := int64(session.PartSize)
+		if offset+length > size {
+			length = size - offset
+		}
+		if plainHash, exist := uploaded[n]; exist {
+			h := sha256.New()
+			if _, err := io.Copy(h, io.NewSectionReader(r, offset, length)); err != nil {
+				return servertypes.WriteResponse{}, errorx.Wrap(err, "failed to read part %d", n)
+			}
+			if bytes.Equal(h.Sum(nil), plainHash) {
+				continue
+			}
+		}
+
+		for i := 1; ; i++ {
+			_, err = c.UploadPart(ctx, opt.PrivateKey, session.SessionID, n, io.NewSectionReader(r, offset, length))
+			if err == nil || i == uploadPartTries || ctx.Err() != nil {
+				break
+			}
+		}
+		if err != nil {
+			return servertypes.WriteResponse{}, errorx.Wrap(err, "failed to upload part %d", n)
+		}
+	}
+
+	return c.CompleteUpload(ctx, opt.PrivateKey, session.SessionID)
+}
+
+// InitiateUpload starts a session to upload a file of length bytes in parts,
+// an unfinished session of the same file is returned along with the parts uploaded
+func (c *Client) InitiateUpload(ctx context.Context, opt UploadOptions, length uint64) (
+	servertypes.UploadSessionResponse, error) {
+
+	if compressor.Enabled(opt.Compression) {
+		return servertypes.UploadSessionResponse{}, errorx.New(errorx.ErrCodeParam,
+			"compressed files can not be uploaded in parts")
+	}
+	privkey, err := ecdsa.DecodePrivateKeyFromString(opt.PrivateKey)
+	if err != nil {
+		return servertypes.UploadSessionResponse{}, err
+	}
+	reqParams := map[string]string{
+		"user":       ecdsa.PublicKeyFromPrivateKey(privkey).String(),
+		"ns":         opt.Namespace,
+		"name":       opt.FileName,
+		"desc":       opt.Description,
+		"ext":        opt.Extra,
+		"expireTime": strconv.FormatInt(opt.ExpireTime, 10),
+		"length":     strconv.FormatUint(length, 10),
+		"partSize":   strconv.FormatUint(opt.PartSize, 10),
+	}
+	msg, err := util.GetSigMessage(reqParams)
+	if err != nil {
+		return servertypes.UploadSessionResponse{}, errorx.Internal(err, "failed to get the message to sign")
+	}
+
+	sig, err := ecdsa.Sign(privkey, hash.HashUsingSha256([]byte(msg)))
+	if err != nil {
+		return servertypes.UploadSessionResponse{}, errorx.Wrap(err, "failed to sign")
+	}
+	reqParams["token"] = sig.String()
+
+	url := c.getRequestsUrl([]string{"file", "upload", "initiate"}, reqParams)
+	var resp servertypes.UploadSessionResponse
+	if err := httpkg.PostResponse(ctx, url.String(), nil, &resp); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// UploadPart uploads a part of file, parts are numbered from 1
+func (c *Client) UploadPart(ctx context.Context, privateKey, sessionID string, part int, r io.Reader) (
+	servertypes.UploadPartResponse, error) {
+
+	var resp servertypes.UploadPartResponse
+	reqParams, err := signUploadSessionParams(privateKey, sessionID, "part", map[string]string{
+		"part": strconv.Itoa(part),
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	url := c.getRequestsUrl([]string{"file", "upload", "part"}, reqParams)
+	if err := httpkg.PostResponse(ctx, url.String(), r, &resp); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// ListUploadParts gets an upload session and the parts uploaded
+func (c *Client) ListUploadParts(ctx context.Context, privateKey, sessionID string) (
+	servertypes.UploadSessionResponse, error) {
+
+	var resp servertypes.UploadSessionResponse
+	reqParams, err := signUploadSessionParams(privateKey, sessionID, "list", nil)
+	if err != nil {
+		return resp, err
+	}
+
+	url := c.getRequestsUrl([]string{"file", "upload", "list"}, reqParams)
+	if err := httpkg.GetResponse(ctx, url.String(), &resp); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// CompleteUpload publishes the file when all parts are uploaded
+func (c *Client) CompleteUpload(ctx context.Context, privateKey, sessionID string) (
+	servertypes.WriteResponse, error) {
+
+	var resp servertypes.WriteResponse
+	reqParams, err := signUploadSessionParams(privateKey, sessionID, "complete", nil)
+	if err != nil {
+		return resp, err
+	}
+
+	url := c.getRequestsUrl([]string{"file", "upload", "complete"}, reqParams)
+	if err := httpkg.PostResponse(ctx, url.String(), nil, &resp); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// AbortUpload removes an upload session, the parts uploaded are deleted
+func (c *Client) AbortUpload(ctx context.Context, privateKey, sessionID string) error {
+	reqParams, err := signUploadSessionParams(privateKey, sessionID, "abort", nil)
+	if err != nil {
+		return err
+	}
+
+	url := c.getRequestsUrl([]string{"file", "upload", "abort"}, reqParams)
+	if _, err := httpkg.Post(ctx, url.String(), nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// signUploadSessionParams signs params to operate an upload session.
+// action is signed but not sent, as the server decides it by the api requested.
+func signUploadSessionParams(privateKey, sessionID, action string, params map[string]string) (
+	map[string]string, error) {
+
+	privkey, err := ecdsa.DecodePrivateKeyFromString(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	reqParams := map[string]string{
+		"sessionID": sessionID,
+		"action":    action,
+		"ctime":     strconv.FormatInt(time.Now().UnixNano(), 10),
+		"user":      ecdsa.PublicKeyFromPrivateKey(privkey).String(),
+	}
+	for k, v := range params {
+		reqParams[k] = v
+	}
+	msg, err := util.GetSigMessage(reqParams)
+	if err != nil {
+		return nil, errorx.Internal(err, "failed to get the message to sign")
+	}
+
+	sig, err := ecdsa.Sign(privkey, hash.HashUsingSha256([]byte(msg)))
+	if err != nil {
+		return nil, errorx.Wrap(err, "failed to sign")
+	}
+	delete(reqParams, "action")
+	reqParams["token"] = sig.String()
+	return reqParams, nil
+}
@@ -62,23 +62,34 @@ var failedCmd = &cobra.Command{
 			TimeStart:  startTime,
 			TimeEnd:    endTime.UnixNano(),
 			Limit:      limit,
+			Cursor:     cursor,
 		}
-		challenges, err := client.GetChallenges(context.Background(), opt, blockchain.ChallengeFailed)
-		if err != nil {
-			fmt.Printf("err：%v\n", err)
-			return
-		}
-
-		if list != 0 {
-			for _, c := range challenges {
-				fileOwner := hex.EncodeToString(c.FileOwner)
-				cTime := time.Unix(0, c.ChallengeTime).Format(timeTemplate)
-				aTime := time.Unix(0, c.AnswerTime).Format(timeTemplate)
-				fmt.Printf("ChallengeID: %s\nFileID: %s\nOwner: %s\nStorageNode: %s\nChallengeTime: %s\nAnswerTime: %s\n\n",
-					c.ID, c.FileID, fileOwner, string(c.TargetNode), cTime, aTime)
+		num := 0
+		for {
+			page, err := client.GetChallenges(context.Background(), opt, blockchain.ChallengeFailed)
+			if err != nil {
+				fmt.Printf("err：%v\n", err)
+				return
+			}
+			if list != 0 {
+				for _, c := range page.Challenges {
+					fileOwner := hex.EncodeToString(c.FileOwner)
+					cTime := time.Unix(0, c.ChallengeTime).Format(timeTemplate)
+					aTime := time.Unix(0, c.AnswerTime).Format(timeTemplate)
+					fmt.Printf("ChallengeID: %s\nFileID: %s\nOwner: %s\nStorageNode: %s\nChallengeTime: %s\nAnswerTime: %s\n\n",
+						c.ID, c.FileID, fileOwner, string(c.TargetNode), cTime, aTime)
+				}
 			}
+			num += len(page.Challenges)
+			// only the first page is listed unless all
+			if opt.Cursor = page.Next; !all || opt.Cursor == "" {
+				break
+			}
+		}
+		fmt.Printf("Failed challenges from %s to %s\nNum: %d\n\n", start, end, num)
+		if opt.Cursor != "" {
+			fmt.Printf("more challenges listed with --cursor %s\n\n", opt.Cursor)
 		}
-		fmt.Printf("Failed challenges from %s to %s\nNum: %d\n\n", start, end, len(challenges))
 	},
 }
 
@@ -92,6 +103,8 @@ func init() {
 	failedCmd.Flags().StringVarP(&end, "end", "e", time.Unix(0, time.Now().UnixNano()).Format(timeTemplate), "challenge after endTime, example '2021-06-10 12:00:00'")
 	failedCmd.Flags().Int64VarP(&limit, "limit", "l", blockchain.ListMaxNumber, "limit")
 	failedCmd.Flags().Int8VarP(&list, "list", "", 1, "show challenges list or not, 0 not to show")
+	failedCmd.Flags().StringVar(&cursor, "cursor", "", "cursor returned by the previous list to get the next page")
+	failedCmd.Flags().BoolVar(&all, "all", false, "list all pages of challenges")
 
 	failedCmd.MarkFlagRequired("node")
 }
@@ -29,6 +29,7 @@ var (
 	end         string
 	limit       int64
 	list        int8
+	all         bool
 )
 
 // rootCmd represents the task command
@@ -0,0 +1,108 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package challenge
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	httpclient "github.com/PaddlePaddle/PaddleDTX/xdb/client/http"
+)
+
+var (
+	status string
+	asc    bool
+	cursor string
+)
+
+// searchCmd searches challenges in the local index of the dataOwner node
+var searchCmd = &cobra.Command{
+	Use:   "search",
+	Short: "search challenges by filters in the local index of the dataOwner node",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := httpclient.New(host)
+		if err != nil {
+			fmt.Printf("err：%v\n", err)
+			return
+		}
+
+		var startTime, endTime int64
+		if start != "" {
+			s, err := time.ParseInLocation(timeTemplate, start, time.Local)
+			if err != nil {
+				fmt.Printf("err：%v\n", err)
+				return
+			}
+			startTime = s.UnixNano()
+		}
+		if end != "" {
+			e, err := time.ParseInLocation(timeTemplate, end, time.Local)
+			if err != nil {
+				fmt.Printf("err：%v\n", err)
+				return
+			}
+			endTime = e.UnixNano()
+		}
+
+		opt := httpclient.SearchChallengesOptions{
+			Owner:      owner,
+			TargetNode: storageNode,
+			FileID:     fileID,
+			Status:     status,
+			Asc:        asc,
+			TimeStart:  startTime,
+			TimeEnd:    endTime,
+			Cursor:     cursor,
+			Limit:      limit,
+		}
+		page, err := client.SearchChallenges(context.Background(), opt)
+		if err != nil {
+			fmt.Printf("err：%v\n", err)
+			return
+		}
+
+		if list != 0 {
+			for _, c := range page.Challenges {
+				fileOwner := hex.EncodeToString(c.FileOwner)
+				cTime := time.Unix(0, c.ChallengeTime).Format(timeTemplate)
+				fmt.Printf("ChallengeID: %s\nFileID: %s\nOwner: %s\nStorageNode: %s\nStatus: %s\nChallengeTime: %s\n\n",
+					c.ID, c.FileID, fileOwner, string(c.TargetNode), c.Status, cTime)
+			}
+		}
+		fmt.Printf("Num: %d\n\n", len(page.Challenges))
+		if page.Next != "" {
+			fmt.Printf("more challenges searched with --cursor %s\n\n", page.Next)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().StringVarP(&owner, "owner", "o", "", "file owner")
+	searchCmd.Flags().StringVarP(&storageNode, "node", "n", "", "storage node, all nodes if not set")
+	searchCmd.Flags().StringVarP(&fileID, "file", "f", "", "file ID")
+	searchCmd.Flags().StringVar(&status, "status", "", "challenge status, 'ToProve', 'Proved' or 'Failed'")
+	searchCmd.Flags().BoolVar(&asc, "asc", false, "sort challenges in ascending order of challenge time")
+	searchCmd.Flags().StringVarP(&start, "start", "s", "", "challenge after startTime, example '2021-06-10 12:00:00'")
+	searchCmd.Flags().StringVarP(&end, "end", "e", "", "challenge before endTime, example '2021-06-10 12:00:00'")
+	searchCmd.Flags().StringVar(&cursor, "cursor", "", "cursor returned by the previous search to get the next page")
+	searchCmd.Flags().Int64VarP(&limit, "limit", "l", blockchain.ListMaxNumber, "limit")
+	searchCmd.Flags().Int8VarP(&list, "list", "", 1, "show challenges list or not, 0 not to show")
+}
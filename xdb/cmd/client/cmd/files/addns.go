@@ -25,7 +25,12 @@ import (
 )
 
 var (
-	replica int
+	replica      int
+	dataShards   int
+	parityShards int
+	compression  string
+	minZones     int
+	regions      []string
 )
 
 // addNsCmd represents the command to add namespace
@@ -52,7 +57,18 @@ var addNsCmd = &cobra.Command{
 			privateKey = strings.TrimSpace(string(privateKeyBytes))
 		}
 
-		err = client.AddFileNs(context.Background(), owner, privateKey, namespace, description, replica)
+		opt := httpclient.AddNsOptions{
+			PrivateKey:   privateKey,
+			Namespace:    namespace,
+			Description:  description,
+			Replica:      replica,
+			DataShards:   dataShards,
+			ParityShards: parityShards,
+			Compression:  compression,
+			MinZones:     minZones,
+			Regions:      regions,
+		}
+		err = client.AddFileNs(context.Background(), opt)
 		if err != nil {
 			fmt.Printf("err：%v\n", err)
 			return
@@ -70,6 +86,12 @@ func init() {
 	addNsCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "namespace for file")
 	addNsCmd.Flags().StringVarP(&description, "description", "d", "", "description")
 	addNsCmd.Flags().IntVarP(&replica, "replica", "r", 0, "replica")
+	addNsCmd.Flags().IntVarP(&dataShards, "dataShards", "", 0, "data shards per stripe for erasure coding, replica must be 1 if set")
+	addNsCmd.Flags().IntVarP(&parityShards, "parityShards", "", 0, "parity shards per stripe for erasure coding, replica must be 1 if set")
+
+	addNsCmd.Flags().StringVarP(&compression, "compression", "", "", "algorithm to compress files written into the namespace, 'zstd' or 'gzip', optional")
+	addNsCmd.Flags().IntVarP(&minZones, "minZones", "", 0, "replicas, or shards of a stripe, are spread across at least minZones zones, optional")
+	addNsCmd.Flags().StringSliceVarP(&regions, "regions", "", nil, "regions replicas are restricted to, separated by commas, optional")
 
 	addNsCmd.MarkFlagRequired("namespace")
 	addNsCmd.MarkFlagRequired("replica")
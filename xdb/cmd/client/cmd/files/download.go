@@ -29,6 +29,8 @@ import (
 var (
 	fileID string
 	output string
+	offset uint64
+	length uint64
 )
 
 // downloadCmd represents the command to download file from xuper db
@@ -61,6 +63,9 @@ var downloadCmd = &cobra.Command{
 			Namespace:  namespace,
 			FileName:   filename,
 			FileID:     fileID,
+			Version:    version,
+			Offset:     offset,
+			Length:     length,
 		}
 
 		reader, err := client.Read(context.Background(), opt)
@@ -95,6 +100,9 @@ func init() {
 	downloadCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "namespace for file")
 	downloadCmd.Flags().StringVarP(&filename, "filename", "m", "", "file name")
 	downloadCmd.Flags().StringVarP(&fileID, "fileid", "f", "", "file id")
+	downloadCmd.Flags().Int64VarP(&version, "version", "v", 0, "version of the file located by namespace+filename, optional, the latest version by default")
+	downloadCmd.Flags().Uint64VarP(&offset, "offset", "", 0, "offset of the range to download, optional")
+	downloadCmd.Flags().Uint64VarP(&length, "length", "", 0, "length of the range to download, optional, download to the end of file by default")
 
 	downloadCmd.MarkFlagRequired("output")
 }
@@ -60,7 +60,12 @@ var getByNameCmd = &cobra.Command{
 			return
 		}
 
-		hf, err := client.GetFileByName(context.Background(), owner, namespace, filename)
+		var hf blockchain.FileH
+		if version > 0 {
+			hf, err = client.GetFileByVersion(context.Background(), owner, namespace, filename, version)
+		} else {
+			hf, err = client.GetFileByName(context.Background(), owner, namespace, filename)
+		}
 		if err != nil {
 			fmt.Printf("err：%v\n", err)
 			return
@@ -69,11 +74,19 @@ var getByNameCmd = &cobra.Command{
 		slicesMap := getFileSliceMap(f)
 		ptime := time.Unix(0, f.PublishTime).Format(timeTemplate)
 		etime := time.Unix(0, f.ExpireTime).Format(timeTemplate)
-		fmt.Printf("FileID: %s\nFileDescription: %s\nSlicesMap: %v\nFileLength: %v\nHealth: %s\nPublishTimes: %s\nExpireTime: %s\nExtra: %s\n\n",
-			f.ID, f.Description, slicesMap, f.Length, hf.Health, ptime, etime, f.Ext)
+		fmt.Printf("FileID: %s\nVersion: %d\nFileDescription: %s\nSlicesMap: %v\nFileLength: %v\nHealth: %s\nPublishTimes: %s\nExpireTime: %s\nExtra: %s\n\n",
+			f.ID, fileVersion(f), f.Description, slicesMap, f.Length, hf.Health, ptime, etime, f.Ext)
 	},
 }
 
+// fileVersion returns version of the file, files published before versioning are the first version
+func fileVersion(f blockchain.File) int64 {
+	if f.Version == 0 {
+		return 1
+	}
+	return f.Version
+}
+
 func getFileSliceMap(file blockchain.File) map[string][]string {
 	ret := make(map[string][]string)
 	for _, slice := range file.Slices {
@@ -91,6 +104,7 @@ func init() {
 	getByNameCmd.Flags().StringVarP(&owner, "owner", "o", "", "owner for file")
 	getByNameCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "namespace for file")
 	getByNameCmd.Flags().StringVarP(&filename, "filename", "m", "", "file name")
+	getByNameCmd.Flags().Int64VarP(&version, "version", "v", 0, "file version, optional, the latest version by default")
 
 	getByIDCmd.MarkFlagRequired("id")
 
@@ -16,6 +16,7 @@ package files
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -45,6 +46,13 @@ var getNsCmd = &cobra.Command{
 
 		fmt.Printf("Name: %s\nFileTotalNum: %d\nFileNormalNum: %d\nFileExpiredNum: %d\nReplica: %d\nGreenFileNum: %d\nYellowFileNum: %d\nRedFileNum: %d\n",
 			ns.Name, ns.FileTotalNum, nsh.FileNormalNum, nsh.FileExpiredNum, ns.Replica, nsh.GreenFileNum, nsh.YellowFileNum, nsh.RedFileNum)
+		if ns.Placement != nil {
+			fmt.Printf("MinZones: %d\nRegions: %s\n", ns.Placement.MinZones, strings.Join(ns.Placement.Regions, ","))
+		}
+		if ns.Lifecycle != nil {
+			fmt.Printf("DefaultTTL: %v\nAutoRenew: %t\nGracePeriod: %v\n", time.Duration(ns.Lifecycle.DefaultTTL),
+				ns.Lifecycle.AutoRenew, time.Duration(ns.Lifecycle.GracePeriod))
+		}
 		fmt.Printf("Description: %s\nUpdateTime: %s\nCreateTime: %s\n\n", ns.Description, utime, ctime)
 	},
 }
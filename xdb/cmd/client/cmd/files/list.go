@@ -24,6 +24,10 @@ import (
 	httpclient "github.com/PaddlePaddle/PaddleDTX/xdb/client/http"
 )
 
+var (
+	all bool
+)
+
 // listFilesCmd represents the command to list files by namespace
 var listFilesCmd = &cobra.Command{
 	Use:   "list",
@@ -60,22 +64,34 @@ var listFilesCmd = &cobra.Command{
 			TimeStart: startTime,
 			TimeEnd:   endTime.UnixNano(),
 			Limit:     limit,
+			Cursor:    cursor,
 		}
-		resp, err := client.ListFiles(context.Background(), opt, false)
-		if err != nil {
-			fmt.Printf("err：%v\n", err)
-			return
-		}
-		for _, f := range resp {
-			ptime := time.Unix(0, f.PublishTime).Format(timeTemplate)
-			etime := time.Unix(0, f.ExpireTime).Format(timeTemplate)
-			fmt.Printf("FileID: %s\nFileName: %s\nFileDescription: %s\nNamespace: %s\nFileLength: %v\nPublishTimes: %s\nExpireTime: %s\n\n",
-				f.ID, f.Name, f.Description, f.Namespace, f.Length, ptime, etime)
+		num := 0
+		for {
+			page, err := client.ListFiles(context.Background(), opt, false)
+			if err != nil {
+				fmt.Printf("err：%v\n", err)
+				return
+			}
+			for _, f := range page.Files {
+				ptime := time.Unix(0, f.PublishTime).Format(timeTemplate)
+				etime := time.Unix(0, f.ExpireTime).Format(timeTemplate)
+				fmt.Printf("FileID: %s\nFileName: %s\nFileDescription: %s\nNamespace: %s\nFileLength: %v\nPublishTimes: %s\nExpireTime: %s\n\n",
+					f.ID, f.Name, f.Description, f.Namespace, f.Length, ptime, etime)
+			}
+			num += len(page.Files)
+			// only the first page is listed unless all
+			if opt.Cursor = page.Next; !all || opt.Cursor == "" {
+				break
+			}
 		}
-		if len(resp) == 0 {
+		if num == 0 {
 			fmt.Printf("\nno files\n\n")
 		} else {
-			fmt.Printf("\nfiles num from %s to %s: %d\n\n", start, end, len(resp))
+			fmt.Printf("\nfiles num from %s to %s: %d\n\n", start, end, num)
+		}
+		if opt.Cursor != "" {
+			fmt.Printf("more files listed with --cursor %s\n\n", opt.Cursor)
 		}
 	},
 }
@@ -116,22 +132,34 @@ var listExpFilesCmd = &cobra.Command{
 			TimeStart: startTime,
 			TimeEnd:   endTime.UnixNano(),
 			Limit:     limit,
+			Cursor:    cursor,
 		}
-		resp, err := client.ListFiles(context.Background(), opt, true)
-		if err != nil {
-			fmt.Printf("err：%v\n", err)
-			return
-		}
-		for _, f := range resp {
-			ptime := time.Unix(0, f.PublishTime).Format(timeTemplate)
-			etime := time.Unix(0, f.ExpireTime).Format(timeTemplate)
-			fmt.Printf("FileID: %s\nFileName: %s\nFileDescription: %s\nNamespace: %s\nFileLength: %v\nPublishTimes: %s\nExpireTime: %s\n\n",
-				f.ID, f.Name, f.Description, f.Namespace, f.Length, ptime, etime)
+		num := 0
+		for {
+			page, err := client.ListFiles(context.Background(), opt, true)
+			if err != nil {
+				fmt.Printf("err：%v\n", err)
+				return
+			}
+			for _, f := range page.Files {
+				ptime := time.Unix(0, f.PublishTime).Format(timeTemplate)
+				etime := time.Unix(0, f.ExpireTime).Format(timeTemplate)
+				fmt.Printf("FileID: %s\nFileName: %s\nFileDescription: %s\nNamespace: %s\nFileLength: %v\nPublishTimes: %s\nExpireTime: %s\n\n",
+					f.ID, f.Name, f.Description, f.Namespace, f.Length, ptime, etime)
+			}
+			num += len(page.Files)
+			// only the first page is listed unless all
+			if opt.Cursor = page.Next; !all || opt.Cursor == "" {
+				break
+			}
 		}
-		if len(resp) == 0 {
+		if num == 0 {
 			fmt.Printf("\nno files\n\n")
 		} else {
-			fmt.Printf("\nfiles num from %s to %s: %d\n\n", start, end, len(resp))
+			fmt.Printf("\nfiles num from %s to %s: %d\n\n", start, end, num)
+		}
+		if opt.Cursor != "" {
+			fmt.Printf("more files listed with --cursor %s\n\n", opt.Cursor)
 		}
 	},
 }
@@ -145,6 +173,8 @@ func init() {
 	listFilesCmd.Flags().StringVarP(&start, "start", "s", "", "file publish after startTime, example '2021-06-10 12:00:00'")
 	listFilesCmd.Flags().StringVarP(&end, "end", "e", time.Unix(0, time.Now().UnixNano()).Format(timeTemplate), "file publish before endTime, example '2021-06-10 12:00:00'")
 	listFilesCmd.Flags().Int64VarP(&limit, "limit", "l", blockchain.ListMaxNumber, "limit for list files")
+	listFilesCmd.Flags().StringVar(&cursor, "cursor", "", "cursor returned by the previous list to get the next page")
+	listFilesCmd.Flags().BoolVar(&all, "all", false, "list all pages of files")
 
 	listFilesCmd.MarkFlagRequired("namespace")
 
@@ -153,6 +183,8 @@ func init() {
 	listExpFilesCmd.Flags().StringVarP(&start, "start", "s", "", "file publish after startTime, example '2021-06-10 12:00:00'")
 	listExpFilesCmd.Flags().StringVarP(&end, "end", "e", time.Unix(0, time.Now().UnixNano()).Format(timeTemplate), "file publish before endTime, example '2021-06-10 12:00:00'")
 	listExpFilesCmd.Flags().Int64VarP(&limit, "limit", "l", blockchain.ListMaxNumber, "limit for list expired files")
+	listExpFilesCmd.Flags().StringVar(&cursor, "cursor", "", "cursor returned by the previous list to get the next page")
+	listExpFilesCmd.Flags().BoolVar(&all, "all", false, "list all pages of expired files")
 
 	listExpFilesCmd.MarkFlagRequired("namespace")
 }
@@ -65,27 +65,39 @@ var fileAuthsListCmd = &cobra.Command{
 			TimeStart: startTime,
 			TimeEnd:   endTime.UnixNano(),
 			Limit:     limit,
+			Cursor:    cursor,
 		}
 
-		response, err := client.ListFileAuths(context.Background(), opt)
-		if err != nil {
-			fmt.Printf("err：%v\n", err)
-			return
-		}
-		// Print each authorization application detailed information under the list
-		for _, fa := range response {
-			ctime := time.Unix(0, fa.CreateTime).Format(timeTemplate)
-			atime := time.Unix(0, fa.ApprovalTime).Format(timeTemplate)
-			etime := time.Unix(0, fa.ExpireTime).Format(timeTemplate)
+		num := 0
+		for {
+			page, err := client.ListFileAuths(context.Background(), opt)
+			if err != nil {
+				fmt.Printf("err：%v\n", err)
+				return
+			}
+			// Print each authorization application detailed information under the list
+			for _, fa := range page.FileAuths {
+				ctime := time.Unix(0, fa.CreateTime).Format(timeTemplate)
+				atime := time.Unix(0, fa.ApprovalTime).Format(timeTemplate)
+				etime := time.Unix(0, fa.ExpireTime).Format(timeTemplate)
 
-			fmt.Printf("AuthID: %s\nFileID: %s\nName: %s\nDescription: %s\nApplier: %x\nAuthorizer: %x\nAuthKey: %x\nStatus: %v\n",
-				fa.ID, fa.FileID, fa.Name, fa.Description, fa.Applier, fa.Authorizer, fa.AuthKey, fa.Status)
+				fmt.Printf("AuthID: %s\nFileID: %s\nName: %s\nDescription: %s\nApplier: %x\nAuthorizer: %x\nAuthKey: %x\nStatus: %v\n",
+					fa.ID, fa.FileID, fa.Name, fa.Description, fa.Applier, fa.Authorizer, fa.AuthKey, fa.Status)
 
-			fmt.Printf("RejectReason: %s\nCreateTime: %s\nApprovalTime: %s\nExpireTime: %s\n\n", fa.RejectReason, ctime, atime, etime)
+				fmt.Printf("RejectReason: %s\nCreateTime: %s\nApprovalTime: %s\nExpireTime: %s\n\n", fa.RejectReason, ctime, atime, etime)
+			}
+			num += len(page.FileAuths)
+			// only the first page is listed unless all
+			if opt.Cursor = page.Next; !all || opt.Cursor == "" {
+				break
+			}
 		}
-		if len(response) == 0 {
+		if num == 0 {
 			fmt.Printf("\nno file authorization applications\n\n")
 		}
+		if opt.Cursor != "" {
+			fmt.Printf("more file authorization applications listed with --cursor %s\n\n", opt.Cursor)
+		}
 	},
 }
 
@@ -100,4 +112,6 @@ func init() {
 	fileAuthsListCmd.Flags().StringVarP(&end, "end", "e", time.Unix(0, time.Now().UnixNano()).Format(timeTemplate),
 		"authorization applications publish before endTime, example '2022-07-10 12:00:00'")
 	fileAuthsListCmd.Flags().Int64VarP(&limit, "limit", "l", blockchain.ListMaxNumber, "limit for list file authorization applications")
+	fileAuthsListCmd.Flags().StringVar(&cursor, "cursor", "", "cursor returned by the previous list to get the next page")
+	fileAuthsListCmd.Flags().BoolVar(&all, "all", false, "list all pages of file authorization applications")
 }
@@ -30,6 +30,7 @@ var (
 	end        string
 	limit      int64
 	id         string
+	version    int64
 )
 
 // rootCmd represents the root command to manage tasks
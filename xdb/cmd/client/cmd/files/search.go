@@ -0,0 +1,127 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	httpclient "github.com/PaddlePaddle/PaddleDTX/xdb/client/http"
+)
+
+var (
+	keywords string
+	ext      []string
+	sortBy   string
+	asc      bool
+	expired  bool
+	cursor   string
+)
+
+// searchFilesCmd represents the command to search files in the local index of the dataOwner node
+var searchFilesCmd = &cobra.Command{
+	Use:   "search",
+	Short: "search files by keywords and extension fields in the local index of the dataOwner node",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := httpclient.New(host)
+		if err != nil {
+			fmt.Printf("err：%v\n", err)
+			return
+		}
+
+		var startTime, endTime int64
+		if start != "" {
+			s, err := time.ParseInLocation(timeTemplate, start, time.Local)
+			if err != nil {
+				fmt.Printf("err：%v\n", err)
+				return
+			}
+			startTime = s.UnixNano()
+		}
+		if end != "" {
+			e, err := time.ParseInLocation(timeTemplate, end, time.Local)
+			if err != nil {
+				fmt.Printf("err：%v\n", err)
+				return
+			}
+			endTime = e.UnixNano()
+		}
+		opt := httpclient.SearchFileOptions{
+			Owner:     owner,
+			Namespace: namespace,
+			Keywords:  keywords,
+			SortBy:    sortBy,
+			Asc:       asc,
+			TimeStart: startTime,
+			TimeEnd:   endTime,
+			Cursor:    cursor,
+			Limit:     limit,
+		}
+		if !expired {
+			opt.CurrentTime = time.Now().UnixNano()
+		}
+		for _, field := range ext {
+			kv := strings.SplitN(field, ":", 2)
+			if len(kv) != 2 {
+				fmt.Printf("invalid ext %s, example 'region:north'\n", field)
+				return
+			}
+			if opt.Ext == nil {
+				opt.Ext = make(map[string]string)
+			}
+			opt.Ext[kv[0]] = kv[1]
+		}
+
+		page, err := client.SearchFiles(context.Background(), opt)
+		if err != nil {
+			fmt.Printf("err：%v\n", err)
+			return
+		}
+		for _, f := range page.Files {
+			ptime := time.Unix(0, f.PublishTime).Format(timeTemplate)
+			etime := time.Unix(0, f.ExpireTime).Format(timeTemplate)
+			fmt.Printf("FileID: %s\nFileName: %s\nFileDescription: %s\nNamespace: %s\nFileLength: %v\nPublishTimes: %s\nExpireTime: %s\nExt: %s\n\n",
+				f.ID, f.Name, f.Description, f.Namespace, f.Length, ptime, etime, string(f.Ext))
+		}
+		if len(page.Files) == 0 {
+			fmt.Printf("\nno files\n\n")
+		} else {
+			fmt.Printf("\nfiles num: %d\n\n", len(page.Files))
+		}
+		if page.Next != "" {
+			fmt.Printf("more files searched with --cursor %s\n\n", page.Next)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchFilesCmd)
+
+	searchFilesCmd.Flags().StringVarP(&owner, "owner", "o", "", "owner for file")
+	searchFilesCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "namespace for file, all namespaces if not set")
+	searchFilesCmd.Flags().StringVarP(&keywords, "keywords", "k", "", "words found in file name or description, example 'sales 2021'")
+	searchFilesCmd.Flags().StringArrayVar(&ext, "ext", nil, "value of a top-level field in file extension, example 'region:north'")
+	searchFilesCmd.Flags().StringVar(&sortBy, "sort", "time", "sort files by 'time', 'name', 'size' or 'expire'")
+	searchFilesCmd.Flags().BoolVar(&asc, "asc", false, "sort files in ascending order")
+	searchFilesCmd.Flags().BoolVar(&expired, "expired", false, "include expired files")
+	searchFilesCmd.Flags().StringVarP(&start, "start", "s", "", "file publish after startTime, example '2021-06-10 12:00:00'")
+	searchFilesCmd.Flags().StringVarP(&end, "end", "e", "", "file publish before endTime, example '2021-06-10 12:00:00'")
+	searchFilesCmd.Flags().StringVar(&cursor, "cursor", "", "cursor returned by the previous search to get the next page")
+	searchFilesCmd.Flags().Int64VarP(&limit, "limit", "l", blockchain.ListMaxNumber, "limit for search files")
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	httpclient "github.com/PaddlePaddle/PaddleDTX/xdb/client/http"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/file"
+)
+
+var (
+	defaultTTL  time.Duration
+	autoRenew   bool
+	gracePeriod time.Duration
+)
+
+// updateNsLifecycleCmd represents the command to update lifecycle policy of namespace
+var updateNsLifecycleCmd = &cobra.Command{
+	Use:   "ulifecycle",
+	Short: "update file lifecycle policy of namespace, the policy is removed if no rule is set",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := httpclient.New(host)
+		if err != nil {
+			fmt.Printf("err：%v\n", err)
+			return
+		}
+		if defaultTTL < 0 || gracePeriod < 0 {
+			fmt.Printf("err: bad param, defaultTTL and gracePeriod must not be negative")
+			return
+		}
+
+		if privateKey == "" {
+			privateKeyBytes, err := file.ReadFile(keyPath, file.PrivateKeyFileName)
+			if err != nil {
+				fmt.Printf("Read privateKey failed, err: %v\n", err)
+				return
+			}
+			privateKey = strings.TrimSpace(string(privateKeyBytes))
+		}
+
+		err = client.UpdateFileNsLifecycle(context.Background(), privateKey, namespace, defaultTTL.Nanoseconds(),
+			autoRenew, gracePeriod.Nanoseconds())
+		if err != nil {
+			fmt.Printf("err：%v\n", err)
+			return
+		}
+
+		fmt.Println("OK")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(updateNsLifecycleCmd)
+
+	updateNsLifecycleCmd.Flags().StringVarP(&privateKey, "privkey", "k", "", "private key")
+	updateNsLifecycleCmd.Flags().StringVarP(&keyPath, "keyPath", "", "./ukeys", "key path")
+	updateNsLifecycleCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "namespace for file")
+	updateNsLifecycleCmd.Flags().DurationVarP(&defaultTTL, "defaultTTL", "", 0, "files uploaded without expire time expire after it, example '720h'")
+	updateNsLifecycleCmd.Flags().BoolVarP(&autoRenew, "autoRenew", "", false, "renew files used by DAI tasks in progress by defaultTTL before they expire")
+	updateNsLifecycleCmd.Flags().DurationVarP(&gracePeriod, "gracePeriod", "", 0, "delete files expired for the grace period, no longer than 168h, example '72h'")
+
+	updateNsLifecycleCmd.MarkFlagRequired("namespace")
+}
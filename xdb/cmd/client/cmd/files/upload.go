@@ -23,7 +23,9 @@ import (
 	"github.com/spf13/cobra"
 
 	httpclient "github.com/PaddlePaddle/PaddleDTX/xdb/client/http"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/compressor"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/file"
+	servertypes "github.com/PaddlePaddle/PaddleDTX/xdb/server/types"
 )
 
 var (
@@ -31,6 +33,7 @@ var (
 	description string
 	extra       string
 	expireTime  string
+	partSize    uint64
 )
 
 // uploadDataCmd represents the command to upload file into xuper db
@@ -50,13 +53,23 @@ var uploadCmd = &cobra.Command{
 			return
 		}
 		defer f.Close()
-
-		stamp, err := time.ParseInLocation(timeTemplate, expireTime, time.Local)
+		info, err := f.Stat()
 		if err != nil {
 			fmt.Printf("err：%v\n", err)
 			return
 		}
 
+		// files uploaded without expire time expire after the default TTL of namespace
+		var expire int64
+		if expireTime != "" {
+			stamp, err := time.ParseInLocation(timeTemplate, expireTime, time.Local)
+			if err != nil {
+				fmt.Printf("err：%v\n", err)
+				return
+			}
+			expire = stamp.UnixNano()
+		}
+
 		if privateKey == "" {
 			privateKeyBytes, err := file.ReadFile(keyPath, file.PrivateKeyFileName)
 			if err != nil {
@@ -66,22 +79,43 @@ var uploadCmd = &cobra.Command{
 			privateKey = strings.TrimSpace(string(privateKeyBytes))
 		}
 
-		opt := httpclient.WriteOptions{
-			PrivateKey:  privateKey,
-			Namespace:   namespace,
-			FileName:    filename,
-			ExpireTime:  stamp.UnixNano(),
-			Description: description,
-			Extra:       extra,
+		opt := httpclient.UploadOptions{
+			WriteOptions: httpclient.WriteOptions{
+				PrivateKey:  privateKey,
+				Namespace:   namespace,
+				FileName:    filename,
+				ExpireTime:  expire,
+				Description: description,
+				Extra:       extra,
+				Compression: compression,
+			},
+			PartSize: partSize,
 		}
 
-		resp, err := client.Write(context.Background(), f, opt)
+		// compressed files can not be uploaded in parts, the file is written in a single request
+		compressed := compressor.Enabled(compression)
+		if compression == "" {
+			ns, err := client.GetNsByName(context.Background(), "", namespace)
+			if err != nil {
+				fmt.Printf("err：%v\n", err)
+				return
+			}
+			compressed = compressor.Enabled(ns.Namespace.Compression)
+		}
+		var resp servertypes.WriteResponse
+		if compressed {
+			resp, err = client.Write(context.Background(), f, opt.WriteOptions)
+		} else {
+			// the file is uploaded in parts, run the command again to resume an interrupted upload
+			resp, err = client.Upload(context.Background(), f, info.Size(), opt)
+		}
 		if err != nil {
 			fmt.Printf("err：%v\n", err)
 			return
 		}
 
 		fmt.Println("FileID:", resp.FileID)
+		fmt.Println("Version:", resp.Version)
 	},
 }
 
@@ -94,11 +128,15 @@ func init() {
 	uploadCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "namespace for file")
 	uploadCmd.Flags().StringVarP(&filename, "filename", "m", "", "file name")
 	uploadCmd.Flags().StringVarP(&description, "description", "d", "", "file description")
-	uploadCmd.Flags().StringVarP(&expireTime, "expireTime", "e", "", "expire time, example '2021-06-10 12:00:00'")
+	uploadCmd.Flags().StringVarP(&expireTime, "expireTime", "e", "",
+		"expire time, example '2021-06-10 12:00:00', the default TTL of namespace is applied if not set")
 	uploadCmd.Flags().StringVar(&extra, "ext", "", "file extra info")
+	uploadCmd.Flags().Uint64Var(&partSize, "partSize", httpclient.DefaultPartSize,
+		"size of parts to upload the file in, must be a multiple of 64KB")
+	uploadCmd.Flags().StringVarP(&compression, "compression", "", "",
+		"algorithm to compress the file, 'zstd', 'gzip' or 'none', the compression of namespace by default")
 
 	uploadCmd.MarkFlagRequired("input")
 	uploadCmd.MarkFlagRequired("namespace")
 	uploadCmd.MarkFlagRequired("filename")
-	uploadCmd.MarkFlagRequired("expireTime")
 }
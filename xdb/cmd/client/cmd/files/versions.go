@@ -0,0 +1,78 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package files
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	httpclient "github.com/PaddlePaddle/PaddleDTX/xdb/client/http"
+)
+
+// listVersionsCmd represents the command to list versions of a file by namespace and file name
+var listVersionsCmd = &cobra.Command{
+	Use:   "versions",
+	Short: "list versions of the file in XuperDB, the latest first",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := httpclient.New(host)
+		if err != nil {
+			fmt.Printf("err：%v\n", err)
+			return
+		}
+		if limit > blockchain.ListMaxNumber {
+			fmt.Printf("invalid limit, the value must smaller than %v \n", blockchain.ListMaxNumber)
+			return
+		}
+
+		opt := httpclient.ListFileVersionsOptions{
+			Owner:     owner,
+			Namespace: namespace,
+			FileName:  filename,
+			Limit:     limit,
+		}
+		resp, err := client.ListFileVersions(context.Background(), opt)
+		if err != nil {
+			fmt.Printf("err：%v\n", err)
+			return
+		}
+		for _, f := range resp {
+			ptime := time.Unix(0, f.PublishTime).Format(timeTemplate)
+			etime := time.Unix(0, f.ExpireTime).Format(timeTemplate)
+			fmt.Printf("Version: %d\nFileID: %s\nFileDescription: %s\nFileLength: %v\nPublishTimes: %s\nExpireTime: %s\n\n",
+				fileVersion(f), f.ID, f.Description, f.Length, ptime, etime)
+		}
+		if len(resp) == 0 {
+			fmt.Printf("\nno versions\n\n")
+		} else {
+			fmt.Printf("\nversions num of %s: %d\n\n", filename, len(resp))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listVersionsCmd)
+
+	listVersionsCmd.Flags().StringVarP(&owner, "owner", "o", "", "owner for file")
+	listVersionsCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "namespace for file")
+	listVersionsCmd.Flags().StringVarP(&filename, "filename", "m", "", "file name")
+	listVersionsCmd.Flags().Int64VarP(&limit, "limit", "l", blockchain.ListMaxNumber, "limit for list versions")
+
+	listVersionsCmd.MarkFlagRequired("namespace")
+	listVersionsCmd.MarkFlagRequired("filename")
+}
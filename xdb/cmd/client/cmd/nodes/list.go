@@ -42,7 +42,8 @@ var listNodesCmd = &cobra.Command{
 		for _, n := range resp {
 			rtime := time.Unix(0, n.RegTime).Format(timeTemplate)
 			utime := time.Unix(0, n.UpdateAt).Format(timeTemplate)
-			fmt.Printf("NodeID: %s\nName: %s\nAddress: %s\nOnline: %v\nRegisterTime: %v\nUpdateTime: %v\n\n", n.ID, n.Name, n.Address, n.Online, rtime, utime)
+			fmt.Printf("NodeID: %s\nName: %s\nAddress: %s\nOnline: %v\nDraining: %v\nRegisterTime: %v\nUpdateTime: %v\n%s\n%s\n\n", n.ID, n.Name, n.Address, n.Online, n.Draining, rtime, utime,
+				formatLabels(n), formatCapacity(n.Capacity, n.FreeSpace))
 		}
 		if len(resp) == 0 {
 			fmt.Printf("\nThere are no storage nodes in the network\n\n")
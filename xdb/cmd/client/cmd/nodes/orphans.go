@@ -0,0 +1,53 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	httpclient "github.com/PaddlePaddle/PaddleDTX/xdb/client/http"
+)
+
+// getOrphanSlicesCmd represents the command to get slices on the storage node not referenced by any file on blockchain
+var getOrphanSlicesCmd = &cobra.Command{
+	Use:   "orphans",
+	Short: "get slices on the storage node not referenced by any file on blockchain",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := httpclient.New(host)
+		if err != nil {
+			fmt.Printf("err：%v\n", err)
+			return
+		}
+		orphans, err := client.GetOrphanSlices(context.Background())
+		if err != nil {
+			fmt.Printf("err：%v\n", err)
+			return
+		}
+		for _, o := range orphans {
+			pTime := time.Unix(0, o.PushedAt).Format(timeTemplate)
+			oTime := time.Unix(0, o.OrphanedAt).Format(timeTemplate)
+			fmt.Printf("\nfileID: %s, SliceID: %s, owner: %s, pushTime: %s, orphanTime: %s\n",
+				o.FileID, o.SliceID, o.Owner, pTime, oTime)
+		}
+		fmt.Printf("\norphan slices: %d\n\n", len(orphans))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(getOrphanSlicesCmd)
+}
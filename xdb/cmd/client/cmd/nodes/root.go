@@ -14,7 +14,11 @@
 package nodes
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
 )
 
 const timeTemplate = "2006-01-02 15:04:05"
@@ -46,3 +50,17 @@ func init() {
 
 	rootCmd.MarkPersistentFlagRequired("host")
 }
+
+// formatCapacity formats capacity reported by storage node heartbeats
+func formatCapacity(capacity, freeSpace uint64) string {
+	if capacity == 0 {
+		return "Capacity: unknown"
+	}
+	return fmt.Sprintf("Capacity: %d bytes\nFreeSpace: %d bytes (%.1f%%)", capacity, freeSpace,
+		float64(freeSpace)*100/float64(capacity))
+}
+
+// formatLabels formats failure domains registered with storage node
+func formatLabels(n blockchain.Node) string {
+	return fmt.Sprintf("Region: %s\nZone: %s\nRack: %s", n.Region, n.Zone, n.Rack)
+}
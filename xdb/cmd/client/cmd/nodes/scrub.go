@@ -0,0 +1,54 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	httpclient "github.com/PaddlePaddle/PaddleDTX/xdb/client/http"
+)
+
+// getScrubReportsCmd represents the command to get slices of a dataOwner node found corrupt or missing on the storage node
+var getScrubReportsCmd = &cobra.Command{
+	Use:   "scrub",
+	Short: "get slices of a dataOwner node found corrupt or missing by scrubbing on the storage node",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := httpclient.New(host)
+		if err != nil {
+			fmt.Printf("err：%v\n", err)
+			return
+		}
+		reports, err := client.GetScrubReports(context.Background(), owner)
+		if err != nil {
+			fmt.Printf("err：%v\n", err)
+			return
+		}
+		for _, r := range reports {
+			cTime := time.Unix(0, r.CheckedAt).Format(timeTemplate)
+			fmt.Printf("\nfileID: %s, SliceID: %s, status: %s, checkTime: %s\n", r.FileID, r.SliceID, r.Status, cTime)
+		}
+		fmt.Printf("\nslices corrupt or missing: %d\n\n", len(reports))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(getScrubReportsCmd)
+
+	getScrubReportsCmd.Flags().StringVarP(&owner, "owner", "o", "", "public key of the dataOwner node")
+	getScrubReportsCmd.MarkFlagRequired("owner")
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	httpclient "github.com/PaddlePaddle/PaddleDTX/xdb/client/http"
+)
+
+var owner string
+
+// getSliceUsageCmd represents the command to get the storage used by a dataOwner node on the storage node
+var getSliceUsageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "get the storage used by a dataOwner node on the storage node and its quota",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := httpclient.New(host)
+		if err != nil {
+			fmt.Printf("err：%v\n", err)
+			return
+		}
+		usage, err := client.GetSliceUsage(context.Background(), owner)
+		if err != nil {
+			fmt.Printf("err：%v\n", err)
+			return
+		}
+		fmt.Printf("Owner: %s\nBytes: %d\nSlices: %d\nMaxBytes: %d\nMaxSlices: %d\n",
+			usage.Owner, usage.Bytes, usage.Slices, usage.MaxBytes, usage.MaxSlices)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(getSliceUsageCmd)
+
+	getSliceUsageCmd.Flags().StringVarP(&owner, "owner", "o", "", "public key of the dataOwner node")
+	getSliceUsageCmd.MarkFlagRequired("owner")
+}
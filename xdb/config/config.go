@@ -40,6 +40,7 @@ type BlockchainConf struct {
 	Type   string
 	Xchain *XchainConf
 	Fabric *FabricConf
+	Local  *LocalChainConf
 }
 
 type XchainConf struct {
@@ -58,12 +59,35 @@ type FabricConf struct {
 	OrgName    string
 }
 
+// LocalChainConf is the configuration of the local blockchain, which runs the contract in process on leveldb
+type LocalChainConf struct {
+	LeveldbRoot string
+}
+
+// MonitorConf is the configuration of monitors, intervals are in hours.
+// Storage node clears orphan slices, which are not referenced by any file on blockchain for OrphanRetainPeriod,
+// every OrphanclearInterval if it's positive, and only reports them if OrphanclearDryRun.
+// OrphanRetainPeriod must be longer than the lifetime of upload sessions, whose slices are not on blockchain yet.
+// DataOwner node rebalances slices every RebalanceInterval if it's positive, moving at most RebalanceMoves
+// replicas a time from nodes whose utilization exceeds the average by RebalanceThreshold percent.
+// DataOwner node applies lifecycle policies of namespaces every LifecycleInterval if it's positive,
+// files expiring within LifecycleWarnBefore are renewed or warned of by posting to LifecycleWebhook
 type MonitorConf struct {
 	ChallengingSwitch    string
 	NodemaintainerSwitch string
 	FileclearInterval    int
+	OrphanclearInterval  int
+	OrphanRetainPeriod   int
+	OrphanclearDryRun    bool
 	FilemaintainerSwitch string
 	FilemigrateInterval  int
+	FilerekeyInterval    int
+	RebalanceInterval    int
+	RebalanceMoves       int
+	RebalanceThreshold   int
+	LifecycleInterval    int
+	LifecycleWarnBefore  int
+	LifecycleWebhook     string
 }
 
 type ServerConf struct {
@@ -27,11 +27,15 @@ type DataOwnerConf struct {
 	Copier     *DataOwnerCopierConf
 	Monitor    *MonitorConf
 	Challenger *DataOwnerChallenger
+	Upload     *DataOwnerUploadConf
+	Index      *DataOwnerIndexConf
+	Dedup      *DataOwnerDedupConf
 }
 
 type DataOwnerSlicerConf struct {
-	Type         string
-	SimpleSlicer *SimpleSlicerConf
+	Type          string
+	SimpleSlicer  *SimpleSlicerConf
+	FastCDCSlicer *FastCDCSlicerConf
 }
 
 type SimpleSlicerConf struct {
@@ -39,12 +43,30 @@ type SimpleSlicerConf struct {
 	QueueSize int64
 }
 
+type FastCDCSlicerConf struct {
+	MinSize   int64
+	AvgSize   int64
+	MaxSize   int64
+	QueueSize int64
+}
+
 type DataOwnerEncryptorConf struct {
 	Type          string
 	SoftEncryptor *SoftEncryptorConf
 }
 
+// SoftEncryptorConf is the configuration of master keys, Password is the key used before key versioning,
+// whose key ID is empty. Keys are versioned master keys, new content is encrypted with the one of CurrentKey,
+// and the others are kept to decrypt content encrypted before rotation
 type SoftEncryptorConf struct {
+	Password   string
+	CurrentKey string
+	Keys       []SoftEncryptorKey
+}
+
+// SoftEncryptorKey is a versioned master key
+type SoftEncryptorKey struct {
+	ID       string
 	Password string
 }
 
@@ -71,3 +93,26 @@ type ChallengerMerkleConf struct {
 type DataOwnerCopierConf struct {
 	Type string
 }
+
+// DataOwnerUploadConf is the configuration of uploading files in parts,
+// SessionTimeout is in hours, sessions not updated within it are abandoned and cleaned,
+// it must not exceed the lifetime of upload sessions
+type DataOwnerUploadConf struct {
+	LeveldbRoot    string
+	SessionTimeout int64
+}
+
+// DataOwnerIndexConf is the configuration of the local index of files and challenges, which is synced from
+// blockchain into LeveldbRoot every SyncInterval minutes, and searched and paged by cursors.
+// Files and challenges of the local node and Owners, public keys of dataOwner node clients, are indexed
+type DataOwnerIndexConf struct {
+	LeveldbRoot  string
+	SyncInterval int64
+	Owners       []string
+}
+
+// DataOwnerDedupConf is the configuration of the local index of slices of deduplicated namespaces,
+// which is looked up by hash of slice plaintext when writing files
+type DataOwnerDedupConf struct {
+	LeveldbRoot string
+}
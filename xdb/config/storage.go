@@ -20,16 +20,50 @@ type StorageConf struct {
 	KeyPath       string
 	PublicAddress string
 
+	// labels of failure domains the node lives in, registered on blockchain with the node
+	Region string
+	Zone   string
+	Rack   string
+
 	Blockchain *BlockchainConf
 	Monitor    *MonitorConf
 	Mode       *StorageModeConf
 	Prover     *ProverConf
+	Quota      *QuotaConf
+	Scrub      *ScrubConf
 }
 
 type StorageModeConf struct {
 	Type  string
 	Local *LocalConf
 	Ipfs  *IPFSConf
+	S3    *S3Conf
+	Pack  *PackConf
+}
+
+// QuotaConf limits the bytes and the number of slices each dataOwner node stores on the storage node,
+// zero means unlimited. Owners override the limits for some dataOwner nodes, usage is recorded in LocalRoot
+type QuotaConf struct {
+	LocalRoot string
+	MaxBytes  int64
+	MaxSlices int64
+	Owners    []OwnerQuotaConf
+}
+
+// OwnerQuotaConf is the quota of a dataOwner node, Owner is its public key
+type OwnerQuotaConf struct {
+	Owner     string
+	MaxBytes  int64
+	MaxSlices int64
+}
+
+// ScrubConf is the configuration of slice scrubbing, slices stored are re-read every Interval hours and checked
+// against the hash of ciphertext on blockchain, reading at most BytesPerSecond bytes per second, zero means unlimited.
+// Slices tracked and those found corrupt or missing are recorded in LocalRoot
+type ScrubConf struct {
+	LocalRoot      string
+	Interval       int64
+	BytesPerSecond int64
 }
 
 type ProverConf struct {
@@ -44,3 +78,27 @@ type IPFSConf struct {
 	Hosts   []string
 	Timeout int64
 }
+
+// PackConf is the configuration of pack-file storage, slices are appended into segment files of SegmentSize bytes
+// under RootPath. Segments whose garbage left by deleted slices reaches CompactRatio of their size
+// are compacted every CompactInterval hours
+type PackConf struct {
+	RootPath        string
+	SegmentSize     int64
+	CompactRatio    float64
+	CompactInterval int64
+}
+
+// S3Conf is the configuration of S3 compatible object storage, slices are stored in Bucket with keys
+// prefixed by Prefix. Slices larger than PartSize bytes are uploaded in parts, Timeout is in milliseconds
+type S3Conf struct {
+	Endpoint           string
+	Region             string
+	Bucket             string
+	Prefix             string
+	AccessKey          string
+	SecretKey          string
+	VirtualHostedStyle bool
+	PartSize           int64
+	Timeout            int64
+}
@@ -114,6 +114,22 @@ func (s *LevelDBStorage) Load(key []byte) (types.Material, error) {
 	return m, nil
 }
 
+func (s *LevelDBStorage) Delete(prefix []byte) error {
+	keyList, err := s.NewIterator(prefix)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate")
+	}
+	batch := leveldb.Batch{}
+	for _, key := range keyList {
+		batch.Delete(key)
+	}
+	if err := s.db.Write(&batch, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write batch")
+	}
+
+	return nil
+}
+
 func (s *LevelDBStorage) Close() {
 	s.db.Close()
 }
@@ -243,6 +243,14 @@ func (m *RandChallenger) Take(fileID string, sliceID string, nodeID []byte) (
 	return ctype.RangeHash{}, errorx.Wrap(errorx.ErrNotFound, "no available challenger materials")
 }
 
+// Remove removes challenge materials of a slice stored on the node, such as replicas dropped
+func (m *RandChallenger) Remove(fileID string, sliceID string, nodeID []byte) error {
+	if err := m.storage.Delete([]byte(fmt.Sprintf("%s:%s:%x:", fileID, sliceID, nodeID))); err != nil {
+		return errorx.Wrap(err, "failed to remove challenge materials")
+	}
+	return nil
+}
+
 func (m *RandChallenger) Close() {
 	m.closeOnce.Do(m.storage.Close)
 }
@@ -171,3 +171,8 @@ func (m *RandChallenger) Save(cms []ctype.Material) error {
 func (m *RandChallenger) Take(fileID string, sliceID string, nodeID []byte) (c ctype.RangeHash, err error) {
 	return c, errorx.New(errorx.ErrCodeInternal, "pairing not implemented method Take")
 }
+
+// Remove does nothing for random challenge, pairing based challenge material is stored with slices on storage nodes
+func (m *RandChallenger) Remove(fileID string, sliceID string, nodeID []byte) error {
+	return nil
+}
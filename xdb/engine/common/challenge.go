@@ -72,12 +72,16 @@ func AddFileNewMerkleChallenge(ctx context.Context, challenger CommonChallenger,
 	}
 	nodesMap := ToNodeHsMap(nodes)
 
+	// referenced slices are challenged by their source files
+	slices := file.OwnSlices()
 	selectedNodes := make(map[string][]string)
-	for _, slice := range file.Slices {
+	replicas := make(map[string]blockchain.PublicSliceMeta)
+	for _, slice := range slices {
 		selectedNodes[slice.ID] = append(selectedNodes[slice.ID], string(slice.NodeID))
+		replicas[slice.ID+string(slice.NodeID)] = slice
 	}
 
-	for _, target := range file.Slices {
+	for _, target := range slices {
 		// pull slice
 		success := false
 		node, exist := nodesMap[string(target.NodeID)]
@@ -134,6 +138,7 @@ func AddFileNewMerkleChallenge(ctx context.Context, challenger CommonChallenger,
 						FileID:  file.ID,
 						SliceID: target.ID,
 						NodeID:  newNode.ID,
+						KeyID:   replicas[target.ID+n].KeyID,
 					}
 					plain, err := chalEncryptor.Recover(r, opt)
 					if err != nil {
@@ -153,6 +158,11 @@ func AddFileNewMerkleChallenge(ctx context.Context, challenger CommonChallenger,
 						logger.WithField("node_id", string(target.NodeID)).WithError(err).Warn("failed to encrypt slice by target node")
 						continue
 					}
+					// the ciphertext differs from the one stored if the key of target has been rotated
+					if cipher.KeyID != target.KeyID {
+						logger.WithField("node_id", string(target.NodeID)).Warn("key of target slice rotated, unable to encrypt it again")
+						break
+					}
 					// get merkle challenge material
 					m, err := GetMCRange(challenger, file.ID, cipher, file.ExpireTime, startTime, interval)
 					if err != nil {
@@ -170,7 +180,7 @@ func AddFileNewMerkleChallenge(ctx context.Context, challenger CommonChallenger,
 		}
 	}
 
-	if len(file.Slices) != len(challengingMaterial) {
+	if len(slices) != len(challengingMaterial) {
 		return errorx.New(errorx.ErrCodeInternal, "failed to add new merkle challenges, slices number and challenge material number not equal")
 	}
 	return SaveMerkleChallenger(challenger, challengingMaterial)
@@ -321,7 +331,7 @@ func AddSlicesNewPairingChallenge(ctx context.Context, pairingConf types.Pairing
 				return
 			}
 
-			storIndex, err := copier.Push(ctx, sliceSigmaID, sourceID, bytes.NewReader(sigmasBytes), &node)
+			storIndex, err := copier.Push(ctx, sliceSigmaID, sourceID, "", bytes.NewReader(sigmasBytes), &node)
 			if err != nil {
 				pushErr = errorx.Wrap(err, "failed to push pairing based challenge material")
 				return
@@ -355,7 +365,8 @@ func AddFilePairingChallenges(ctx context.Context, pairingConf types.PairingChal
 
 	var addErr error
 	wg := sync.WaitGroup{}
-	for _, target := range file.Slices {
+	// referenced slices are challenged by their source files
+	for _, target := range file.OwnSlices() {
 		wg.Add(1)
 		go func(target blockchain.PublicSliceMeta) {
 			defer wg.Done()
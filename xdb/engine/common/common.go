@@ -31,11 +31,14 @@ var xchainClient = new(fl_crypto.XchainCryptoClient)
 // CommonCopier defines slice copier when migrating a file
 type CommonCopier interface {
 	// Push pushes slices onto Storage Node, returns storage index of slice
-	Push(ctx context.Context, id, sourceID string, r io.Reader, node *blockchain.Node) (string, error)
+	Push(ctx context.Context, id, sourceID, fileID string, r io.Reader, node *blockchain.Node) (string, error)
 
 	// Pull pulls slice from storage node
 	Pull(ctx context.Context, id, storIndex, fileID string, node *blockchain.Node) (io.ReadCloser, error)
 
+	// Delete removes slice from storage node
+	Delete(ctx context.Context, id, storIndex string, node *blockchain.Node) error
+
 	// ReplicaExpansion slice performs Replica-Expand, that is to
 	//  pull slices from original nodes and decrypt and re-encrypt those slices,
 	//  then push them onto new Storage Nodes.
@@ -48,6 +51,7 @@ type CommonChallenger interface {
 	Setup(sliceData []byte, rangeAmount int) ([]ctype.RangeHash, error)
 	Save(cms []ctype.Material) error
 	Take(fileID string, sliceID string, nodeID []byte) (ctype.RangeHash, error)
+	Remove(fileID string, sliceID string, nodeID []byte) error
 
 	GetChallengeConf() (string, types.PairingChallengeConf)
 }
@@ -69,3 +73,11 @@ type CommonChain interface {
 	ListFileNs(opt *blockchain.ListNsOptions) ([]blockchain.Namespace, error)
 	UpdateFilePublicSliceMeta(opt *blockchain.UpdateFilePSMOptions) error
 }
+
+// StreamSalts returns salts of the parts of a file in chunked format uploaded in parts, nil if there is none
+func StreamSalts(f blockchain.File) *encryptor.StreamSalts {
+	if len(f.PartSalts) == 0 {
+		return nil
+	}
+	return &encryptor.StreamSalts{PartSize: f.PartSize, Salts: f.PartSalts}
+}
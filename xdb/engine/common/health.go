@@ -55,13 +55,13 @@ func GetHealthNodes(chain CommonChain) (nodes blockchain.NodeHs, err error) {
 	return nodes, nil
 }
 
-// FindNewNodes selects a new healthy node for slice
+// FindNewNodes selects a new healthy node for slice, draining nodes are never selected
 func FindNewNodes(healthNodes blockchain.NodeHs, selected []string) (blockchain.Nodes, error) {
 	// get green and yellow nodes set
 	var greenNodeList blockchain.Nodes
 	var yellowNodeList blockchain.Nodes
 	for _, n := range healthNodes {
-		if strInSet(selected, string(n.Node.ID)) {
+		if strInSet(selected, string(n.Node.ID)) || n.Node.Draining {
 			continue
 		}
 		if n.Health == blockchain.NodeHealthGood {
@@ -146,6 +146,9 @@ func GetFileHealth(ctx context.Context, chain CommonChain, file blockchain.File,
 	if len(file.Slices) == 0 {
 		return health, errorx.New(errorx.ErrCodeInternal, "failed get file health, file is illegal")
 	}
+	if file.ErasureCoded() {
+		return getStripedFileHealth(ctx, chain, file)
+	}
 
 	sliceList := make(map[string][]string)
 	used := make(map[string]struct{})
@@ -210,6 +213,50 @@ func GetFileHealth(ctx context.Context, chain CommonChain, file blockchain.File,
 	return blockchain.NodeHealthMedium, nil
 }
 
+// getStripedFileHealth gets heath status of erasure coded file
+// a stripe can be recovered from any DataShards shards, so the file is red if any stripe has fewer
+// shards on non-red nodes than its data shards, and green only if all shards are on green nodes
+func getStripedFileHealth(ctx context.Context, chain CommonChain, file blockchain.File) (health string, err error) {
+	stripes := make(map[int][]string)
+	used := make(map[string]struct{})
+	var fileNodes []string
+	for _, eslice := range file.Slices {
+		stripes[eslice.Stripe] = append(stripes[eslice.Stripe], string(eslice.NodeID))
+		if _, exist := used[string(eslice.NodeID)]; !exist {
+			used[string(eslice.NodeID)] = struct{}{}
+			fileNodes = append(fileNodes, string(eslice.NodeID))
+		}
+	}
+
+	fileHealthNodes, err := getFileNodesHealth(ctx, fileNodes, chain)
+	if err != nil {
+		return health, errorx.Wrap(err, "failed to get file node health")
+	}
+
+	health = blockchain.NodeHealthGood
+	for _, nodes := range stripes {
+		dataShards := len(nodes) - file.ParityShards
+		available, green := 0, 0
+		for _, node := range nodes {
+			switch fileHealthNodes[node] {
+			case blockchain.NodeHealthGood:
+				green += 1
+				available += 1
+			case blockchain.NodeHealthMedium:
+				available += 1
+			}
+		}
+		// stripe cannot be recovered
+		if dataShards <= 0 || available < dataShards {
+			return blockchain.NodeHealthBad, nil
+		}
+		if green < len(nodes) {
+			health = blockchain.NodeHealthMedium
+		}
+	}
+	return health, nil
+}
+
 // GetSliceAvgHealth get slice health status
 func GetSliceAvgHealth(nodes []string, fhns map[string]string) (health string, err error) {
 	var nhlist []string
@@ -19,6 +19,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"io/ioutil"
+	"sort"
 	"time"
 
 	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
@@ -60,9 +61,10 @@ func PullAndDec(ctx context.Context, copier CommonCopier, encrypt CommonEncrypto
 
 	// decrypt the slice
 	decOpt := encryptor.RecoverOptions{
-		FileID:  fileID,
+		FileID:  slice.KeyFileID(fileID),
 		SliceID: slice.ID,
 		NodeID:  node.ID,
+		KeyID:   slice.KeyID,
 	}
 	return encrypt.Recover(bytes.NewReader(cipherText), &decOpt)
 }
@@ -82,23 +84,24 @@ func EncAndPush(ctx context.Context, copier CommonCopier, encrypt CommonEncrypto
 		return es, "", err
 	}
 
-	storIndex, err := copier.Push(ctx, es.SliceID, sourceID, bytes.NewReader(es.CipherText), node)
+	storIndex, err := copier.Push(ctx, es.SliceID, sourceID, fileID, bytes.NewReader(es.CipherText), node)
 	return es, storIndex, err
 }
 
-// ExpandFileSlices expand each slice to specific replica
+// ExpandFileSlices expand each slice to specific replica, new replicas are placed by the placement policy
 // 1. find new storage node for the slice
 // 2. pull slice from other node and re-encrypt for new node
 // 3. push slice to new storage node
 // 4. generate challenge material for new storage node
 func ExpandFileSlices(ctx context.Context, privkey ecdsa.PrivateKey, cp CommonCopier, enc CommonEncryptor, chain CommonChain,
 	challenger CommonChallenger, file blockchain.File, nodesMap map[string]blockchain.Node, replica int,
-	healthNodes blockchain.NodeHs, interval int64, l *logrus.Entry) error {
+	placement *blockchain.PlacementPolicy, healthNodes blockchain.NodeHs, interval int64, l *logrus.Entry) error {
 
 	slices := file.Slices
 	ca, pairingConf := challenger.GetChallengeConf()
 	oldSliceLen := len(slices)
-	sliceNodesMap := GetSliceNodes(slices, nodesMap)
+	// referenced slices are expanded by their source files
+	sliceNodesMap := GetSliceNodes(file.OwnSlices(), nodesMap)
 
 	// record new slices
 	var expandSlices []encryptor.EncryptedSlice
@@ -115,6 +118,7 @@ func ExpandFileSlices(ctx context.Context, privkey ecdsa.PrivateKey, cp CommonCo
 			NodesList:     healthNodes,
 			PrivateKey:    privkey[:],
 			SliceMetas:    slices,
+			Placement:     placement,
 		}
 		if ca == types.PairingChallengeAlgorithm {
 			opt.PairingConf = pairingConf
@@ -171,22 +175,82 @@ func ExpandFileSlices(ctx context.Context, privkey ecdsa.PrivateKey, cp CommonCo
 	}
 
 	// update file slices on blockchain
-	opt := blockchain.UpdateFilePSMOptions{
-		FileID: file.ID,
-		Owner:  file.Owner,
-		Slices: slices,
+	if err := updateFileSlices(chain, privkey, file, slices); err != nil {
+		l.WithFields(logrus.Fields{
+			"file_id":     file.ID,
+			"new_replica": replica,
+		}).WithError(err).Error("failed to update file slice meta")
+		return err
 	}
-	msg, err := util.GetSigMessage(opt)
-	if err != nil {
-		return errorx.Internal(err, "failed to get the message to sign")
+
+	l.WithFields(logrus.Fields{
+		"file_id":       file.ID,
+		"new_replica":   replica,
+		"new_slice_len": len(slices),
+		"old_slice_len": oldSliceLen,
+	}).Info("success file slices expanded")
+	return nil
+}
+
+// ReduceFileSlices drops surplus replicas of each slice down to specific replica
+// 1. pick the replicas on the least healthy nodes to drop
+// 2. update file slices on blockchain
+// 3. delete dropped replicas from storage nodes
+// 4. remove challenge material of dropped replicas
+func ReduceFileSlices(ctx context.Context, privkey ecdsa.PrivateKey, cp CommonCopier, chain CommonChain,
+	challenger CommonChallenger, file blockchain.File, nodesMap map[string]blockchain.Node, replica int,
+	l *logrus.Entry) error {
+
+	// referenced slices are reduced by their source files
+	sliceReplicas := make(map[string][]blockchain.PublicSliceMeta)
+	var sliceIDs []string
+	for _, slice := range file.OwnSlices() {
+		if _, exist := sliceReplicas[slice.ID]; !exist {
+			sliceIDs = append(sliceIDs, slice.ID)
+		}
+		sliceReplicas[slice.ID] = append(sliceReplicas[slice.ID], slice)
 	}
-	sign, err := ecdsa.Sign(privkey, xchainClient.HashUsingSha256([]byte(msg)))
-	if err != nil {
-		return errorx.NewCode(err, errorx.ErrCodeCrypto, "failed to sign slices")
+
+	ranks := make(map[string]int)
+	dropped := make(map[[2]string]struct{})
+	var droppedSlices []blockchain.PublicSliceMeta
+	for _, sid := range sliceIDs {
+		replicas := sliceReplicas[sid]
+		if len(replicas) <= replica {
+			continue
+		}
+		for _, slice := range replicas {
+			if _, exist := ranks[string(slice.NodeID)]; exist {
+				continue
+			}
+			rank, err := nodeHealthRank(chain, nodesMap, slice.NodeID)
+			if err != nil {
+				return err
+			}
+			ranks[string(slice.NodeID)] = rank
+		}
+		sort.SliceStable(replicas, func(i, j int) bool {
+			return ranks[string(replicas[i].NodeID)] < ranks[string(replicas[j].NodeID)]
+		})
+		for _, slice := range replicas[:len(replicas)-replica] {
+			dropped[[2]string{slice.ID, string(slice.NodeID)}] = struct{}{}
+			droppedSlices = append(droppedSlices, slice)
+		}
+	}
+	if len(droppedSlices) == 0 {
+		return nil
+	}
+
+	slices := make([]blockchain.PublicSliceMeta, 0, len(file.Slices)-len(droppedSlices))
+	for _, slice := range file.Slices {
+		if _, exist := dropped[[2]string{slice.ID, string(slice.NodeID)}]; exist && !slice.Referenced() {
+			continue
+		}
+		slices = append(slices, slice)
 	}
-	opt.Signature = sign[:]
 
-	if err := chain.UpdateFilePublicSliceMeta(&opt); err != nil {
+	// update file slices on blockchain before replicas are deleted, so that they are never read
+	if err := updateFileSlices(chain, privkey, file, slices); err != nil {
 		l.WithFields(logrus.Fields{
 			"file_id":     file.ID,
 			"new_replica": replica,
@@ -194,11 +258,71 @@ func ExpandFileSlices(ctx context.Context, privkey ecdsa.PrivateKey, cp CommonCo
 		return err
 	}
 
+	// dropped replicas are no longer recorded on blockchain, replicas failed to delete are cleared as orphans
+	for _, slice := range droppedSlices {
+		fields := logrus.Fields{
+			"file_id":     file.ID,
+			"slice_id":    slice.ID,
+			"target_node": string(slice.NodeID),
+		}
+		if node, exist := nodesMap[string(slice.NodeID)]; exist {
+			if err := cp.Delete(ctx, slice.ID, slice.StorIndex, &node); err != nil {
+				l.WithFields(fields).WithError(err).Warn("failed to delete dropped replica")
+			}
+		}
+		if err := challenger.Remove(file.ID, slice.ID, slice.NodeID); err != nil {
+			l.WithFields(fields).WithError(err).Warn("failed to remove challenge material of dropped replica")
+		}
+	}
+
 	l.WithFields(logrus.Fields{
 		"file_id":       file.ID,
 		"new_replica":   replica,
 		"new_slice_len": len(slices),
-		"old_slice_len": oldSliceLen,
-	}).Info("success file slices expanded")
+		"old_slice_len": len(file.Slices),
+	}).Info("success file slices reduced")
 	return nil
 }
+
+// nodeHealthRank ranks a node by its health, the lower the less healthy,
+// nodes offline or draining are ranked lowest
+func nodeHealthRank(chain CommonChain, nodesMap map[string]blockchain.Node, id []byte) (int, error) {
+	node, exist := nodesMap[string(id)]
+	if !exist || !node.Online || node.Draining {
+		return 0, nil
+	}
+	health, err := chain.GetNodeHealth(id)
+	if err != nil {
+		return 0, errorx.Wrap(err, "failed to get node health")
+	}
+	switch health {
+	case blockchain.NodeHealthGood:
+		return 3, nil
+	case blockchain.NodeHealthMedium:
+		return 2, nil
+	default:
+		return 1, nil
+	}
+}
+
+// updateFileSlices updates file slices on blockchain
+func updateFileSlices(chain CommonChain, privkey ecdsa.PrivateKey, file blockchain.File,
+	slices []blockchain.PublicSliceMeta) error {
+
+	opt := blockchain.UpdateFilePSMOptions{
+		FileID: file.ID,
+		Owner:  file.Owner,
+		Slices: slices,
+	}
+	msg, err := util.GetSigMessage(opt)
+	if err != nil {
+		return errorx.Internal(err, "failed to get the message to sign")
+	}
+	sign, err := ecdsa.Sign(privkey, xchainClient.HashUsingSha256([]byte(msg)))
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeCrypto, "failed to sign slices")
+	}
+	opt.Signature = sign[:]
+
+	return chain.UpdateFilePublicSliceMeta(&opt)
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// ZoneOf returns the zone a node lives in qualified by its region, empty if unknown
+func ZoneOf(n blockchain.Node) string {
+	if n.Zone == "" {
+		return ""
+	}
+	return n.Region + "/" + n.Zone
+}
+
+// RackOf returns the rack a node lives in qualified by its zone, empty if unknown
+func RackOf(n blockchain.Node) string {
+	if n.Zone == "" || n.Rack == "" {
+		return ""
+	}
+	return ZoneOf(n) + "/" + n.Rack
+}
+
+// CountZones returns the number of distinct known zones of nodes
+func CountZones(nodes blockchain.Nodes) int {
+	zones := make(map[string]struct{})
+	for _, n := range nodes {
+		if z := ZoneOf(n); z != "" {
+			zones[z] = struct{}{}
+		}
+	}
+	return len(zones)
+}
+
+// failure domain tiers of a candidate node relative to the nodes holding replicas
+const (
+	tierNewZone = iota
+	tierNewRack
+	tierUsed
+)
+
+// domainTiers groups candidates by failure domain tiers, the relative order in each tier is kept
+func domainTiers(candidates, holders blockchain.Nodes) [3]blockchain.Nodes {
+	zones := make(map[string]struct{})
+	racks := make(map[string]struct{})
+	for _, n := range holders {
+		zones[ZoneOf(n)] = struct{}{}
+		racks[RackOf(n)] = struct{}{}
+	}
+
+	var tiers [3]blockchain.Nodes
+	for _, n := range candidates {
+		tier := tierUsed
+		if z := ZoneOf(n); z != "" {
+			if _, used := zones[z]; !used {
+				tier = tierNewZone
+			} else if r := RackOf(n); r != "" {
+				if _, used := racks[r]; !used {
+					tier = tierNewRack
+				}
+			}
+		}
+		tiers[tier] = append(tiers[tier], n)
+	}
+	return tiers
+}
+
+// NewZoneNodes returns candidates in known zones not used by holders
+func NewZoneNodes(candidates, holders blockchain.Nodes) blockchain.Nodes {
+	return domainTiers(candidates, holders)[tierNewZone]
+}
+
+// PreferredNodes returns candidates spreading replicas across the most failure domains:
+// nodes in zones not used by holders if any, otherwise nodes in racks not used, otherwise all candidates
+func PreferredNodes(candidates, holders blockchain.Nodes) blockchain.Nodes {
+	for _, nodes := range domainTiers(candidates, holders) {
+		if len(nodes) > 0 {
+			return nodes
+		}
+	}
+	return candidates
+}
+
+// SpreadNodes orders candidates by failure domains relative to holders,
+// nodes in new zones first, then nodes in new racks, then the others
+func SpreadNodes(candidates, holders blockchain.Nodes) blockchain.Nodes {
+	tiers := domainTiers(candidates, holders)
+	nodes := make(blockchain.Nodes, 0, len(candidates))
+	for _, t := range tiers {
+		nodes = append(nodes, t...)
+	}
+	return nodes
+}
+
+// PlaceNewNodes finds new healthy nodes for a slice like FindNewNodes, and applies the placement policy.
+// holders are the nodes keeping replicas of the slice, or shards of the same stripe, once it's placed.
+// Only nodes in regions allowed are returned, nodes spreading replicas across more failure domains come first,
+// and if holders are spread across fewer zones than required, only nodes in new zones are returned
+func PlaceNewNodes(healthNodes blockchain.NodeHs, excludes, holders []string,
+	policy *blockchain.PlacementPolicy) (blockchain.Nodes, error) {
+
+	newNodes, err := FindNewNodes(healthNodes, excludes)
+	if err != nil {
+		return nil, err
+	}
+	var holderNodes blockchain.Nodes
+	for _, n := range healthNodes {
+		if strInSet(holders, string(n.Node.ID)) {
+			holderNodes = append(holderNodes, n.Node)
+		}
+	}
+
+	var allowed blockchain.Nodes
+	for _, n := range newNodes {
+		if policy.Allows(n) {
+			allowed = append(allowed, n)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil, errorx.New(errorx.ErrCodeNotFound, "no more healthy nodes in regions allowed by placement policy")
+	}
+	if CountZones(holderNodes) < policy.ZonesRequired() {
+		allowed = NewZoneNodes(allowed, holderNodes)
+		if len(allowed) == 0 {
+			return nil, errorx.New(errorx.ErrCodeNotFound, "no more healthy nodes to spread replicas across %d zones",
+				policy.ZonesRequired())
+		}
+	}
+	return SpreadNodes(allowed, holderNodes), nil
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// SliceFetcher pulls ciphertext of a replica from the storage node holding it
+type SliceFetcher func(ctx context.Context, target blockchain.PublicSliceMeta, node *blockchain.Node) (io.ReadCloser, error)
+
+// SliceDecrypter decrypts ciphertext of a replica pulled from its storage node
+type SliceDecrypter func(target blockchain.PublicSliceMeta, cipherText []byte) ([]byte, error)
+
+// ShardReader reads plaintext of a slice of the file
+type ShardReader func(ctx context.Context, slice blockchain.PrivateSliceMeta) ([]byte, error)
+
+// PullSlice pulls a slice from one of the storage nodes in targetPool, checks its length and hash and decrypts it.
+// Replicas on nodes offline, or failing in pulling, checking or decryption are skipped
+func PullSlice(ctx context.Context, fileID, sliceID string, targetPool []blockchain.PublicSliceMeta,
+	nodesMap map[string]blockchain.Node, fetch SliceFetcher, decrypt SliceDecrypter, l *logrus.Entry) ([]byte, error) {
+	for _, target := range targetPool {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		// pull slice
+		node, exist := nodesMap[string(target.NodeID)]
+		if !exist || !node.Online {
+			l.WithField("node_id", string(target.NodeID)).Warn("abnormal node")
+			continue
+		}
+
+		r, err := fetch(ctx, target, &node)
+		if err != nil {
+			l.WithFields(logrus.Fields{
+				"slice_id":         target.ID,
+				"slice_stor_index": target.StorIndex,
+				"file_id":          fileID,
+				"target_node":      string(node.ID),
+			}).WithError(err).Warn("failed to pull slice")
+			continue
+		}
+
+		// read
+		cipherText, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			l.WithError(err).Warn("failed to read slice from target node")
+			continue
+		}
+		if len(cipherText) != int(target.Length) {
+			l.WithFields(logrus.Fields{"expected": target.Length, "got": len(cipherText)}).
+				Warn("invalid slice length.")
+			continue
+		}
+		hGot := xchainClient.HashUsingSha256(cipherText)
+		if !bytes.Equal(hGot, target.CipherHash) {
+			l.WithFields(logrus.Fields{"expected": target.CipherHash, "got": hGot}).
+				Warn("invalid slice hash.")
+			continue
+		}
+
+		// decrypt
+		plainText, err := decrypt(target, cipherText)
+		if err != nil {
+			l.WithError(err).Error("failed to decrypt slice")
+			continue
+		}
+		return plainText, nil
+	}
+
+	return nil, errorx.New(errorx.ErrCodeNotFound, "failed to pull slice %s", sliceID)
+}
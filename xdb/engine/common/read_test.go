@@ -0,0 +1,72 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+func TestPullSlice(t *testing.T) {
+	cipherText := []byte("ciphertext")
+	replica := func(nodeID string) blockchain.PublicSliceMeta {
+		return blockchain.PublicSliceMeta{
+			ID:         "s1",
+			NodeID:     []byte(nodeID),
+			Length:     uint64(len(cipherText)),
+			CipherHash: xchainClient.HashUsingSha256(cipherText),
+		}
+	}
+	nodesMap := map[string]blockchain.Node{
+		"offline": {ID: []byte("offline")},
+		"broken":  {ID: []byte("broken"), Online: true},
+		"corrupt": {ID: []byte("corrupt"), Online: true},
+		"good":    {ID: []byte("good"), Online: true},
+	}
+	var fetched []string
+	fetch := func(ctx context.Context, target blockchain.PublicSliceMeta, node *blockchain.Node) (io.ReadCloser, error) {
+		fetched = append(fetched, string(node.ID))
+		switch string(node.ID) {
+		case "broken":
+			return nil, errorx.New(errorx.ErrCodeNotFound, "slice not found")
+		case "corrupt":
+			return ioutil.NopCloser(bytes.NewReader([]byte("CIPHERTEXT"))), nil
+		}
+		return ioutil.NopCloser(bytes.NewReader(cipherText)), nil
+	}
+	decrypt := func(target blockchain.PublicSliceMeta, cipherText []byte) ([]byte, error) {
+		return bytes.ToUpper(cipherText), nil
+	}
+	l := logrus.WithField("test", "read")
+
+	// replicas on offline nodes, failing in pulling or with mismatched hash are skipped
+	pool := []blockchain.PublicSliceMeta{replica("offline"), replica("unknown"), replica("broken"),
+		replica("corrupt"), replica("good")}
+	plainText, err := PullSlice(context.Background(), "f1", "s1", pool, nodesMap, fetch, decrypt, l)
+	require.NoError(t, err)
+	require.Equal(t, "CIPHERTEXT", string(plainText))
+	require.Equal(t, []string{"broken", "corrupt", "good"}, fetched)
+
+	_, err = PullSlice(context.Background(), "f1", "s1", pool[:4], nodesMap, fetch, decrypt, l)
+	require.True(t, errorx.Is(err, errorx.ErrCodeNotFound))
+}
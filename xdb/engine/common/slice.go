@@ -0,0 +1,68 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+
+	"github.com/google/uuid"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// sliceSourceSpace is the name space to derive keys recording sources of slices
+var sliceSourceSpace = uuid.MustParse("5d3c6f2e-8a41-4f7b-9c52-1e0d7a6b3f90")
+
+// GetSliceSourceKey returns the key in prove storage of storage node which records the dataOwner node
+// pushing a slice. Keys of local storage must be uuid, so the key is derived from slice id by name based uuid.
+func GetSliceSourceKey(sliceID string) string {
+	return uuid.NewSHA1(sliceSourceSpace, []byte(sliceID)).String()
+}
+
+// ContentReader reads content pushed to storage node, and fails at the end of content
+// if its sha256 does not match the hash signed by the dataOwner node
+type ContentReader struct {
+	r        io.Reader
+	h        hash.Hash
+	expected []byte
+	mismatch bool
+}
+
+// NewContentReader creates a ContentReader, contentHash is hex encoded sha256 of the content
+func NewContentReader(r io.Reader, contentHash string) (*ContentReader, error) {
+	expected, err := hex.DecodeString(contentHash)
+	if err != nil || len(expected) != sha256.Size {
+		return nil, errorx.New(errorx.ErrCodeParam, "bad content hash")
+	}
+	return &ContentReader{r: r, h: sha256.New(), expected: expected}, nil
+}
+
+func (c *ContentReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.h.Write(p[:n])
+	if err == io.EOF && !bytes.Equal(c.h.Sum(nil), c.expected) {
+		c.mismatch = true
+		return n, errorx.New(errorx.ErrCodeParam, "content hash mismatch")
+	}
+	return n, err
+}
+
+// Mismatched returns true if the content read does not match the hash
+func (c *ContentReader) Mismatched() bool {
+	return c.mismatch
+}
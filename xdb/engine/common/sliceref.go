@@ -0,0 +1,72 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+)
+
+// FileGetter gets files from blockchain, source files of referenced slices are got by it
+type FileGetter interface {
+	GetFileByID(id string) (blockchain.File, error)
+}
+
+// MakeSlicesPool4Read groups replicas of slices by slice ID
+func MakeSlicesPool4Read(srs []blockchain.PublicSliceMeta) map[string][]blockchain.PublicSliceMeta {
+	slicesPool := make(map[string][]blockchain.PublicSliceMeta)
+	for _, s := range srs {
+		slicesPool[s.ID] = append(slicesPool[s.ID], s)
+	}
+	return slicesPool
+}
+
+// ResolveSliceRefs replaces replicas of referenced slices by the current ones of their source files,
+// which may have been migrated since the file was published.
+// Replicas recorded in the file are kept if the source file can not be read.
+func ResolveSliceRefs(chain FileGetter, slices []blockchain.PublicSliceMeta, l *logrus.Entry) []blockchain.PublicSliceMeta {
+	sources := make(map[string]map[string][]blockchain.PublicSliceMeta)
+	resolved := make(map[string]bool)
+	var res []blockchain.PublicSliceMeta
+	for _, s := range slices {
+		if !s.Referenced() {
+			res = append(res, s)
+			continue
+		}
+		if resolved[s.ID] {
+			continue
+		}
+		pool, ok := sources[s.SourceFileID]
+		if !ok {
+			if f, err := chain.GetFileByID(s.SourceFileID); err == nil {
+				pool = MakeSlicesPool4Read(f.OwnSlices())
+			} else {
+				l.WithField("file_id", s.SourceFileID).WithError(err).Warn("failed to get source file of referenced slice")
+			}
+			sources[s.SourceFileID] = pool
+		}
+		replicas, ok := pool[s.ID]
+		if !ok {
+			res = append(res, s)
+			continue
+		}
+		for _, r := range replicas {
+			r.SourceFileID = s.SourceFileID
+			res = append(res, r)
+		}
+		resolved[s.ID] = true
+	}
+	return res
+}
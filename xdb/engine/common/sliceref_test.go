@@ -0,0 +1,62 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+type filesByID map[string]blockchain.File
+
+func (f filesByID) GetFileByID(id string) (blockchain.File, error) {
+	file, ok := f[id]
+	if !ok {
+		return file, errorx.New(errorx.ErrCodeNotFound, "file not found")
+	}
+	return file, nil
+}
+
+func TestResolveSliceRefs(t *testing.T) {
+	// s1 of f1 is migrated from n1 to n2 and n3 after f2 references it
+	files := filesByID{
+		"f1": {ID: "f1", Slices: []blockchain.PublicSliceMeta{
+			{ID: "s1", NodeID: []byte("n2")},
+			{ID: "s1", NodeID: []byte("n3")},
+			{ID: "s2", NodeID: []byte("n1")},
+		}},
+	}
+	slices := []blockchain.PublicSliceMeta{
+		{ID: "s1", NodeID: []byte("n1"), SourceFileID: "f1"},
+		{ID: "s3", NodeID: []byte("n1")},
+		{ID: "s4", NodeID: []byte("n1"), SourceFileID: "f0"},
+	}
+	resolved := ResolveSliceRefs(files, slices, logrus.WithField("test", "sliceref"))
+	require.Equal(t, []blockchain.PublicSliceMeta{
+		{ID: "s1", NodeID: []byte("n2"), SourceFileID: "f1"},
+		{ID: "s1", NodeID: []byte("n3"), SourceFileID: "f1"},
+		{ID: "s3", NodeID: []byte("n1")},
+		// replicas recorded are kept if the source file is not found
+		{ID: "s4", NodeID: []byte("n1"), SourceFileID: "f0"},
+	}, resolved)
+
+	pool := MakeSlicesPool4Read(resolved)
+	require.Len(t, pool, 3)
+	require.Len(t, pool["s1"], 2)
+}
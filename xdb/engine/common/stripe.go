@@ -0,0 +1,196 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/erasure"
+)
+
+// ReconstructStripe rebuilds the missing shards of a stripe in place,
+// plains are plaintext of shards in the order of the stripe's private meta, missing ones are nil
+func ReconstructStripe(plains [][]byte, shards blockchain.FileStructure) error {
+	if len(plains) != len(shards) {
+		return errorx.New(errorx.ErrCodeInternal, "bad stripe, shards number mismatch")
+	}
+	dataShards := 0
+	missing := false
+	for i, s := range shards {
+		if !s.Parity {
+			dataShards++
+		}
+		if plains[i] == nil {
+			missing = true
+		}
+	}
+	if !missing {
+		return nil
+	}
+
+	if err := erasure.Reconstruct(plains, dataShards); err != nil {
+		return err
+	}
+	// remove the padding and verify the rebuilt shards
+	for i, s := range shards {
+		if uint64(len(plains[i])) > s.Length {
+			plains[i] = plains[i][:s.Length]
+		}
+		if !bytes.Equal(xchainClient.HashUsingSha256(plains[i]), s.PlainHash) {
+			return errorx.New(errorx.ErrCodeCrypto, "hash of reconstructed shard %s not match", s.SliceID)
+		}
+	}
+	return nil
+}
+
+// ReadStripe reads enough shards of a stripe by read to recover its data slices,
+// and reconstructs the missing ones from parity shards.
+// shards is the private meta of the stripe in order, returns plaintext of data slices in order.
+func ReadStripe(ctx context.Context, shards blockchain.FileStructure, read ShardReader, l *logrus.Entry) ([][]byte, error) {
+	dataShards := 0
+	for _, s := range shards {
+		if !s.Parity {
+			dataShards++
+		}
+	}
+
+	got := 0
+	plains := make([][]byte, len(shards))
+	// data shards first, parity shards are only pulled when some data shards are missing
+	for i, s := range shards {
+		if got == dataShards {
+			break
+		}
+		plain, err := read(ctx, s)
+		if err != nil {
+			l.WithField("slice_id", s.SliceID).WithError(err).Warn("failed to pull shard")
+			continue
+		}
+		plains[i] = plain
+		got++
+	}
+	if got < dataShards {
+		return nil, errorx.New(errorx.ErrCodeNotFound, "failed to pull enough shards of stripe %d", shards[0].Stripe)
+	}
+
+	if err := ReconstructStripe(plains, shards); err != nil {
+		return nil, err
+	}
+	return plains[:dataShards], nil
+}
+
+// GroupStripes splits file structure into stripes in order,
+// each slice is a stripe by itself if the file is not erasure coded
+func GroupStripes(fs blockchain.FileStructure, erasureCoded bool) []blockchain.FileStructure {
+	var stripes []blockchain.FileStructure
+	for i, s := range fs {
+		if !erasureCoded || i == 0 || s.Stripe != fs[i-1].Stripe {
+			stripes = append(stripes, blockchain.FileStructure{s})
+			continue
+		}
+		stripes[len(stripes)-1] = append(stripes[len(stripes)-1], s)
+	}
+	return stripes
+}
+
+// StripeOf returns private meta of the stripe which the slice belongs to, in stripe order
+func StripeOf(fs blockchain.FileStructure, sliceID string) (blockchain.FileStructure, error) {
+	stripe := -1
+	for _, s := range fs {
+		if s.SliceID == sliceID {
+			stripe = s.Stripe
+			break
+		}
+	}
+	if stripe < 0 {
+		return nil, errorx.New(errorx.ErrCodeNotFound, "slice %s not found in file structure", sliceID)
+	}
+
+	var shards blockchain.FileStructure
+	for _, s := range fs {
+		if s.Stripe == stripe {
+			shards = append(shards, s)
+		}
+	}
+	return shards, nil
+}
+
+// RecoverStripeShard recovers plaintext of a shard of an erasure coded file from other shards of the same stripe,
+// it is used when the shard can not be pulled from any storage node
+func RecoverStripeShard(ctx context.Context, cp CommonCopier, enc CommonEncryptor, file blockchain.File,
+	sliceID string, nodesMap map[string]blockchain.Node) ([]byte, error) {
+
+	// decrypt file structure to find the stripe
+	raw, err := enc.Recover(bytes.NewReader(file.Structure), &encryptor.RecoverOptions{FileID: file.ID,
+		KeyID: file.KeyID, WrappedKey: file.WrappedKey})
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeCrypto, "failed to decrypt file structure")
+	}
+	var fs blockchain.FileStructure
+	if err := fs.Parse(raw); err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to parse file structure")
+	}
+	shards, err := StripeOf(fs, sliceID)
+	if err != nil {
+		return nil, err
+	}
+
+	dataShards := 0
+	for _, s := range shards {
+		if !s.Parity {
+			dataShards++
+		}
+	}
+
+	got := 0
+	target := -1
+	plains := make([][]byte, len(shards))
+	for i, s := range shards {
+		if s.SliceID == sliceID {
+			target = i
+			continue
+		}
+		if got == dataShards {
+			continue
+		}
+		for _, slice := range file.Slices {
+			if slice.ID != s.SliceID {
+				continue
+			}
+			node, exist := nodesMap[string(slice.NodeID)]
+			if !exist {
+				continue
+			}
+			if plain, err := PullAndDec(ctx, cp, enc, slice, &node, file.ID); err == nil {
+				plains[i] = plain
+				got++
+				break
+			}
+		}
+	}
+	if got < dataShards {
+		return nil, errorx.New(errorx.ErrCodeNotFound, "failed to pull enough shards to recover slice %s", sliceID)
+	}
+
+	if err := ReconstructStripe(plains, shards); err != nil {
+		return nil, err
+	}
+	return plains[target], nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/erasure"
+)
+
+func TestReadStripe(t *testing.T) {
+	// two stripes of 2 data shards and 1 parity shard, the last data shard is shorter
+	stripesData := [][][]byte{
+		{[]byte("0123456789"), []byte("abcdefghij")},
+		{[]byte("ABCDEFGHIJ"), []byte("klm")},
+	}
+	var fs blockchain.FileStructure
+	shards := make(map[string][]byte)
+	for i, data := range stripesData {
+		parity, err := erasure.Encode(data, 1)
+		require.NoError(t, err)
+		for j, d := range append(data, parity...) {
+			id := fmt.Sprintf("s%d-%d", i, j)
+			shards[id] = d
+			fs = append(fs, blockchain.PrivateSliceMeta{
+				SliceID:   id,
+				PlainHash: hash.HashUsingSha256(d),
+				Length:    uint64(len(d)),
+				Stripe:    i,
+				Parity:    j >= len(data),
+			})
+		}
+	}
+
+	// the first data shard of the last stripe is missing
+	missing := "s1-0"
+	var pulled []string
+	pull := func(ctx context.Context, slice blockchain.PrivateSliceMeta) ([]byte, error) {
+		pulled = append(pulled, slice.SliceID)
+		if slice.SliceID == missing {
+			return nil, errorx.New(errorx.ErrCodeNotFound, "failed to pull slice %s", slice.SliceID)
+		}
+		return shards[slice.SliceID], nil
+	}
+
+	var file []byte
+	for _, stripe := range GroupStripes(fs, true) {
+		plains, err := ReadStripe(context.Background(), stripe, pull, logrus.WithField("test", "stripe"))
+		require.NoError(t, err)
+		require.Equal(t, 2, len(plains))
+		for _, p := range plains {
+			file = append(file, p...)
+		}
+	}
+	require.Equal(t, "0123456789abcdefghijABCDEFGHIJklm", string(file))
+	// parity shards are pulled only for the stripe with a missing shard
+	require.Equal(t, []string{"s0-0", "s0-1", "s1-0", "s1-1", "s1-2"}, pulled)
+
+	// a stripe can not be rebuilt if more shards than parity shards are missing
+	shards["s1-2"] = nil
+	_, err := ReadStripe(context.Background(), GroupStripes(fs, true)[1], func(ctx context.Context,
+		slice blockchain.PrivateSliceMeta) ([]byte, error) {
+		if shards[slice.SliceID] == nil || slice.SliceID == missing {
+			return nil, errorx.New(errorx.ErrCodeNotFound, "failed to pull slice %s", slice.SliceID)
+		}
+		return shards[slice.SliceID], nil
+	}, logrus.WithField("test", "stripe"))
+	require.Error(t, err)
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compressor
+
+import (
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// Algorithms to compress file plaintext before encryption, recorded in blockchain.File.Compression
+const (
+	// None disables compression of a file written into a namespace which compresses files
+	None = "none"
+	Gzip = "gzip"
+	Zstd = "zstd"
+)
+
+// Compressor compresses and decompresses data as a stream
+type Compressor interface {
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+var compressors = map[string]Compressor{
+	Gzip: gzipCompressor{},
+	Zstd: zstdCompressor{},
+}
+
+// Register adds a compression algorithm, it should be called on initialization
+func Register(algorithm string, c Compressor) {
+	compressors[algorithm] = c
+}
+
+// Enabled returns true if data is compressed by the algorithm
+func Enabled(algorithm string) bool {
+	return algorithm != "" && algorithm != None
+}
+
+// Check checks if the algorithm is supported, empty algorithm and None mean no compression
+func Check(algorithm string) error {
+	if !Enabled(algorithm) {
+		return nil
+	}
+	if _, ok := compressors[algorithm]; !ok {
+		return errorx.New(errorx.ErrCodeParam, "unsupported compression %s", algorithm)
+	}
+	return nil
+}
+
+// Compress returns a reader of data read from r and compressed by the algorithm.
+// Errors are returned when reading from the returned reader, which must be closed
+// if it is not read to the end
+func Compress(algorithm string, r io.Reader) (io.ReadCloser, error) {
+	c, ok := compressors[algorithm]
+	if !ok {
+		return nil, errorx.New(errorx.ErrCodeParam, "unsupported compression %s", algorithm)
+	}
+	reader, writer := io.Pipe()
+	w, err := c.NewWriter(writer)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to create %s writer", algorithm)
+	}
+	go func() {
+		if _, err := io.Copy(w, r); err != nil {
+			w.Close()
+			writer.CloseWithError(err)
+			return
+		}
+		writer.CloseWithError(w.Close())
+	}()
+	return reader, nil
+}
+
+// Decompress returns a reader of data decompressed from r by the algorithm
+func Decompress(algorithm string, r io.Reader) (io.ReadCloser, error) {
+	c, ok := compressors[algorithm]
+	if !ok {
+		return nil, errorx.New(errorx.ErrCodeParam, "unsupported compression %s", algorithm)
+	}
+	reader, err := c.NewReader(r)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeCrypto, "failed to create %s reader", algorithm)
+	}
+	return reader, nil
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+type zstdCompressor struct{}
+
+func (zstdCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+func (zstdCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return zstdReader{d}, nil
+}
+
+// zstdReader releases resources of zstd.Decoder on Close
+type zstdReader struct {
+	*zstd.Decoder
+}
+
+func (z zstdReader) Close() error {
+	z.Decoder.Close()
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compressor
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressAndDecompress(t *testing.T) {
+	data := bytes.Repeat([]byte("id,age,income\n1,35,9000\n2,42,12000\n"), 1000)
+	for _, algorithm := range []string{Gzip, Zstd} {
+		r, err := Compress(algorithm, bytes.NewReader(data))
+		require.NoError(t, err)
+		compressed, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		require.True(t, len(compressed) < len(data)/5, algorithm)
+
+		dr, err := Decompress(algorithm, bytes.NewReader(compressed))
+		require.NoError(t, err)
+		plain, err := ioutil.ReadAll(dr)
+		require.NoError(t, err)
+		require.NoError(t, dr.Close())
+		require.Equal(t, data, plain, algorithm)
+
+		// corrupted data
+		dr, err = Decompress(algorithm, bytes.NewReader(compressed[:len(compressed)/2]))
+		if err == nil {
+			_, err = ioutil.ReadAll(dr)
+		}
+		require.Error(t, err, algorithm)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	require.NoError(t, Check(""))
+	require.NoError(t, Check(None))
+	require.NoError(t, Check(Gzip))
+	require.NoError(t, Check(Zstd))
+	require.Error(t, Check("lz4"))
+	require.False(t, Enabled(None))
+	require.True(t, Enabled(Zstd))
+}
@@ -27,10 +27,15 @@ type LocatedSlice struct {
 
 // SelectOptions contains some options for selecting Storage Nodes
 //  Replica is the number of replicas
-//  Excludes is a reserved field, not used
+//  Excludes is the set of nodes which must not be selected
+//  Placement is the placement policy of the namespace, nil if not restricted
+//  Holders are nodes already selected for the same group, such as other shards of the same stripe,
+//  replicas are spread across failure domains together with them
 type SelectOptions struct {
-	Replica  uint32
-	Excludes map[string]struct{} // nodeID -> struct{}
+	Replica   uint32
+	Excludes  map[string]struct{} // nodeID -> struct{}
+	Placement *blockchain.PlacementPolicy
+	Holders   blockchain.Nodes
 }
 
 // ReplicaExpOptions contains some options for expanding replicas.
@@ -42,4 +47,5 @@ type ReplicaExpOptions struct {
 	NodesList     blockchain.NodeHs            // all node lists
 	SliceMetas    []blockchain.PublicSliceMeta // slice metas
 	PairingConf   types.PairingChallengeConf   // pairing based challenge config
+	Placement     *blockchain.PlacementPolicy  // placement policy of the namespace, nil if not restricted
 }
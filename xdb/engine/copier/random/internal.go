@@ -60,6 +60,49 @@ func getSliceOptimalNodes(nodeHs blockchain.NodeHs, replica int) (nodes blockcha
 	return nodes
 }
 
+// getNodeWeights weights nodes by free space, a node with unknown capacity is weighted by
+// the average of nodes with known capacity, or 1 if none is known. The ranges of weights
+// are consecutive and the end of each range is inclusive, as bSearch expects
+func getNodeWeights(nodes blockchain.Nodes) []nodeWeight {
+	var known, sum uint64
+	for _, n := range nodes {
+		if n.Capacity > 0 {
+			known++
+			sum += n.FreeSpace
+		}
+	}
+	average := uint64(1)
+	if known > 0 && sum/known > 0 {
+		average = sum / known
+	}
+
+	weights := make([]nodeWeight, 0, len(nodes))
+	var start uint64
+	for _, n := range nodes {
+		w := average
+		if n.Capacity > 0 {
+			w = n.FreeSpace
+		}
+		if w == 0 {
+			w = 1
+		}
+		weights = append(weights, nodeWeight{nodeID: n.ID, start: start, end: start + w - 1})
+		start += w
+	}
+	return weights
+}
+
+// removeNode removes the node from nodes, nodes is not modified
+func removeNode(nodes blockchain.Nodes, id []byte) blockchain.Nodes {
+	rest := make(blockchain.Nodes, 0, len(nodes))
+	for _, n := range nodes {
+		if string(n.ID) != string(id) {
+			rest = append(rest, n)
+		}
+	}
+	return rest
+}
+
 // bSearch must exist
 func bSearch(nodes []nodeWeight, target uint64) []byte {
 	i, j := 0, len(nodes)
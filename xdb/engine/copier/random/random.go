@@ -14,10 +14,15 @@
 package random
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math/rand"
+	"strings"
 	"time"
 
 	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
@@ -69,6 +74,28 @@ func (m *RandomCopier) Select(slice slicer.Slice, nodes blockchain.NodeHs, opt *
 		opt.Excludes = make(map[string]struct{})
 	}
 
+	// skip excluded nodes, such as nodes storing other shards of the same stripe,
+	// draining nodes and nodes without enough free space for the slice
+	size := slice.Length
+	if uint64(len(slice.Data)) > size {
+		size = uint64(len(slice.Data))
+	}
+	var candidates blockchain.NodeHs
+	for _, n := range nodes {
+		if _, exist := opt.Excludes[string(n.Node.ID)]; exist || n.Node.Draining {
+			continue
+		}
+		if n.Node.Capacity > 0 && n.Node.FreeSpace < size {
+			continue
+		}
+		// nodes out of the regions allowed by placement policy are never selected
+		if !opt.Placement.Allows(n.Node) {
+			continue
+		}
+		candidates = append(candidates, n)
+	}
+	nodes = candidates
+
 	if len(nodes) == 0 {
 		return copier.LocatedSlice{}, errorx.New(errorx.ErrCodeInternal, "empty nodes array")
 	}
@@ -80,23 +107,42 @@ func (m *RandomCopier) Select(slice slicer.Slice, nodes blockchain.NodeHs, opt *
 
 	rand.Seed(time.Now().UnixNano())
 
-	// green nodes first
+	// green nodes first, nodes with more free space are more likely to be selected
 	nodesList := getSliceOptimalNodes(nodes, targetReplica)
-	used := make(map[int]struct{})
+	var all blockchain.Nodes
+	for _, n := range nodes {
+		all = append(all, n.Node)
+	}
+	holders := append(blockchain.Nodes{}, opt.Holders...)
+	zonesRequired := opt.Placement.ZonesRequired()
+
 	var selected blockchain.Nodes
-	for {
-		if len(selected) == targetReplica {
-			break
-		}
-		if len(selected) == len(nodesList) {
-			break
+	for len(selected) < targetReplica && len(nodesList) > 0 {
+		// replicas are spread across failure domains, nodes in zones or racks not used yet are preferred
+		pool := common.PreferredNodes(nodesList, holders)
+		if common.CountZones(holders) < zonesRequired {
+			// a node in a new zone is required, which may only be found in less healthy nodes
+			if pool = common.NewZoneNodes(nodesList, holders); len(pool) == 0 {
+				pool = common.NewZoneNodes(all, holders)
+			}
+			if len(pool) == 0 {
+				return copier.LocatedSlice{}, errorx.New(errorx.ErrCodeNotFound,
+					"no node to spread replicas across %d zones", zonesRequired)
+			}
 		}
 
-		index := rand.Int() % len(nodesList)
-		if _, existed := used[index]; !existed {
-			used[index] = struct{}{}
-			selected = append(selected, nodesList[index])
+		weights := getNodeWeights(pool)
+		target := rand.Uint64() % (weights[len(weights)-1].end + 1)
+		id := bSearch(weights, target)
+		for _, n := range pool {
+			if string(n.ID) == string(id) {
+				selected = append(selected, n)
+				holders = append(holders, n)
+				break
+			}
 		}
+		nodesList = removeNode(nodesList, id)
+		all = removeNode(all, id)
 	}
 	logger.WithFields(logrus.Fields{
 		"slice_id":       slice.ID,
@@ -111,11 +157,35 @@ func (m *RandomCopier) Select(slice slicer.Slice, nodes blockchain.NodeHs, opt *
 	return ls, nil
 }
 
-// Push pushes slices onto Storage Node
-// returns storage index of slice
-func (m *RandomCopier) Push(ctx context.Context, id, sourceID string, r io.Reader, node *blockchain.Node) (string, error) {
-	// Todo add signature when pushing slices into storage nodes
-	url := fmt.Sprintf("http://%s/v1/slice/push?slice_id=%s&source_id=%s", node.Address, id, sourceID)
+// Push pushes slices onto Storage Node, fileID is the file the slice belongs to,
+// which the storage node scrubs the slice against. Returns storage index of slice
+func (m *RandomCopier) Push(ctx context.Context, id, sourceID, fileID string, r io.Reader, node *blockchain.Node) (string, error) {
+	contentHash, r, err := hashContent(r)
+	if err != nil {
+		return "", err
+	}
+	// Add signature when pushing slices into storage nodes, the storage node verifies it with the same options,
+	// and the content against the hash signed
+	timestamp := time.Now().UnixNano()
+	sliceID := strings.TrimSuffix(id, common.ChallengeFileSuffix)
+	msg, err := util.GetSigMessage(types.PushOptions{
+		SliceID:     sliceID,
+		SourceID:    sourceID,
+		FileID:      fileID,
+		Timestamp:   timestamp,
+		NotASlice:   sliceID != id,
+		ContentHash: contentHash,
+	})
+	if err != nil {
+		return "", errorx.Internal(err, "failed to get the message to sign for push slices")
+	}
+
+	sig, err := ecdsa.Sign(m.privateKey, hash.HashUsingSha256([]byte(msg)))
+	if err != nil {
+		return "", errorx.Wrap(err, "failed to sign slice push")
+	}
+	url := fmt.Sprintf("http://%s/v1/slice/push?slice_id=%s&source_id=%s&file_id=%s&timestamp=%d&content_hash=%s&signature=%s",
+		node.Address, id, sourceID, fileID, timestamp, contentHash, sig.String())
 
 	var resp types.PushResponse
 	if err := http.PostResponse(ctx, url, r, &resp); err != nil {
@@ -130,6 +200,31 @@ func (m *RandomCopier) Push(ctx context.Context, id, sourceID string, r io.Reade
 	return resp.SliceStorIndex, nil
 }
 
+// hashContent returns the hex encoded sha256 of content read from r, and a reader to read the content again.
+// r is rewound if it is seekable, otherwise the content is read into memory
+func hashContent(r io.Reader) (string, io.Reader, error) {
+	h := sha256.New()
+	if rs, ok := r.(io.ReadSeeker); ok {
+		start, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return "", nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to seek content")
+		}
+		if _, err := io.Copy(h, rs); err != nil {
+			return "", nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read content")
+		}
+		if _, err := rs.Seek(start, io.SeekStart); err != nil {
+			return "", nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to seek content")
+		}
+		return hex.EncodeToString(h.Sum(nil)), rs, nil
+	}
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read content")
+	}
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil)), bytes.NewReader(content), nil
+}
+
 // Pull pulls slice from storage no
 func (m *RandomCopier) Pull(ctx context.Context, id, storIndex, fileID string, node *blockchain.Node) (io.ReadCloser, error) {
 	// Add signature when pulling slices from storage nodes
@@ -164,6 +259,49 @@ func (m *RandomCopier) Pull(ctx context.Context, id, storIndex, fileID string, n
 	return r, nil
 }
 
+// Delete deletes slice from storage node, only the slices pushed by the local node can be deleted
+func (m *RandomCopier) Delete(ctx context.Context, id, storIndex string, node *blockchain.Node) error {
+	// Add signature when deleting slices from storage nodes
+	timestamp := time.Now().UnixNano()
+	msg, err := util.GetSigMessage(types.DeleteSliceOptions{
+		SliceID:   id,
+		StorIndex: storIndex,
+		Timestamp: timestamp,
+	})
+	if err != nil {
+		return errorx.Internal(err, "failed to get the message to sign for delete slices")
+	}
+
+	sig, err := ecdsa.Sign(m.privateKey, hash.HashUsingSha256([]byte(msg)))
+	if err != nil {
+		return errorx.Wrap(err, "failed to sign slice delete")
+	}
+	url := fmt.Sprintf("http://%s/v1/slice/delete?slice_id=%s&slice_stor_index=%s&timestamp=%d&signature=%s",
+		node.Address, id, storIndex, timestamp, sig.String())
+
+	if _, err := http.Post(ctx, url, nil); err != nil {
+		return errorx.Wrap(err, "failed to do post")
+	}
+
+	logger.WithFields(logrus.Fields{
+		"SliceId":        id,
+		"SliceStorIndex": storIndex,
+	}).Debug("successfully deleted")
+
+	return nil
+}
+
+// ScrubReports gets slices pushed by owner found corrupt or missing by scrubbing on storage node
+func (m *RandomCopier) ScrubReports(ctx context.Context, owner string, node *blockchain.Node) ([]types.SliceScrub, error) {
+	url := fmt.Sprintf("http://%s/v1/slice/scrub?owner=%s", node.Address, owner)
+
+	var reports []types.SliceScrub
+	if err := http.GetResponse(ctx, url, &reports); err != nil {
+		return nil, errorx.Wrap(err, "failed to do get")
+	}
+	return reports, nil
+}
+
 // ReplicaExpansion slice performs Replica-Expand, that is to
 //  pull slices from original nodes and decrypt and re-encrypt those slices,
 //  then push them onto new Storage Nodes.
@@ -171,7 +309,7 @@ func (m *RandomCopier) ReplicaExpansion(ctx context.Context, opt *copier.Replica
 	enc common.CommonEncryptor, challengeAlgorithm, sourceID, fileID string) (
 	nSlice []blockchain.PublicSliceMeta, eSlices []encryptor.EncryptedSlice, err error) {
 	// 1 get more proper storage nodes
-	nNodes, err := getOptionalNode(opt.SelectedNodes, opt.NodesList)
+	nNodes, err := getOptionalNode(opt.SelectedNodes, opt.NodesList, opt.Placement)
 	if err != nil {
 		return nSlice, eSlices, errorx.NewCode(err, errorx.ErrCodeInternal, "no optional node to expand replica")
 	}
@@ -202,6 +340,7 @@ func (m *RandomCopier) ReplicaExpansion(ctx context.Context, opt *copier.Replica
 				Length:     es.Length,
 				NodeID:     es.NodeID,
 				StorIndex:  storIndex,
+				KeyID:      es.KeyID,
 			}
 			if challengeAlgorithm == types.PairingChallengeAlgorithm {
 				// 4 gets SliceIdx
@@ -220,7 +359,7 @@ func (m *RandomCopier) ReplicaExpansion(ctx context.Context, opt *copier.Replica
 			return nSlice, eSlices, errorx.Wrap(err, "replica slice re-pushed error")
 		}
 		if i+1 < sliceExpandNum {
-			nNodes, err = getOptionalNode(opt.SelectedNodes, opt.NodesList)
+			nNodes, err = getOptionalNode(opt.SelectedNodes, opt.NodesList, opt.Placement)
 			if err != nil {
 				return nSlice, eSlices, errorx.Wrap(err, "no optional node to expand replica")
 			}
@@ -229,13 +368,14 @@ func (m *RandomCopier) ReplicaExpansion(ctx context.Context, opt *copier.Replica
 	return nSlice, eSlices, nil
 }
 
-// getOptionalNode gets more proper Storage Nodes
-func getOptionalNode(selectedNodes blockchain.Nodes, allNodes blockchain.NodeHs) (expandNodes blockchain.Nodes, err error) {
+// getOptionalNode gets more proper Storage Nodes, restricted by the placement policy
+func getOptionalNode(selectedNodes blockchain.Nodes, allNodes blockchain.NodeHs,
+	placement *blockchain.PlacementPolicy) (expandNodes blockchain.Nodes, err error) {
 	var selectedS []string
 	for _, n := range selectedNodes {
 		selectedS = append(selectedS, string(n.ID))
 	}
-	return common.FindNewNodes(allNodes, selectedS)
+	return common.PlaceNewNodes(allNodes, selectedS, selectedS, placement)
 }
 
 // pullSlice pull slices from selected Storage Nodes
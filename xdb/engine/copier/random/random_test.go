@@ -14,13 +14,19 @@
 package random
 
 import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/common"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/copier"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/slicer"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
 )
 
 func TestBSearch(t *testing.T) {
@@ -121,4 +127,211 @@ func TestSelection(t *testing.T) {
 	ls, err = c.Select(slice, nodes, &copier.SelectOptions{Replica: 3})
 	require.NoError(t, err)
 	require.Equal(t, 3, len(ls.Nodes))
+	// select with excluded nodes
+	ls, err = c.Select(slice, nodes, &copier.SelectOptions{
+		Replica:  3,
+		Excludes: map[string]struct{}{string(node1.Node.ID): {}},
+	})
+	require.NoError(t, err)
+	for _, n := range ls.Nodes {
+		require.NotEqual(t, node1.Node.ID, n.ID)
+	}
+}
+
+func TestCapacitySelection(t *testing.T) {
+	c := &RandomCopier{}
+
+	slice := slicer.Slice{}
+	slice.ID = "hello"
+	slice.Data = []byte("0a0b")
+
+	full := blockchain.NodeH{
+		Node: blockchain.Node{
+			ID:        []byte{1},
+			Capacity:  1000,
+			FreeSpace: 2,
+		},
+		Health: blockchain.NodeHealthGood,
+	}
+	free := blockchain.NodeH{
+		Node: blockchain.Node{
+			ID:        []byte{2},
+			Capacity:  1000,
+			FreeSpace: 500,
+		},
+		Health: blockchain.NodeHealthGood,
+	}
+	unknown := blockchain.NodeH{
+		Node: blockchain.Node{
+			ID: []byte{3},
+		},
+		Health: blockchain.NodeHealthGood,
+	}
+
+	// nodes without enough free space are never selected
+	nodes := blockchain.NodeHs{full, free, unknown}
+	for i := 0; i < 20; i++ {
+		ls, err := c.Select(slice, nodes, &copier.SelectOptions{Replica: 3})
+		require.NoError(t, err)
+		require.Equal(t, 2, len(ls.Nodes))
+		for _, n := range ls.Nodes {
+			require.NotEqual(t, full.Node.ID, n.ID)
+		}
+	}
+
+	_, err := c.Select(slice, blockchain.NodeHs{full}, &copier.SelectOptions{Replica: 1})
+	require.Error(t, err)
+}
+
+func TestDrainingSelection(t *testing.T) {
+	c := &RandomCopier{}
+
+	slice := slicer.Slice{}
+	slice.ID = "hello"
+	slice.Data = []byte("0a0b")
+
+	draining := blockchain.NodeH{
+		Node: blockchain.Node{
+			ID:       []byte{1},
+			Online:   true,
+			Draining: true,
+		},
+		Health: blockchain.NodeHealthGood,
+	}
+	online := blockchain.NodeH{
+		Node: blockchain.Node{
+			ID:     []byte{2},
+			Online: true,
+		},
+		Health: blockchain.NodeHealthGood,
+	}
+
+	// draining nodes are never selected
+	for i := 0; i < 20; i++ {
+		ls, err := c.Select(slice, blockchain.NodeHs{draining, online}, &copier.SelectOptions{Replica: 2})
+		require.NoError(t, err)
+		require.Equal(t, 1, len(ls.Nodes))
+		require.Equal(t, online.Node.ID, ls.Nodes[0].ID)
+	}
+
+	_, err := c.Select(slice, blockchain.NodeHs{draining}, &copier.SelectOptions{Replica: 1})
+	require.Error(t, err)
+}
+
+func TestNodeWeights(t *testing.T) {
+	nodes := blockchain.Nodes{
+		{ID: []byte{1}, Capacity: 1000, FreeSpace: 100},
+		{ID: []byte{2}, Capacity: 1000, FreeSpace: 300},
+		{ID: []byte{3}},
+	}
+	weights := getNodeWeights(nodes)
+	require.Equal(t, []nodeWeight{
+		{nodeID: []byte{1}, start: 0, end: 99},
+		{nodeID: []byte{2}, start: 100, end: 399},
+		{nodeID: []byte{3}, start: 400, end: 599},
+	}, weights)
+	require.Equal(t, []byte{2}, bSearch(weights, 100))
+	require.Equal(t, []byte{3}, bSearch(weights, 599))
+
+	// weighted by 1 if no capacity is known
+	weights = getNodeWeights(blockchain.Nodes{{ID: []byte{1}}, {ID: []byte{2}}})
+	require.Equal(t, []nodeWeight{
+		{nodeID: []byte{1}, start: 0, end: 0},
+		{nodeID: []byte{2}, start: 1, end: 1},
+	}, weights)
+}
+
+func TestPlacementSelection(t *testing.T) {
+	c := &RandomCopier{}
+
+	slice := slicer.Slice{}
+	slice.ID = "hello"
+	slice.Data = []byte("0a0b")
+
+	newNode := func(id byte, region, zone, rack string) blockchain.NodeH {
+		return blockchain.NodeH{
+			Node: blockchain.Node{
+				ID:     []byte{id},
+				Region: region,
+				Zone:   zone,
+				Rack:   rack,
+			},
+			Health: blockchain.NodeHealthGood,
+		}
+	}
+	nodes := blockchain.NodeHs{
+		newNode(1, "r1", "z1", "a"),
+		newNode(2, "r1", "z1", "b"),
+		newNode(3, "r1", "z1", "b"),
+		newNode(4, "r1", "z2", "a"),
+		newNode(5, "r2", "z1", "a"),
+	}
+
+	// replicas are spread across zones and racks
+	for i := 0; i < 20; i++ {
+		ls, err := c.Select(slice, nodes, &copier.SelectOptions{Replica: 4})
+		require.NoError(t, err)
+		require.Equal(t, 4, len(ls.Nodes))
+		require.Equal(t, 3, common.CountZones(ls.Nodes))
+		// nodes 2 and 3 are in the same rack
+		racks := make(map[string]struct{})
+		for _, n := range ls.Nodes {
+			racks[common.RackOf(n)] = struct{}{}
+		}
+		require.Equal(t, 4, len(racks))
+	}
+
+	// restricted to allowed regions
+	policy := &blockchain.PlacementPolicy{MinZones: 2, Regions: []string{"r1"}}
+	for i := 0; i < 20; i++ {
+		ls, err := c.Select(slice, nodes, &copier.SelectOptions{Replica: 2, Placement: policy})
+		require.NoError(t, err)
+		require.Equal(t, 2, common.CountZones(ls.Nodes))
+		for _, n := range ls.Nodes {
+			require.Equal(t, "r1", n.Region)
+		}
+	}
+
+	// holders are taken into account
+	ls, err := c.Select(slice, nodes, &copier.SelectOptions{
+		Replica:   1,
+		Placement: policy,
+		Holders:   blockchain.Nodes{nodes[3].Node},
+		Excludes:  map[string]struct{}{string(nodes[3].Node.ID): {}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "z1", ls.Nodes[0].Zone)
+	require.Equal(t, "r1", ls.Nodes[0].Region)
+
+	// not enough zones
+	policy = &blockchain.PlacementPolicy{MinZones: 3, Regions: []string{"r1"}}
+	_, err = c.Select(slice, nodes, &copier.SelectOptions{Replica: 3, Placement: policy})
+	require.Error(t, err)
+}
+
+func TestHashContent(t *testing.T) {
+	content := []byte("slice content")
+	// seekable content is rewound, other content is read into memory
+	for _, r := range []io.Reader{bytes.NewReader(content), strings.NewReader(string(content)), bytes.NewBuffer(content)} {
+		contentHash, r, err := hashContent(r)
+		require.NoError(t, err)
+
+		// content read is verified by storage node against the hash
+		cr, err := common.NewContentReader(r, contentHash)
+		require.NoError(t, err)
+		got, err := ioutil.ReadAll(cr)
+		require.NoError(t, err)
+		require.Equal(t, content, got)
+	}
+
+	contentHash, _, err := hashContent(bytes.NewReader(content))
+	require.NoError(t, err)
+	cr, err := common.NewContentReader(strings.NewReader("replayed content"), contentHash)
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(cr)
+	require.True(t, errorx.Is(err, errorx.ErrCodeParam))
+	require.True(t, cr.Mismatched())
+
+	_, err = common.NewContentReader(bytes.NewReader(content), "bad hash")
+	require.Error(t, err)
 }
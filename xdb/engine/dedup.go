@@ -0,0 +1,328 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
+	"github.com/google/uuid"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/common"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/dedup"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/slicer"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// convergentSecretLabel is mixed into the secret of a namespace deriving keys of slices in convergent format
+const convergentSecretLabel = "xdb convergent secret"
+
+// contentDefinedSlicer is implemented by slicers which cut data at content-defined boundaries
+type contentDefinedSlicer interface {
+	ContentDefined() bool
+}
+
+// deduplicated returns true if files written into the namespace are deduplicated,
+// that requires a content-defined slicer and the dedup index, and erasure coded namespace is not supported
+// since shards of a stripe depend on each other
+func (e *Engine) deduplicated(ns blockchain.Namespace) bool {
+	s, ok := e.slicer.(contentDefinedSlicer)
+	return ok && s.ContentDefined() && e.dedup != nil && !ns.ErasureCoded()
+}
+
+// dedupSource is a slice stored by a file in convergent format, which can be referenced by new files
+type dedupSource struct {
+	meta       slicer.SliceMeta
+	replicas   []blockchain.PublicSliceMeta
+	expireTime int64
+}
+
+// dedupResult is the outcome of dedupRoutine
+type dedupResult struct {
+	metas []slicer.SliceMeta           // all slices of the file in order, hash and length of plaintext
+	refs  []blockchain.PublicSliceMeta // replicas of slices referenced from other files
+}
+
+// dedupIndex looks up slices stored by files in convergent format of the namespace in the local index,
+// which expire no earlier than expireTime, the expire time of the new file.
+// Only slices stored by a file itself are indexed, and the one of the file expiring last is preferred,
+// as referenced slices are challenged and migrated by their source files.
+type dedupIndex struct {
+	e          *Engine
+	owner      []byte
+	namespace  string
+	expireTime int64
+	files      map[string]*dedupFile // source files read from blockchain, nil if they can not be referenced
+}
+
+// dedupFile is a source file read from blockchain, with its slices by ID
+type dedupFile struct {
+	file     blockchain.File
+	metas    map[string]blockchain.PrivateSliceMeta
+	replicas map[string][]blockchain.PublicSliceMeta
+}
+
+// newDedupIndex returns the index to look up slices of the namespace for a new file expiring at expireTime,
+// files of the namespace published before are indexed on its first use
+func (e *Engine) newDedupIndex(owner []byte, namespace string, expireTime int64) (*dedupIndex, error) {
+	built, err := e.dedup.Built(owner, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if !built {
+		if err := e.buildDedupIndex(owner, namespace); err != nil {
+			return nil, errorx.Wrap(err, "failed to build dedup index of namespace %s", namespace)
+		}
+	}
+	return &dedupIndex{
+		e:          e,
+		owner:      owner,
+		namespace:  namespace,
+		expireTime: expireTime,
+		files:      make(map[string]*dedupFile),
+	}, nil
+}
+
+// buildDedupIndex indexes unexpired files in convergent format of the namespace, listed page by page
+func (e *Engine) buildDedupIndex(owner []byte, namespace string) error {
+	now := time.Now().UnixNano()
+	opt := blockchain.ListFileOptions{
+		Owner:       owner,
+		Namespace:   namespace,
+		TimeEnd:     now,
+		Limit:       indexSyncBatchSize,
+		CurrentTime: now,
+	}
+	for {
+		files, err := e.chain.ListFiles(&opt)
+		if err != nil {
+			return errorx.Wrap(err, "failed to list files from blockchain")
+		}
+		for _, f := range files {
+			if f.CipherFormat != encryptor.FormatConvergentGCM {
+				continue
+			}
+			fs, err := e.recoverChainFileStructure(f)
+			if err != nil {
+				logger.WithField("file_id", f.ID).WithError(err).Warn("failed to recover file structure for deduplication")
+				continue
+			}
+			hashes := make(map[string][]byte, len(fs))
+			for _, s := range fs {
+				hashes[s.SliceID] = s.PlainHash
+			}
+			if err := e.dedup.Put(toDedupFile(f, hashes)); err != nil {
+				return err
+			}
+		}
+		if opt.Cursor = blockchain.NextFileCursor(files, opt.Limit); opt.Cursor == "" {
+			break
+		}
+	}
+	return e.dedup.SetBuilt(owner, namespace)
+}
+
+// indexDedupFile indexes slices stored by a file in convergent format published by the node,
+// metas are all slices of the file with hash of their plaintext
+func (e *Engine) indexDedupFile(f blockchain.File, metas []slicer.SliceMeta) {
+	hashes := make(map[string][]byte, len(metas))
+	for _, m := range metas {
+		hashes[m.ID] = m.Hash
+	}
+	if err := e.dedup.Put(toDedupFile(f, hashes)); err != nil {
+		logger.WithField("file_id", f.ID).WithError(err).Warn("failed to index file for deduplication")
+	}
+}
+
+// unindexDedupFile removes a file deleted by the node from the dedup index
+func (e *Engine) unindexDedupFile(f blockchain.File) {
+	if e.dedup == nil || f.CipherFormat != encryptor.FormatConvergentGCM {
+		return
+	}
+	if err := e.dedup.Delete(f.ID); err != nil {
+		logger.WithField("file_id", f.ID).WithError(err).Warn("failed to remove file from dedup index")
+	}
+}
+
+// toDedupFile returns slices stored by the file itself, hashes are plaintext hashes by slice ID
+func toDedupFile(f blockchain.File, hashes map[string][]byte) dedup.File {
+	df := dedup.File{ID: f.ID, Owner: f.Owner, Namespace: f.Namespace}
+	seen := make(map[string]bool)
+	for _, s := range f.OwnSlices() {
+		h, ok := hashes[s.ID]
+		if !ok || seen[s.ID] {
+			continue
+		}
+		seen[s.ID] = true
+		df.Slices = append(df.Slices, dedup.Slice{ID: s.ID, Hash: h})
+	}
+	return df
+}
+
+// lookup returns the slice whose plaintext is of hash h and length, found false if there is none
+func (i *dedupIndex) lookup(h []byte, length uint64) (src dedupSource, found bool, err error) {
+	sources, err := i.e.dedup.Sources(i.owner, i.namespace, h)
+	if err != nil {
+		return src, false, err
+	}
+	for _, source := range sources {
+		df, err := i.file(source.FileID)
+		if err != nil {
+			return src, false, err
+		}
+		if df == nil || df.file.ExpireTime < i.expireTime || (found && src.expireTime >= df.file.ExpireTime) {
+			continue
+		}
+		meta, ok := df.metas[source.SliceID]
+		replicas := df.replicas[source.SliceID]
+		if !ok || len(replicas) == 0 || meta.Length != length || !bytes.Equal(meta.PlainHash, h) {
+			continue
+		}
+		refs := make([]blockchain.PublicSliceMeta, 0, len(replicas))
+		for _, r := range replicas {
+			r.SourceFileID = df.file.ID
+			r.SliceIdx = 0
+			refs = append(refs, r)
+		}
+		src = dedupSource{
+			meta: slicer.SliceMeta{
+				ID:     meta.SliceID,
+				Hash:   meta.PlainHash,
+				Length: meta.Length,
+				Key:    meta.Key,
+			},
+			replicas:   refs,
+			expireTime: df.file.ExpireTime,
+		}
+		found = true
+	}
+	return src, found, nil
+}
+
+// file reads a source file from blockchain once, files deleted or expired are removed from the index
+func (i *dedupIndex) file(id string) (*dedupFile, error) {
+	if df, ok := i.files[id]; ok {
+		return df, nil
+	}
+	var df *dedupFile
+	f, err := i.e.chain.GetFileByID(id)
+	if err != nil {
+		if !errorx.Is(err, errorx.ErrCodeNotFound) && !errorx.Is(err, errorx.ErrCodeExpired) {
+			return nil, errorx.Wrap(err, "failed to read blockchain")
+		}
+		if err := i.e.dedup.Delete(id); err != nil {
+			return nil, err
+		}
+	} else if fs, err := i.e.recoverChainFileStructure(f); err != nil {
+		logger.WithField("file_id", f.ID).WithError(err).Warn("failed to recover file structure for deduplication")
+	} else {
+		df = &dedupFile{
+			file:     f,
+			metas:    make(map[string]blockchain.PrivateSliceMeta, len(fs)),
+			replicas: common.MakeSlicesPool4Read(f.OwnSlices()),
+		}
+		for _, s := range fs {
+			df.metas[s.SliceID] = s
+		}
+	}
+	i.files[id] = df
+	return df, nil
+}
+
+// convergentSecret derives the secret keying slices of the namespace in convergent format from the master key
+// recorded by the namespace, so slices are only deduplicated within the namespace, and the secret stays the same
+// after key rotation. Keys of slices are kept in file structure, reading the files doesn't need the secret.
+func (e *Engine) convergentSecret(ns blockchain.Namespace) ([]byte, error) {
+	key, err := e.encryptor.GetKey(ns.KeyID, convergentSecretLabel, ns.Name, ns.Owner)
+	if err != nil {
+		return nil, errorx.Wrap(err, "failed to derive convergent secret of namespace %s", ns.Name)
+	}
+	return key.Key, nil
+}
+
+// dedupRoutine block current routine, used for files in convergent format.
+// Slices of plaintext found in index or earlier in the same file are referenced, the others are sealed
+// by encryptor.ConvergentSeal with keys derived from secret and sent to newQueue,
+// which is closed when sliceQueue is closed.
+// An empty file still has an empty slice, so that it can be published.
+func (e *Engine) dedupRoutine(ctx context.Context, index *dedupIndex, secret []byte, sliceQueue <-chan slicer.Slice,
+	newQueue chan<- slicer.Slice, result *dedupResult, onErr func(err error)) {
+	defer close(newQueue)
+
+	seen := make(map[string]slicer.SliceMeta)
+	for {
+		var s slicer.Slice
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return
+		case s, ok = <-sliceQueue:
+		}
+		if !ok {
+			if len(result.metas) > 0 {
+				return
+			}
+			id, _ := uuid.NewRandom()
+			s = slicer.Slice{SliceMeta: slicer.SliceMeta{ID: id.String(), Hash: hash.HashUsingSha256(nil)}}
+		}
+
+		if meta, exist := seen[string(s.Hash)]; exist {
+			result.metas = append(result.metas, meta)
+			continue
+		}
+		src, exist, err := index.lookup(s.Hash, s.Length)
+		if err != nil {
+			onErr(err)
+			return
+		}
+		if exist {
+			seen[string(s.Hash)] = src.meta
+			result.metas = append(result.metas, src.meta)
+			result.refs = append(result.refs, src.replicas...)
+			continue
+		}
+
+		s.Key = encryptor.ConvergentKey(secret, s.Hash)
+		sealed, err := encryptor.ConvergentSeal(s.Data, s.Key)
+		if err != nil {
+			onErr(err)
+			return
+		}
+		seen[string(s.Hash)] = s.SliceMeta
+		result.metas = append(result.metas, s.SliceMeta)
+
+		select {
+		case <-ctx.Done():
+			return
+		case newQueue <- slicer.Slice{SliceMeta: s.SliceMeta, Data: sealed}:
+		}
+	}
+}
+
+// sourceFileIDs returns IDs of files whose slices are referenced by the file
+func sourceFileIDs(f blockchain.File) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, s := range f.Slices {
+		if s.Referenced() && !seen[s.SourceFileID] {
+			seen[s.SourceFileID] = true
+			ids = append(ids, s.SourceFileID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedup
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+const (
+	dbName = "dedupDB"
+
+	sourcePrefix = "source:"
+	filePrefix   = "file:"
+	builtPrefix  = "built:"
+)
+
+// Slice is a slice stored by a file, Hash is the hash of its plaintext
+type Slice struct {
+	ID   string `json:"id"`
+	Hash []byte `json:"hash"`
+}
+
+// File is a file in convergent format, Slices are the ones stored by the file itself
+type File struct {
+	ID        string  `json:"id"`
+	Owner     []byte  `json:"owner"`
+	Namespace string  `json:"namespace"`
+	Slices    []Slice `json:"slices"`
+}
+
+// Source is a slice found by the hash of its plaintext, and the file storing it
+type Source struct {
+	FileID  string
+	SliceID string
+}
+
+// LevelDBIndex indexes slices stored by files in convergent format by hash of their plaintext in levelDB,
+// so that slices of a new file are looked up one by one rather than by reading all files of the namespace
+type LevelDBIndex struct {
+	db *leveldb.DB
+}
+
+// New creates a levelDB to index slices
+func New(root string) (*LevelDBIndex, error) {
+	db, err := leveldb.OpenFile(filepath.Join(root, dbName), nil)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "cannot open leveldb")
+	}
+	return &LevelDBIndex{db: db}, nil
+}
+
+// Put indexes slices stored by a file, replacing the ones indexed before
+func (i *LevelDBIndex) Put(f File) error {
+	batch := leveldb.Batch{}
+	if err := i.deleteFile(&batch, f.ID); err != nil {
+		return err
+	}
+	value, err := json.Marshal(f)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal file")
+	}
+	batch.Put([]byte(filePrefix+f.ID), value)
+	for _, s := range f.Slices {
+		batch.Put(makeSourceKey(f.Owner, f.Namespace, s.Hash, f.ID), []byte(s.ID))
+	}
+	if err := i.db.Write(&batch, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write batch")
+	}
+	return nil
+}
+
+// Sources returns slices of the namespace whose plaintext hash is hash
+func (i *LevelDBIndex) Sources(owner []byte, namespace string, hash []byte) ([]Source, error) {
+	prefix := makeSourcePrefix(owner, namespace, hash)
+	iter := i.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	var sources []Source
+	for iter.Next() {
+		sources = append(sources, Source{
+			FileID:  string(iter.Key()[len(prefix):]),
+			SliceID: string(iter.Value()),
+		})
+	}
+	if err := iter.Error(); err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate sources")
+	}
+	return sources, nil
+}
+
+// Delete removes slices of a file from the index
+func (i *LevelDBIndex) Delete(fileID string) error {
+	batch := leveldb.Batch{}
+	if err := i.deleteFile(&batch, fileID); err != nil {
+		return err
+	}
+	if err := i.db.Write(&batch, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write batch")
+	}
+	return nil
+}
+
+// Built returns true if files of the namespace published before the index are indexed
+func (i *LevelDBIndex) Built(owner []byte, namespace string) (bool, error) {
+	has, err := i.db.Has(makeBuiltKey(owner, namespace), nil)
+	if err != nil {
+		return false, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to get namespace state")
+	}
+	return has, nil
+}
+
+// SetBuilt marks files of the namespace published before the index are indexed
+func (i *LevelDBIndex) SetBuilt(owner []byte, namespace string) error {
+	if err := i.db.Put(makeBuiltKey(owner, namespace), nil, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to put namespace state")
+	}
+	return nil
+}
+
+func (i *LevelDBIndex) Close() {
+	i.db.Close()
+}
+
+// deleteFile adds deletion of the file and its slices into batch
+func (i *LevelDBIndex) deleteFile(batch *leveldb.Batch, fileID string) error {
+	value, err := i.db.Get([]byte(filePrefix+fileID), nil)
+	if err == leveldb.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to get file")
+	}
+	var f File
+	if err := json.Unmarshal(value, &f); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal file")
+	}
+	for _, s := range f.Slices {
+		batch.Delete(makeSourceKey(f.Owner, f.Namespace, s.Hash, f.ID))
+	}
+	batch.Delete([]byte(filePrefix + fileID))
+	return nil
+}
+
+// namespaces are hex encoded in keys, so that keys are not confused by separators in names
+func makeSourcePrefix(owner []byte, namespace string, hash []byte) []byte {
+	return []byte(fmt.Sprintf("%s%x:%x:%x:", sourcePrefix, owner, namespace, hash))
+}
+
+func makeSourceKey(owner []byte, namespace string, hash []byte, fileID string) []byte {
+	return append(makeSourcePrefix(owner, namespace, hash), fileID...)
+}
+
+func makeBuiltKey(owner []byte, namespace string) []byte {
+	return []byte(fmt.Sprintf("%s%x:%x", builtPrefix, owner, namespace))
+}
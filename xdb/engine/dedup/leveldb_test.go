@@ -0,0 +1,76 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedup
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelDBIndex(t *testing.T) {
+	root, err := ioutil.TempDir("", "dedup")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	i, err := New(root)
+	require.NoError(t, err)
+	defer i.Close()
+
+	owner := []byte("owner")
+	require.NoError(t, i.Put(File{ID: "f1", Owner: owner, Namespace: "ns", Slices: []Slice{
+		{ID: "s1", Hash: []byte("h1")},
+		{ID: "s2", Hash: []byte("h2")},
+	}}))
+	require.NoError(t, i.Put(File{ID: "f2", Owner: owner, Namespace: "ns", Slices: []Slice{
+		{ID: "s3", Hash: []byte("h1")},
+	}}))
+	require.NoError(t, i.Put(File{ID: "f3", Owner: owner, Namespace: "ns/a", Slices: []Slice{
+		{ID: "s4", Hash: []byte("h1")},
+	}}))
+
+	sources, err := i.Sources(owner, "ns", []byte("h1"))
+	require.NoError(t, err)
+	require.Equal(t, []Source{{FileID: "f1", SliceID: "s1"}, {FileID: "f2", SliceID: "s3"}}, sources)
+	// slices are only found within the namespace of the owner
+	sources, err = i.Sources([]byte("other"), "ns", []byte("h1"))
+	require.NoError(t, err)
+	require.Empty(t, sources)
+	sources, err = i.Sources(owner, "ns/a", []byte("h1"))
+	require.NoError(t, err)
+	require.Equal(t, []Source{{FileID: "f3", SliceID: "s4"}}, sources)
+
+	// slices of a file are replaced when it's indexed again, and removed with it
+	require.NoError(t, i.Put(File{ID: "f1", Owner: owner, Namespace: "ns", Slices: []Slice{
+		{ID: "s2", Hash: []byte("h2")},
+	}}))
+	sources, err = i.Sources(owner, "ns", []byte("h1"))
+	require.NoError(t, err)
+	require.Equal(t, []Source{{FileID: "f2", SliceID: "s3"}}, sources)
+	require.NoError(t, i.Delete("f1"))
+	require.NoError(t, i.Delete("f0"))
+	sources, err = i.Sources(owner, "ns", []byte("h2"))
+	require.NoError(t, err)
+	require.Empty(t, sources)
+
+	built, err := i.Built(owner, "ns")
+	require.NoError(t, err)
+	require.False(t, built)
+	require.NoError(t, i.SetBuilt(owner, "ns"))
+	built, err = i.Built(owner, "ns")
+	require.NoError(t, err)
+	require.True(t, built)
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/dedup"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor/soft"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/slicer"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// filesChain lists and gets files kept in memory
+type filesChain struct {
+	Blockchain
+	files []blockchain.File
+	lists int
+}
+
+func (c *filesChain) ListFiles(opt *blockchain.ListFileOptions) ([]blockchain.File, error) {
+	c.lists++
+	return c.files, nil
+}
+
+func (c *filesChain) GetFileByID(id string) (blockchain.File, error) {
+	for _, f := range c.files {
+		if f.ID == id {
+			return f, nil
+		}
+	}
+	return blockchain.File{}, errorx.New(errorx.ErrCodeNotFound, "file not found")
+}
+
+func TestConvergentSecret(t *testing.T) {
+	keys := []config.SoftEncryptorKey{{ID: "2021", Password: "hello"}, {ID: "2022", Password: "world"}}
+	before, err := soft.New(&config.SoftEncryptorConf{CurrentKey: "2021", Keys: keys})
+	require.NoError(t, err)
+	after, err := soft.New(&config.SoftEncryptorConf{CurrentKey: "2022", Keys: keys})
+	require.NoError(t, err)
+
+	// the secret is derived from the key recorded by the namespace, whichever key is current
+	ns := blockchain.Namespace{Name: "ns", Owner: []byte("owner"), KeyID: "2021"}
+	secret, err := (&Engine{encryptor: before}).convergentSecret(ns)
+	require.NoError(t, err)
+	rotated, err := (&Engine{encryptor: after}).convergentSecret(ns)
+	require.NoError(t, err)
+	require.Equal(t, secret, rotated)
+
+	other, err := (&Engine{encryptor: after}).convergentSecret(blockchain.Namespace{Name: "other", Owner: []byte("owner"), KeyID: "2021"})
+	require.NoError(t, err)
+	require.NotEqual(t, secret, other)
+
+	// namespaces created before key versioning use the legacy key, which is not configured here
+	_, err = (&Engine{encryptor: after}).convergentSecret(blockchain.Namespace{Name: "ns", Owner: []byte("owner")})
+	require.Error(t, err)
+}
+
+func TestDedupIndex(t *testing.T) {
+	root, err := ioutil.TempDir("", "dedup")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	index, err := dedup.New(root)
+	require.NoError(t, err)
+	defer index.Close()
+	enc, err := soft.New(&config.SoftEncryptorConf{Password: "hello"})
+	require.NoError(t, err)
+	chain := &filesChain{}
+	e := &Engine{encryptor: enc, chain: chain, dedup: index}
+
+	owner, ns := []byte("owner"), "ns"
+	newFile := func(id string, expireTime int64, metas ...slicer.SliceMeta) blockchain.File {
+		f := blockchain.File{ID: id, Owner: owner, Namespace: ns, ExpireTime: expireTime,
+			CipherFormat: encryptor.FormatConvergentGCM}
+		var fs blockchain.FileStructure
+		for _, m := range metas {
+			fs = append(fs, blockchain.PrivateSliceMeta{SliceID: m.ID, PlainHash: m.Hash, Length: m.Length, Key: m.Key})
+			f.Slices = append(f.Slices, blockchain.PublicSliceMeta{ID: m.ID, NodeID: []byte("n1"), SliceIdx: 1})
+		}
+		f.Structure, _, err = e.packChainFileStructure(fs, id)
+		require.NoError(t, err)
+		return f
+	}
+	s1 := slicer.SliceMeta{ID: "s1", Hash: hash.HashUsingSha256([]byte("first")), Length: 5, Key: []byte("k1")}
+	s2 := slicer.SliceMeta{ID: "s2", Hash: hash.HashUsingSha256([]byte("second")), Length: 6, Key: []byte("k2")}
+
+	// files published before are indexed on the first use of the namespace only
+	chain.files = []blockchain.File{newFile("f1", 100, s1)}
+	i, err := e.newDedupIndex(owner, ns, 50)
+	require.NoError(t, err)
+	_, err = e.newDedupIndex(owner, ns, 50)
+	require.NoError(t, err)
+	require.Equal(t, 1, chain.lists)
+
+	src, found, err := i.lookup(s1.Hash, s1.Length)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, s1, src.meta)
+	require.Equal(t, []blockchain.PublicSliceMeta{{ID: "s1", NodeID: []byte("n1"), SourceFileID: "f1"}}, src.replicas)
+	_, found, err = i.lookup(s1.Hash, s1.Length+1)
+	require.NoError(t, err)
+	require.False(t, found)
+	_, found, err = i.lookup(s2.Hash, s2.Length)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	// source files must not expire before the new file, the one expiring last is preferred
+	f2 := newFile("f2", 200, s1, s2)
+	chain.files = append(chain.files, f2)
+	e.indexDedupFile(f2, []slicer.SliceMeta{s1, s2})
+	i, err = e.newDedupIndex(owner, ns, 150)
+	require.NoError(t, err)
+	src, found, err = i.lookup(s1.Hash, s1.Length)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "f2", src.replicas[0].SourceFileID)
+	i, err = e.newDedupIndex(owner, ns, 300)
+	require.NoError(t, err)
+	_, found, err = i.lookup(s2.Hash, s2.Length)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	// files deleted on blockchain are removed from the index once found missing
+	chain.files = chain.files[:1]
+	i, err = e.newDedupIndex(owner, ns, 50)
+	require.NoError(t, err)
+	src, found, err = i.lookup(s1.Hash, s1.Length)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "f1", src.replicas[0].SourceFileID)
+	sources, err := index.Sources(owner, ns, s2.Hash)
+	require.NoError(t, err)
+	require.Empty(t, sources)
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryptor
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+
+	xaes "github.com/PaddlePaddle/PaddleDTX/crypto/core/aes"
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// FormatConvergentGCM slices the plaintext of a file before encryption, and each slice is sealed by AES-GCM
+// with a key derived from the hash of its plaintext and a secret of the namespace owner. Identical slices of
+// the namespace produce identical ciphertext, so that a slice already stored by another file of the namespace
+// can be referenced instead of pushed again.
+// The key is kept in the encrypted file structure, and slices are still encrypted by the keys of storage nodes.
+const FormatConvergentGCM = "convergent-gcm"
+
+var convergentNonceLabel = []byte("xdb convergent nonce")
+
+// ConvergentKey derives the key of a slice in FormatConvergentGCM by HMAC-SHA256 of the hash of its plaintext,
+// keyed by secret of the namespace owner, so that whether a known plaintext is stored can not be confirmed
+// by anyone without the secret
+func ConvergentKey(secret, plainHash []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(plainHash)
+	return mac.Sum(nil)
+}
+
+// convergentAESKey returns the AES-GCM key of a slice in FormatConvergentGCM,
+// the nonce is fixed since the key is never used for different plaintext
+func convergentAESKey(key []byte) xaes.AESKey {
+	return xaes.AESKey{
+		Key:   key,
+		Nonce: hash.HashUsingSha256(append(append([]byte{}, convergentNonceLabel...), key...))[:12],
+	}
+}
+
+// ConvergentSeal seals a slice in FormatConvergentGCM with the key derived by ConvergentKey
+func ConvergentSeal(plain, key []byte) ([]byte, error) {
+	sealed, err := xaes.EncryptUsingAESGCM(convergentAESKey(key), plain, nil)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeCrypto, "failed to seal slice")
+	}
+	return sealed, nil
+}
+
+// ConvergentOpen opens a slice in FormatConvergentGCM and checks the hash of its plaintext
+func ConvergentOpen(sealed, key, plainHash []byte) ([]byte, error) {
+	plain, err := xaes.DecryptUsingAESGCM(convergentAESKey(key), sealed, nil)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeCrypto, "failed to open slice")
+	}
+	if !bytes.Equal(hash.HashUsingSha256(plain), plainHash) {
+		return nil, errorx.New(errorx.ErrCodeCrypto, "hash of slice plaintext not match")
+	}
+	return plain, nil
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryptor
+
+import (
+	"testing"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvergent(t *testing.T) {
+	plain := []byte("a slice shared by files")
+	h := hash.HashUsingSha256(plain)
+	secret := []byte("secret of the namespace")
+	key := ConvergentKey(secret, h)
+
+	sealed, err := ConvergentSeal(plain, key)
+	require.NoError(t, err)
+	require.Equal(t, len(plain)+gcmTagSize, len(sealed))
+
+	// identical slices produce identical ciphertext with the same secret
+	again, err := ConvergentSeal(plain, ConvergentKey(secret, h))
+	require.NoError(t, err)
+	require.Equal(t, sealed, again)
+
+	// the key can not be derived from the plaintext without the secret
+	other, err := ConvergentSeal(plain, ConvergentKey([]byte("another secret"), h))
+	require.NoError(t, err)
+	require.NotEqual(t, sealed, other)
+	_, err = ConvergentOpen(sealed, ConvergentKey([]byte("another secret"), h), h)
+	require.Error(t, err)
+
+	got, err := ConvergentOpen(sealed, key, h)
+	require.NoError(t, err)
+	require.Equal(t, plain, got)
+
+	// wrong hash
+	_, err = ConvergentOpen(sealed, key, hash.HashUsingSha256([]byte("another slice")))
+	require.Error(t, err)
+
+	// tampered ciphertext
+	sealed[0] ^= 1
+	_, err = ConvergentOpen(sealed, key, h)
+	require.Error(t, err)
+}
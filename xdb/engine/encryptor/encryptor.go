@@ -13,7 +13,8 @@
 
 package encryptor
 
-// EncryptOptions use fileID, sliceID and nodeID info when encrypting slice content
+// EncryptOptions use fileID, sliceID and nodeID info when encrypting slice content,
+// content is always encrypted with the current master key
 type EncryptOptions struct {
 	FileID  string
 	SliceID string
@@ -25,6 +26,7 @@ type EncryptedSliceMeta struct {
 	NodeID     []byte
 	CipherHash []byte // hash of slice ciphertext
 	Length     uint64 // length of slice ciphertext
+	KeyID      string // ID of the master key the slice is encrypted with
 }
 
 type EncryptedSlice struct {
@@ -33,9 +35,15 @@ type EncryptedSlice struct {
 	CipherText []byte // slice ciphertext
 }
 
-// RecoverOptions use fileID, sliceID and nodeID info when recovering slice content
+// RecoverOptions use fileID, sliceID and nodeID info when recovering slice content,
+// KeyID is ID of the master key the content is encrypted with, empty for the legacy key.
+// Salts are salts of parts of content in FormatChunkedGCM encrypted separately, nil if there is none.
+// WrappedKey is the key of a rekeyed file sealed by the master key of KeyID, content of the file is decrypted by it
 type RecoverOptions struct {
-	FileID  string
-	SliceID string
-	NodeID  []byte
+	FileID     string
+	SliceID    string
+	NodeID     []byte
+	KeyID      string
+	Salts      *StreamSalts
+	WrappedKey []byte
 }
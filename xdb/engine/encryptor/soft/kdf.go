@@ -21,9 +21,13 @@ import (
 	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
 )
 
-// getKey derive encrypt key by password, fileID, sliceID and NodeID using key derivation function
-func (se *SoftEncryptor) getKey(fileID, sliceID string, nodeID []byte) []byte {
-	secret := []byte(se.password)
+// getKey derive encrypt key by master key of keyID, fileID, sliceID and NodeID using key derivation function
+func (se *SoftEncryptor) getKey(keyID, fileID, sliceID string, nodeID []byte) ([]byte, error) {
+	password, err := se.keys.Get(keyID)
+	if err != nil {
+		return nil, err
+	}
+	secret := []byte(password)
 	salt := append(append([]byte(fileID), []byte(sliceID)...), nodeID...)
 	r := hkdf.New(hash.DefaultHasher, secret, salt, nil)
 
@@ -33,5 +37,5 @@ func (se *SoftEncryptor) getKey(fileID, sliceID string, nodeID []byte) []byte {
 		panic(err)
 	}
 
-	return key
+	return key, nil
 }
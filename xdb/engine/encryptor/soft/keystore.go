@@ -0,0 +1,80 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package soft
+
+import (
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// LegacyKeyID is the ID of the master key configured by "password",
+// content encrypted before key versioning has no key ID recorded and is decrypted with it
+const LegacyKeyID = ""
+
+// KeyStore holds versioned master keys, one of them is current and used to encrypt new content,
+// the others are kept to decrypt content encrypted before rotation
+type KeyStore struct {
+	keys    map[string]string
+	current string
+}
+
+// NewKeyStore creates KeyStore by "password", "currentKey" and "keys" configuration.
+// If no versioned key is configured, the legacy password is the current key
+func NewKeyStore(conf *config.SoftEncryptorConf) (*KeyStore, error) {
+	ks := &KeyStore{
+		keys: make(map[string]string),
+	}
+	if len(conf.Password) != 0 {
+		ks.keys[LegacyKeyID] = conf.Password
+	}
+	for _, k := range conf.Keys {
+		if len(k.ID) == 0 {
+			return nil, errorx.New(errorx.ErrCodeConfig, "missing key id")
+		}
+		if len(k.Password) == 0 {
+			return nil, errorx.New(errorx.ErrCodeConfig, "missing password of key %s", k.ID)
+		}
+		if _, exist := ks.keys[k.ID]; exist {
+			return nil, errorx.New(errorx.ErrCodeConfig, "duplicated key %s", k.ID)
+		}
+		ks.keys[k.ID] = k.Password
+	}
+	if len(ks.keys) == 0 {
+		return nil, errorx.New(errorx.ErrCodeConfig, "missing password")
+	}
+
+	if len(conf.Keys) != 0 && len(conf.CurrentKey) == 0 {
+		return nil, errorx.New(errorx.ErrCodeConfig, "missing current key")
+	}
+	if _, exist := ks.keys[conf.CurrentKey]; !exist {
+		return nil, errorx.New(errorx.ErrCodeConfig, "current key %s not found", conf.CurrentKey)
+	}
+	ks.current = conf.CurrentKey
+
+	return ks, nil
+}
+
+// Current returns ID of the current key
+func (ks *KeyStore) Current() string {
+	return ks.current
+}
+
+// Get returns master key by key ID
+func (ks *KeyStore) Get(id string) (string, error) {
+	password, exist := ks.keys[id]
+	if !exist {
+		return "", errorx.New(errorx.ErrCodeNotFound, "master key %s not found", id)
+	}
+	return password, nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package soft
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
+)
+
+func TestKeyStore(t *testing.T) {
+	ks, err := NewKeyStore(&config.SoftEncryptorConf{Password: "hello world"})
+	require.NoError(t, err)
+	require.Equal(t, LegacyKeyID, ks.Current())
+
+	conf := &config.SoftEncryptorConf{
+		Password:   "hello world",
+		CurrentKey: "2022",
+		Keys: []config.SoftEncryptorKey{
+			{ID: "2021", Password: "foo"},
+			{ID: "2022", Password: "bar"},
+		},
+	}
+	ks, err = NewKeyStore(conf)
+	require.NoError(t, err)
+	require.Equal(t, "2022", ks.Current())
+	password, err := ks.Get(LegacyKeyID)
+	require.NoError(t, err)
+	require.Equal(t, "hello world", password)
+	_, err = ks.Get("2020")
+	require.Error(t, err)
+
+	// bad configurations
+	_, err = NewKeyStore(&config.SoftEncryptorConf{})
+	require.Error(t, err)
+	_, err = NewKeyStore(&config.SoftEncryptorConf{Keys: conf.Keys})
+	require.Error(t, err)
+	_, err = NewKeyStore(&config.SoftEncryptorConf{CurrentKey: "2020", Keys: conf.Keys})
+	require.Error(t, err)
+	_, err = NewKeyStore(&config.SoftEncryptorConf{CurrentKey: "2021", Keys: append(conf.Keys, conf.Keys[0])})
+	require.Error(t, err)
+}
+
+func TestKeyRotation(t *testing.T) {
+	data := []byte("b66ba2a42e96f93beb07f194026d3b3e7ed363e99c098089fc611747d845c9b1")
+	eopt := encryptor.EncryptOptions{FileID: "26bf4ded-5b36-44fa-9488-259de30a3c37"}
+
+	// encrypt with the legacy key
+	se, err := New(&config.SoftEncryptorConf{Password: "hello world"})
+	require.NoError(t, err)
+	legacy, err := se.Encrypt(bytes.NewReader(data), &eopt)
+	require.NoError(t, err)
+	require.Equal(t, LegacyKeyID, legacy.KeyID)
+
+	// rotate the key, content encrypted before rotation can be decrypted as well
+	se, err = New(&config.SoftEncryptorConf{
+		Password:   "hello world",
+		CurrentKey: "2022",
+		Keys:       []config.SoftEncryptorKey{{ID: "2022", Password: "foo"}},
+	})
+	require.NoError(t, err)
+	rotated, err := se.Encrypt(bytes.NewReader(data), &eopt)
+	require.NoError(t, err)
+	require.Equal(t, "2022", rotated.KeyID)
+	require.NotEqual(t, legacy.CipherText, rotated.CipherText)
+
+	for _, es := range []encryptor.EncryptedSlice{legacy, rotated} {
+		plain, err := se.Recover(bytes.NewReader(es.CipherText), &encryptor.RecoverOptions{
+			FileID: eopt.FileID,
+			KeyID:  es.KeyID,
+		})
+		require.NoError(t, err)
+		require.Equal(t, data, plain)
+	}
+
+	// wrong key
+	_, err = se.Recover(bytes.NewReader(rotated.CipherText), &encryptor.RecoverOptions{FileID: eopt.FileID})
+	require.Error(t, err)
+	_, err = se.Recover(bytes.NewReader(rotated.CipherText), &encryptor.RecoverOptions{FileID: eopt.FileID, KeyID: "2020"})
+	require.Error(t, err)
+}
@@ -25,27 +25,40 @@ import (
 	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
 )
 
-// SoftEncryptor encrypts data or decrypts encoded data
+// wrapKeyLabel is used in place of slice ID to derive keys sealing keys of rekeyed files
+const wrapKeyLabel = "xdb wrapped file key"
+
+// SoftEncryptor encrypts data or decrypts encoded data,
+// keys are derived from versioned master keys held by KeyStore
 type SoftEncryptor struct {
-	password string
+	keys *KeyStore
 }
 
-// New creat SoftEncryptor by "password" configuration
+// New creat SoftEncryptor by "password", "currentKey" and "keys" configuration
 func New(conf *config.SoftEncryptorConf) (*SoftEncryptor, error) {
-	if len(conf.Password) == 0 {
-		return nil, errorx.New(errorx.ErrCodeConfig, "missing password")
+	keys, err := NewKeyStore(conf)
+	if err != nil {
+		return nil, err
 	}
 
 	se := &SoftEncryptor{
-		password: conf.Password,
+		keys: keys,
 	}
 
 	return se, nil
 }
 
-// GetKey derive key using fileID, nodeID and slice ID
-func (se *SoftEncryptor) GetKey(fileID, sliceID string, nodeID []byte) aes.AESKey {
-	key := se.getKey(fileID, sliceID, nodeID)
+// CurrentKeyID returns ID of the master key new content is encrypted with
+func (se *SoftEncryptor) CurrentKeyID() string {
+	return se.keys.Current()
+}
+
+// GetKey derive key using master key of keyID, fileID, nodeID and slice ID
+func (se *SoftEncryptor) GetKey(keyID, fileID, sliceID string, nodeID []byte) (aes.AESKey, error) {
+	key, err := se.getKey(keyID, fileID, sliceID, nodeID)
+	if err != nil {
+		return aes.AESKey{}, err
+	}
 
 	salt := append(append([]byte(fileID), []byte(sliceID)...), nodeID...)
 	nonce := hash.HashUsingSha256(salt)[:12]
@@ -54,10 +67,50 @@ func (se *SoftEncryptor) GetKey(fileID, sliceID string, nodeID []byte) aes.AESKe
 		Key:   key,
 		Nonce: nonce,
 	}
-	return aesKey
+	return aesKey, nil
 }
 
-// Encrypt derive key using nodeID and slice ID, then encrypt content using AES-GCM
+// FileKey returns the key content and structure of a file are encrypted with. The key is derived from
+// the master key of keyID, or sealed by it as wrappedKey once the file is rekeyed
+func (se *SoftEncryptor) FileKey(keyID, fileID string, wrappedKey []byte) (aes.AESKey, error) {
+	if len(wrappedKey) == 0 {
+		return se.GetKey(keyID, fileID, "", nil)
+	}
+	kek, err := se.GetKey(keyID, fileID, wrapKeyLabel, nil)
+	if err != nil {
+		return aes.AESKey{}, err
+	}
+	key, err := aes.DecryptUsingAESGCM(kek, wrappedKey, nil)
+	if err != nil {
+		return aes.AESKey{}, errorx.NewCode(err, errorx.ErrCodeCrypto, "failed to unwrap key of file %s", fileID)
+	}
+	// the nonce is derived from the file ID only, it's the same whichever master key derives the key
+	return aes.AESKey{
+		Key:   key,
+		Nonce: hash.HashUsingSha256([]byte(fileID))[:12],
+	}, nil
+}
+
+// WrapFileKey seals the key of a file by the current master key, so that the file is read without
+// the master key of keyID. Returns ID of the current master key and the key wrapped
+func (se *SoftEncryptor) WrapFileKey(keyID, fileID string, wrappedKey []byte) (string, []byte, error) {
+	key, err := se.FileKey(keyID, fileID, wrappedKey)
+	if err != nil {
+		return "", nil, err
+	}
+	current := se.CurrentKeyID()
+	kek, err := se.GetKey(current, fileID, wrapKeyLabel, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	wrapped, err := aes.EncryptUsingAESGCM(kek, key.Key, nil)
+	if err != nil {
+		return "", nil, errorx.Wrap(err, "failed to wrap key of file %s", fileID)
+	}
+	return current, wrapped, nil
+}
+
+// Encrypt derive key using the current master key, nodeID and slice ID, then encrypt content using AES-GCM
 func (se *SoftEncryptor) Encrypt(r io.Reader, opt *encryptor.EncryptOptions) (
 	encryptor.EncryptedSlice, error) {
 
@@ -66,7 +119,11 @@ func (se *SoftEncryptor) Encrypt(r io.Reader, opt *encryptor.EncryptOptions) (
 		return encryptor.EncryptedSlice{},
 			errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read plaintext during Encrypt")
 	}
-	aesKey := se.GetKey(opt.FileID, opt.SliceID, opt.NodeID)
+	keyID := se.CurrentKeyID()
+	aesKey, err := se.GetKey(keyID, opt.FileID, opt.SliceID, opt.NodeID)
+	if err != nil {
+		return encryptor.EncryptedSlice{}, err
+	}
 	ciphertext, err := aes.EncryptUsingAESGCM(aesKey, plaintext, nil)
 	if err != nil {
 		return encryptor.EncryptedSlice{}, errorx.Wrap(err, "failed to encrypt")
@@ -79,6 +136,7 @@ func (se *SoftEncryptor) Encrypt(r io.Reader, opt *encryptor.EncryptOptions) (
 			NodeID:     opt.NodeID,
 			CipherHash: h,
 			Length:     uint64(len(ciphertext)),
+			KeyID:      keyID,
 		},
 		CipherText: ciphertext,
 	}
@@ -86,14 +144,17 @@ func (se *SoftEncryptor) Encrypt(r io.Reader, opt *encryptor.EncryptOptions) (
 	return es, nil
 }
 
-// Recover derive key using nodeID and slice ID, then decrypt content using AES-GCM
+// Recover derive key using master key of opt.KeyID, nodeID and slice ID, then decrypt content using AES-GCM
 func (se *SoftEncryptor) Recover(r io.Reader, opt *encryptor.RecoverOptions) (
 	[]byte, error) {
 	ciphertext, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read ciphertext during Recover")
 	}
-	aesKey := se.GetKey(opt.FileID, opt.SliceID, opt.NodeID)
+	aesKey, err := se.recoverKey(opt)
+	if err != nil {
+		return nil, err
+	}
 	plaintext, err := aes.DecryptUsingAESGCM(aesKey, ciphertext, nil)
 	if err != nil {
 		return nil, errorx.Wrap(err, "failed to decrypt")
@@ -101,3 +162,51 @@ func (se *SoftEncryptor) Recover(r io.Reader, opt *encryptor.RecoverOptions) (
 
 	return plaintext, nil
 }
+
+// EncryptStream derive key using the current master key, nodeID and slice ID, then encrypt content as a stream
+// in encryptor.FormatChunkedGCM, errors occurred are returned when reading from the returned reader
+func (se *SoftEncryptor) EncryptStream(r io.Reader, opt *encryptor.EncryptOptions) io.Reader {
+	aesKey, err := se.GetKey(se.CurrentKeyID(), opt.FileID, opt.SliceID, opt.NodeID)
+	if err != nil {
+		return errReader{err: err}
+	}
+	return encryptor.NewStreamEncrypter(aesKey, r, encryptor.DefaultChunkSize)
+}
+
+// EncryptStreamPart derive key using the current master key, nodeID and slice ID, then encrypt a part of content
+// as a stream in encryptor.FormatChunkedGCM, parts encrypted separately are joined into the ciphertext of the whole content
+func (se *SoftEncryptor) EncryptStreamPart(r io.Reader, part encryptor.StreamPart, opt *encryptor.EncryptOptions) io.Reader {
+	aesKey, err := se.GetKey(se.CurrentKeyID(), opt.FileID, opt.SliceID, opt.NodeID)
+	if err != nil {
+		return errReader{err: err}
+	}
+	return encryptor.NewStreamPartEncrypter(aesKey, r, encryptor.DefaultChunkSize, part)
+}
+
+// RecoverStream derive key using master key of opt.KeyID, nodeID and slice ID, then decrypt plaintext [offset, offset+n)
+// of content in encryptor.FormatChunkedGCM as a stream, length is the plaintext length of the whole content,
+// and r holds the ciphertext from the start returned by encryptor.StreamCipherRange, opt.Salts are salts of parts
+func (se *SoftEncryptor) RecoverStream(r io.Reader, length, offset, n uint64, opt *encryptor.RecoverOptions) io.Reader {
+	aesKey, err := se.recoverKey(opt)
+	if err != nil {
+		return errReader{err: err}
+	}
+	return encryptor.NewStreamRangeDecrypter(aesKey, r, length, encryptor.DefaultChunkSize, offset, n, opt.Salts)
+}
+
+// recoverKey returns the key to decrypt content by, the key of a rekeyed file is unwrapped
+func (se *SoftEncryptor) recoverKey(opt *encryptor.RecoverOptions) (aes.AESKey, error) {
+	if len(opt.WrappedKey) != 0 {
+		return se.FileKey(opt.KeyID, opt.FileID, opt.WrappedKey)
+	}
+	return se.GetKey(opt.KeyID, opt.FileID, opt.SliceID, opt.NodeID)
+}
+
+// errReader returns err on every read, it is returned by stream methods when the key can not be derived
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
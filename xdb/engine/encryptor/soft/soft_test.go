@@ -21,13 +21,15 @@ import (
 	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
 	"github.com/stretchr/testify/require"
 
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
 )
 
 func TestEncrypt(t *testing.T) {
-	se := SoftEncryptor{
-		password: "hello world",
-	}
+	se, err := New(&config.SoftEncryptorConf{
+		Password: "hello world",
+	})
+	require.NoError(t, err)
 
 	data := []byte("b66ba2a42e96f93beb07f194026d3b3e7ed363e99c098089fc611747d845c9b1xxs02be1b7bb7a5e2a61ce1ef")
 
@@ -62,3 +64,51 @@ func TestEncrypt(t *testing.T) {
 	require.Error(t, err)
 	require.NotEqual(t, data, recovered)
 }
+
+func TestWrapFileKey(t *testing.T) {
+	keys := []config.SoftEncryptorKey{{ID: "2021", Password: "hello"}, {ID: "2022", Password: "world"}}
+	old, err := New(&config.SoftEncryptorConf{CurrentKey: "2021", Keys: keys})
+	require.NoError(t, err)
+	se, err := New(&config.SoftEncryptorConf{CurrentKey: "2022", Keys: keys})
+	require.NoError(t, err)
+
+	fileID := "d0a19e8b-6e28-4d1e-9d4c-6e1d2b5e1a7c"
+	data := []byte("content of the file")
+	es, err := old.Encrypt(bytes.NewReader(data), &encryptor.EncryptOptions{FileID: fileID})
+	require.NoError(t, err)
+	require.Equal(t, "2021", es.KeyID)
+
+	// the key of the file is sealed by the current key
+	keyID, wrapped, err := se.WrapFileKey(es.KeyID, fileID, nil)
+	require.NoError(t, err)
+	require.Equal(t, "2022", keyID)
+	key, err := se.FileKey(es.KeyID, fileID, nil)
+	require.NoError(t, err)
+	unwrapped, err := se.FileKey(keyID, fileID, wrapped)
+	require.NoError(t, err)
+	require.Equal(t, key, unwrapped)
+
+	// the old key is no longer needed to read the file
+	retired, err := New(&config.SoftEncryptorConf{CurrentKey: "2022", Keys: keys[1:]})
+	require.NoError(t, err)
+	_, err = retired.Recover(bytes.NewReader(es.CipherText), &encryptor.RecoverOptions{FileID: fileID, KeyID: es.KeyID})
+	require.Error(t, err)
+	plain, err := retired.Recover(bytes.NewReader(es.CipherText),
+		&encryptor.RecoverOptions{FileID: fileID, KeyID: keyID, WrappedKey: wrapped})
+	require.NoError(t, err)
+	require.Equal(t, data, plain)
+
+	// a wrapped key is wrapped again after another rotation
+	keys = append(keys, config.SoftEncryptorKey{ID: "2023", Password: "again"})
+	next, err := New(&config.SoftEncryptorConf{CurrentKey: "2023", Keys: keys[1:]})
+	require.NoError(t, err)
+	keyID, rewrapped, err := next.WrapFileKey(keyID, fileID, wrapped)
+	require.NoError(t, err)
+	require.Equal(t, "2023", keyID)
+	unwrapped, err = next.FileKey(keyID, fileID, rewrapped)
+	require.NoError(t, err)
+	require.Equal(t, key, unwrapped)
+
+	_, err = next.FileKey(keyID, fileID, wrapped)
+	require.Error(t, err)
+}
@@ -0,0 +1,429 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryptor
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
+	xaes "github.com/PaddlePaddle/PaddleDTX/crypto/core/aes"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// Formats of file ciphertext, recorded in blockchain.File.CipherFormat
+//  FormatGCM is the legacy format, the whole file is sealed by AES-GCM at once, denoted by empty string
+//  FormatChunkedGCM splits the file into chunks, each chunk is sealed by AES-GCM separately,
+//  so that a file can be encrypted and decrypted as a stream
+const (
+	FormatGCM        = ""
+	FormatChunkedGCM = "chunked-gcm"
+)
+
+// DefaultChunkSize is the plaintext size of a chunk in FormatChunkedGCM
+const DefaultChunkSize = 64 * 1024
+
+const (
+	streamVersion      = 1
+	streamHeaderSize   = 9 // magic(4) + version(1) + chunk size(4)
+	streamMaxChunkSize = 16 * 1024 * 1024
+	gcmTagSize         = 16
+	streamSaltSize     = 16
+)
+
+var streamMagic = []byte("XDBC")
+
+// StreamCipherLength returns length of the ciphertext in FormatChunkedGCM
+// for a plaintext of length bytes sealed in chunks of chunkSize bytes
+func StreamCipherLength(length uint64, chunkSize int) uint64 {
+	return streamHeaderSize + length + chunkCount(length, chunkSize)*gcmTagSize
+}
+
+// StreamPart locates a part of a file which is encrypted separately in FormatChunkedGCM,
+// Offset is the plaintext offset of the part in the file and must be a multiple of the chunk size,
+// Last denotes the final part of the file. Ciphertext of all the parts joined in order is the same
+// as the ciphertext of the whole file if no Salt is given.
+// Salt is drawn by NewStreamSalt every time the part is encrypted, chunks of the part are then sealed
+// by a key derived from the file key and Salt, so that a part encrypted again never reuses nonces
+type StreamPart struct {
+	Offset uint64
+	Last   bool
+	Salt   []byte
+}
+
+// StreamSalts are salts of the parts of a file encrypted separately, Salts[i] is the salt of the i-th part
+// holding PartSize bytes of plaintext, and is empty if the part has no salt
+type StreamSalts struct {
+	PartSize uint64
+	Salts    [][]byte
+}
+
+// NewStreamSalt returns a random salt to encrypt a part
+func NewStreamSalt() ([]byte, error) {
+	salt := make([]byte, streamSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeCrypto, "failed to generate salt")
+	}
+	return salt, nil
+}
+
+// saltOf returns the salt of the chunk of index, nil if the chunk has no salt
+func (s *StreamSalts) saltOf(index uint64, chunkSize int) []byte {
+	if s == nil || s.PartSize == 0 {
+		return nil
+	}
+	part := index * uint64(chunkSize) / s.PartSize
+	if part >= uint64(len(s.Salts)) {
+		return nil
+	}
+	return s.Salts[part]
+}
+
+// partKey derives the key of a part from the file key and salt of the part
+func partKey(key xaes.AESKey, salt []byte) xaes.AESKey {
+	derive := func(label string) []byte {
+		mac := hmac.New(sha256.New, key.Key)
+		mac.Write([]byte(label))
+		mac.Write(salt)
+		return mac.Sum(nil)
+	}
+	return xaes.AESKey{
+		Key:   derive("xdb stream part key")[:len(key.Key)],
+		Nonce: derive("xdb stream part nonce")[:len(key.Nonce)],
+	}
+}
+
+// StreamPartCipherLength returns length of the ciphertext of a part holding length bytes of plaintext,
+// the stream header is in the first part only
+func StreamPartCipherLength(part StreamPart, length uint64, chunkSize int) uint64 {
+	if part.Offset == 0 {
+		return StreamCipherLength(length, chunkSize)
+	}
+	return length + chunkCount(length, chunkSize)*gcmTagSize
+}
+
+// NewStreamEncrypter returns a reader of ciphertext in FormatChunkedGCM which encrypts r chunk by chunk.
+// Every chunk is sealed with a nonce derived from key.Nonce and chunk index, and the last chunk is marked
+// as final in additional data, so that reordering or truncation of chunks will be detected.
+// Errors are returned when reading from the returned reader.
+func NewStreamEncrypter(key xaes.AESKey, r io.Reader, chunkSize int) io.Reader {
+	return NewStreamPartEncrypter(key, r, chunkSize, StreamPart{Last: true})
+}
+
+// NewStreamPartEncrypter returns a reader of ciphertext of a part of a file in FormatChunkedGCM.
+// Chunks are indexed from the beginning of the file, and only the last chunk of the final part is
+// marked as final, so plaintext of parts other than the final one must be full chunks.
+func NewStreamPartEncrypter(key xaes.AESKey, r io.Reader, chunkSize int, part StreamPart) io.Reader {
+	s := &streamEncrypter{
+		src:   bufio.NewReader(r),
+		chunk: make([]byte, chunkSize),
+		last:  part.Last,
+	}
+	if len(part.Salt) > 0 {
+		key = partKey(key, part.Salt)
+	}
+	s.aead, s.nonce, s.header, s.err = newStreamCipher(key, chunkSize)
+	s.buf = new(bytes.Buffer)
+	if s.err == nil && part.Offset%uint64(chunkSize) != 0 {
+		s.err = errorx.New(errorx.ErrCodeParam, "part offset %d is not a multiple of chunk size", part.Offset)
+	}
+	if s.err == nil {
+		s.index = part.Offset / uint64(chunkSize)
+		if part.Offset == 0 {
+			s.buf.Write(s.header)
+		}
+	}
+	return s
+}
+
+// NewStreamDecrypter returns a reader of plaintext which decrypts r in FormatChunkedGCM.
+// length is the plaintext length of the file, data after the final chunk in r is ignored,
+// so zero padding at the end of the last slice need not be removed.
+// salts are the salts of parts of the file encrypted separately, nil if there is none
+func NewStreamDecrypter(key xaes.AESKey, r io.Reader, length uint64, salts *StreamSalts) io.Reader {
+	return &streamDecrypter{
+		key:    key,
+		src:    r,
+		length: length,
+		salts:  salts,
+	}
+}
+
+// StreamCipherRange returns the range [start, end) of the ciphertext in FormatChunkedGCM which holds
+// the chunks covering plaintext [offset, offset+n), the plaintext is of length bytes
+func StreamCipherRange(length uint64, chunkSize int, offset, n uint64) (uint64, uint64) {
+	size := uint64(chunkSize)
+	first := offset / size
+	start := streamHeaderSize + first*(size+gcmTagSize)
+	if n == 0 {
+		return start, start
+	}
+	last := (offset + n - 1) / size
+	lastSize := size
+	if length-last*size < size {
+		lastSize = length - last*size
+	}
+	return start, streamHeaderSize + last*(size+gcmTagSize) + lastSize + gcmTagSize
+}
+
+// NewStreamRangeDecrypter returns a reader of plaintext [offset, offset+n) of a file in FormatChunkedGCM,
+// length is the plaintext length of the file and r holds the ciphertext from the start returned by
+// StreamCipherRange. The stream header is not read from r but still authenticated with every chunk,
+// so chunkSize must be the one which the file is encrypted with.
+func NewStreamRangeDecrypter(key xaes.AESKey, r io.Reader, length uint64, chunkSize int, offset, n uint64,
+	salts *StreamSalts) io.Reader {
+	s := &streamDecrypter{
+		key:    key,
+		src:    r,
+		length: length,
+		salts:  salts,
+	}
+	s.aead, s.nonce, s.header, s.err = newStreamCipher(key, chunkSize)
+	if s.err == nil {
+		s.chunkSize = chunkSize
+		s.chunks = chunkCount(length, chunkSize)
+		s.index = offset / uint64(chunkSize)
+		s.remain = length - s.index*uint64(chunkSize)
+		s.skip = offset - s.index*uint64(chunkSize)
+	}
+	return io.LimitReader(s, int64(n))
+}
+
+type streamEncrypter struct {
+	aead   cipher.AEAD
+	nonce  []byte
+	header []byte
+
+	src   *bufio.Reader
+	chunk []byte
+	index uint64
+	done  bool
+	last  bool // whether the stream ends the file
+
+	buf *bytes.Buffer
+	err error
+}
+
+func (s *streamEncrypter) Read(p []byte) (int, error) {
+	for s.buf.Len() == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		if s.done {
+			return 0, io.EOF
+		}
+		s.sealChunk()
+	}
+	return s.buf.Read(p)
+}
+
+// sealChunk reads next chunk from source and writes its ciphertext into buf
+func (s *streamEncrypter) sealChunk() {
+	n, err := io.ReadFull(s.src, s.chunk)
+	switch err {
+	case nil:
+		// the chunk is final if nothing follows
+		if _, err := s.src.Peek(1); err == io.EOF {
+			s.done = true
+		} else if err != nil {
+			s.err = errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read plaintext during Encrypt")
+			return
+		}
+	case io.EOF, io.ErrUnexpectedEOF:
+		s.done = true
+	default:
+		s.err = errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read plaintext during Encrypt")
+		return
+	}
+
+	sealed := s.aead.Seal(nil, chunkNonce(s.nonce, s.index), s.chunk[:n], chunkAD(s.header, s.done && s.last))
+	s.buf.Write(sealed)
+	s.index++
+}
+
+type streamDecrypter struct {
+	key    xaes.AESKey
+	src    io.Reader
+	length uint64
+	salts  *StreamSalts
+
+	aead   cipher.AEAD
+	nonce  []byte
+	header []byte
+
+	// cipher of the salted part last read
+	salt      []byte
+	saltAEAD  cipher.AEAD
+	saltNonce []byte
+
+	chunkSize int
+	chunks    uint64
+	index     uint64
+	remain    uint64
+	skip      uint64 // plaintext to drop at the beginning of the first chunk read
+
+	buf []byte
+	err error
+}
+
+func (s *streamDecrypter) Read(p []byte) (int, error) {
+	if s.aead == nil && s.err == nil {
+		s.readHeader()
+	}
+	for len(s.buf) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		if s.index == s.chunks {
+			return 0, io.EOF
+		}
+		s.openChunk()
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// readHeader reads and checks the stream header
+func (s *streamDecrypter) readHeader() {
+	header := make([]byte, streamHeaderSize)
+	if _, err := io.ReadFull(s.src, header); err != nil {
+		s.err = errorx.NewCode(err, errorx.ErrCodeCrypto, "failed to read stream header")
+		return
+	}
+	if !bytes.Equal(header[:len(streamMagic)], streamMagic) {
+		s.err = errorx.New(errorx.ErrCodeCrypto, "bad stream header")
+		return
+	}
+	if header[4] != streamVersion {
+		s.err = errorx.New(errorx.ErrCodeCrypto, "unsupported stream version %d", header[4])
+		return
+	}
+	chunkSize := int(binary.BigEndian.Uint32(header[5:]))
+	aead, nonce, expected, err := newStreamCipher(s.key, chunkSize)
+	if err != nil {
+		s.err = err
+		return
+	}
+
+	s.aead, s.nonce, s.header = aead, nonce, expected
+	s.chunkSize = chunkSize
+	s.chunks = chunkCount(s.length, chunkSize)
+	s.remain = s.length
+}
+
+// openChunk reads next chunk from source and decrypts it into buf
+func (s *streamDecrypter) openChunk() {
+	size := uint64(s.chunkSize)
+	if s.remain < size {
+		size = s.remain
+	}
+	sealed := make([]byte, size+gcmTagSize)
+	if _, err := io.ReadFull(s.src, sealed); err != nil {
+		s.err = errorx.NewCode(err, errorx.ErrCodeCrypto, "failed to read chunk %d, ciphertext truncated", s.index)
+		return
+	}
+
+	aead, nonce, err := s.chunkCipher()
+	if err != nil {
+		s.err = err
+		return
+	}
+	final := s.index == s.chunks-1
+	plain, err := aead.Open(sealed[:0], chunkNonce(nonce, s.index), sealed, chunkAD(s.header, final))
+	if err != nil {
+		s.err = errorx.NewCode(err, errorx.ErrCodeCrypto, "failed to decrypt chunk %d", s.index)
+		return
+	}
+	s.buf = plain[s.skip:]
+	s.skip = 0
+	s.remain -= size
+	s.index++
+}
+
+// chunkCipher returns the cipher and base nonce of the chunk to open, which is derived from the salt
+// of the part the chunk belongs to
+func (s *streamDecrypter) chunkCipher() (cipher.AEAD, []byte, error) {
+	salt := s.salts.saltOf(s.index, s.chunkSize)
+	if len(salt) == 0 {
+		return s.aead, s.nonce, nil
+	}
+	if !bytes.Equal(salt, s.salt) {
+		aead, nonce, _, err := newStreamCipher(partKey(s.key, salt), s.chunkSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.salt, s.saltAEAD, s.saltNonce = salt, aead, nonce
+	}
+	return s.saltAEAD, s.saltNonce, nil
+}
+
+// newStreamCipher creates AES-GCM cipher of key, returns the base nonce and the stream header
+func newStreamCipher(key xaes.AESKey, chunkSize int) (cipher.AEAD, []byte, []byte, error) {
+	if chunkSize <= 0 || chunkSize > streamMaxChunkSize {
+		return nil, nil, nil, errorx.New(errorx.ErrCodeParam, "invalid chunk size %d", chunkSize)
+	}
+	block, err := aes.NewCipher(key.Key)
+	if err != nil {
+		return nil, nil, nil, errorx.NewCode(err, errorx.ErrCodeCrypto, "failed to create aes cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, nil, errorx.NewCode(err, errorx.ErrCodeCrypto, "failed to create gcm cipher")
+	}
+	if len(key.Nonce) != aead.NonceSize() {
+		return nil, nil, nil, errorx.New(errorx.ErrCodeCrypto, "invalid nonce size %d", len(key.Nonce))
+	}
+
+	header := make([]byte, streamHeaderSize)
+	copy(header, streamMagic)
+	header[4] = streamVersion
+	binary.BigEndian.PutUint32(header[5:], uint32(chunkSize))
+	return aead, key.Nonce, header, nil
+}
+
+// chunkNonce xors the chunk index into the last 8 bytes of the base nonce
+func chunkNonce(nonce []byte, index uint64) []byte {
+	n := append([]byte{}, nonce...)
+	var idx [8]byte
+	binary.BigEndian.PutUint64(idx[:], index)
+	for i := range idx {
+		n[len(n)-8+i] ^= idx[i]
+	}
+	return n
+}
+
+// chunkAD binds the stream header and the final flag to a chunk
+func chunkAD(header []byte, final bool) []byte {
+	ad := append([]byte{}, header...)
+	if final {
+		return append(ad, 1)
+	}
+	return append(ad, 0)
+}
+
+// chunkCount returns the number of chunks of a plaintext, an empty plaintext still has a final chunk
+func chunkCount(length uint64, chunkSize int) uint64 {
+	if length == 0 {
+		return 1
+	}
+	return (length + uint64(chunkSize) - 1) / uint64(chunkSize)
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryptor
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"io/ioutil"
+	"testing"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/aes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStream(t *testing.T) {
+	key := sha256.Sum256([]byte("test key"))
+	nonce := sha256.Sum256([]byte("test nonce"))
+	aesKey := aes.AESKey{Key: key[:], Nonce: nonce[:12]}
+	chunkSize := 16
+
+	for _, length := range []int{0, 1, 15, 16, 17, 32, 100} {
+		plain := make([]byte, length)
+		_, err := rand.Read(plain)
+		require.NoError(t, err)
+
+		cipherText, err := ioutil.ReadAll(NewStreamEncrypter(aesKey, bytes.NewReader(plain), chunkSize))
+		require.NoError(t, err)
+		require.Equal(t, StreamCipherLength(uint64(length), chunkSize), uint64(len(cipherText)))
+
+		// zero padding after the final chunk is ignored
+		padded := append(append([]byte{}, cipherText...), make([]byte, 10)...)
+		got, err := ioutil.ReadAll(NewStreamDecrypter(aesKey, bytes.NewReader(padded), uint64(length), nil))
+		require.NoError(t, err)
+		require.Equal(t, plain, got)
+
+		// truncated ciphertext
+		_, err = ioutil.ReadAll(NewStreamDecrypter(aesKey, bytes.NewReader(cipherText[:len(cipherText)-1]), uint64(length), nil))
+		require.Error(t, err)
+	}
+
+	plain := []byte("0123456789abcdef0123456789abcdef0123")
+	cipherText, err := ioutil.ReadAll(NewStreamEncrypter(aesKey, bytes.NewReader(plain), chunkSize))
+	require.NoError(t, err)
+
+	// stop at a chunk boundary, the last chunk read is not final
+	_, err = ioutil.ReadAll(NewStreamDecrypter(aesKey, bytes.NewReader(cipherText), 32, nil))
+	require.Error(t, err)
+
+	// tampered chunk
+	tampered := append([]byte{}, cipherText...)
+	tampered[streamHeaderSize+1] ^= 1
+	_, err = ioutil.ReadAll(NewStreamDecrypter(aesKey, bytes.NewReader(tampered), uint64(len(plain)), nil))
+	require.Error(t, err)
+
+	// wrong key
+	wrong := aes.AESKey{Key: nonce[:], Nonce: nonce[:12]}
+	_, err = ioutil.ReadAll(NewStreamDecrypter(wrong, bytes.NewReader(cipherText), uint64(len(plain)), nil))
+	require.Error(t, err)
+}
+
+func TestStreamRange(t *testing.T) {
+	key := sha256.Sum256([]byte("test key"))
+	nonce := sha256.Sum256([]byte("test nonce"))
+	aesKey := aes.AESKey{Key: key[:], Nonce: nonce[:12]}
+	chunkSize := 16
+
+	plain := make([]byte, 100)
+	_, err := rand.Read(plain)
+	require.NoError(t, err)
+	cipherText, err := ioutil.ReadAll(NewStreamEncrypter(aesKey, bytes.NewReader(plain), chunkSize))
+	require.NoError(t, err)
+
+	ranges := [][2]uint64{{0, 100}, {0, 1}, {15, 2}, {16, 16}, {30, 70}, {99, 1}, {50, 0}}
+	for _, r := range ranges {
+		offset, n := r[0], r[1]
+		start, end := StreamCipherRange(100, chunkSize, offset, n)
+		// only ciphertext of the range is provided
+		got, err := ioutil.ReadAll(NewStreamRangeDecrypter(aesKey, bytes.NewReader(cipherText[start:end]),
+			100, chunkSize, offset, n, nil))
+		require.NoError(t, err)
+		require.Equal(t, plain[offset:offset+n], got)
+	}
+
+	// wrong chunk size is detected by the authenticated header
+	start, end := StreamCipherRange(100, chunkSize, 0, 10)
+	_, err = ioutil.ReadAll(NewStreamRangeDecrypter(aesKey, bytes.NewReader(cipherText[start:end]), 100, 32, 0, 10, nil))
+	require.Error(t, err)
+}
+
+func TestStreamPart(t *testing.T) {
+	key := sha256.Sum256([]byte("test key"))
+	nonce := sha256.Sum256([]byte("test nonce"))
+	aesKey := aes.AESKey{Key: key[:], Nonce: nonce[:12]}
+	chunkSize := 16
+
+	plain := make([]byte, 100)
+	_, err := rand.Read(plain)
+	require.NoError(t, err)
+	whole, err := ioutil.ReadAll(NewStreamEncrypter(aesKey, bytes.NewReader(plain), chunkSize))
+	require.NoError(t, err)
+
+	// parts of 32 bytes, encrypted in any order
+	var joined []byte
+	for offset := uint64(0); offset < 100; offset += 32 {
+		end := offset + 32
+		if end > 100 {
+			end = 100
+		}
+		part := StreamPart{Offset: offset, Last: end == 100}
+		cipherText, err := ioutil.ReadAll(NewStreamPartEncrypter(aesKey, bytes.NewReader(plain[offset:end]), chunkSize, part))
+		require.NoError(t, err)
+		require.Equal(t, StreamPartCipherLength(part, end-offset, chunkSize), uint64(len(cipherText)))
+		joined = append(joined, cipherText...)
+	}
+	require.Equal(t, whole, joined)
+
+	// the file ends at a part which is not the final one
+	first, err := ioutil.ReadAll(NewStreamPartEncrypter(aesKey, bytes.NewReader(plain[:32]), chunkSize, StreamPart{}))
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(NewStreamDecrypter(aesKey, bytes.NewReader(first), 32, nil))
+	require.Error(t, err)
+
+	// offset must be aligned with chunks
+	_, err = ioutil.ReadAll(NewStreamPartEncrypter(aesKey, bytes.NewReader(plain[10:]), chunkSize, StreamPart{Offset: 10, Last: true}))
+	require.Error(t, err)
+}
+
+func TestStreamPartSalt(t *testing.T) {
+	key := sha256.Sum256([]byte("test key"))
+	nonce := sha256.Sum256([]byte("test nonce"))
+	aesKey := aes.AESKey{Key: key[:], Nonce: nonce[:12]}
+	chunkSize := 16
+
+	plain := make([]byte, 100)
+	_, err := rand.Read(plain)
+	require.NoError(t, err)
+	encryptPart := func(offset uint64, content []byte) ([]byte, []byte) {
+		salt, err := NewStreamSalt()
+		require.NoError(t, err)
+		part := StreamPart{Offset: offset, Last: offset+uint64(len(content)) == 100, Salt: salt}
+		cipherText, err := ioutil.ReadAll(NewStreamPartEncrypter(aesKey, bytes.NewReader(content), chunkSize, part))
+		require.NoError(t, err)
+		require.Equal(t, StreamPartCipherLength(part, uint64(len(content)), chunkSize), uint64(len(cipherText)))
+		return cipherText, salt
+	}
+
+	// the second part is uploaded again with different content
+	salts := &StreamSalts{PartSize: 32}
+	var parts [][]byte
+	for offset := uint64(0); offset < 100; offset += 32 {
+		end := offset + 32
+		if end > 100 {
+			end = 100
+		}
+		cipherText, salt := encryptPart(offset, plain[offset:end])
+		parts = append(parts, cipherText)
+		salts.Salts = append(salts.Salts, salt)
+	}
+	former, formerPlain := parts[1], append([]byte{}, plain[32:64]...)
+	replaced := make([]byte, 32)
+	_, err = rand.Read(replaced)
+	require.NoError(t, err)
+	parts[1], salts.Salts[1] = encryptPart(32, replaced)
+	copy(plain[32:64], replaced)
+
+	// the part encrypted again is sealed by another key stream, xor of the ciphertexts
+	// does not reveal xor of the plaintexts
+	xor := func(a, b []byte) []byte {
+		x := make([]byte, len(a))
+		for i := range a {
+			x[i] = a[i] ^ b[i]
+		}
+		return x
+	}
+	require.NotEqual(t, xor(formerPlain[:chunkSize], replaced[:chunkSize]), xor(former[:chunkSize], parts[1][:chunkSize]))
+
+	joined := bytes.Join(parts, nil)
+	got, err := ioutil.ReadAll(NewStreamDecrypter(aesKey, bytes.NewReader(joined), 100, salts))
+	require.NoError(t, err)
+	require.Equal(t, plain, got)
+	// the former ciphertext of the part is not accepted
+	_, err = ioutil.ReadAll(NewStreamDecrypter(aesKey, bytes.NewReader(bytes.Join([][]byte{parts[0], former, parts[2], parts[3]}, nil)), 100, salts))
+	require.Error(t, err)
+	// salts are required to decrypt
+	_, err = ioutil.ReadAll(NewStreamDecrypter(aesKey, bytes.NewReader(joined), 100, nil))
+	require.Error(t, err)
+
+	start, end := StreamCipherRange(100, chunkSize, 40, 50)
+	got, err = ioutil.ReadAll(NewStreamRangeDecrypter(aesKey, bytes.NewReader(joined[start:end]), 100, chunkSize, 40, 50, salts))
+	require.NoError(t, err)
+	require.Equal(t, plain[40:90], got)
+}
@@ -16,6 +16,8 @@ package engine
 import (
 	"context"
 	"io"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -26,9 +28,13 @@ import (
 	ctype "github.com/PaddlePaddle/PaddleDTX/xdb/engine/challenger/merkle/types"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/common"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/copier"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/dedup"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/index"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/quota"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/slicer"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/upload"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/peer"
 )
@@ -46,11 +52,20 @@ type Slicer interface {
 	GetBlockSize() int
 }
 
-// Encryptor encrypts data and decrypts encoded data
+// Encryptor encrypts data and decrypts encoded data,
+// content is encrypted with the current master key, and decrypted with the one of the key ID recorded
 type Encryptor interface {
-	GetKey(fileID, sliceID string, nodeID []byte) aes.AESKey
+	CurrentKeyID() string
+	GetKey(keyID, fileID, sliceID string, nodeID []byte) (aes.AESKey, error)
+	FileKey(keyID, fileID string, wrappedKey []byte) (aes.AESKey, error)
+	WrapFileKey(keyID, fileID string, wrappedKey []byte) (string, []byte, error)
 	Encrypt(r io.Reader, opt *encryptor.EncryptOptions) (encryptor.EncryptedSlice, error)
 	Recover(r io.Reader, opt *encryptor.RecoverOptions) ([]byte, error)
+
+	// EncryptStream, EncryptStreamPart and RecoverStream work on files in encryptor.FormatChunkedGCM
+	EncryptStream(r io.Reader, opt *encryptor.EncryptOptions) io.Reader
+	EncryptStreamPart(r io.Reader, part encryptor.StreamPart, opt *encryptor.EncryptOptions) io.Reader
+	RecoverStream(r io.Reader, length, offset, n uint64, opt *encryptor.RecoverOptions) io.Reader
 }
 
 // Challenger generates challenge requests as dataOwner-node for storage-nodes to answer
@@ -64,20 +79,25 @@ type Challenger interface {
 	Setup(sliceData []byte, rangeAmount int) ([]ctype.RangeHash, error)
 	Save(cms []ctype.Material) error
 	Take(fileID string, sliceID string, nodeID []byte) (ctype.RangeHash, error)
+	Remove(fileID string, sliceID string, nodeID []byte) error
 
 	GetChallengeConf() (string, types.PairingChallengeConf)
 	Close()
 }
 
 // Copier selects Storage Nodes randomly from healthy candidates.
-//  You can call Push() to push slices onto Storage Node, and Pull() to pull slices from Storage Node.
+//  You can call Push() to push slices onto Storage Node, and Pull() to pull slices from Storage Node,
+//  Delete() removes slices pushed but never published, such as those of an abandoned upload.
+//  ScrubReports() gets slices found corrupt or missing by scrubbing on Storage Node.
 //  If you want more Storage Nodes, you can call ReplicaExpansion(),
 //  and it pulls slices from original nodes and decrypts and re-encrypts those slices,
 //  then push them onto new Storage Nodes.
 type Copier interface {
 	Select(slice slicer.Slice, nodes blockchain.NodeHs, opt *copier.SelectOptions) (copier.LocatedSlice, error)
-	Push(ctx context.Context, id, sourceID string, r io.Reader, node *blockchain.Node) (string, error)
+	Push(ctx context.Context, id, sourceID, fileID string, r io.Reader, node *blockchain.Node) (string, error)
 	Pull(ctx context.Context, id, storIndex, fileID string, node *blockchain.Node) (io.ReadCloser, error)
+	Delete(ctx context.Context, id, storIndex string, node *blockchain.Node) error
+	ScrubReports(ctx context.Context, owner string, node *blockchain.Node) ([]types.SliceScrub, error)
 	ReplicaExpansion(ctx context.Context, opt *copier.ReplicaExpOptions, enc common.CommonEncryptor,
 		challengeAlgorithm, sourceID, fileID string) ([]blockchain.PublicSliceMeta, []encryptor.EncryptedSlice, error)
 }
@@ -93,25 +113,33 @@ type Blockchain interface {
 	GetNode(id []byte) (blockchain.Node, error)
 	NodeOffline(opt *blockchain.NodeOperateOptions) error
 	NodeOnline(opt *blockchain.NodeOperateOptions) error
+	NodeDrain(opt *blockchain.NodeOperateOptions) error
 	Heartbeat(opt *blockchain.NodeHeartBeatOptions) error
 	GetHeartbeatNum(id []byte, timestamp int64) (int, error)
 	GetNodeHealth(id []byte) (string, error)
 	ListNodesExpireSlice(opt *blockchain.ListNodeSliceOptions) ([][2]string, error)
+	ListNodesDeletedSlice(opt *blockchain.ListNodeSliceOptions) ([][2]string, error)
 	GetSliceMigrateRecords(opt *blockchain.NodeSliceMigrateOptions) (string, error)
 
 	// The following contract methods are used by dataOwner node
-	PublishFile(file *blockchain.PublishFileOptions) error
+	PublishFile(file *blockchain.PublishFileOptions) (blockchain.File, error)
 	GetFileByName(owner []byte, ns, name string) (blockchain.File, error)
+	GetFileByVersion(owner []byte, ns, name string, version int64) (blockchain.File, error)
 	GetFileByID(id string) (blockchain.File, error)
 	UpdateFileExpireTime(opt *blockchain.UpdateExptimeOptions) (blockchain.File, error)
+	DeleteFile(opt *blockchain.DeleteFileOptions) error
 	AddFileNs(opt *blockchain.AddNsOptions) error
 	UpdateNsReplica(opt *blockchain.UpdateNsReplicaOptions) error
+	UpdateNsLifecycle(opt *blockchain.UpdateNsLifecycleOptions) error
 	UpdateFilePublicSliceMeta(opt *blockchain.UpdateFilePSMOptions) error
 	SliceMigrateRecord(opt *blockchain.SliceMigrateOptions) error
 	GetNsByName(owner []byte, name string) (blockchain.Namespace, error)
 	ListFileNs(opt *blockchain.ListNsOptions) ([]blockchain.Namespace, error)
 	ListFiles(opt *blockchain.ListFileOptions) ([]blockchain.File, error)
+	ListFileVersions(opt *blockchain.ListFileVersionsOptions) ([]blockchain.File, error)
 	ListExpiredFiles(opt *blockchain.ListFileOptions) ([]blockchain.File, error)
+	// ListTaskFiles lists files used by DAI tasks in progress, only if the contract is shared with DAI
+	ListTaskFiles(owner []byte) (map[string]struct{}, error)
 	// The following contract methods used for authorizers to operate the file authorization application
 	GetAuthApplicationByID(authID string) (blockchain.FileAuthApplication, error)
 	ListFileAuthApplications(opt *blockchain.ListFileAuthOptions) (blockchain.FileAuthApplications, error)
@@ -131,6 +159,7 @@ type SliceStorage interface {
 	Exist(key string, index string) (bool, error)
 	Delete(key string, index string) error
 	LoadStr(key string, index string) (string, error)
+	Capacity() (uint64, uint64, error)
 }
 
 // ProveStorage is local storage
@@ -144,6 +173,68 @@ type ProveStorage interface {
 	SaveAndUpdate(key string, value io.Reader) error
 }
 
+// Quota records the storage used by dataOwner nodes on storage node, and checks it against their quota.
+// Check reserves the next slice pushed by the dataOwner node, which is committed once the slice is saved
+type Quota interface {
+	Usage(owner string) (types.SliceUsage, error)
+	Check(owner string) (*quota.Reservation, error)
+	Release(sliceID string) error
+	Close()
+}
+
+// Scrub tracks slices stored on storage node with the files they belong to, and records those found
+// corrupt or missing when scrubbing them, reports of a dataOwner node are queried by Reports.
+// Slices not referenced by any file on blockchain are marked by MarkOrphan and queried by Orphans
+type Scrub interface {
+	Track(sliceID, storIndex, fileID, owner string) error
+	Untrack(sliceID string) error
+	List(after string, limit int) ([]types.SliceScrub, error)
+	Report(sliceID, status string) error
+	Reports(owner string) ([]types.SliceScrub, error)
+	MarkOrphan(sliceID string, orphaned bool) (types.SliceScrub, error)
+	Orphans() ([]types.SliceScrub, error)
+	Close()
+}
+
+// UploadStorage persists sessions of files uploaded in parts on dataOwner node,
+// ciphertext of slices pushed is only kept for pairing based challenge
+type UploadStorage interface {
+	Save(session upload.Session) error
+	Load(id string) (upload.Session, error)
+	List() ([]upload.Session, error)
+	Delete(id string) error
+
+	SaveCipherText(sessionID string, part int, slices []encryptor.EncryptedSlice) error
+	LoadCipherText(sessionID string, part int) ([]encryptor.EncryptedSlice, error)
+	DeleteCipherText(sessionID string, part int) error
+	Close()
+}
+
+// Index indexes files and challenges of dataOwner nodes synced from blockchain, which are searched and
+// paged by cursors without contract queries. Progress of synchronizing an owner is kept by SetSyncState,
+// see more from engine.index
+type Index interface {
+	PutFile(f blockchain.File) error
+	DeleteFile(id string) error
+	Files(q index.FileQuery) ([]blockchain.File, string, error)
+	PutChallenge(c blockchain.Challenge) error
+	Challenges(q index.ChallengeQuery) ([]blockchain.Challenge, string, error)
+	SyncState(owner []byte) (index.SyncState, error)
+	SetSyncState(owner []byte, s index.SyncState) error
+	Close()
+}
+
+// DedupIndex indexes slices stored by files of deduplicated namespaces by hash of their plaintext,
+// files published before the index of a namespace is built are indexed once, see more from engine.dedup
+type DedupIndex interface {
+	Put(f dedup.File) error
+	Sources(owner []byte, namespace string, hash []byte) ([]dedup.Source, error)
+	Delete(fileID string) error
+	Built(owner []byte, namespace string) (bool, error)
+	SetBuilt(owner []byte, namespace string) error
+	Close()
+}
+
 type Engine struct {
 	slicer       Slicer
 	encryptor    Encryptor
@@ -154,6 +245,13 @@ type Engine struct {
 	sliceStorage SliceStorage
 
 	monitor *Monitor
+	uploads *uploadSessions // nil if uploading in parts is not enabled
+	quota   Quota           // nil if quota is not enabled
+	scrub   Scrub           // nil if scrubbing is not enabled
+	index   *localIndex     // nil if the local index is not enabled
+	dedup   DedupIndex      // nil if files are not deduplicated
+
+	owners sync.Map // dataOwner nodes verified to be registered on blockchain
 }
 
 // NewEngineOption contains parameters for initiating Engine
@@ -167,6 +265,29 @@ type NewEngineOption struct {
 	Copier     Copier
 	ProveStor  ProveStorage
 	SliceStor  SliceStorage
+
+	// UploadStor is used by dataOwner node to upload files in parts,
+	// sessions not updated within UploadTimeout are abandoned
+	UploadStor    UploadStorage
+	UploadTimeout time.Duration
+
+	// Quota is used by storage node to limit the storage used by each dataOwner node
+	Quota Quota
+
+	// Scrub is used by storage node to track slices stored and scrub them every ScrubInterval,
+	// reading at most ScrubRate bytes per second, zero means unlimited
+	Scrub         Scrub
+	ScrubInterval time.Duration
+	ScrubRate     int64
+
+	// Index is used by dataOwner node to search files and challenges of the local node and IndexOwners,
+	// public keys of its clients, which are synced from blockchain every IndexInterval
+	Index         Index
+	IndexOwners   []string
+	IndexInterval time.Duration
+
+	// DedupIndex is used by dataOwner node to look up slices already stored in deduplicated namespaces
+	DedupIndex DedupIndex
 }
 
 // NewEngine initiates Engine by the node's configuration file
@@ -184,12 +305,30 @@ func NewEngine(conf *config.MonitorConf, opt *NewEngineOption) (*Engine, error)
 		proveStorage: opt.ProveStor,
 		sliceStorage: opt.SliceStor,
 		monitor:      monitor,
+		quota:        opt.Quota,
+		scrub:        opt.Scrub,
+		dedup:        opt.DedupIndex,
+	}
+	if opt.UploadStor != nil {
+		e.uploads = newUploadSessions(opt.UploadStor, opt.UploadTimeout)
+	}
+	if opt.Index != nil {
+		e.index, err = newLocalIndex(opt.Index, opt.LocalNode, opt.IndexOwners, opt.IndexInterval)
+		if err != nil {
+			return nil, errorx.Wrap(err, "failed to create local index")
+		}
 	}
 	return e, nil
 }
 
 // Start starts Engine
 func (e *Engine) Start(ctx context.Context) error {
+	if e.uploads != nil {
+		go e.cleanUploadSessions(ctx)
+	}
+	if e.index != nil {
+		go e.syncIndex(ctx)
+	}
 	return e.monitor.Start(ctx)
 }
 
@@ -200,4 +339,19 @@ func (e *Engine) Close() {
 	if e.monitor != nil {
 		e.monitor.Close()
 	}
+	if e.uploads != nil {
+		e.uploads.storage.Close()
+	}
+	if e.quota != nil {
+		e.quota.Close()
+	}
+	if e.scrub != nil {
+		e.scrub.Close()
+	}
+	if e.index != nil {
+		e.index.storage.Close()
+	}
+	if e.dedup != nil {
+		e.dedup.Close()
+	}
 }
@@ -17,42 +17,104 @@ import (
 	"bytes"
 	"encoding/hex"
 	"io"
+	"io/ioutil"
+	"strings"
 	"time"
 
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
 	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
 	"github.com/sirupsen/logrus"
 
 	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/common"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/quota"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
 	util "github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/strings"
 )
 
 // Push receive slices from dataOwner nodes
-// rewriting a slice is not allowed
+// rewriting a slice is not allowed, and pairing based challenge material can only be pushed by the
+// dataOwner node which pushed the slice. To prevent the request is forged or replayed, it's signed by
+// the dataOwner node registered on blockchain, and the request's validity is five minutes.
+// The signature covers sha256 of the content, which is verified while the content is read
 func (e *Engine) Push(opt types.PushOptions, r io.Reader) (
 	types.PushResponse, error) {
 
 	var resp types.PushResponse
+	if err := e.verifyPush(opt); err != nil {
+		return resp, err
+	}
+	// the content is verified against the hash signed while being read
+	cr, err := common.NewContentReader(r, opt.ContentHash)
+	if err != nil {
+		return resp, err
+	}
 	// for content not a slice, save or update content
 	if opt.NotASlice {
-		if err := e.proveStorage.SaveAndUpdate(opt.SliceID, r); err != nil {
+		source, err := e.proveStorage.LoadStr(common.GetSliceSourceKey(opt.SliceID))
+		if err != nil {
+			return resp, errorx.Wrap(err, "failed to load source of slice")
+		}
+		if source != opt.SourceID {
+			return resp, errorx.New(errorx.ErrCodeNotAuthorized, "slice %s was not pushed by %s", opt.SliceID, opt.SourceID)
+		}
+		// content saved before is replaced, so it's verified before saving
+		content, err := ioutil.ReadAll(cr)
+		if err != nil {
+			return resp, errorx.Wrap(err, "failed to read challenge material")
+		}
+		if err := e.proveStorage.SaveAndUpdate(opt.SliceID, bytes.NewReader(content)); err != nil {
 			logger.WithError(err).Errorf("push challenge material %s", opt.SliceID)
 			return resp, errorx.Wrap(err, "failed to save slice")
 		}
 		resp.SliceStorIndex = opt.SliceID
 	} else {
-		storIndex, err := e.sliceStorage.Save(opt.SliceID, r)
+		// the slice is aborted once the quota of the dataOwner node is exceeded,
+		// storage is reserved while the slice is saved so that concurrent pushes don't overrun the quota
+		var reservation *quota.Reservation
+		qr := quota.NewReader(cr, -1)
+		if e.quota != nil {
+			if reservation, err = e.quota.Check(opt.SourceID); err != nil {
+				return resp, err
+			}
+			defer reservation.Release()
+			qr = reservation.Reader(cr)
+		}
+		storIndex, err := e.sliceStorage.Save(opt.SliceID, qr)
+		saved := err == nil
 		if err == nil {
 			resp.SliceStorIndex = storIndex
 		} else if errorx.Is(err, errorx.ErrCodeAlreadyExists) {
 			// only in the case that storage.index is same with storage.key
 			// could we receive this error code
 			resp.SliceStorIndex = opt.SliceID
+		} else if cr.Mismatched() {
+			return resp, errorx.New(errorx.ErrCodeParam, "content hash mismatch")
+		} else if exceeded := qr.Exceeded(); exceeded != nil {
+			return resp, exceeded
 		} else {
 			logger.WithError(err).Errorf("push %s", opt.SliceID)
 			return resp, errorx.Wrap(err, "failed to save slice")
 		}
+		// record the dataOwner node pushing the slice first, which is allowed to delete it
+		if err := e.saveSliceSource(opt.SliceID, opt.SourceID); err != nil {
+			logger.WithError(err).Errorf("push %s", opt.SliceID)
+			return resp, err
+		}
+		if reservation != nil && saved {
+			if err := reservation.Commit(opt.SliceID); err != nil {
+				logger.WithError(err).Errorf("push %s", opt.SliceID)
+				return resp, errorx.Wrap(err, "failed to add slice to quota")
+			}
+		}
+		// track the slice to be scrubbed against the file it belongs to
+		if e.scrub != nil && opt.FileID != "" {
+			if err := e.scrub.Track(opt.SliceID, resp.SliceStorIndex, opt.FileID, opt.SourceID); err != nil {
+				logger.WithError(err).Errorf("push %s", opt.SliceID)
+				return resp, errorx.Wrap(err, "failed to track slice to scrub")
+			}
+		}
 	}
 
 	logger.WithFields(logrus.Fields{
@@ -62,6 +124,71 @@ func (e *Engine) Push(opt types.PushOptions, r io.Reader) (
 	return resp, nil
 }
 
+// GetSliceUsage returns the storage used by a dataOwner node on the storage node and its quota
+func (e *Engine) GetSliceUsage(owner string) (types.SliceUsage, error) {
+	if e.quota == nil {
+		return types.SliceUsage{}, errorx.New(errorx.ErrCodeConfig, "quota is not enabled")
+	}
+	if _, err := ecdsa.DecodePublicKeyFromString(owner); err != nil {
+		return types.SliceUsage{}, errorx.NewCode(err, errorx.ErrCodeParam, "bad owner")
+	}
+	return e.quota.Usage(owner)
+}
+
+// GetScrubReports returns slices of a dataOwner node found corrupt or missing by scrubbing on the storage node
+func (e *Engine) GetScrubReports(owner string) ([]types.SliceScrub, error) {
+	if e.scrub == nil {
+		return nil, errorx.New(errorx.ErrCodeConfig, "scrub is not enabled")
+	}
+	if _, err := ecdsa.DecodePublicKeyFromString(owner); err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeParam, "bad owner")
+	}
+	return e.scrub.Reports(owner)
+}
+
+// GetOrphanSlices returns slices on the storage node not referenced by any file on blockchain,
+// which are cleared once orphaned for the retain period unless orphan clear runs in dry-run mode
+func (e *Engine) GetOrphanSlices() ([]types.SliceScrub, error) {
+	if e.scrub == nil {
+		return nil, errorx.New(errorx.ErrCodeConfig, "scrub is not enabled")
+	}
+	return e.scrub.Orphans()
+}
+
+// verifyPush checks the signature of a push request, and the dataOwner node signing it is registered
+// on blockchain, that is to say it has added a file namespace
+func (e *Engine) verifyPush(opt types.PushOptions) error {
+	var requestExpiredTime time.Duration = 5 * time.Minute
+	if opt.Timestamp < (time.Now().UnixNano() - requestExpiredTime.Nanoseconds()) {
+		return errorx.New(errorx.ErrCodeParam, "request has expired")
+	}
+	msg, err := util.GetSigMessage(opt)
+	if err != nil {
+		return errorx.Internal(err, "failed to get the message to sign for push slices")
+	}
+	if err := verifyUserToken(opt.SourceID, opt.Signature, hash.HashUsingSha256([]byte(msg))); err != nil {
+		return errorx.Wrap(err, "failed to verify slice push token")
+	}
+
+	if _, ok := e.owners.Load(opt.SourceID); ok {
+		return nil
+	}
+	owner, _ := ecdsa.DecodePublicKeyFromString(opt.SourceID)
+	nsList, err := e.chain.ListFileNs(&blockchain.ListNsOptions{
+		Owner:   owner[:],
+		TimeEnd: time.Now().UnixNano(),
+		Limit:   1,
+	})
+	if err != nil {
+		return errorx.Wrap(err, "failed to read namespaces of dataOwner from chain")
+	}
+	if len(nsList) == 0 {
+		return errorx.New(errorx.ErrCodeNotAuthorized, "dataOwner %s not registered on chain", opt.SourceID)
+	}
+	e.owners.Store(opt.SourceID, struct{}{})
+	return nil
+}
+
 // Pull load ciphertext slices locally and return them to the dataOwner node
 // To prevent the request is intercepted and the slice is downloaded maliciously,
 // the request's validity is five minutes
@@ -119,6 +246,70 @@ func (e *Engine) Pull(opt types.PullOptions) (io.ReadCloser, error) {
 	return rc, nil
 }
 
+// DeleteSlice removes a slice and its pairing based challenge material from local storage,
+// only the dataOwner node which pushed the slice is allowed to delete it, such as slices of abandoned uploads.
+// To prevent the request is replayed, the request's validity is five minutes
+func (e *Engine) DeleteSlice(opt types.DeleteSliceOptions) error {
+	var requestExpiredTime time.Duration = 5 * time.Minute
+	if int64(opt.Timestamp) < (time.Now().UnixNano() - requestExpiredTime.Nanoseconds()) {
+		return errorx.New(errorx.ErrCodeParam, "request has expired")
+	}
+	sourceKey := common.GetSliceSourceKey(opt.SliceID)
+	source, err := e.proveStorage.LoadStr(sourceKey)
+	if err != nil {
+		return errorx.Wrap(err, "failed to load source of slice")
+	}
+	// Verify Signature
+	msg, err := util.GetSigMessage(opt)
+	if err != nil {
+		return errorx.Internal(err, "failed to get the message to sign for delete slices")
+	}
+	if err := verifyUserToken(source, opt.Signature, hash.HashUsingSha256([]byte(msg))); err != nil {
+		return errorx.Wrap(err, "failed to verify slice delete token")
+	}
+
+	if exist, _ := e.sliceStorage.Exist(opt.SliceID, opt.StorIndex); exist {
+		if err := e.sliceStorage.Delete(opt.SliceID, opt.StorIndex); err != nil {
+			return errorx.Wrap(err, "failed to delete slice")
+		}
+	}
+	for _, key := range []string{opt.SliceID, sourceKey} {
+		if exist, _ := e.proveStorage.Exist(key); exist {
+			if _, err := e.proveStorage.Delete(key); err != nil {
+				return errorx.Wrap(err, "failed to delete slice sigmas")
+			}
+		}
+	}
+	if e.quota != nil {
+		if err := e.quota.Release(opt.SliceID); err != nil {
+			return errorx.Wrap(err, "failed to release slice from quota")
+		}
+	}
+	if e.scrub != nil {
+		if err := e.scrub.Untrack(opt.SliceID); err != nil {
+			return errorx.Wrap(err, "failed to untrack slice from scrub")
+		}
+	}
+
+	logger.WithFields(logrus.Fields{
+		"slice_id": opt.SliceID,
+		"from":     source,
+	}).Debug("slice deleted")
+	return nil
+}
+
+// saveSliceSource records the dataOwner node pushing a slice, the record is never overwritten
+func (e *Engine) saveSliceSource(sliceID, sourceID string) error {
+	if sourceID == "" {
+		return nil
+	}
+	err := e.proveStorage.Save(common.GetSliceSourceKey(sliceID), strings.NewReader(sourceID))
+	if err != nil && !errorx.Is(err, errorx.ErrCodeAlreadyExists) {
+		return errorx.Wrap(err, "failed to save source of slice")
+	}
+	return nil
+}
+
 // checkApplierFileAuth used to check applier's file authorization application
 // In addition to allowing file owners to download slice, authorized appliers can also download
 func (e *Engine) checkApplierFileAuth(applier, authorizer []byte, fileID string) error {
@@ -16,6 +16,7 @@ package engine
 import (
 	"context"
 	"encoding/json"
+	"strings"
 	"sync"
 	"time"
 
@@ -33,27 +34,27 @@ import (
 
 // ListFiles lists unExpired files from blockchain
 func (e *Engine) ListUnExpiredFiles(opt types.ListFileOptions) (
-	[]blockchain.File, error) {
+	types.FilePage, error) {
 	return e.listFiles(opt, false)
 }
 
 // ListExpiredFiles list expired but still valid files
-func (e *Engine) ListExpiredFiles(opt types.ListFileOptions) ([]blockchain.File, error) {
+func (e *Engine) ListExpiredFiles(opt types.ListFileOptions) (types.FilePage, error) {
 	return e.listFiles(opt, true)
 }
 
 func (e *Engine) listFiles(opt types.ListFileOptions, isExpired bool) (
-	files []blockchain.File, err error) {
+	page types.FilePage, err error) {
 	owner, err := e.getPubKey(opt.Owner)
 	if err != nil {
-		return nil, err
+		return page, err
 	}
 	// check file's namespace is exist
 	if _, err := e.chain.GetNsByName(owner[:], opt.Namespace); err != nil {
 		if errorx.Is(err, errorx.ErrCodeNotFound) {
-			return nil, errorx.New(errorx.ErrCodeNotFound, "ns not found")
+			return page, errorx.New(errorx.ErrCodeNotFound, "ns not found")
 		}
-		return nil, errorx.Wrap(err, "failed to get ns from blockchain")
+		return page, errorx.Wrap(err, "failed to get ns from blockchain")
 	}
 	bcopt := blockchain.ListFileOptions{
 		Owner:       owner,
@@ -62,12 +63,37 @@ func (e *Engine) listFiles(opt types.ListFileOptions, isExpired bool) (
 		TimeEnd:     opt.TimeEnd,
 		Limit:       opt.Limit,
 		CurrentTime: opt.CurrentTime,
+		Cursor:      opt.Cursor,
+	}
+	// answer from the local index if files of the owner are synced
+	if page, ok, err := e.listIndexedFiles(bcopt, isExpired); ok {
+		return page, err
 	}
 	if isExpired {
-		files, err = e.chain.ListExpiredFiles(&bcopt)
+		page.Files, err = e.chain.ListExpiredFiles(&bcopt)
 	} else {
-		files, err = e.chain.ListFiles(&bcopt)
+		page.Files, err = e.chain.ListFiles(&bcopt)
+	}
+	if err != nil {
+		return page, errorx.Wrap(err, "failed to read blockchain")
+	}
+	page.Next = blockchain.NextFileCursor(page.Files, bcopt.Limit)
+	return page, nil
+}
+
+// ListFileVersions lists versions of the file with the name, from the latest to the earliest
+func (e *Engine) ListFileVersions(opt types.ListFileVersionsOptions) ([]blockchain.File, error) {
+	owner, err := e.getPubKey(opt.Owner)
+	if err != nil {
+		return nil, err
 	}
+	files, err := e.chain.ListFileVersions(&blockchain.ListFileVersionsOptions{
+		Owner:       owner,
+		Namespace:   opt.Namespace,
+		Name:        opt.Name,
+		CurrentTime: opt.CurrentTime,
+		Limit:       opt.Limit,
+	})
 	if err != nil {
 		return nil, errorx.Wrap(err, "failed to read blockchain")
 	}
@@ -101,15 +127,19 @@ func (e *Engine) GetFileByID(ctx context.Context, id string) (hfile blockchain.F
 	return hfile, nil
 }
 
-// GetFileByName gets file by name from blockchain
-func (e *Engine) GetFileByName(ctx context.Context, pubkey, ns, name string) (
+// GetFileByName gets file by name from blockchain, the latest version is got if version is 0
+func (e *Engine) GetFileByName(ctx context.Context, pubkey, ns, name string, version int64) (
 	hfile blockchain.FileH, err error) {
 	var file blockchain.File
 	owner, err := e.getPubKey(pubkey)
 	if err != nil {
 		return hfile, err
 	}
-	file, err = e.chain.GetFileByName(owner, ns, name)
+	if version > 0 {
+		file, err = e.chain.GetFileByVersion(owner, ns, name, version)
+	} else {
+		file, err = e.chain.GetFileByName(owner, ns, name)
+	}
 	if err != nil {
 		if errorx.Is(err, errorx.ErrCodeNotFound) {
 			return hfile, err
@@ -164,13 +194,39 @@ func (e *Engine) UpdateFileExpireTime(ctx context.Context, opt types.UpdateFileE
 		return errorx.New(errorx.ErrCodeParam, "invalid param expireTime, newExpireTime is too small")
 	}
 
+	return e.extendFile(ctx, file, opt.ExpireTime, opt.CurrentTime, opt.User)
+}
+
+// extendFile updates the expire time of the file and adds challenge material for the extended period.
+// Source files of slices referenced by the file are extended first if they expire earlier,
+// so that the referenced slices are still maintained by them. Source files deleted are skipped,
+// whose slices are kept by the contract as long as they are referenced.
+func (e *Engine) extendFile(ctx context.Context, file blockchain.File, expireTime, currentTime int64,
+	user string) error {
+	for _, id := range sourceFileIDs(file) {
+		source, err := e.chain.GetFileByID(id)
+		if err != nil {
+			if errorx.Is(err, errorx.ErrCodeNotFound) {
+				continue
+			} else if !errorx.Is(err, errorx.ErrCodeExpired) {
+				return errorx.Wrap(err, "failed to get source file %s of referenced slices", id)
+			}
+		}
+		if source.ExpireTime >= expireTime {
+			continue
+		}
+		if err := e.extendFile(ctx, source, expireTime, currentTime, user); err != nil {
+			return errorx.Wrap(err, "failed to extend source file %s of referenced slices", id)
+		}
+	}
+
 	uopt := &blockchain.UpdateExptimeOptions{
-		FileID:        opt.FileID,
-		NewExpireTime: opt.ExpireTime,
-		CurrentTime:   opt.CurrentTime,
+		FileID:        file.ID,
+		NewExpireTime: expireTime,
+		CurrentTime:   currentTime,
 	}
 
-	msg, err = util.GetSigMessage(uopt)
+	msg, err := util.GetSigMessage(uopt)
 	if err != nil {
 		return errorx.Internal(err, "failed to get the message to sign for update file expire time")
 	}
@@ -187,15 +243,16 @@ func (e *Engine) UpdateFileExpireTime(ctx context.Context, opt types.UpdateFileE
 		}
 		return errorx.Wrap(err, "failed to update file on blockchain")
 	}
+	e.indexFile(newFile)
 	logger.WithFields(logrus.Fields{
-		"file_id":     opt.FileID,
-		"expire_time": time.Unix(0, opt.ExpireTime).Format("2006-01-02 15:04:05"),
+		"file_id":     file.ID,
+		"expire_time": time.Unix(0, expireTime).Format("2006-01-02 15:04:05"),
 	}).Info("updated file expire time")
 
 	// add new challenge material
 	startTime := file.ExpireTime
-	if startTime <= opt.CurrentTime {
-		startTime = opt.CurrentTime
+	if startTime <= currentTime {
+		startTime = currentTime
 	}
 	challengeAlgorithm, pairingConf := e.challenger.GetChallengeConf()
 	interval := e.monitor.challengingMonitor.RequestInterval.Nanoseconds()
@@ -205,13 +262,73 @@ func (e *Engine) UpdateFileExpireTime(ctx context.Context, opt types.UpdateFileE
 		}
 	}
 	if challengeAlgorithm == types.PairingChallengeAlgorithm {
-		if err := common.AddFilePairingChallenges(ctx, pairingConf, e.chain, e.copier, newFile, opt.User, startTime, interval, logger); err != nil {
+		if err := common.AddFilePairingChallenges(ctx, pairingConf, e.chain, e.copier, newFile, user, startTime, interval, logger); err != nil {
 			return errorx.Wrap(err, "failed to add pairing based challenge material")
 		}
 	}
 	return nil
 }
 
+// DeleteFile deletes file before it expires, only the owner is allowed.
+// A tombstone of the file is left on chain, and storage nodes remove the slices once they see it.
+func (e *Engine) DeleteFile(ctx context.Context, opt types.DeleteFileOptions) error {
+	if err := e.verifyUserID(opt.User); err != nil {
+		return err
+	}
+	// get the message to sign
+	msg, err := util.GetSigMessage(opt)
+	if err != nil {
+		return errorx.Internal(err, "failed to get the message to sign")
+	}
+	if err := verifyUserToken(opt.User, opt.Token, hash.HashUsingSha256([]byte(msg))); err != nil {
+		return err
+	}
+
+	if opt.CurrentTime+5*time.Second.Nanoseconds() < time.Now().UnixNano() {
+		return errorx.New(errorx.ErrCodeExpired, "request expired")
+	}
+
+	// expired files within the retain period can be deleted as well
+	file, err := e.chain.GetFileByID(opt.FileID)
+	if err != nil {
+		if errorx.Is(err, errorx.ErrCodeNotFound) {
+			return err
+		} else if !errorx.Is(err, errorx.ErrCodeExpired) {
+			return errorx.Wrap(err, "failed to read blockchain")
+		}
+	}
+
+	// slices referenced by other files are kept on storage nodes by the contract until those files expire
+	dopt := &blockchain.DeleteFileOptions{
+		FileID:      opt.FileID,
+		CurrentTime: opt.CurrentTime,
+	}
+	msg, err = util.GetSigMessage(dopt)
+	if err != nil {
+		return errorx.Internal(err, "failed to get the message to sign for delete file")
+	}
+	sig, err := ecdsa.Sign(e.monitor.challengingMonitor.PrivateKey, hash.HashUsingSha256([]byte(msg)))
+	if err != nil {
+		return errorx.Wrap(err, "failed to sign")
+	}
+	dopt.Signature = sig[:]
+
+	if err := e.chain.DeleteFile(dopt); err != nil {
+		if errorx.Is(err, errorx.ErrCodeNotFound) {
+			return err
+		}
+		return errorx.Wrap(err, "failed to delete file on blockchain")
+	}
+	e.unindexFile(file)
+	e.unindexDedupFile(file)
+	logger.WithFields(logrus.Fields{
+		"file_id":   opt.FileID,
+		"namespace": file.Namespace,
+		"name":      file.Name,
+	}).Info("deleted file")
+	return nil
+}
+
 // AddFileNs adds file namespace, opt.User is dataOwner node client's public key
 func (e *Engine) AddFileNs(opt types.AddNsOptions) (err error) {
 	if err := e.verifyUserID(opt.User); err != nil {
@@ -237,6 +354,11 @@ func (e *Engine) AddFileNs(opt types.AddNsOptions) (err error) {
 			UpdateTime:   opt.CreateTime,
 			Replica:      opt.Replica,
 			FileTotalNum: 0,
+			DataShards:   opt.DataShards,
+			ParityShards: opt.ParityShards,
+			Compression:  opt.Compression,
+			Placement:    placementPolicy(opt.MinZones, opt.Regions),
+			KeyID:        e.encryptor.CurrentKeyID(),
 		},
 	}
 	msg, err = util.GetSigMessage(namespace)
@@ -258,6 +380,21 @@ func (e *Engine) AddFileNs(opt types.AddNsOptions) (err error) {
 	return nil
 }
 
+// placementPolicy makes the placement policy of a namespace, nil if not restricted
+func placementPolicy(minZones int, regions string) *blockchain.PlacementPolicy {
+	var p blockchain.PlacementPolicy
+	p.MinZones = minZones
+	for _, r := range strings.Split(regions, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			p.Regions = append(p.Regions, r)
+		}
+	}
+	if p.MinZones == 0 && len(p.Regions) == 0 {
+		return nil
+	}
+	return &p
+}
+
 // UpdateNsReplica updates file namespace replica
 func (e *Engine) UpdateNsReplica(ctx context.Context, opt types.UpdateNsOptions) error {
 	if err := e.verifyUserID(opt.User); err != nil {
@@ -281,17 +418,24 @@ func (e *Engine) UpdateNsReplica(ctx context.Context, opt types.UpdateNsOptions)
 	if err != nil {
 		return errorx.Wrap(err, "failed to get ns from blockchain")
 	}
-	if ns.Replica >= opt.Replica {
-		return errorx.New(errorx.ErrCodeParam, "bad param: replica")
+	if ns.ErasureCoded() {
+		return errorx.New(errorx.ErrCodeParam, "bad param: replica of erasure coded namespace can not be updated")
 	}
-	// get healthy node to expand slice
-	healthNodes, err := common.GetHealthNodes(e.chain)
-	if err != nil {
-		return errorx.Wrap(err, "failed to get nodes from blockchain")
+	if ns.Replica == opt.Replica {
+		return errorx.New(errorx.ErrCodeParam, "bad param: replica")
 	}
-	// new replica must less than nodes
-	if opt.Replica > len(healthNodes) {
-		return errorx.New(errorx.ErrCodeInternal, "no optional healthy node to expand replica")
+	expansion := opt.Replica > ns.Replica
+	var healthNodes blockchain.NodeHs
+	if expansion {
+		// get healthy node to expand slice
+		healthNodes, err = common.GetHealthNodes(e.chain)
+		if err != nil {
+			return errorx.Wrap(err, "failed to get nodes from blockchain")
+		}
+		// new replica must less than nodes
+		if opt.Replica > len(healthNodes) {
+			return errorx.New(errorx.ErrCodeInternal, "no optional healthy node to expand replica")
+		}
 	}
 
 	// query file by ns
@@ -328,13 +472,68 @@ func (e *Engine) UpdateNsReplica(ctx context.Context, opt types.UpdateNsOptions)
 		return errorx.Wrap(err, "failed to update file ns replica on blockchain")
 	}
 
+	if !expansion {
+		// drop surplus slices and challenge material
+		if err := e.nsReplicaReduction(ctx, files, opt.Replica, localPrv); err != nil {
+			return errorx.Wrap(err, "reduce file slices failed")
+		}
+		return nil
+	}
 	// expand slices and challenge material
-	if err := e.nsReplicaExpansion(ctx, files, healthNodes, opt.Replica, localPrv); err != nil {
+	if err := e.nsReplicaExpansion(ctx, files, healthNodes, opt.Replica, ns.Placement, localPrv); err != nil {
 		return errorx.Wrap(err, "expand file slices failed")
 	}
 	return nil
 }
 
+// UpdateNsLifecycle updates lifecycle policy of file namespace, which is applied by the file maintainer
+func (e *Engine) UpdateNsLifecycle(opt types.UpdateNsLifecycleOptions) error {
+	if err := e.verifyUserID(opt.User); err != nil {
+		return err
+	}
+	// get the message to sign
+	msg, err := util.GetSigMessage(opt)
+	if err != nil {
+		return errorx.Internal(err, "failed to get the message to sign")
+	}
+	if err := verifyUserToken(opt.User, opt.Token, hash.HashUsingSha256([]byte(msg))); err != nil {
+		return err
+	}
+
+	// sign using local key
+	localPrv := e.monitor.challengingMonitor.PrivateKey
+	localPub := ecdsa.PublicKeyFromPrivateKey(localPrv)
+
+	sopt := &blockchain.UpdateNsLifecycleOptions{
+		Owner:       localPub[:],
+		Name:        opt.Namespace,
+		CurrentTime: opt.CurrentTime,
+	}
+	if opt.DefaultTTL != 0 || opt.AutoRenew || opt.GracePeriod != 0 {
+		sopt.Lifecycle = &blockchain.LifecyclePolicy{
+			DefaultTTL:  opt.DefaultTTL,
+			AutoRenew:   opt.AutoRenew,
+			GracePeriod: opt.GracePeriod,
+		}
+	}
+	msg, err = util.GetSigMessage(sopt)
+	if err != nil {
+		return errorx.Internal(err, "failed to get the message to sign for update ns lifecycle")
+	}
+	sig, err := ecdsa.Sign(localPrv, hash.HashUsingSha256([]byte(msg)))
+	if err != nil {
+		return errorx.Wrap(err, "failed to sign")
+	}
+	sopt.Signature = sig[:]
+	if err := e.chain.UpdateNsLifecycle(sopt); err != nil {
+		if errorx.Is(err, errorx.ErrCodeNotFound) {
+			return err
+		}
+		return errorx.Wrap(err, "failed to update file ns lifecycle on blockchain")
+	}
+	return nil
+}
+
 // ListFileNs lists file namespaces by owner
 func (e *Engine) ListFileNs(opt types.ListNsOptions) (nss []blockchain.Namespace, err error) {
 	owner, err := e.getPubKey(opt.Owner)
@@ -402,19 +601,19 @@ func (e *Engine) GetFileSysHealth(ctx context.Context, pubkey string) (fh blockc
 }
 
 // ListFileAuths query the list of authorization applications
-func (e *Engine) ListFileAuths(opt types.ListFileAuthOptions) (fileAuths blockchain.FileAuthApplications, err error) {
+func (e *Engine) ListFileAuths(opt types.ListFileAuthOptions) (page types.FileAuthPage, err error) {
 	authorizer, err := e.getPubKey(opt.Authorizer)
 	if err != nil {
-		return fileAuths, err
+		return page, err
 	}
 	// if FileID not empty, judge whether the file on chain exists
 	if opt.FileID != "" {
 		_, err := e.chain.GetFileByID(opt.FileID)
 		if err != nil {
 			if errorx.Is(err, errorx.ErrCodeNotFound) {
-				return fileAuths, err
+				return page, err
 			}
-			return fileAuths, errorx.Wrap(err, "failed to read blockchain")
+			return page, errorx.Wrap(err, "failed to read blockchain")
 		}
 	}
 	bcopt := blockchain.ListFileAuthOptions{
@@ -424,20 +623,22 @@ func (e *Engine) ListFileAuths(opt types.ListFileAuthOptions) (fileAuths blockch
 		TimeEnd:    opt.TimeEnd,
 		Limit:      opt.Limit,
 		Status:     opt.Status,
+		Cursor:     opt.Cursor,
 	}
 	// if opt.Applier not empty, check applier's public key
 	if opt.Applier != "" {
 		applier, err := ecdsa.DecodePublicKeyFromString(opt.Applier)
 		if err != nil {
-			return fileAuths, err
+			return page, err
 		}
 		bcopt.Applier = applier[:]
 	}
-	fileAuths, err = e.chain.ListFileAuthApplications(&bcopt)
+	page.FileAuths, err = e.chain.ListFileAuthApplications(&bcopt)
 	if err != nil {
-		return nil, errorx.Wrap(err, "failed to read blockchain")
+		return page, errorx.Wrap(err, "failed to read blockchain")
 	}
-	return fileAuths, nil
+	page.Next = blockchain.NextFileAuthCursor(page.FileAuths, bcopt.Limit)
+	return page, nil
 }
 
 // ConfirmAuth the dataOwner node confirms or rejects the applier's file authorization application
@@ -510,16 +711,24 @@ func (e *Engine) getAuthKey(fileID string, applier []byte, expireTime int64) ([]
 	}
 	authKey := make(map[string]interface{})
 	// Get the first-level derived key
-	firstEncSecret := e.encryptor.GetKey(fileID, "", []byte{})
+	firstEncSecret, err := e.encryptor.FileKey(file.KeyID, fileID, file.WrappedKey)
+	if err != nil {
+		return nil, errorx.Wrap(err, "failed to get the first-level derived key")
+	}
 	authKey["firstEncSecret"] = firstEncSecret
 
-	// Get the second-level derived key
+	// Get the second-level derived key, referenced slices are encrypted with keys of their source files,
+	// and each replica with the master key it is encrypted with
 	secondEncSecret := make(map[string]map[string]interface{})
-	slicesPool := makeSlicesPool4Read(file.Slices)
+	slicesPool := common.MakeSlicesPool4Read(common.ResolveSliceRefs(e.chain, file.Slices, logger))
 	for sliceID, targetPools := range slicesPool {
 		secondEncSecret[sliceID] = make(map[string]interface{})
 		for _, slice := range targetPools {
-			secondEncSecret[sliceID][string(slice.NodeID)] = e.encryptor.GetKey(fileID, sliceID, slice.NodeID)
+			key, err := e.encryptor.GetKey(slice.KeyID, slice.KeyFileID(fileID), sliceID, slice.NodeID)
+			if err != nil {
+				return nil, errorx.Wrap(err, "failed to get the second-level derived key")
+			}
+			secondEncSecret[sliceID][string(slice.NodeID)] = key
 		}
 	}
 	authKey["secondEncSecret"] = secondEncSecret
@@ -568,30 +777,35 @@ func (e *Engine) GetChallengeByID(id string) (challenge blockchain.Challenge, er
 }
 
 // GetChallenges lists all challenges with given status from blockchain
-func (e *Engine) GetChallenges(opt blockchain.ListChallengeOptions) (challenges []blockchain.Challenge, err error) {
+func (e *Engine) GetChallenges(opt blockchain.ListChallengeOptions) (page types.ChallengePage, err error) {
 	_, err = e.chain.GetNode(opt.TargetNode)
 	if err != nil {
 		if errorx.Is(err, errorx.ErrCodeNotFound) {
-			return challenges, errorx.New(errorx.ErrCodeNotFound, "node not found")
+			return page, errorx.New(errorx.ErrCodeNotFound, "node not found")
 		}
-		return challenges, errorx.Wrap(err, "failed to read blockchain")
+		return page, errorx.Wrap(err, "failed to read blockchain")
 	}
-	challenges, err = e.chain.ListChallengeRequests(&opt)
+	// answer from the local index if challenges of the owner are synced
+	if page, ok, err := e.listIndexedChallenges(opt); ok {
+		return page, err
+	}
+	page.Challenges, err = e.chain.ListChallengeRequests(&opt)
 	if err != nil {
 		if errorx.Is(err, errorx.ErrCodeNotFound) {
-			return challenges, errorx.New(errorx.ErrCodeNotFound, "challenge not found")
+			return page, errorx.New(errorx.ErrCodeNotFound, "challenge not found")
 		}
-		return challenges, errorx.Wrap(err, "failed to read blockchain")
+		return page, errorx.Wrap(err, "failed to read blockchain")
 	}
-	return challenges, nil
+	page.Next = blockchain.NextChallengeCursor(page.Challenges, opt.Limit)
+	return page, nil
 }
 
-// nsReplicaExpansion expand file replica under the namespace
+// nsReplicaExpansion expand file replica under the namespace, new replicas are placed by the placement policy
 // After each slice under the file is restored,
 // the replica is copied and pushed to the new storage node
 // challenges will be generated for new storage node's slices
 func (e *Engine) nsReplicaExpansion(ctx context.Context, files []blockchain.File, healthNodes blockchain.NodeHs,
-	replica int, pri ecdsa.PrivateKey) error {
+	replica int, placement *blockchain.PlacementPolicy, pri ecdsa.PrivateKey) error {
 
 	nodesMap := common.ToNodeHsMap(healthNodes)
 	interval := e.monitor.challengingMonitor.RequestInterval.Nanoseconds()
@@ -602,7 +816,7 @@ func (e *Engine) nsReplicaExpansion(ctx context.Context, files []blockchain.File
 		go func(f blockchain.File) {
 			defer wg.Done()
 			err := common.ExpandFileSlices(ctx, pri, e.copier, e.encryptor, e.chain, e.challenger,
-				f, nodesMap, replica, healthNodes, interval, logger)
+				f, nodesMap, replica, placement, healthNodes, interval, logger)
 			if err != nil {
 				logger.WithField("file_id", f.ID).WithError(err).Error("failed to expand file")
 			} else {
@@ -613,3 +827,31 @@ func (e *Engine) nsReplicaExpansion(ctx context.Context, files []blockchain.File
 	wg.Wait()
 	return nil
 }
+
+// nsReplicaReduction reduces file replica under the namespace,
+// surplus replicas of each slice on the least healthy nodes are dropped from the file,
+// then deleted from storage nodes along with their challenge material
+func (e *Engine) nsReplicaReduction(ctx context.Context, files []blockchain.File, replica int, pri ecdsa.PrivateKey) error {
+	// dropped replicas may be stored on nodes offline
+	nodes, err := e.chain.ListNodes()
+	if err != nil {
+		return errorx.Wrap(err, "failed to get nodes from blockchain")
+	}
+	nodesMap := common.ToNodesMap(nodes)
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(files))
+	for _, f := range files {
+		go func(f blockchain.File) {
+			defer wg.Done()
+			err := common.ReduceFileSlices(ctx, pri, e.copier, e.chain, e.challenger, f, nodesMap, replica, logger)
+			if err != nil {
+				logger.WithField("file_id", f.ID).WithError(err).Error("failed to reduce file")
+			} else {
+				logger.WithField("file_id", f.ID).Info("successfully reduced file")
+			}
+		}(f)
+	}
+	wg.Wait()
+	return nil
+}
@@ -49,15 +49,22 @@ func (e *Engine) GetNode(id []byte) (blockchain.Node, error) {
 
 // NodeOffline set storage node status to offline
 func (e *Engine) NodeOffline(opt types.NodeOperateOptions) error {
-	return e.storageNodeOperate(opt, false)
+	return e.storageNodeOperate(opt, e.chain.NodeOffline)
 }
 
 // NodeOnline set storage node status to online
 func (e *Engine) NodeOnline(opt types.NodeOperateOptions) error {
-	return e.storageNodeOperate(opt, true)
+	return e.storageNodeOperate(opt, e.chain.NodeOnline)
 }
 
-func (e *Engine) storageNodeOperate(opt types.NodeOperateOptions, isOnline bool) error {
+// NodeDrain set storage node status to draining, dataOwners migrate slices off the node,
+// and the node is set offline once empty
+func (e *Engine) NodeDrain(opt types.NodeOperateOptions) error {
+	return e.storageNodeOperate(opt, e.chain.NodeDrain)
+}
+
+func (e *Engine) storageNodeOperate(opt types.NodeOperateOptions,
+	operate func(opt *blockchain.NodeOperateOptions) error) error {
 	if err := e.verifyUserIDIsLocalNodeID(opt.NodeID); err != nil {
 		return err
 	}
@@ -84,12 +91,7 @@ func (e *Engine) storageNodeOperate(opt types.NodeOperateOptions, isOnline bool)
 	}
 	nodeOpts.Signature = sig[:]
 
-	if isOnline {
-		err = e.chain.NodeOnline(nodeOpts)
-	} else {
-		err = e.chain.NodeOffline(nodeOpts)
-	}
-	if err != nil {
+	if err := operate(nodeOpts); err != nil {
 		if errorx.Is(err, errorx.ErrCodeNotFound) {
 			return errorx.New(errorx.ErrCodeNotFound, "node not found")
 		}
@@ -22,12 +22,12 @@ import (
 	"time"
 
 	"github.com/cjqpker/slidewindow"
-	"github.com/sirupsen/logrus"
 
 	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
 	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/common"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/compressor"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
@@ -63,28 +63,32 @@ func verifyReadToken(opt types.ReadOptions) error {
 // 3. decrypt the file's struct to get slice's order
 // 4. download slices from the storage node, if request fails, pull slices from other storage nodes
 // 5. slices decryption and combination
-// 6. decrypt the combined slices to get the original file
-func (e *Engine) Read(ctx context.Context, opt types.ReadOptions) (io.ReadCloser, error) {
+// 6. decrypt the combined slices to get the original file, and decompress it if compressed
+// If a range of the file is requested, only slices covering the range are pulled,
+// except for files in legacy format which are sealed as a whole, and compressed files
+// which are decompressed from the beginning. FileLength of resp is set as well when the range
+// is not satisfiable.
+func (e *Engine) Read(ctx context.Context, opt types.ReadOptions) (resp types.ReadResponse, err error) {
 	ctx, cancel := context.WithCancel(ctx)
 
 	// check key match
 	if err := e.verifyUserID(opt.User); err != nil {
 		cancel()
-		return nil, err
+		return resp, err
 	}
 	// verify token
 	if err := verifyReadToken(opt); err != nil {
 		cancel()
-		return nil, err
+		return resp, err
 	}
-        pubkey := ecdsa.PublicKeyFromPrivateKey(e.monitor.challengingMonitor.PrivateKey)
+	pubkey := ecdsa.PublicKeyFromPrivateKey(e.monitor.challengingMonitor.PrivateKey)
 	opt.User = pubkey.String()
 
 	// prepare
 	allNodes, err := e.chain.ListNodes()
 	if err != nil {
 		cancel()
-		return nil, errorx.Wrap(err, "failed to get nodes from blockchain")
+		return resp, errorx.Wrap(err, "failed to get nodes from blockchain")
 	}
 	// get online nodes
 	var nodes blockchain.Nodes
@@ -95,7 +99,7 @@ func (e *Engine) Read(ctx context.Context, opt types.ReadOptions) (io.ReadCloser
 	}
 	if len(nodes) == 0 {
 		cancel()
-		return nil, errorx.New(errorx.ErrCodeInternal, "empty online nodes")
+		return resp, errorx.New(errorx.ErrCodeInternal, "empty online nodes")
 	}
 	nodesMap := common.ToNodesMap(nodes)
 
@@ -103,24 +107,72 @@ func (e *Engine) Read(ctx context.Context, opt types.ReadOptions) (io.ReadCloser
 	f, err := getBlockchainFile4Read(e.chain, &opt)
 	if err != nil {
 		cancel()
-		return nil, err
+		return resp, err
 	}
 	if opt.User != hex.EncodeToString(f.Owner) {
 		cancel()
-		return nil, errorx.New(errorx.ErrCodeNotAuthorized, "not authorized")
+		return resp, errorx.New(errorx.ErrCodeNotAuthorized, "not authorized")
 	}
 	opt.FileID = f.ID
+	if f.CipherFormat != encryptor.FormatGCM && f.CipherFormat != encryptor.FormatChunkedGCM &&
+		f.CipherFormat != encryptor.FormatConvergentGCM {
+		cancel()
+		return resp, errorx.New(errorx.ErrCodeCrypto, "unsupported cipher format %s", f.CipherFormat)
+	}
+	compressed := compressor.Enabled(f.Compression)
+	if compressed && f.CipherFormat != encryptor.FormatChunkedGCM {
+		cancel()
+		return resp, errorx.New(errorx.ErrCodeCrypto, "unsupported compression in cipher format %s", f.CipherFormat)
+	}
+
+	// check the range to read
+	// file length is returned with the error as well if the range is not satisfiable
+	resp.FileLength = f.Length
+	offset, length, err := readRange(opt, f.Length)
+	if err != nil {
+		cancel()
+		return resp, err
+	}
+	resp.Offset, resp.Length = offset, length
+	if length == 0 {
+		cancel()
+		resp.ReadCloser = ioutil.NopCloser(bytes.NewReader(nil))
+		return resp, nil
+	}
 
 	// recover structure
-	fs, err := e.recoverChainFileStructure(f.Structure, opt.FileID)
+	fs, err := e.recoverChainFileStructure(f)
 	if err != nil {
 		cancel()
-		return nil, err
+		return resp, err
+	}
+
+	// find stripes holding ciphertext of the range, erasure coded files are read stripe by stripe.
+	// files in chunked format are encrypted with encryptor.DefaultChunkSize,
+	// slices of files in convergent format are decrypted one by one, so the range is located in plaintext
+	cipherStart, cipherEnd := uint64(0), f.Length+16
+	switch f.CipherFormat {
+	case encryptor.FormatChunkedGCM:
+		if compressed {
+			cipherStart, cipherEnd = encryptor.StreamCipherRange(f.CompressedLength, encryptor.DefaultChunkSize,
+				0, f.CompressedLength)
+			break
+		}
+		cipherStart, cipherEnd = encryptor.StreamCipherRange(f.Length, encryptor.DefaultChunkSize, offset, length)
+	case encryptor.FormatConvergentGCM:
+		cipherStart, cipherEnd = offset, offset+length
+	}
+	stripes := common.GroupStripes(fs, f.ErasureCoded())
+	first, last, skip := stripeRange(stripes, uniformSliceLength(f), cipherStart, cipherEnd)
+	if first >= last {
+		cancel()
+		return resp, errorx.Internal(nil, "bad file structure")
 	}
+	stripes = stripes[first:last]
 
 	// use sliding window
 	sw := slidewindow.SlideWindow{
-		Total:       uint64(len(fs)),
+		Total:       uint64(len(stripes)),
 		Concurrency: defaultConcurrency,
 	}
 
@@ -128,79 +180,37 @@ func (e *Engine) Read(ctx context.Context, opt types.ReadOptions) (io.ReadCloser
 		return nil
 	}
 
-	slicesPool := makeSlicesPool4Read(f.Slices)
+	slicesPool := common.MakeSlicesPool4Read(common.ResolveSliceRefs(e.chain, f.Slices, logger))
 	sw.Task = func(ctx context.Context, s *slidewindow.Session) error {
-		slice := fs[int(s.Index())]
+		stripe := stripes[int(s.Index())]
 
-		targetPool, ok := slicesPool[slice.SliceID]
-		if !ok {
-			return errorx.Internal(nil, "bad file structure")
-		}
-		for _, target := range targetPool {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
+		if !f.ErasureCoded() {
+			slice := stripe[0]
+			targetPool, ok := slicesPool[slice.SliceID]
+			if !ok {
+				return errorx.Internal(nil, "bad file structure")
 			}
-
-			// pull slice
-			node, exist := nodesMap[string(target.NodeID)]
-			if !exist || !node.Online {
-				logger.WithField("node_id", string(target.NodeID)).Warn("abnormal node")
-				continue
-			}
-
-			r, err := e.copier.Pull(ctx, target.ID, target.StorIndex, f.ID, &node)
+			plainText, err := e.pullSlice(ctx, opt.FileID, slice, targetPool, nodesMap)
 			if err != nil {
-				logger.WithFields(logrus.Fields{
-					"slice_id":         target.ID,
-					"slice_stor_index": target.StorIndex,
-					"file_id":          opt.FileID,
-					"target_node":      string(node.ID),
-				}).WithError(err).Warn("failed to pull slice")
-				continue
+				return err
 			}
-			defer r.Close()
-
-			// read
-			cipherText, err := ioutil.ReadAll(r)
-			if err != nil {
-				logger.WithError(err).Warn("failed to read slice from target node")
-				continue
+			if f.CipherFormat == encryptor.FormatConvergentGCM {
+				if plainText, err = encryptor.ConvergentOpen(plainText, slice.Key, slice.PlainHash); err != nil {
+					return errorx.Wrap(err, "failed to decrypt slice %s", slice.SliceID)
+				}
 			}
-			if len(cipherText) != int(target.Length) {
-				logger.WithFields(logrus.Fields{"expected": target.Length, "got": len(cipherText)}).
-					Warn("invalid slice length.")
-				continue
-			}
-			hGot := hash.HashUsingSha256(cipherText)
-			if !bytes.Equal(hGot, target.CipherHash) {
-				logger.WithFields(logrus.Fields{"expected": target.CipherHash, "got": hGot}).
-					Warn("invalid slice hash.")
-				continue
-			}
-
-			// decrypt
-			eOpt := encryptor.RecoverOptions{
-				FileID:  opt.FileID,
-				SliceID: target.ID,
-				NodeID:  target.NodeID,
-			}
-			plainText, err := e.encryptor.Recover(bytes.NewReader(cipherText), &eOpt)
-			if err != nil {
-				logger.WithError(err).Error("failed to decrypt slice")
-				continue
-			}
-
-			s.Set("data", plainText)
-
-			break
+			s.Set("data", [][]byte{plainText})
+			return nil
 		}
 
-		if _, exist := s.Get("data"); !exist {
-			return errorx.New(errorx.ErrCodeNotFound, "failed to pull slice %s", slice.SliceID)
+		read := func(ctx context.Context, s blockchain.PrivateSliceMeta) ([]byte, error) {
+			return e.pullSlice(ctx, opt.FileID, s, slicesPool[s.SliceID], nodesMap)
 		}
-
+		plainTexts, err := common.ReadStripe(ctx, stripe, read, logger)
+		if err != nil {
+			return err
+		}
+		s.Set("data", plainTexts)
 		return nil
 	}
 
@@ -210,13 +220,15 @@ func (e *Engine) Read(ctx context.Context, opt types.ReadOptions) (io.ReadCloser
 		if !exist {
 			return errorx.New(errorx.ErrCodeNotFound, "failed to find data")
 		}
-		sliceData := bytes.TrimRight(data.([]byte), string([]byte{0}))
-		if _, err := writer.Write(sliceData); err != nil {
-			return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write")
+		// zero padding at the end of the last slice is dropped by the file decryption
+		for _, d := range data.([][]byte) {
+			if _, err := writer.Write(d); err != nil {
+				return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write")
+			}
 		}
 
 		// exit on success
-		if s.Index() == uint64(len(fs)-1) {
+		if s.Index() == uint64(len(stripes)-1) {
 			writer.Close()
 		}
 
@@ -230,18 +242,165 @@ func (e *Engine) Read(ctx context.Context, opt types.ReadOptions) (io.ReadCloser
 		}
 	}()
 
-	// remove the extra 0 at the end of the file ciphertext
+	// drop ciphertext before the range in the first stripe
+	if _, err := io.CopyN(ioutil.Discard, reader, int64(skip)); err != nil {
+		reader.Close()
+		return resp, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read ciphertext during Recover")
+	}
+	cipherReader := io.LimitReader(reader, int64(cipherEnd-cipherStart))
+
+	// slices are decrypted already in convergent format
+	if f.CipherFormat == encryptor.FormatConvergentGCM {
+		resp.ReadCloser = &fileReader{Reader: cipherReader, pipe: reader, cancel: cancel}
+		return resp, nil
+	}
+
+	// decrypt and decompress the whole file as a stream, and drop plaintext out of the range
+	if compressed {
+		plain := e.encryptor.RecoverStream(cipherReader, f.CompressedLength, 0, f.CompressedLength,
+			&encryptor.RecoverOptions{FileID: opt.FileID, KeyID: f.KeyID, Salts: common.StreamSalts(f),
+				WrappedKey: f.WrappedKey})
+		dr, err := compressor.Decompress(f.Compression, plain)
+		if err != nil {
+			reader.Close()
+			return resp, err
+		}
+		if _, err := io.CopyN(ioutil.Discard, dr, int64(offset)); err != nil {
+			reader.Close()
+			dr.Close()
+			return resp, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to decompress file")
+		}
+		resp.ReadCloser = &fileReader{Reader: io.LimitReader(dr, int64(length)), pipe: reader, cancel: cancel,
+			decompressor: dr}
+		return resp, nil
+	}
+
+	// decrypt recovered file as a stream
+	if f.CipherFormat == encryptor.FormatChunkedGCM {
+		plain := e.encryptor.RecoverStream(cipherReader, f.Length, offset, length,
+			&encryptor.RecoverOptions{FileID: opt.FileID, KeyID: f.KeyID, Salts: common.StreamSalts(f),
+				WrappedKey: f.WrappedKey})
+		resp.ReadCloser = &fileReader{Reader: plain, pipe: reader, cancel: cancel}
+		return resp, nil
+	}
+
+	// the whole file is sealed at once in legacy format,
 	// the length of the ciphertext is 16 more than the original text length
-	fileCiphertext, err := ioutil.ReadAll(reader)
+	fileCiphertext, err := ioutil.ReadAll(cipherReader)
+	reader.Close()
 	if err != nil {
-		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read ciphertext during Recover")
+		return resp, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read ciphertext during Recover")
 	}
 	// decrypt recovered file
-	plain, err := e.encryptor.Recover(bytes.NewReader(fileCiphertext[:f.Length+16]), &encryptor.RecoverOptions{FileID: opt.FileID})
+	plain, err := e.encryptor.Recover(bytes.NewReader(fileCiphertext),
+		&encryptor.RecoverOptions{FileID: opt.FileID, KeyID: f.KeyID, WrappedKey: f.WrappedKey})
 	if err != nil {
-		return nil, errorx.NewCode(err, errorx.ErrCodeCrypto, "failed to recover original file")
+		return resp, errorx.NewCode(err, errorx.ErrCodeCrypto, "failed to recover original file")
+	}
+	resp.ReadCloser = ioutil.NopCloser(bytes.NewReader(plain[offset : offset+length]))
+	return resp, nil
+}
+
+// fileReader reads plaintext of a file decrypted as a stream,
+// Close stops pulling the remaining slices of the file
+type fileReader struct {
+	io.Reader
+	pipe         *io.PipeReader
+	cancel       context.CancelFunc
+	decompressor io.Closer // set if the file is compressed
+}
+
+// the pipe is closed first, so that the decompressor blocked in reading is stopped
+func (f *fileReader) Close() error {
+	f.cancel()
+	err := f.pipe.Close()
+	if f.decompressor != nil {
+		f.decompressor.Close()
 	}
-	return ioutil.NopCloser(bytes.NewReader(plain)), nil
+	return err
+}
+
+// readRange returns offset and length of the range to read, the whole file by default
+func readRange(opt types.ReadOptions, fileLength uint64) (uint64, uint64, error) {
+	if !opt.Ranged() {
+		return 0, fileLength, nil
+	}
+	if opt.Suffix > 0 {
+		if fileLength == 0 {
+			return 0, 0, errorx.New(errorx.ErrCodeRangeNotSatisfiable, "range not satisfiable, empty file")
+		}
+		if opt.Suffix > fileLength {
+			return 0, fileLength, nil
+		}
+		return fileLength - opt.Suffix, opt.Suffix, nil
+	}
+	if opt.Offset >= fileLength {
+		return 0, 0, errorx.New(errorx.ErrCodeRangeNotSatisfiable,
+			"range not satisfiable, offset %d, file length %d", opt.Offset, fileLength)
+	}
+	length := fileLength - opt.Offset
+	if opt.Length > 0 && opt.Length < length {
+		length = opt.Length
+	}
+	return opt.Offset, length, nil
+}
+
+// stripeRange finds stripes holding the file ciphertext [start, end), sliceLength is the length of
+// data slices whose length is not recorded in file structure.
+// Returns the stripes in [first, last) and the number of bytes before start in the first stripe.
+func stripeRange(stripes []blockchain.FileStructure, sliceLength, start, end uint64) (int, int, uint64) {
+	first, last := len(stripes), 0
+	var skip, pos uint64
+	for i, stripe := range stripes {
+		var size uint64
+		for _, s := range stripe {
+			if s.Parity {
+				continue
+			}
+			if s.Length > 0 {
+				size += s.Length
+			} else {
+				size += sliceLength
+			}
+		}
+		if pos+size > start && pos < end {
+			if i < first {
+				first = i
+				skip = start - pos
+			}
+			last = i + 1
+		}
+		pos += size
+	}
+	return first, last, skip
+}
+
+// uniformSliceLength returns the plaintext length of slices of files whose structure does not
+// record slice length, all slices of such files are padded to the same length by the slicer
+func uniformSliceLength(f blockchain.File) uint64 {
+	if len(f.Slices) == 0 || f.Slices[0].Length < 16 {
+		return 0
+	}
+	// slices are sealed by AES-GCM with a 16 bytes tag
+	return f.Slices[0].Length - 16
+}
+
+// pullSlice pulls a slice from one of the storage nodes in targetPool and decrypts it
+func (e *Engine) pullSlice(ctx context.Context, fileID string, slice blockchain.PrivateSliceMeta,
+	targetPool []blockchain.PublicSliceMeta, nodesMap map[string]blockchain.Node) ([]byte, error) {
+	fetch := func(ctx context.Context, target blockchain.PublicSliceMeta, node *blockchain.Node) (io.ReadCloser, error) {
+		return e.copier.Pull(ctx, target.ID, target.StorIndex, fileID, node)
+	}
+	// referenced slices are encrypted with keys of their source files
+	decrypt := func(target blockchain.PublicSliceMeta, cipherText []byte) ([]byte, error) {
+		return e.encryptor.Recover(bytes.NewReader(cipherText), &encryptor.RecoverOptions{
+			FileID:  target.KeyFileID(fileID),
+			SliceID: target.ID,
+			NodeID:  target.NodeID,
+			KeyID:   target.KeyID,
+		})
+	}
+	return common.PullSlice(ctx, fileID, slice.SliceID, targetPool, nodesMap, fetch, decrypt, logger)
 }
 
 // getBlockchainFile4Read query file details by fileID or fileName from blockchain
@@ -253,7 +412,11 @@ func getBlockchainFile4Read(chain Blockchain, opt *types.ReadOptions) (
 		f, err = chain.GetFileByID(opt.FileID)
 	} else {
 		pubkey, _ := hex.DecodeString(opt.User)
-		f, err = chain.GetFileByName(pubkey, opt.Namespace, opt.FileName)
+		if opt.Version > 0 {
+			f, err = chain.GetFileByVersion(pubkey, opt.Namespace, opt.FileName, opt.Version)
+		} else {
+			f, err = chain.GetFileByName(pubkey, opt.Namespace, opt.FileName)
+		}
 	}
 	if err != nil {
 		return f, errorx.Wrap(err, "failed to read file from blockchain")
@@ -261,20 +424,3 @@ func getBlockchainFile4Read(chain Blockchain, opt *types.ReadOptions) (
 
 	return f, nil
 }
-
-// makeSlicesPool4Read get the list of storage nodes of slices by blockchain.PublicSliceMeta.
-// return map, key is sliceID, value is storage nodeID lists
-func makeSlicesPool4Read(srs []blockchain.PublicSliceMeta) map[string][]blockchain.PublicSliceMeta {
-	slicesPool := make(map[string][]blockchain.PublicSliceMeta)
-	for _, s := range srs {
-		var ss []blockchain.PublicSliceMeta
-		if v, exist := slicesPool[s.ID]; exist {
-			ss = v
-		}
-		ss = append(ss, s)
-		slicesPool[s.ID] = ss
-	}
-
-	return slicesPool
-}
-
@@ -0,0 +1,157 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor/soft"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+func TestReadRange(t *testing.T) {
+	tests := []struct {
+		name           string
+		opt            types.ReadOptions
+		fileLength     uint64
+		offset, length uint64
+		unsatisfiable  bool
+	}{
+		{name: "whole file", fileLength: 10, length: 10},
+		{name: "offset", opt: types.ReadOptions{Offset: 4}, fileLength: 10, offset: 4, length: 6},
+		{name: "offset and length", opt: types.ReadOptions{Offset: 4, Length: 3}, fileLength: 10, offset: 4, length: 3},
+		{name: "length beyond the end", opt: types.ReadOptions{Offset: 4, Length: 30}, fileLength: 10, offset: 4, length: 6},
+		{name: "length only", opt: types.ReadOptions{Length: 3}, fileLength: 10, length: 3},
+		{name: "last byte", opt: types.ReadOptions{Offset: 9}, fileLength: 10, offset: 9, length: 1},
+		{name: "offset at the end", opt: types.ReadOptions{Offset: 10}, fileLength: 10, unsatisfiable: true},
+		{name: "suffix", opt: types.ReadOptions{Suffix: 3}, fileLength: 10, offset: 7, length: 3},
+		{name: "suffix of whole file", opt: types.ReadOptions{Suffix: 10}, fileLength: 10, length: 10},
+		{name: "suffix beyond the start", opt: types.ReadOptions{Suffix: 30}, fileLength: 10, length: 10},
+		{name: "suffix overrides offset", opt: types.ReadOptions{Offset: 2, Length: 2, Suffix: 3}, fileLength: 10,
+			offset: 7, length: 3},
+		{name: "zero-length file", fileLength: 0},
+		{name: "offset of zero-length file", opt: types.ReadOptions{Offset: 1}, unsatisfiable: true},
+		{name: "length of zero-length file", opt: types.ReadOptions{Length: 1}, unsatisfiable: true},
+		{name: "suffix of zero-length file", opt: types.ReadOptions{Suffix: 1}, unsatisfiable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, length, err := readRange(tt.opt, tt.fileLength)
+			if tt.unsatisfiable {
+				require.True(t, errorx.Is(err, errorx.ErrCodeRangeNotSatisfiable))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.offset, offset)
+			require.Equal(t, tt.length, length)
+		})
+	}
+}
+
+func TestStripeRange(t *testing.T) {
+	// slices without recorded length, as written by early versions
+	legacy := func(n int) []blockchain.FileStructure {
+		var stripes []blockchain.FileStructure
+		for i := 0; i < n; i++ {
+			stripes = append(stripes, blockchain.FileStructure{{SliceID: "s"}})
+		}
+		return stripes
+	}
+	// stripes of erasure coded files, lengths are of data shards, followed by a parity shard
+	erasureCoded := func(lengths ...[]uint64) []blockchain.FileStructure {
+		var stripes []blockchain.FileStructure
+		for i, ls := range lengths {
+			var stripe blockchain.FileStructure
+			for _, l := range ls {
+				stripe = append(stripe, blockchain.PrivateSliceMeta{Length: l, Stripe: i})
+			}
+			stripe = append(stripe, blockchain.PrivateSliceMeta{Length: ls[0], Stripe: i, Parity: true})
+			stripes = append(stripes, stripe)
+		}
+		return stripes
+	}
+
+	tests := []struct {
+		name              string
+		stripes           []blockchain.FileStructure
+		sliceLength       uint64
+		start, end        uint64
+		first, last, skip int
+	}{
+		{name: "legacy whole file", stripes: legacy(7), sliceLength: 4, start: 0, end: 26, first: 0, last: 7},
+		{name: "legacy within a slice", stripes: legacy(7), sliceLength: 4, start: 5, end: 7, first: 1, last: 2, skip: 1},
+		{name: "legacy across slices", stripes: legacy(7), sliceLength: 4, start: 7, end: 13, first: 1, last: 4, skip: 3},
+		{name: "legacy last partial slice", stripes: legacy(7), sliceLength: 4, start: 25, end: 26, first: 6, last: 7, skip: 1},
+		{name: "recorded length overrides slice length", sliceLength: 100, start: 6, end: 8, first: 1, last: 2, skip: 2,
+			stripes: []blockchain.FileStructure{{{Length: 4}}, {{Length: 4}}, {{Length: 2}}}},
+		{name: "erasure coded first stripe", stripes: erasureCoded([]uint64{4, 4}, []uint64{4, 2}), start: 0, end: 8,
+			first: 0, last: 1},
+		{name: "erasure coded across stripes", stripes: erasureCoded([]uint64{4, 4}, []uint64{4, 2}), start: 7, end: 9,
+			first: 0, last: 2, skip: 7},
+		{name: "erasure coded last partial stripe", stripes: erasureCoded([]uint64{4, 4}, []uint64{4, 2}), start: 9, end: 14,
+			first: 1, last: 2, skip: 1},
+		{name: "zero-length file", stripes: []blockchain.FileStructure{{{Length: 0}}}, start: 0, end: 0, first: 1, last: 0},
+		{name: "no stripes", start: 0, end: 16, first: 0, last: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, last, skip := stripeRange(tt.stripes, tt.sliceLength, tt.start, tt.end)
+			require.Equal(t, tt.first, first)
+			require.Equal(t, tt.last, last)
+			require.Equal(t, uint64(tt.skip), skip)
+		})
+	}
+}
+
+func TestUniformSliceLength(t *testing.T) {
+	enc, err := soft.New(&config.SoftEncryptorConf{Password: "hello"})
+	require.NoError(t, err)
+	// slices are sealed for each storage node, ciphertext is 16 bytes longer than the slice
+	sealed := func(plain []byte) blockchain.PublicSliceMeta {
+		es, err := enc.Encrypt(bytes.NewReader(plain), &encryptor.EncryptOptions{FileID: "f", SliceID: "s", NodeID: []byte("n")})
+		require.NoError(t, err)
+		require.Equal(t, uint64(len(es.CipherText)), es.Length)
+		return blockchain.PublicSliceMeta{Length: es.Length}
+	}
+
+	tests := []struct {
+		name   string
+		file   blockchain.File
+		length uint64
+	}{
+		{name: "legacy", length: 4096,
+			file: blockchain.File{CipherFormat: encryptor.FormatGCM, Slices: []blockchain.PublicSliceMeta{sealed(make([]byte, 4096))}}},
+		{name: "chunked", length: 1024,
+			file: blockchain.File{CipherFormat: encryptor.FormatChunkedGCM, Slices: []blockchain.PublicSliceMeta{sealed(make([]byte, 1024))}}},
+		{name: "erasure coded", length: 512,
+			file: blockchain.File{CipherFormat: encryptor.FormatChunkedGCM, DataShards: 2, ParityShards: 1,
+				Slices: []blockchain.PublicSliceMeta{sealed(make([]byte, 512))}}},
+		{name: "empty slice", length: 0,
+			file: blockchain.File{CipherFormat: encryptor.FormatConvergentGCM, Slices: []blockchain.PublicSliceMeta{sealed(nil)}}},
+		{name: "no slices", file: blockchain.File{}},
+		{name: "unknown slice length", file: blockchain.File{Slices: []blockchain.PublicSliceMeta{{SourceFileID: "source"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.length, uniformSliceLength(tt.file))
+		})
+	}
+}
@@ -0,0 +1,431 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
+	"github.com/sirupsen/logrus"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/index"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/peer"
+)
+
+const (
+	defaultIndexSyncInterval = 10 * time.Minute
+
+	// number of files or challenges listed from blockchain at a time
+	indexSyncBatchSize = blockchain.ListMaxNumber
+	// challenges published within the overlap before the last synchronization are listed again,
+	// they may be committed on chain after it
+	indexSyncOverlap = 10 * time.Minute
+)
+
+// localIndex keeps files and challenges of owners synced from blockchain
+type localIndex struct {
+	storage  Index
+	owners   map[string][]byte // hex encoded public keys of owners indexed
+	interval time.Duration     // interval of synchronizing files and challenges completely
+}
+
+func newLocalIndex(storage Index, local peer.Local, owners []string, interval time.Duration) (*localIndex, error) {
+	if interval <= 0 {
+		interval = defaultIndexSyncInterval
+	}
+	localKey := ecdsa.PublicKeyFromPrivateKey(local.PrivateKey)
+	i := &localIndex{
+		storage:  storage,
+		owners:   map[string][]byte{localKey.String(): localKey[:]},
+		interval: interval,
+	}
+	for _, o := range owners {
+		pubkey, err := ecdsa.DecodePublicKeyFromString(o)
+		if err != nil {
+			return nil, errorx.Wrap(err, "failed to decode owner public key %s", o)
+		}
+		i.owners[pubkey.String()] = pubkey[:]
+	}
+	return i, nil
+}
+
+// synced returns the sync state of the owner, false if the owner is not indexed
+func (i *localIndex) synced(owner []byte) (index.SyncState, bool) {
+	if i == nil {
+		return index.SyncState{}, false
+	}
+	if _, ok := i.owners[hex.EncodeToString(owner)]; !ok {
+		return index.SyncState{}, false
+	}
+	s, err := i.storage.SyncState(owner)
+	if err != nil {
+		logger.WithError(err).Warn("failed to get index sync state")
+		return s, false
+	}
+	return s, true
+}
+
+// SearchFiles searches files in the local index by keywords and extension fields, and returns a page of them
+// in the order required, along with the cursor of the next page
+func (e *Engine) SearchFiles(opt types.SearchFileOptions) (page types.FilePage, err error) {
+	if e.index == nil {
+		return page, errorx.New(errorx.ErrCodeConfig, "local index is not enabled")
+	}
+	owner, err := e.getPubKey(opt.Owner)
+	if err != nil {
+		return page, err
+	}
+	state, ok := e.index.synced(owner)
+	if !ok {
+		return page, errorx.New(errorx.ErrCodeNotFound, "files of the owner are not indexed")
+	}
+	if state.FilesSynced == 0 {
+		return page, errorx.New(errorx.ErrCodeNotFound, "files of the owner are not synced yet")
+	}
+	q := index.FileQuery{
+		Owner:     owner,
+		Namespace: opt.Namespace,
+		Keywords:  opt.Keywords,
+		Ext:       opt.Ext,
+		SortBy:    opt.SortBy,
+		Asc:       opt.Asc,
+		TimeStart: opt.TimeStart,
+		TimeEnd:   opt.TimeEnd,
+		Cursor:    opt.Cursor,
+		Limit:     opt.Limit,
+	}
+	if opt.CurrentTime > 0 {
+		q.ExpireStart = opt.CurrentTime + 1
+	}
+	page.Files, page.Next, err = e.index.storage.Files(q)
+	if err != nil {
+		return page, errorx.Wrap(err, "failed to search files")
+	}
+	return page, nil
+}
+
+// SearchChallenges searches challenges of files in the local index, and returns a page of them
+// along with the cursor of the next page
+func (e *Engine) SearchChallenges(opt types.SearchChallengeOptions) (page types.ChallengePage, err error) {
+	if e.index == nil {
+		return page, errorx.New(errorx.ErrCodeConfig, "local index is not enabled")
+	}
+	owner, err := e.getPubKey(opt.Owner)
+	if err != nil {
+		return page, err
+	}
+	state, ok := e.index.synced(owner)
+	if !ok {
+		return page, errorx.New(errorx.ErrCodeNotFound, "challenges of the owner are not indexed")
+	}
+	if state.ChallengesSynced == 0 {
+		return page, errorx.New(errorx.ErrCodeNotFound, "challenges of the owner are not synced yet")
+	}
+	page.Challenges, page.Next, err = e.index.storage.Challenges(index.ChallengeQuery{
+		FileOwner:  owner,
+		TargetNode: []byte(opt.TargetNode),
+		FileID:     opt.FileID,
+		Status:     opt.Status,
+		Asc:        opt.Asc,
+		TimeStart:  opt.TimeStart,
+		TimeEnd:    opt.TimeEnd,
+		Cursor:     opt.Cursor,
+		Limit:      opt.Limit,
+	})
+	if err != nil {
+		return page, errorx.Wrap(err, "failed to search challenges")
+	}
+	return page, nil
+}
+
+// listIndexedFiles lists files from the local index as the contract does, returns false if files of the owner
+// are not indexed or synced yet, or the page is continued from a cursor returned by the contract
+func (e *Engine) listIndexedFiles(opt blockchain.ListFileOptions, isExpired bool) (page types.FilePage, ok bool, err error) {
+	state, ok := e.index.synced(opt.Owner)
+	if !ok || state.FilesSynced == 0 || isChainCursor(opt.Cursor) {
+		return page, false, nil
+	}
+	q := index.FileQuery{
+		Owner:       opt.Owner,
+		Namespace:   opt.Namespace,
+		TimeStart:   opt.TimeStart,
+		TimeEnd:     opt.TimeEnd,
+		ExpireStart: opt.CurrentTime + 1,
+		Cursor:      opt.Cursor,
+		Limit:       opt.Limit,
+	}
+	if isExpired {
+		q.ExpireStart = opt.CurrentTime - blockchain.FileRetainPeriod.Nanoseconds()
+		q.ExpireEnd = opt.CurrentTime
+	}
+	page.Files, page.Next, err = e.index.storage.Files(q)
+	if err != nil {
+		return page, true, errorx.Wrap(err, "failed to list files from local index")
+	}
+	return page, true, nil
+}
+
+// listIndexedChallenges lists challenges from the local index as the contract does, returns false
+// if challenges of the owner are not indexed or synced yet, or the page is continued from a cursor returned by the contract
+func (e *Engine) listIndexedChallenges(opt blockchain.ListChallengeOptions) (page types.ChallengePage, ok bool, err error) {
+	state, ok := e.index.synced(opt.FileOwner)
+	if !ok || state.ChallengesSynced == 0 || isChainCursor(opt.Cursor) {
+		return page, false, nil
+	}
+	page.Challenges, page.Next, err = e.index.storage.Challenges(index.ChallengeQuery{
+		FileOwner:  opt.FileOwner,
+		TargetNode: opt.TargetNode,
+		FileID:     opt.FileID,
+		Status:     opt.Status,
+		TimeStart:  opt.TimeStart,
+		TimeEnd:    opt.TimeEnd,
+		Cursor:     opt.Cursor,
+		Limit:      opt.Limit,
+	})
+	if err != nil {
+		return page, true, errorx.Wrap(err, "failed to list challenges from local index")
+	}
+	return page, true, nil
+}
+
+// isChainCursor checks if the cursor is returned by contracts rather than the local index,
+// so that lists started from blockchain are continued there even if the index is synced in between
+func isChainCursor(cursor string) bool {
+	if cursor == "" {
+		return false
+	}
+	_, err := blockchain.ParseCursor(cursor)
+	return err == nil
+}
+
+// indexFile updates a file published or updated by the node in the local index,
+// so that it's searched before the next synchronization
+func (e *Engine) indexFile(f blockchain.File) {
+	if _, ok := e.index.synced(f.Owner); !ok {
+		return
+	}
+	if err := e.index.storage.PutFile(f); err != nil {
+		logger.WithField("file_id", f.ID).WithError(err).Warn("failed to index file")
+	}
+}
+
+// unindexFile removes a file deleted by the node from the local index
+func (e *Engine) unindexFile(f blockchain.File) {
+	if _, ok := e.index.synced(f.Owner); !ok {
+		return
+	}
+	if err := e.index.storage.DeleteFile(f.ID); err != nil {
+		logger.WithField("file_id", f.ID).WithError(err).Warn("failed to remove file from index")
+	}
+}
+
+// syncIndex synchronizes files and challenges of owners from blockchain into the local index regularly,
+// challenges are updated once their events are received between synchronizations
+func (e *Engine) syncIndex(ctx context.Context) {
+	l := logger.WithField("runner", "index sync loop")
+	defer l.Info("index sync stopped")
+
+	events := blockchain.Watch(ctx, e.chain, blockchain.EventChallengeRequest, blockchain.EventChallengeAnswer)
+	ticker := time.NewTicker(e.index.interval)
+	defer ticker.Stop()
+
+	e.syncOwners(ctx, l)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.syncOwners(ctx, l)
+		case ev := <-events:
+			e.indexChallengeEvent(ev, l)
+		}
+	}
+}
+
+// syncOwners synchronizes files and challenges of all owners indexed
+func (e *Engine) syncOwners(ctx context.Context, l *logrus.Entry) {
+	for id, owner := range e.index.owners {
+		ol := l.WithField("owner", id)
+		state, err := e.index.storage.SyncState(owner)
+		if err != nil {
+			ol.WithError(err).Warn("failed to get index sync state")
+			continue
+		}
+
+		start := time.Now().UnixNano()
+		if err := e.syncFiles(ctx, owner); err != nil {
+			ol.WithError(err).Warn("failed to sync files")
+		} else {
+			state.FilesSynced = start
+		}
+		if synced, err := e.syncChallenges(ctx, owner, state.ChallengesSynced); err != nil {
+			ol.WithError(err).Warn("failed to sync challenges")
+		} else {
+			state.ChallengesSynced = synced
+		}
+		if err := e.index.storage.SetSyncState(owner, state); err != nil {
+			ol.WithError(err).Warn("failed to record index sync state")
+			continue
+		}
+		ol.WithField("cost", time.Since(time.Unix(0, start)).String()).Debug("synced local index")
+	}
+}
+
+// syncFiles lists all files of the owner from blockchain page by page with cursors, the latest first,
+// and removes files indexed but no longer listed, which are deleted or no longer retained
+func (e *Engine) syncFiles(ctx context.Context, owner []byte) error {
+	nss, err := e.chain.ListFileNs(&blockchain.ListNsOptions{Owner: owner})
+	if err != nil {
+		return errorx.Wrap(err, "failed to list namespaces")
+	}
+	for _, ns := range nss {
+		seen := make(map[string]struct{})
+		opt := blockchain.ListFileOptions{
+			Owner:     owner,
+			Namespace: ns.Name,
+			Limit:     indexSyncBatchSize,
+		}
+		// zero CurrentTime lists expired files as well
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+			files, err := e.chain.ListFiles(&opt)
+			if err != nil {
+				return errorx.Wrap(err, "failed to list files of namespace %s", ns.Name)
+			}
+			for _, f := range files {
+				seen[f.ID] = struct{}{}
+				if err := e.index.storage.PutFile(f); err != nil {
+					return err
+				}
+			}
+			if opt.Cursor = blockchain.NextFileCursor(files, opt.Limit); opt.Cursor == "" {
+				break
+			}
+		}
+
+		indexed, _, err := e.index.storage.Files(index.FileQuery{Owner: owner, Namespace: ns.Name})
+		if err != nil {
+			return err
+		}
+		for _, f := range indexed {
+			if _, ok := seen[f.ID]; ok {
+				continue
+			}
+			if err := e.index.storage.DeleteFile(f.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncChallenges lists challenges of the owner's files published since the last synchronization from blockchain,
+// and refreshes challenges published before which are still to prove, returns the time of this synchronization
+func (e *Engine) syncChallenges(ctx context.Context, owner []byte, since int64) (int64, error) {
+	now := time.Now().UnixNano()
+	start := since - indexSyncOverlap.Nanoseconds()
+	if since == 0 || start < 0 {
+		start = 0
+	}
+
+	nodes, err := e.chain.ListNodes()
+	if err != nil {
+		return since, errorx.Wrap(err, "failed to list nodes")
+	}
+	for _, node := range nodes {
+		for _, status := range []string{blockchain.ChallengeToProve, blockchain.ChallengeProved, blockchain.ChallengeFailed} {
+			opt := blockchain.ListChallengeOptions{
+				FileOwner:  owner,
+				TargetNode: node.ID,
+				Status:     status,
+				TimeStart:  start,
+				TimeEnd:    now,
+				Limit:      indexSyncBatchSize,
+			}
+			for {
+				select {
+				case <-ctx.Done():
+					return since, ctx.Err()
+				default:
+				}
+				cs, err := e.chain.ListChallengeRequests(&opt)
+				if err != nil && !errorx.Is(err, errorx.ErrCodeNotFound) {
+					return since, errorx.Wrap(err, "failed to list challenges")
+				}
+				for _, c := range cs {
+					if err := e.index.storage.PutChallenge(c); err != nil {
+						return since, err
+					}
+				}
+				if opt.Cursor = blockchain.NextChallengeCursor(cs, opt.Limit); opt.Cursor == "" {
+					break
+				}
+			}
+		}
+	}
+
+	// challenges answered since, but published before the period listed
+	if start > 0 {
+		pending, _, err := e.index.storage.Challenges(index.ChallengeQuery{
+			FileOwner: owner,
+			Status:    blockchain.ChallengeToProve,
+			TimeEnd:   start - 1,
+		})
+		if err != nil {
+			return since, err
+		}
+		for _, p := range pending {
+			c, err := e.chain.GetChallengeByID(p.ID)
+			if errorx.Is(err, errorx.ErrCodeNotFound) {
+				continue
+			} else if err != nil {
+				return since, errorx.Wrap(err, "failed to get challenge")
+			}
+			if err := e.index.storage.PutChallenge(c); err != nil {
+				return since, err
+			}
+		}
+	}
+	return now, nil
+}
+
+// indexChallengeEvent updates the challenge requested or answered in the local index
+func (e *Engine) indexChallengeEvent(ev blockchain.Event, l *logrus.Entry) {
+	var ce blockchain.ChallengeEvent
+	if err := json.Unmarshal(ev.Payload, &ce); err != nil {
+		l.WithError(err).Warn("failed to unmarshal challenge event")
+		return
+	}
+	if _, ok := e.index.synced(ce.FileOwner); !ok {
+		return
+	}
+	c, err := e.chain.GetChallengeByID(ce.ID)
+	if err != nil {
+		l.WithField("challenge_id", ce.ID).WithError(err).Warn("failed to get challenge")
+		return
+	}
+	if err := e.index.storage.PutChallenge(c); err != nil {
+		l.WithField("challenge_id", ce.ID).WithError(err).Warn("failed to index challenge")
+	}
+}
@@ -0,0 +1,664 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/common"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/upload"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	util "github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/strings"
+)
+
+const (
+	// The maximum number of parts a file is cut into
+	maxUploadParts = 10000
+	// The validity of requests operating upload sessions
+	uploadRequestExpiredTime = 5 * time.Minute
+	// The interval to clean up abandoned upload sessions
+	uploadCleanInterval = 10 * time.Minute
+
+	defaultUploadSessionTimeout = 24 * time.Hour
+)
+
+// uploadSessions keeps sessions uploading files in parts
+type uploadSessions struct {
+	storage UploadStorage
+	timeout time.Duration // sessions not updated within timeout are abandoned, at most upload.MaxSessionLifetime
+
+	lock       sync.Mutex
+	completing map[string]struct{} // sessions being completed, no more parts are accepted
+}
+
+func newUploadSessions(storage UploadStorage, timeout time.Duration) *uploadSessions {
+	if timeout <= 0 {
+		timeout = defaultUploadSessionTimeout
+	}
+	if timeout > upload.MaxSessionLifetime {
+		timeout = upload.MaxSessionLifetime
+	}
+	return &uploadSessions{
+		storage:    storage,
+		timeout:    timeout,
+		completing: make(map[string]struct{}),
+	}
+}
+
+// InitiateUpload starts a session to upload a file in parts, files uploaded in parts are not compressed.
+// If there is an unfinished session uploading the same file, the session is returned along with the parts
+// uploaded, so that an interrupted upload can be resumed by uploading the missing parts only.
+// An unfinished session of the same file name but different parameters, or older than
+// upload.MaxSessionLifetime, is aborted.
+func (e *Engine) InitiateUpload(ctx context.Context, opt types.InitiateUploadOptions) (types.UploadSession, error) {
+	if e.uploads == nil {
+		return types.UploadSession{}, errorx.New(errorx.ErrCodeConfig, "upload sessions not enabled")
+	}
+	// check key match
+	if err := e.verifyUserID(opt.User); err != nil {
+		return types.UploadSession{}, err
+	}
+	// verify token
+	msg, err := util.GetSigMessage(opt)
+	if err != nil {
+		return types.UploadSession{}, errorx.Internal(err, "failed to get the message to sign for upload files")
+	}
+	if err := verifyUserToken(opt.User, opt.Token, hash.HashUsingSha256([]byte(msg))); err != nil {
+		return types.UploadSession{}, errorx.Wrap(err, "failed to verify token")
+	}
+	if opt.PartSize%encryptor.DefaultChunkSize != 0 {
+		return types.UploadSession{}, errorx.New(errorx.ErrCodeParam,
+			"invalid part size, must be a multiple of %d", encryptor.DefaultChunkSize)
+	}
+
+	pubkey := ecdsa.PublicKeyFromPrivateKey(e.monitor.challengingMonitor.PrivateKey)
+	ns, err := e.chain.GetNsByName(pubkey[:], opt.Namespace)
+	if err != nil {
+		return types.UploadSession{}, errorx.Wrap(err, "failed to get ns from blockchain")
+	}
+
+	now := time.Now().UnixNano()
+	session := upload.Session{
+		Namespace:    opt.Namespace,
+		FileName:     opt.FileName,
+		Description:  opt.Description,
+		Extra:        opt.Extra,
+		ExpireTime:   opt.ExpireTime,
+		FileLength:   opt.FileLength,
+		PartSize:     opt.PartSize,
+		CipherFormat: e.cipherFormat(ns),
+		KeyID:        e.encryptor.CurrentKeyID(),
+		CreateTime:   now,
+		UpdateTime:   now,
+		Parts:        make(map[int]upload.Part),
+	}
+	if session.PartCount() > maxUploadParts {
+		return types.UploadSession{}, errorx.New(errorx.ErrCodeParam, "too many parts, part size is too small")
+	}
+
+	e.uploads.lock.Lock()
+	defer e.uploads.lock.Unlock()
+	sessions, err := e.uploads.storage.List()
+	if err != nil {
+		return types.UploadSession{}, errorx.Wrap(err, "failed to list upload sessions")
+	}
+	for _, s := range sessions {
+		if s.Namespace != opt.Namespace || s.FileName != opt.FileName {
+			continue
+		}
+		if _, exist := e.uploads.completing[s.ID]; exist {
+			return types.UploadSession{}, errorx.New(errorx.ErrCodeAlreadyExists, "file is being published")
+		}
+		// a session started without expire time expires by the default TTL of namespace, and is resumed as it is
+		if s.FileLength == session.FileLength && s.PartSize == session.PartSize &&
+			(session.ExpireTime == 0 || s.ExpireTime == session.ExpireTime) &&
+			s.Description == session.Description && s.Extra == session.Extra && s.CipherFormat == session.CipherFormat &&
+			s.KeyID == session.KeyID && !s.Expired(time.Now()) {
+			logger.WithField("session_id", s.ID).Info("resume upload session")
+			return toUploadSession(s), nil
+		}
+		// a new upload of the file supersedes the unfinished one
+		if err := e.uploads.storage.Delete(s.ID); err != nil {
+			return types.UploadSession{}, errorx.Wrap(err, "failed to delete upload session")
+		}
+		go e.deleteSessionSlices(context.Background(), s)
+	}
+
+	if session.ExpireTime == 0 {
+		if session.ExpireTime, err = defaultExpireTime(ns); err != nil {
+			return types.UploadSession{}, err
+		}
+	}
+	sessionID, err := uuid.NewRandom()
+	if err != nil {
+		return types.UploadSession{}, errorx.Internal(err, "failed to get uuid")
+	}
+	fileID, err := uuid.NewRandom()
+	if err != nil {
+		return types.UploadSession{}, errorx.Internal(err, "failed to get uuid")
+	}
+	session.ID = sessionID.String()
+	session.FileID = fileID.String()
+	if err := e.uploads.storage.Save(session); err != nil {
+		return types.UploadSession{}, errorx.Wrap(err, "failed to save upload session")
+	}
+
+	logger.WithFields(logrus.Fields{
+		"session_id": session.ID,
+		"file_id":    session.FileID,
+		"file_name":  opt.FileName,
+		"namespace":  opt.Namespace,
+		"parts":      session.PartCount(),
+	}).Info("initiate upload session")
+	return toUploadSession(session), nil
+}
+
+// UploadPart uploads a part of file, slices of the part are pushed to storage nodes at once,
+// and the part state is persisted locally. A part uploaded again replaces the former one.
+// Plaintext of the part must be of the length the session expects.
+func (e *Engine) UploadPart(ctx context.Context, opt types.UploadPartOptions, r io.Reader) (types.UploadedPart, error) {
+	session, err := e.verifyUploadSession(opt.UploadSessionOptions, opt)
+	if err != nil {
+		return types.UploadedPart{}, err
+	}
+	if opt.PartNumber > session.PartCount() {
+		return types.UploadedPart{}, errorx.New(errorx.ErrCodeParam, "invalid part number, the file has %d parts",
+			session.PartCount())
+	}
+	if e.isCompleting(session.ID) {
+		return types.UploadedPart{}, errorx.New(errorx.ErrCodeAlreadyExists, "file is being published")
+	}
+	if session.Expired(time.Now()) {
+		return types.UploadedPart{}, errorx.New(errorx.ErrCodeExpired, "upload session expired, upload the file again")
+	}
+	if err := e.checkSessionKey(session); err != nil {
+		return types.UploadedPart{}, err
+	}
+
+	pubkey := ecdsa.PublicKeyFromPrivateKey(e.monitor.challengingMonitor.PrivateKey)
+	ns, err := e.chain.GetNsByName(pubkey[:], session.Namespace)
+	if err != nil {
+		return types.UploadedPart{}, errorx.Wrap(err, "failed to get ns from blockchain")
+	}
+	nodes, err := e.getWriteNodes(ns)
+	if err != nil {
+		return types.UploadedPart{}, err
+	}
+
+	// read one more byte than expected to find out oversize parts
+	offset, length := session.PartRange(opt.PartNumber)
+	hasher := sha256.New()
+	plainReader := &countingReader{r: io.TeeReader(io.LimitReader(r, int64(length)+1), hasher)}
+	wopt := writeSlicesOptions{
+		fileID:       session.FileID,
+		owner:        pubkey.String(),
+		expireTime:   session.ExpireTime,
+		ns:           ns,
+		nodes:        nodes,
+		cipherFormat: session.CipherFormat,
+	}
+	// parts in chunked format are encrypted as a continuation of the former ones,
+	// slices are cut at the end of the part to be joined with slices of the next part.
+	// A fresh salt is drawn every time a part is uploaded, so that content uploaded again
+	// is never sealed by the same key and nonces as the former one
+	var cipherReader io.Reader = plainReader
+	var salt []byte
+	if session.CipherFormat == encryptor.FormatChunkedGCM {
+		if salt, err = encryptor.NewStreamSalt(); err != nil {
+			return types.UploadedPart{}, err
+		}
+		part := encryptor.StreamPart{Offset: offset, Last: opt.PartNumber == session.PartCount(), Salt: salt}
+		cipherReader = e.encryptor.EncryptStreamPart(plainReader, part, &encryptor.EncryptOptions{FileID: session.FileID})
+		wopt.length = encryptor.StreamPartCipherLength(part, length, encryptor.DefaultChunkSize)
+	} else if wopt.index, err = e.newDedupIndex(pubkey[:], session.Namespace, session.ExpireTime); err != nil {
+		return types.UploadedPart{}, err
+	}
+
+	ws, err := e.writeSlices(ctx, wopt, cipherReader)
+	defer ws.release()
+	if err == nil && plainReader.n != length {
+		err = errorx.New(errorx.ErrCodeParam, "invalid part length %d, expected %d", plainReader.n, length)
+	}
+	if err != nil {
+		e.deleteSlices(context.Background(), toPushedSlices(ws))
+		return types.UploadedPart{}, errorx.Wrap(err, "error occurred in writing part")
+	}
+
+	part := upload.Part{
+		Number:     opt.PartNumber,
+		Length:     length,
+		PlainHash:  hasher.Sum(nil),
+		UploadTime: time.Now().UnixNano(),
+		Salt:       salt,
+		Slices:     ws.metas,
+		Shards:     ws.shards,
+		Refs:       ws.refs,
+		Pushed:     toPushedSlices(ws),
+		Materials:  ws.materials,
+	}
+	former, err := e.savePart(session.ID, part, ws)
+	if err != nil {
+		e.deleteSlices(context.Background(), part.Pushed)
+		return types.UploadedPart{}, err
+	}
+	// slices of the part uploaded before are replaced
+	if former != nil {
+		go e.deleteSlices(context.Background(), former.Pushed)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"session_id": session.ID,
+		"part":       opt.PartNumber,
+		"slices":     len(part.Pushed),
+	}).Info("part uploaded")
+	return toUploadedPart(part), nil
+}
+
+// ListUploadParts returns an upload session and the parts uploaded
+func (e *Engine) ListUploadParts(opt types.UploadSessionOptions) (types.UploadSession, error) {
+	session, err := e.verifyUploadSession(opt, opt)
+	if err != nil {
+		return types.UploadSession{}, err
+	}
+	return toUploadSession(session), nil
+}
+
+// CompleteUpload joins all parts of an upload session into a file and publishes it on blockchain,
+// the session is removed once the file is published
+func (e *Engine) CompleteUpload(ctx context.Context, opt types.UploadSessionOptions) (types.WriteResponse, error) {
+	var resp types.WriteResponse
+	session, err := e.verifyUploadSession(opt, opt)
+	if err != nil {
+		return resp, err
+	}
+	for n := 1; n <= session.PartCount(); n++ {
+		if _, exist := session.Parts[n]; !exist {
+			return resp, errorx.New(errorx.ErrCodeParam, "part %d not uploaded", n)
+		}
+	}
+	if session.ExpireTime <= time.Now().UnixNano() {
+		return resp, errorx.New(errorx.ErrCodeParam, "invalid file expire time")
+	}
+	// storage nodes may have removed slices of the session as orphans
+	if session.Expired(time.Now()) {
+		return resp, errorx.New(errorx.ErrCodeExpired, "upload session expired, upload the file again")
+	}
+	if err := e.checkSessionKey(session); err != nil {
+		return resp, err
+	}
+
+	e.uploads.lock.Lock()
+	if _, exist := e.uploads.completing[session.ID]; exist {
+		e.uploads.lock.Unlock()
+		return resp, errorx.New(errorx.ErrCodeAlreadyExists, "file is being published")
+	}
+	e.uploads.completing[session.ID] = struct{}{}
+	e.uploads.lock.Unlock()
+	defer func() {
+		e.uploads.lock.Lock()
+		delete(e.uploads.completing, session.ID)
+		e.uploads.lock.Unlock()
+	}()
+	// parts may be uploaded again before the session is marked as completing
+	if session, err = e.uploads.storage.Load(session.ID); err != nil {
+		return resp, errorx.Wrap(err, "failed to load upload session")
+	}
+
+	pubkey := ecdsa.PublicKeyFromPrivateKey(e.monitor.challengingMonitor.PrivateKey)
+	ns, err := e.chain.GetNsByName(pubkey[:], session.Namespace)
+	if err != nil {
+		return resp, errorx.Wrap(err, "failed to get ns from blockchain")
+	}
+
+	ws, err := e.joinParts(session)
+	if err != nil {
+		return resp, err
+	}
+	defer ws.release()
+	wopt := types.WriteOptions{
+		User:        pubkey.String(),
+		Namespace:   session.Namespace,
+		FileName:    session.FileName,
+		ExpireTime:  session.ExpireTime,
+		Description: session.Description,
+		Extra:       session.Extra,
+	}
+	content := fileContent{cipherFormat: session.CipherFormat, length: session.FileLength}
+	if session.CipherFormat == encryptor.FormatChunkedGCM {
+		content.salts = &encryptor.StreamSalts{PartSize: session.PartSize}
+		for n := 1; n <= session.PartCount(); n++ {
+			content.salts.Salts = append(content.salts.Salts, session.Parts[n].Salt)
+		}
+	}
+	file, err := e.publishFile(ctx, session.FileID, wopt, ns, content, ws)
+	if err != nil {
+		return resp, err
+	}
+	if err := e.uploads.storage.Delete(session.ID); err != nil {
+		logger.WithField("session_id", session.ID).WithError(err).Warn("failed to delete upload session")
+	}
+
+	logger.WithFields(logrus.Fields{
+		"session_id": session.ID,
+		"file_id":    session.FileID,
+		"version":    file.Version,
+	}).Debug("file uploaded")
+	resp.FileID = session.FileID
+	resp.Version = file.Version
+	return resp, nil
+}
+
+// AbortUpload removes an upload session, and deletes slices of the parts uploaded from storage nodes
+func (e *Engine) AbortUpload(ctx context.Context, opt types.UploadSessionOptions) error {
+	session, err := e.verifyUploadSession(opt, opt)
+	if err != nil {
+		return err
+	}
+
+	e.uploads.lock.Lock()
+	if _, exist := e.uploads.completing[session.ID]; exist {
+		e.uploads.lock.Unlock()
+		return errorx.New(errorx.ErrCodeAlreadyExists, "file is being published")
+	}
+	err = e.uploads.storage.Delete(session.ID)
+	e.uploads.lock.Unlock()
+	if err != nil {
+		return errorx.Wrap(err, "failed to delete upload session")
+	}
+
+	e.deleteSessionSlices(ctx, session)
+	logger.WithField("session_id", session.ID).Info("upload session aborted")
+	return nil
+}
+
+// cleanUploadSessions runs in background and removes abandoned upload sessions,
+// which are not updated within the session timeout or older than upload.MaxSessionLifetime. Slices of the sessions are deleted from storage nodes,
+// unless the file has been published, in case that the node stopped while completing a session
+func (e *Engine) cleanUploadSessions(ctx context.Context) {
+	l := logger.WithField("runner", "upload session clean loop")
+	defer l.Info("upload session clean stopped")
+
+	ticker := time.NewTicker(uploadCleanInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		sessions, err := e.uploads.storage.List()
+		if err != nil {
+			l.WithError(err).Warn("failed to list upload sessions")
+			continue
+		}
+		now := time.Now()
+		deadline := now.Add(-e.uploads.timeout).UnixNano()
+		for _, s := range sessions {
+			if s.UpdateTime > deadline && !s.Expired(now) {
+				continue
+			}
+			_, err := e.chain.GetFileByID(s.FileID)
+			published := err == nil || errorx.Is(err, errorx.ErrCodeExpired)
+			if err != nil && !published && !errorx.Is(err, errorx.ErrCodeNotFound) {
+				l.WithError(err).Warn("failed to read blockchain")
+				continue
+			}
+
+			e.uploads.lock.Lock()
+			if _, exist := e.uploads.completing[s.ID]; exist {
+				e.uploads.lock.Unlock()
+				continue
+			}
+			err = e.uploads.storage.Delete(s.ID)
+			e.uploads.lock.Unlock()
+			if err != nil {
+				l.WithError(err).Warn("failed to delete upload session")
+				continue
+			}
+			if !published {
+				e.deleteSessionSlices(ctx, s)
+			}
+			l.WithFields(logrus.Fields{
+				"session_id": s.ID,
+				"published":  published,
+			}).Info("abandoned upload session removed")
+		}
+	}
+}
+
+// verifyUploadSession checks the request to operate an upload session, and returns the session.
+// signed is the options signed by user, in which opt is embedded
+func (e *Engine) verifyUploadSession(opt types.UploadSessionOptions, signed interface{}) (upload.Session, error) {
+	if e.uploads == nil {
+		return upload.Session{}, errorx.New(errorx.ErrCodeConfig, "upload sessions not enabled")
+	}
+	// check key match
+	if err := e.verifyUserID(opt.User); err != nil {
+		return upload.Session{}, err
+	}
+	// verify token
+	msg, err := util.GetSigMessage(signed)
+	if err != nil {
+		return upload.Session{}, errorx.Internal(err, "failed to get the message to sign")
+	}
+	if err := verifyUserToken(opt.User, opt.Token, hash.HashUsingSha256([]byte(msg))); err != nil {
+		return upload.Session{}, errorx.Wrap(err, "failed to verify token")
+	}
+	if opt.CurrentTime < time.Now().Add(-uploadRequestExpiredTime).UnixNano() {
+		return upload.Session{}, errorx.New(errorx.ErrCodeExpired, "request expired")
+	}
+
+	session, err := e.uploads.storage.Load(opt.SessionID)
+	if err != nil {
+		return session, errorx.Wrap(err, "failed to load upload session")
+	}
+	return session, nil
+}
+
+// checkSessionKey checks if the master key is rotated since the session was initiated,
+// parts in chunked format are encrypted as one stream, and can not be joined if encrypted with different keys
+func (e *Engine) checkSessionKey(session upload.Session) error {
+	if session.CipherFormat == encryptor.FormatChunkedGCM && session.KeyID != e.encryptor.CurrentKeyID() {
+		return errorx.New(errorx.ErrCodeParam, "master key rotated since the upload session was initiated, upload the file again")
+	}
+	return nil
+}
+
+// isCompleting checks if an upload session is being completed
+func (e *Engine) isCompleting(sessionID string) bool {
+	e.uploads.lock.Lock()
+	defer e.uploads.lock.Unlock()
+	_, exist := e.uploads.completing[sessionID]
+	return exist
+}
+
+// savePart saves a part into the upload session, returns the part replaced if it was uploaded before.
+// Ciphertext of slices is saved as well for pairing based challenge.
+func (e *Engine) savePart(sessionID string, part upload.Part, ws writtenSlices) (*upload.Part, error) {
+	e.uploads.lock.Lock()
+	defer e.uploads.lock.Unlock()
+
+	if _, exist := e.uploads.completing[sessionID]; exist {
+		return nil, errorx.New(errorx.ErrCodeAlreadyExists, "file is being published")
+	}
+	// the session may be aborted while uploading the part
+	session, err := e.uploads.storage.Load(sessionID)
+	if err != nil {
+		return nil, errorx.Wrap(err, "failed to load upload session")
+	}
+	if ws.cipherTexts != nil {
+		slices := make([]encryptor.EncryptedSlice, 0, len(ws.encSlices))
+		for _, es := range ws.encSlices {
+			cipherText, err := ws.cipherTexts.Load(es.EncryptedSliceMeta)
+			if err != nil {
+				return nil, err
+			}
+			es.CipherText = cipherText
+			slices = append(slices, es)
+		}
+		if err := e.uploads.storage.SaveCipherText(sessionID, part.Number, slices); err != nil {
+			return nil, errorx.Wrap(err, "failed to save ciphertext of part")
+		}
+	}
+
+	var former *upload.Part
+	if p, exist := session.Parts[part.Number]; exist {
+		former = &p
+	}
+	session.Parts[part.Number] = part
+	session.UpdateTime = time.Now().UnixNano()
+	if err := e.uploads.storage.Save(session); err != nil {
+		return nil, errorx.Wrap(err, "failed to save upload session")
+	}
+	return former, nil
+}
+
+// joinParts joins slices of all parts of an upload session in order, as if the file is written at once.
+// Stripes of erasure coded file are renumbered, and ciphertext of slices is loaded part by part
+// when required by pairing based challenge.
+func (e *Engine) joinParts(session upload.Session) (writtenSlices, error) {
+	var ws writtenSlices
+	var cipherTexts *partCipherTexts
+	if ca, _ := e.challenger.GetChallengeConf(); ca == types.PairingChallengeAlgorithm {
+		cipherTexts = &partCipherTexts{
+			storage:   e.uploads.storage,
+			sessionID: session.ID,
+			parts:     make(map[string]int),
+		}
+		ws.cipherTexts = cipherTexts
+	}
+	stripeBase := 0
+	refs := make(map[string]struct{})
+	for n := 1; n <= session.PartCount(); n++ {
+		part := session.Parts[n]
+		ws.metas = append(ws.metas, part.Slices...)
+
+		stripes := 0
+		for _, s := range part.Shards {
+			if s.Stripe+1 > stripes {
+				stripes = s.Stripe + 1
+			}
+			s.Stripe += stripeBase
+			ws.shards = append(ws.shards, s)
+		}
+		stripeBase += stripes
+
+		// slices referenced by several parts are recorded once
+		for _, r := range part.Refs {
+			key := r.ID + string(r.NodeID)
+			if _, exist := refs[key]; !exist {
+				refs[key] = struct{}{}
+				ws.refs = append(ws.refs, r)
+			}
+		}
+
+		for _, p := range part.Pushed {
+			es := encryptor.EncryptedSlice{EncryptedSliceMeta: p.EncryptedSliceMeta}
+			if cipherTexts != nil {
+				cipherTexts.parts[p.SliceID+string(p.NodeID)] = n
+			}
+			ws.encSlices = append(ws.encSlices, es)
+			ws.storIndexes = append(ws.storIndexes, p.StorIndex)
+		}
+		ws.materials = append(ws.materials, part.Materials...)
+	}
+	return ws, nil
+}
+
+// deleteSessionSlices deletes slices of all parts of an upload session from storage nodes
+func (e *Engine) deleteSessionSlices(ctx context.Context, session upload.Session) {
+	for _, part := range session.Parts {
+		e.deleteSlices(ctx, part.Pushed)
+	}
+}
+
+// deleteSlices deletes slices pushed from storage nodes, failures are only logged,
+// as slices are left to be cleaned by storage nodes if they are never published
+func (e *Engine) deleteSlices(ctx context.Context, slices []upload.PushedSlice) {
+	if len(slices) == 0 {
+		return
+	}
+	nodes, err := e.chain.ListNodes()
+	if err != nil {
+		logger.WithError(err).Warn("failed to list nodes to delete slices")
+		return
+	}
+	nodesMap := common.ToNodesMap(nodes)
+	for _, s := range slices {
+		node, exist := nodesMap[string(s.NodeID)]
+		if !exist {
+			continue
+		}
+		if err := e.copier.Delete(ctx, s.SliceID, s.StorIndex, &node); err != nil {
+			logger.WithFields(logrus.Fields{
+				"slice_id":    s.SliceID,
+				"target_node": string(s.NodeID),
+			}).WithError(err).Warn("failed to delete slice")
+		}
+	}
+}
+
+// toPushedSlices returns replicas of slices pushed to storage nodes
+func toPushedSlices(ws writtenSlices) []upload.PushedSlice {
+	pushed := make([]upload.PushedSlice, 0, len(ws.encSlices))
+	for i, es := range ws.encSlices {
+		pushed = append(pushed, upload.PushedSlice{
+			EncryptedSliceMeta: es.EncryptedSliceMeta,
+			StorIndex:          ws.storIndexes[i],
+		})
+	}
+	return pushed
+}
+
+func toUploadSession(s upload.Session) types.UploadSession {
+	session := types.UploadSession{
+		SessionID:  s.ID,
+		FileID:     s.FileID,
+		Namespace:  s.Namespace,
+		FileName:   s.FileName,
+		FileLength: s.FileLength,
+		PartSize:   s.PartSize,
+		PartCount:  s.PartCount(),
+		ExpireTime: s.ExpireTime,
+		CreateTime: s.CreateTime,
+		Parts:      []types.UploadedPart{},
+	}
+	for n := 1; n <= s.PartCount(); n++ {
+		if part, exist := s.Parts[n]; exist {
+			session.Parts = append(session.Parts, toUploadedPart(part))
+		}
+	}
+	return session
+}
+
+func toUploadedPart(p upload.Part) types.UploadedPart {
+	return types.UploadedPart{
+		Number:     p.Number,
+		Length:     p.Length,
+		PlainHash:  p.PlainHash,
+		UploadTime: p.UploadTime,
+	}
+}
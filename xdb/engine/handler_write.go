@@ -17,6 +17,7 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"strconv"
 	"sync"
 	"time"
 
@@ -28,6 +29,7 @@ import (
 	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
 	ctype "github.com/PaddlePaddle/PaddleDTX/xdb/engine/challenger/merkle/types"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/common"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/compressor"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/copier"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/slicer"
@@ -57,19 +59,14 @@ type finishWrittenSlice struct {
 
 // Write upload a file and push file slices to storage nodes
 // The detailed steps are as follows:
-// 1. check parameters, the file is not allowed upload repeatedly
-// 2. first file encryption
-// 3. divide the file into multiple slices by a fixed size and generate copies
+// 1. check parameters, a file uploaded with an existing name becomes the next version of it
+// 2. compress the file if required by the request or namespace, then first file encryption
+// 3. divide the file into multiple slices and generate copies, slices already stored are referenced if deduplicated
 // 4. second encryption of ciphertext slices
 // 5. push slices into storage nodes, retry five times if push failed
 // 6. store file's digest info into blockchain
 func (e *Engine) Write(ctx context.Context, opt types.WriteOptions,
 	r io.Reader) (resp types.WriteResponse, err error) {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	var errOccurred error
-
 	// check key match
 	if err := e.verifyUserID(opt.User); err != nil {
 		return resp, err
@@ -86,28 +83,23 @@ func (e *Engine) Write(ctx context.Context, opt types.WriteOptions,
 	pubkey := ecdsa.PublicKeyFromPrivateKey(e.monitor.challengingMonitor.PrivateKey)
 	opt.User = pubkey.String()
 
-	// duplicate check
-	if _, err := e.chain.GetFileByName(pubkey[:], opt.Namespace, opt.FileName); err == nil {
-		return resp, errorx.New(errorx.ErrCodeAlreadyExists, "duplicated name")
-	} else if !errorx.Is(err, errorx.ErrCodeNotFound) {
-		return resp, errorx.Wrap(err, "failed to read blockchain")
-	}
 	ns, err := e.chain.GetNsByName(pubkey[:], opt.Namespace)
 	if err != nil {
 		return resp, errorx.Wrap(err, "failed to get ns from blockchain")
 	}
+	if opt.ExpireTime == 0 {
+		if opt.ExpireTime, err = defaultExpireTime(ns); err != nil {
+			return resp, err
+		}
+	}
 	fileID, err := uuid.NewRandom()
 	if err != nil {
 		return resp, errorx.Internal(err, "failed to get uuid")
 	}
-	nodes, err := common.GetHealthNodes(e.chain)
+	nodes, err := e.getWriteNodes(ns)
 	if err != nil {
 		return resp, err
 	}
-	if len(nodes) < ns.Replica {
-		return resp, errorx.Internal(err, "available healthy nodes smaller than replica")
-	}
-	nodesMap := common.ToNodeHsMap(nodes)
 
 	logger.WithFields(logrus.Fields{
 		"file_id":       fileID.String(),
@@ -117,59 +109,281 @@ func (e *Engine) Write(ctx context.Context, opt types.WriteOptions,
 		"exp_time":      time.Unix(0, opt.ExpireTime).Format("2006-01-02 15:04:05"),
 	}).Info("write file")
 
-	// encrypt file first
-	cipher, err := e.encryptor.Encrypt(r, &encryptor.EncryptOptions{FileID: fileID.String()})
+	// encrypt file first, the file is encrypted chunk by chunk while being sliced,
+	// so that it is never held in memory as a whole.
+	// If the namespace is deduplicated, plaintext is sliced and each slice is encrypted by itself instead
+	cipherFormat := e.cipherFormat(ns)
+	var index *dedupIndex
+	if cipherFormat == encryptor.FormatConvergentGCM {
+		if index, err = e.newDedupIndex(pubkey[:], opt.Namespace, opt.ExpireTime); err != nil {
+			return resp, err
+		}
+	}
+	plainReader := &countingReader{r: r}
+	r = plainReader
+	content := fileContent{cipherFormat: cipherFormat}
+	compressedReader := plainReader
+	if content.compression = e.compression(ns, opt.Compression, cipherFormat); content.compression != "" {
+		cr, err := compressor.Compress(content.compression, plainReader)
+		if err != nil {
+			return resp, err
+		}
+		defer cr.Close()
+		compressedReader = &countingReader{r: cr}
+	}
+	if cipherFormat == encryptor.FormatChunkedGCM {
+		r = e.encryptor.EncryptStream(compressedReader, &encryptor.EncryptOptions{FileID: fileID.String()})
+	}
+
+	ws, err := e.writeSlices(ctx, writeSlicesOptions{
+		fileID:       fileID.String(),
+		owner:        opt.User,
+		expireTime:   opt.ExpireTime,
+		ns:           ns,
+		nodes:        nodes,
+		cipherFormat: cipherFormat,
+		index:        index,
+	}, r)
+	defer ws.release()
+	if err != nil {
+		return resp, errorx.Wrap(err, "error occurred in writing")
+	}
+
+	// Write meta info to blockchain
+	content.length = plainReader.n
+	if content.compression != "" {
+		content.compressedLength = compressedReader.n
+	}
+	file, err := e.publishFile(ctx, fileID.String(), opt, ns, content, ws)
 	if err != nil {
-		logger.WithError(err).Error("file encryption failed")
-		return resp, errorx.NewCode(err, errorx.ErrCodeCrypto, "file encryption failed")
+		return resp, err
 	}
-	r = bytes.NewReader(cipher.CipherText)
-	originalLen := len(cipher.CipherText) - 16
+
+	logger.WithFields(logrus.Fields{
+		"file_id":           fileID.String(),
+		"version":           file.Version,
+		"compression":       content.compression,
+		"length":            content.length,
+		"compressed_length": content.compressedLength,
+	}).Debug("file uploaded")
+	resp.FileID = fileID.String()
+	resp.Version = file.Version
+	return resp, nil
+}
+
+// getWriteNodes returns healthy storage nodes to write files of the namespace,
+// there must be enough nodes to hold replicas of a slice or shards of a stripe on distinct nodes
+func (e *Engine) getWriteNodes(ns blockchain.Namespace) (blockchain.NodeHs, error) {
+	nodes, err := common.GetHealthNodes(e.chain)
+	if err != nil {
+		return nil, err
+	}
+	if ns.ErasureCoded() {
+		if len(nodes) < ns.DataShards+ns.ParityShards {
+			return nil, errorx.New(errorx.ErrCodeInternal, "available healthy nodes smaller than shards")
+		}
+	} else if len(nodes) < ns.Replica {
+		return nil, errorx.New(errorx.ErrCodeInternal, "available healthy nodes smaller than replica")
+	}
+	return nodes, nil
+}
+
+// cipherFormat returns the format to encrypt files of the namespace in
+func (e *Engine) cipherFormat(ns blockchain.Namespace) string {
+	if e.deduplicated(ns) {
+		return encryptor.FormatConvergentGCM
+	}
+	return encryptor.FormatChunkedGCM
+}
+
+// compression returns the algorithm to compress a file with, requested is the one in write options
+// which overrides the compression of namespace. Empty is returned if the file is not compressed.
+// Files in convergent format are never compressed, as compression makes slices of similar files differ
+func (e *Engine) compression(ns blockchain.Namespace, requested, cipherFormat string) string {
+	compression := requested
+	if compression == "" {
+		compression = ns.Compression
+	}
+	if !compressor.Enabled(compression) || cipherFormat != encryptor.FormatChunkedGCM {
+		return ""
+	}
+	return compression
+}
+
+// fileContent describes how content of a file is stored
+type fileContent struct {
+	cipherFormat     string
+	compression      string
+	length           uint64                 // plaintext length
+	compressedLength uint64                 // plaintext length after compression
+	salts            *encryptor.StreamSalts // salts of parts, for files in chunked format uploaded in parts
+}
+
+// writeSlicesOptions are parameters of writeSlices
+type writeSlicesOptions struct {
+	fileID       string
+	owner        string // public key of file owner, the source of slices pushed
+	expireTime   int64
+	ns           blockchain.Namespace
+	nodes        blockchain.NodeHs
+	cipherFormat string
+	index        *dedupIndex // slices to reference, for files in convergent format
+
+	// if greater than 0, slices are cut to hold exactly length bytes of content in total,
+	// so that slices of parts of a file can be joined without zero padding in between
+	length uint64
+}
+
+// writtenSlices are slices of a file, or of a part of a file, pushed to storage nodes
+type writtenSlices struct {
+	metas       []slicer.SliceMeta           // slices in order, referenced ones included
+	shards      blockchain.FileStructure     // private meta of data and parity shards of erasure coded file
+	refs        []blockchain.PublicSliceMeta // replicas of slices referenced from other files
+	encSlices   []encryptor.EncryptedSlice   // ciphertext is released once slices are pushed
+	storIndexes []string
+	materials   []ctype.Material // merkle challenge materials
+	cipherTexts cipherTextSource // ciphertext of slices pushed, only for pairing based challenge
+}
+
+// release releases ciphertext of slices kept for pairing based challenge
+func (ws writtenSlices) release() {
+	if ws.cipherTexts != nil {
+		if err := ws.cipherTexts.Close(); err != nil {
+			logger.WithError(err).Warn("failed to release ciphertext of slices")
+		}
+	}
+}
+
+// add records a slice pushed, ciphertext of the slice is moved to ws.cipherTexts if it is kept
+func (ws *writtenSlices) add(m finishWrittenSlice) error {
+	if spill, ok := ws.cipherTexts.(*cipherSpill); ok {
+		if err := spill.Put(m.eSlice); err != nil {
+			return err
+		}
+	}
+	m.eSlice.CipherText = nil
+	ws.encSlices = append(ws.encSlices, m.eSlice)
+	ws.storIndexes = append(ws.storIndexes, m.storIndex)
+	return nil
+}
+
+// writeSlices cuts content read from r into slices, and pushes them to storage nodes.
+// r reads ciphertext of the file, or plaintext if the file is in convergent format.
+// Callers should release slices returned once they are published or cleaned up.
+func (e *Engine) writeSlices(ctx context.Context, opt writeSlicesOptions, r io.Reader) (writtenSlices, error) {
+	var ws writtenSlices
+	// get challenge config, ciphertext of slices pushed is spilled to a temporary file for pairing based challenge
+	ca, _ := e.challenger.GetChallengeConf()
+	if ca == types.PairingChallengeAlgorithm {
+		spill, err := newCipherSpill()
+		if err != nil {
+			return ws, err
+		}
+		ws.cipherTexts = spill
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var errOccurred error
+	nodesMap := common.ToNodeHsMap(opt.nodes)
 
 	// Slice. sliceQueue will be closed when slicer get EOF
 	sliceOpts := slicer.SliceOptions{}
 	sliceQueue := e.slicer.Slice(ctx, r, &sliceOpts, func(err error) {
 		logger.WithError(err).Error("slicing stopped")
+		errOccurred = err
 		cancel()
 	})
+	if opt.length > 0 {
+		sliceQueue = trimSlices(ctx, sliceQueue, opt.length)
+	}
+
+	// Deduplicate. slices already stored in the namespace are referenced rather than pushed again,
+	// only new slices are sent to the following routines
+	metaWg := sync.WaitGroup{}
+	var deduped dedupResult
+	if opt.cipherFormat == encryptor.FormatConvergentGCM {
+		secret, err := e.convergentSecret(opt.ns)
+		if err != nil {
+			return ws, err
+		}
+		newSliceQueue := make(chan slicer.Slice, 10)
+		metaWg.Add(1)
+		go func() {
+			defer metaWg.Done()
+			e.dedupRoutine(ctx, opt.index, secret, sliceQueue, newSliceQueue, &deduped, func(err error) {
+				logger.WithError(err).Error("slice deduplication stopped")
+				errOccurred = err
+				cancel()
+			})
+		}()
+		sliceQueue = newSliceQueue
+	}
 
 	// Find nodes for slices.
 	// Both sliceMetaQueue and locatedSliceQueue will be closed when sliceQueue is closed
 	sliceMetaQueue := make(chan slicer.SliceMeta, 10)
 	locatedSliceQueue := make(chan copier.LocatedSlice, defaultLocatorAmount*2)
-	go e.locateRoutine(ctx, ns.Replica, nodes, sliceQueue, locatedSliceQueue, sliceMetaQueue, func(err error) {
+	onLocateErr := func(err error) {
 		logger.WithError(err).Error("slice location stopped")
 		errOccurred = err
 		cancel()
-	})
-	var sliceMetas []slicer.SliceMeta
+	}
+	// for erasure coded namespace, shardQueue receives private meta of data and parity shards
+	shardQueue := make(chan blockchain.PrivateSliceMeta, 10)
+	if opt.ns.ErasureCoded() {
+		go e.locateStripeRoutine(ctx, opt.ns, opt.nodes, sliceQueue, locatedSliceQueue, sliceMetaQueue, shardQueue, onLocateErr)
+	} else {
+		close(shardQueue)
+		go e.locateRoutine(ctx, opt.ns.Replica, opt.ns.Placement, opt.nodes, sliceQueue, locatedSliceQueue, sliceMetaQueue,
+			onLocateErr)
+	}
+	metaWg.Add(2)
 	go func() {
+		defer metaWg.Done()
 		for s := range sliceMetaQueue {
-			sliceMetas = append(sliceMetas, s)
+			ws.metas = append(ws.metas, s)
+		}
+	}()
+	go func() {
+		defer metaWg.Done()
+		for s := range shardQueue {
+			ws.shards = append(ws.shards, s)
 		}
 	}()
 
 	// Encrypt. encryptedSliceQueue will be closed when locatedSliceQueue is closed
 	encryptedSliceQueue := make(chan encryptor.EncryptedSlice, 10)
-	go e.encryptRoutine(ctx, fileID.String(), locatedSliceQueue, encryptedSliceQueue, func(err error) {
+	go e.encryptRoutine(ctx, opt.fileID, locatedSliceQueue, encryptedSliceQueue, func(err error) {
 		logger.WithError(err).Error("slice encryption stopped")
 		errOccurred = err
 		cancel()
 	})
 
-	// get challenge config
-	ca, pairingConf := e.challenger.GetChallengeConf()
-
 	// Setup challenging materials && Distribute
 	// both finishedQueue and failedQueue will be closed when encryptedSliceQueue is closed
 	finishedQueue := make(chan finishWrittenSlice, 10)
 	failedQueue := make(chan encryptor.EncryptedSlice, 10)
-	go e.distributeRoutine(ctx, nodesMap, encryptedSliceQueue, finishedQueue, failedQueue, opt.User)
-	var finishedEncSlices []encryptor.EncryptedSlice
-	var storIndexes []string
+	go e.distributeRoutine(ctx, nodesMap, encryptedSliceQueue, finishedQueue, failedQueue, opt.owner, opt.fileID)
+	// merkle challenge materials are generated as soon as slices are pushed,
+	// and ciphertext of pushed slices is released, see writtenSlices.add
+	addFinished := func(m finishWrittenSlice) {
+		if ca == types.MerkleChallengeAlgorithm {
+			material, err := e.generateMerkle(m.eSlice, opt.fileID, opt.expireTime)
+			if err != nil {
+				errOccurred = err
+				cancel()
+			}
+			ws.materials = append(ws.materials, material)
+		}
+		if err := ws.add(m); err != nil {
+			errOccurred = err
+			cancel()
+		}
+	}
 	for m := range finishedQueue {
-		finishedEncSlices = append(finishedEncSlices, m.eSlice)
-		storIndexes = append(storIndexes, m.storIndex)
+		addFinished(m)
 	}
 
 	// retry push
@@ -179,53 +393,76 @@ func (e *Engine) Write(ctx context.Context, opt types.WriteOptions,
 	}
 	finishedQueue2 := make(chan finishWrittenSlice, 10)
 	failedQueue2 := make(chan encryptor.EncryptedSlice, 10)
-	e.retryRoutine(ctx, failedSlices, finishedQueue2, failedQueue2, nodesMap, opt.User)
+	e.retryRoutine(ctx, failedSlices, finishedQueue2, failedQueue2, nodesMap, opt.owner, opt.fileID)
 	for m := range finishedQueue2 {
-		finishedEncSlices = append(finishedEncSlices, m.eSlice)
-		storIndexes = append(storIndexes, m.storIndex)
+		addFinished(m)
 	}
 	var failedTwice []encryptor.EncryptedSlice
 	for m := range failedQueue2 {
 		failedTwice = append(failedTwice, m)
 	}
+	metaWg.Wait()
 
 	// if push fails again, push to another node
-	finishedQueue3 := e.pushToOtherNode(ctx, opt.User, fileID.String(),
-		failedTwice, finishedEncSlices, nodes, func(err error) {
+	finishedQueue3 := e.pushToOtherNode(ctx, opt.owner, opt.fileID,
+		failedTwice, ws.encSlices, ws.shards, opt.nodes, opt.ns.Placement, func(err error) {
 			logger.WithError(err).Error("pushToOtherNode failed")
 			errOccurred = err
 			cancel()
 		})
 
-	// check writing error
-	if errOccurred != nil {
-		return resp, errorx.Wrap(errOccurred, "error occurred in writing")
-	}
-
 	// all pushed slice info
 	for _, m := range finishedQueue3 {
-		finishedEncSlices = append(finishedEncSlices, m.eSlice)
-		storIndexes = append(storIndexes, m.storIndex)
+		addFinished(m)
+	}
+
+	// the structure of deduplicated file contains referenced slices as well
+	if opt.cipherFormat == encryptor.FormatConvergentGCM {
+		ws.metas = deduped.metas
+		ws.refs = deduped.refs
 	}
 
-	// generate and save merkle challenge material for each slice and storage node
+	// check writing error, slices pushed are returned so that they can be cleaned up
+	return ws, errOccurred
+}
+
+// publishFile packs slices written into a file and publishes it on blockchain,
+// challenge materials of the slices are saved or pushed to storage nodes before publishing.
+// opt.User is the public key of file owner.
+// The file published is returned, with the version assigned on chain
+func (e *Engine) publishFile(ctx context.Context, fileID string, opt types.WriteOptions, ns blockchain.Namespace,
+	content fileContent, ws writtenSlices) (blockchain.File, error) {
+	ca, pairingConf := e.challenger.GetChallengeConf()
+
+	// save merkle challenge material for each slice and storage node
 	if ca == types.MerkleChallengeAlgorithm {
-		if err := e.generateAndSaveMerkle(finishedEncSlices, fileID.String(), opt.ExpireTime); err != nil {
-			return resp, err
+		if err := common.SaveMerkleChallenger(e.challenger, ws.materials); err != nil {
+			return blockchain.File{}, err
 		}
 	}
 
-	// Write meta info to blockchain
-	chainFile, err := e.packChainFile(fileID.String(), ca, opt, sliceMetas, originalLen, finishedEncSlices, storIndexes, pairingConf)
+	// slices are rearranged by packChainFile, copies are passed so that ws is kept in order
+	encSlices := append([]encryptor.EncryptedSlice(nil), ws.encSlices...)
+	storIndexes := append([]string(nil), ws.storIndexes...)
+	chainFile, err := e.packChainFile(fileID, ca, opt, ws.metas, ws.shards, int(content.length), encSlices, storIndexes, pairingConf)
 	if err != nil {
-		return resp, errorx.Wrap(err, "failed to pack chain file")
+		return blockchain.File{}, errorx.Wrap(err, "failed to pack chain file")
+	}
+	chainFile.Slices = append(chainFile.Slices, ws.refs...)
+	chainFile.DataShards = ns.DataShards
+	chainFile.ParityShards = ns.ParityShards
+	chainFile.CipherFormat = content.cipherFormat
+	chainFile.Compression = content.compression
+	chainFile.CompressedLength = content.compressedLength
+	if content.salts != nil {
+		chainFile.PartSize = content.salts.PartSize
+		chainFile.PartSalts = content.salts.Salts
 	}
 	// generate and push pairing based challenge material for each slice and storage node
 	// slice index is required in calculation, which is obtained after packChainFile
 	if ca == types.PairingChallengeAlgorithm {
-		if err := common.AddSlicesNewPairingChallenge(ctx, pairingConf, e.copier, finishedEncSlices, chainFile, e.chain, opt.User,
-			e.monitor.challengingMonitor.RequestInterval.Nanoseconds(), time.Now().UnixNano(), opt.ExpireTime, nil, logger); err != nil {
-			return resp, err
+		if err := e.addPairingChallenge(ctx, pairingConf, chainFile, opt, ws); err != nil {
+			return blockchain.File{}, err
 		}
 	}
 
@@ -234,26 +471,87 @@ func (e *Engine) Write(ctx context.Context, opt types.WriteOptions,
 		File: chainFile,
 	}
 	// get the message to sign
-	msg, err = util.GetSigMessage(publishFileOpt)
+	msg, err := util.GetSigMessage(publishFileOpt)
 	if err != nil {
-		return resp, errorx.Internal(err, "failed to get the message to sign for upload files")
+		return blockchain.File{}, errorx.Internal(err, "failed to get the message to sign for upload files")
 	}
 	sig, err := ecdsa.Sign(e.monitor.challengingMonitor.PrivateKey, hash.HashUsingSha256([]byte(msg)))
 	if err != nil {
-		return resp, errorx.Wrap(err, "failed to sign File")
+		return blockchain.File{}, errorx.Wrap(err, "failed to sign File")
 	}
 	publishFileOpt.Signature = sig[:]
-	if err := e.chain.PublishFile(&publishFileOpt); err != nil {
-		return resp, errorx.Wrap(err, "failed to write file to blockchain")
+	file, err := e.chain.PublishFile(&publishFileOpt)
+	if err != nil {
+		return file, errorx.Wrap(err, "failed to write file to blockchain")
 	}
+	e.indexFile(file)
+	if content.cipherFormat == encryptor.FormatConvergentGCM {
+		e.indexDedupFile(file, ws.metas)
+	}
+	return file, nil
+}
 
-	logger.WithField("file_id", fileID.String()).Debug("file uploaded")
-	resp.FileID = fileID.String()
-	return resp, nil
+// addPairingChallenge generates and pushes pairing based challenge material of slices written,
+// ciphertext of slices is loaded in batches so that only a few of them are held in memory
+func (e *Engine) addPairingChallenge(ctx context.Context, pairingConf types.PairingChallengeConf, chainFile blockchain.File,
+	opt types.WriteOptions, ws writtenSlices) error {
+	if ws.cipherTexts == nil {
+		return errorx.New(errorx.ErrCodeInternal, "ciphertext of slices not kept")
+	}
+	interval := e.monitor.challengingMonitor.RequestInterval.Nanoseconds()
+	start := time.Now().UnixNano()
+	for i := 0; i < len(ws.encSlices); i += pairingBatchSize {
+		end := i + pairingBatchSize
+		if end > len(ws.encSlices) {
+			end = len(ws.encSlices)
+		}
+		batch := make([]encryptor.EncryptedSlice, 0, end-i)
+		for _, es := range ws.encSlices[i:end] {
+			cipherText, err := ws.cipherTexts.Load(es.EncryptedSliceMeta)
+			if err != nil {
+				return err
+			}
+			es.CipherText = cipherText
+			batch = append(batch, es)
+		}
+		if err := common.AddSlicesNewPairingChallenge(ctx, pairingConf, e.copier, batch, chainFile, e.chain, opt.User,
+			interval, start, opt.ExpireTime, nil, logger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trimSlices cuts slices from sliceQueue to hold length bytes in total, zero padding appended
+// by slicer at the end of the last slice is removed, and the slice is digested again
+func trimSlices(ctx context.Context, sliceQueue <-chan slicer.Slice, length uint64) chan slicer.Slice {
+	trimmed := make(chan slicer.Slice, cap(sliceQueue))
+	go func() {
+		defer close(trimmed)
+		var pos uint64
+		for s := range sliceQueue {
+			if pos >= length {
+				continue
+			}
+			if pos+uint64(len(s.Data)) > length {
+				s.Data = s.Data[:length-pos]
+				s.Hash = hash.HashUsingSha256(s.Data)
+				s.Length = uint64(len(s.Data))
+			}
+			pos += uint64(len(s.Data))
+			select {
+			case <-ctx.Done():
+				return
+			case trimmed <- s:
+			}
+		}
+	}()
+	return trimmed
 }
 
 // locateRoutine block current routine, used to select storage nodes for slices
-func (e *Engine) locateRoutine(ctx context.Context, replica int, nodes blockchain.NodeHs, sliceQueue <-chan slicer.Slice,
+func (e *Engine) locateRoutine(ctx context.Context, replica int, placement *blockchain.PlacementPolicy,
+	nodes blockchain.NodeHs, sliceQueue <-chan slicer.Slice,
 	locatedQueue chan<- copier.LocatedSlice, metaQueue chan<- slicer.SliceMeta, onErr func(err error)) {
 	wg := sync.WaitGroup{}
 
@@ -290,7 +588,10 @@ func (e *Engine) locateRoutine(ctx context.Context, replica int, nodes blockchai
 					return
 				}
 
-				locatedSlice, err := e.copier.Select(slice, nodes, &copier.SelectOptions{Replica: uint32(replica)})
+				locatedSlice, err := e.copier.Select(slice, nodes, &copier.SelectOptions{
+					Replica:   uint32(replica),
+					Placement: placement,
+				})
 				if err != nil {
 					onErr(errorx.Wrap(err, "failed to select nodes for slice %x", slice.Hash))
 					return
@@ -376,7 +677,7 @@ func (e *Engine) encryptRoutine(ctx context.Context, fileID string, locatedQueue
 // distributeRoutine push slices to storage nodes
 func (e *Engine) distributeRoutine(ctx context.Context, nodes map[string]blockchain.Node,
 	encryptedQueue <-chan encryptor.EncryptedSlice, finishedQueue chan<- finishWrittenSlice,
-	failedQueue chan<- encryptor.EncryptedSlice, owner string) {
+	failedQueue chan<- encryptor.EncryptedSlice, owner, fileID string) {
 	wg := sync.WaitGroup{}
 
 	wg.Add(defaultDistributorAmount)
@@ -398,7 +699,7 @@ func (e *Engine) distributeRoutine(ctx context.Context, nodes map[string]blockch
 				// push slice
 				node := nodes[string(es.NodeID)]
 				dataReader := bytes.NewReader(es.CipherText)
-				sIdx, err := e.copier.Push(ctx, es.SliceID, owner, dataReader, &node)
+				sIdx, err := e.copier.Push(ctx, es.SliceID, owner, fileID, dataReader, &node)
 				if err != nil {
 					logger.WithError(err).Errorf("failed to push to: %v, slice: %s", node, es.SliceID)
 					failedQueue <- es
@@ -425,7 +726,7 @@ func (e *Engine) distributeRoutine(ctx context.Context, nodes map[string]blockch
 // retryRoutine re-push failed slice
 func (e *Engine) retryRoutine(ctx context.Context, failedSlices []encryptor.EncryptedSlice,
 	finishedQueue chan<- finishWrittenSlice, failedQueue chan<- encryptor.EncryptedSlice,
-	nodes map[string]blockchain.Node, owner string) {
+	nodes map[string]blockchain.Node, owner, fileID string) {
 
 	wg := sync.WaitGroup{}
 	wg.Add(len(failedSlices))
@@ -443,7 +744,7 @@ func (e *Engine) retryRoutine(ctx context.Context, failedSlices []encryptor.Encr
 			for time.Now().Unix() < endTime {
 				select {
 				case <-ticker.C:
-					if sIdx, err := e.copier.Push(ctx, es.SliceID, owner, dataReader, &node); err == nil {
+					if sIdx, err := e.copier.Push(ctx, es.SliceID, owner, fileID, dataReader, &node); err == nil {
 						logger.WithFields(logrus.Fields{
 							"target_node": node.Name,
 							"address":     node.Address,
@@ -472,21 +773,46 @@ func (e *Engine) retryRoutine(ctx context.Context, failedSlices []encryptor.Encr
 	close(failedQueue)
 }
 
-// pushToOtherNode re-push failed slice to another node
+// pushToOtherNode re-push failed slice to another node allowed by the placement policy
+// shards is the private meta of erasure coded file, shards of the same stripe are kept on distinct nodes
 func (e *Engine) pushToOtherNode(ctx context.Context, owner, fileID string, failedSlices []encryptor.EncryptedSlice,
-	finishedEncSlices []encryptor.EncryptedSlice, nodes blockchain.NodeHs, onErr func(error)) []finishWrittenSlice {
+	finishedEncSlices []encryptor.EncryptedSlice, shards blockchain.FileStructure, nodes blockchain.NodeHs,
+	placement *blockchain.PlacementPolicy, onErr func(error)) []finishWrittenSlice {
+
+	stripes := make(map[string]string)
+	for _, s := range shards {
+		stripes[s.SliceID] = strconv.Itoa(s.Stripe)
+	}
+	groupOf := func(sliceID string) string {
+		if stripe, ok := stripes[sliceID]; ok {
+			return stripe
+		}
+		return sliceID
+	}
+
+	failed := make(map[string]struct{})
+	for _, slice := range failedSlices {
+		failed[slice.SliceID+string(slice.NodeID)] = struct{}{}
+	}
 
 	var finishedSlices []finishWrittenSlice
 	alreadySelected := make(map[string][]string)
+	// nodes holding replicas of a slice or shards of a stripe, which failure domains are spread across
+	holders := make(map[string][]string)
 	for _, slice := range finishedEncSlices {
-		alreadySelected[slice.SliceID] = append(alreadySelected[slice.SliceID], string(slice.NodeID))
+		group := groupOf(slice.SliceID)
+		alreadySelected[group] = append(alreadySelected[group], string(slice.NodeID))
+		if _, exist := failed[slice.SliceID+string(slice.NodeID)]; !exist {
+			holders[group] = append(holders[group], string(slice.NodeID))
+		}
 	}
 
 	for _, slice := range failedSlices {
-		alreadySelected[slice.SliceID] = append(alreadySelected[slice.SliceID], string(slice.NodeID))
+		group := groupOf(slice.SliceID)
+		alreadySelected[group] = append(alreadySelected[group], string(slice.NodeID))
 
 		// select available nodes for failed slice
-		nodeList, err := common.FindNewNodes(nodes, alreadySelected[slice.SliceID])
+		nodeList, err := common.PlaceNewNodes(nodes, alreadySelected[group], holders[group], placement)
 		if err != nil {
 			logger.WithError(err).Errorf("findNewNodes failed for slice: %s", slice.SliceID)
 			onErr(errorx.Wrap(err, "failed to findNewNodes"))
@@ -496,13 +822,14 @@ func (e *Engine) pushToOtherNode(ctx context.Context, owner, fileID string, fail
 		done := false
 		for _, node := range nodeList {
 			logger.Infof("re-push %s to %s", slice.SliceID, node.ID)
-			alreadySelected[slice.SliceID] = append(alreadySelected[slice.SliceID], string(node.ID))
+			alreadySelected[group] = append(alreadySelected[group], string(node.ID))
 
 			// decrypt
 			ropt := encryptor.RecoverOptions{
 				FileID:  fileID,
 				SliceID: slice.SliceID,
 				NodeID:  slice.NodeID,
+				KeyID:   slice.KeyID,
 			}
 			plain, err := e.encryptor.Recover(bytes.NewReader(slice.CipherText), &ropt)
 			if err != nil {
@@ -528,13 +855,14 @@ func (e *Engine) pushToOtherNode(ctx context.Context, owner, fileID string, fail
 			}
 
 			// push to new node
-			if sIdx, err := e.copier.Push(ctx, es.SliceID, owner, bytes.NewReader(es.CipherText), &node); err == nil {
+			if sIdx, err := e.copier.Push(ctx, es.SliceID, owner, fileID, bytes.NewReader(es.CipherText), &node); err == nil {
 				logger.WithFields(logrus.Fields{
 					"slice_id":    es.SliceID,
 					"target_node": string(node.ID),
 				}).Debug("slice re-pushed")
 
 				finishedSlices = append(finishedSlices, finishWrittenSlice{eSlice: es, storIndex: sIdx})
+				holders[group] = append(holders[group], string(node.ID))
 				done = true
 				break
 			} else {
@@ -554,35 +882,25 @@ func (e *Engine) pushToOtherNode(ctx context.Context, owner, fileID string, fail
 	return finishedSlices
 }
 
-// generateAndSaveMerkle file owner generates and saves merkle challenges to local
-func (e *Engine) generateAndSaveMerkle(finishedEncSlices []encryptor.EncryptedSlice,
-	fileID string, expireTime int64) error {
-	var isSaveErr error
-	wg := sync.WaitGroup{}
-	timeInterval := e.monitor.challengingMonitor.RequestInterval.Nanoseconds()
-	for _, es := range finishedEncSlices {
-		wg.Add(1)
-		go func(wg *sync.WaitGroup, es encryptor.EncryptedSlice) {
-			defer wg.Done()
-			sliceMaterial, _ := common.GetMCRange(e.challenger, fileID, es, expireTime, time.Now().UnixNano(), timeInterval)
-			if len(sliceMaterial.Ranges) == 0 {
-				logger.WithFields(logrus.Fields{
-					"file_id":     sliceMaterial.FileID,
-					"slice_id":    sliceMaterial.SliceID,
-					"target_node": sliceMaterial.NodeID,
-				}).Warnf("empty hashes")
-				isSaveErr = errorx.New(errorx.ErrCodeInternal, "failed get challenging merkle materials, ranges empty")
-				return
-			}
-			csMaterial := []ctype.Material{sliceMaterial}
-			if err := common.SaveMerkleChallenger(e.challenger, csMaterial); err != nil {
-				isSaveErr = errorx.Wrap(err, "failed save challenging merkle materials")
-			}
-		}(&wg, es)
+// defaultExpireTime returns the expire time of a file uploaded without it by the default TTL of namespace
+func defaultExpireTime(ns blockchain.Namespace) (int64, error) {
+	if ns.Lifecycle == nil || ns.Lifecycle.DefaultTTL == 0 {
+		return 0, errorx.New(errorx.ErrCodeParam, "invalid file expire time, no default TTL of namespace")
 	}
-	wg.Wait()
-	if isSaveErr != nil {
-		return isSaveErr
+	return time.Now().UnixNano() + ns.Lifecycle.DefaultTTL, nil
+}
+
+// generateMerkle file owner generates merkle challenge material for a pushed slice
+func (e *Engine) generateMerkle(es encryptor.EncryptedSlice, fileID string, expireTime int64) (ctype.Material, error) {
+	timeInterval := e.monitor.challengingMonitor.RequestInterval.Nanoseconds()
+	sliceMaterial, _ := common.GetMCRange(e.challenger, fileID, es, expireTime, time.Now().UnixNano(), timeInterval)
+	if len(sliceMaterial.Ranges) == 0 {
+		logger.WithFields(logrus.Fields{
+			"file_id":     sliceMaterial.FileID,
+			"slice_id":    sliceMaterial.SliceID,
+			"target_node": sliceMaterial.NodeID,
+		}).Warnf("empty hashes")
+		return sliceMaterial, errorx.New(errorx.ErrCodeInternal, "failed get challenging merkle materials, ranges empty")
 	}
-	return nil
+	return sliceMaterial, nil
 }
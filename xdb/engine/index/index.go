@@ -0,0 +1,319 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/kvindex"
+)
+
+const (
+	dbName = "indexDB"
+
+	kindFile      = "file"
+	kindChallenge = "challenge"
+)
+
+// define orders of files searched
+const (
+	SortTime   = "time"   // by publish time
+	SortName   = "name"   // by file name, case-insensitive
+	SortSize   = "size"   // by plaintext length
+	SortExpire = "expire" // by expire time
+)
+
+// FileQuery is the query of files of Owner in the local index.
+//  Namespace is empty to search all namespaces of Owner.
+//  Keywords are words found in the file name or description, case-insensitive.
+//  Ext are values of top-level fields in the json extension of files, values not string are compared in json.
+//  Files are sorted by SortBy, SortTime by default, in descending order unless Asc.
+//  TimeStart and TimeEnd are the period of publish time, ExpireStart and ExpireEnd are the period of expire time,
+//  both are inclusive and zero end is unbounded
+type FileQuery struct {
+	Owner     []byte
+	Namespace string
+	Keywords  string
+	Ext       map[string]string
+	SortBy    string
+	Asc       bool
+
+	TimeStart   int64
+	TimeEnd     int64
+	ExpireStart int64
+	ExpireEnd   int64
+
+	Cursor string
+	Limit  int64
+}
+
+// ChallengeQuery is the query of challenges of files owned by FileOwner in the local index,
+// TargetNode, FileID and Status are optional. Challenges are sorted by challenge time,
+// in descending order unless Asc, TimeStart and TimeEnd are inclusive and zero TimeEnd is unbounded
+type ChallengeQuery struct {
+	FileOwner  []byte
+	TargetNode []byte
+	FileID     string
+	Status     string
+	Asc        bool
+
+	TimeStart int64
+	TimeEnd   int64
+
+	Cursor string
+	Limit  int64
+}
+
+// SyncState is the progress of synchronizing an owner's files and challenges from blockchain.
+//  FilesSynced is the time files were last synced completely, zero if never,
+//  ChallengesSynced is the time challenges were last synced, challenges published before are indexed
+type SyncState struct {
+	FilesSynced      int64 `json:"filesSynced"`
+	ChallengesSynced int64 `json:"challengesSynced"`
+}
+
+// Index indexes files and challenges of dataOwner nodes in levelDB, which are synced from blockchain
+// and sorted by multiple keys, so that they can be searched and paged by cursors without contract queries
+type Index struct {
+	store *kvindex.Store
+}
+
+// New creates an Index by index configuration
+func New(conf *config.DataOwnerIndexConf) (*Index, error) {
+	if len(conf.LeveldbRoot) == 0 {
+		return nil, errorx.New(errorx.ErrCodeConfig, "missing config: leveldbRoot")
+	}
+	if conf.SyncInterval < 0 {
+		return nil, errorx.New(errorx.ErrCodeConfig, "invalid index config, negative syncInterval")
+	}
+	store, err := kvindex.Open(filepath.Join(conf.LeveldbRoot, dbName))
+	if err != nil {
+		return nil, err
+	}
+	return &Index{store: store}, nil
+}
+
+// PutFile adds a file or updates it in the index
+func (i *Index) PutFile(f blockchain.File) error {
+	var keys []kvindex.Key
+	// files are sorted within the namespace and across all namespaces of the owner
+	for _, ns := range []string{f.Namespace, ""} {
+		keys = append(keys,
+			kvindex.Key{Sort: fileSort(f.Owner, ns, SortTime), Value: kvindex.Int(f.PublishTime)},
+			kvindex.Key{Sort: fileSort(f.Owner, ns, SortName), Value: strings.ToLower(f.Name)},
+			kvindex.Key{Sort: fileSort(f.Owner, ns, SortSize), Value: kvindex.Int(int64(f.Length))},
+			kvindex.Key{Sort: fileSort(f.Owner, ns, SortExpire), Value: kvindex.Int(f.ExpireTime)},
+		)
+	}
+	return i.store.Put(kindFile, f.ID, f, keys...)
+}
+
+// DeleteFile removes a file from the index, a file not indexed is skipped
+func (i *Index) DeleteFile(id string) error {
+	return i.store.Delete(kindFile, id)
+}
+
+// Files returns files matched by q and the cursor of the next page, which is empty if no files are left
+func (i *Index) Files(q FileQuery) ([]blockchain.File, string, error) {
+	sortBy := q.SortBy
+	if sortBy == "" {
+		sortBy = SortTime
+	}
+	switch sortBy {
+	case SortTime, SortName, SortSize, SortExpire:
+	default:
+		return nil, "", errorx.New(errorx.ErrCodeParam, "invalid sort: %s", sortBy)
+	}
+	keywords := strings.Fields(strings.ToLower(q.Keywords))
+
+	kq := kvindex.Query{
+		Kind:   kindFile,
+		Sort:   fileSort(q.Owner, q.Namespace, sortBy),
+		Desc:   !q.Asc,
+		Cursor: q.Cursor,
+		Limit:  int(q.Limit),
+	}
+	// time periods bound the iteration if files are sorted by them
+	switch sortBy {
+	case SortTime:
+		kq.Min, kq.Max = bounds(q.TimeStart, q.TimeEnd)
+	case SortExpire:
+		kq.Min, kq.Max = bounds(q.ExpireStart, q.ExpireEnd)
+	}
+	kq.Match = func(value []byte) (bool, error) {
+		var f blockchain.File
+		if err := json.Unmarshal(value, &f); err != nil {
+			return false, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal file")
+		}
+		if !inPeriod(f.PublishTime, q.TimeStart, q.TimeEnd) || !inPeriod(f.ExpireTime, q.ExpireStart, q.ExpireEnd) {
+			return false, nil
+		}
+		return matchKeywords(f, keywords) && matchExt(f.Ext, q.Ext), nil
+	}
+
+	values, next, err := i.store.Query(kq)
+	if err != nil {
+		return nil, "", err
+	}
+	files := make([]blockchain.File, 0, len(values))
+	for _, v := range values {
+		var f blockchain.File
+		if err := json.Unmarshal(v, &f); err != nil {
+			return nil, "", errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal file")
+		}
+		files = append(files, f)
+	}
+	return files, next, nil
+}
+
+// PutChallenge adds a challenge or updates its status in the index
+func (i *Index) PutChallenge(c blockchain.Challenge) error {
+	value := kvindex.Int(c.ChallengeTime)
+	return i.store.Put(kindChallenge, c.ID, c,
+		kvindex.Key{Sort: challengeSort(c.FileOwner, nil), Value: value},
+		kvindex.Key{Sort: challengeSort(c.FileOwner, c.TargetNode), Value: value},
+	)
+}
+
+// Challenges returns challenges matched by q and the cursor of the next page, which is empty if no challenges are left
+func (i *Index) Challenges(q ChallengeQuery) ([]blockchain.Challenge, string, error) {
+	kq := kvindex.Query{
+		Kind:   kindChallenge,
+		Sort:   challengeSort(q.FileOwner, q.TargetNode),
+		Desc:   !q.Asc,
+		Cursor: q.Cursor,
+		Limit:  int(q.Limit),
+	}
+	kq.Min, kq.Max = bounds(q.TimeStart, q.TimeEnd)
+	kq.Match = func(value []byte) (bool, error) {
+		var c blockchain.Challenge
+		if err := json.Unmarshal(value, &c); err != nil {
+			return false, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal challenge")
+		}
+		return (q.FileID == "" || c.FileID == q.FileID) && (q.Status == "" || c.Status == q.Status), nil
+	}
+
+	values, next, err := i.store.Query(kq)
+	if err != nil {
+		return nil, "", err
+	}
+	challenges := make([]blockchain.Challenge, 0, len(values))
+	for _, v := range values {
+		var c blockchain.Challenge
+		if err := json.Unmarshal(v, &c); err != nil {
+			return nil, "", errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal challenge")
+		}
+		challenges = append(challenges, c)
+	}
+	return challenges, next, nil
+}
+
+// SyncState returns the progress of synchronizing the owner's files and challenges
+func (i *Index) SyncState(owner []byte) (SyncState, error) {
+	var s SyncState
+	_, err := i.store.GetMeta(syncStateName(owner), &s)
+	return s, err
+}
+
+// SetSyncState records the progress of synchronizing the owner's files and challenges
+func (i *Index) SetSyncState(owner []byte, s SyncState) error {
+	return i.store.PutMeta(syncStateName(owner), s)
+}
+
+// Close closes levelDB
+func (i *Index) Close() {
+	i.store.Close()
+}
+
+// fileSort returns the name of the sort of files, ns is empty for all namespaces of the owner.
+// Names are hex encoded so that they never contain separators of the store
+func fileSort(owner []byte, ns, sortBy string) string {
+	return fmt.Sprintf("%x/%x/%s", owner, ns, sortBy)
+}
+
+// challengeSort returns the name of the sort of challenges, node is empty for all storage nodes
+func challengeSort(owner, node []byte) string {
+	return fmt.Sprintf("%x/%x", owner, node)
+}
+
+func syncStateName(owner []byte) string {
+	return "sync/" + hex.EncodeToString(owner)
+}
+
+// bounds returns sort values bounding the period, zero end is unbounded
+func bounds(start, end int64) (string, string) {
+	var min, max string
+	if start > 0 {
+		min = kvindex.Int(start)
+	}
+	if end > 0 {
+		max = kvindex.Int(end)
+	}
+	return min, max
+}
+
+func inPeriod(t, start, end int64) bool {
+	return t >= start && (end <= 0 || t <= end)
+}
+
+// matchKeywords checks if all keywords are found in the file name or description
+func matchKeywords(f blockchain.File, keywords []string) bool {
+	if len(keywords) == 0 {
+		return true
+	}
+	text := strings.ToLower(f.Name + "\n" + f.Description)
+	for _, w := range keywords {
+		if !strings.Contains(text, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchExt checks if top-level fields of the json extension have the values,
+// an extension not in json object matches no fields
+func matchExt(ext []byte, fields map[string]string) bool {
+	if len(fields) == 0 {
+		return true
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(ext, &obj); err != nil {
+		return false
+	}
+	for k, v := range fields {
+		raw, ok := obj[k]
+		if !ok {
+			return false
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			if s != v {
+				return false
+			}
+			continue
+		}
+		if !bytes.Equal(bytes.TrimSpace(raw), []byte(v)) {
+			return false
+		}
+	}
+	return true
+}
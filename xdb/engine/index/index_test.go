@@ -0,0 +1,165 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+)
+
+func fileIDs(files []blockchain.File) []string {
+	var ids []string
+	for _, f := range files {
+		ids = append(ids, f.ID)
+	}
+	return ids
+}
+
+func TestFiles(t *testing.T) {
+	root, err := ioutil.TempDir("", "index")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	i, err := New(&config.DataOwnerIndexConf{LeveldbRoot: root})
+	require.NoError(t, err)
+	defer i.Close()
+
+	owner, other := []byte("owner"), []byte("other")
+	files := []blockchain.File{
+		{ID: "f1", Owner: owner, Namespace: "ns1", Name: "Report.csv", Description: "sales of 2021",
+			Length: 300, PublishTime: 1, ExpireTime: 100, Ext: []byte(`{"region":"north","year":2021}`)},
+		{ID: "f2", Owner: owner, Namespace: "ns1", Name: "data.csv", Description: "年度销售报表",
+			Length: 100, PublishTime: 2, ExpireTime: 50, Ext: []byte(`{"region":"south","year":2022}`)},
+		{ID: "f3", Owner: owner, Namespace: "ns2", Name: "model.bin",
+			Length: 200, PublishTime: 3, ExpireTime: 80},
+		{ID: "f4", Owner: other, Namespace: "ns1", Name: "report.csv", PublishTime: 4, ExpireTime: 100},
+	}
+	for _, f := range files {
+		require.NoError(t, i.PutFile(f))
+	}
+
+	// the latest first within the namespace, or across all namespaces
+	list, next, err := i.Files(FileQuery{Owner: owner, Namespace: "ns1"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"f2", "f1"}, fileIDs(list))
+	require.Empty(t, next)
+	list, _, err = i.Files(FileQuery{Owner: owner})
+	require.NoError(t, err)
+	require.Equal(t, []string{"f3", "f2", "f1"}, fileIDs(list))
+
+	// sorts
+	list, _, err = i.Files(FileQuery{Owner: owner, SortBy: SortName, Asc: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"f2", "f3", "f1"}, fileIDs(list))
+	list, _, err = i.Files(FileQuery{Owner: owner, SortBy: SortSize})
+	require.NoError(t, err)
+	require.Equal(t, []string{"f1", "f3", "f2"}, fileIDs(list))
+	_, _, err = i.Files(FileQuery{Owner: owner, SortBy: "color"})
+	require.Error(t, err)
+
+	// pages
+	list, next, err = i.Files(FileQuery{Owner: owner, SortBy: SortExpire, Asc: true, Limit: 2})
+	require.NoError(t, err)
+	require.Equal(t, []string{"f2", "f3"}, fileIDs(list))
+	require.NotEmpty(t, next)
+	list, next, err = i.Files(FileQuery{Owner: owner, SortBy: SortExpire, Asc: true, Limit: 2, Cursor: next})
+	require.NoError(t, err)
+	require.Equal(t, []string{"f1"}, fileIDs(list))
+	require.Empty(t, next)
+
+	// keywords, extension and periods
+	list, _, err = i.Files(FileQuery{Owner: owner, Keywords: "REPORT 2021"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"f1"}, fileIDs(list))
+	list, _, err = i.Files(FileQuery{Owner: owner, Keywords: "销售"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"f2"}, fileIDs(list))
+	list, _, err = i.Files(FileQuery{Owner: owner, Ext: map[string]string{"region": "south", "year": "2022"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"f2"}, fileIDs(list))
+	list, _, err = i.Files(FileQuery{Owner: owner, Ext: map[string]string{"year": "2020"}})
+	require.NoError(t, err)
+	require.Empty(t, list)
+	list, _, err = i.Files(FileQuery{Owner: owner, TimeStart: 2, TimeEnd: 3})
+	require.NoError(t, err)
+	require.Equal(t, []string{"f3", "f2"}, fileIDs(list))
+	list, _, err = i.Files(FileQuery{Owner: owner, SortBy: SortName, ExpireStart: 60})
+	require.NoError(t, err)
+	require.Equal(t, []string{"f1", "f3"}, fileIDs(list))
+
+	// files moved out of the sorts once updated or deleted
+	files[1].ExpireTime = 1000
+	require.NoError(t, i.PutFile(files[1]))
+	list, _, err = i.Files(FileQuery{Owner: owner, SortBy: SortExpire})
+	require.NoError(t, err)
+	require.Equal(t, []string{"f2", "f1", "f3"}, fileIDs(list))
+	require.NoError(t, i.DeleteFile("f1"))
+	list, _, err = i.Files(FileQuery{Owner: owner})
+	require.NoError(t, err)
+	require.Equal(t, []string{"f3", "f2"}, fileIDs(list))
+}
+
+func TestChallenges(t *testing.T) {
+	root, err := ioutil.TempDir("", "index")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	i, err := New(&config.DataOwnerIndexConf{LeveldbRoot: root})
+	require.NoError(t, err)
+	defer i.Close()
+
+	owner := []byte("owner")
+	for _, c := range []blockchain.Challenge{
+		{ID: "c1", FileOwner: owner, TargetNode: []byte("n1"), FileID: "f1", Status: blockchain.ChallengeProved, ChallengeTime: 1},
+		{ID: "c2", FileOwner: owner, TargetNode: []byte("n2"), FileID: "f1", Status: blockchain.ChallengeToProve, ChallengeTime: 2},
+		{ID: "c3", FileOwner: owner, TargetNode: []byte("n1"), FileID: "f2", Status: blockchain.ChallengeToProve, ChallengeTime: 3},
+	} {
+		require.NoError(t, i.PutChallenge(c))
+	}
+
+	ids := func(q ChallengeQuery) []string {
+		q.FileOwner = owner
+		list, _, err := i.Challenges(q)
+		require.NoError(t, err)
+		var ids []string
+		for _, c := range list {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+	require.Equal(t, []string{"c3", "c2", "c1"}, ids(ChallengeQuery{}))
+	require.Equal(t, []string{"c1", "c3"}, ids(ChallengeQuery{TargetNode: []byte("n1"), Asc: true}))
+	require.Equal(t, []string{"c3", "c2"}, ids(ChallengeQuery{Status: blockchain.ChallengeToProve}))
+	require.Equal(t, []string{"c2", "c1"}, ids(ChallengeQuery{FileID: "f1"}))
+	require.Equal(t, []string{"c2"}, ids(ChallengeQuery{TimeStart: 2, TimeEnd: 2}))
+
+	// status updated
+	require.NoError(t, i.PutChallenge(blockchain.Challenge{ID: "c3", FileOwner: owner, TargetNode: []byte("n1"),
+		FileID: "f2", Status: blockchain.ChallengeFailed, ChallengeTime: 3}))
+	require.Equal(t, []string{"c3"}, ids(ChallengeQuery{Status: blockchain.ChallengeFailed}))
+
+	s, err := i.SyncState(owner)
+	require.NoError(t, err)
+	require.Zero(t, s.FilesSynced)
+	require.NoError(t, i.SetSyncState(owner, SyncState{FilesSynced: 1, ChallengesSynced: 2}))
+	s, err = i.SyncState(owner)
+	require.NoError(t, err)
+	require.Equal(t, SyncState{FilesSynced: 1, ChallengesSynced: 2}, s)
+}
@@ -18,6 +18,7 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
+	"io"
 	"math/big"
 	"time"
 
@@ -33,8 +34,25 @@ import (
 // merkle tree root, used to ensure the file is not tampered
 // slices meta, used to pull slices from storage nodes
 // slices structure, ensure the file can be recovered in a correct slice order
+// shards is the private meta of data and parity shards in stripe order, only used for erasure coded files
 func (e *Engine) packChainFile(fileID, challengeAlgorithm string, opt types.WriteOptions, originalSlices slicer.SliceMetas,
-	originalLen int, encryptedSlices []encryptor.EncryptedSlice, storIndexes []string, pairingConf types.PairingChallengeConf) (blockchain.File, error) {
+	shards blockchain.FileStructure, originalLen int, encryptedSlices []encryptor.EncryptedSlice, storIndexes []string,
+	pairingConf types.PairingChallengeConf) (blockchain.File, error) {
+
+	if len(shards) == 0 {
+		for _, s := range originalSlices {
+			shards = append(shards, blockchain.PrivateSliceMeta{
+				SliceID:   s.ID,
+				PlainHash: s.Hash,
+				Length:    s.Length,
+				Key:       s.Key,
+			})
+		}
+	}
+	stripes := make(map[string]int)
+	for _, s := range shards {
+		stripes[s.SliceID] = s.Stripe
+	}
 
 	sliceIdxMap := make(map[string]int)
 	chainSlices := make([]blockchain.PublicSliceMeta, 0, len(originalSlices))
@@ -48,6 +66,8 @@ func (e *Engine) packChainFile(fileID, challengeAlgorithm string, opt types.Writ
 			NodeID:     s.NodeID,
 			CipherHash: s.CipherHash,
 			StorIndex:  randStorIndexes[i],
+			Stripe:     stripes[s.SliceID],
+			KeyID:      s.KeyID,
 		}
 		if challengeAlgorithm == types.PairingChallengeAlgorithm {
 			// denote slice index for each node (for pairing based challenge)
@@ -61,7 +81,7 @@ func (e *Engine) packChainFile(fileID, challengeAlgorithm string, opt types.Writ
 		}
 		chainSlices = append(chainSlices, sps)
 	}
-	structure, err := e.packChainFileStructure(originalSlices, fileID)
+	structure, keyID, err := e.packChainFileStructure(shards, fileID)
 	if err != nil {
 		return blockchain.File{}, errorx.Wrap(err, "failed to pack chain file structure")
 	}
@@ -82,6 +102,7 @@ func (e *Engine) packChainFile(fileID, challengeAlgorithm string, opt types.Writ
 		PublishTime: time.Now().UnixNano(),
 		ExpireTime:  opt.ExpireTime,
 		Ext:         []byte(opt.Extra),
+		KeyID:       keyID,
 	}
 	if challengeAlgorithm == types.PairingChallengeAlgorithm {
 		chainFile.PdpPubkey = pairingConf.Pubkey
@@ -92,34 +113,30 @@ func (e *Engine) packChainFile(fileID, challengeAlgorithm string, opt types.Writ
 	return chainFile, nil
 }
 
-// packChainFileStructure pack file private structure and encrypt it
-func (e *Engine) packChainFileStructure(originalSlices slicer.SliceMetas, fileID string) ([]byte, error) {
-	structure := make(blockchain.FileStructure, 0, len(originalSlices))
-	for _, s := range originalSlices {
-		structure = append(structure, blockchain.PrivateSliceMeta{
-			SliceID:   s.ID,
-			PlainHash: s.Hash,
-		})
-	}
+// packChainFileStructure pack file private structure and encrypt it,
+// returns ID of the master key it is encrypted with as well
+func (e *Engine) packChainFileStructure(structure blockchain.FileStructure, fileID string) ([]byte, string, error) {
 	raw, err := structure.Marshal()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	// encrypt structure
 	encStruct, err := e.encryptor.Encrypt(bytes.NewReader(raw), &encryptor.EncryptOptions{
 		FileID: fileID,
 	})
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return encStruct.CipherText, err
+	return encStruct.CipherText, encStruct.KeyID, err
 }
 
 // recoverChainFileStructure get file structure from blockchain and decrypt it
-func (e *Engine) recoverChainFileStructure(bs []byte, fileID string) (blockchain.FileStructure, error) {
+func (e *Engine) recoverChainFileStructure(f blockchain.File) (blockchain.FileStructure, error) {
 	// decrypt structure
-	decStruct, err := e.encryptor.Recover(bytes.NewReader(bs), &encryptor.RecoverOptions{
-		FileID: fileID,
+	decStruct, err := e.encryptor.Recover(bytes.NewReader(f.Structure), &encryptor.RecoverOptions{
+		FileID:     f.ID,
+		KeyID:      f.KeyID,
+		WrappedKey: f.WrappedKey,
 	})
 	if err != nil {
 		return nil, err
@@ -153,3 +170,15 @@ func rearrangeEncSlices(encryptedSlices []encryptor.EncryptedSlice, storeIndexes
 	}
 	return encryptedSlices, storeIndexes
 }
+
+// countingReader counts bytes read from r
+type countingReader struct {
+	r io.Reader
+	n uint64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += uint64(n)
+	return n, err
+}
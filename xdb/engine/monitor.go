@@ -29,7 +29,8 @@ import (
 //  NodeMaintainer runs if local node is storage-node, and its main work is to clean expired encrypted slices
 //     and to send heartbeats regularly in order to claim it's alive
 //  FileMaintainer runs if local node is dataOwner-node, and its main work is to check storage-nodes health conditions
-//     and migrate slices from bad nodes to healthy nodes.
+//     and migrate slices from bad nodes to healthy nodes, to re-encrypt slices with the current master key,
+//     to rebalance slices from the most utilized nodes to under-utilized ones, and to apply lifecycle policies of namespaces.
 type Monitor struct {
 	challengingMonitor *challenging.ChallengingMonitor
 	nodeMaintainer     *nodemaintainer.NodeMaintainer
@@ -91,7 +92,7 @@ func newChallengingMonitor(conf *config.MonitorConf, opt *NewEngineOption) (
 
 // newNodeMaintainer initiates NodeMaintainer
 // for storage node, nodeMaintainer can register node's address into blockchain,
-// clean expired file's slices and heartbeat
+// clean expired file's slices, scrub slices stored and heartbeat
 func newNodeMaintainer(conf *config.MonitorConf, opt *NewEngineOption) (*nodemaintainer.NodeMaintainer, error) {
 	nodemaintainerSwitch := conf.NodemaintainerSwitch
 	if nodemaintainerSwitch != "on" {
@@ -103,6 +104,11 @@ func newNodeMaintainer(conf *config.MonitorConf, opt *NewEngineOption) (*nodemai
 		LocalNode:    opt.LocalNode,
 		SliceStorage: opt.SliceStor,
 		ProveStorage: opt.ProveStor,
+		Quota:        opt.Quota,
+
+		Scrub:         opt.Scrub,
+		ScrubInterval: opt.ScrubInterval,
+		ScrubRate:     opt.ScrubRate,
 	}
 
 	nodeMaintainer, err := nodemaintainer.New(conf, &mmOpt)
@@ -142,9 +148,14 @@ func (m *Monitor) Start(ctx context.Context) error {
 	case config.NodeTypeDataOwner:
 		// If the dataOwner node's filemaintainerSwitch is enabled,
 		// the node's fileMaintainer will check storage-nodes health conditions and
-		// migrate slices from bad nodes to healthy nodes.
+		// migrate slices from bad nodes to healthy nodes, re-encrypt slices with the current master key,
+		// rebalance slices from the most utilized nodes to under-utilized ones and apply lifecycle policies
+		// of namespaces if enabled.
 		if m.fileMaintainer != nil {
 			m.fileMaintainer.Migrate(ctx)
+			m.fileMaintainer.Rekey(ctx)
+			m.fileMaintainer.Rebalance(ctx)
+			m.fileMaintainer.Lifecycle(ctx)
 		}
 		if m.challengingMonitor != nil {
 			m.challengingMonitor.StartChallengeRequest(ctx)
@@ -152,12 +163,15 @@ func (m *Monitor) Start(ctx context.Context) error {
 
 	case config.NodeTypeStorage:
 		// If the storage node's nodemaintainer is enabled,
-		// the node's automatic registration, heartbeat detection, and expired file cleanup will be enabled.
+		// the node's automatic registration, heartbeat detection, expired file cleanup, slice scrubbing
+		// and setting the node offline once drained will be enabled.
 		if m.nodeMaintainer != nil {
 			if err := m.nodeMaintainer.NodeAutoRegister(); err != nil {
 				return err
 			}
 			m.nodeMaintainer.StartFileClear(ctx)
+			m.nodeMaintainer.StartScrub(ctx)
+			m.nodeMaintainer.StartDrain(ctx)
 			m.nodeMaintainer.HeartBeat(ctx)
 		}
 		if m.challengingMonitor != nil {
@@ -178,10 +192,15 @@ func (m *Monitor) Close() {
 
 	if m.fileMaintainer != nil {
 		m.fileMaintainer.StopMigrate()
+		m.fileMaintainer.StopRekey()
+		m.fileMaintainer.StopRebalance()
+		m.fileMaintainer.StopLifecycle()
 	}
 
 	if m.nodeMaintainer != nil {
 		m.nodeMaintainer.StopFileClear()
+		m.nodeMaintainer.StopScrub()
+		m.nodeMaintainer.StopDrain()
 		m.nodeMaintainer.StopHeartBeat()
 	}
 }
@@ -14,6 +14,7 @@
 package challenging
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"io/ioutil"
@@ -33,7 +34,8 @@ import (
 )
 
 // loopAnswer listens challenge requests and answer them in order to prove it's storing related files
-//   and blocks current routine
+//   and blocks current routine, requests are answered once watched if the blockchain supports events,
+//   and are listed every AnswerInterval in case any is missed
 func (c *ChallengingMonitor) loopAnswer(ctx context.Context) {
 	pubkey := ecdsa.PublicKeyFromPrivateKey(c.PrivateKey)
 	l := logger.WithField("runner", "answer loop")
@@ -71,10 +73,16 @@ func (c *ChallengingMonitor) loopAnswer(ctx context.Context) {
 	c.doneLoopAnsC = make(chan struct{})
 	defer close(c.doneLoopAnsC)
 
+	// answer challenges as soon as they're requested, and poll for those missed
+	events := blockchain.Watch(ctx, c.blockchain, blockchain.EventChallengeRequest)
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case e := <-events:
+			c.answerRequested(e, pubkey, l)
+			continue
 		case <-ticker.C:
 		}
 		// list requests
@@ -93,17 +101,43 @@ func (c *ChallengingMonitor) loopAnswer(ctx context.Context) {
 			continue
 		}
 		for _, r := range requests {
-			if r.ChallengeAlgorithm == types.PairingChallengeAlgorithm {
-				c.doPairingChallengeAnswer(r, l)
-			} else if r.ChallengeAlgorithm == types.MerkleChallengeAlgorithm {
-				c.doMerkleChallengeAnswer(r, l)
-			} else {
-				l.WithField("challenge_id", r.ID).Debug("challenge answer failed, algorithm not support")
-			}
+			c.doChallengeAnswer(r, l)
 		}
 	}
 }
 
+// answerRequested answers the challenge requested in the event if it targets local node
+func (c *ChallengingMonitor) answerRequested(e blockchain.Event, pubkey ecdsa.PublicKey, l *logrus.Entry) {
+	var ce blockchain.ChallengeEvent
+	if err := json.Unmarshal(e.Payload, &ce); err != nil {
+		l.WithError(err).Warn("failed to unmarshal challenge event")
+		return
+	}
+	if !bytes.Equal(ce.TargetNode, []byte(pubkey.String())) || ce.Status != blockchain.ChallengeToProve {
+		return
+	}
+	r, err := c.blockchain.GetChallengeByID(ce.ID)
+	if err != nil {
+		l.WithField("challenge_id", ce.ID).WithError(err).Warn("failed to get challenge request from blockchain")
+		return
+	}
+	// the challenge may have been answered by polling
+	if r.Status != blockchain.ChallengeToProve {
+		return
+	}
+	c.doChallengeAnswer(r, l)
+}
+
+func (c *ChallengingMonitor) doChallengeAnswer(r blockchain.Challenge, l *logrus.Entry) {
+	if r.ChallengeAlgorithm == types.PairingChallengeAlgorithm {
+		c.doPairingChallengeAnswer(r, l)
+	} else if r.ChallengeAlgorithm == types.MerkleChallengeAlgorithm {
+		c.doMerkleChallengeAnswer(r, l)
+	} else {
+		l.WithField("challenge_id", r.ID).Debug("challenge answer failed, algorithm not support")
+	}
+}
+
 func (c *ChallengingMonitor) doPairingChallengeAnswer(r blockchain.Challenge, l *logrus.Entry) error {
 	// answer for each request
 	// calculate proof
@@ -59,6 +59,7 @@ type Blockchain interface {
 	ListFiles(opt *blockchain.ListFileOptions) ([]blockchain.File, error)
 	ListFileNs(opt *blockchain.ListNsOptions) ([]blockchain.Namespace, error)
 	ListChallengeRequests(opt *blockchain.ListChallengeOptions) ([]blockchain.Challenge, error)
+	GetChallengeByID(id string) (blockchain.Challenge, error)
 	ChallengeRequest(opt *blockchain.ChallengeRequestOptions) error
 	ChallengeAnswer(opt *blockchain.ChallengeAnswerOptions) ([]byte, error)
 	NodeOffline(opt *blockchain.NodeOperateOptions) error
@@ -124,10 +124,15 @@ func (c *ChallengingMonitor) loopRequest(ctx context.Context) {
 func (c *ChallengingMonitor) doPairingChallengeRequest(challengeAlgorithm string, files []blockchain.File,
 	pubkey ecdsa.PublicKey, l *logrus.Entry) error {
 
-	// select just one file and nodeID
+	// select just one file and nodeID, referenced slices are challenged by their source files
 	rand.Seed(time.Now().UnixNano())
 	fileSelected := files[rand.Int()%len(files)]
-	sliceSelected := fileSelected.Slices[rand.Int()%len(fileSelected.Slices)]
+	ownSlices := fileSelected.OwnSlices()
+	if len(ownSlices) == 0 {
+		l.WithField("fileID", fileSelected.ID).Debug("no slice stored by the file, do nothing")
+		return nil
+	}
+	sliceSelected := ownSlices[rand.Int()%len(ownSlices)]
 	nodeSelected := sliceSelected.NodeID
 
 	l.WithField("fileID", fileSelected.ID).Info("file selected")
@@ -136,7 +141,7 @@ func (c *ChallengingMonitor) doPairingChallengeRequest(challengeAlgorithm string
 	// get map from sliceIdx to sliceID
 	var sliceList []int
 	sliceMap := make(map[int]blockchain.PublicSliceMeta)
-	for _, slice := range fileSelected.Slices {
+	for _, slice := range ownSlices {
 		if reflect.DeepEqual(slice.NodeID, nodeSelected) {
 			sliceList = append(sliceList, slice.SliceIdx)
 			sliceMap[slice.SliceIdx] = slice
@@ -208,9 +213,14 @@ func (c *ChallengingMonitor) doPairingChallengeRequest(challengeAlgorithm string
 
 func (c *ChallengingMonitor) doMerkleChallengeRequest(challengeAlgorithm string, files []blockchain.File,
 	pubkey ecdsa.PublicKey, l *logrus.Entry) error {
-	// select just one slice
+	// select just one slice, referenced slices are challenged by their source files
 	fileSelected := files[rand.Int()%len(files)]
-	sliceSelected := fileSelected.Slices[rand.Int()%len(fileSelected.Slices)]
+	ownSlices := fileSelected.OwnSlices()
+	if len(ownSlices) == 0 {
+		l.WithField("fileID", fileSelected.ID).Debug("no slice stored by the file, do nothing")
+		return nil
+	}
+	sliceSelected := ownSlices[rand.Int()%len(ownSlices)]
 
 	// take one range
 	rangeSelected, err := c.challengeDB.Take(fileSelected.ID, sliceSelected.ID, sliceSelected.NodeID)
@@ -33,6 +33,16 @@ import (
 const (
 	// Defines the default interval for files migration
 	defaultFileMigrateInterval = time.Hour * 1
+	// Defines the default interval for re-encrypting slices with the current key
+	defaultFileRekeyInterval = time.Hour * 24
+	// Defines the default number of replicas moved in a round of rebalancing
+	defaultRebalanceMoves = 100
+	// Defines the default utilization in percent a node exceeds the average by to be rebalanced
+	defaultRebalanceThreshold = 10
+	// Defines the default period before files expire in which they are renewed or warned of
+	defaultLifecycleWarnBefore = time.Hour * 72
+	// Defines the period in which failed challenges are merged into one migration
+	failedChallengeWindow = time.Second * 10
 )
 
 var (
@@ -40,24 +50,32 @@ var (
 )
 
 type Copier interface {
-	Push(ctx context.Context, id, sourceID string, r io.Reader, node *blockchain.Node) (string, error)
+	Push(ctx context.Context, id, sourceID, fileID string, r io.Reader, node *blockchain.Node) (string, error)
 	Pull(ctx context.Context, id, storIndex, fileID string, node *blockchain.Node) (io.ReadCloser, error)
+	Delete(ctx context.Context, id, storIndex string, node *blockchain.Node) error
+	ScrubReports(ctx context.Context, owner string, node *blockchain.Node) ([]types.SliceScrub, error)
 	ReplicaExpansion(ctx context.Context, opt *copier.ReplicaExpOptions, enc common.CommonEncryptor,
 		challengeAlgorithm, sourceID, fileID string) ([]blockchain.PublicSliceMeta, []encryptor.EncryptedSlice, error)
 }
 
 type Encryptor interface {
+	CurrentKeyID() string
+	WrapFileKey(keyID, fileID string, wrappedKey []byte) (string, []byte, error)
 	Encrypt(r io.Reader, opt *encryptor.EncryptOptions) (encryptor.EncryptedSlice, error)
 	Recover(r io.Reader, opt *encryptor.RecoverOptions) ([]byte, error)
 }
 
 type Blockchain interface {
-	PublishFile(file *blockchain.PublishFileOptions) error
+	PublishFile(file *blockchain.PublishFileOptions) (blockchain.File, error)
 	ListFiles(opt *blockchain.ListFileOptions) ([]blockchain.File, error)
 	GetFileByID(id string) (blockchain.File, error)
 	ListFileNs(opt *blockchain.ListNsOptions) ([]blockchain.Namespace, error)
 	UpdateFilePublicSliceMeta(opt *blockchain.UpdateFilePSMOptions) error
 	SliceMigrateRecord(opt *blockchain.SliceMigrateOptions) error
+	UpdateFileExpireTime(opt *blockchain.UpdateExptimeOptions) (blockchain.File, error)
+	DeleteFile(opt *blockchain.DeleteFileOptions) error
+	ListExpiredFiles(opt *blockchain.ListFileOptions) ([]blockchain.File, error)
+	ListTaskFiles(owner []byte) (map[string]struct{}, error)
 
 	ListNodes() (blockchain.Nodes, error)
 	GetNode(id []byte) (blockchain.Node, error)
@@ -70,6 +88,7 @@ type Challenger interface {
 	Setup(sliceData []byte, rangeAmount int) ([]ctype.RangeHash, error)
 	Save(cms []ctype.Material) error
 	Take(fileID string, sliceID string, nodeID []byte) (ctype.RangeHash, error)
+	Remove(fileID string, sliceID string, nodeID []byte) error
 
 	GetChallengeConf() (string, types.PairingChallengeConf)
 	Close()
@@ -84,7 +103,9 @@ type NewFileMaintainerOptions struct {
 }
 
 // FileMaintainer runs if local node is dataOwner-node, and its main work is to check storage-nodes health conditions
-//  and migrate slices from bad nodes to healthy nodes.
+//  and migrate slices from bad nodes to healthy nodes, to re-encrypt slices with the current master key,
+//  to rebalance slices from the most utilized nodes to under-utilized ones,
+//  and to apply lifecycle policies of namespaces.
 type FileMaintainer struct {
 	localNode  peer.Local
 	blockchain Blockchain
@@ -95,8 +116,21 @@ type FileMaintainer struct {
 	challengerInterval int64
 
 	fileMigrateInterval time.Duration
+	fileRekeyInterval   time.Duration
 
-	doneMigrateC chan struct{} //doneMigrateC will be closed when loop breaks
+	rebalanceInterval  time.Duration // zero if rebalancing is disabled
+	rebalanceMoves     int
+	rebalanceThreshold float64
+
+	lifecycleInterval   time.Duration // zero if lifecycle policies are not applied
+	lifecycleWarnBefore time.Duration
+	lifecycleWebhook    string
+	warned              map[string]int64 // expire time of files warned of, to warn once
+
+	doneMigrateC   chan struct{} //doneMigrateC will be closed when loop breaks
+	doneRekeyC     chan struct{} //doneRekeyC will be closed when loop breaks
+	doneRebalanceC chan struct{} //doneRebalanceC will be closed when loop breaks
+	doneLifecycleC chan struct{} //doneLifecycleC will be closed when loop breaks
 }
 
 func New(conf *config.MonitorConf, opt *NewFileMaintainerOptions, interval int64) (*FileMaintainer, error) {
@@ -106,8 +140,36 @@ func New(conf *config.MonitorConf, opt *NewFileMaintainerOptions, interval int64
 		fileMigrateInterval = defaultFileMigrateInterval
 	}
 
+	fileRekeyInterval := time.Duration(conf.FilerekeyInterval) * time.Hour
+	if fileRekeyInterval == 0 {
+		fileRekeyInterval = defaultFileRekeyInterval
+	}
+
+	rebalanceInterval := time.Duration(conf.RebalanceInterval) * time.Hour
+	rebalanceMoves := conf.RebalanceMoves
+	if rebalanceMoves <= 0 {
+		rebalanceMoves = defaultRebalanceMoves
+	}
+	rebalanceThreshold := conf.RebalanceThreshold
+	if rebalanceThreshold <= 0 {
+		rebalanceThreshold = defaultRebalanceThreshold
+	}
+
+	lifecycleInterval := time.Duration(conf.LifecycleInterval) * time.Hour
+	lifecycleWarnBefore := time.Duration(conf.LifecycleWarnBefore) * time.Hour
+	if lifecycleWarnBefore <= 0 {
+		lifecycleWarnBefore = defaultLifecycleWarnBefore
+	}
+
 	logger.WithFields(logrus.Fields{
 		"filemigrate-interval": fileMigrateInterval,
+		"filerekey-interval":   fileRekeyInterval,
+		"rebalance-interval":   rebalanceInterval,
+		"rebalance-moves":      rebalanceMoves,
+		"rebalance-threshold":  rebalanceThreshold,
+		"lifecycle-interval":   lifecycleInterval,
+		"lifecycle-warnbefore": lifecycleWarnBefore,
+		"lifecycle-webhook":    conf.LifecycleWebhook,
 	}).Info("monitor initialize...")
 
 	return &FileMaintainer{
@@ -118,6 +180,14 @@ func New(conf *config.MonitorConf, opt *NewFileMaintainerOptions, interval int64
 		challenger:          opt.Challenger,
 		challengerInterval:  interval,
 		fileMigrateInterval: fileMigrateInterval,
+		fileRekeyInterval:   fileRekeyInterval,
+		rebalanceInterval:   rebalanceInterval,
+		rebalanceMoves:      rebalanceMoves,
+		rebalanceThreshold:  float64(rebalanceThreshold) / 100,
+		lifecycleInterval:   lifecycleInterval,
+		lifecycleWarnBefore: lifecycleWarnBefore,
+		lifecycleWebhook:    conf.LifecycleWebhook,
+		warned:              make(map[string]int64),
 	}, nil
 }
 
@@ -142,3 +212,61 @@ func (m *FileMaintainer) StopMigrate() {
 
 	<-m.doneMigrateC
 }
+
+// Rekey starts re-encrypting slices with the current master key
+func (m *FileMaintainer) Rekey(ctx context.Context) {
+	go m.rekey(ctx)
+}
+
+// StopRekey stops re-encrypting slices
+func (m *FileMaintainer) StopRekey() {
+	if m.doneRekeyC == nil {
+		return
+	}
+
+	logger.Info("stops file rekey ...")
+
+	select {
+	case <-m.doneRekeyC:
+		return
+	default:
+	}
+
+	<-m.doneRekeyC
+}
+
+// Rebalance starts rebalancing slices across storage nodes if enabled
+func (m *FileMaintainer) Rebalance(ctx context.Context) {
+	if m.rebalanceInterval <= 0 {
+		return
+	}
+	go m.rebalance(ctx)
+}
+
+// StopRebalance stops rebalancing slices
+func (m *FileMaintainer) StopRebalance() {
+	if m.doneRebalanceC == nil {
+		return
+	}
+
+	logger.Info("stops file rebalance ...")
+	<-m.doneRebalanceC
+}
+
+// Lifecycle starts applying lifecycle policies of namespaces if enabled
+func (m *FileMaintainer) Lifecycle(ctx context.Context) {
+	if m.lifecycleInterval <= 0 {
+		return
+	}
+	go m.lifecycle(ctx)
+}
+
+// StopLifecycle stops applying lifecycle policies
+func (m *FileMaintainer) StopLifecycle() {
+	if m.doneLifecycleC == nil {
+		return
+	}
+
+	logger.Info("stops file lifecycle ...")
+	<-m.doneLifecycleC
+}
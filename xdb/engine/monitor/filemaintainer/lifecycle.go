@@ -0,0 +1,294 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filemaintainer
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
+	"github.com/sirupsen/logrus"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/common"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	util "github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/strings"
+)
+
+const (
+	// lifecycleEventExpiring is the event posted to the webhook when a file is about to expire
+	lifecycleEventExpiring = "file-expiring"
+	// webhookTimeout is the timeout of posting an event to the webhook
+	webhookTimeout = time.Second * 10
+)
+
+var ll = logger.WithField("runner", "file lifecycle loop")
+
+// lifecycleEvent is posted to the webhook in JSON, times are in nanoseconds
+type lifecycleEvent struct {
+	Event      string `json:"event"`
+	FileID     string `json:"fileID"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	Owner      string `json:"owner"`
+	ExpireTime int64  `json:"expireTime"`
+	DeleteTime int64  `json:"deleteTime,omitempty"` // the file is deleted then if the namespace has a grace period
+}
+
+// lifecycle applies lifecycle policies of namespaces regularly. Files expiring within lifecycleWarnBefore
+// are renewed by the default TTL if the namespace is auto-renewed and they are used by DAI tasks in progress,
+// otherwise a warning is posted to the webhook once for each expire time. Files expired for the grace period
+// are deleted, so that their slices are cleared by storage nodes before the retain period ends
+func (m *FileMaintainer) lifecycle(ctx context.Context) {
+	pubkey := ecdsa.PublicKeyFromPrivateKey(m.localNode.PrivateKey)
+
+	defer ll.Info("file lifecycle stopped")
+
+	ticker := time.NewTicker(m.lifecycleInterval)
+	defer ticker.Stop()
+
+	m.doneLifecycleC = make(chan struct{})
+	defer close(m.doneLifecycleC)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		m.applyLifecycle(ctx, pubkey[:])
+	}
+}
+
+// applyLifecycle applies lifecycle policies of all the namespaces of owner
+func (m *FileMaintainer) applyLifecycle(ctx context.Context, owner []byte) {
+	nsList, err := m.blockchain.ListFileNs(&blockchain.ListNsOptions{
+		Owner:   owner,
+		TimeEnd: time.Now().UnixNano(),
+	})
+	if err != nil {
+		ll.WithError(err).Error("failed to find ns list")
+		return
+	}
+
+	// files used by DAI tasks are listed once a round, and only if any namespace is auto-renewed
+	var inUse map[string]struct{}
+	var renewed, warned, deleted int
+	for _, ns := range nsList {
+		if ns.Lifecycle == nil {
+			continue
+		}
+		policy := *ns.Lifecycle
+		if policy.AutoRenew && inUse == nil {
+			if inUse, err = m.blockchain.ListTaskFiles(owner); err != nil {
+				ll.WithError(err).Warn("failed to list files used by DAI tasks, no file is renewed")
+				inUse = make(map[string]struct{})
+			}
+		}
+
+		now := time.Now().UnixNano()
+		listOpt := blockchain.ListFileOptions{
+			Owner:       owner,
+			Namespace:   ns.Name,
+			TimeEnd:     now,
+			CurrentTime: now,
+		}
+		files, err := m.blockchain.ListFiles(&listOpt)
+		if err != nil {
+			ll.WithField("namespace", ns.Name).WithError(err).Error("failed to find file list")
+			continue
+		}
+		// files expired but still used are renewed as well, and those expired for the grace period are deleted
+		var expired []blockchain.File
+		if policy.AutoRenew || policy.GracePeriod > 0 {
+			if expired, err = m.blockchain.ListExpiredFiles(&listOpt); err != nil {
+				ll.WithField("namespace", ns.Name).WithError(err).Error("failed to find expired file list")
+			}
+		}
+
+		for _, file := range append(files, expired...) {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			fields := logrus.Fields{
+				"file_id":     file.ID,
+				"namespace":   ns.Name,
+				"expire_time": time.Unix(0, file.ExpireTime).Format("2006-01-02 15:04:05"),
+			}
+			if file.ExpireTime-now > m.lifecycleWarnBefore.Nanoseconds() {
+				continue
+			}
+			if _, used := inUse[file.ID]; used && policy.AutoRenew {
+				if err := m.renewFile(ctx, file, policy.DefaultTTL); err != nil {
+					ll.WithFields(fields).WithError(err).Warn("failed to renew file")
+				} else {
+					renewed++
+					continue
+				}
+			}
+
+			if file.ExpireTime > now {
+				if m.warned[file.ID] == file.ExpireTime {
+					continue
+				}
+				if err := m.warnExpiring(ctx, file, policy); err != nil {
+					ll.WithFields(fields).WithError(err).Warn("failed to warn of file expiring")
+					continue
+				}
+				m.warned[file.ID] = file.ExpireTime
+				warned++
+				continue
+			}
+
+			delete(m.warned, file.ID)
+			if policy.GracePeriod <= 0 || now-file.ExpireTime < policy.GracePeriod {
+				continue
+			}
+			if err := m.deleteFile(file.ID); err != nil {
+				ll.WithFields(fields).WithError(err).Warn("failed to delete file expired for the grace period")
+				continue
+			}
+			deleted++
+			ll.WithFields(fields).Info("file expired for the grace period deleted")
+		}
+	}
+
+	ll.WithFields(logrus.Fields{
+		"renewed": renewed,
+		"warned":  warned,
+		"deleted": deleted,
+	}).Info("file lifecycle applied")
+}
+
+// renewFile extends the expire time of the file by ttl from the time it expires, or from now if expired,
+// and adds challenge materials for the extended period like updating expire time by the owner
+func (m *FileMaintainer) renewFile(ctx context.Context, file blockchain.File, ttl int64) error {
+	now := time.Now().UnixNano()
+	startTime := file.ExpireTime
+	if startTime <= now {
+		startTime = now
+	}
+	opt := &blockchain.UpdateExptimeOptions{
+		FileID:        file.ID,
+		NewExpireTime: startTime + ttl,
+		CurrentTime:   now,
+	}
+	msg, err := util.GetSigMessage(opt)
+	if err != nil {
+		return errorx.Internal(err, "failed to get the message to sign for update file expire time")
+	}
+	sig, err := ecdsa.Sign(m.localNode.PrivateKey, hash.HashUsingSha256([]byte(msg)))
+	if err != nil {
+		return errorx.Wrap(err, "failed to sign")
+	}
+	opt.Signature = sig[:]
+
+	newFile, err := m.blockchain.UpdateFileExpireTime(opt)
+	if err != nil {
+		return errorx.Wrap(err, "failed to update file on blockchain")
+	}
+	ll.WithFields(logrus.Fields{
+		"file_id":     file.ID,
+		"expire_time": time.Unix(0, newFile.ExpireTime).Format("2006-01-02 15:04:05"),
+	}).Info("file used by DAI tasks renewed")
+
+	challengeAlgorithm, pairingConf := m.challenger.GetChallengeConf()
+	if challengeAlgorithm == types.MerkleChallengeAlgorithm {
+		if err := common.AddFileNewMerkleChallenge(ctx, m.challenger, m.blockchain, m.copier, m.encryptor, newFile,
+			startTime, m.challengerInterval, ll); err != nil {
+			return errorx.Wrap(err, "failed to add merkle challenge material")
+		}
+	}
+	if challengeAlgorithm == types.PairingChallengeAlgorithm {
+		if err := common.AddFilePairingChallenges(ctx, pairingConf, m.blockchain, m.copier, newFile,
+			hex.EncodeToString(file.Owner), startTime, m.challengerInterval, ll); err != nil {
+			return errorx.Wrap(err, "failed to add pairing based challenge material")
+		}
+	}
+	return nil
+}
+
+// warnExpiring posts a warning of the file about to expire to the webhook, or only logs it if no webhook is configured
+func (m *FileMaintainer) warnExpiring(ctx context.Context, file blockchain.File, policy blockchain.LifecyclePolicy) error {
+	event := lifecycleEvent{
+		Event:      lifecycleEventExpiring,
+		FileID:     file.ID,
+		Namespace:  file.Namespace,
+		Name:       file.Name,
+		Owner:      hex.EncodeToString(file.Owner),
+		ExpireTime: file.ExpireTime,
+	}
+	if policy.GracePeriod > 0 {
+		event.DeleteTime = file.ExpireTime + policy.GracePeriod
+	}
+	ll.WithFields(logrus.Fields{
+		"file_id":     file.ID,
+		"namespace":   file.Namespace,
+		"name":        file.Name,
+		"expire_time": time.Unix(0, file.ExpireTime).Format("2006-01-02 15:04:05"),
+	}).Warn("file about to expire")
+	if m.lifecycleWebhook == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errorx.Internal(err, "failed to marshal lifecycle event")
+	}
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodPost, m.lifecycleWebhook, bytes.NewReader(body))
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeParam, "invalid lifecycle webhook")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to post to lifecycle webhook")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errorx.New(errorx.ErrCodeInternal, "lifecycle webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// deleteFile deletes the file on blockchain, storage nodes remove its slices once they see the tombstone
+func (m *FileMaintainer) deleteFile(fileID string) error {
+	opt := &blockchain.DeleteFileOptions{
+		FileID:      fileID,
+		CurrentTime: time.Now().UnixNano(),
+	}
+	msg, err := util.GetSigMessage(opt)
+	if err != nil {
+		return errorx.Internal(err, "failed to get the message to sign for delete file")
+	}
+	sig, err := ecdsa.Sign(m.localNode.PrivateKey, hash.HashUsingSha256([]byte(msg)))
+	if err != nil {
+		return errorx.Wrap(err, "failed to sign")
+	}
+	opt.Signature = sig[:]
+	if err := m.blockchain.DeleteFile(opt); err != nil {
+		return errorx.Wrap(err, "failed to delete file %s on blockchain", fileID)
+	}
+	return nil
+}
@@ -14,8 +14,10 @@
 package filemaintainer
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"sync"
 	"time"
 
@@ -36,7 +38,11 @@ var l = logger.WithField("runner", "file migrate loop")
 // migrate checks storage-nodes health conditions and migrate slices from bad nodes to healthy nodes
 // The health of slices is determined by the number of slices's replicas and
 // the health of storage nodes where slices stored,
-// if the number of replicas is not enough, expand the slice replicas firstly during slice migration
+// if the number of replicas is not enough, expand the slice replicas firstly during slice migration.
+// Replicas found corrupt or missing by scrubbing on storage nodes, and those on draining nodes,
+// are migrated like those on bad nodes, and deleted from the nodes once migrated.
+// Migration runs every fileMigrateInterval. If the blockchain supports events, challenges failed within
+// failedChallengeWindow are merged, and only files with slices on the nodes failing them are migrated at once
 func (m *FileMaintainer) migrate(ctx context.Context) {
 	pubkey := ecdsa.PublicKeyFromPrivateKey(m.localNode.PrivateKey)
 
@@ -50,10 +56,22 @@ func (m *FileMaintainer) migrate(ctx context.Context) {
 	m.doneMigrateC = make(chan struct{})
 	defer close(m.doneMigrateC)
 
+	// a failed challenge may make the target node unhealthy, so migrate right away without waiting for the ticker
+	events := blockchain.Watch(ctx, m.blockchain, blockchain.EventChallengeAnswer)
+
 	for {
+		// nodes failing challenges, all files are checked if nil
+		var failedNodes map[string]struct{}
 		select {
 		case <-ctx.Done():
 			return
+		case e := <-events:
+			node, ok := failedChallengeNode(e, pubkey[:])
+			if !ok {
+				continue
+			}
+			failedNodes = mergeFailedChallenges(ctx, events, node, pubkey[:])
+			l.WithField("nodes", len(failedNodes)).Info("challenge failed, start migrating")
 		case <-ticker.C:
 		}
 
@@ -91,6 +109,8 @@ func (m *FileMaintainer) migrate(ctx context.Context) {
 				greenNodes = append(greenNodes, node)
 			}
 		}
+		damaged := m.scrubReports(ctx, pubkey.String(), healthNodes)
+		drained := newDrainProgress(healthNodes)
 
 		wg := sync.WaitGroup{}
 		wg.Add(len(nsList))
@@ -122,12 +142,15 @@ func (m *FileMaintainer) migrate(ctx context.Context) {
 					}
 					go func(file blockchain.File) {
 						defer wgf.Done()
+						if failedNodes != nil && !storedOn(file, failedNodes) {
+							return
+						}
 						health, err := common.GetFileHealth(ctx, m.blockchain, file, ns.Replica)
 						if err != nil {
 							l.WithField("file_id", file.ID).WithError(err).Error("failed to get file health")
 							return
 						}
-						if health == blockchain.NodeHealthGood {
+						if health == blockchain.NodeHealthGood && len(damaged[file.ID]) == 0 && !drained.stores(file) {
 							return
 						}
 
@@ -142,7 +165,7 @@ func (m *FileMaintainer) migrate(ctx context.Context) {
 						if len(file.Slices) < ns.Replica*len(sliceNum) {
 							nodesMap := common.ToNodeHsMap(healthNodes)
 							if err := common.ExpandFileSlices(ctx, m.localNode.PrivateKey, m.copier, m.encryptor, m.blockchain,
-								m.challenger, file, nodesMap, ns.Replica, healthNodes, interval, l); err != nil {
+								m.challenger, file, nodesMap, ns.Replica, ns.Placement, healthNodes, interval, l); err != nil {
 								l.WithField("file_id", file.ID).WithError(err).Error("failed to migrate file")
 								return
 							}
@@ -165,27 +188,44 @@ func (m *FileMaintainer) migrate(ctx context.Context) {
 						fileUpdated := false
 						newSlices := file.Slices
 						var yellowNodeSlices []blockchain.PublicSliceMeta
+						var replacedReplicas []blockchain.PublicSliceMeta
 						var migrateEncSlices []encryptor.EncryptedSlice
 						var mSlice encryptor.EncryptedSlice
 						for _, slice := range file.Slices {
+							// referenced slices are migrated by their source files
+							if slice.Referenced() {
+								continue
+							}
 							nodeSliceMap := nodeSliceMap(newSlices, slice.ID)
 							nh, err := m.blockchain.GetNodeHealth(slice.NodeID)
 							if err != nil {
 								l.WithField("slice_id", slice.ID).WithError(err).Error("failed to get slice node health")
 								continue
 							}
-							if nh == blockchain.NodeHealthBad {
+							isDamaged := damaged.has(file.ID, slice)
+							isDraining := drained.draining(slice.NodeID)
+							if nh == blockchain.NodeHealthBad || isDamaged || isDraining {
 								newSlices, mSlice, selectedNodes, err = m.migrateSliceToNewNode(ctx, slice, nodeSliceMap, healthNodes,
-									healthNodesMap, selectedNodes, file.ID, newSlices, challengeAlgorithm, hex.EncodeToString(file.Owner))
+									healthNodesMap, selectedNodes, file, newSlices, ns.Placement, challengeAlgorithm,
+									hex.EncodeToString(file.Owner))
 								if err != nil {
 									l.WithFields(logrus.Fields{
 										"file_id":  file.ID,
 										"slice_id": slice.ID,
+										"damaged":  isDamaged,
+										"draining": isDraining,
 									}).WithError(err).Error("migrate red node failed")
 								} else {
 									fileUpdated = true
 									migrateEncSlices = append(migrateEncSlices, mSlice)
+									if isDamaged || isDraining {
+										replacedReplicas = append(replacedReplicas, slice)
+									}
+								}
+								if isDraining {
+									drained.add(slice.NodeID, err == nil)
 								}
+								continue
 							}
 							if nh == blockchain.NodeHealthMedium {
 								yellowNodeSlices = append(yellowNodeSlices, slice)
@@ -199,7 +239,8 @@ func (m *FileMaintainer) migrate(ctx context.Context) {
 							for _, slice := range yellowNodeSlices {
 								nodeSliceMap := nodeSliceMap(newSlices, slice.ID)
 								newSlices, mSlice, selectedNodes, err = m.migrateSliceToNewNode(ctx, slice, nodeSliceMap, greenNodes,
-									healthNodesMap, selectedNodes, file.ID, newSlices, challengeAlgorithm, hex.EncodeToString(file.Owner))
+									healthNodesMap, selectedNodes, file, newSlices, ns.Placement, challengeAlgorithm,
+									hex.EncodeToString(file.Owner))
 								if err != nil {
 									l.WithFields(logrus.Fields{
 										"file_id":  file.ID,
@@ -242,6 +283,17 @@ func (m *FileMaintainer) migrate(ctx context.Context) {
 								l.WithField("file_id", file.ID).Info("file migrate finished")
 							} else {
 								l.WithField("file_id", file.ID).WithError(err).Error("updateFileSlicesOnChain failed")
+								return
+							}
+							// damaged replicas and those on draining nodes are no longer recorded on blockchain
+							for _, slice := range replacedReplicas {
+								node := healthNodesMap[string(slice.NodeID)].Node
+								if err := m.copier.Delete(ctx, slice.ID, slice.StorIndex, &node); err != nil {
+									l.WithFields(logrus.Fields{
+										"slice_id":    slice.ID,
+										"target_node": string(slice.NodeID),
+									}).WithError(err).Warn("failed to delete replaced replica")
+								}
 							}
 						}
 
@@ -252,6 +304,7 @@ func (m *FileMaintainer) migrate(ctx context.Context) {
 			}(ns)
 		}
 		wg.Wait()
+		drained.report()
 		l.WithFields(logrus.Fields{
 			"namespace_len": len(nsList),
 			"end_time":      time.Now().Format("2006-01-02 15:04:05"),
@@ -259,19 +312,31 @@ func (m *FileMaintainer) migrate(ctx context.Context) {
 	}
 }
 
-// migrateSliceToNewNode find available healthy node and migrate a slice from bad node to it
-// 1. pull slice from healthy node and decrypt it
+// migrateSliceToNewNode find available healthy node allowed by the placement policy and migrate a slice from bad node to it
+// 1. pull slice from healthy node and decrypt it, erasure coded shard is reconstructed from its stripe
 // 2. encrypt slice and push into the new storage node
 // 3. record slice migrated info and update it to the blockchain
 func (m FileMaintainer) migrateSliceToNewNode(ctx context.Context, slice blockchain.PublicSliceMeta,
 	nodeSliceMap map[string]blockchain.PublicSliceMeta, healthNodes blockchain.NodeHs,
-	healthNodesMap map[string]blockchain.NodeH, selectedNodes map[string][]string, fileID string,
-	slices []blockchain.PublicSliceMeta, challengeAlgorithm, sourceID string) ([]blockchain.PublicSliceMeta,
+	healthNodesMap map[string]blockchain.NodeH, selectedNodes map[string][]string, file blockchain.File,
+	slices []blockchain.PublicSliceMeta, placement *blockchain.PlacementPolicy, challengeAlgorithm, sourceID string) ([]blockchain.PublicSliceMeta,
 	encryptor.EncryptedSlice, map[string][]string, error) {
 
+	fileID := file.ID
 	var newMigrateEnSlice encryptor.EncryptedSlice
-	// find new nodes to migrate slice
-	newNodes, err := common.FindNewNodes(healthNodes, selectedNodes[slice.ID])
+	// find new nodes to migrate slice, shards of the same stripe are kept on distinct nodes
+	excludes := selectedNodes[slice.ID]
+	if file.ErasureCoded() {
+		excludes = stripeNodes(slices, slice.Stripe)
+	}
+	// the replica on the bad node is replaced, so the node is not taken as a holder of the slice
+	var holders []string
+	for _, n := range excludes {
+		if n != string(slice.NodeID) {
+			holders = append(holders, n)
+		}
+	}
+	newNodes, err := common.PlaceNewNodes(healthNodes, excludes, holders, placement)
 	if err != nil {
 		return slices, newMigrateEnSlice, selectedNodes, errorx.Wrap(err, "failed to find new nodes")
 	}
@@ -299,6 +364,24 @@ func (m FileMaintainer) migrateSliceToNewNode(ctx context.Context, slice blockch
 			break
 		}
 	}
+	// erasure coded shard has no replica, reconstruct it from other shards of the stripe
+	if len(plaintext) == 0 && file.ErasureCoded() {
+		nodesMap := make(map[string]blockchain.Node)
+		for id, nodeH := range healthNodesMap {
+			nodesMap[id] = nodeH.Node
+		}
+		// other shards of the stripe may have been migrated already
+		stripeFile := file
+		stripeFile.Slices = slices
+		pulled = true
+		plaintext, pullErr = common.RecoverStripeShard(ctx, m.copier, m.encryptor, stripeFile, slice.ID, nodesMap)
+		if pullErr != nil {
+			l.WithFields(logrus.Fields{
+				"file_id":  fileID,
+				"slice_id": slice.ID,
+			}).WithError(pullErr).Error("failed to reconstruct shard")
+		}
+	}
 	if len(plaintext) == 0 {
 		if !pulled {
 			return slices, newMigrateEnSlice, selectedNodes, errorx.New(errorx.ErrCodeInternal, "no healthy nodes to recover slice")
@@ -328,7 +411,7 @@ func (m FileMaintainer) migrateSliceToNewNode(ctx context.Context, slice blockch
 			m.migrateRecordOnChain(string(slice.NodeID), fileID, slice.ID)
 			if challengeAlgorithm == types.PairingChallengeAlgorithm {
 				// rearrange file slices, remove bad slice and insert new slice with new index
-				slices, err = m.rearrangeSlices(slices, slice.ID, storIndex, string(slice.NodeID), string(es.NodeID), es.CipherText)
+				slices, err = m.rearrangeSlices(slices, slice.ID, storIndex, string(slice.NodeID), es)
 				if err != nil {
 					l.WithFields(logrus.Fields{
 						"slice_id": slice.ID,
@@ -345,6 +428,8 @@ func (m FileMaintainer) migrateSliceToNewNode(ctx context.Context, slice blockch
 					Length:     es.EncryptedSliceMeta.Length,
 					NodeID:     es.EncryptedSliceMeta.NodeID,
 					StorIndex:  storIndex,
+					Stripe:     slice.Stripe,
+					KeyID:      es.EncryptedSliceMeta.KeyID,
 				}
 				slices = append(slices, newMigrateSlice)
 				slices = removeSlice(slices, slice)
@@ -364,6 +449,156 @@ func (m FileMaintainer) migrateSliceToNewNode(ctx context.Context, slice blockch
 	return slices, newMigrateEnSlice, selectedNodes, nil
 }
 
+// failedChallengeNode returns the target node of the event if it is of a failed challenge on files owned by owner
+func failedChallengeNode(e blockchain.Event, owner []byte) ([]byte, bool) {
+	var ce blockchain.ChallengeEvent
+	if err := json.Unmarshal(e.Payload, &ce); err != nil {
+		l.WithError(err).Warn("failed to unmarshal challenge event")
+		return nil, false
+	}
+	if ce.Status != blockchain.ChallengeFailed || !bytes.Equal(ce.FileOwner, owner) {
+		return nil, false
+	}
+	return ce.TargetNode, true
+}
+
+// mergeFailedChallenges collects nodes failing challenges on files owned by owner in failedChallengeWindow,
+// starting from node, so that challenges failed together are handled by one migration
+func mergeFailedChallenges(ctx context.Context, events <-chan blockchain.Event, node, owner []byte) map[string]struct{} {
+	nodes := map[string]struct{}{string(node): {}}
+	timer := time.NewTimer(failedChallengeWindow)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nodes
+		case <-timer.C:
+			return nodes
+		case e := <-events:
+			if node, ok := failedChallengeNode(e, owner); ok {
+				nodes[string(node)] = struct{}{}
+			}
+		}
+	}
+}
+
+// storedOn checks whether the file has replicas on any of the nodes
+func storedOn(file blockchain.File, nodes map[string]struct{}) bool {
+	for _, slice := range file.Slices {
+		if _, ok := nodes[string(slice.NodeID)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// damagedSlices are replicas found corrupt or missing by scrubbing on storage nodes,
+// indexed by file ID, then by slice ID and node ID
+type damagedSlices map[string]map[[2]string]struct{}
+
+// has returns true if the replica of a file is damaged
+func (d damagedSlices) has(fileID string, slice blockchain.PublicSliceMeta) bool {
+	_, exist := d[fileID][[2]string{slice.ID, string(slice.NodeID)}]
+	return exist
+}
+
+// scrubReports collects replicas pushed by owner found corrupt or missing by scrubbing on healthy nodes,
+// nodes not scrubbing or failing to respond are skipped
+func (m FileMaintainer) scrubReports(ctx context.Context, owner string, healthNodes blockchain.NodeHs) damagedSlices {
+	damaged := make(damagedSlices)
+	for _, nodeH := range healthNodes {
+		node := nodeH.Node
+		reports, err := m.copier.ScrubReports(ctx, owner, &node)
+		if err != nil {
+			l.WithField("target_node", string(node.ID)).WithError(err).Debug("failed to get scrub reports")
+			continue
+		}
+		for _, r := range reports {
+			if damaged[r.FileID] == nil {
+				damaged[r.FileID] = make(map[[2]string]struct{})
+			}
+			damaged[r.FileID][[2]string{r.SliceID, string(node.ID)}] = struct{}{}
+		}
+		if len(reports) > 0 {
+			l.WithField("target_node", string(node.ID)).Warnf("%d replicas reported corrupt or missing", len(reports))
+		}
+	}
+	return damaged
+}
+
+// drainProgress counts replicas migrated off draining nodes in a round of migration,
+// and those failed to migrate, which are migrated in the next round
+type drainProgress struct {
+	lock     sync.Mutex
+	nodes    map[string]struct{}
+	migrated map[string]int
+	failed   map[string]int
+}
+
+// newDrainProgress finds draining nodes in healthy nodes, draining nodes turning bad are migrated as bad nodes
+func newDrainProgress(healthNodes blockchain.NodeHs) *drainProgress {
+	p := &drainProgress{
+		nodes:    make(map[string]struct{}),
+		migrated: make(map[string]int),
+		failed:   make(map[string]int),
+	}
+	for _, n := range healthNodes {
+		if n.Node.Draining {
+			p.nodes[string(n.Node.ID)] = struct{}{}
+		}
+	}
+	return p
+}
+
+// draining returns true if the node is draining
+func (p *drainProgress) draining(nodeID []byte) bool {
+	_, exist := p.nodes[string(nodeID)]
+	return exist
+}
+
+// stores returns true if any replica of the file is stored on draining nodes
+func (p *drainProgress) stores(file blockchain.File) bool {
+	for _, slice := range file.Slices {
+		if !slice.Referenced() && p.draining(slice.NodeID) {
+			return true
+		}
+	}
+	return false
+}
+
+// add counts a replica migrated off a draining node, or failed to migrate
+func (p *drainProgress) add(nodeID []byte, migrated bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if migrated {
+		p.migrated[string(nodeID)]++
+	} else {
+		p.failed[string(nodeID)]++
+	}
+}
+
+// report logs the progress of each draining node
+func (p *drainProgress) report() {
+	for id := range p.nodes {
+		l.WithFields(logrus.Fields{
+			"target_node": id,
+			"migrated":    p.migrated[id],
+			"failed":      p.failed[id],
+		}).Info("replicas migrated off draining node")
+	}
+}
+
+// stripeNodes returns storage nodes of all the shards in a stripe of erasure coded file
+func stripeNodes(sliceMetas []blockchain.PublicSliceMeta, stripe int) []string {
+	var nodes []string
+	for _, slice := range sliceMetas {
+		if slice.Stripe == stripe {
+			nodes = append(nodes, string(slice.NodeID))
+		}
+	}
+	return nodes
+}
+
 // nodeSliceMap map node->sliceMeta for specific sliceID
 func nodeSliceMap(sliceMetas []blockchain.PublicSliceMeta, sliceID string) map[string]blockchain.PublicSliceMeta {
 	ret := make(map[string]blockchain.PublicSliceMeta)
@@ -375,9 +610,12 @@ func nodeSliceMap(sliceMetas []blockchain.PublicSliceMeta, sliceID string) map[s
 	return ret
 }
 
-// rearrangeSlices update slices in file structure saved on blockchain
-func (m FileMaintainer) rearrangeSlices(oldSlices []blockchain.PublicSliceMeta, sliceID, storIndex, badNode,
-	newNode string, ciphertext []byte) ([]blockchain.PublicSliceMeta, error) {
+// rearrangeSlices update slices in file structure saved on blockchain,
+// the replica on badNode is replaced by es pushed to a new node
+func (m FileMaintainer) rearrangeSlices(oldSlices []blockchain.PublicSliceMeta, sliceID, storIndex, badNode string,
+	es encryptor.EncryptedSlice) ([]blockchain.PublicSliceMeta, error) {
+
+	newNode := string(es.NodeID)
 
 	var badSlice blockchain.PublicSliceMeta
 	newNodeLargestIdx := 0
@@ -397,14 +635,16 @@ func (m FileMaintainer) rearrangeSlices(oldSlices []blockchain.PublicSliceMeta,
 	newSlices := removeSlice(oldSlices, badSlice)
 
 	// get slice for new node
-	newSliceHash := hash.HashUsingSha256(ciphertext)
+	newSliceHash := hash.HashUsingSha256(es.CipherText)
 	newNodeSlice := blockchain.PublicSliceMeta{
 		ID:         sliceID,
 		CipherHash: newSliceHash,
-		Length:     uint64(len(ciphertext)),
+		Length:     uint64(len(es.CipherText)),
 		NodeID:     []byte(newNode),
 		SliceIdx:   newNodeLargestIdx + 1,
 		StorIndex:  storIndex,
+		Stripe:     badSlice.Stripe,
+		KeyID:      es.KeyID,
 	}
 	newSlices = append(newSlices, newNodeSlice)
 	return newSlices, nil
@@ -448,11 +688,15 @@ func (m FileMaintainer) migrateRecordOnChain(nodeID, fileID, sliceID string) {
 
 // updateFileSlicesOnChain update file slices structure on blockchain
 func (m FileMaintainer) updateFileSlicesOnChain(fileID string, owner []byte, slices []blockchain.PublicSliceMeta) error {
-	opt := &blockchain.UpdateFilePSMOptions{
+	return m.updateFileOnChain(&blockchain.UpdateFilePSMOptions{
 		FileID: fileID,
 		Owner:  owner,
 		Slices: slices,
-	}
+	})
+}
+
+// updateFileOnChain signs and updates file slices on blockchain, with the key of the file if it's rekeyed
+func (m FileMaintainer) updateFileOnChain(opt *blockchain.UpdateFilePSMOptions) error {
 	msg, err := util.GetSigMessage(opt)
 	if err != nil {
 		return errorx.Internal(err, "failed to get the message to sign")
@@ -0,0 +1,310 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filemaintainer
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
+	"github.com/sirupsen/logrus"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/common"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+var bl = logger.WithField("runner", "file rebalance loop")
+
+// rebalance moves replicas of slices from the most utilized storage nodes to under-utilized healthy ones regularly,
+// such as nodes newly joined which only receive new writes. Utilization of nodes is reported by heartbeats,
+// nodes exceeding the average utilization by the threshold are rebalanced until they reach it,
+// and at most rebalanceMoves replicas are moved a round. Replicas are moved like those migrated from bad nodes,
+// restricted by the placement policy of namespaces, and deleted from the old nodes once the file is updated
+func (m *FileMaintainer) rebalance(ctx context.Context) {
+	pubkey := ecdsa.PublicKeyFromPrivateKey(m.localNode.PrivateKey)
+
+	defer bl.Info("file rebalance stopped")
+
+	ticker := time.NewTicker(m.rebalanceInterval)
+	defer ticker.Stop()
+
+	m.doneRebalanceC = make(chan struct{})
+	defer close(m.doneRebalanceC)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		healthNodes, err := common.GetHealthNodes(m.blockchain)
+		if err != nil {
+			bl.WithError(err).Error("failed to find healthy nodes")
+			continue
+		}
+		usage := newNodesUsage(healthNodes, m.rebalanceThreshold)
+		if len(usage.overNodes()) == 0 || len(usage.underNodes()) == 0 {
+			bl.Info("storage nodes balanced, no slice to move")
+			continue
+		}
+
+		listNsOpt := blockchain.ListNsOptions{
+			Owner:   pubkey[:],
+			TimeEnd: time.Now().UnixNano(),
+		}
+		nsList, err := m.blockchain.ListFileNs(&listNsOpt)
+		if err != nil {
+			bl.WithError(err).Error("failed to find ns list")
+			continue
+		}
+
+		moved := 0
+	nsLoop:
+		for _, ns := range nsList {
+			listFileOpt := blockchain.ListFileOptions{
+				Owner:       pubkey[:],
+				Namespace:   ns.Name,
+				TimeEnd:     time.Now().UnixNano(),
+				CurrentTime: time.Now().UnixNano(),
+			}
+			files, err := m.blockchain.ListFiles(&listFileOpt)
+			if err != nil {
+				bl.WithField("namespace", ns.Name).WithError(err).Error("failed to find file list")
+				continue
+			}
+			for _, file := range files {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+				if moved >= m.rebalanceMoves || len(usage.overNodes()) == 0 || len(usage.underNodes()) == 0 {
+					break nsLoop
+				}
+				n, err := m.rebalanceFile(ctx, file, ns, usage, healthNodes, m.rebalanceMoves-moved)
+				if err != nil {
+					bl.WithField("file_id", file.ID).WithError(err).Error("failed to rebalance file")
+				}
+				moved += n
+			}
+		}
+		bl.WithFields(logrus.Fields{
+			"moved":      moved,
+			"over_nodes": len(usage.overNodes()),
+		}).Info("file rebalance finished")
+	}
+}
+
+// rebalanceFile moves at most limit replicas of the file's own slices on over-utilized nodes
+// to under-utilized ones, referenced slices are moved by their source files, and files not healthy
+// are left to migration. Returns the number of replicas moved.
+func (m *FileMaintainer) rebalanceFile(ctx context.Context, file blockchain.File, ns blockchain.Namespace,
+	usage *nodesUsage, healthNodes blockchain.NodeHs, limit int) (int, error) {
+
+	var candidates []blockchain.PublicSliceMeta
+	for _, slice := range file.OwnSlices() {
+		if usage.over(slice.NodeID) {
+			candidates = append(candidates, slice)
+		}
+	}
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+	health, err := common.GetFileHealth(ctx, m.blockchain, file, ns.Replica)
+	if err != nil {
+		return 0, errorx.Wrap(err, "failed to get file health")
+	}
+	if health != blockchain.NodeHealthGood {
+		return 0, nil
+	}
+
+	challengeAlgorithm, pairingConf := m.challenger.GetChallengeConf()
+	healthNodesMap := make(map[string]blockchain.NodeH)
+	for _, node := range healthNodes {
+		healthNodesMap[string(node.Node.ID)] = node
+	}
+	selectedNodes := make(map[string][]string)
+	for _, slice := range file.Slices {
+		selectedNodes[slice.ID] = append(selectedNodes[slice.ID], string(slice.NodeID))
+	}
+	sourceID := hex.EncodeToString(file.Owner)
+
+	slices := file.Slices
+	var movedSlices []encryptor.EncryptedSlice
+	var oldSlices []blockchain.PublicSliceMeta
+	for _, slice := range candidates {
+		if len(movedSlices) >= limit {
+			break
+		}
+		// the node may have been relieved by slices moved before
+		if !usage.over(slice.NodeID) {
+			continue
+		}
+		targets := usage.underNodes()
+		if len(targets) == 0 {
+			break
+		}
+		// nodes holding the slice, or shards of the same stripe, are never selected,
+		// but are counted by the placement policy to spread replicas across failure domains
+		holders := selectedNodes[slice.ID]
+		if file.ErasureCoded() {
+			holders = stripeNodes(slices, slice.Stripe)
+		}
+		for _, id := range holders {
+			if n, exist := healthNodesMap[id]; exist && !usage.under([]byte(id)) {
+				targets = append(targets, n)
+			}
+		}
+		var es encryptor.EncryptedSlice
+		slices, es, selectedNodes, err = m.migrateSliceToNewNode(ctx, slice, nodeSliceMap(slices, slice.ID), targets,
+			healthNodesMap, selectedNodes, file, slices, ns.Placement, challengeAlgorithm, sourceID)
+		if err != nil {
+			bl.WithFields(logrus.Fields{
+				"file_id":  file.ID,
+				"slice_id": slice.ID,
+				"old_node": string(slice.NodeID),
+			}).WithError(err).Warn("failed to move slice")
+			continue
+		}
+		usage.move(slice.NodeID, es.NodeID, slice.Length)
+		movedSlices = append(movedSlices, es)
+		oldSlices = append(oldSlices, slice)
+	}
+	if len(movedSlices) == 0 {
+		return 0, nil
+	}
+
+	// challenge materials for the new replicas
+	interval := m.challengerInterval
+	if challengeAlgorithm == types.MerkleChallengeAlgorithm {
+		if err := common.AddSlicesNewMerkleChallenge(m.challenger, file, movedSlices, interval, bl); err != nil {
+			return 0, errorx.Wrap(err, "failed to add slices merkle challenge material")
+		}
+	}
+	if challengeAlgorithm == types.PairingChallengeAlgorithm {
+		file.Slices = slices
+		if err := common.AddSlicesNewPairingChallenge(ctx, pairingConf, m.copier, movedSlices, file, m.blockchain,
+			hex.EncodeToString(file.Owner), interval, time.Now().UnixNano(), file.ExpireTime, nil, bl); err != nil {
+			return 0, errorx.Wrap(err, "failed to add slices pairing challenge material")
+		}
+	}
+	if err := m.updateFileSlicesOnChain(file.ID, file.Owner, slices); err != nil {
+		return 0, errorx.Wrap(err, "failed to update file slices on chain")
+	}
+
+	// old replicas are no longer recorded on blockchain
+	for _, slice := range oldSlices {
+		node := healthNodesMap[string(slice.NodeID)].Node
+		if err := m.copier.Delete(ctx, slice.ID, slice.StorIndex, &node); err != nil {
+			bl.WithFields(logrus.Fields{
+				"slice_id":    slice.ID,
+				"target_node": string(slice.NodeID),
+			}).WithError(err).Warn("failed to delete old replica")
+		}
+	}
+	bl.WithFields(logrus.Fields{
+		"file_id": file.ID,
+		"moved":   len(movedSlices),
+	}).Info("file rebalanced")
+	return len(movedSlices), nil
+}
+
+// nodeUsage is the storage used and capacity of a node in bytes
+type nodeUsage struct {
+	node     blockchain.NodeH
+	used     float64
+	capacity float64
+}
+
+// nodesUsage estimates utilization of healthy nodes reported by heartbeats while slices are moved,
+// nodes of unknown capacity and draining nodes are not rebalanced
+type nodesUsage struct {
+	nodes     map[string]*nodeUsage
+	average   float64
+	threshold float64
+}
+
+func newNodesUsage(healthNodes blockchain.NodeHs, threshold float64) *nodesUsage {
+	u := &nodesUsage{
+		nodes:     make(map[string]*nodeUsage),
+		threshold: threshold,
+	}
+	var used, capacity float64
+	for _, n := range healthNodes {
+		if n.Node.Capacity == 0 || n.Node.Draining {
+			continue
+		}
+		nu := &nodeUsage{
+			node:     n,
+			used:     float64(n.Node.Capacity - n.Node.FreeSpace),
+			capacity: float64(n.Node.Capacity),
+		}
+		u.nodes[string(n.Node.ID)] = nu
+		used += nu.used
+		capacity += nu.capacity
+	}
+	if capacity > 0 {
+		u.average = used / capacity
+	}
+	return u
+}
+
+// over returns true if utilization of the node exceeds the average by the threshold
+func (u *nodesUsage) over(nodeID []byte) bool {
+	n, exist := u.nodes[string(nodeID)]
+	return exist && n.used/n.capacity > u.average+u.threshold
+}
+
+// under returns true if utilization of the node is below the average by the threshold
+func (u *nodesUsage) under(nodeID []byte) bool {
+	n, exist := u.nodes[string(nodeID)]
+	return exist && n.used/n.capacity < u.average-u.threshold
+}
+
+// overNodes returns nodes whose utilization exceeds the average by the threshold
+func (u *nodesUsage) overNodes() blockchain.NodeHs {
+	var nodes blockchain.NodeHs
+	for _, n := range u.nodes {
+		if n.used/n.capacity > u.average+u.threshold {
+			nodes = append(nodes, n.node)
+		}
+	}
+	return nodes
+}
+
+// underNodes returns nodes whose utilization is below the average by the threshold
+func (u *nodesUsage) underNodes() blockchain.NodeHs {
+	var nodes blockchain.NodeHs
+	for _, n := range u.nodes {
+		if n.used/n.capacity < u.average-u.threshold {
+			nodes = append(nodes, n.node)
+		}
+	}
+	return nodes
+}
+
+// move records a slice of length bytes moved from node from to node to
+func (u *nodesUsage) move(from, to []byte, length uint64) {
+	if n, exist := u.nodes[string(from)]; exist {
+		n.used -= float64(length)
+	}
+	if n, exist := u.nodes[string(to)]; exist {
+		n.used += float64(length)
+	}
+}
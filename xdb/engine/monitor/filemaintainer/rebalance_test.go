@@ -0,0 +1,231 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filemaintainer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/common"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor/soft"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/peer"
+)
+
+// healthyChain reports all nodes healthy, and applies slices updated to the file
+type healthyChain struct {
+	*fileChain
+}
+
+func (c healthyChain) GetNodeHealth(id []byte) (string, error) {
+	return blockchain.NodeHealthGood, nil
+}
+
+func (c healthyChain) SliceMigrateRecord(opt *blockchain.SliceMigrateOptions) error {
+	return nil
+}
+
+// usageNodes returns healthy nodes of capacity 100 bytes with storage used by ID
+func usageNodes(used map[string]uint64) blockchain.NodeHs {
+	var nodes blockchain.NodeHs
+	for id, u := range used {
+		n := blockchain.Node{ID: []byte(id), Online: true, Capacity: 100, FreeSpace: 100 - u}
+		nodes = append(nodes, blockchain.NodeH{Node: n, Health: blockchain.NodeHealthGood})
+	}
+	return nodes
+}
+
+func TestNodesUsage(t *testing.T) {
+	nodes := usageNodes(map[string]uint64{"n1": 90, "n2": 50, "n3": 50, "u1": 10, "u2": 10})
+	// nodes of unknown capacity and draining nodes are not rebalanced
+	nodes = append(nodes,
+		blockchain.NodeH{Node: blockchain.Node{ID: []byte("unknown"), Online: true}, Health: blockchain.NodeHealthGood},
+		blockchain.NodeH{Node: blockchain.Node{ID: []byte("draining"), Online: true, Capacity: 100, Draining: true},
+			Health: blockchain.NodeHealthGood})
+
+	// the average utilization is 0.42, over nodes exceed 0.52 and under nodes are below 0.32
+	usage := newNodesUsage(nodes, 0.1)
+	require.InDelta(t, 0.42, usage.average, 1e-9)
+	tests := []struct {
+		node        string
+		over, under bool
+	}{
+		{node: "n1", over: true},
+		{node: "n2"},
+		{node: "n3"},
+		{node: "u1", under: true},
+		{node: "u2", under: true},
+		{node: "unknown"},
+		{node: "draining"},
+		{node: "missing"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.over, usage.over([]byte(tt.node)), tt.node)
+		require.Equal(t, tt.under, usage.under([]byte(tt.node)), tt.node)
+	}
+	require.Len(t, usage.overNodes(), 1)
+	require.Len(t, usage.underNodes(), 2)
+
+	// nodes are relieved by slices moved away, and filled by slices moved in
+	usage.move([]byte("n1"), []byte("u1"), 20)
+	require.True(t, usage.over([]byte("n1")))
+	require.True(t, usage.under([]byte("u1")))
+	usage.move([]byte("n1"), []byte("u1"), 20)
+	require.False(t, usage.over([]byte("n1")))
+	require.False(t, usage.under([]byte("u1")))
+	require.Empty(t, usage.overNodes())
+	require.Len(t, usage.underNodes(), 1)
+	// moves from or to nodes not rebalanced are ignored
+	usage.move([]byte("unknown"), []byte("missing"), 20)
+	require.InDelta(t, 50, usage.nodes["n1"].used, 1e-9)
+
+	// nothing is over or under the average of nodes equally used
+	usage = newNodesUsage(usageNodes(map[string]uint64{"n1": 40, "n2": 40}), 0)
+	require.Empty(t, usage.overNodes())
+	require.Empty(t, usage.underNodes())
+}
+
+// newRebalancer returns a file maintainer moving replicas in memory, and the file with slices of plaintexts pushed
+func newRebalancer(t *testing.T, file blockchain.File, plaintexts map[string][]byte,
+	replicas map[string][]string, healthNodes blockchain.NodeHs) (*FileMaintainer, *fileChain) {
+	enc, err := soft.New(&config.SoftEncryptorConf{Password: "hello"})
+	require.NoError(t, err)
+	privkey, _, err := ecdsa.GenerateKeyPair()
+	require.NoError(t, err)
+	cp := &memCopier{replicas: make(map[string][]byte)}
+
+	nodesMap := make(map[string]blockchain.Node)
+	for _, n := range healthNodes {
+		nodesMap[string(n.Node.ID)] = n.Node
+	}
+	for i, id := range []string{"s1", "s2", "s3"} {
+		for _, nodeID := range replicas[id] {
+			node := nodesMap[nodeID]
+			es, storIndex, err := common.EncAndPush(context.Background(), cp, enc, plaintexts[id], id, "", file.ID, &node)
+			require.NoError(t, err)
+			slice := blockchain.PublicSliceMeta{
+				ID:         id,
+				CipherHash: es.CipherHash,
+				Length:     es.Length,
+				NodeID:     es.NodeID,
+				StorIndex:  storIndex,
+			}
+			if file.ErasureCoded() {
+				slice.Stripe = 0
+				slice.SliceIdx = i + 1
+			}
+			file.Slices = append(file.Slices, slice)
+		}
+	}
+
+	chain := &fileChain{file: file}
+	return &FileMaintainer{
+		localNode:          peer.Local{PrivateKey: privkey},
+		blockchain:         healthyChain{chain},
+		copier:             cp,
+		encryptor:          enc,
+		challenger:         merkleChallenger{},
+		challengerInterval: 3600 * 1e9,
+	}, chain
+}
+
+func TestRebalanceFile(t *testing.T) {
+	healthNodes := usageNodes(map[string]uint64{"n1": 90, "n2": 50, "n3": 50, "u1": 10, "u2": 10})
+	// slices of 4 bytes are sealed into 20 bytes
+	plaintexts := map[string][]byte{"s1": []byte("one."), "s2": []byte("two."), "s3": []byte("3rd.")}
+	file := blockchain.File{ID: "file", ExpireTime: time.Now().Add(time.Hour).UnixNano()}
+	replicas := map[string][]string{"s1": {"n1", "n2"}, "s2": {"n1", "n2"}, "s3": {"n1", "n2"}}
+	m, chain := newRebalancer(t, file, plaintexts, replicas, healthNodes)
+	file = chain.file
+
+	// n1 is relieved to 50 bytes used after two slices moved, below 0.52 of the average plus threshold,
+	// the third slice stays
+	usage := newNodesUsage(healthNodes, 0.1)
+	n, err := m.rebalanceFile(context.Background(), file, blockchain.Namespace{Replica: 2}, usage, healthNodes, 10)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, 1, chain.updates)
+	require.InDelta(t, 50, usage.nodes["n1"].used, 1e-9)
+	require.InDelta(t, 60, usage.nodes["u1"].used+usage.nodes["u2"].used, 1e-9)
+	require.False(t, usage.over([]byte("n1")))
+
+	holders := make(map[string][]string)
+	for _, s := range chain.file.Slices {
+		holders[s.ID] = append(holders[s.ID], string(s.NodeID))
+	}
+	moved := 0
+	for id, nodes := range holders {
+		require.Len(t, nodes, 2, id)
+		require.Contains(t, nodes, "n2", id)
+		if nodes[0] != "n1" && nodes[1] != "n1" {
+			moved++
+			require.Subset(t, []string{"n2", "u1", "u2"}, nodes, id)
+		}
+	}
+	require.Equal(t, 2, moved)
+	// old replicas are deleted
+	require.Len(t, m.copier.(*memCopier).replicas, 6)
+
+	// at most limit replicas are moved
+	m, chain = newRebalancer(t, blockchain.File{ID: "file", ExpireTime: file.ExpireTime}, plaintexts, replicas, healthNodes)
+	n, err = m.rebalanceFile(context.Background(), chain.file, blockchain.Namespace{Replica: 2},
+		newNodesUsage(healthNodes, 0.1), healthNodes, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	// nothing is moved if no replica is on over-utilized nodes
+	m, chain = newRebalancer(t, blockchain.File{ID: "file", ExpireTime: file.ExpireTime}, plaintexts,
+		map[string][]string{"s1": {"n2", "n3"}}, healthNodes)
+	n, err = m.rebalanceFile(context.Background(), chain.file, blockchain.Namespace{Replica: 2},
+		newNodesUsage(healthNodes, 0.1), healthNodes, 10)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	require.Equal(t, 0, chain.updates)
+}
+
+func TestRebalanceStripe(t *testing.T) {
+	healthNodes := usageNodes(map[string]uint64{"n1": 90, "n2": 50, "n3": 50, "u1": 10, "u2": 10})
+	plaintexts := map[string][]byte{"s1": []byte("one."), "s2": []byte("two."), "s3": []byte("par.")}
+	// a stripe of 2 data shards and 1 parity shard, one shard is held by the under-utilized node u1
+	file := blockchain.File{ID: "file", ExpireTime: time.Now().Add(time.Hour).UnixNano(), DataShards: 2, ParityShards: 1}
+	replicas := map[string][]string{"s1": {"n1"}, "s2": {"u1"}, "s3": {"n2"}}
+
+	// stripe holders are never picked as targets, so the shard on n1 is only moved to u2
+	for i := 0; i < 5; i++ {
+		m, chain := newRebalancer(t, file, plaintexts, replicas, healthNodes)
+		n, err := m.rebalanceFile(context.Background(), chain.file, blockchain.Namespace{}, newNodesUsage(healthNodes, 0.1),
+			healthNodes, 10)
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+		nodes := make(map[string]string)
+		for _, s := range chain.file.Slices {
+			nodes[s.ID] = string(s.NodeID)
+		}
+		require.Equal(t, map[string]string{"s1": "u2", "s2": "u1", "s3": "n2"}, nodes)
+	}
+
+	// no shard is moved if all under-utilized nodes hold shards of the stripe
+	replicas = map[string][]string{"s1": {"n1"}, "s2": {"u1"}, "s3": {"u2"}}
+	m, chain := newRebalancer(t, file, plaintexts, replicas, healthNodes)
+	n, err := m.rebalanceFile(context.Background(), chain.file, blockchain.Namespace{}, newNodesUsage(healthNodes, 0.1),
+		healthNodes, 10)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	require.Equal(t, 0, chain.updates)
+}
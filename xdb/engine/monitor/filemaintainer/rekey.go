@@ -0,0 +1,252 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filemaintainer
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
+	"github.com/sirupsen/logrus"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/common"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+var rl = logger.WithField("runner", "file rekey loop")
+
+// rekey re-encrypts replicas of slices with the current master key regularly, so that keys rotated can be retired.
+// A replica encrypted with an old key is pulled and decrypted, encrypted with the current key and pushed to
+// another healthy storage node, then the old replica is deleted once the file is updated on blockchain.
+// File content and structure are not re-encrypted, the key of the file is sealed by the current key instead,
+// and the file is updated with the key wrapped and the ID of the current key.
+func (m *FileMaintainer) rekey(ctx context.Context) {
+	pubkey := ecdsa.PublicKeyFromPrivateKey(m.localNode.PrivateKey)
+
+	defer rl.Info("file rekey stopped")
+
+	ticker := time.NewTicker(m.fileRekeyInterval)
+	defer ticker.Stop()
+
+	m.doneRekeyC = make(chan struct{})
+	defer close(m.doneRekeyC)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := m.encryptor.CurrentKeyID()
+		listNsOpt := blockchain.ListNsOptions{
+			Owner:   pubkey[:],
+			TimeEnd: time.Now().UnixNano(),
+		}
+		nsList, err := m.blockchain.ListFileNs(&listNsOpt)
+		if err != nil {
+			rl.WithError(err).Error("failed to find ns list")
+			continue
+		}
+		healthNodes, err := common.GetHealthNodes(m.blockchain)
+		if err != nil {
+			rl.WithError(err).Error("failed to find healthy nodes")
+			continue
+		}
+
+		rekeyed := 0
+		for _, ns := range nsList {
+			listFileOpt := blockchain.ListFileOptions{
+				Owner:       pubkey[:],
+				Namespace:   ns.Name,
+				TimeEnd:     time.Now().UnixNano(),
+				CurrentTime: time.Now().UnixNano(),
+			}
+			files, err := m.blockchain.ListFiles(&listFileOpt)
+			if err != nil {
+				rl.WithField("namespace", ns.Name).WithError(err).Error("failed to find file list")
+				continue
+			}
+			for _, file := range files {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+				n, err := m.rekeyFile(ctx, file, ns.Placement, current, healthNodes)
+				if err != nil {
+					rl.WithField("file_id", file.ID).WithError(err).Error("failed to rekey file")
+				}
+				rekeyed += n
+			}
+		}
+		rl.WithFields(logrus.Fields{
+			"current_key": current,
+			"rekeyed":     rekeyed,
+		}).Info("file rekey finished")
+	}
+}
+
+// rekeyFile re-encrypts replicas of the file's own slices which are not encrypted with the current key,
+// referenced slices are re-encrypted by their source files, and wraps the key of the file by the current key.
+// Returns the number of replicas re-encrypted.
+func (m *FileMaintainer) rekeyFile(ctx context.Context, file blockchain.File, placement *blockchain.PlacementPolicy,
+	current string, healthNodes blockchain.NodeHs) (int, error) {
+
+	var stale []blockchain.PublicSliceMeta
+	for _, slice := range file.OwnSlices() {
+		if slice.KeyID != current {
+			stale = append(stale, slice)
+		}
+	}
+	staleKey := file.KeyID != current
+	if len(stale) == 0 && !staleKey {
+		return 0, nil
+	}
+
+	challengeAlgorithm, pairingConf := m.challenger.GetChallengeConf()
+	nodesMap := common.ToNodeHsMap(healthNodes)
+	sourceID := hex.EncodeToString(file.Owner)
+
+	slices := file.Slices
+	var rekeyedSlices []encryptor.EncryptedSlice
+	var oldSlices []blockchain.PublicSliceMeta
+	for _, slice := range stale {
+		// replicas on unhealthy nodes are migrated first
+		node, exist := nodesMap[string(slice.NodeID)]
+		if !exist {
+			continue
+		}
+		plaintext, err := common.PullAndDec(ctx, m.copier, m.encryptor, slice, &node, file.ID)
+		if err != nil {
+			rl.WithFields(logrus.Fields{
+				"file_id":  file.ID,
+				"slice_id": slice.ID,
+			}).WithError(err).Warn("failed to recover slice")
+			continue
+		}
+
+		// the new replica is pushed to another node allowed by the placement policy,
+		// shards of the same stripe are kept on distinct nodes
+		excludes := sliceNodes(slices, slice.ID)
+		if file.ErasureCoded() {
+			excludes = stripeNodes(slices, slice.Stripe)
+		}
+		var holders []string
+		for _, n := range excludes {
+			if n != string(slice.NodeID) {
+				holders = append(holders, n)
+			}
+		}
+		newNodes, err := common.PlaceNewNodes(healthNodes, excludes, holders, placement)
+		if err != nil {
+			rl.WithFields(logrus.Fields{
+				"file_id":  file.ID,
+				"slice_id": slice.ID,
+			}).WithError(err).Warn("no node to re-encrypt slice to")
+			continue
+		}
+		for _, n := range newNodes {
+			es, storIndex, err := common.EncAndPush(ctx, m.copier, m.encryptor, plaintext, slice.ID, sourceID, file.ID, &n)
+			if err != nil {
+				rl.WithFields(logrus.Fields{
+					"slice_id":    slice.ID,
+					"target_node": string(n.ID),
+				}).WithError(err).Warn("failed to push re-encrypted slice")
+				continue
+			}
+			if challengeAlgorithm == types.PairingChallengeAlgorithm {
+				slices, _ = m.rearrangeSlices(slices, slice.ID, storIndex, string(slice.NodeID), es)
+			} else {
+				slices = append(removeSlice(slices, slice), blockchain.PublicSliceMeta{
+					ID:         es.SliceID,
+					CipherHash: es.CipherHash,
+					Length:     es.Length,
+					NodeID:     es.NodeID,
+					StorIndex:  storIndex,
+					Stripe:     slice.Stripe,
+					KeyID:      es.KeyID,
+				})
+			}
+			rekeyedSlices = append(rekeyedSlices, es)
+			oldSlices = append(oldSlices, slice)
+			break
+		}
+	}
+	if len(stale) != 0 && len(rekeyedSlices) == 0 && !staleKey {
+		return 0, errorx.New(errorx.ErrCodeInternal, "no slice re-encrypted, %d replicas left", len(stale))
+	}
+
+	// challenge materials for the new replicas
+	interval := m.challengerInterval
+	if len(rekeyedSlices) != 0 && challengeAlgorithm == types.MerkleChallengeAlgorithm {
+		if err := common.AddSlicesNewMerkleChallenge(m.challenger, file, rekeyedSlices, interval, rl); err != nil {
+			return 0, errorx.Wrap(err, "failed to add slices merkle challenge material")
+		}
+	}
+	if len(rekeyedSlices) != 0 && challengeAlgorithm == types.PairingChallengeAlgorithm {
+		file.Slices = slices
+		if err := common.AddSlicesNewPairingChallenge(ctx, pairingConf, m.copier, rekeyedSlices, file, m.blockchain,
+			hex.EncodeToString(file.Owner), interval, time.Now().UnixNano(), file.ExpireTime, nil, rl); err != nil {
+			return 0, errorx.Wrap(err, "failed to add slices pairing challenge material")
+		}
+	}
+
+	opt := blockchain.UpdateFilePSMOptions{
+		FileID: file.ID,
+		Owner:  file.Owner,
+		Slices: slices,
+	}
+	if staleKey {
+		keyID, wrappedKey, err := m.encryptor.WrapFileKey(file.KeyID, file.ID, file.WrappedKey)
+		if err != nil {
+			return 0, errorx.Wrap(err, "failed to wrap the key of file")
+		}
+		opt.KeyID, opt.WrappedKey = keyID, wrappedKey
+	}
+	if err := m.updateFileOnChain(&opt); err != nil {
+		return 0, errorx.Wrap(err, "failed to update file on chain")
+	}
+
+	// old replicas are no longer recorded on blockchain
+	for _, slice := range oldSlices {
+		node := nodesMap[string(slice.NodeID)]
+		if err := m.copier.Delete(ctx, slice.ID, slice.StorIndex, &node); err != nil {
+			rl.WithFields(logrus.Fields{
+				"slice_id":    slice.ID,
+				"target_node": string(slice.NodeID),
+			}).WithError(err).Warn("failed to delete old replica")
+		}
+	}
+	rl.WithFields(logrus.Fields{
+		"file_id":  file.ID,
+		"rekeyed":  len(rekeyedSlices),
+		"replicas": len(stale),
+		"file_key": staleKey,
+	}).Info("file rekeyed")
+	return len(rekeyedSlices), nil
+}
+
+// sliceNodes returns storage nodes holding replicas of the slice
+func sliceNodes(sliceMetas []blockchain.PublicSliceMeta, sliceID string) []string {
+	var nodes []string
+	for node := range nodeSliceMap(sliceMetas, sliceID) {
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filemaintainer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	ctype "github.com/PaddlePaddle/PaddleDTX/xdb/engine/challenger/merkle/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/common"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor/soft"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/peer"
+)
+
+// memCopier keeps replicas in memory by node ID and storage index
+type memCopier struct {
+	Copier
+	replicas map[string][]byte
+}
+
+func (c *memCopier) Push(ctx context.Context, id, sourceID, fileID string, r io.Reader, node *blockchain.Node) (string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	c.replicas[string(node.ID)+"/"+id] = data
+	return id, nil
+}
+
+func (c *memCopier) Pull(ctx context.Context, id, storIndex, fileID string, node *blockchain.Node) (io.ReadCloser, error) {
+	data, exist := c.replicas[string(node.ID)+"/"+storIndex]
+	if !exist {
+		return nil, errorx.New(errorx.ErrCodeNotFound, "replica not found")
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (c *memCopier) Delete(ctx context.Context, id, storIndex string, node *blockchain.Node) error {
+	delete(c.replicas, string(node.ID)+"/"+storIndex)
+	return nil
+}
+
+// merkleChallenger generates no challenge material
+type merkleChallenger struct {
+	Challenger
+}
+
+func (c merkleChallenger) Setup(sliceData []byte, rangeAmount int) ([]ctype.RangeHash, error) {
+	return nil, nil
+}
+
+func (c merkleChallenger) Save(cms []ctype.Material) error {
+	return nil
+}
+
+func (c merkleChallenger) GetChallengeConf() (string, types.PairingChallengeConf) {
+	return types.MerkleChallengeAlgorithm, types.PairingChallengeConf{}
+}
+
+// fileChain applies slices and keys updated to the file
+type fileChain struct {
+	Blockchain
+	file    blockchain.File
+	updates int
+}
+
+func (c *fileChain) UpdateFilePublicSliceMeta(opt *blockchain.UpdateFilePSMOptions) error {
+	c.file.Slices = opt.Slices
+	if len(opt.WrappedKey) != 0 {
+		c.file.KeyID = opt.KeyID
+		c.file.WrappedKey = opt.WrappedKey
+	}
+	c.updates++
+	return nil
+}
+
+func TestRekeyFile(t *testing.T) {
+	keys := []config.SoftEncryptorKey{{ID: "2021", Password: "hello"}, {ID: "2022", Password: "world"}}
+	old, err := soft.New(&config.SoftEncryptorConf{CurrentKey: "2021", Keys: keys})
+	require.NoError(t, err)
+	enc, err := soft.New(&config.SoftEncryptorConf{CurrentKey: "2022", Keys: keys})
+	require.NoError(t, err)
+	privkey, pubkey, err := ecdsa.GenerateKeyPair()
+	require.NoError(t, err)
+
+	var healthNodes blockchain.NodeHs
+	nodesMap := make(map[string]blockchain.Node)
+	for _, id := range []string{"n1", "n2", "n3"} {
+		n := blockchain.Node{ID: []byte(id), Online: true}
+		healthNodes = append(healthNodes, blockchain.NodeH{Node: n, Health: blockchain.NodeHealthGood})
+		nodesMap[id] = n
+	}
+
+	// a file published with the old key, with a slice referenced from another file
+	fileID := "file"
+	cp := &memCopier{replicas: make(map[string][]byte)}
+	plaintexts := map[string][]byte{"s1": []byte("first slice"), "s2": []byte("second slice")}
+	file := blockchain.File{
+		ID:         fileID,
+		Owner:      pubkey[:],
+		ExpireTime: 1 << 62,
+		KeyID:      old.CurrentKeyID(),
+	}
+	for id, node := range map[string]string{"s1": "n1", "s2": "n2"} {
+		n := nodesMap[node]
+		es, storIndex, err := common.EncAndPush(context.Background(), cp, old, plaintexts[id], id, "", fileID, &n)
+		require.NoError(t, err)
+		file.Slices = append(file.Slices, blockchain.PublicSliceMeta{
+			ID:         id,
+			CipherHash: es.CipherHash,
+			Length:     es.Length,
+			NodeID:     es.NodeID,
+			StorIndex:  storIndex,
+			KeyID:      es.KeyID,
+		})
+	}
+	referenced := blockchain.PublicSliceMeta{ID: "s3", NodeID: []byte("n3"), SourceFileID: "source", KeyID: "2021"}
+	file.Slices = append(file.Slices, referenced)
+	structure, err := old.Encrypt(bytes.NewReader([]byte("structure")), &encryptor.EncryptOptions{FileID: fileID})
+	require.NoError(t, err)
+	file.Structure = structure.CipherText
+
+	chain := &fileChain{file: file}
+	m := &FileMaintainer{
+		localNode:          peer.Local{PrivateKey: privkey},
+		blockchain:         chain,
+		copier:             cp,
+		encryptor:          enc,
+		challenger:         merkleChallenger{},
+		challengerInterval: 3600 * 1e9,
+	}
+	n, err := m.rekeyFile(context.Background(), file, nil, enc.CurrentKeyID(), healthNodes)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, 1, chain.updates)
+
+	// own slices and the key of the file are moved to the current key, referenced slices are left to the source file
+	rekeyed := chain.file
+	require.Equal(t, "2022", rekeyed.KeyID)
+	require.NotEmpty(t, rekeyed.WrappedKey)
+	require.Len(t, rekeyed.Slices, 3)
+	require.Contains(t, rekeyed.Slices, referenced)
+	require.Len(t, cp.replicas, 2)
+
+	// the file is read without the old key
+	retired, err := soft.New(&config.SoftEncryptorConf{CurrentKey: "2022", Keys: keys[1:]})
+	require.NoError(t, err)
+	raw, err := retired.Recover(bytes.NewReader(rekeyed.Structure),
+		&encryptor.RecoverOptions{FileID: fileID, KeyID: rekeyed.KeyID, WrappedKey: rekeyed.WrappedKey})
+	require.NoError(t, err)
+	require.Equal(t, "structure", string(raw))
+	for _, slice := range rekeyed.OwnSlices() {
+		require.Equal(t, "2022", slice.KeyID)
+		node := nodesMap[string(slice.NodeID)]
+		plain, err := common.PullAndDec(context.Background(), cp, retired, slice, &node, fileID)
+		require.NoError(t, err)
+		require.Equal(t, plaintexts[slice.ID], plain)
+	}
+
+	// nothing is done for files rekeyed already
+	n, err = m.rekeyFile(context.Background(), rekeyed, nil, enc.CurrentKeyID(), healthNodes)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	require.Equal(t, 1, chain.updates)
+
+	// the key of a file with referenced slices only is wrapped as well
+	chain.file = blockchain.File{ID: "ref", Owner: pubkey[:], KeyID: "2021", Slices: []blockchain.PublicSliceMeta{referenced}}
+	n, err = m.rekeyFile(context.Background(), chain.file, nil, enc.CurrentKeyID(), healthNodes)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	require.Equal(t, "2022", chain.file.KeyID)
+	require.NotEmpty(t, chain.file.WrappedKey)
+}
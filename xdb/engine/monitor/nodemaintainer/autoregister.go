@@ -31,9 +31,21 @@ func (m *NodeMaintainer) autoRegister() error {
 
 	pubkey := ecdsa.PublicKeyFromPrivateKey(m.localNode.PrivateKey)
 	node, err := m.blockchain.GetNode([]byte(pubkey.String()))
+	if err == nil && (node.Region != m.localNode.Region || node.Zone != m.localNode.Zone || node.Rack != m.localNode.Rack) {
+		// labels are registered with the node only once
+		logrus.WithFields(logrus.Fields{
+			"region": node.Region,
+			"zone":   node.Zone,
+			"rack":   node.Rack,
+		}).Warn("labels configured differ from the ones registered on blockchain, which are kept")
+	}
 	if err == nil && node.Online {
 		logrus.Info("node already registered on blockchain")
 		return nil
+	} else if err == nil && node.Draining {
+		// a drained node is kept offline until set online manually
+		logrus.Warn("node drained and offline on blockchain")
+		return nil
 	} else if err == nil && !node.Online {
 		// get sig message
 		nodeOpts := &blockchain.NodeOperateOptions{
@@ -67,6 +79,9 @@ func (m *NodeMaintainer) autoRegister() error {
 				Online:   true,
 				RegTime:  timestamp,
 				UpdateAt: timestamp,
+				Region:   m.localNode.Region,
+				Zone:     m.localNode.Zone,
+				Rack:     m.localNode.Rack,
 			},
 		}
 		msg, err := util.GetSigMessage(opt)
@@ -0,0 +1,131 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodemaintainer
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+const (
+	deletedSliceClearInterval = 10 * time.Minute
+	// deletedSliceClearDelay leaves recently deleted files alone,
+	// so that deletions in blocks not yet synchronized by the node are not skipped
+	deletedSliceClearDelay = 10 * time.Minute
+)
+
+// deletedClearSpace is the name space to derive the key storing progress of deletedSliceClear
+var deletedClearSpace = uuid.MustParse("0c8e3b56-2f7d-4a19-8e64-b9d1c7a5f203")
+
+// deletedSliceClear removes slices of files deleted by owners, it does not wait for
+// the file retain period as sliceClear does, since the owner asks for deletion explicitly
+func (m *NodeMaintainer) deletedSliceClear(ctx context.Context) {
+	pubkey := ecdsa.PublicKeyFromPrivateKey(m.localNode.PrivateKey)
+	clearKey := uuid.NewSHA1(deletedClearSpace, pubkey[:]).String()
+
+	l := logger.WithField("runner", "deleted slice clear loop")
+	node, err := m.blockchain.GetNode([]byte(pubkey.String()))
+	if err != nil {
+		l.WithError(err).Warn("failed to get node info")
+		return
+	}
+	defer l.Info("deleted slice clear stopped")
+
+	ticker := time.NewTicker(deletedSliceClearInterval)
+	defer ticker.Stop()
+
+	m.doneDeletedClearC = make(chan struct{})
+	defer close(m.doneDeletedClearC)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		startTime, err := m.getDeletedClearTime(clearKey, node.RegTime)
+		if err != nil {
+			l.WithError(err).Warn("failed to get deleted slice clear time")
+			continue
+		}
+		endTime := time.Now().Add(-deletedSliceClearDelay).UnixNano()
+		if endTime <= startTime {
+			continue
+		}
+
+		opt := &blockchain.ListNodeSliceOptions{
+			Target:    []byte(pubkey.String()),
+			StartTime: startTime,
+			EndTime:   endTime,
+		}
+		sliceList, err := m.blockchain.ListNodesDeletedSlice(opt)
+		if err != nil {
+			l.WithError(err).Warn("failed to get deleted slice")
+			continue
+		}
+		var deleteSlices []string
+		var deleteErr error
+		for _, slice := range sliceList {
+			if deleteErr = m.removeSlice(slice[0], slice[1]); deleteErr != nil {
+				break
+			}
+			deleteSlices = append(deleteSlices, slice[0])
+		}
+		if deleteErr != nil {
+			l.WithError(deleteErr).Warn("failed to delete node slice")
+			continue
+		}
+
+		r := bytes.NewBufferString(strconv.FormatInt(endTime, 10))
+		if err := m.proveStorage.SaveAndUpdate(clearKey, r); err != nil {
+			l.WithError(err).Warn("failed to update deleted slice clear time")
+		}
+		if len(deleteSlices) == 0 {
+			continue
+		}
+		l.WithFields(logrus.Fields{
+			"start_time":     time.Unix(0, startTime).Format("2006-01-02 15:04:05"),
+			"end_time":       time.Unix(0, endTime).Format("2006-01-02 15:04:05"),
+			"dslice_id_list": strings.Join(deleteSlices, ","),
+		}).Info("successfully cleared slice of deleted files")
+	}
+}
+
+// getDeletedClearTime returns the time until which slices of deleted files have been cleared,
+// regTime is returned if none has been cleared
+func (m *NodeMaintainer) getDeletedClearTime(clearKey string, regTime int64) (int64, error) {
+	if exist, _ := m.proveStorage.Exist(clearKey); !exist {
+		return regTime, nil
+	}
+	ftime, err := m.proveStorage.LoadStr(clearKey)
+	if err != nil {
+		return 0, errorx.Wrap(err, "failed to load deleted slice clear time")
+	}
+	startTime, err := strconv.ParseInt(ftime, 10, 64)
+	if err != nil {
+		return 0, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to parse deleted slice clear time")
+	}
+	return startTime, nil
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodemaintainer
+
+import (
+	"context"
+	"expvar"
+	"math"
+	"time"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	util "github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/strings"
+)
+
+// drainCheckInterval is the interval a draining node counts slices remaining on it
+const drainCheckInterval = time.Minute * 30
+
+// drainStats is published as metric "drain", the number of slices remaining on the draining node
+var drainStats = expvar.NewMap("drain")
+
+// drain checks regularly whether the node is draining, and counts slices remaining on it,
+// which dataOwners are migrating to other nodes. The node is set offline once no slice remains.
+// Slices are counted from those tracked by scrub and those indexed for the node on blockchain,
+// so that the node is never set offline if scrubbing is not enabled
+func (m *NodeMaintainer) drain(ctx context.Context) {
+	l := logger.WithField("runner", "drain loop")
+	defer l.Info("drain stopped")
+
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+
+	m.doneDrainC = make(chan struct{})
+	defer close(m.doneDrainC)
+
+	nodeID := ecdsa.PublicKeyFromPrivateKey(m.localNode.PrivateKey).String()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		node, err := m.blockchain.GetNode([]byte(nodeID))
+		if err != nil {
+			l.WithError(err).Warn("failed to get node info")
+			continue
+		}
+		if !node.Draining || !node.Online {
+			continue
+		}
+		if m.scrubber == nil {
+			l.Warn("node draining, slices are not tracked without scrub, set the node offline manually once migrated")
+			continue
+		}
+
+		remaining, err := m.countRemainingSlices(ctx, nodeID)
+		if err != nil {
+			l.WithError(err).Warn("failed to count slices remaining on draining node")
+			continue
+		}
+		v := new(expvar.Int)
+		v.Set(int64(remaining))
+		drainStats.Set("remaining", v)
+		if remaining > 0 {
+			l.WithField("remaining", remaining).Info("node draining, slices remain to be migrated")
+			continue
+		}
+
+		if err := m.nodeOffline(nodeID); err != nil {
+			l.WithError(err).Warn("failed to set drained node offline")
+			continue
+		}
+		l.Info("node drained and set offline")
+	}
+}
+
+// countRemainingSlices counts slices on the node still referenced by unexpired files on blockchain.
+// Slices pushed recently are counted, the files they belong to may not be published yet,
+// and so are slices indexed for the node but not tracked as long as they are stored
+func (m *NodeMaintainer) countRemainingSlices(ctx context.Context, nodeID string) (int, error) {
+	now := time.Now().UnixNano()
+	indexed, err := m.blockchain.ListNodesExpireSlice(&blockchain.ListNodeSliceOptions{
+		Target:    []byte(nodeID),
+		StartTime: now,
+		EndTime:   math.MaxInt64,
+	})
+	if err != nil {
+		return 0, errorx.Wrap(err, "failed to list slices of node")
+	}
+	indexedSlices := make(map[string]struct{}, len(indexed))
+	for _, s := range indexed {
+		indexedSlices[s[0]] = struct{}{}
+	}
+
+	remaining := 0
+	tracked := make(map[string]struct{})
+	after := ""
+	for {
+		list, err := m.scrubber.List(after, scrubBatchSize)
+		if err != nil {
+			return 0, errorx.Wrap(err, "failed to list slices tracked")
+		}
+		if len(list) == 0 {
+			break
+		}
+		after = list[len(list)-1].SliceID
+
+		files := make(map[string]blockchain.File)
+		for _, s := range list {
+			select {
+			case <-ctx.Done():
+				return 0, errorx.New(errorx.ErrCodeInternal, "context is canceled")
+			default:
+			}
+			tracked[s.SliceID] = struct{}{}
+			if now-s.PushedAt < scrubGracePeriod.Nanoseconds() {
+				remaining++
+				continue
+			}
+			referenced, err := m.sliceReferenced(s, nodeID, indexedSlices, files)
+			if err != nil {
+				return 0, err
+			}
+			// slices of expired files are not migrated, and cleared once out of the retain period
+			if file, exist := files[s.FileID]; !referenced || (exist && file.ExpireTime <= now) {
+				continue
+			}
+			remaining++
+		}
+	}
+
+	for _, s := range indexed {
+		if _, exist := tracked[s[0]]; exist {
+			continue
+		}
+		exist, err := m.sliceStorage.Exist(s[0], s[1])
+		if err != nil {
+			return 0, errorx.Wrap(err, "failed to check slice existence")
+		}
+		if exist {
+			remaining++
+		}
+	}
+	return remaining, nil
+}
+
+// nodeOffline sets the node offline on blockchain
+func (m *NodeMaintainer) nodeOffline(nodeID string) error {
+	nodeOpts := &blockchain.NodeOperateOptions{
+		NodeID: []byte(nodeID),
+		Nonce:  time.Now().UnixNano(),
+	}
+	msg, err := util.GetSigMessage(nodeOpts)
+	if err != nil {
+		return errorx.Internal(err, "failed to get the message to sign")
+	}
+	sig, err := ecdsa.Sign(m.localNode.PrivateKey, hash.HashUsingSha256([]byte(msg)))
+	if err != nil {
+		return errorx.Wrap(err, "failed to sign")
+	}
+	nodeOpts.Signature = sig[:]
+	return m.blockchain.NodeOffline(nodeOpts)
+}
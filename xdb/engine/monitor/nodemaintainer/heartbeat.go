@@ -53,6 +53,14 @@ func (m *NodeMaintainer) heartbeat(ctx context.Context) {
 			CurrentTime:   timestamp,
 			BeginningTime: common.TodayBeginning(timestamp),
 		}
+		// report capacity so that dataOwner nodes avoid selecting nodes running out of space
+		total, free, err := m.sliceStorage.Capacity()
+		if err != nil {
+			l.WithError(err).Warn("failed to get storage capacity")
+		} else {
+			opt.Capacity = total
+			opt.FreeSpace = free
+		}
 		msg, err := util.GetSigMessage(opt)
 		if err != nil {
 			l.WithError(err).Warn("failed to get the message to sign for heartbeat")
@@ -73,6 +81,8 @@ func (m *NodeMaintainer) heartbeat(ctx context.Context) {
 		l.WithFields(logrus.Fields{
 			"target_node": hex.EncodeToString(pubkey[:4]),
 			"update_at":   timestamp,
+			"capacity":    opt.Capacity,
+			"free_space":  opt.FreeSpace,
 		}).Info("successfully updated heartbeat of node")
 	}
 
@@ -22,11 +22,16 @@ import (
 
 	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/upload"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/peer"
 )
 
 const (
 	defaultFileClearInterval = time.Hour * 24
+	defaultScrubInterval     = time.Hour * 24
+	defaultOrphanRetain      = time.Hour * 72
 )
 
 var (
@@ -37,14 +42,18 @@ type Blockchain interface {
 	AddNode(opt *blockchain.AddNodeOptions) error
 	GetNode(id []byte) (blockchain.Node, error)
 	NodeOnline(opt *blockchain.NodeOperateOptions) error
+	NodeOffline(opt *blockchain.NodeOperateOptions) error
 	Heartbeat(opt *blockchain.NodeHeartBeatOptions) error
 	ListNodesExpireSlice(opt *blockchain.ListNodeSliceOptions) ([][2]string, error)
+	ListNodesDeletedSlice(opt *blockchain.ListNodeSliceOptions) ([][2]string, error)
+	GetFileByID(id string) (blockchain.File, error)
 }
 type SliceStorage interface {
 	Load(key string, index string) (io.ReadCloser, error)
 	Exist(key string, index string) (bool, error)
 	Delete(key string, index string) error
 	LoadStr(key string, index string) (string, error)
+	Capacity() (uint64, uint64, error)
 }
 
 // ProveStorage is Storage interface used in `Replication Holding Proof` process
@@ -57,6 +66,20 @@ type ProveStorage interface {
 	SaveAndUpdate(key string, value io.Reader) error
 }
 
+// Quota records the storage used by dataOwner nodes, slices removed are released from it
+type Quota interface {
+	Release(sliceID string) error
+}
+
+// Scrub tracks slices stored with the files they belong to, and records the results of scrubbing them
+// and whether they are orphaned, slices removed are untracked
+type Scrub interface {
+	Untrack(sliceID string) error
+	List(after string, limit int) ([]types.SliceScrub, error)
+	Report(sliceID, status string) error
+	MarkOrphan(sliceID string, orphaned bool) (types.SliceScrub, error)
+}
+
 type NewNodeMaintainerOptions struct {
 	LocalNode peer.Local
 
@@ -64,6 +87,13 @@ type NewNodeMaintainerOptions struct {
 
 	SliceStorage SliceStorage
 	ProveStorage ProveStorage
+	Quota        Quota // nil if quota is not enabled
+
+	// Scrub is nil if scrubbing is not enabled, slices are scrubbed every ScrubInterval,
+	// reading at most ScrubRate bytes per second, zero means unlimited
+	Scrub         Scrub
+	ScrubInterval time.Duration
+	ScrubRate     int64
 }
 
 // NodeMaintainer runs if local node is storage-node, and its main work is to clean expired encrypted slices
@@ -75,13 +105,26 @@ type NodeMaintainer struct {
 
 	sliceStorage SliceStorage
 	proveStorage ProveStorage
+	quota        Quota
+	scrubber     Scrub
 
 	heartbeatInterval  time.Duration
 	fileClearInterval  time.Duration
 	fileRetainInterval time.Duration
+	scrubInterval      time.Duration
+	scrubRate          int64
+
+	orphanClearInterval time.Duration // zero if orphan clear is disabled
+	orphanRetainPeriod  time.Duration
+	orphanDryRun        bool
 
 	doneHbC         chan struct{} //doneHbC will be closed when loop breaks
 	doneSliceClearC chan struct{} //doneSliceClearC will be closed when loop breaks
+
+	doneDeletedClearC chan struct{} //doneDeletedClearC will be closed when loop breaks
+	doneScrubC        chan struct{} //doneScrubC will be closed when loop breaks
+	doneOrphanClearC  chan struct{} //doneOrphanClearC will be closed when loop breaks
+	doneDrainC        chan struct{} //doneDrainC will be closed when loop breaks
 }
 
 func New(conf *config.MonitorConf, opt *NewNodeMaintainerOptions) (*NodeMaintainer, error) {
@@ -90,11 +133,29 @@ func New(conf *config.MonitorConf, opt *NewNodeMaintainerOptions) (*NodeMaintain
 	if fileClearInterval == 0 {
 		fileClearInterval = defaultFileClearInterval
 	}
+	scrubInterval := opt.ScrubInterval
+	if scrubInterval == 0 {
+		scrubInterval = defaultScrubInterval
+	}
+	orphanClearInterval := time.Duration(int64(conf.OrphanclearInterval)) * time.Hour
+	orphanRetainPeriod := time.Duration(int64(conf.OrphanRetainPeriod)) * time.Hour
+	if orphanRetainPeriod == 0 {
+		orphanRetainPeriod = defaultOrphanRetain
+	}
+	// slices of unfinished upload sessions are orphans until the file is published
+	if orphanRetainPeriod <= upload.MaxSessionLifetime {
+		return nil, errorx.New(errorx.ErrCodeConfig, "orphanRetainPeriod must be longer than %v, the lifetime of upload sessions",
+			upload.MaxSessionLifetime)
+	}
 
 	logger.WithFields(logrus.Fields{
-		"heartbeat-interval":  heartbeatInterval,
-		"fileclear-interval":  fileClearInterval,
-		"fileretain-interval": blockchain.FileRetainPeriod,
+		"heartbeat-interval":   heartbeatInterval,
+		"fileclear-interval":   fileClearInterval,
+		"fileretain-interval":  blockchain.FileRetainPeriod,
+		"scrub-enabled":        opt.Scrub != nil,
+		"orphanclear-interval": orphanClearInterval,
+		"orphanretain-period":  orphanRetainPeriod,
+		"orphanclear-dryrun":   conf.OrphanclearDryRun,
 	}).Info("monitor initialize...")
 
 	mm := &NodeMaintainer{
@@ -102,9 +163,17 @@ func New(conf *config.MonitorConf, opt *NewNodeMaintainerOptions) (*NodeMaintain
 		blockchain:         opt.Blockchain,
 		sliceStorage:       opt.SliceStorage,
 		proveStorage:       opt.ProveStorage,
+		quota:              opt.Quota,
+		scrubber:           opt.Scrub,
 		heartbeatInterval:  heartbeatInterval,
 		fileClearInterval:  fileClearInterval,
 		fileRetainInterval: blockchain.FileRetainPeriod,
+		scrubInterval:      scrubInterval,
+		scrubRate:          opt.ScrubRate,
+
+		orphanClearInterval: orphanClearInterval,
+		orphanRetainPeriod:  orphanRetainPeriod,
+		orphanDryRun:        conf.OrphanclearDryRun,
 	}
 
 	return mm, nil
@@ -137,24 +206,61 @@ func (m *NodeMaintainer) NodeAutoRegister() error {
 	return m.autoRegister()
 }
 
-// StartFileClear starts task to clear files
+// StartFileClear starts task to clear files, and orphan slices if enabled
 func (m *NodeMaintainer) StartFileClear(ctx context.Context) {
 	go m.sliceClear(ctx)
+	go m.deletedSliceClear(ctx)
+	if m.orphanClearInterval > 0 {
+		if m.scrubber == nil {
+			logger.Warn("orphan clear requires slices tracked by scrub, which is not enabled")
+		} else {
+			go m.orphanClear(ctx)
+		}
+	}
 }
 
 // StopFileClear stops task clearing files
 func (m *NodeMaintainer) StopFileClear() {
-	if m.doneSliceClearC == nil {
-		return
+	logger.Info("stops task clearing files ...")
+
+	if m.doneSliceClearC != nil {
+		<-m.doneSliceClearC
 	}
+	if m.doneDeletedClearC != nil {
+		<-m.doneDeletedClearC
+	}
+	if m.doneOrphanClearC != nil {
+		<-m.doneOrphanClearC
+	}
+}
 
-	logger.Info("stops task clearing files ...")
+// StartScrub starts task to scrub slices stored if scrubbing is enabled
+func (m *NodeMaintainer) StartScrub(ctx context.Context) {
+	if m.scrubber == nil {
+		return
+	}
+	go m.scrub(ctx)
+}
 
-	select {
-	case <-m.doneSliceClearC:
+// StopScrub stops task scrubbing slices
+func (m *NodeMaintainer) StopScrub() {
+	if m.doneScrubC == nil {
 		return
-	default:
 	}
+	logger.Info("stops task scrubbing slices ...")
+	<-m.doneScrubC
+}
 
-	<-m.doneSliceClearC
+// StartDrain starts task to set the node offline once drained
+func (m *NodeMaintainer) StartDrain(ctx context.Context) {
+	go m.drain(ctx)
+}
+
+// StopDrain stops task checking whether the node is drained
+func (m *NodeMaintainer) StopDrain() {
+	if m.doneDrainC == nil {
+		return
+	}
+	logger.Info("stops task checking node drained ...")
+	<-m.doneDrainC
 }
@@ -0,0 +1,175 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodemaintainer
+
+import (
+	"context"
+	"expvar"
+	"math"
+	"time"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
+	"github.com/sirupsen/logrus"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// orphanStats is published as metric "orphan", counting orphan slices found and those cleared
+var orphanStats = expvar.NewMap("orphan")
+
+// orphanClear clears slices not referenced by any file on blockchain, such as slices of files failed to publish
+// and those migrated to other nodes. Slices are compared with the files they are pushed for, and slices
+// of files not found are compared with the index of slices stored by the node on blockchain,
+// in case they are referenced by other files. An orphan slice is cleared once orphaned for the retain period,
+// or only reported in dry-run mode. Slices not tracked by scrub are never cleared
+func (m *NodeMaintainer) orphanClear(ctx context.Context) {
+	l := logger.WithField("runner", "orphan clear loop")
+	defer l.Info("orphan clear stopped")
+
+	ticker := time.NewTicker(m.orphanClearInterval)
+	defer ticker.Stop()
+
+	m.doneOrphanClearC = make(chan struct{})
+	defer close(m.doneOrphanClearC)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		m.clearOrphans(ctx, l)
+	}
+}
+
+// clearOrphans checks all the slices tracked in batches, and clears those orphaned for the retain period
+func (m *NodeMaintainer) clearOrphans(ctx context.Context, l *logrus.Entry) {
+	nodeID := ecdsa.PublicKeyFromPrivateKey(m.localNode.PrivateKey).String()
+
+	// all the slices indexed for the node on blockchain, including those of files expired but not cleared
+	indexed, err := m.blockchain.ListNodesExpireSlice(&blockchain.ListNodeSliceOptions{
+		Target:    []byte(nodeID),
+		StartTime: 0,
+		EndTime:   math.MaxInt64,
+	})
+	if err != nil {
+		l.WithError(err).Warn("failed to list slices of node")
+		return
+	}
+	indexedSlices := make(map[string]struct{}, len(indexed))
+	for _, s := range indexed {
+		indexedSlices[s[0]] = struct{}{}
+	}
+
+	var found, orphans, cleared int
+	after := ""
+	for {
+		list, err := m.scrubber.List(after, scrubBatchSize)
+		if err != nil {
+			l.WithError(err).Warn("failed to list slices tracked")
+			return
+		}
+		if len(list) == 0 {
+			break
+		}
+		after = list[len(list)-1].SliceID
+
+		files := make(map[string]blockchain.File)
+		for _, s := range list {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			referenced, err := m.sliceReferenced(s, nodeID, indexedSlices, files)
+			if err != nil {
+				l.WithField("slice_id", s.SliceID).WithError(err).Warn("failed to check slice references")
+				continue
+			}
+			orphanedAt := s.OrphanedAt
+			if referenced != (orphanedAt == 0) {
+				r, err := m.scrubber.MarkOrphan(s.SliceID, !referenced)
+				if err != nil {
+					l.WithField("slice_id", s.SliceID).WithError(err).Warn("failed to mark orphan slice")
+					continue
+				}
+				orphanedAt = r.OrphanedAt
+				if !referenced {
+					found++
+					orphanStats.Add("found", 1)
+				}
+			}
+			if referenced {
+				continue
+			}
+
+			orphans++
+			fields := logrus.Fields{
+				"slice_id":    s.SliceID,
+				"file_id":     s.FileID,
+				"owner":       s.Owner,
+				"orphaned_at": time.Unix(0, orphanedAt).Format("2006-01-02 15:04:05"),
+			}
+			if time.Now().UnixNano()-orphanedAt < m.orphanRetainPeriod.Nanoseconds() {
+				continue
+			}
+			if m.orphanDryRun {
+				l.WithFields(fields).Info("orphan slice to clear, skipped in dry-run mode")
+				continue
+			}
+			if err := m.removeSlice(s.SliceID, s.StorIndex); err != nil {
+				l.WithFields(fields).WithError(err).Warn("failed to clear orphan slice")
+				continue
+			}
+			cleared++
+			orphanStats.Add("cleared", 1)
+			l.WithFields(fields).Debug("orphan slice cleared")
+		}
+	}
+
+	l.WithFields(logrus.Fields{
+		"found":   found,
+		"orphans": orphans,
+		"cleared": cleared,
+		"dry_run": m.orphanDryRun,
+	}).Info("orphan slices checked")
+}
+
+// sliceReferenced returns true if the slice stored by the node is referenced by the file it is pushed for,
+// or by any file indexed on blockchain if the file is not found
+func (m *NodeMaintainer) sliceReferenced(s types.SliceScrub, nodeID string, indexed map[string]struct{},
+	files map[string]blockchain.File) (bool, error) {
+
+	file, ok := files[s.FileID]
+	if !ok {
+		var err error
+		file, err = m.blockchain.GetFileByID(s.FileID)
+		if errorx.Is(err, errorx.ErrCodeNotFound) {
+			_, exist := indexed[s.SliceID]
+			return exist, nil
+		}
+		if err != nil {
+			return false, errorx.Wrap(err, "failed to get file from blockchain")
+		}
+		files[s.FileID] = file
+	}
+	for _, slice := range file.Slices {
+		if slice.ID == s.SliceID && string(slice.NodeID) == nodeID && !slice.Referenced() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
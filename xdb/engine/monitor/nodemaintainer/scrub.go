@@ -0,0 +1,195 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodemaintainer
+
+import (
+	"bytes"
+	"context"
+	"expvar"
+	"io/ioutil"
+	"time"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
+	"github.com/sirupsen/logrus"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+const (
+	// number of slices listed from scrubbing records at a time
+	scrubBatchSize = 100
+	// slices pushed recently are skipped, the file they belong to may not be updated on blockchain yet
+	scrubGracePeriod = time.Hour
+)
+
+// scrubStats is published as metric "scrub", counting slices scrubbed and those found corrupt or missing
+var scrubStats = expvar.NewMap("scrub")
+
+// scrub re-reads slices stored regularly and checks them against the hash of ciphertext on blockchain,
+// slices found corrupt or missing are reported to their owners, which migrate them to other nodes
+func (m *NodeMaintainer) scrub(ctx context.Context) {
+	l := logger.WithField("runner", "slice scrub loop")
+	defer l.Info("slice scrub stopped")
+
+	ticker := time.NewTicker(m.scrubInterval)
+	defer ticker.Stop()
+
+	m.doneScrubC = make(chan struct{})
+	defer close(m.doneScrubC)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		m.scrubSlices(ctx, l)
+	}
+}
+
+// scrubSlices scrubs all the slices tracked in batches
+func (m *NodeMaintainer) scrubSlices(ctx context.Context, l *logrus.Entry) {
+	nodeID := ecdsa.PublicKeyFromPrivateKey(m.localNode.PrivateKey).String()
+
+	var scrubbed, corrupt, missing int
+	after := ""
+	for {
+		list, err := m.scrubber.List(after, scrubBatchSize)
+		if err != nil {
+			l.WithError(err).Warn("failed to list slices to scrub")
+			return
+		}
+		if len(list) == 0 {
+			break
+		}
+		after = list[len(list)-1].SliceID
+
+		// slices of the same file are often listed together
+		files := make(map[string]blockchain.File)
+		for _, s := range list {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			if time.Now().UnixNano()-s.PushedAt < scrubGracePeriod.Nanoseconds() {
+				continue
+			}
+			status, checked, err := m.scrubSlice(s, nodeID, files)
+			if err != nil {
+				l.WithFields(logrus.Fields{
+					"slice_id": s.SliceID,
+					"file_id":  s.FileID,
+				}).WithError(err).Warn("failed to scrub slice")
+				continue
+			}
+			if !checked {
+				continue
+			}
+			if err := m.scrubber.Report(s.SliceID, status); err != nil {
+				l.WithField("slice_id", s.SliceID).WithError(err).Warn("failed to record scrub result")
+				continue
+			}
+
+			scrubbed++
+			scrubStats.Add("scrubbed", 1)
+			switch status {
+			case types.ScrubStatusCorrupt:
+				corrupt++
+				scrubStats.Add("corrupt", 1)
+			case types.ScrubStatusMissing:
+				missing++
+				scrubStats.Add("missing", 1)
+			}
+			if status != "" {
+				l.WithFields(logrus.Fields{
+					"slice_id": s.SliceID,
+					"file_id":  s.FileID,
+					"owner":    s.Owner,
+				}).Warnf("slice %s", status)
+			}
+		}
+	}
+
+	l.WithFields(logrus.Fields{
+		"scrubbed": scrubbed,
+		"corrupt":  corrupt,
+		"missing":  missing,
+	}).Info("slices scrubbed")
+}
+
+// scrubSlice checks a slice against the hash of ciphertext on blockchain, and returns its status,
+// checked is false if the slice is not referenced by the file on blockchain
+func (m *NodeMaintainer) scrubSlice(s types.SliceScrub, nodeID string, files map[string]blockchain.File) (
+	status string, checked bool, err error) {
+
+	file, ok := files[s.FileID]
+	if !ok {
+		file, err = m.blockchain.GetFileByID(s.FileID)
+		if errorx.Is(err, errorx.ErrCodeNotFound) {
+			// slices of files deleted or expired are removed by slice clear, and those never published by orphan clear
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, errorx.Wrap(err, "failed to get file from blockchain")
+		}
+		files[s.FileID] = file
+	}
+
+	var meta *blockchain.PublicSliceMeta
+	for i, slice := range file.Slices {
+		if slice.ID == s.SliceID && string(slice.NodeID) == nodeID && !slice.Referenced() {
+			meta = &file.Slices[i]
+			break
+		}
+	}
+	// the slice is migrated to other nodes, and cleared as an orphan
+	if meta == nil {
+		return "", false, nil
+	}
+
+	exist, err := m.sliceStorage.Exist(s.SliceID, meta.StorIndex)
+	if err != nil {
+		return "", false, errorx.Wrap(err, "failed to check slice existence")
+	}
+	if !exist {
+		return types.ScrubStatusMissing, true, nil
+	}
+	r, err := m.sliceStorage.Load(s.SliceID, meta.StorIndex)
+	if err != nil {
+		return "", false, errorx.Wrap(err, "failed to load slice")
+	}
+	defer r.Close()
+	cipherText, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", false, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read slice")
+	}
+	m.throttleScrub(len(cipherText))
+
+	if !bytes.Equal(hash.HashUsingSha256(cipherText), meta.CipherHash) {
+		return types.ScrubStatusCorrupt, true, nil
+	}
+	return "", true, nil
+}
+
+// throttleScrub sleeps for the time reading n bytes takes at the scrub rate
+func (m *NodeMaintainer) throttleScrub(n int) {
+	if m.scrubRate <= 0 {
+		return
+	}
+	time.Sleep(time.Duration(int64(n) * int64(time.Second) / m.scrubRate))
+}
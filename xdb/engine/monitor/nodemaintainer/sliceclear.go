@@ -25,6 +25,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/common"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
 )
 
@@ -81,38 +82,15 @@ func (m *NodeMaintainer) sliceClear(ctx context.Context) {
 			continue
 		}
 		var deleteSlices []string
-		var deleteContentErr, deleteSigmasErr error
-		var deleteContent, deleteSigmas bool
+		var deleteErr error
 		for _, slice := range sliceList {
-			sliceID := slice[0]
-			sliceStorIndex := slice[1]
-			// if slice exists, remove it
-			sliceSigmas := sliceID
-			if exist, _ := m.sliceStorage.Exist(sliceID, sliceStorIndex); exist {
-				deleteContentErr = m.sliceStorage.Delete(sliceID, sliceStorIndex)
-				if deleteContentErr == nil {
-					deleteContent = true
-				} else {
-					deleteContent = false
-				}
-			}
-			// delete pairing based challenge material if exists
-			if exist, _ := m.proveStorage.Exist(sliceSigmas); exist {
-				deleteSigmas, deleteSigmasErr = m.proveStorage.Delete(sliceSigmas)
-			} else {
-				deleteSigmas = true
-			}
-			if !deleteContent || !deleteSigmas {
+			if deleteErr = m.removeSlice(slice[0], slice[1]); deleteErr != nil {
 				break
 			}
-			deleteSlices = append(deleteSlices, sliceID)
-		}
-		if deleteContentErr != nil {
-			l.WithError(deleteContentErr).Warn("failed to delete node slice")
-			continue
+			deleteSlices = append(deleteSlices, slice[0])
 		}
-		if deleteSigmasErr != nil {
-			l.WithError(deleteSigmasErr).Warn("failed to delete node slice sigmas")
+		if deleteErr != nil {
+			l.WithError(deleteErr).Warn("failed to delete node slice")
 			continue
 		}
 
@@ -130,6 +108,39 @@ func (m *NodeMaintainer) sliceClear(ctx context.Context) {
 	}
 }
 
+// removeSlice removes a slice with its pairing based challenge material and the record of its source,
+// releases it from the quota of its source and stops scrubbing it, those already removed are skipped
+func (m *NodeMaintainer) removeSlice(sliceID, storIndex string) error {
+	if exist, _ := m.sliceStorage.Exist(sliceID, storIndex); exist {
+		if err := m.sliceStorage.Delete(sliceID, storIndex); err != nil {
+			return errorx.Wrap(err, "failed to delete slice %s", sliceID)
+		}
+	}
+	// sigmas of slice are stored with slice id as key
+	if exist, _ := m.proveStorage.Exist(sliceID); exist {
+		if _, err := m.proveStorage.Delete(sliceID); err != nil {
+			return errorx.Wrap(err, "failed to delete sigmas of slice %s", sliceID)
+		}
+	}
+	sourceKey := common.GetSliceSourceKey(sliceID)
+	if exist, _ := m.proveStorage.Exist(sourceKey); exist {
+		if _, err := m.proveStorage.Delete(sourceKey); err != nil {
+			return errorx.Wrap(err, "failed to delete source of slice %s", sliceID)
+		}
+	}
+	if m.quota != nil {
+		if err := m.quota.Release(sliceID); err != nil {
+			return errorx.Wrap(err, "failed to release slice %s from quota", sliceID)
+		}
+	}
+	if m.scrubber != nil {
+		if err := m.scrubber.Untrack(sliceID); err != nil {
+			return errorx.Wrap(err, "failed to untrack slice %s from scrub", sliceID)
+		}
+	}
+	return nil
+}
+
 // getExpireRangeTime used to query expired slices in the startTime-endTime
 // clearKey stores the time of the last query
 func (m *NodeMaintainer) getExpireRangeTime(clearKey string, latestTime, regTime int64) (int64, int64, error) {
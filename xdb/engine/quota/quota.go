@@ -0,0 +1,385 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sync"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+const (
+	dbName = "quotaDB"
+
+	usagePrefix = "usage:"
+	slicePrefix = "slice:"
+)
+
+// limit is the quota of a dataOwner node, zero means unlimited
+type limit struct {
+	maxBytes  int64
+	maxSlices int64
+}
+
+// usage is the storage used by a dataOwner node
+type usage struct {
+	Bytes  int64
+	Slices int64
+}
+
+// sliceRecord records the dataOwner node a slice is counted for
+type sliceRecord struct {
+	Owner string
+	Size  int64
+}
+
+// Accountant records the bytes and the number of slices each dataOwner node stores on the storage node
+// in levelDB, and checks them against the quota before a slice is saved.
+// Storage of slices being pushed is reserved in memory, so that slices pushed concurrently
+// are checked against each other as well.
+type Accountant struct {
+	lock sync.Mutex
+	db   *leveldb.DB
+
+	defaults limit
+	owners   map[string]limit
+	reserved map[string]usage // storage reserved for slices being pushed, by owner
+}
+
+// New creates an Accountant by quota configuration
+func New(conf *config.QuotaConf) (*Accountant, error) {
+	if len(conf.LocalRoot) == 0 {
+		return nil, errorx.New(errorx.ErrCodeConfig, "missing config: localRoot")
+	}
+	if conf.MaxBytes < 0 || conf.MaxSlices < 0 {
+		return nil, errorx.New(errorx.ErrCodeConfig, "invalid quota, negative value")
+	}
+	a := &Accountant{
+		defaults: limit{maxBytes: conf.MaxBytes, maxSlices: conf.MaxSlices},
+		owners:   make(map[string]limit),
+		reserved: make(map[string]usage),
+	}
+	for _, o := range conf.Owners {
+		if _, err := ecdsa.DecodePublicKeyFromString(o.Owner); err != nil {
+			return nil, errorx.NewCode(err, errorx.ErrCodeConfig, "invalid owner %s", o.Owner)
+		}
+		if o.MaxBytes < 0 || o.MaxSlices < 0 {
+			return nil, errorx.New(errorx.ErrCodeConfig, "invalid quota of owner %s, negative value", o.Owner)
+		}
+		if _, exist := a.owners[o.Owner]; exist {
+			return nil, errorx.New(errorx.ErrCodeConfig, "duplicated quota of owner %s", o.Owner)
+		}
+		a.owners[o.Owner] = limit{maxBytes: o.MaxBytes, maxSlices: o.MaxSlices}
+	}
+
+	db, err := leveldb.OpenFile(filepath.Join(conf.LocalRoot, dbName), nil)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "cannot open leveldb")
+	}
+	a.db = db
+	return a, nil
+}
+
+// Usage returns the storage used by a dataOwner node and its quota
+func (a *Accountant) Usage(owner string) (types.SliceUsage, error) {
+	u, err := a.loadUsage(owner)
+	if err != nil {
+		return types.SliceUsage{}, err
+	}
+	l := a.limitOf(owner)
+	return types.SliceUsage{
+		Owner:     owner,
+		Bytes:     u.Bytes,
+		Slices:    u.Slices,
+		MaxBytes:  l.maxBytes,
+		MaxSlices: l.maxSlices,
+	}, nil
+}
+
+// Check checks if a dataOwner node is allowed to save one more slice, and reserves the slice for it.
+// Bytes of the slice are reserved as they are read by Reservation.Reader, the reservation must be
+// committed once the slice is saved, or released otherwise
+func (a *Accountant) Check(owner string) (*Reservation, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	u, err := a.usageWithReserved(owner)
+	if err != nil {
+		return nil, err
+	}
+	l := a.limitOf(owner)
+	if l.maxSlices > 0 && u.Slices >= l.maxSlices {
+		return nil, errorx.New(errorx.ErrCodeQuotaExceeded, "slices quota exceeded, %d slices stored", u.Slices)
+	}
+	left := int64(-1)
+	if l.maxBytes > 0 {
+		if u.Bytes >= l.maxBytes {
+			return nil, errorx.New(errorx.ErrCodeQuotaExceeded, "bytes quota exceeded, %d bytes stored", u.Bytes)
+		}
+		left = l.maxBytes - u.Bytes
+	}
+	r := a.reserved[owner]
+	r.Slices++
+	a.reserved[owner] = r
+	return &Reservation{a: a, owner: owner, left: left}, nil
+}
+
+// Add counts a slice saved for a dataOwner node, a slice already counted is skipped
+func (a *Accountant) Add(owner, sliceID string, size int64) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.add(owner, sliceID, size)
+}
+
+func (a *Accountant) add(owner, sliceID string, size int64) error {
+	if _, exist, err := a.loadSlice(sliceID); err != nil || exist {
+		return err
+	}
+	u, err := a.loadUsage(owner)
+	if err != nil {
+		return err
+	}
+	u.Bytes += size
+	u.Slices++
+
+	batch := leveldb.Batch{}
+	if err := putJSON(&batch, usagePrefix+owner, u); err != nil {
+		return err
+	}
+	if err := putJSON(&batch, slicePrefix+sliceID, sliceRecord{Owner: owner, Size: size}); err != nil {
+		return err
+	}
+	if err := a.db.Write(&batch, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write batch")
+	}
+	return nil
+}
+
+// Release stops counting a slice removed, a slice not counted is skipped
+func (a *Accountant) Release(sliceID string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	r, exist, err := a.loadSlice(sliceID)
+	if err != nil || !exist {
+		return err
+	}
+	u, err := a.loadUsage(r.Owner)
+	if err != nil {
+		return err
+	}
+	u.Bytes -= r.Size
+	u.Slices--
+
+	batch := leveldb.Batch{}
+	if u.Slices <= 0 {
+		batch.Delete([]byte(usagePrefix + r.Owner))
+	} else if err := putJSON(&batch, usagePrefix+r.Owner, u); err != nil {
+		return err
+	}
+	batch.Delete([]byte(slicePrefix + sliceID))
+	if err := a.db.Write(&batch, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write batch")
+	}
+	return nil
+}
+
+// Close closes levelDB
+func (a *Accountant) Close() {
+	a.db.Close()
+}
+
+func (a *Accountant) limitOf(owner string) limit {
+	if l, exist := a.owners[owner]; exist {
+		return l
+	}
+	return a.defaults
+}
+
+// usageWithReserved returns the storage used by a dataOwner node, including the storage reserved
+func (a *Accountant) usageWithReserved(owner string) (usage, error) {
+	u, err := a.loadUsage(owner)
+	if err != nil {
+		return u, err
+	}
+	r := a.reserved[owner]
+	u.Bytes += r.Bytes
+	u.Slices += r.Slices
+	return u, nil
+}
+
+// unreserve releases storage reserved by a slice
+func (a *Accountant) unreserve(owner string, bytes int64) {
+	r := a.reserved[owner]
+	r.Bytes -= bytes
+	r.Slices--
+	if r.Slices <= 0 {
+		delete(a.reserved, owner)
+	} else {
+		a.reserved[owner] = r
+	}
+}
+
+func (a *Accountant) loadUsage(owner string) (usage, error) {
+	var u usage
+	value, err := a.db.Get([]byte(usagePrefix+owner), nil)
+	if err == leveldb.ErrNotFound {
+		return u, nil
+	} else if err != nil {
+		return u, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to get usage")
+	}
+	if err := json.Unmarshal(value, &u); err != nil {
+		return u, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal usage")
+	}
+	return u, nil
+}
+
+func (a *Accountant) loadSlice(sliceID string) (sliceRecord, bool, error) {
+	var r sliceRecord
+	value, err := a.db.Get([]byte(slicePrefix+sliceID), nil)
+	if err == leveldb.ErrNotFound {
+		return r, false, nil
+	} else if err != nil {
+		return r, false, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to get slice record")
+	}
+	if err := json.Unmarshal(value, &r); err != nil {
+		return r, false, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal slice record")
+	}
+	return r, true, nil
+}
+
+func putJSON(batch *leveldb.Batch, key string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal %s", key)
+	}
+	batch.Put([]byte(key), value)
+	return nil
+}
+
+// Reservation is the storage reserved for a slice being pushed by a dataOwner node
+type Reservation struct {
+	a     *Accountant
+	owner string
+	left  int64 // bytes left when checked, -1 if unlimited
+	bytes int64 // bytes reserved
+	done  bool
+}
+
+// Left returns the max bytes of the slice when it was checked, -1 if unlimited
+func (r *Reservation) Left() int64 {
+	return r.left
+}
+
+// Reader returns a Reader reserving bytes read for the slice
+func (r *Reservation) Reader(reader io.Reader) *Reader {
+	return &Reader{r: reader, limit: -1, res: r}
+}
+
+// reserve reserves n more bytes for the slice, fails if the bytes quota is exceeded
+func (r *Reservation) reserve(n int64) error {
+	r.a.lock.Lock()
+	defer r.a.lock.Unlock()
+
+	if l := r.a.limitOf(r.owner); l.maxBytes > 0 {
+		u, err := r.a.usageWithReserved(r.owner)
+		if err != nil {
+			return err
+		}
+		if u.Bytes+n > l.maxBytes {
+			return errorx.New(errorx.ErrCodeQuotaExceeded, "bytes quota exceeded, %d bytes left", l.maxBytes-u.Bytes)
+		}
+	}
+	u := r.a.reserved[r.owner]
+	u.Bytes += n
+	r.a.reserved[r.owner] = u
+	r.bytes += n
+	return nil
+}
+
+// Commit counts the slice saved with the bytes reserved, and releases the reservation
+func (r *Reservation) Commit(sliceID string) error {
+	r.a.lock.Lock()
+	defer r.a.lock.Unlock()
+
+	if r.done {
+		return nil
+	}
+	r.done = true
+	r.a.unreserve(r.owner, r.bytes)
+	return r.a.add(r.owner, sliceID, r.bytes)
+}
+
+// Release releases the reservation if it's not committed
+func (r *Reservation) Release() {
+	r.a.lock.Lock()
+	defer r.a.lock.Unlock()
+
+	if r.done {
+		return
+	}
+	r.done = true
+	r.a.unreserve(r.owner, r.bytes)
+}
+
+// Reader counts bytes read from a slice pushed,
+// and fails once more bytes than the quota allows are read
+type Reader struct {
+	r        io.Reader
+	limit    int64        // -1 if unlimited
+	res      *Reservation // bytes read are reserved if not nil
+	n        int64
+	exceeded error
+}
+
+// NewReader creates a Reader, limit is the max bytes to read, -1 if unlimited
+func NewReader(r io.Reader, limit int64) *Reader {
+	return &Reader{r: r, limit: limit}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	if r.limit >= 0 && r.n > r.limit {
+		r.exceeded = errorx.New(errorx.ErrCodeQuotaExceeded, "bytes quota exceeded, %d bytes left", r.limit)
+		return n, r.exceeded
+	}
+	if r.res != nil && n > 0 {
+		if rerr := r.res.reserve(int64(n)); rerr != nil {
+			if errorx.Is(rerr, errorx.ErrCodeQuotaExceeded) {
+				r.exceeded = rerr
+			}
+			return n, rerr
+		}
+	}
+	return n, err
+}
+
+// N returns the number of bytes read
+func (r *Reader) N() int64 {
+	return r.n
+}
+
+// Exceeded returns the error if reading failed as the quota is exceeded, nil otherwise
+func (r *Reader) Exceeded() error {
+	return r.exceeded
+}
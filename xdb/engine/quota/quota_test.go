@@ -0,0 +1,170 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/ecdsa"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+func newOwner(t *testing.T) string {
+	_, pubkey, err := ecdsa.GenerateKeyPair()
+	require.NoError(t, err)
+	return pubkey.String()
+}
+
+func TestAccountant(t *testing.T) {
+	root, err := ioutil.TempDir("", "quota")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	owner1, owner2 := newOwner(t), newOwner(t)
+	a, err := New(&config.QuotaConf{
+		LocalRoot: root,
+		MaxBytes:  100,
+		MaxSlices: 2,
+		Owners: []config.OwnerQuotaConf{
+			{Owner: owner2, MaxBytes: 0, MaxSlices: 1},
+		},
+	})
+	require.NoError(t, err)
+
+	res, err := a.Check(owner1)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), res.Left())
+	res.Release()
+
+	require.NoError(t, a.Add(owner1, "s1", 60))
+	// a slice already counted is skipped
+	require.NoError(t, a.Add(owner1, "s1", 60))
+	res, err = a.Check(owner1)
+	require.NoError(t, err)
+	require.Equal(t, int64(40), res.Left())
+	res.Release()
+
+	require.NoError(t, a.Add(owner1, "s2", 10))
+	_, err = a.Check(owner1)
+	require.True(t, errorx.Is(err, errorx.ErrCodeQuotaExceeded))
+	u, err := a.Usage(owner1)
+	require.NoError(t, err)
+	require.Equal(t, int64(70), u.Bytes)
+	require.Equal(t, int64(2), u.Slices)
+	require.Equal(t, int64(100), u.MaxBytes)
+
+	// bytes of owner2 are unlimited
+	res, err = a.Check(owner2)
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), res.Left())
+	res.Release()
+	require.NoError(t, a.Add(owner2, "s3", 1000))
+	_, err = a.Check(owner2)
+	require.True(t, errorx.Is(err, errorx.ErrCodeQuotaExceeded))
+
+	require.NoError(t, a.Release("s2"))
+	require.NoError(t, a.Release("s2"))
+	require.NoError(t, a.Release("s3"))
+	u, err = a.Usage(owner1)
+	require.NoError(t, err)
+	require.Equal(t, int64(60), u.Bytes)
+	require.Equal(t, int64(1), u.Slices)
+	u, err = a.Usage(owner2)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), u.Bytes)
+	require.Equal(t, int64(0), u.Slices)
+
+	// usage is persisted
+	a.Close()
+	a, err = New(&config.QuotaConf{LocalRoot: root})
+	require.NoError(t, err)
+	defer a.Close()
+	u, err = a.Usage(owner1)
+	require.NoError(t, err)
+	require.Equal(t, int64(60), u.Bytes)
+	require.Equal(t, int64(0), u.MaxBytes)
+
+	for _, bad := range []config.QuotaConf{
+		{},
+		{LocalRoot: root, MaxBytes: -1},
+		{LocalRoot: root, Owners: []config.OwnerQuotaConf{{Owner: "bad"}}},
+		{LocalRoot: root, Owners: []config.OwnerQuotaConf{{Owner: owner1}, {Owner: owner1}}},
+	} {
+		_, err := New(&bad)
+		require.Error(t, err)
+	}
+}
+
+func TestReservation(t *testing.T) {
+	root, err := ioutil.TempDir("", "quota")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	owner := newOwner(t)
+	a, err := New(&config.QuotaConf{LocalRoot: root, MaxBytes: 100, MaxSlices: 2})
+	require.NoError(t, err)
+	defer a.Close()
+
+	// slices pushed concurrently are checked against each other
+	res1, err := a.Check(owner)
+	require.NoError(t, err)
+	res2, err := a.Check(owner)
+	require.NoError(t, err)
+	_, err = a.Check(owner)
+	require.True(t, errorx.Is(err, errorx.ErrCodeQuotaExceeded))
+
+	_, err = ioutil.ReadAll(res1.Reader(strings.NewReader(strings.Repeat("a", 60))))
+	require.NoError(t, err)
+	r2 := res2.Reader(strings.NewReader(strings.Repeat("b", 60)))
+	_, err = ioutil.ReadAll(r2)
+	require.True(t, errorx.Is(err, errorx.ErrCodeQuotaExceeded))
+	require.Error(t, r2.Exceeded())
+
+	// the reservation is released on failure, and committed on success
+	res2.Release()
+	require.NoError(t, res1.Commit("s1"))
+	res1.Release()
+	u, err := a.Usage(owner)
+	require.NoError(t, err)
+	require.Equal(t, int64(60), u.Bytes)
+	require.Equal(t, int64(1), u.Slices)
+
+	res, err := a.Check(owner)
+	require.NoError(t, err)
+	require.Equal(t, int64(40), res.Left())
+	res.Release()
+	require.Equal(t, 0, len(a.reserved))
+}
+
+func TestReader(t *testing.T) {
+	r := NewReader(strings.NewReader("Hello world!"), -1)
+	content, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "Hello world!", string(content))
+	require.Equal(t, int64(12), r.N())
+
+	r = NewReader(strings.NewReader("Hello world!"), 12)
+	_, err = ioutil.ReadAll(r)
+	require.NoError(t, err)
+
+	r = NewReader(strings.NewReader("Hello world!"), 11)
+	_, err = ioutil.ReadAll(r)
+	require.True(t, errorx.Is(err, errorx.ErrCodeQuotaExceeded))
+}
@@ -0,0 +1,269 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scrub
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+const (
+	dbName = "scrubDB"
+
+	slicePrefix  = "slice:"
+	reportPrefix = "report:"
+	orphanPrefix = "orphan:"
+)
+
+// Recorder records slices stored on the storage node with the files they belong to in levelDB,
+// so that they can be scrubbed against the hash of ciphertext on blockchain, and those no longer referenced
+// by any file on blockchain can be cleared. Slices found corrupt or missing are indexed by their owners,
+// until they are scrubbed healthy or removed, and slices found orphaned are indexed until referenced or removed
+type Recorder struct {
+	lock sync.Mutex
+	db   *leveldb.DB
+}
+
+// New creates a Recorder by scrubbing configuration
+func New(conf *config.ScrubConf) (*Recorder, error) {
+	if len(conf.LocalRoot) == 0 {
+		return nil, errorx.New(errorx.ErrCodeConfig, "missing config: localRoot")
+	}
+	if conf.Interval < 0 || conf.BytesPerSecond < 0 {
+		return nil, errorx.New(errorx.ErrCodeConfig, "invalid scrub config, negative value")
+	}
+	db, err := leveldb.OpenFile(filepath.Join(conf.LocalRoot, dbName), nil)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "cannot open leveldb")
+	}
+	return &Recorder{db: db}, nil
+}
+
+// Track records a slice pushed by owner for a file, a slice pushed again is tracked as a new one
+func (r *Recorder) Track(sliceID, storIndex, fileID, owner string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	old, exist, err := r.load(sliceID)
+	if err != nil {
+		return err
+	}
+	batch := leveldb.Batch{}
+	if exist {
+		batch.Delete([]byte(reportKey(old.Owner, sliceID)))
+		batch.Delete([]byte(orphanPrefix + sliceID))
+	}
+	s := types.SliceScrub{
+		SliceID:   sliceID,
+		StorIndex: storIndex,
+		FileID:    fileID,
+		Owner:     owner,
+		PushedAt:  time.Now().UnixNano(),
+	}
+	if err := putJSON(&batch, slicePrefix+sliceID, s); err != nil {
+		return err
+	}
+	if err := r.db.Write(&batch, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write batch")
+	}
+	return nil
+}
+
+// Untrack stops tracking a slice removed, a slice not tracked is skipped
+func (r *Recorder) Untrack(sliceID string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	s, exist, err := r.load(sliceID)
+	if err != nil || !exist {
+		return err
+	}
+	batch := leveldb.Batch{}
+	batch.Delete([]byte(slicePrefix + sliceID))
+	batch.Delete([]byte(reportKey(s.Owner, sliceID)))
+	batch.Delete([]byte(orphanPrefix + sliceID))
+	if err := r.db.Write(&batch, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write batch")
+	}
+	return nil
+}
+
+// List returns at most limit slices tracked in the order of their IDs, starting after the slice ID after
+func (r *Recorder) List(after string, limit int) ([]types.SliceScrub, error) {
+	iter := r.db.NewIterator(util.BytesPrefix([]byte(slicePrefix)), nil)
+	defer iter.Release()
+
+	var ok bool
+	if after == "" {
+		ok = iter.First()
+	} else if ok = iter.Seek([]byte(slicePrefix + after)); ok && string(iter.Key()) == slicePrefix+after {
+		ok = iter.Next()
+	}
+	var list []types.SliceScrub
+	for ; ok && len(list) < limit; ok = iter.Next() {
+		var s types.SliceScrub
+		if err := json.Unmarshal(iter.Value(), &s); err != nil {
+			return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal slice record")
+		}
+		list = append(list, s)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate slice records")
+	}
+	return list, nil
+}
+
+// Report records the result of scrubbing a slice, status is empty if the slice is healthy,
+// a slice not tracked is skipped
+func (r *Recorder) Report(sliceID, status string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	s, exist, err := r.load(sliceID)
+	if err != nil || !exist {
+		return err
+	}
+	s.Status = status
+	s.CheckedAt = time.Now().UnixNano()
+
+	batch := leveldb.Batch{}
+	if err := putJSON(&batch, slicePrefix+sliceID, s); err != nil {
+		return err
+	}
+	if status == "" {
+		batch.Delete([]byte(reportKey(s.Owner, sliceID)))
+	} else {
+		batch.Put([]byte(reportKey(s.Owner, sliceID)), nil)
+	}
+	if err := r.db.Write(&batch, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write batch")
+	}
+	return nil
+}
+
+// Reports returns slices of a dataOwner node found corrupt or missing
+func (r *Recorder) Reports(owner string) ([]types.SliceScrub, error) {
+	prefix := reportKey(owner, "")
+	iter := r.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+
+	var reports []types.SliceScrub
+	for iter.Next() {
+		sliceID := string(iter.Key())[len(prefix):]
+		s, exist, err := r.load(sliceID)
+		if err != nil {
+			return nil, err
+		}
+		if exist && s.Status != "" {
+			reports = append(reports, s)
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate scrub reports")
+	}
+	return reports, nil
+}
+
+// MarkOrphan records whether a slice is referenced by any file on blockchain, and returns the slice record.
+// OrphanedAt of the record is the time the slice is first found orphaned since it was last referenced,
+// a slice not tracked is skipped
+func (r *Recorder) MarkOrphan(sliceID string, orphaned bool) (types.SliceScrub, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	s, exist, err := r.load(sliceID)
+	if err != nil || !exist {
+		return s, err
+	}
+	if orphaned == (s.OrphanedAt != 0) {
+		return s, nil
+	}
+	batch := leveldb.Batch{}
+	if orphaned {
+		s.OrphanedAt = time.Now().UnixNano()
+		batch.Put([]byte(orphanPrefix+sliceID), nil)
+	} else {
+		s.OrphanedAt = 0
+		batch.Delete([]byte(orphanPrefix + sliceID))
+	}
+	if err := putJSON(&batch, slicePrefix+sliceID, s); err != nil {
+		return s, err
+	}
+	if err := r.db.Write(&batch, nil); err != nil {
+		return s, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write batch")
+	}
+	return s, nil
+}
+
+// Orphans returns slices found not referenced by any file on blockchain
+func (r *Recorder) Orphans() ([]types.SliceScrub, error) {
+	iter := r.db.NewIterator(util.BytesPrefix([]byte(orphanPrefix)), nil)
+	defer iter.Release()
+
+	var orphans []types.SliceScrub
+	for iter.Next() {
+		s, exist, err := r.load(string(iter.Key())[len(orphanPrefix):])
+		if err != nil {
+			return nil, err
+		}
+		if exist && s.OrphanedAt != 0 {
+			orphans = append(orphans, s)
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate orphan slices")
+	}
+	return orphans, nil
+}
+
+// Close closes levelDB
+func (r *Recorder) Close() {
+	r.db.Close()
+}
+
+func (r *Recorder) load(sliceID string) (types.SliceScrub, bool, error) {
+	var s types.SliceScrub
+	value, err := r.db.Get([]byte(slicePrefix+sliceID), nil)
+	if err == leveldb.ErrNotFound {
+		return s, false, nil
+	} else if err != nil {
+		return s, false, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to get slice record")
+	}
+	if err := json.Unmarshal(value, &s); err != nil {
+		return s, false, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal slice record")
+	}
+	return s, true, nil
+}
+
+func reportKey(owner, sliceID string) string {
+	return reportPrefix + owner + ":" + sliceID
+}
+
+func putJSON(batch *leveldb.Batch, key string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal %s", key)
+	}
+	batch.Put([]byte(key), value)
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scrub
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
+)
+
+func TestRecorder(t *testing.T) {
+	root, err := ioutil.TempDir("", "scrub")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	r, err := New(&config.ScrubConf{LocalRoot: root})
+	require.NoError(t, err)
+	defer r.Close()
+
+	require.NoError(t, r.Track("s1", "s1", "f1", "o1"))
+	require.NoError(t, r.Track("s2", "s2", "f1", "o1"))
+	require.NoError(t, r.Track("s3", "i3", "f2", "o2"))
+
+	// list slices in batches
+	list, err := r.List("", 2)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, "s1", list[0].SliceID)
+	require.Equal(t, "f1", list[0].FileID)
+	require.Equal(t, "o1", list[0].Owner)
+	require.Equal(t, "s1", list[0].StorIndex)
+	list, err = r.List(list[1].SliceID, 2)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, "s3", list[0].SliceID)
+	list, err = r.List("s3", 2)
+	require.NoError(t, err)
+	require.Len(t, list, 0)
+
+	// reports are indexed by owners
+	require.NoError(t, r.Report("s1", types.ScrubStatusCorrupt))
+	require.NoError(t, r.Report("s2", ""))
+	require.NoError(t, r.Report("s3", types.ScrubStatusMissing))
+	require.NoError(t, r.Report("s4", types.ScrubStatusMissing))
+	reports, err := r.Reports("o1")
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	require.Equal(t, "s1", reports[0].SliceID)
+	require.Equal(t, types.ScrubStatusCorrupt, reports[0].Status)
+	require.NotZero(t, reports[0].CheckedAt)
+
+	// a slice scrubbed healthy is no longer reported
+	require.NoError(t, r.Report("s1", ""))
+	reports, err = r.Reports("o1")
+	require.NoError(t, err)
+	require.Len(t, reports, 0)
+
+	// a slice pushed again is tracked as a new one
+	require.NoError(t, r.Report("s1", types.ScrubStatusCorrupt))
+	require.NoError(t, r.Track("s1", "s1", "f1", "o1"))
+	reports, err = r.Reports("o1")
+	require.NoError(t, err)
+	require.Len(t, reports, 0)
+
+	// a slice removed is no longer tracked or reported
+	require.NoError(t, r.Untrack("s3"))
+	require.NoError(t, r.Untrack("s4"))
+	reports, err = r.Reports("o2")
+	require.NoError(t, err)
+	require.Len(t, reports, 0)
+	list, err = r.List("", 10)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	// orphan slices are listed until referenced or removed
+	s, err := r.MarkOrphan("s1", true)
+	require.NoError(t, err)
+	orphanedAt := s.OrphanedAt
+	require.NotZero(t, orphanedAt)
+	s, err = r.MarkOrphan("s1", true)
+	require.NoError(t, err)
+	require.Equal(t, orphanedAt, s.OrphanedAt)
+	_, err = r.MarkOrphan("s2", true)
+	require.NoError(t, err)
+	orphans, err := r.Orphans()
+	require.NoError(t, err)
+	require.Len(t, orphans, 2)
+
+	s, err = r.MarkOrphan("s2", false)
+	require.NoError(t, err)
+	require.Zero(t, s.OrphanedAt)
+	require.NoError(t, r.Untrack("s1"))
+	orphans, err = r.Orphans()
+	require.NoError(t, err)
+	require.Len(t, orphans, 0)
+}
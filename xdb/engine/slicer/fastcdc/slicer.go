@@ -0,0 +1,236 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package fastcdc
+
+import (
+	"context"
+	"io"
+	"math/bits"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/slicer"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+const (
+	defaultAvgSize   = 1024 * 1024 // 1MB
+	defaultQueueSize = 4
+)
+
+var (
+	logger = logrus.WithField("module", "fastcdc-slicer")
+
+	// gear maps every byte to a random 64 bits number for the rolling hash
+	gear = makeGear()
+)
+
+// FastCDCSlicer cuts a file into slices of variable size at content-defined boundaries
+// using the FastCDC algorithm, boundaries are determined by a gear based rolling hash
+// over the data, so that an insertion or deletion only changes the slices around it.
+// Sizes of slices are between minSize and maxSize, and normalized around avgSize.
+type FastCDCSlicer struct {
+	minSize   int
+	avgSize   int
+	maxSize   int
+	queueSize uint32
+
+	maskS uint64 // harder to match, used before avgSize
+	maskL uint64 // easier to match, used after avgSize
+}
+
+// New create a FastCDCSlicer instance by configuration,
+// minSize defaults to a quarter of avgSize and maxSize defaults to four times of avgSize
+func New(conf *config.FastCDCSlicerConf) (*FastCDCSlicer, error) {
+	avgSize := int(conf.AvgSize)
+	if avgSize == 0 {
+		avgSize = defaultAvgSize
+	}
+	minSize := int(conf.MinSize)
+	if minSize == 0 {
+		minSize = avgSize / 4
+	}
+	maxSize := int(conf.MaxSize)
+	if maxSize == 0 {
+		maxSize = avgSize * 4
+	}
+	if minSize <= 0 || minSize >= avgSize || avgSize >= maxSize {
+		return nil, errorx.New(errorx.ErrCodeConfig,
+			"invalid slice size, minSize %d, avgSize %d and maxSize %d must be in ascending order", minSize, avgSize, maxSize)
+	}
+	logger.WithFields(logrus.Fields{
+		"minSize": minSize,
+		"avgSize": avgSize,
+		"maxSize": maxSize,
+	}).Info("slicer initialization")
+
+	queueSize := uint32(conf.QueueSize)
+	if queueSize == 0 {
+		queueSize = defaultQueueSize
+	}
+	logger.WithField("queueSize", queueSize).Info("slicer initialization")
+
+	// normalized chunking, one more bit for maskS and one less bit for maskL than log2(avgSize)
+	n := bits.Len(uint(avgSize)) - 1
+	s := &FastCDCSlicer{
+		minSize:   minSize,
+		avgSize:   avgSize,
+		maxSize:   maxSize,
+		queueSize: queueSize,
+		maskS:     makeMask(n + 1),
+		maskL:     makeMask(n - 1),
+	}
+
+	return s, nil
+}
+
+// Slice reads from IO and cut the data into slices at content-defined boundaries,
+// and digests every slice by sha256
+func (fs *FastCDCSlicer) Slice(ctx context.Context, r io.Reader, opt *slicer.SliceOptions,
+	onErr func(err error)) chan slicer.Slice {
+	resCh := make(chan slicer.Slice, fs.queueSize)
+
+	go func() {
+		defer close(resCh)
+
+		buf := make([]byte, fs.maxSize)
+		filled := 0
+		eof := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			// keep at most maxSize bytes in buffer, so that a boundary can always be found in it
+			if !eof {
+				// TODO: cancelable read
+				n, err := io.ReadFull(r, buf[filled:])
+				filled += n
+				switch err {
+				case nil:
+				case io.EOF, io.ErrUnexpectedEOF:
+					eof = true
+				default:
+					onErr(errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read file during Slice"))
+					return
+				}
+			}
+			if filled == 0 {
+				return
+			}
+
+			cut := fs.cutPoint(buf[:filled])
+			data := make([]byte, cut)
+			copy(data, buf[:cut])
+			filled = copy(buf, buf[cut:filled])
+
+			select {
+			case <-ctx.Done():
+				return
+			case resCh <- makeSlice(data):
+			}
+		}
+	}()
+
+	return resCh
+}
+
+// GetBlockSize returns the maximum size of slice
+func (fs *FastCDCSlicer) GetBlockSize() int {
+	return fs.maxSize
+}
+
+// ContentDefined reports that slices are cut at content-defined boundaries,
+// identical data produces identical slices wherever it is located in a file
+func (fs *FastCDCSlicer) ContentDefined() bool {
+	return true
+}
+
+// cutPoint returns length of the first slice in data, data shorter than maxSize is at the end of file
+func (fs *FastCDCSlicer) cutPoint(data []byte) int {
+	n := len(data)
+	if n <= fs.minSize {
+		return n
+	}
+	normal := fs.avgSize
+	if n < normal {
+		normal = n
+	}
+	if n > fs.maxSize {
+		n = fs.maxSize
+	}
+
+	var h uint64
+	i := fs.minSize
+	for ; i < normal; i++ {
+		h = (h << 1) + gear[data[i]]
+		if h&fs.maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		h = (h << 1) + gear[data[i]]
+		if h&fs.maskL == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
+
+// makeSlice digests a slice by sha256 to make a SliceMeta
+func makeSlice(bs []byte) slicer.Slice {
+	h := hash.HashUsingSha256(bs)
+	id, _ := uuid.NewRandom()
+	return slicer.Slice{
+		SliceMeta: slicer.SliceMeta{
+			ID:     id.String(),
+			Hash:   h,
+			Length: uint64(len(bs)),
+		},
+		Data: bs,
+	}
+}
+
+// makeMask returns a mask with the highest n bits set, the highest bits of the gear hash
+// are affected by the most recent 64 bytes
+func makeMask(n int) uint64 {
+	if n <= 0 {
+		return 0
+	}
+	if n >= 64 {
+		return ^uint64(0)
+	}
+	return ^uint64(0) << uint(64-n)
+}
+
+// makeGear generates the gear table by splitmix64 with a fixed seed,
+// the table must never change, otherwise boundaries of existing files move
+func makeGear() [256]uint64 {
+	var g [256]uint64
+	x := uint64(0x5844424643444321)
+	for i := range g {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		g[i] = z ^ (z >> 31)
+	}
+	return g
+}
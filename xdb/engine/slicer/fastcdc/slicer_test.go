@@ -0,0 +1,88 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package fastcdc
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/slicer"
+)
+
+func TestFastCDCSlice(t *testing.T) {
+	logrus.SetLevel(logrus.ErrorLevel)
+
+	s, err := New(&config.FastCDCSlicerConf{
+		MinSize: 256,
+		AvgSize: 1024,
+		MaxSize: 4096,
+	})
+	require.NoError(t, err)
+
+	data := make([]byte, 100*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	slices := sliceAll(t, s, data)
+	require.True(t, len(slices) > 1)
+	var joined []byte
+	for i, sl := range slices {
+		require.True(t, len(sl.Data) <= 4096)
+		if i < len(slices)-1 {
+			require.True(t, len(sl.Data) > 256)
+		}
+		require.Equal(t, uint64(len(sl.Data)), sl.Length)
+		joined = append(joined, sl.Data...)
+	}
+	require.Equal(t, data, joined)
+
+	// insert some bytes in the middle, most of the slices are not changed
+	modified := append(append(append([]byte{}, data[:50*1024]...), []byte("inserted row")...), data[50*1024:]...)
+	hashes := make(map[string]struct{})
+	for _, sl := range slices {
+		hashes[string(sl.Hash)] = struct{}{}
+	}
+	modifiedSlices := sliceAll(t, s, modified)
+	changed := 0
+	for _, sl := range modifiedSlices {
+		if _, ok := hashes[string(sl.Hash)]; !ok {
+			changed++
+		}
+	}
+	require.True(t, changed <= 3, "%d slices changed", changed)
+
+	// empty file
+	require.Equal(t, 0, len(sliceAll(t, s, nil)))
+
+	// bad sizes
+	_, err = New(&config.FastCDCSlicerConf{MinSize: 2048, AvgSize: 1024})
+	require.Error(t, err)
+}
+
+func sliceAll(t *testing.T, s *FastCDCSlicer, data []byte) []slicer.Slice {
+	resCh := s.Slice(context.TODO(), bytes.NewReader(data), &slicer.SliceOptions{}, func(err error) {
+		require.NoError(t, err)
+	})
+	var slices []slicer.Slice
+	for sl := range resCh {
+		slices = append(slices, sl)
+	}
+	return slices
+}
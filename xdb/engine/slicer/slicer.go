@@ -31,4 +31,5 @@ type SliceMeta struct {
 	ID     string // sliceID, uuid
 	Hash   []byte // hash of slice content
 	Length uint64 // length of slice content
+	Key    []byte // key sealing the slice content, only for slices of files in convergent format
 }
@@ -0,0 +1,126 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// pairingBatchSize is the number of slices whose ciphertext is loaded at a time
+// to generate pairing based challenge material
+const pairingBatchSize = 16
+
+// cipherTextSource provides ciphertext of slices pushed to storage nodes.
+// Pairing based challenge material is generated on publishing, as slice indexes are assigned then,
+// so slices are released as soon as they are pushed, and ciphertext is loaded back in batches
+type cipherTextSource interface {
+	Load(es encryptor.EncryptedSliceMeta) ([]byte, error)
+	Close() error
+}
+
+// cipherSpill keeps ciphertext of slices pushed in a temporary file
+type cipherSpill struct {
+	f       *os.File
+	size    int64
+	offsets map[string]spillRange // by slice ID and node ID
+}
+
+type spillRange struct {
+	offset int64
+	length int64
+}
+
+func newCipherSpill() (*cipherSpill, error) {
+	f, err := ioutil.TempFile("", "xdb-slices-")
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to create temporary file for slices")
+	}
+	return &cipherSpill{
+		f:       f,
+		offsets: make(map[string]spillRange),
+	}, nil
+}
+
+// Put writes ciphertext of a slice into the file
+func (s *cipherSpill) Put(es encryptor.EncryptedSlice) error {
+	if _, err := s.f.WriteAt(es.CipherText, s.size); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write slice %s", es.SliceID)
+	}
+	s.offsets[es.SliceID+string(es.NodeID)] = spillRange{offset: s.size, length: int64(len(es.CipherText))}
+	s.size += int64(len(es.CipherText))
+	return nil
+}
+
+// Load reads ciphertext of a slice written before
+func (s *cipherSpill) Load(es encryptor.EncryptedSliceMeta) ([]byte, error) {
+	r, exist := s.offsets[es.SliceID+string(es.NodeID)]
+	if !exist {
+		return nil, errorx.New(errorx.ErrCodeInternal, "ciphertext of slice %s not found", es.SliceID)
+	}
+	cipherText := make([]byte, r.length)
+	if _, err := s.f.ReadAt(cipherText, r.offset); err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read slice %s", es.SliceID)
+	}
+	return cipherText, nil
+}
+
+// Close removes the file
+func (s *cipherSpill) Close() error {
+	s.f.Close()
+	return os.Remove(s.f.Name())
+}
+
+// partCipherTexts loads ciphertext of slices saved for parts of an upload session,
+// ciphertext of only one part is held at a time
+type partCipherTexts struct {
+	storage   UploadStorage
+	sessionID string
+	parts     map[string]int // part number by slice ID and node ID
+
+	part   int
+	loaded map[string][]byte
+}
+
+func (p *partCipherTexts) Load(es encryptor.EncryptedSliceMeta) ([]byte, error) {
+	key := es.SliceID + string(es.NodeID)
+	part, exist := p.parts[key]
+	if !exist {
+		return nil, errorx.New(errorx.ErrCodeInternal, "ciphertext of slice %s not found", es.SliceID)
+	}
+	if part != p.part || p.loaded == nil {
+		slices, err := p.storage.LoadCipherText(p.sessionID, part)
+		if err != nil {
+			return nil, errorx.Wrap(err, "failed to load ciphertext of part %d", part)
+		}
+		p.part = part
+		p.loaded = make(map[string][]byte, len(slices))
+		for _, s := range slices {
+			p.loaded[s.SliceID+string(s.NodeID)] = s.CipherText
+		}
+	}
+	cipherText, exist := p.loaded[key]
+	if !exist {
+		return nil, errorx.New(errorx.ErrCodeInternal, "ciphertext of slice %s not found", es.SliceID)
+	}
+	return cipherText, nil
+}
+
+func (p *partCipherTexts) Close() error {
+	p.loaded = nil
+	return nil
+}
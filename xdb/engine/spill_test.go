@@ -0,0 +1,105 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/upload"
+)
+
+func encryptedSlice(id, node string, length int) encryptor.EncryptedSlice {
+	return encryptor.EncryptedSlice{
+		EncryptedSliceMeta: encryptor.EncryptedSliceMeta{
+			SliceID: id,
+			NodeID:  []byte(node),
+			Length:  uint64(length),
+		},
+		CipherText: bytes.Repeat([]byte(id+node), length/len(id+node)),
+	}
+}
+
+func TestWrittenSlicesReleased(t *testing.T) {
+	spill, err := newCipherSpill()
+	require.NoError(t, err)
+	ws := writtenSlices{cipherTexts: spill}
+
+	// slices are released once pushed in pairing based challenge
+	slices := []encryptor.EncryptedSlice{
+		encryptedSlice("s1", "n1", 1024),
+		encryptedSlice("s1", "n2", 1024),
+		encryptedSlice("s2", "n1", 512),
+	}
+	for i, es := range slices {
+		require.NoError(t, ws.add(finishWrittenSlice{eSlice: es, storIndex: es.SliceID}))
+		require.Nil(t, ws.encSlices[i].CipherText)
+	}
+	require.Equal(t, []string{"s1", "s1", "s2"}, ws.storIndexes)
+	for i, es := range ws.encSlices {
+		cipherText, err := ws.cipherTexts.Load(es.EncryptedSliceMeta)
+		require.NoError(t, err)
+		require.Equal(t, slices[i].CipherText, cipherText)
+	}
+	_, err = ws.cipherTexts.Load(encryptedSlice("s2", "n2", 2).EncryptedSliceMeta)
+	require.Error(t, err)
+
+	// the temporary file is removed on release
+	ws.release()
+	_, err = os.Stat(spill.f.Name())
+	require.True(t, os.IsNotExist(err))
+
+	// ciphertext is not kept without pairing based challenge
+	ws = writtenSlices{}
+	require.NoError(t, ws.add(finishWrittenSlice{eSlice: encryptedSlice("s1", "n1", 8)}))
+	require.Nil(t, ws.encSlices[0].CipherText)
+	ws.release()
+}
+
+func TestPartCipherTexts(t *testing.T) {
+	root, err := ioutil.TempDir("", "upload")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	storage, err := upload.New(root)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	part1 := []encryptor.EncryptedSlice{encryptedSlice("s1", "n1", 8), encryptedSlice("s2", "n1", 8)}
+	part2 := []encryptor.EncryptedSlice{encryptedSlice("s3", "n2", 8)}
+	require.NoError(t, storage.SaveCipherText("session", 1, part1))
+	require.NoError(t, storage.SaveCipherText("session", 2, part2))
+
+	p := &partCipherTexts{
+		storage:   storage,
+		sessionID: "session",
+		parts:     map[string]int{"s1n1": 1, "s2n1": 1, "s3n2": 2},
+	}
+	defer p.Close()
+	for _, es := range append(part1, part2...) {
+		cipherText, err := p.Load(es.EncryptedSliceMeta)
+		require.NoError(t, err)
+		require.Equal(t, es.CipherText, cipherText)
+	}
+	// only ciphertext of the last part loaded is held
+	require.Equal(t, 2, p.part)
+	require.Equal(t, 1, len(p.loaded))
+
+	_, err = p.Load(encryptedSlice("s4", "n1", 8).EncryptedSliceMeta)
+	require.Error(t, err)
+}
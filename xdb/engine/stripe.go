@@ -0,0 +1,131 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+
+	"github.com/PaddlePaddle/PaddleDTX/crypto/core/hash"
+	"github.com/google/uuid"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/copier"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/slicer"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/erasure"
+)
+
+// locateStripeRoutine block current routine, used for erasure coded namespace.
+// Slices are grouped into stripes of ns.DataShards slices, ns.ParityShards parity shards are computed
+// for each stripe, and every shard of a stripe is located on a distinct storage node.
+// Private meta of all the shards in stripe order is sent to shardQueue,
+// metaQueue only receives data slices, the same as locateRoutine.
+func (e *Engine) locateStripeRoutine(ctx context.Context, ns blockchain.Namespace, nodes blockchain.NodeHs,
+	sliceQueue <-chan slicer.Slice, locatedQueue chan<- copier.LocatedSlice, metaQueue chan<- slicer.SliceMeta,
+	shardQueue chan<- blockchain.PrivateSliceMeta, onErr func(err error)) {
+	defer func() {
+		close(metaQueue)
+		close(shardQueue)
+		close(locatedQueue)
+	}()
+
+	stripe := 0
+	var data []slicer.Slice
+	flush := func() error {
+		if len(data) == 0 {
+			return nil
+		}
+		raw := make([][]byte, 0, len(data))
+		for _, s := range data {
+			raw = append(raw, s.Data)
+		}
+		parity, err := erasure.Encode(raw, ns.ParityShards)
+		if err != nil {
+			return errorx.Wrap(err, "failed to encode stripe %d", stripe)
+		}
+		shards := append([]slicer.Slice{}, data...)
+		for _, p := range parity {
+			shards = append(shards, makeParityShard(p))
+		}
+
+		// shards of a stripe must be stored on distinct nodes, and are spread across failure domains
+		excludes := make(map[string]struct{})
+		var holders blockchain.Nodes
+		for i, shard := range shards {
+			located, err := e.copier.Select(shard, nodes, &copier.SelectOptions{
+				Replica:   1,
+				Excludes:  excludes,
+				Placement: ns.Placement,
+				Holders:   holders,
+			})
+			if err != nil {
+				return errorx.Wrap(err, "failed to select nodes for shard %x", shard.Hash)
+			}
+			for _, n := range located.Nodes {
+				excludes[string(n.ID)] = struct{}{}
+			}
+			holders = append(holders, located.Nodes...)
+			shardQueue <- blockchain.PrivateSliceMeta{
+				SliceID:   shard.ID,
+				PlainHash: shard.Hash,
+				Stripe:    stripe,
+				Parity:    i >= len(data),
+				Length:    shard.Length,
+			}
+			locatedQueue <- located
+		}
+		for _, s := range data {
+			metaQueue <- s.SliceMeta
+		}
+
+		stripe++
+		data = nil
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case s, ok := <-sliceQueue:
+			if !ok {
+				if err := flush(); err != nil {
+					onErr(err)
+				}
+				return
+			}
+			data = append(data, s)
+			if len(data) < ns.DataShards {
+				continue
+			}
+			if err := flush(); err != nil {
+				onErr(err)
+				return
+			}
+		}
+	}
+}
+
+// makeParityShard digests a parity shard by sha256 to make a Slice
+func makeParityShard(bs []byte) slicer.Slice {
+	id, _ := uuid.NewRandom()
+	return slicer.Slice{
+		SliceMeta: slicer.SliceMeta{
+			ID:     id.String(),
+			Hash:   hash.HashUsingSha256(bs),
+			Length: uint64(len(bs)),
+		},
+		Data: bs,
+	}
+}
@@ -14,9 +14,13 @@
 package types
 
 import (
+	"strings"
 	"time"
 
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/compressor"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/erasure"
 )
 
 // WriteOptions options for writing file to system
@@ -28,6 +32,10 @@ type WriteOptions struct {
 	Description string `json:"desc"`
 	Extra       string `json:"ext"`
 	Token       string `json:"-"`
+
+	// algorithm to compress the file with, overrides the compression of namespace,
+	// compressor.None disables compression
+	Compression string `json:"compression,omitempty"`
 }
 
 // Valid checks if WriteOptions is valid
@@ -48,15 +56,93 @@ func (o *WriteOptions) Valid() error {
 		return errorx.New(errorx.ErrCodeParam, "empty file name")
 	}
 
-	if o.ExpireTime <= time.Now().UnixNano() {
+	// files uploaded without expire time expire after the default TTL of namespace
+	if o.ExpireTime != 0 && o.ExpireTime <= time.Now().UnixNano() {
 		return errorx.New(errorx.ErrCodeParam, "invalid file expire time")
 	}
 
+	if err := compressor.Check(o.Compression); err != nil {
+		return errorx.Wrap(err, "invalid param, compression")
+	}
+
+	return nil
+}
+
+// InitiateUploadOptions options for initiating a session to upload a file in parts,
+// the file of FileLength bytes is cut into parts of PartSize bytes, except the last part
+type InitiateUploadOptions struct {
+	WriteOptions
+	FileLength uint64 `json:"length"`
+	PartSize   uint64 `json:"partSize"`
+}
+
+// Valid checks if InitiateUploadOptions is valid
+func (o *InitiateUploadOptions) Valid() error {
+	if err := o.WriteOptions.Valid(); err != nil {
+		return err
+	}
+	if o.PartSize == 0 {
+		return errorx.New(errorx.ErrCodeParam, "invalid part size")
+	}
+	// parts are encrypted at their plaintext offsets, which are unknown once the file is compressed
+	if compressor.Enabled(o.Compression) {
+		return errorx.New(errorx.ErrCodeParam, "compression is not supported for files uploaded in parts")
+	}
+	return nil
+}
+
+// UploadSessionOptions options for operating an upload session, Action is
+// one of the UploadAction values and set by server according to the api requested
+type UploadSessionOptions struct {
+	SessionID   string `json:"sessionID"`
+	Action      string `json:"action"`
+	CurrentTime int64  `json:"ctime"`
+	User        string `json:"user"`
+	Token       string `json:"-"`
+}
+
+// Valid checks if UploadSessionOptions is valid
+func (o *UploadSessionOptions) Valid() error {
+	if len(o.SessionID) == 0 {
+		return errorx.New(errorx.ErrCodeParam, "empty session id")
+	}
+	if len(o.User) == 0 {
+		return errorx.New(errorx.ErrCodeParam, "empty user")
+	}
+	if len(o.Token) == 0 {
+		return errorx.New(errorx.ErrCodeParam, "empty token")
+	}
+	return nil
+}
+
+// actions of operating an upload session, signed in UploadSessionOptions
+// so that a request can not be replayed to do something else
+const (
+	UploadActionPart     = "part"
+	UploadActionList     = "list"
+	UploadActionComplete = "complete"
+	UploadActionAbort    = "abort"
+)
+
+// UploadPartOptions options for uploading a part of a file, parts are numbered from 1
+type UploadPartOptions struct {
+	UploadSessionOptions
+	PartNumber int `json:"part"`
+}
+
+// Valid checks if UploadPartOptions is valid
+func (o *UploadPartOptions) Valid() error {
+	if err := o.UploadSessionOptions.Valid(); err != nil {
+		return err
+	}
+	if o.PartNumber <= 0 {
+		return errorx.New(errorx.ErrCodeParam, "invalid part number")
+	}
 	return nil
 }
 
 // ReadOptions read file from engine
-// use user+namespace+filename or fileID to locate a file
+// use user+namespace+filename(+version) or fileID to locate a file
 // will use fileID first if not empty
 type ReadOptions struct {
 	User      string `json:"user"`
@@ -65,6 +151,21 @@ type ReadOptions struct {
 	FileName  string `json:"name"`
 	FileID    string `json:"file_id"`
 	Token     string `json:"-"`
+
+	// version of the file to read by name, the latest version is read if not set
+	Version int64 `json:"version,omitempty"`
+
+	// range of the file to read, the whole file is read if none is set.
+	// Length 0 means reading to the end of the file, and Suffix reads the last Suffix bytes,
+	// Offset and Length are ignored if Suffix is set
+	Offset uint64 `json:"-"`
+	Length uint64 `json:"-"`
+	Suffix uint64 `json:"-"`
+}
+
+// Ranged returns true if a range of the file is requested
+func (r *ReadOptions) Ranged() bool {
+	return r.Offset > 0 || r.Length > 0 || r.Suffix > 0
 }
 
 // Valid check if ReadOptions is valid
@@ -93,11 +194,16 @@ func (r *ReadOptions) Valid() error {
 	return nil
 }
 
-// PushOptions options for pushing slice to storage node
+// PushOptions options for pushing slice to storage node,
+// the request is signed by the dataOwner node pushing the slice
 type PushOptions struct {
-	SliceID   string
-	SourceID  string // dataOwner node id
-	NotASlice bool   // denote if pushed content is not a slice, current pairing based challenge sigmas is supported
+	SliceID     string `json:"slice_id"`
+	SourceID    string `json:"source_id"`         // dataOwner node id
+	FileID      string `json:"file_id,omitempty"` // ID of the file the slice belongs to, used to scrub the slice
+	Timestamp   int64  `json:"timestamp"`
+	NotASlice   bool   `json:"notASlice"`    // denote if pushed content is not a slice, current pairing based challenge sigmas is supported
+	ContentHash string `json:"content_hash"` // hex encoded sha256 of the content pushed, so that the signature covers the content
+	Signature   string `json:"signature"`
 }
 
 // PullOptions options for pulling slice from storage node
@@ -111,6 +217,15 @@ type PullOptions struct {
 	Signature string `json:"signature"`
 }
 
+// DeleteSliceOptions options for deleting slice from storage node,
+// the request is signed by the dataOwner node which pushed the slice
+type DeleteSliceOptions struct {
+	SliceID   string `json:"slice_id"`
+	StorIndex string `json:"slice_stor_index"`
+	Timestamp int64  `json:"timestamp"`
+	Signature string `json:"signature"`
+}
+
 // NodeOperateOptions options for setting storage node with online or offline status on blockchain
 type NodeOperateOptions struct {
 	NodeID string `json:"node"`
@@ -127,6 +242,8 @@ type ListFileOptions struct {
 	TimeEnd     int64
 	CurrentTime int64 // current time
 	Limit       int64 // file limit
+
+	Cursor string // cursor of the page returned by the previous one
 }
 
 // Valid checks if ListFileOptions is valid
@@ -137,6 +254,24 @@ func (o *ListFileOptions) Valid() error {
 	return nil
 }
 
+// ListFileVersionsOptions options for listing versions of a file name
+type ListFileVersionsOptions struct {
+	Owner     string // file owner
+	Namespace string // file namespace
+	Name      string // file name
+
+	CurrentTime int64 // current time
+	Limit       int64 // version limit
+}
+
+// Valid checks if ListFileVersionsOptions is valid
+func (o *ListFileVersionsOptions) Valid() error {
+	if len(o.Namespace) == 0 || len(o.Name) == 0 {
+		return errorx.New(errorx.ErrCodeParam, "empty namespace or file name")
+	}
+	return nil
+}
+
 // UpdateFileEtimeOptions options for updating file expire time
 type UpdateFileEtimeOptions struct {
 	FileID      string `json:"id"`
@@ -154,6 +289,22 @@ func (o *UpdateFileEtimeOptions) Valid() error {
 	return nil
 }
 
+// DeleteFileOptions options for deleting file by its owner
+type DeleteFileOptions struct {
+	FileID      string `json:"id"`
+	CurrentTime int64  `json:"ctime"`
+	User        string `json:"user"`
+	Token       string `json:"-"`
+}
+
+// Valid checks if DeleteFileOptions is valid
+func (o *DeleteFileOptions) Valid() error {
+	if len(o.FileID) == 0 {
+		return errorx.New(errorx.ErrCodeParam, "invalid file id")
+	}
+	return nil
+}
+
 // AddNsOptions options for adding namespace on blockchain
 type AddNsOptions struct {
 	Namespace   string `json:"ns"`
@@ -162,11 +313,54 @@ type AddNsOptions struct {
 	CreateTime  int64  `json:"ctime"`
 	User        string `json:"user"`
 	Token       string `json:"-"`
+
+	// erasure coding, files are stored as DataShards data shards and ParityShards parity shards per stripe
+	DataShards   int `json:"dataShards,omitempty"`
+	ParityShards int `json:"parityShards,omitempty"`
+
+	// algorithm to compress files written into the namespace with
+	Compression string `json:"compression,omitempty"`
+
+	// placement policy, replicas are spread across at least MinZones zones and only placed in Regions,
+	// Regions are separated by commas
+	MinZones int    `json:"minZones,omitempty"`
+	Regions  string `json:"regions,omitempty"`
 }
 
 // Valid checks if AddNsOptions is valid
 func (o *AddNsOptions) Valid() error {
-	return checkOperateNsOptions(o.User, o.Namespace, o.Token, o.Replica)
+	if err := checkOperateNsOptions(o.User, o.Namespace, o.Token, o.Replica); err != nil {
+		return err
+	}
+	if err := compressor.Check(o.Compression); err != nil {
+		return errorx.Wrap(err, "invalid param, compression")
+	}
+	if o.MinZones < 0 {
+		return errorx.New(errorx.ErrCodeParam, "invalid param minZones")
+	}
+	for _, r := range strings.Split(o.Regions, ",") {
+		if len(o.Regions) > 0 && len(strings.TrimSpace(r)) == 0 {
+			return errorx.New(errorx.ErrCodeParam, "invalid param regions, empty region")
+		}
+	}
+	if o.DataShards == 0 && o.ParityShards == 0 {
+		// replicas of a slice can't be spread across more zones than the number of replicas
+		if o.MinZones > o.Replica {
+			return errorx.New(errorx.ErrCodeParam, "invalid param minZones, must be no greater than replica")
+		}
+		return nil
+	}
+	if err := erasure.Check(o.DataShards, o.ParityShards); err != nil {
+		return errorx.Wrap(err, "invalid param, erasure coding shards")
+	}
+	if o.MinZones > o.DataShards+o.ParityShards {
+		return errorx.New(errorx.ErrCodeParam, "invalid param minZones, must be no greater than shards per stripe")
+	}
+	// every shard of an erasure coded file is stored only once
+	if o.Replica != 1 {
+		return errorx.New(errorx.ErrCodeParam, "invalid param replica, must be 1 for erasure coded namespace")
+	}
+	return nil
 }
 
 // UpdateNsOptions options for updating namespace replica
@@ -183,6 +377,36 @@ func (o *UpdateNsOptions) Valid() error {
 	return checkOperateNsOptions(o.User, o.Namespace, o.Token, o.Replica)
 }
 
+// UpdateNsLifecycleOptions options for updating namespace lifecycle policy, durations are in nanoseconds,
+// the policy is removed if none of the rules is set
+type UpdateNsLifecycleOptions struct {
+	Namespace   string `json:"ns"`
+	DefaultTTL  int64  `json:"defaultTTL"`
+	AutoRenew   bool   `json:"autoRenew"`
+	GracePeriod int64  `json:"gracePeriod"`
+	CurrentTime int64  `json:"ctime"`
+	User        string `json:"user"`
+	Token       string `json:"-"`
+}
+
+// Valid checks if UpdateNsLifecycleOptions is valid
+func (o *UpdateNsLifecycleOptions) Valid() error {
+	if err := checkOperateNsOptions(o.User, o.Namespace, o.Token, 1); err != nil {
+		return err
+	}
+	if o.DefaultTTL < 0 {
+		return errorx.New(errorx.ErrCodeParam, "invalid param defaultTTL")
+	}
+	if o.AutoRenew && o.DefaultTTL == 0 {
+		return errorx.New(errorx.ErrCodeParam, "invalid param defaultTTL, files are renewed by default TTL")
+	}
+	if o.GracePeriod < 0 || o.GracePeriod > blockchain.FileRetainPeriod.Nanoseconds() {
+		return errorx.New(errorx.ErrCodeParam, "invalid param gracePeriod, must be no greater than %v",
+			blockchain.FileRetainPeriod)
+	}
+	return nil
+}
+
 // checkOperateNsOptions checks UpdateNsOptions or AddNsOptions is valid
 func checkOperateNsOptions(user, ns, token string, replica int) error {
 	if len(user) == 0 {
@@ -211,6 +435,8 @@ type ListFileAuthOptions struct {
 	TimeStart  int64
 	TimeEnd    int64
 	Limit      int64
+
+	Cursor string // cursor of the page returned by the previous one
 }
 
 // ConfirmAuthOptions parameters for authorizers confirm or reject file authorization application
@@ -241,3 +467,58 @@ func (o *ConfirmAuthOptions) Valid(status bool) error {
 	}
 	return nil
 }
+
+// SearchMaxNumber is the max number of files or challenges searched in the local index at a time
+const SearchMaxNumber = 1000
+
+// SearchFileOptions options for searching files in the local index of dataOwner node
+//  Keywords are words found in file name or description, and Ext are values of top-level fields
+//  in the json extension of files. Files are sorted by SortBy, one of time, name, size and expire,
+//  in descending order unless Asc. Files expired at CurrentTime are excluded unless it's zero
+type SearchFileOptions struct {
+	Owner     string            // file owner, the local node by default
+	Namespace string            // file namespace, empty for all namespaces
+	Keywords  string            // words to search
+	Ext       map[string]string // values of extension fields
+	SortBy    string
+	Asc       bool
+
+	TimeStart   int64 // publish time period
+	TimeEnd     int64
+	CurrentTime int64 // current time
+
+	Cursor string // cursor of the page returned by the previous search
+	Limit  int64  // file limit
+}
+
+// Valid checks if SearchFileOptions is valid
+func (o *SearchFileOptions) Valid() error {
+	if o.Limit <= 0 || o.Limit > SearchMaxNumber {
+		return errorx.New(errorx.ErrCodeParam, "invalid limit, the value must be in (0, %d]", SearchMaxNumber)
+	}
+	return nil
+}
+
+// SearchChallengeOptions options for searching challenges of files in the local index of dataOwner node,
+// challenges are sorted by challenge time in descending order unless Asc
+type SearchChallengeOptions struct {
+	Owner      string // file owner, the local node by default
+	TargetNode string // storage node, optional
+	FileID     string // optional
+	Status     string // challenge status, optional
+	Asc        bool
+
+	TimeStart int64 // challenge time period
+	TimeEnd   int64
+
+	Cursor string // cursor of the page returned by the previous search
+	Limit  int64  // challenge limit
+}
+
+// Valid checks if SearchChallengeOptions is valid
+func (o *SearchChallengeOptions) Valid() error {
+	if o.Limit <= 0 || o.Limit > SearchMaxNumber {
+		return errorx.New(errorx.ErrCodeParam, "invalid limit, the value must be in (0, %d]", SearchMaxNumber)
+	}
+	return nil
+}
@@ -13,9 +13,80 @@
 
 package types
 
-// WriteResponse is response of uploading a file, only task id
+import (
+	"io"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+)
+
+// WriteResponse is response of uploading a file, Version is the version of the file with the same name
 type WriteResponse struct {
-	FileID string `json:"file_id"`
+	FileID  string `json:"file_id"`
+	Version int64  `json:"version"`
+}
+
+// UploadSession is the state of a session uploading a file in parts, published on chain when completed
+//  PartCount is the number of parts the file is cut into, Parts are the ones already uploaded
+type UploadSession struct {
+	SessionID  string         `json:"sessionID"`
+	FileID     string         `json:"fileID"`
+	Namespace  string         `json:"ns"`
+	FileName   string         `json:"name"`
+	FileLength uint64         `json:"length"`
+	PartSize   uint64         `json:"partSize"`
+	PartCount  int            `json:"partCount"`
+	ExpireTime int64          `json:"expireTime"`
+	CreateTime int64          `json:"ctime"`
+	Parts      []UploadedPart `json:"parts"`
+}
+
+// UploadedPart is a part of file uploaded, PlainHash is sha256 hash of the part
+// for clients to check if the part is the same as the local one when resuming an upload
+type UploadedPart struct {
+	Number     int    `json:"number"`
+	Length     uint64 `json:"length"`
+	PlainHash  []byte `json:"plainHash"`
+	UploadTime int64  `json:"utime"`
+}
+
+// ReadResponse is response of downloading a file, reading the plaintext of the requested range
+//  Offset and Length are the range actually read, FileLength is the plaintext length of the whole file
+type ReadResponse struct {
+	io.ReadCloser
+
+	Offset     uint64
+	Length     uint64
+	FileLength uint64
+}
+
+// SliceUsage is the storage used by a dataOwner node on a storage node,
+//  MaxBytes and MaxSlices are its quota, zero means unlimited
+type SliceUsage struct {
+	Owner     string `json:"owner"`
+	Bytes     int64  `json:"bytes"`
+	Slices    int64  `json:"slices"`
+	MaxBytes  int64  `json:"maxBytes"`
+	MaxSlices int64  `json:"maxSlices"`
+}
+
+// scrubbing status of slices stored on storage node
+const (
+	ScrubStatusCorrupt = "Corrupt"
+	ScrubStatusMissing = "Missing"
+)
+
+// SliceScrub is a slice tracked by scrubbing on a storage node,
+//  Status is empty unless the slice is found corrupt or missing at CheckedAt,
+//  OrphanedAt is the time the slice is found not referenced by any file on blockchain, zero if referenced
+type SliceScrub struct {
+	SliceID    string `json:"sliceID"`
+	StorIndex  string `json:"storIndex"`
+	FileID     string `json:"fileID"`
+	Owner      string `json:"owner"`
+	PushedAt   int64  `json:"pushedAt"`
+	Status     string `json:"status,omitempty"`
+	CheckedAt  int64  `json:"checkedAt,omitempty"`
+	OrphanedAt int64  `json:"orphanedAt,omitempty"`
 }
 
 // PushResponse is response of receiving a slice
@@ -23,3 +94,24 @@ type WriteResponse struct {
 type PushResponse struct {
 	SliceStorIndex string `json:"slice_stor_index"`
 }
+
+// FilePage is a page of files listed from blockchain or searched in the local index of dataOwner node,
+// Next is the cursor to list or search the next page, empty if no files are left
+type FilePage struct {
+	Files []blockchain.File `json:"files"`
+	Next  string            `json:"next,omitempty"`
+}
+
+// ChallengePage is a page of challenges listed from blockchain or searched in the local index of dataOwner node,
+// Next is the cursor to list or search the next page, empty if no challenges are left
+type ChallengePage struct {
+	Challenges []blockchain.Challenge `json:"challenges"`
+	Next       string                 `json:"next,omitempty"`
+}
+
+// FileAuthPage is a page of file authorization applications listed from blockchain,
+// Next is the cursor to list the next page, empty if no applications are left
+type FileAuthPage struct {
+	FileAuths blockchain.FileAuthApplications `json:"fileAuths"`
+	Next      string                          `json:"next,omitempty"`
+}
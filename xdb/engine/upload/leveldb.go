@@ -0,0 +1,189 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package upload
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+const (
+	dbName = "uploadDB"
+
+	sessionPrefix = "session:"
+	cipherPrefix  = "cipher:"
+)
+
+// LevelDBStorage persists upload sessions in levelDB, along with ciphertext of pushed slices
+// which is required to generate pairing based challenge materials when a session is completed
+type LevelDBStorage struct {
+	root string
+	db   *leveldb.DB
+}
+
+// New creates a levelDB to save upload sessions
+func New(root string) (*LevelDBStorage, error) {
+	f := filepath.Join(root, dbName)
+	db, err := leveldb.OpenFile(f, nil)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "cannot open leveldb")
+	}
+
+	ldb := &LevelDBStorage{
+		root: root,
+		db:   db,
+	}
+	return ldb, nil
+}
+
+// Save saves or updates an upload session
+func (s *LevelDBStorage) Save(session Session) error {
+	value, err := json.Marshal(session)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal session")
+	}
+	if err := s.db.Put([]byte(sessionPrefix+session.ID), value, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to put session")
+	}
+	return nil
+}
+
+// Load gets an upload session by id
+func (s *LevelDBStorage) Load(id string) (Session, error) {
+	var session Session
+	value, err := s.db.Get([]byte(sessionPrefix+id), nil)
+	if err == leveldb.ErrNotFound {
+		return session, errorx.New(errorx.ErrCodeNotFound, "upload session %s not found", id)
+	} else if err != nil {
+		return session, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to get session")
+	}
+	if err := json.Unmarshal(value, &session); err != nil {
+		return session, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal session")
+	}
+	return session, nil
+}
+
+// List lists all upload sessions
+func (s *LevelDBStorage) List() ([]Session, error) {
+	iter := s.db.NewIterator(util.BytesPrefix([]byte(sessionPrefix)), nil)
+	defer iter.Release()
+
+	var sessions []Session
+	for iter.Next() {
+		var session Session
+		if err := json.Unmarshal(iter.Value(), &session); err != nil {
+			return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal session")
+		}
+		sessions = append(sessions, session)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate sessions")
+	}
+	return sessions, nil
+}
+
+// Delete deletes an upload session and ciphertext of all its parts
+func (s *LevelDBStorage) Delete(id string) error {
+	batch := leveldb.Batch{}
+	batch.Delete([]byte(sessionPrefix + id))
+	if err := s.deleteByPrefix(&batch, []byte(cipherPrefix+id+":")); err != nil {
+		return err
+	}
+	if err := s.db.Write(&batch, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write batch")
+	}
+	return nil
+}
+
+// SaveCipherText saves ciphertext of slices pushed for a part, replacing the ones saved before
+func (s *LevelDBStorage) SaveCipherText(sessionID string, part int, slices []encryptor.EncryptedSlice) error {
+	batch := leveldb.Batch{}
+	if err := s.deleteByPrefix(&batch, makeCipherPrefix(sessionID, part)); err != nil {
+		return err
+	}
+	for _, es := range slices {
+		value, err := json.Marshal(es)
+		if err != nil {
+			return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal slice")
+		}
+		batch.Put(makeCipherKey(sessionID, part, es.SliceID, es.NodeID), value)
+	}
+	if err := s.db.Write(&batch, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write batch")
+	}
+	return nil
+}
+
+// LoadCipherText gets ciphertext of slices pushed for a part
+func (s *LevelDBStorage) LoadCipherText(sessionID string, part int) ([]encryptor.EncryptedSlice, error) {
+	iter := s.db.NewIterator(util.BytesPrefix(makeCipherPrefix(sessionID, part)), nil)
+	defer iter.Release()
+
+	var slices []encryptor.EncryptedSlice
+	for iter.Next() {
+		var es encryptor.EncryptedSlice
+		if err := json.Unmarshal(iter.Value(), &es); err != nil {
+			return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal slice")
+		}
+		slices = append(slices, es)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate slices")
+	}
+	return slices, nil
+}
+
+// DeleteCipherText deletes ciphertext of slices pushed for a part
+func (s *LevelDBStorage) DeleteCipherText(sessionID string, part int) error {
+	batch := leveldb.Batch{}
+	if err := s.deleteByPrefix(&batch, makeCipherPrefix(sessionID, part)); err != nil {
+		return err
+	}
+	if err := s.db.Write(&batch, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write batch")
+	}
+	return nil
+}
+
+func (s *LevelDBStorage) Close() {
+	s.db.Close()
+}
+
+// deleteByPrefix adds deletion of all keys with prefix into batch
+func (s *LevelDBStorage) deleteByPrefix(batch *leveldb.Batch, prefix []byte) error {
+	iter := s.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+	for iter.Next() {
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	if err := iter.Error(); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate keys")
+	}
+	return nil
+}
+
+func makeCipherPrefix(sessionID string, part int) []byte {
+	return []byte(fmt.Sprintf("%s%s:%d:", cipherPrefix, sessionID, part))
+}
+
+func makeCipherKey(sessionID string, part int, sliceID string, nodeID []byte) []byte {
+	return []byte(fmt.Sprintf("%s%s:%d:%s:%x", cipherPrefix, sessionID, part, sliceID, nodeID))
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package upload
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+func TestLevelDBStorage(t *testing.T) {
+	root, err := ioutil.TempDir("", "upload")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	s, err := New(root)
+	require.NoError(t, err)
+	defer s.Close()
+
+	session := Session{
+		ID:         "s1",
+		FileID:     "f1",
+		FileLength: 100,
+		PartSize:   32,
+		Parts: map[int]Part{
+			2: {Number: 2, Length: 32, PlainHash: []byte("hash")},
+		},
+	}
+	require.Equal(t, 4, session.PartCount())
+	offset, length := session.PartRange(4)
+	require.Equal(t, uint64(96), offset)
+	require.Equal(t, uint64(4), length)
+
+	// sessions expire by the time they are created, not updated
+	now := time.Now()
+	session.CreateTime = now.Add(-MaxSessionLifetime + time.Minute).UnixNano()
+	session.UpdateTime = now.UnixNano()
+	require.False(t, session.Expired(now))
+	require.True(t, session.Expired(now.Add(time.Minute)))
+
+	require.NoError(t, s.Save(session))
+	require.NoError(t, s.Save(Session{ID: "s2"}))
+	got, err := s.Load("s1")
+	require.NoError(t, err)
+	require.Equal(t, session, got)
+	sessions, err := s.List()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(sessions))
+
+	// ciphertext of a part is replaced when the part is uploaded again
+	slice := func(id string) encryptor.EncryptedSlice {
+		return encryptor.EncryptedSlice{
+			EncryptedSliceMeta: encryptor.EncryptedSliceMeta{SliceID: id, NodeID: []byte("node")},
+			CipherText:         []byte(id),
+		}
+	}
+	require.NoError(t, s.SaveCipherText("s1", 2, []encryptor.EncryptedSlice{slice("a"), slice("b")}))
+	require.NoError(t, s.SaveCipherText("s1", 2, []encryptor.EncryptedSlice{slice("c")}))
+	require.NoError(t, s.SaveCipherText("s1", 3, []encryptor.EncryptedSlice{slice("d")}))
+	slices, err := s.LoadCipherText("s1", 2)
+	require.NoError(t, err)
+	require.Equal(t, []encryptor.EncryptedSlice{slice("c")}, slices)
+
+	require.NoError(t, s.DeleteCipherText("s1", 3))
+	slices, err = s.LoadCipherText("s1", 3)
+	require.NoError(t, err)
+	require.Empty(t, slices)
+
+	require.NoError(t, s.Delete("s1"))
+	_, err = s.Load("s1")
+	require.True(t, errorx.Is(err, errorx.ErrCodeNotFound))
+	slices, err = s.LoadCipherText("s1", 2)
+	require.NoError(t, err)
+	require.Empty(t, slices)
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package upload
+
+import (
+	"time"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+	ctype "github.com/PaddlePaddle/PaddleDTX/xdb/engine/challenger/merkle/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/slicer"
+)
+
+// MaxSessionLifetime is the longest time a session is kept since it's created, however often it's updated.
+// Slices of unfinished sessions are not on chain yet, so storage nodes must retain orphan slices longer than it.
+const MaxSessionLifetime = 48 * time.Hour
+
+// Session is a session uploading a file in parts, persisted by dataOwner node until it is completed or aborted.
+// Slices of every part are pushed to storage nodes as soon as the part is uploaded,
+// and the file is published on chain when the session is completed.
+type Session struct {
+	ID           string
+	FileID       string
+	Namespace    string
+	FileName     string
+	Description  string
+	Extra        string
+	ExpireTime   int64
+	FileLength   uint64
+	PartSize     uint64
+	CipherFormat string
+	KeyID        string // ID of the master key parts are encrypted with
+
+	CreateTime int64
+	UpdateTime int64 // time of the last operation, used to find abandoned sessions
+
+	Parts map[int]Part
+}
+
+// Expired checks whether the session has outlived MaxSessionLifetime
+func (s *Session) Expired(now time.Time) bool {
+	return now.Add(-MaxSessionLifetime).UnixNano() >= s.CreateTime
+}
+
+// PartCount returns the number of parts the file is cut into, an empty file still has a part
+func (s *Session) PartCount() int {
+	if s.FileLength == 0 || s.PartSize == 0 {
+		return 1
+	}
+	return int((s.FileLength + s.PartSize - 1) / s.PartSize)
+}
+
+// PartRange returns plaintext offset and length of a part in the file, parts are numbered from 1
+func (s *Session) PartRange(number int) (uint64, uint64) {
+	offset := uint64(number-1) * s.PartSize
+	if offset >= s.FileLength {
+		return s.FileLength, 0
+	}
+	length := s.FileLength - offset
+	if length > s.PartSize {
+		length = s.PartSize
+	}
+	return offset, length
+}
+
+// Part is a part of file uploaded, whose slices are pushed to storage nodes already
+type Part struct {
+	Number     int
+	Length     uint64
+	PlainHash  []byte
+	UploadTime int64
+	Salt       []byte // salt the part in chunked format is encrypted with, drawn every time it's uploaded
+
+	Slices    []slicer.SliceMeta           // slices of the part in order, referenced ones included
+	Shards    blockchain.FileStructure     // shards of erasure coded file, stripes are numbered within the part
+	Refs      []blockchain.PublicSliceMeta // replicas of slices referenced from other files
+	Pushed    []PushedSlice                // replicas of slices pushed to storage nodes
+	Materials []ctype.Material             // merkle challenge materials of pushed slices
+}
+
+// PushedSlice is a replica of slice pushed to a storage node
+type PushedSlice struct {
+	encryptor.EncryptedSliceMeta
+	StorIndex string
+}
@@ -33,4 +33,7 @@ const (
 	ErrCodeReadBlockchain  = "10011" // errors occurred when reading data from blockchain
 	ErrCodeWriteBlockchain = "10012" // errors occurred when writing data to blockchain
 	ErrCodeAlreadyUpdate   = "10013" // duplicate updating error
+
+	ErrCodeRangeNotSatisfiable = "10014" // requested range of a file not satisfiable
+	ErrCodeQuotaExceeded       = "10015" // storage quota exceeded
 )
@@ -7,6 +7,7 @@ require (
 	github.com/PaddlePaddle/PaddleDTX/crypto v0.0.0-20220705024525-b5b6c6a3ad76
 	github.com/Shopify/sarama v1.30.0 // indirect
 	github.com/cjqpker/slidewindow v1.0.2
+	github.com/golang/protobuf v1.4.2
 	github.com/google/go-querystring v1.1.0 // indirect
 	github.com/google/uuid v1.2.0
 	github.com/hashicorp/go-version v1.3.0 // indirect
@@ -19,6 +20,9 @@ require (
 	github.com/ipfs/go-ipfs-util v0.0.2
 	github.com/k0kubun/colorstring v0.0.0-20150214042306-9440f1994b88 // indirect
 	github.com/kataras/iris/v12 v12.2.0-alpha2.0.20210413181054-382e7c14cbd3
+	github.com/klauspost/compress v1.13.6
+	github.com/klauspost/cpuid v1.3.1 // indirect
+	github.com/klauspost/reedsolomon v1.9.3
 	github.com/lestrrat-go/file-rotatelogs v2.4.0+incompatible
 	github.com/lestrrat-go/strftime v1.0.5 // indirect
 	github.com/op/go-logging v0.0.0-20160315200505-970db520ece7 // indirect
@@ -30,6 +34,7 @@ require (
 	github.com/stretchr/testify v1.7.0
 	github.com/sykesm/zap-logfmt v0.0.4 // indirect
 	github.com/syndtr/goleveldb v1.0.1-0.20190923125748-758128399b1d
+	github.com/test-go/testify v1.1.4
 	github.com/xuperchain/xuper-sdk-go v0.0.0-20210430070222-16051cc40b09
 	github.com/xuperchain/xuperchain v0.0.0-20210208123615-2d08ff11de3e
 	github.com/yudai/pp v2.0.1+incompatible // indirect
@@ -30,20 +30,29 @@ import (
 	flag "github.com/spf13/pflag"
 
 	fabricblockchain "github.com/PaddlePaddle/PaddleDTX/xdb/blockchain/fabric"
+	localblockchain "github.com/PaddlePaddle/PaddleDTX/xdb/blockchain/local"
 	xchainblockchain "github.com/PaddlePaddle/PaddleDTX/xdb/blockchain/xchain"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/engine"
 	merklechallenger "github.com/PaddlePaddle/PaddleDTX/xdb/engine/challenger/merkle"
 	pairingchallenger "github.com/PaddlePaddle/PaddleDTX/xdb/engine/challenger/pairing"
 	randomcopier "github.com/PaddlePaddle/PaddleDTX/xdb/engine/copier/random"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/dedup"
 	softencryptor "github.com/PaddlePaddle/PaddleDTX/xdb/engine/encryptor/soft"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/index"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/quota"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/scrub"
+	fastcdcslicer "github.com/PaddlePaddle/PaddleDTX/xdb/engine/slicer/fastcdc"
 	simpleslicer "github.com/PaddlePaddle/PaddleDTX/xdb/engine/slicer/simple"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/engine/upload"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/peer"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/server"
 	storage "github.com/PaddlePaddle/PaddleDTX/xdb/storage"
 	ipfs_storage "github.com/PaddlePaddle/PaddleDTX/xdb/storage/ipfs"
 	local_storage "github.com/PaddlePaddle/PaddleDTX/xdb/storage/local"
+	pack_storage "github.com/PaddlePaddle/PaddleDTX/xdb/storage/pack"
+	s3_storage "github.com/PaddlePaddle/PaddleDTX/xdb/storage/s3"
 )
 
 var (
@@ -123,6 +132,16 @@ func getDataOwnerEngine(localNode peer.Local, blockchain engine.Blockchain, conf
 	engineOption.Encryptor = mustGetEncryptor(conf.Encryptor)
 	engineOption.Challenger = mustGetChallenger(conf.Challenger, localNode.PrivateKey)
 	engineOption.Copier = mustGetCopier(conf.Copier, localNode.PrivateKey)
+	engineOption.UploadStor, engineOption.UploadTimeout = mustGetUploadStorage(conf.Upload)
+	if conf.Index != nil {
+		engineOption.Index = mustGetIndex(conf.Index)
+		engineOption.IndexOwners = conf.Index.Owners
+		engineOption.IndexInterval = time.Duration(conf.Index.SyncInterval) * time.Minute
+	}
+	// slices are only deduplicated by content-defined slicers
+	if conf.Slicer.Type == "fastcdcSlicer" {
+		engineOption.DedupIndex = mustGetDedupIndex(conf.Dedup)
+	}
 	engine, err := engine.NewEngine(conf.Monitor, &engineOption)
 	if err != nil {
 		appExit(err)
@@ -133,12 +152,25 @@ func getDataOwnerEngine(localNode peer.Local, blockchain engine.Blockchain, conf
 
 // getStorageEngine initiates Storage Engine.
 func getStorageEngine(localNode peer.Local, blockchain engine.Blockchain, conf *config.StorageConf) *engine.Engine {
+	// failure domains registered with the storage node
+	localNode.Region = conf.Region
+	localNode.Zone = conf.Zone
+	localNode.Rack = conf.Rack
+
 	engineOption := engine.NewEngineOption{
 		LocalNode: localNode,
 		Chain:     blockchain,
 	}
 	engineOption.SliceStor = mustGetSliceStorage(conf)
 	engineOption.ProveStor = mustGetProveStorage(conf)
+	if conf.Quota != nil {
+		engineOption.Quota = mustGetQuota(conf.Quota)
+	}
+	if conf.Scrub != nil {
+		engineOption.Scrub = mustGetScrub(conf.Scrub)
+		engineOption.ScrubInterval = time.Duration(conf.Scrub.Interval) * time.Hour
+		engineOption.ScrubRate = conf.Scrub.BytesPerSecond
+	}
 	engine, err := engine.NewEngine(conf.Monitor, &engineOption)
 	if err != nil {
 		appExit(err)
@@ -156,6 +188,12 @@ func mustGetSlicer(conf *config.DataOwnerSlicerConf) engine.Slicer {
 			appExit(err)
 		}
 		s = simpleSlicer
+	case "fastcdcSlicer":
+		fastcdcSlicer, err := fastcdcslicer.New(conf.FastCDCSlicer)
+		if err != nil {
+			appExit(err)
+		}
+		s = fastcdcSlicer
 	default:
 		appExit(errors.New("invalid slicer type: " + conf.Type))
 	}
@@ -181,8 +219,9 @@ func mustGetEncryptor(conf *config.DataOwnerEncryptorConf) engine.Encryptor {
 }
 
 // mustGetChallenger initiates Challenger
-//  Pairing-based and MerkleTree-based are both supported
-//  see more from engine.challenger
+//
+//	Pairing-based and MerkleTree-based are both supported
+//	see more from engine.challenger
 func mustGetChallenger(conf *config.DataOwnerChallenger, signer ecdsa.PrivateKey) engine.Challenger {
 	var err error
 	var c engine.Challenger
@@ -203,7 +242,7 @@ func mustGetChallenger(conf *config.DataOwnerChallenger, signer ecdsa.PrivateKey
 }
 
 // mustGetBlockchain initiates XChain client which is used for connecting and requesting blockchain
-// XChain and Fabric are both supported
+// XChain and Fabric are both supported, and the local blockchain running the contract in process for development
 func mustGetBlockchain(conf *config.BlockchainConf) engine.Blockchain {
 	var b engine.Blockchain
 	var err error
@@ -212,6 +251,8 @@ func mustGetBlockchain(conf *config.BlockchainConf) engine.Blockchain {
 		b, err = xchainblockchain.New(conf.Xchain)
 	case "fabric":
 		b, err = fabricblockchain.New(conf.Fabric)
+	case "local":
+		b, err = localblockchain.New(conf.Local)
 	default:
 		appExit(errors.New("invalid blockchain type: " + conf.Type))
 	}
@@ -236,6 +277,50 @@ func mustGetCopier(conf *config.DataOwnerCopierConf, signer ecdsa.PrivateKey) en
 	return c
 }
 
+// mustGetUploadStorage initiates leveldb to store sessions of uploading files in parts
+func mustGetUploadStorage(conf *config.DataOwnerUploadConf) (engine.UploadStorage, time.Duration) {
+	root, timeout := "./upload", int64(24)
+	if conf != nil {
+		if conf.LeveldbRoot != "" {
+			root = conf.LeveldbRoot
+		}
+		if conf.SessionTimeout > 0 {
+			timeout = conf.SessionTimeout
+		}
+	}
+	if time.Duration(timeout)*time.Hour > upload.MaxSessionLifetime {
+		appExit(errorx.New(errorx.ErrCodeConfig, "sessionTimeout must not exceed %v, the lifetime of upload sessions",
+			upload.MaxSessionLifetime))
+	}
+	s, err := upload.New(root)
+	if err != nil {
+		appExit(errorx.Wrap(err, "failed to create upload storage"))
+	}
+	return s, time.Duration(timeout) * time.Hour
+}
+
+// mustGetIndex initiates the local index of files and challenges synced from blockchain
+func mustGetIndex(conf *config.DataOwnerIndexConf) engine.Index {
+	i, err := index.New(conf)
+	if err != nil {
+		appExit(fmt.Errorf("failed to create index, err: %v", err))
+	}
+	return i
+}
+
+// mustGetDedupIndex initiates leveldb to index slices of deduplicated namespaces
+func mustGetDedupIndex(conf *config.DataOwnerDedupConf) engine.DedupIndex {
+	root := "./dedup"
+	if conf != nil && conf.LeveldbRoot != "" {
+		root = conf.LeveldbRoot
+	}
+	i, err := dedup.New(root)
+	if err != nil {
+		appExit(errorx.Wrap(err, "failed to create dedup index"))
+	}
+	return i
+}
+
 // mustGetStorage initiates storage to store encrypted slices
 func mustGetSliceStorage(conf *config.StorageConf) engine.SliceStorage {
 
@@ -253,6 +338,22 @@ func mustGetSliceStorage(conf *config.StorageConf) engine.SliceStorage {
 		if err != nil {
 			appExit(fmt.Errorf("failed to create ipfs storage, err: %v", err))
 		}
+	case "pack":
+		if conf.Mode.Pack == nil {
+			appExit(errors.New("missing pack storage configuration"))
+		}
+		s, err = pack_storage.New(conf.Mode.Pack)
+		if err != nil {
+			appExit(fmt.Errorf("failed to create pack storage, err: %v", err))
+		}
+	case "s3":
+		if conf.Mode.S3 == nil {
+			appExit(errors.New("missing s3 storage configuration"))
+		}
+		s, err = s3_storage.New(conf.Mode.S3)
+		if err != nil {
+			appExit(fmt.Errorf("failed to create s3 storage, err: %v", err))
+		}
 	default:
 		appExit(errors.New("invalid storage type: " + storageType))
 	}
@@ -270,6 +371,24 @@ func mustGetProveStorage(conf *config.StorageConf) engine.ProveStorage {
 	return s
 }
 
+// mustGetQuota initiates quota to limit the storage used by each dataOwner node
+func mustGetQuota(conf *config.QuotaConf) engine.Quota {
+	q, err := quota.New(conf)
+	if err != nil {
+		appExit(fmt.Errorf("failed to create quota, err: %v", err))
+	}
+	return q
+}
+
+// mustGetScrub initiates scrub records to track slices stored and those found corrupt or missing
+func mustGetScrub(conf *config.ScrubConf) engine.Scrub {
+	s, err := scrub.New(conf)
+	if err != nil {
+		appExit(fmt.Errorf("failed to create scrub, err: %v", err))
+	}
+	return s
+}
+
 // mustGetNode initiates local account
 func mustGetNode(conf *config.ServerConf) peer.Local {
 	if conf == nil {
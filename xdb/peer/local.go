@@ -21,4 +21,9 @@ type Local struct {
 	Name       string
 	PrivateKey ecdsa.PrivateKey
 	Address    string
+
+	// failure domains the node lives in, only for storage nodes
+	Region string
+	Zone   string
+	Rack   string
 }
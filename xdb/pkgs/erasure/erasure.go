@@ -0,0 +1,113 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erasure
+
+import (
+	"github.com/klauspost/reedsolomon"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// MaxShards is the maximum number of data and parity shards in a stripe
+const MaxShards = 256
+
+// Check checks if dataShards and parityShards make a valid Reed-Solomon layout
+func Check(dataShards, parityShards int) error {
+	if dataShards <= 0 || parityShards <= 0 {
+		return errorx.New(errorx.ErrCodeParam, "data shards and parity shards must be greater than 0")
+	}
+	if dataShards+parityShards > MaxShards {
+		return errorx.New(errorx.ErrCodeParam, "too many shards, the sum of data shards and parity shards must be no greater than %d", MaxShards)
+	}
+	return nil
+}
+
+// Encode computes parityShards parity shards for a stripe of data shards.
+// Data shards shorter than the longest one are treated as zero padded, the input is left untouched.
+func Encode(data [][]byte, parityShards int) ([][]byte, error) {
+	if err := Check(len(data), parityShards); err != nil {
+		return nil, err
+	}
+	enc, err := reedsolomon.New(len(data), parityShards)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to create reed-solomon encoder")
+	}
+
+	size := shardSize(data)
+	shards := make([][]byte, 0, len(data)+parityShards)
+	for _, d := range data {
+		shards = append(shards, pad(d, size))
+	}
+	for i := 0; i < parityShards; i++ {
+		shards = append(shards, make([]byte, size))
+	}
+	if err := enc.Encode(shards); err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to encode stripe")
+	}
+
+	return shards[len(data):], nil
+}
+
+// Reconstruct rebuilds missing shards of a stripe in place, shards are ordered data shards first
+// and parity shards last, missing shards are nil. At least dataShards shards must be present.
+// Rebuilt data shards are zero padded to the size of the parity shards.
+func Reconstruct(shards [][]byte, dataShards int) error {
+	parityShards := len(shards) - dataShards
+	if err := Check(dataShards, parityShards); err != nil {
+		return err
+	}
+	present := 0
+	for _, s := range shards {
+		if s != nil {
+			present++
+		}
+	}
+	if present < dataShards {
+		return errorx.New(errorx.ErrCodeNotFound, "too few shards to reconstruct stripe, got %d, need %d",
+			present, dataShards)
+	}
+	enc, err := reedsolomon.New(dataShards, parityShards)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to create reed-solomon encoder")
+	}
+
+	size := shardSize(shards)
+	for i, s := range shards {
+		if s != nil {
+			shards[i] = pad(s, size)
+		}
+	}
+	if err := enc.Reconstruct(shards); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to reconstruct stripe")
+	}
+	return nil
+}
+
+// shardSize returns the length of the longest shard
+func shardSize(shards [][]byte) int {
+	size := 0
+	for _, s := range shards {
+		if len(s) > size {
+			size = len(s)
+		}
+	}
+	return size
+}
+
+// pad returns a copy of bs zero padded to size
+func pad(bs []byte, size int) []byte {
+	padded := make([]byte, size)
+	copy(padded, bs)
+	return padded
+}
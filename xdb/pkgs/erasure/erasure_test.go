@@ -0,0 +1,51 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erasure
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeAndReconstruct(t *testing.T) {
+	data := [][]byte{
+		[]byte("0123456789"),
+		[]byte("abcdefghij"),
+		[]byte("ABCDE"), // shorter shard, padded with zeros
+	}
+	parity, err := Encode(data, 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(parity))
+	require.Equal(t, 10, len(parity[0]))
+
+	// lose two shards, one data shard and one parity shard
+	shards := [][]byte{data[0], nil, data[2], nil, parity[1]}
+	require.NoError(t, Reconstruct(shards, 3))
+	require.Equal(t, data[1], shards[1])
+	require.Equal(t, parity[0], shards[3])
+	require.Equal(t, data[2], bytes.TrimRight(shards[2], string([]byte{0})))
+
+	// lose too many shards
+	shards = [][]byte{data[0], nil, nil, nil, parity[1]}
+	require.Error(t, Reconstruct(shards, 3))
+}
+
+func TestCheck(t *testing.T) {
+	require.NoError(t, Check(4, 2))
+	require.Error(t, Check(0, 2))
+	require.Error(t, Check(4, 0))
+	require.Error(t, Check(200, 57))
+}
@@ -28,6 +28,7 @@ type response struct {
 	Code    string          `json:"code"`
 	Message string          `json:"message"`
 	Data    json.RawMessage `json:"data"`
+	Next    string          `json:"next,omitempty"`
 }
 
 func Post(ctx context.Context, url string, input io.Reader) (io.ReadCloser, error) {
@@ -49,33 +50,40 @@ func Get(ctx context.Context, url string) (io.ReadCloser, error) {
 }
 
 func GetResponse(ctx context.Context, url string, output interface{}) error {
+	_, err := doResponse(ctx, "GET", url, nil, output)
+	return err
+}
+
+// GetPageResponse gets a page of a list, and returns the cursor of the next page, empty if the list ends
+func GetPageResponse(ctx context.Context, url string, output interface{}) (string, error) {
 	return doResponse(ctx, "GET", url, nil, output)
 }
 
 func PostResponse(ctx context.Context, url string, input io.Reader, output interface{}) error {
-	return doResponse(ctx, "POST", url, input, output)
+	_, err := doResponse(ctx, "POST", url, input, output)
+	return err
 }
 
-func doResponse(ctx context.Context, method string, url string, input io.Reader, output interface{}) error {
+func doResponse(ctx context.Context, method string, url string, input io.Reader, output interface{}) (string, error) {
 	body, err := do(ctx, method, url, input)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer body.Close()
 
 	var result response
 	if err := json.NewDecoder(body).Decode(&result); err != nil {
-		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to decode response")
+		return "", errorx.NewCode(err, errorx.ErrCodeInternal, "failed to decode response")
 	}
 
 	if result.Code != errorx.SuccessCode {
-		return errorx.New(result.Code, result.Message)
+		return "", errorx.New(result.Code, result.Message)
 	}
 
 	if err := json.Unmarshal(result.Data, output); err != nil {
-		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to decode response data")
+		return "", errorx.NewCode(err, errorx.ErrCodeInternal, "failed to decode response data")
 	}
-	return nil
+	return result.Next, nil
 }
 
 func do(ctx context.Context, method string, url string, input io.Reader) (io.ReadCloser, error) {
@@ -0,0 +1,304 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kvindex keeps json records in levelDB along with their sort keys,
+// records of a kind are queried page by page in the order of one of their sort keys,
+// and each page returns an opaque cursor to continue from.
+package kvindex
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// keys are separated by sep, which is not expected in kinds, sort names or values
+const sep = "\x00"
+
+const (
+	recordPrefix = "r" + sep // r <kind> <id> -> record
+	sortPrefix   = "s" + sep // s <kind> <sort> <value> <id> -> nil
+	metaPrefix   = "m" + sep // m <name> -> json value
+)
+
+// Key is a sort key of a record, records are ordered by Value bytewise within the sort named Sort.
+// Integers are encoded by Int to be ordered numerically
+type Key struct {
+	Sort  string `json:"sort"`
+	Value string `json:"value"`
+}
+
+// Int encodes v to a sort value ordered numerically
+func Int(v int64) string {
+	return fmt.Sprintf("%016x", uint64(v)^(1<<63))
+}
+
+// Query queries records of Kind in the order of the sort key named Sort.
+// Min and Max are inclusive bounds of sort values, empty for unbounded.
+// Records are returned from Cursor returned by the previous page, at most Limit records
+// matched by Match are returned, zero Limit returns all and nil Match matches all
+type Query struct {
+	Kind     string
+	Sort     string
+	Min, Max string
+	Desc     bool
+	Cursor   string
+	Limit    int
+	Match    func(value []byte) (bool, error)
+}
+
+type record struct {
+	Value json.RawMessage `json:"value"`
+	Keys  []Key           `json:"keys"`
+}
+
+// Store is a levelDB of records indexed by sort keys
+type Store struct {
+	lock sync.Mutex
+	db   *leveldb.DB
+}
+
+// Open opens the levelDB at path
+func Open(path string) (*Store, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "cannot open leveldb")
+	}
+	return &Store{db: db}, nil
+}
+
+// Put saves v as the record of kind with the id, sort keys of the old record are replaced by keys
+func (s *Store) Put(kind, id string, v interface{}, keys ...Key) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal %s %s", kind, id)
+	}
+	newRec, err := json.Marshal(record{Value: value, Keys: keys})
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal record")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	old, exist, err := s.load(kind, id)
+	if err != nil {
+		return err
+	}
+	batch := leveldb.Batch{}
+	if exist {
+		if bytes.Equal(old.Value, value) && sameKeys(old.Keys, keys) {
+			return nil
+		}
+		for _, k := range old.Keys {
+			batch.Delete(sortKey(kind, k.Sort, k.Value, id))
+		}
+	}
+	for _, k := range keys {
+		batch.Put(sortKey(kind, k.Sort, k.Value, id), nil)
+	}
+	batch.Put(recordKey(kind, id), newRec)
+	if err := s.db.Write(&batch, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write batch")
+	}
+	return nil
+}
+
+// Get gets the record of kind with the id into v, returns false if not found
+func (s *Store) Get(kind, id string, v interface{}) (bool, error) {
+	r, exist, err := s.load(kind, id)
+	if err != nil || !exist {
+		return false, err
+	}
+	if err := json.Unmarshal(r.Value, v); err != nil {
+		return false, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal %s %s", kind, id)
+	}
+	return true, nil
+}
+
+// Delete removes the record of kind with the id along with its sort keys, a record not found is skipped
+func (s *Store) Delete(kind, id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	r, exist, err := s.load(kind, id)
+	if err != nil || !exist {
+		return err
+	}
+	batch := leveldb.Batch{}
+	for _, k := range r.Keys {
+		batch.Delete(sortKey(kind, k.Sort, k.Value, id))
+	}
+	batch.Delete(recordKey(kind, id))
+	if err := s.db.Write(&batch, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write batch")
+	}
+	return nil
+}
+
+// Query returns records matched by q and the cursor of the next page, which is empty if no records are left
+func (s *Store) Query(q Query) ([][]byte, string, error) {
+	prefix := sortKey(q.Kind, q.Sort, "", "")
+	prefix = prefix[:len(prefix)-len(sep)]
+	r := util.BytesPrefix(prefix)
+	if q.Min != "" {
+		r.Start = append(append([]byte{}, prefix...), q.Min...)
+	}
+	if q.Max != "" {
+		r.Limit = append(append(append([]byte{}, prefix...), q.Max...), sep[0]+1)
+	}
+
+	iter := s.db.NewIterator(r, nil)
+	defer iter.Release()
+
+	ok, err := seek(iter, prefix, q.Cursor, q.Desc)
+	if err != nil {
+		return nil, "", err
+	}
+	move := iter.Next
+	if q.Desc {
+		move = iter.Prev
+	}
+
+	var values [][]byte
+	var last []byte
+	for ; ok; ok = move() {
+		key := iter.Key()
+		id := key[bytes.LastIndex(key, []byte(sep))+len(sep):]
+		rec, exist, err := s.load(q.Kind, string(id))
+		if err != nil {
+			return nil, "", err
+		}
+		if !exist {
+			continue
+		}
+		if q.Match != nil {
+			matched, err := q.Match(rec.Value)
+			if err != nil {
+				return nil, "", err
+			}
+			if !matched {
+				continue
+			}
+		}
+		// one more record matched than the limit, so there is a next page
+		if q.Limit > 0 && len(values) == q.Limit {
+			return values, base64.RawURLEncoding.EncodeToString(last[len(prefix):]), nil
+		}
+		values = append(values, rec.Value)
+		last = append(last[:0], key...)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, "", errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate %s records", q.Kind)
+	}
+	return values, "", nil
+}
+
+// PutMeta saves v as the value of name, which is used to keep states such as synchronization progress
+func (s *Store) PutMeta(name string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal %s", name)
+	}
+	if err := s.db.Put([]byte(metaPrefix+name), value, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to put %s", name)
+	}
+	return nil
+}
+
+// GetMeta gets the value of name into v, returns false if not found
+func (s *Store) GetMeta(name string, v interface{}) (bool, error) {
+	value, err := s.db.Get([]byte(metaPrefix+name), nil)
+	if err == leveldb.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to get %s", name)
+	}
+	if err := json.Unmarshal(value, v); err != nil {
+		return false, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal %s", name)
+	}
+	return true, nil
+}
+
+// Close closes levelDB
+func (s *Store) Close() {
+	s.db.Close()
+}
+
+func (s *Store) load(kind, id string) (record, bool, error) {
+	var r record
+	value, err := s.db.Get(recordKey(kind, id), nil)
+	if err == leveldb.ErrNotFound {
+		return r, false, nil
+	} else if err != nil {
+		return r, false, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to get %s %s", kind, id)
+	}
+	if err := json.Unmarshal(value, &r); err != nil {
+		return r, false, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to unmarshal record")
+	}
+	return r, true, nil
+}
+
+// seek moves iter to the first key to return, which is the one next to the key of cursor if any
+func seek(iter iterator.Iterator, prefix []byte, cursor string, desc bool) (bool, error) {
+	if cursor == "" {
+		if desc {
+			return iter.Last(), nil
+		}
+		return iter.First(), nil
+	}
+	suffix, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !bytes.Contains(suffix, []byte(sep)) {
+		return false, errorx.New(errorx.ErrCodeParam, "invalid cursor")
+	}
+	key := append(append([]byte{}, prefix...), suffix...)
+	found := iter.Seek(key)
+	if desc {
+		if !found {
+			return iter.Last(), nil
+		}
+		return iter.Prev(), nil
+	}
+	if found && bytes.Equal(iter.Key(), key) {
+		return iter.Next(), nil
+	}
+	return found, nil
+}
+
+func recordKey(kind, id string) []byte {
+	return []byte(recordPrefix + kind + sep + id)
+}
+
+func sortKey(kind, sort, value, id string) []byte {
+	return []byte(sortPrefix + kind + sep + sort + sep + value + sep + id)
+}
+
+func sameKeys(a, b []Key) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
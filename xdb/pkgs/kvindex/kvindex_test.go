@@ -0,0 +1,142 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvindex
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+type item struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+func putItem(t *testing.T, s *Store, it item) {
+	require.NoError(t, s.Put("item", it.ID, it,
+		Key{Sort: "name", Value: it.Name},
+		Key{Sort: "size", Value: Int(it.Size)}))
+}
+
+func queryItems(t *testing.T, s *Store, q Query) ([]string, string) {
+	q.Kind = "item"
+	values, next, err := s.Query(q)
+	require.NoError(t, err)
+	var ids []string
+	for _, v := range values {
+		var it item
+		require.NoError(t, json.Unmarshal(v, &it))
+		ids = append(ids, it.ID)
+	}
+	return ids, next
+}
+
+func TestStore(t *testing.T) {
+	root, err := ioutil.TempDir("", "kvindex")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	s, err := Open(filepath.Join(root, "db"))
+	require.NoError(t, err)
+	defer s.Close()
+
+	putItem(t, s, item{ID: "1", Name: "b", Size: -1})
+	putItem(t, s, item{ID: "2", Name: "a", Size: 20})
+	putItem(t, s, item{ID: "3", Name: "c", Size: 3})
+	putItem(t, s, item{ID: "4", Name: "a", Size: 100})
+
+	var it item
+	exist, err := s.Get("item", "3", &it)
+	require.NoError(t, err)
+	require.True(t, exist)
+	require.Equal(t, "c", it.Name)
+	exist, err = s.Get("item", "5", &it)
+	require.NoError(t, err)
+	require.False(t, exist)
+
+	// integers are ordered numerically, ties are ordered by IDs
+	ids, next := queryItems(t, s, Query{Sort: "size"})
+	require.Equal(t, []string{"1", "3", "2", "4"}, ids)
+	require.Empty(t, next)
+	ids, _ = queryItems(t, s, Query{Sort: "name", Desc: true})
+	require.Equal(t, []string{"3", "1", "4", "2"}, ids)
+
+	// page by cursors in both orders
+	for _, desc := range []bool{false, true} {
+		var all []string
+		var cursor string
+		for {
+			ids, next := queryItems(t, s, Query{Sort: "name", Desc: desc, Cursor: cursor, Limit: 3})
+			all = append(all, ids...)
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		if desc {
+			require.Equal(t, []string{"3", "1", "4", "2"}, all)
+		} else {
+			require.Equal(t, []string{"2", "4", "1", "3"}, all)
+		}
+	}
+	// no next page if the page holds all records left
+	ids, next = queryItems(t, s, Query{Sort: "name", Limit: 4})
+	require.Len(t, ids, 4)
+	require.Empty(t, next)
+
+	// bounds and filters
+	ids, _ = queryItems(t, s, Query{Sort: "size", Min: Int(0), Max: Int(20)})
+	require.Equal(t, []string{"3", "2"}, ids)
+	ids, _ = queryItems(t, s, Query{Sort: "name", Min: "a", Max: "a", Desc: true})
+	require.Equal(t, []string{"4", "2"}, ids)
+	ids, next = queryItems(t, s, Query{Sort: "size", Limit: 1, Match: func(v []byte) (bool, error) {
+		var it item
+		err := json.Unmarshal(v, &it)
+		return it.Size > 10, err
+	}})
+	require.Equal(t, []string{"2"}, ids)
+	ids, _ = queryItems(t, s, Query{Sort: "size", Cursor: next})
+	require.Equal(t, []string{"4"}, ids)
+
+	_, _, err = s.Query(Query{Kind: "item", Sort: "size", Cursor: "!"})
+	require.True(t, errorx.Is(err, errorx.ErrCodeParam))
+
+	// sort keys are replaced once a record is updated, and removed along with it
+	putItem(t, s, item{ID: "4", Name: "d", Size: 100})
+	ids, _ = queryItems(t, s, Query{Sort: "name"})
+	require.Equal(t, []string{"2", "1", "3", "4"}, ids)
+	require.NoError(t, s.Delete("item", "1"))
+	require.NoError(t, s.Delete("item", "1"))
+	ids, _ = queryItems(t, s, Query{Sort: "name"})
+	require.Equal(t, []string{"2", "3", "4"}, ids)
+
+	// meta values
+	var round int64
+	exist, err = s.GetMeta("round", &round)
+	require.NoError(t, err)
+	require.False(t, exist)
+	require.NoError(t, s.PutMeta("round", int64(7)))
+	exist, err = s.GetMeta("round", &round)
+	require.NoError(t, err)
+	require.True(t, exist)
+	require.Equal(t, int64(7), round)
+}
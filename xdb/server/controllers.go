@@ -16,6 +16,9 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -39,6 +42,7 @@ func (s *Server) write(ictx iris.Context) {
 		ExpireTime:  ictx.URLParamInt64Default("expireTime", 0),
 		Description: ictx.URLParam("desc"),
 		Extra:       ictx.URLParam("ext"),
+		Compression: ictx.URLParam("compression"),
 	}
 	if err := req.Valid(); err != nil {
 		responseError(ictx, errorx.Wrap(err, "invalid params"))
@@ -54,12 +58,170 @@ func (s *Server) write(ictx iris.Context) {
 		return
 	}
 	resp := types.WriteResponse{
-		FileID: result.FileID,
+		FileID:  result.FileID,
+		Version: result.Version,
 	}
 	responseJSON(ictx, resp)
 }
 
+// initiateUpload starts a session to upload a file in parts,
+// an unfinished session of the same file is responded to resume the upload
+func (s *Server) initiateUpload(ictx iris.Context) {
+	req := etype.InitiateUploadOptions{
+		WriteOptions: etype.WriteOptions{
+			User:        ictx.URLParam("user"),
+			Token:       ictx.URLParam("token"),
+			Namespace:   ictx.URLParam("ns"),
+			FileName:    ictx.URLParam("name"),
+			ExpireTime:  ictx.URLParamInt64Default("expireTime", 0),
+			Description: ictx.URLParam("desc"),
+			Extra:       ictx.URLParam("ext"),
+		},
+	}
+	length, partSize := ictx.URLParamInt64Default("length", -1), ictx.URLParamInt64Default("partSize", 0)
+	if length < 0 || partSize < 0 {
+		responseError(ictx, errorx.New(errorx.ErrCodeParam, "invalid params, negative length or part size"))
+		return
+	}
+	req.FileLength, req.PartSize = uint64(length), uint64(partSize)
+	if err := req.Valid(); err != nil {
+		responseError(ictx, errorx.Wrap(err, "invalid params"))
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ictx.OnConnectionClose(func(iris.Context) { cancel() })
+
+	result, err := s.handler.InitiateUpload(ctx, req)
+	if err != nil {
+		responseError(ictx, errorx.Wrap(err, "failed to initiate upload"))
+		return
+	}
+	responseJSON(ictx, toUploadSessionResponse(result))
+}
+
+// uploadPart uploads a part of file in request body
+func (s *Server) uploadPart(ictx iris.Context) {
+	req := etype.UploadPartOptions{
+		UploadSessionOptions: getUploadSessionOptions(ictx, etype.UploadActionPart),
+		PartNumber:           ictx.URLParamIntDefault("part", 0),
+	}
+	if err := req.Valid(); err != nil {
+		responseError(ictx, errorx.Wrap(err, "invalid params"))
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ictx.OnConnectionClose(func(iris.Context) { cancel() })
+
+	result, err := s.handler.UploadPart(ctx, req, ictx.Request().Body)
+	if err != nil {
+		responseError(ictx, errorx.Wrap(err, "failed to upload part"))
+		return
+	}
+	responseJSON(ictx, toUploadPartResponse(result))
+}
+
+// listUploadParts responds an upload session and the parts uploaded
+func (s *Server) listUploadParts(ictx iris.Context) {
+	req := getUploadSessionOptions(ictx, etype.UploadActionList)
+	if err := req.Valid(); err != nil {
+		responseError(ictx, errorx.Wrap(err, "invalid params"))
+		return
+	}
+
+	result, err := s.handler.ListUploadParts(req)
+	if err != nil {
+		responseError(ictx, errorx.Wrap(err, "failed to list upload parts"))
+		return
+	}
+	responseJSON(ictx, toUploadSessionResponse(result))
+}
+
+// completeUpload publishes the file uploaded in parts
+func (s *Server) completeUpload(ictx iris.Context) {
+	req := getUploadSessionOptions(ictx, etype.UploadActionComplete)
+	if err := req.Valid(); err != nil {
+		responseError(ictx, errorx.Wrap(err, "invalid params"))
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ictx.OnConnectionClose(func(iris.Context) { cancel() })
+
+	result, err := s.handler.CompleteUpload(ctx, req)
+	if err != nil {
+		responseError(ictx, errorx.Wrap(err, "failed to complete upload"))
+		return
+	}
+	resp := types.WriteResponse{
+		FileID:  result.FileID,
+		Version: result.Version,
+	}
+	responseJSON(ictx, resp)
+}
+
+// abortUpload removes an upload session and the parts uploaded
+func (s *Server) abortUpload(ictx iris.Context) {
+	req := getUploadSessionOptions(ictx, etype.UploadActionAbort)
+	if err := req.Valid(); err != nil {
+		responseError(ictx, errorx.Wrap(err, "invalid params"))
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ictx.OnConnectionClose(func(iris.Context) { cancel() })
+
+	if err := s.handler.AbortUpload(ctx, req); err != nil {
+		responseError(ictx, errorx.Wrap(err, "failed to abort upload"))
+		return
+	}
+	responseJSON(ictx, "success")
+}
+
+// getUploadSessionOptions gets params of operating an upload session,
+// action is decided by the api requested rather than URL params
+func getUploadSessionOptions(ictx iris.Context, action string) etype.UploadSessionOptions {
+	return etype.UploadSessionOptions{
+		SessionID:   ictx.URLParam("sessionID"),
+		Action:      action,
+		CurrentTime: ictx.URLParamInt64Default("ctime", 0),
+		User:        ictx.URLParam("user"),
+		Token:       ictx.URLParam("token"),
+	}
+}
+
+func toUploadSessionResponse(session etype.UploadSession) types.UploadSessionResponse {
+	resp := types.UploadSessionResponse{
+		SessionID:  session.SessionID,
+		FileID:     session.FileID,
+		Namespace:  session.Namespace,
+		FileName:   session.FileName,
+		FileLength: session.FileLength,
+		PartSize:   session.PartSize,
+		PartCount:  session.PartCount,
+		ExpireTime: session.ExpireTime,
+		CreateTime: session.CreateTime,
+		Parts:      make([]types.UploadPartResponse, 0, len(session.Parts)),
+	}
+	for _, p := range session.Parts {
+		resp.Parts = append(resp.Parts, toUploadPartResponse(p))
+	}
+	return resp
+}
+
+func toUploadPartResponse(part etype.UploadedPart) types.UploadPartResponse {
+	return types.UploadPartResponse{
+		Number:     part.Number,
+		Length:     part.Length,
+		PlainHash:  part.PlainHash,
+		UploadTime: part.UploadTime,
+	}
+}
+
 // read download a file to local
+// a range of the file can be read by URL params "offset" and "length",
+// or by a single range in "Range" header, in which case 206 is responded
 func (s *Server) read(ictx iris.Context) {
 	req := etype.ReadOptions{
 		User:      ictx.URLParam("user"),
@@ -68,31 +230,65 @@ func (s *Server) read(ictx iris.Context) {
 		FileName:  ictx.URLParam("name"),
 		FileID:    ictx.URLParam("file_id"),
 		Timestamp: ictx.URLParamInt64Default("timestamp", 0),
+		Version:   ictx.URLParamInt64Default("version", 0),
 	}
 	if err := req.Valid(); err != nil {
 		responseError(ictx, errorx.Wrap(err, "invalid params"))
 		return
 	}
+	offset, length := ictx.URLParamInt64Default("offset", 0), ictx.URLParamInt64Default("length", 0)
+	if offset < 0 || length < 0 {
+		responseError(ictx, errorx.New(errorx.ErrCodeParam, "invalid params, negative offset or length"))
+		return
+	}
+	req.Offset, req.Length = uint64(offset), uint64(length)
+
+	partial := false
+	if header := ictx.GetHeader("Range"); header != "" {
+		// unsupported or malformed range is ignored, the whole file is responded
+		if ok := parseRange(header, &req); ok {
+			partial = true
+		}
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	ictx.OnConnectionClose(func(iris.Context) { cancel() })
 
-	reader, err := s.handler.Read(ctx, req)
+	resp, err := s.handler.Read(ctx, req)
 	if err != nil {
+		if partial && errorx.Is(err, errorx.ErrCodeRangeNotSatisfiable) {
+			// the error is written in body, otherwise the response is replaced by the error code handler
+			ictx.Header("Content-Range", fmt.Sprintf("bytes */%d", resp.FileLength))
+			ictx.StatusCode(http.StatusRequestedRangeNotSatisfiable)
+			code, message := errorx.Parse(err)
+			ictx.JSON(response{Code: code, Message: message})
+			return
+		}
 		responseError(ictx, errorx.Wrap(err, "failed to read"))
 		return
 	}
-	defer reader.Close()
+	defer resp.Close()
 
-	responseStream(ictx, reader)
+	ictx.Header("Accept-Ranges", "bytes")
+	if partial {
+		ictx.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", resp.Offset, resp.Offset+resp.Length-1, resp.FileLength))
+		ictx.Header("Content-Length", strconv.FormatUint(resp.Length, 10))
+		responseStreamWithStatus(ictx, resp, http.StatusPartialContent)
+		return
+	}
+	responseStream(ictx, resp)
 }
 
 // push receives slice from others
 func (s *Server) push(ictx iris.Context) {
 	opt := etype.PushOptions{
-		SliceID:  ictx.URLParam("slice_id"),
-		SourceID: ictx.URLParam("source_id"),
+		SliceID:     ictx.URLParam("slice_id"),
+		SourceID:    ictx.URLParam("source_id"),
+		FileID:      ictx.URLParam("file_id"),
+		Timestamp:   ictx.URLParamInt64Default("timestamp", 0),
+		ContentHash: ictx.URLParam("content_hash"),
+		Signature:   ictx.URLParam("signature"),
 	}
 	// if sliceID has suffix, like 'sigmas', the pushed content is not a slice
 	// currently, pairing based challenge material sigmas is supported
@@ -104,7 +300,7 @@ func (s *Server) push(ictx iris.Context) {
 
 	result, err := s.handler.Push(opt, ictx.Request().Body)
 	if err != nil {
-		responseError(ictx, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to push slice"))
+		responseError(ictx, errorx.Wrap(err, "failed to push slice"))
 		return
 	}
 
@@ -114,6 +310,70 @@ func (s *Server) push(ictx iris.Context) {
 	responseJSON(ictx, resp)
 }
 
+// getSliceUsage gets the storage used by a dataOwner node on the storage node and its quota
+func (s *Server) getSliceUsage(ictx iris.Context) {
+	owner := ictx.URLParam("owner")
+
+	usage, err := s.handler.GetSliceUsage(owner)
+	if err != nil {
+		responseError(ictx, errorx.Wrap(err, "failed to get slice usage"))
+		return
+	}
+
+	resp := types.SliceUsageResponse{
+		Owner:     usage.Owner,
+		Bytes:     usage.Bytes,
+		Slices:    usage.Slices,
+		MaxBytes:  usage.MaxBytes,
+		MaxSlices: usage.MaxSlices,
+	}
+	responseJSON(ictx, resp)
+}
+
+// getScrubReports gets slices of a dataOwner node found corrupt or missing by scrubbing on the storage node
+func (s *Server) getScrubReports(ictx iris.Context) {
+	owner := ictx.URLParam("owner")
+
+	reports, err := s.handler.GetScrubReports(owner)
+	if err != nil {
+		responseError(ictx, errorx.Wrap(err, "failed to get scrub reports"))
+		return
+	}
+
+	resp := make([]types.SliceScrubResponse, 0, len(reports))
+	for _, r := range reports {
+		resp = append(resp, types.SliceScrubResponse{
+			SliceID:   r.SliceID,
+			FileID:    r.FileID,
+			Owner:     r.Owner,
+			Status:    r.Status,
+			CheckedAt: r.CheckedAt,
+		})
+	}
+	responseJSON(ictx, resp)
+}
+
+// getOrphanSlices gets slices on the storage node not referenced by any file on blockchain
+func (s *Server) getOrphanSlices(ictx iris.Context) {
+	orphans, err := s.handler.GetOrphanSlices()
+	if err != nil {
+		responseError(ictx, errorx.Wrap(err, "failed to get orphan slices"))
+		return
+	}
+
+	resp := make([]types.SliceOrphanResponse, 0, len(orphans))
+	for _, o := range orphans {
+		resp = append(resp, types.SliceOrphanResponse{
+			SliceID:    o.SliceID,
+			FileID:     o.FileID,
+			Owner:      o.Owner,
+			PushedAt:   o.PushedAt,
+			OrphanedAt: o.OrphanedAt,
+		})
+	}
+	responseJSON(ictx, resp)
+}
+
 // pull offers file slices to owner
 func (s *Server) pull(ictx iris.Context) {
 	opt := etype.PullOptions{
@@ -151,6 +411,21 @@ func (s *Server) pull(ictx iris.Context) {
 	responseStream(ictx, resultReader)
 }
 
+// deleteSlice removes a slice which is never published, requested by the dataOwner node pushed the slice
+func (s *Server) deleteSlice(ictx iris.Context) {
+	opt := etype.DeleteSliceOptions{
+		SliceID:   ictx.URLParam("slice_id"),
+		StorIndex: ictx.URLParam("slice_stor_index"),
+		Timestamp: ictx.URLParamInt64Default("timestamp", 0),
+		Signature: ictx.URLParam("signature"),
+	}
+	if err := s.handler.DeleteSlice(opt); err != nil {
+		responseError(ictx, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to delete slice"))
+		return
+	}
+	responseJSON(ictx, "success")
+}
+
 // listNodes list storage nodes
 func (s *Server) listNodes(ictx iris.Context) {
 	resp, err := s.handler.ListNodes()
@@ -175,23 +450,17 @@ func (s *Server) getNode(ictx iris.Context) {
 
 // nodeOffline set storage node status to offline
 func (s *Server) nodeOffline(ictx iris.Context) {
-	s.nodeOperate(ictx, false)
+	s.nodeOperate(ictx, s.handler.NodeOffline, "failed to take node offline")
 }
 
-func (s *Server) nodeOperate(ictx iris.Context, isOnline bool) {
+func (s *Server) nodeOperate(ictx iris.Context, operate func(etype.NodeOperateOptions) error, errMsg string) {
 	req := etype.NodeOperateOptions{
 		NodeID: ictx.URLParam("node"),
 		Nonce:  ictx.URLParamInt64Default("nonce", 0),
 		Token:  ictx.URLParam("token"),
 	}
-	var err error
-	if isOnline {
-		err = s.handler.NodeOnline(req)
-	} else {
-		err = s.handler.NodeOffline(req)
-	}
-	if err != nil {
-		responseError(ictx, errorx.Wrap(err, "failed to take node offline"))
+	if err := operate(req); err != nil {
+		responseError(ictx, errorx.Wrap(err, errMsg))
 		return
 	}
 	responseJSON(ictx, "success")
@@ -199,7 +468,12 @@ func (s *Server) nodeOperate(ictx iris.Context, isOnline bool) {
 
 // nodeOnline set storage node status to online
 func (s *Server) nodeOnline(ictx iris.Context) {
-	s.nodeOperate(ictx, true)
+	s.nodeOperate(ictx, s.handler.NodeOnline, "failed to take node online")
+}
+
+// nodeDrain set storage node status to draining
+func (s *Server) nodeDrain(ictx iris.Context) {
+	s.nodeOperate(ictx, s.handler.NodeDrain, "failed to drain node")
 }
 
 // getMRecord get storage node migration records
@@ -262,13 +536,14 @@ func (s *Server) listFiles(ictx iris.Context, isExpired bool) {
 		TimeEnd:     ictx.URLParamInt64Default("end", time.Now().UnixNano()),
 		CurrentTime: ictx.URLParamInt64Default("ctime", time.Now().UnixNano()),
 		Limit:       ictx.URLParamInt64Default("limit", blockchain.ListMaxNumber),
+		Cursor:      ictx.URLParam("cursor"),
 	}
 
 	if err := req.Valid(); err != nil {
 		responseError(ictx, errorx.Wrap(err, "invalid params"))
 		return
 	}
-	var resp []blockchain.File
+	var resp etype.FilePage
 	var err error
 	if isExpired {
 		resp, err = s.handler.ListExpiredFiles(req)
@@ -279,7 +554,45 @@ func (s *Server) listFiles(ictx iris.Context, isExpired bool) {
 		responseError(ictx, errorx.Wrap(err, "failed to list files"))
 		return
 	}
-	responseJSON(ictx, resp)
+	responsePage(ictx, resp.Files, resp.Next)
+}
+
+// searchFiles searches files in the local index by keywords and extension fields,
+// each ext param is a field of file extension in the form of "key:value"
+func (s *Server) searchFiles(ictx iris.Context) {
+	req := etype.SearchFileOptions{
+		Owner:       ictx.URLParam("owner"),
+		Namespace:   ictx.URLParam("ns"),
+		Keywords:    ictx.URLParam("keywords"),
+		SortBy:      ictx.URLParam("sort"),
+		Asc:         ictx.URLParam("order") == "asc",
+		TimeStart:   ictx.URLParamInt64Default("start", 0),
+		TimeEnd:     ictx.URLParamInt64Default("end", 0),
+		CurrentTime: ictx.URLParamInt64Default("ctime", time.Now().UnixNano()),
+		Cursor:      ictx.URLParam("cursor"),
+		Limit:       ictx.URLParamInt64Default("limit", blockchain.ListMaxNumber),
+	}
+	for _, field := range ictx.URLParamSlice("ext") {
+		kv := strings.SplitN(field, ":", 2)
+		if len(kv) != 2 {
+			responseError(ictx, errorx.New(errorx.ErrCodeParam, "bad params:invalid ext %s", field))
+			return
+		}
+		if req.Ext == nil {
+			req.Ext = make(map[string]string)
+		}
+		req.Ext[kv[0]] = kv[1]
+	}
+	if err := req.Valid(); err != nil {
+		responseError(ictx, errorx.Wrap(err, "invalid params"))
+		return
+	}
+	resp, err := s.handler.SearchFiles(req)
+	if err != nil {
+		responseError(ictx, errorx.Wrap(err, "failed to search files"))
+		return
+	}
+	responseJSON(ictx, types.FilePageResponse{Files: resp.Files, Next: resp.Next})
 }
 
 // getFileByID get file by id
@@ -305,7 +618,12 @@ func (s *Server) getFileByName(ictx iris.Context) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	ictx.OnConnectionClose(func(iris.Context) { cancel() })
-	resp, err := s.handler.GetFileByName(ctx, ictx.URLParam("owner"), ictx.URLParam("ns"), ictx.URLParam("name"))
+	version := ictx.URLParamInt64Default("version", 0)
+	if version < 0 {
+		responseError(ictx, errorx.New(errorx.ErrCodeParam, "bad params:negative version"))
+		return
+	}
+	resp, err := s.handler.GetFileByName(ctx, ictx.URLParam("owner"), ictx.URLParam("ns"), ictx.URLParam("name"), version)
 	if err != nil {
 		responseError(ictx, errorx.Wrap(err, "failed to get file by name"))
 		return
@@ -313,6 +631,27 @@ func (s *Server) getFileByName(ictx iris.Context) {
 	responseJSON(ictx, resp)
 }
 
+// listFileVersions list versions of a file by file name and namespace, the latest first
+func (s *Server) listFileVersions(ictx iris.Context) {
+	req := etype.ListFileVersionsOptions{
+		Owner:       ictx.URLParam("owner"),
+		Namespace:   ictx.URLParam("ns"),
+		Name:        ictx.URLParam("name"),
+		CurrentTime: ictx.URLParamInt64Default("ctime", time.Now().UnixNano()),
+		Limit:       ictx.URLParamInt64Default("limit", blockchain.ListMaxNumber),
+	}
+	if err := req.Valid(); err != nil {
+		responseError(ictx, errorx.Wrap(err, "invalid params"))
+		return
+	}
+	resp, err := s.handler.ListFileVersions(req)
+	if err != nil {
+		responseError(ictx, errorx.Wrap(err, "failed to list file versions"))
+		return
+	}
+	responseJSON(ictx, resp)
+}
+
 // updateFileExpireTime update file expire time
 func (s *Server) updateFileExpireTime(ictx iris.Context) {
 	req := etype.UpdateFileEtimeOptions{
@@ -337,6 +676,29 @@ func (s *Server) updateFileExpireTime(ictx iris.Context) {
 	responseJSON(ictx, "success")
 }
 
+// deleteFile delete a file by its owner
+func (s *Server) deleteFile(ictx iris.Context) {
+	req := etype.DeleteFileOptions{
+		FileID:      ictx.URLParam("id"),
+		CurrentTime: ictx.URLParamInt64Default("ctime", 0),
+		User:        ictx.URLParam("user"),
+		Token:       ictx.URLParam("token"),
+	}
+	if err := req.Valid(); err != nil {
+		responseError(ictx, errorx.Wrap(err, "invalid params"))
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ictx.OnConnectionClose(func(iris.Context) { cancel() })
+
+	if err := s.handler.DeleteFile(ctx, req); err != nil {
+		responseError(ictx, errorx.Wrap(err, "failed to delete file"))
+		return
+	}
+	responseJSON(ictx, "success")
+}
+
 // addFileNs add a file namespace
 func (s *Server) addFileNs(ictx iris.Context) {
 	// check files replica of namespace, replica must no greater than nodes number
@@ -350,13 +712,25 @@ func (s *Server) addFileNs(ictx iris.Context) {
 		responseError(ictx, errorx.New(errorx.ErrCodeParam, "invalid param: replica must no greater than nodes number"))
 		return
 	}
+	// shards of a stripe are stored on distinct nodes
+	dataShards := ictx.URLParamIntDefault("dataShards", 0)
+	parityShards := ictx.URLParamIntDefault("parityShards", 0)
+	if dataShards+parityShards > len(lresp) {
+		responseError(ictx, errorx.New(errorx.ErrCodeParam, "invalid param: shards must no greater than nodes number"))
+		return
+	}
 	req := etype.AddNsOptions{
-		Namespace:   ictx.URLParam("ns"),
-		Description: ictx.URLParam("desc"),
-		Replica:     replica,
-		CreateTime:  ictx.URLParamInt64Default("ctime", time.Now().UnixNano()),
-		User:        ictx.URLParam("user"),
-		Token:       ictx.URLParam("token"),
+		Namespace:    ictx.URLParam("ns"),
+		Description:  ictx.URLParam("desc"),
+		Replica:      replica,
+		CreateTime:   ictx.URLParamInt64Default("ctime", time.Now().UnixNano()),
+		User:         ictx.URLParam("user"),
+		Token:        ictx.URLParam("token"),
+		DataShards:   dataShards,
+		ParityShards: parityShards,
+		Compression:  ictx.URLParam("compression"),
+		MinZones:     ictx.URLParamIntDefault("minZones", 0),
+		Regions:      ictx.URLParam("regions"),
 	}
 	if err := req.Valid(); err != nil {
 		responseError(ictx, errorx.Wrap(err, "invalid params"))
@@ -394,6 +768,33 @@ func (s *Server) updateNsReplica(ictx iris.Context) {
 	responseJSON(ictx, "success")
 }
 
+// updateNsLifecycle update file namespace lifecycle policy
+func (s *Server) updateNsLifecycle(ictx iris.Context) {
+	autoRenew, err := ictx.URLParamBool("autoRenew")
+	if err != nil {
+		responseError(ictx, errorx.Wrap(err, "invalid param autoRenew"))
+		return
+	}
+	req := etype.UpdateNsLifecycleOptions{
+		Namespace:   ictx.URLParam("ns"),
+		DefaultTTL:  ictx.URLParamInt64Default("defaultTTL", 0),
+		AutoRenew:   autoRenew,
+		GracePeriod: ictx.URLParamInt64Default("gracePeriod", 0),
+		CurrentTime: ictx.URLParamInt64Default("ctime", time.Now().UnixNano()),
+		User:        ictx.URLParam("user"),
+		Token:       ictx.URLParam("token"),
+	}
+	if err := req.Valid(); err != nil {
+		responseError(ictx, errorx.Wrap(err, "invalid params"))
+		return
+	}
+	if err := s.handler.UpdateNsLifecycle(req); err != nil {
+		responseError(ictx, errorx.Wrap(err, "failed to update file ns lifecycle"))
+		return
+	}
+	responseJSON(ictx, "success")
+}
+
 // listFileNs list namespaces by owner
 func (s *Server) listFileNs(ictx iris.Context) {
 	req := etype.ListNsOptions{
@@ -453,6 +854,7 @@ func (s *Server) listFileAuths(ictx iris.Context) {
 		TimeStart:  ictx.URLParamInt64Default("start", 0),
 		TimeEnd:    ictx.URLParamInt64Default("end", time.Now().UnixNano()),
 		Limit:      ictx.URLParamInt64Default("limit", blockchain.ListMaxNumber),
+		Cursor:     ictx.URLParam("cursor"),
 	}
 
 	resp, err := s.handler.ListFileAuths(req)
@@ -460,7 +862,7 @@ func (s *Server) listFileAuths(ictx iris.Context) {
 		responseError(ictx, errorx.Wrap(err, "failed to get file authorization applications"))
 		return
 	}
-	responseJSON(ictx, resp)
+	responsePage(ictx, resp.FileAuths, resp.Next)
 }
 
 // confirmAuth the dataOwner node confirms or rejects the applier's file authorization application
@@ -555,6 +957,7 @@ func (s *Server) getChallengesByStatus(ictx iris.Context, status string) {
 		TimeStart:  ictx.URLParamInt64Default("start", 0),
 		TimeEnd:    ictx.URLParamInt64Default("end", time.Now().UnixNano()),
 		Limit:      ictx.URLParamInt64Default("limit", blockchain.ListMaxNumber),
+		Cursor:     ictx.URLParam("cursor"),
 	}
 
 	resp, err := s.handler.GetChallenges(opt)
@@ -562,7 +965,32 @@ func (s *Server) getChallengesByStatus(ictx iris.Context, status string) {
 		responseError(ictx, errorx.Wrap(err, "failed to get %s challenge", status))
 		return
 	}
-	responseJSON(ictx, resp)
+	responsePage(ictx, resp.Challenges, resp.Next)
+}
+
+// searchChallenges searches challenges of files in the local index, by storage node, file and status optionally
+func (s *Server) searchChallenges(ictx iris.Context) {
+	req := etype.SearchChallengeOptions{
+		Owner:      ictx.URLParam("owner"),
+		TargetNode: ictx.URLParam("node"),
+		FileID:     ictx.URLParam("file"),
+		Status:     ictx.URLParam("status"),
+		Asc:        ictx.URLParam("order") == "asc",
+		TimeStart:  ictx.URLParamInt64Default("start", 0),
+		TimeEnd:    ictx.URLParamInt64Default("end", 0),
+		Cursor:     ictx.URLParam("cursor"),
+		Limit:      ictx.URLParamInt64Default("limit", blockchain.ListMaxNumber),
+	}
+	if err := req.Valid(); err != nil {
+		responseError(ictx, errorx.Wrap(err, "invalid params"))
+		return
+	}
+	resp, err := s.handler.SearchChallenges(req)
+	if err != nil {
+		responseError(ictx, errorx.Wrap(err, "failed to search challenges"))
+		return
+	}
+	responseJSON(ictx, types.ChallengePageResponse{Challenges: resp.Challenges, Next: resp.Next})
 }
 
 // getNodeHealth get storage node health status
@@ -0,0 +1,82 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	etype "github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// readHandler serves files of the same content, other methods of Handler are not implemented
+type readHandler struct {
+	Handler
+	content []byte
+}
+
+func (h *readHandler) Read(ctx context.Context, opt etype.ReadOptions) (etype.ReadResponse, error) {
+	resp := etype.ReadResponse{FileLength: uint64(len(h.content))}
+	offset, length := opt.Offset, uint64(len(h.content))-opt.Offset
+	if opt.Suffix > 0 {
+		offset, length = uint64(len(h.content))-opt.Suffix, opt.Suffix
+	} else if opt.Offset >= uint64(len(h.content)) {
+		return resp, errorx.New(errorx.ErrCodeRangeNotSatisfiable, "range not satisfiable")
+	} else if opt.Length > 0 && opt.Length < length {
+		length = opt.Length
+	}
+	resp.Offset, resp.Length = offset, length
+	resp.ReadCloser = ioutil.NopCloser(bytes.NewReader(h.content[offset : offset+length]))
+	return resp, nil
+}
+
+func TestReadRange(t *testing.T) {
+	s, err := New(":0", &readHandler{content: []byte("Hello world!")})
+	require.NoError(t, err)
+	require.NoError(t, s.setRoute(config.NodeTypeDataOwner))
+	require.NoError(t, s.app.Build())
+
+	read := func(rangeHeader string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/file/read?user=u&file_id=f&timestamp=1", nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		w := httptest.NewRecorder()
+		s.app.ServeHTTP(w, req)
+		return w
+	}
+
+	w := read("")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "Hello world!", w.Body.String())
+
+	w = read("bytes=6-10")
+	require.Equal(t, http.StatusPartialContent, w.Code)
+	require.Equal(t, "bytes 6-10/12", w.Header().Get("Content-Range"))
+	require.Equal(t, "world", w.Body.String())
+
+	// the length of the file is responded if the range is not satisfiable
+	w = read("bytes=12-")
+	require.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+	require.Equal(t, "bytes */12", w.Header().Get("Content-Range"))
+	require.Contains(t, w.Body.String(), errorx.ErrCodeRangeNotSatisfiable)
+}
@@ -16,10 +16,13 @@ package server
 import (
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/kataras/iris/v12"
 	"github.com/sirupsen/logrus"
 
+	etype "github.com/PaddlePaddle/PaddleDTX/xdb/engine/types"
 	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
 )
 
@@ -39,6 +42,7 @@ type response struct {
 	Code    string      `json:"code"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data"`
+	Next    string      `json:"next,omitempty"` // cursor of the next page of lists, empty if no items are left
 }
 
 func responseJSON(ctx iris.Context, o interface{}) {
@@ -50,12 +54,27 @@ func responseJSON(ctx iris.Context, o interface{}) {
 	})
 }
 
+// responsePage responds a page of a list along with the cursor of the next page
+func responsePage(ctx iris.Context, o interface{}, next string) {
+	ctx.StatusCode(http.StatusOK)
+	ctx.JSON(response{
+		Code:    errorx.SuccessCode,
+		Message: "",
+		Data:    o,
+		Next:    next,
+	})
+}
+
 func responseBytes(ctx iris.Context, bs []byte) {
 	ctx.StatusCode(http.StatusOK)
 	ctx.Binary(bs)
 }
 
 func responseStream(ctx iris.Context, r io.Reader) {
+	responseStreamWithStatus(ctx, r, http.StatusOK)
+}
+
+func responseStreamWithStatus(ctx iris.Context, r io.Reader, status int) {
 	// check first byte in case of error
 	firstByte := make([]byte, 1)
 	if n, err := r.Read(firstByte); err != nil {
@@ -65,7 +84,50 @@ func responseStream(ctx iris.Context, r io.Reader) {
 		return
 	}
 
-	ctx.StatusCode(http.StatusOK)
+	ctx.StatusCode(status)
 	ctx.ResponseWriter().Write(firstByte)
 	io.Copy(ctx.ResponseWriter(), r)
 }
+
+// parseRange parses a single byte range in "Range" header into opt,
+// returns false if the header is malformed or multiple ranges are requested
+func parseRange(header string, opt *etype.ReadOptions) bool {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return false
+	}
+	spec := strings.TrimSpace(strings.TrimPrefix(header, prefix))
+	if strings.Contains(spec, ",") {
+		return false
+	}
+	i := strings.Index(spec, "-")
+	if i < 0 {
+		return false
+	}
+	start, end := strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+
+	// suffix range, the last bytes of the file
+	if start == "" {
+		suffix, err := strconv.ParseUint(end, 10, 64)
+		if err != nil || suffix == 0 {
+			return false
+		}
+		opt.Offset, opt.Length, opt.Suffix = 0, 0, suffix
+		return true
+	}
+
+	offset, err := strconv.ParseUint(start, 10, 64)
+	if err != nil {
+		return false
+	}
+	var length uint64
+	if end != "" {
+		last, err := strconv.ParseUint(end, 10, 64)
+		if err != nil || last < offset {
+			return false
+		}
+		length = last - offset + 1
+	}
+	opt.Offset, opt.Length, opt.Suffix = offset, length, 0
+	return true
+}
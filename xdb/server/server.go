@@ -15,6 +15,7 @@ package server
 
 import (
 	"context"
+	"expvar"
 	"io"
 	"strings"
 
@@ -32,25 +33,41 @@ import (
 type Handler interface {
 	// The dataOwner node uses Write() and Read() to publish or download files
 	Write(context.Context, etype.WriteOptions, io.Reader) (etype.WriteResponse, error)
-	Read(context.Context, etype.ReadOptions) (io.ReadCloser, error)
-
-	ListUnExpiredFiles(etype.ListFileOptions) ([]blockchain.File, error)
-	ListExpiredFiles(etype.ListFileOptions) ([]blockchain.File, error)
+	Read(context.Context, etype.ReadOptions) (etype.ReadResponse, error)
+	// Large files are uploaded in parts within a session, which can be resumed if interrupted
+	InitiateUpload(context.Context, etype.InitiateUploadOptions) (etype.UploadSession, error)
+	UploadPart(context.Context, etype.UploadPartOptions, io.Reader) (etype.UploadedPart, error)
+	ListUploadParts(etype.UploadSessionOptions) (etype.UploadSession, error)
+	CompleteUpload(context.Context, etype.UploadSessionOptions) (etype.WriteResponse, error)
+	AbortUpload(context.Context, etype.UploadSessionOptions) error
+
+	ListUnExpiredFiles(etype.ListFileOptions) (etype.FilePage, error)
+	ListExpiredFiles(etype.ListFileOptions) (etype.FilePage, error)
 	GetFileByID(ctx context.Context, id string) (blockchain.FileH, error)
-	GetFileByName(ctx context.Context, pubkey, ns, name string) (blockchain.FileH, error)
+	GetFileByName(ctx context.Context, pubkey, ns, name string, version int64) (blockchain.FileH, error)
+	ListFileVersions(etype.ListFileVersionsOptions) ([]blockchain.File, error)
 	UpdateFileExpireTime(ctx context.Context, opt etype.UpdateFileEtimeOptions) error
+	DeleteFile(ctx context.Context, opt etype.DeleteFileOptions) error
 	AddFileNs(opt etype.AddNsOptions) error
 	UpdateNsReplica(ctx context.Context, opt etype.UpdateNsOptions) error
+	UpdateNsLifecycle(opt etype.UpdateNsLifecycleOptions) error
 	ListFileNs(opt etype.ListNsOptions) ([]blockchain.Namespace, error)
 	GetNsByName(ctx context.Context, pubkey, name string) (blockchain.NamespaceH, error)
 	GetFileSysHealth(ctx context.Context, pubkey string) (blockchain.FileSysHealth, error)
 	GetChallengeByID(id string) (blockchain.Challenge, error)
-	GetChallenges(opt blockchain.ListChallengeOptions) ([]blockchain.Challenge, error)
+	GetChallenges(opt blockchain.ListChallengeOptions) (etype.ChallengePage, error)
+	// The dataOwner node uses SearchFiles() and SearchChallenges() to search the local index synced from blockchain
+	SearchFiles(etype.SearchFileOptions) (etype.FilePage, error)
+	SearchChallenges(etype.SearchChallengeOptions) (etype.ChallengePage, error)
 	// The Storage node uses Push() or Pull() to store or provide ciphertext slices
 	Push(etype.PushOptions, io.Reader) (etype.PushResponse, error)
 	Pull(etype.PullOptions) (io.ReadCloser, error)
+	DeleteSlice(etype.DeleteSliceOptions) error
+	GetSliceUsage(owner string) (etype.SliceUsage, error)
+	GetScrubReports(owner string) ([]etype.SliceScrub, error)
+	GetOrphanSlices() ([]etype.SliceScrub, error)
 	// The dataOwner node uses the following methods to operate the applier's authorization request
-	ListFileAuths(etype.ListFileAuthOptions) (blockchain.FileAuthApplications, error)
+	ListFileAuths(etype.ListFileAuthOptions) (etype.FileAuthPage, error)
 	ConfirmAuth(etype.ConfirmAuthOptions) error
 	GetAuthByID(id string) (blockchain.FileAuthApplication, error)
 
@@ -60,6 +77,7 @@ type Handler interface {
 	GetNodeHealth([]byte) (string, error)
 	NodeOffline(etype.NodeOperateOptions) error
 	NodeOnline(etype.NodeOperateOptions) error
+	NodeDrain(etype.NodeOperateOptions) error
 	GetSliceMigrateRecords(opt *blockchain.NodeSliceMigrateOptions) (string, error)
 }
 
@@ -114,27 +132,44 @@ func (s *Server) setRoute(serverType string) (err error) {
 	nodeParty.Get("/gethbnum", s.getHeartbeatNum)
 
 	switch serverType {
-	// If the storage node, setting the '/v1/slice', '/v1/node/online' and '/v1/node/offline' routing
+	// If the storage node, setting the '/v1/slice', '/v1/node/online', '/v1/node/offline' and '/v1/node/drain' routing,
+	// and '/debug/vars' exposing metrics such as slices scrubbed
 	case config.NodeTypeStorage:
 		sliceParty := v1.Party("/slice")
 		sliceParty.Post("/push", s.push)
 		sliceParty.Get("/pull", s.pull)
+		sliceParty.Post("/delete", s.deleteSlice)
+		sliceParty.Get("/usage", s.getSliceUsage)
+		sliceParty.Get("/scrub", s.getScrubReports)
+		sliceParty.Get("/orphans", s.getOrphanSlices)
+		s.app.Get("/debug/vars", iris.FromStd(expvar.Handler()))
 
 		nodeParty.Post("/offline", s.nodeOffline)
 		nodeParty.Post("/online", s.nodeOnline)
+		nodeParty.Post("/drain", s.nodeDrain)
 	// If the dataOwner node, setting the '/v1/file' and '/v1/challenge' routing
 	case config.NodeTypeDataOwner:
 		fileParty := v1.Party("/file")
 		fileParty.Post("/write", s.write)
+		uploadParty := fileParty.Party("/upload")
+		uploadParty.Post("/initiate", s.initiateUpload)
+		uploadParty.Post("/part", s.uploadPart)
+		uploadParty.Get("/list", s.listUploadParts)
+		uploadParty.Post("/complete", s.completeUpload)
+		uploadParty.Post("/abort", s.abortUpload)
 		fileParty.Post("/updatexptime", s.updateFileExpireTime)
+		fileParty.Post("/delete", s.deleteFile)
 		fileParty.Post("/addns", s.addFileNs)
 		fileParty.Post("/ureplica", s.updateNsReplica)
+		fileParty.Post("/ulifecycle", s.updateNsLifecycle)
 
 		fileParty.Get("/read", s.read)
 		fileParty.Get("/list", s.listUnExpiredFiles)
 		fileParty.Get("/listexp", s.listExpiredFiles)
+		fileParty.Get("/search", s.searchFiles)
 		fileParty.Get("/getbyid", s.getFileByID)
 		fileParty.Get("/getbyname", s.getFileByName)
+		fileParty.Get("/listversions", s.listFileVersions)
 		fileParty.Get("/listns", s.listFileNs)
 		fileParty.Get("/getns", s.getNsByName)
 		fileParty.Get("/getsyshealth", s.getSysHealth)
@@ -148,6 +183,7 @@ func (s *Server) setRoute(serverType string) (err error) {
 		challParty.Get("/toprove", s.getToProveChallenges)
 		challParty.Get("/proved", s.getProvedChallenges)
 		challParty.Get("/failed", s.getFailedChallenges)
+		challParty.Get("/search", s.searchChallenges)
 	default:
 		err = errorx.New(errorx.ErrCodeConfig, "wrong config: server.server-type")
 	}
@@ -13,9 +13,35 @@
 
 package types
 
-// WriteResponse is response of uploading a file, only file id
+import "github.com/PaddlePaddle/PaddleDTX/xdb/blockchain"
+
+// WriteResponse is response of uploading a file, Version is the version of the file with the same name
 type WriteResponse struct {
-	FileID string `json:"file_id"`
+	FileID  string `json:"file_id"`
+	Version int64  `json:"version"`
+}
+
+// UploadSessionResponse is response of initiating or listing an upload session
+//  Parts are the ones already uploaded, a file of PartCount parts is published by completing the session
+type UploadSessionResponse struct {
+	SessionID  string               `json:"sessionID"`
+	FileID     string               `json:"fileID"`
+	Namespace  string               `json:"ns"`
+	FileName   string               `json:"name"`
+	FileLength uint64               `json:"length"`
+	PartSize   uint64               `json:"partSize"`
+	PartCount  int                  `json:"partCount"`
+	ExpireTime int64                `json:"expireTime"`
+	CreateTime int64                `json:"ctime"`
+	Parts      []UploadPartResponse `json:"parts"`
+}
+
+// UploadPartResponse is response of uploading a part, PlainHash is sha256 hash of the part
+type UploadPartResponse struct {
+	Number     int    `json:"number"`
+	Length     uint64 `json:"length"`
+	PlainHash  []byte `json:"plainHash"`
+	UploadTime int64  `json:"utime"`
 }
 
 // PushResponse is response of receiving a slice
@@ -23,3 +49,54 @@ type WriteResponse struct {
 type PushResponse struct {
 	SliceStorIndex string `json:"slice_stor_index"`
 }
+
+// SliceUsageResponse is response of getting the storage used by a dataOwner node on a storage node
+//  MaxBytes and MaxSlices are its quota, zero means unlimited
+type SliceUsageResponse struct {
+	Owner     string `json:"owner"`
+	Bytes     int64  `json:"bytes"`
+	Slices    int64  `json:"slices"`
+	MaxBytes  int64  `json:"maxBytes"`
+	MaxSlices int64  `json:"maxSlices"`
+}
+
+// SliceScrubResponse is a slice of a dataOwner node found corrupt or missing by scrubbing on a storage node
+//  Status is Corrupt or Missing, CheckedAt is the time the slice was scrubbed
+type SliceScrubResponse struct {
+	SliceID   string `json:"sliceID"`
+	FileID    string `json:"fileID"`
+	Owner     string `json:"owner"`
+	Status    string `json:"status"`
+	CheckedAt int64  `json:"checkedAt"`
+}
+
+// SliceOrphanResponse is a slice on a storage node not referenced by any file on blockchain
+//  FileID is the file the slice was pushed for, OrphanedAt is the time it was found orphaned
+type SliceOrphanResponse struct {
+	SliceID    string `json:"sliceID"`
+	FileID     string `json:"fileID"`
+	Owner      string `json:"owner"`
+	PushedAt   int64  `json:"pushedAt"`
+	OrphanedAt int64  `json:"orphanedAt"`
+}
+
+// FilePageResponse is a page of files listed from blockchain or searched in the local index of dataOwner node,
+// Next is the cursor to list or search the next page, empty if no files are left
+type FilePageResponse struct {
+	Files []blockchain.File `json:"files"`
+	Next  string            `json:"next,omitempty"`
+}
+
+// ChallengePageResponse is a page of challenges listed from blockchain or searched in the local index of dataOwner node,
+// Next is the cursor to list or search the next page, empty if no challenges are left
+type ChallengePageResponse struct {
+	Challenges []blockchain.Challenge `json:"challenges"`
+	Next       string                 `json:"next,omitempty"`
+}
+
+// FileAuthPageResponse is a page of file authorization applications,
+// Next is the cursor to list the next page, empty if no applications are left
+type FileAuthPageResponse struct {
+	FileAuths blockchain.FileAuthApplications `json:"fileAuths"`
+	Next      string                          `json:"next,omitempty"`
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/file"
 	s "github.com/PaddlePaddle/PaddleDTX/xdb/storage"
 )
 
@@ -80,6 +81,8 @@ func (s *Storage) Save(key string, value io.Reader) error {
 	defer f.Close()
 
 	if _, err := io.Copy(f, value); err != nil {
+		// remove the incomplete file, so that it can be saved again
+		os.Remove(filePath)
 		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write")
 	}
 
@@ -247,6 +250,15 @@ func (s *storageV2) Update(key string, index string, value io.Reader) (string, e
 	return index, nil
 }
 
+// Capacity returns total and free bytes of the file system storing files
+func (s *storageV2) Capacity() (uint64, uint64, error) {
+	total, free, err := file.DiskUsage(s.RootPath)
+	if err != nil {
+		return 0, 0, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to get disk usage")
+	}
+	return total, free, nil
+}
+
 func isValidKey(key string) bool {
 	// we know the key(slice id) is a uuid, use uuid.Parse to defend path attacking
 	_, err := uuid.Parse(key)
@@ -0,0 +1,162 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pack
+
+import (
+	"context"
+	"hash/crc32"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// compactLoop compacts segments regularly
+func (s *Storage) compactLoop(ctx context.Context) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.compactInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.Compact(); err != nil {
+			logger.WithError(err).Error("failed to compact segments")
+		}
+	}
+}
+
+// Compact compacts segments whose garbage reaches compactRatio of their size, the active segment excluded.
+// Live slices of a segment are moved into the active segment, then the segment is removed
+func (s *Storage) Compact() error {
+	segments, err := listSegments(s.root)
+	if err != nil {
+		return err
+	}
+	s.lock.RLock()
+	active := s.activeNum
+	s.lock.RUnlock()
+
+	for _, n := range segments {
+		if n >= active {
+			continue
+		}
+		info, err := os.Stat(segmentPath(s.root, n))
+		if err != nil {
+			return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to stat segment")
+		}
+		garbage, err := s.getGarbage(n)
+		if err != nil {
+			return err
+		}
+		if info.Size() > 0 && float64(garbage) < float64(info.Size())*s.compactRatio {
+			continue
+		}
+		moved, err := s.compactSegment(n)
+		if err != nil {
+			return errorx.Wrap(err, "failed to compact segment %d", n)
+		}
+		logger.WithFields(logrus.Fields{
+			"segment": n,
+			"size":    info.Size(),
+			"garbage": garbage,
+			"moved":   moved,
+		}).Info("segment compacted")
+	}
+	return nil
+}
+
+// compactSegment moves live slices of a segment into the active segment and removes the segment,
+// returns the number of slices moved. A slice failing checksum verification stops compacting,
+// so that the corrupted data is not hidden by a new checksum
+func (s *Storage) compactSegment(n uint32) (int, error) {
+	live := make(map[string]entry)
+	iter := s.db.NewIterator(util.BytesPrefix([]byte(indexPrefix)), nil)
+	for iter.Next() {
+		e, err := unmarshalEntry(iter.Value())
+		if err != nil {
+			iter.Release()
+			return 0, err
+		}
+		if e.Segment == n {
+			live[strings.TrimPrefix(string(iter.Key()), indexPrefix)] = e
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return 0, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to iterate index")
+	}
+
+	moved := 0
+	if len(live) > 0 {
+		f, err := os.Open(segmentPath(s.root, n))
+		if err != nil {
+			return 0, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to open segment")
+		}
+		defer f.Close()
+
+		for key, e := range live {
+			data := make([]byte, e.Length)
+			if _, err := f.ReadAt(data, e.dataOffset()); err != nil {
+				return moved, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read record")
+			}
+			if crc32.Checksum(data, crcTable) != e.Crc {
+				return moved, errorx.New(errorx.ErrCodeInternal, "checksum mismatch, segment %d offset %d", e.Segment, e.Offset)
+			}
+			ok, err := s.move(key, e, data)
+			if err != nil {
+				return moved, err
+			}
+			if ok {
+				moved++
+			}
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := os.Remove(segmentPath(s.root, n)); err != nil {
+		return moved, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to remove segment")
+	}
+	if err := s.db.Delete(garbageKey(n), nil); err != nil {
+		return moved, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to delete garbage")
+	}
+	return moved, nil
+}
+
+// move appends a slice into the active segment, unless it's deleted or updated during compacting
+func (s *Storage) move(key string, e entry, data []byte) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	cur, exist, err := s.getEntry(key)
+	if err != nil {
+		return false, err
+	}
+	if !exist || cur != e {
+		return false, nil
+	}
+	if err := s.append(key, data, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
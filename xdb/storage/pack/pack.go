@@ -0,0 +1,383 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pack
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/pkgs/file"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/storage"
+)
+
+const (
+	dbName = "indexDB"
+
+	indexPrefix   = "idx:"
+	garbagePrefix = "gbg:" // bytes of deleted records in a segment
+
+	defaultSegmentSize     = 1 << 30
+	defaultCompactRatio    = 0.5
+	defaultCompactInterval = time.Hour
+)
+
+var (
+	logger = logrus.WithField("module", "storage.pack")
+)
+
+// Storage appends slices into large segment files under root path, instead of a file for each slice,
+// so that millions of small slices don't exhaust inodes. The location of each slice is indexed in levelDB.
+// Slices deleted leave garbage in segments, segments with too much garbage are compacted in background
+// by moving live slices into the active segment. Key and index of a slice are the same.
+type Storage struct {
+	root string
+	db   *leveldb.DB
+
+	segmentSize     int64
+	compactRatio    float64
+	compactInterval time.Duration
+
+	lock       sync.RWMutex
+	active     *os.File // the segment slices are appended into
+	activeNum  uint32
+	activeSize int64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates Storage with given configuration, and starts compacting in background
+func New(conf *config.PackConf) (*Storage, error) {
+	if len(conf.RootPath) == 0 {
+		return nil, errorx.New(errorx.ErrCodeConfig, "missing config: rootPath")
+	}
+	if conf.SegmentSize < 0 || conf.CompactRatio < 0 || conf.CompactRatio >= 1 || conf.CompactInterval < 0 {
+		return nil, errorx.New(errorx.ErrCodeConfig, "invalid segmentSize, compactRatio or compactInterval")
+	}
+	// only create the outer dir, the same as local storage
+	if _, err := os.Stat(conf.RootPath); err != nil {
+		if err := os.Mkdir(conf.RootPath, 0777); err != nil {
+			return nil, errorx.NewCode(err, errorx.ErrCodeConfig, "failed to mkdir for storage")
+		}
+	}
+
+	s := &Storage{
+		root:            conf.RootPath,
+		segmentSize:     conf.SegmentSize,
+		compactRatio:    conf.CompactRatio,
+		compactInterval: time.Duration(conf.CompactInterval) * time.Hour,
+		done:            make(chan struct{}),
+	}
+	if s.segmentSize == 0 {
+		s.segmentSize = defaultSegmentSize
+	}
+	if s.compactRatio == 0 {
+		s.compactRatio = defaultCompactRatio
+	}
+	if s.compactInterval == 0 {
+		s.compactInterval = defaultCompactInterval
+	}
+
+	db, err := leveldb.OpenFile(filepath.Join(conf.RootPath, dbName), nil)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "cannot open leveldb")
+	}
+	s.db = db
+
+	// always append into a new segment, the tail of the last one may be broken by a crash
+	segments, err := listSegments(s.root)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	var last uint32
+	for _, n := range segments {
+		if n > last {
+			last = n
+		}
+	}
+	if err := s.rotate(last + 1); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go s.compactLoop(ctx)
+
+	logger.WithFields(logrus.Fields{
+		"root_path":        s.root,
+		"segments":         len(segments),
+		"segment_size":     s.segmentSize,
+		"compact_ratio":    s.compactRatio,
+		"compact_interval": s.compactInterval,
+	}).Info("new storage")
+	return s, nil
+}
+
+// Save appends a slice into the active segment, returns ErrCodeAlreadyExists if the key exists
+func (s *Storage) Save(key string, value io.Reader) (string, error) {
+	if len(key) == 0 || len(key) > math.MaxUint16 {
+		return "", errorx.New(errorx.ErrCodeParam, "invalid key: %s", key)
+	}
+	// read the slice before locking, so that slow pushes don't block others
+	data, err := ioutil.ReadAll(value)
+	if err != nil {
+		return "", errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, exist, err := s.getEntry(key); err != nil {
+		return "", err
+	} else if exist {
+		return "", errorx.New(errorx.ErrCodeAlreadyExists, "key already exist")
+	}
+	if err := s.append(key, data, nil); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Load returns a reader of a slice, the checksum is verified when all the data is read
+func (s *Storage) Load(key string, index string) (io.ReadCloser, error) {
+	if key != index {
+		return nil, errorx.New(errorx.ErrCodeParam, "invalid key or index: %s, %s", key, index)
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	e, exist, err := s.getEntry(key)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, errorx.New(errorx.ErrCodeNotFound, "key not found")
+	}
+	// the file opened is still readable even if the segment is removed by compacting
+	f, err := os.Open(segmentPath(s.root, e.Segment))
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to open segment")
+	}
+	if err := checkHeader(f, key, e); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return newRecordReader(f, e), nil
+}
+
+// Exist checks if a slice exists
+func (s *Storage) Exist(key string, index string) (bool, error) {
+	if key != index {
+		return false, errorx.New(errorx.ErrCodeParam, "invalid key or index: %s, %s", key, index)
+	}
+	_, exist, err := s.getEntry(key)
+	return exist, err
+}
+
+// Delete removes a slice from index, the record left in segment is garbage to be compacted
+func (s *Storage) Delete(key string, index string) error {
+	if key != index {
+		return errorx.New(errorx.ErrCodeParam, "invalid key or index: %s, %s", key, index)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e, exist, err := s.getEntry(key)
+	if err != nil || !exist {
+		return err
+	}
+	batch := leveldb.Batch{}
+	batch.Delete([]byte(indexPrefix + key))
+	if err := s.addGarbage(&batch, e); err != nil {
+		return err
+	}
+	if err := s.db.Write(&batch, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write batch")
+	}
+	return nil
+}
+
+// Update appends a new record of a slice, the old one becomes garbage
+func (s *Storage) Update(key string, index string, value io.Reader) (string, error) {
+	if key != index {
+		return "", errorx.New(errorx.ErrCodeParam, "invalid key or index: %s, %s", key, index)
+	}
+	data, err := ioutil.ReadAll(value)
+	if err != nil {
+		return "", errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e, exist, err := s.getEntry(key)
+	if err != nil {
+		return "", err
+	}
+	var old *entry
+	if exist {
+		old = &e
+	}
+	if err := s.append(key, data, old); err != nil {
+		return "", err
+	}
+	return index, nil
+}
+
+// Capacity returns total and free bytes of the file system storing segments
+func (s *Storage) Capacity() (uint64, uint64, error) {
+	total, free, err := file.DiskUsage(s.root)
+	if err != nil {
+		return 0, 0, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to get disk usage")
+	}
+	return total, free, nil
+}
+
+// Close stops compacting and closes segments and index
+func (s *Storage) Close() {
+	s.cancel()
+	<-s.done
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.active.Close()
+	s.db.Close()
+}
+
+// append appends a record into the active segment and indexes it, old is the record replaced.
+// The segment is synced before indexing, so that an indexed slice is never lost
+func (s *Storage) append(key string, data []byte, old *entry) error {
+	if s.activeSize >= s.segmentSize {
+		if err := s.rotate(s.activeNum + 1); err != nil {
+			return err
+		}
+	}
+
+	record, crc := encodeRecord(key, data)
+	if _, err := s.active.WriteAt(record, s.activeSize); err != nil {
+		s.active.Truncate(s.activeSize)
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write segment")
+	}
+	if err := s.active.Sync(); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to sync segment")
+	}
+	e := entry{
+		Segment: s.activeNum,
+		Offset:  s.activeSize,
+		KeyLen:  len(key),
+		Length:  int64(len(data)),
+		Crc:     crc,
+	}
+	s.activeSize += int64(len(record))
+
+	batch := leveldb.Batch{}
+	batch.Put([]byte(indexPrefix+key), e.marshal())
+	if old != nil {
+		if err := s.addGarbage(&batch, *old); err != nil {
+			return err
+		}
+	}
+	if err := s.db.Write(&batch, nil); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to write batch")
+	}
+	return nil
+}
+
+// rotate closes the active segment and creates a new one
+func (s *Storage) rotate(n uint32) error {
+	f, err := os.OpenFile(segmentPath(s.root, n), os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to create segment")
+	}
+	if s.active != nil {
+		s.active.Close()
+	}
+	s.active = f
+	s.activeNum = n
+	s.activeSize = 0
+	return nil
+}
+
+func (s *Storage) getEntry(key string) (entry, bool, error) {
+	value, err := s.db.Get([]byte(indexPrefix+key), nil)
+	if err == leveldb.ErrNotFound {
+		return entry{}, false, nil
+	} else if err != nil {
+		return entry{}, false, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to get index")
+	}
+	e, err := unmarshalEntry(value)
+	if err != nil {
+		return entry{}, false, err
+	}
+	return e, true, nil
+}
+
+// addGarbage counts a record deleted into garbage of its segment
+func (s *Storage) addGarbage(batch *leveldb.Batch, e entry) error {
+	garbage, err := s.getGarbage(e.Segment)
+	if err != nil {
+		return err
+	}
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(garbage+e.size()))
+	batch.Put(garbageKey(e.Segment), value)
+	return nil
+}
+
+func (s *Storage) getGarbage(n uint32) (int64, error) {
+	value, err := s.db.Get(garbageKey(n), nil)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	} else if err != nil {
+		return 0, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to get garbage")
+	}
+	return int64(binary.BigEndian.Uint64(value)), nil
+}
+
+func garbageKey(n uint32) []byte {
+	return []byte(garbagePrefix + segmentName(n))
+}
+
+// checkHeader checks the record header in segment matches the index entry
+func checkHeader(f *os.File, key string, e entry) error {
+	header := make([]byte, recordHeaderSize+e.KeyLen)
+	if _, err := f.ReadAt(header, e.Offset); err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read record header")
+	}
+	if binary.BigEndian.Uint32(header[0:]) != recordMagic ||
+		int64(binary.BigEndian.Uint64(header[6:])) != e.Length ||
+		!bytes.Equal(header[recordHeaderSize:], []byte(key)) {
+		return errorx.New(errorx.ErrCodeInternal, "bad record header, segment %d offset %d", e.Segment, e.Offset)
+	}
+	return nil
+}
+
+var _ storage.BasicStorage = (*Storage)(nil)
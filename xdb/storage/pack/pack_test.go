@@ -0,0 +1,187 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pack
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+func load(t *testing.T, s *Storage, key string) ([]byte, error) {
+	r, err := s.Load(key, key)
+	require.NoError(t, err)
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func TestStorage(t *testing.T) {
+	root, err := ioutil.TempDir("", "pack")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	conf := &config.PackConf{RootPath: root}
+	s, err := New(conf)
+	require.NoError(t, err)
+
+	key := "18f168b6-2ef2-491e-8b26-4aa6df18378a"
+	index, err := s.Save(key, strings.NewReader("Hello world!"))
+	require.NoError(t, err)
+	require.Equal(t, key, index)
+	_, err = s.Save(key, strings.NewReader("Hello again!"))
+	require.True(t, errorx.Is(err, errorx.ErrCodeAlreadyExists))
+
+	exist, err := s.Exist(key, index)
+	require.NoError(t, err)
+	require.True(t, exist)
+	content, err := load(t, s, key)
+	require.NoError(t, err)
+	require.Equal(t, "Hello world!", string(content))
+
+	_, err = s.Load(key, "bad index")
+	require.True(t, errorx.Is(err, errorx.ErrCodeParam))
+
+	index, err = s.Update(key, index, strings.NewReader("Hello again!"))
+	require.NoError(t, err)
+	content, err = load(t, s, key)
+	require.NoError(t, err)
+	require.Equal(t, "Hello again!", string(content))
+
+	// empty slice
+	_, err = s.Save("empty", bytes.NewReader(nil))
+	require.NoError(t, err)
+	content, err = load(t, s, "empty")
+	require.NoError(t, err)
+	require.Equal(t, 0, len(content))
+
+	// slices are persisted
+	s.Close()
+	s, err = New(conf)
+	require.NoError(t, err)
+	defer s.Close()
+	content, err = load(t, s, key)
+	require.NoError(t, err)
+	require.Equal(t, "Hello again!", string(content))
+
+	require.NoError(t, s.Delete(key, index))
+	require.NoError(t, s.Delete(key, index))
+	exist, err = s.Exist(key, index)
+	require.NoError(t, err)
+	require.False(t, exist)
+	_, err = s.Load(key, index)
+	require.True(t, errorx.Is(err, errorx.ErrCodeNotFound))
+}
+
+func TestCompact(t *testing.T) {
+	root, err := ioutil.TempDir("", "pack")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	// every segment holds about 4 slices
+	s, err := New(&config.PackConf{RootPath: root, SegmentSize: 4000})
+	require.NoError(t, err)
+	defer s.Close()
+
+	data := func(i int) []byte {
+		return bytes.Repeat([]byte{byte(i)}, 1000)
+	}
+	for i := 0; i < 20; i++ {
+		_, err := s.Save(fmt.Sprintf("slice-%d", i), bytes.NewReader(data(i)))
+		require.NoError(t, err)
+	}
+	segments, err := listSegments(root)
+	require.NoError(t, err)
+	require.Equal(t, 5, len(segments))
+
+	// delete 3 of every 4 slices
+	for i := 0; i < 20; i++ {
+		if i%4 != 0 {
+			key := fmt.Sprintf("slice-%d", i)
+			require.NoError(t, s.Delete(key, key))
+		}
+	}
+	require.NoError(t, s.Compact())
+
+	// the slices left are moved into the active segment, which is then rotated
+	segments, err = listSegments(root)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(segments))
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("slice-%d", i)
+		exist, err := s.Exist(key, key)
+		require.NoError(t, err)
+		require.Equal(t, i%4 == 0, exist)
+		if exist {
+			content, err := load(t, s, key)
+			require.NoError(t, err)
+			require.Equal(t, data(i), content)
+		}
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	root, err := ioutil.TempDir("", "pack")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	s, err := New(&config.PackConf{RootPath: root})
+	require.NoError(t, err)
+	defer s.Close()
+
+	_, err = s.Save("slice", strings.NewReader("Hello world!"))
+	require.NoError(t, err)
+	_, err = s.Save("other", strings.NewReader("Hello again!"))
+	require.NoError(t, err)
+
+	// corrupt the data
+	e, _, err := s.getEntry("slice")
+	require.NoError(t, err)
+	f, err := os.OpenFile(segmentPath(root, e.Segment), os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte("J"), e.dataOffset())
+	require.NoError(t, err)
+	f.Close()
+
+	_, err = load(t, s, "slice")
+	require.Error(t, err)
+
+	// the corrupted slice is not moved by compacting
+	require.NoError(t, s.rotate(s.activeNum+1))
+	require.NoError(t, s.Delete("other", "other"))
+	require.Error(t, s.Compact())
+	_, err = os.Stat(segmentPath(root, e.Segment))
+	require.NoError(t, err)
+	exist, err := s.Exist("slice", "slice")
+	require.NoError(t, err)
+	require.True(t, exist)
+}
+
+func TestNew(t *testing.T) {
+	for _, bad := range []config.PackConf{
+		{},
+		{RootPath: "/tmp/pack", SegmentSize: -1},
+		{RootPath: "/tmp/pack", CompactRatio: 1},
+	} {
+		_, err := New(&bad)
+		require.Error(t, err)
+	}
+}
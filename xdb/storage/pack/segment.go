@@ -0,0 +1,161 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// A record of a slice appended into a segment file is laid out as
+//  | magic(4) | key length(2) | data length(8) | key | data | crc32 of data(4) |
+// integers are encoded in big endian, crc32 uses Castagnoli polynomial
+const (
+	recordMagic      = 0x58444250 // "XDBP"
+	recordHeaderSize = 4 + 2 + 8
+	recordCrcSize    = 4
+
+	segmentSuffix = ".seg"
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// entry is the index of a slice, locating its data in a segment file
+type entry struct {
+	Segment uint32 // number of the segment
+	Offset  int64  // offset of the record in the segment
+	KeyLen  int    // length of the key
+	Length  int64  // length of the data
+	Crc     uint32 // checksum of the data
+}
+
+const entrySize = 4 + 8 + 2 + 8 + 4
+
+// dataOffset returns offset of the data in the segment
+func (e entry) dataOffset() int64 {
+	return e.Offset + recordHeaderSize + int64(e.KeyLen)
+}
+
+// size returns size of the whole record
+func (e entry) size() int64 {
+	return recordHeaderSize + int64(e.KeyLen) + e.Length + recordCrcSize
+}
+
+func (e entry) marshal() []byte {
+	bs := make([]byte, entrySize)
+	binary.BigEndian.PutUint32(bs[0:], e.Segment)
+	binary.BigEndian.PutUint64(bs[4:], uint64(e.Offset))
+	binary.BigEndian.PutUint16(bs[12:], uint16(e.KeyLen))
+	binary.BigEndian.PutUint64(bs[14:], uint64(e.Length))
+	binary.BigEndian.PutUint32(bs[22:], e.Crc)
+	return bs
+}
+
+func unmarshalEntry(bs []byte) (entry, error) {
+	if len(bs) != entrySize {
+		return entry{}, errorx.New(errorx.ErrCodeInternal, "bad index entry, length %d", len(bs))
+	}
+	return entry{
+		Segment: binary.BigEndian.Uint32(bs[0:]),
+		Offset:  int64(binary.BigEndian.Uint64(bs[4:])),
+		KeyLen:  int(binary.BigEndian.Uint16(bs[12:])),
+		Length:  int64(binary.BigEndian.Uint64(bs[14:])),
+		Crc:     binary.BigEndian.Uint32(bs[22:]),
+	}, nil
+}
+
+// encodeRecord encodes a record of a slice
+func encodeRecord(key string, data []byte) ([]byte, uint32) {
+	crc := crc32.Checksum(data, crcTable)
+	bs := make([]byte, recordHeaderSize+len(key)+len(data)+recordCrcSize)
+	binary.BigEndian.PutUint32(bs[0:], recordMagic)
+	binary.BigEndian.PutUint16(bs[4:], uint16(len(key)))
+	binary.BigEndian.PutUint64(bs[6:], uint64(len(data)))
+	n := recordHeaderSize
+	n += copy(bs[n:], key)
+	n += copy(bs[n:], data)
+	binary.BigEndian.PutUint32(bs[n:], crc)
+	return bs, crc
+}
+
+// segmentName returns file name of the segment
+func segmentName(n uint32) string {
+	return fmt.Sprintf("%08d%s", n, segmentSuffix)
+}
+
+// listSegments returns numbers of segment files under root
+func listSegments(root string) ([]uint32, error) {
+	files, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read dir")
+	}
+	var segments []uint32
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 32)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, uint32(n))
+	}
+	return segments, nil
+}
+
+func segmentPath(root string, n uint32) string {
+	return filepath.Join(root, segmentName(n))
+}
+
+// recordReader reads data of a record from segment file,
+// and verifies the checksum once all the data is read
+type recordReader struct {
+	f   *os.File
+	r   io.Reader
+	crc hash.Hash32
+	e   entry
+}
+
+func newRecordReader(f *os.File, e entry) *recordReader {
+	return &recordReader{
+		f:   f,
+		r:   io.NewSectionReader(f, e.dataOffset(), e.Length),
+		crc: crc32.New(crcTable),
+		e:   e,
+	}
+}
+
+func (r *recordReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.crc.Write(p[:n])
+	if err == io.EOF && r.crc.Sum32() != r.e.Crc {
+		return n, errorx.New(errorx.ErrCodeInternal, "checksum mismatch, segment %d offset %d", r.e.Segment, r.e.Offset)
+	}
+	return n, err
+}
+
+func (r *recordReader) Close() error {
+	return r.f.Close()
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// client is a minimal client of the S3 API, only object operations used by Storage are implemented
+type client struct {
+	endpoint      *url.URL
+	bucket        string
+	virtualHosted bool // the bucket is addressed in host name rather than in path
+	signer        signer
+	hc            *http.Client
+}
+
+// errorResponse is the error returned by S3 in XML
+type errorResponse struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+type initiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// objectURL returns URL of the object, in path style or in virtual hosted style
+func (c *client) objectURL(key string, query url.Values) *url.URL {
+	u := *c.endpoint
+	if c.virtualHosted {
+		u.Host = c.bucket + "." + u.Host
+		u.Path = "/" + key
+	} else {
+		u.Path = "/" + c.bucket + "/" + key
+	}
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+// do sends a signed request, error is returned if the response status is not 2xx,
+// ErrCodeNotFound for 404. The caller must close the body of the response returned
+func (c *client) do(method, key string, query url.Values, body []byte) (*http.Response, error) {
+	var payloadHash string
+	if len(body) == 0 {
+		payloadHash = emptyPayloadHash
+	} else {
+		payloadHash = hexSha256(body)
+	}
+	req, err := http.NewRequest(method, c.objectURL(key, query).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to create request")
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	c.signer.sign(req, payloadHash, time.Now())
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to request s3")
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	code := errorx.ErrCodeInternal
+	if resp.StatusCode == http.StatusNotFound {
+		code = errorx.ErrCodeNotFound
+	}
+	var er errorResponse
+	content, _ := ioutil.ReadAll(resp.Body)
+	if err := xml.Unmarshal(content, &er); err != nil || er.Code == "" {
+		return nil, errorx.New(code, "s3 responded %s", resp.Status)
+	}
+	return nil, errorx.New(code, "s3 responded %s, %s: %s", resp.Status, er.Code, er.Message)
+}
+
+// putObject uploads an object at once
+func (c *client) putObject(key string, data []byte) error {
+	resp, err := c.do(http.MethodPut, key, nil, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// getObject downloads an object
+func (c *client) getObject(key string) (io.ReadCloser, error) {
+	resp, err := c.do(http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// headObject checks existence of an object
+func (c *client) headObject(key string) (bool, error) {
+	resp, err := c.do(http.MethodHead, key, nil, nil)
+	if err != nil {
+		if errorx.Is(err, errorx.ErrCodeNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+// deleteObject deletes an object, deleting an object not existing succeeds as well
+func (c *client) deleteObject(key string) error {
+	resp, err := c.do(http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// createMultipartUpload initiates a multipart upload and returns its upload ID
+func (c *client) createMultipartUpload(key string) (string, error) {
+	resp, err := c.do(http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result initiateMultipartUploadResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", errorx.NewCode(err, errorx.ErrCodeInternal, "failed to decode multipart upload")
+	}
+	if result.UploadID == "" {
+		return "", errorx.New(errorx.ErrCodeInternal, "empty upload id")
+	}
+	return result.UploadID, nil
+}
+
+// uploadPart uploads a part of a multipart upload, parts are numbered from 1
+func (c *client) uploadPart(key, uploadID string, number int, data []byte) (completedPart, error) {
+	query := url.Values{
+		"partNumber": {strconv.Itoa(number)},
+		"uploadId":   {uploadID},
+	}
+	resp, err := c.do(http.MethodPut, key, query, data)
+	if err != nil {
+		return completedPart{}, err
+	}
+	resp.Body.Close()
+	return completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")}, nil
+}
+
+// completeMultipartUpload joins parts uploaded into the object
+func (c *client) completeMultipartUpload(key, uploadID string, parts []completedPart) error {
+	body, err := xml.Marshal(completeMultipartUpload{Parts: parts})
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to marshal parts")
+	}
+	resp, err := c.do(http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// errors occurred in joining parts are returned in the body with status 200
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read response")
+	}
+	var er errorResponse
+	if xml.Unmarshal(content, &er) == nil && er.Code != "" {
+		return errorx.New(errorx.ErrCodeInternal, "failed to complete multipart upload, %s: %s", er.Code, er.Message)
+	}
+	return nil
+}
+
+// abortMultipartUpload aborts a multipart upload, parts uploaded are discarded
+func (c *client) abortMultipartUpload(key, uploadID string) error {
+	resp, err := c.do(http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/storage"
+)
+
+const (
+	// MinPartSize is the minimum size of parts in multipart uploads except the last one, required by S3
+	MinPartSize = 5 << 20
+	// defaultPartSize is the default size of parts, slices no larger than it are uploaded at once
+	defaultPartSize = 16 << 20
+
+	defaultRegion = "us-east-1"
+)
+
+var (
+	logger = logrus.WithField("module", "storage.s3")
+)
+
+// Storage is implemented with S3 compatible object storage.
+// The index of stored data is the same with its key, as the object key is decided by the key
+type Storage struct {
+	c        *client
+	prefix   string
+	partSize int
+}
+
+// New creates Storage with S3 endpoint, bucket and credentials,
+// returns error if any mistake occured
+func New(conf *config.S3Conf) (storage.BasicStorage, error) {
+	endpoint, err := url.Parse(conf.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, errorx.New(errorx.ErrCodeConfig, "invalid s3 endpoint: %s", conf.Endpoint)
+	}
+	if conf.Bucket == "" {
+		return nil, errorx.New(errorx.ErrCodeConfig, "missing s3 bucket")
+	}
+	if conf.AccessKey == "" || conf.SecretKey == "" {
+		return nil, errorx.New(errorx.ErrCodeConfig, "missing s3 access key or secret key")
+	}
+	partSize := int(conf.PartSize)
+	if partSize == 0 {
+		partSize = defaultPartSize
+	}
+	if partSize < MinPartSize {
+		return nil, errorx.New(errorx.ErrCodeConfig, "s3 part size must be no smaller than %d", MinPartSize)
+	}
+	region := conf.Region
+	if region == "" {
+		region = defaultRegion
+	}
+	prefix := strings.Trim(conf.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	s := &Storage{
+		c: &client{
+			endpoint:      endpoint,
+			bucket:        conf.Bucket,
+			virtualHosted: conf.VirtualHostedStyle,
+			signer: signer{
+				accessKey: conf.AccessKey,
+				secretKey: conf.SecretKey,
+				region:    region,
+				service:   "s3",
+			},
+			hc: &http.Client{Timeout: time.Millisecond * time.Duration(conf.Timeout)},
+		},
+		prefix:   prefix,
+		partSize: partSize,
+	}
+	logger.WithFields(logrus.Fields{
+		"endpoint":  conf.Endpoint,
+		"bucket":    conf.Bucket,
+		"prefix":    prefix,
+		"part_size": partSize,
+	}).Info("new storage")
+	return s, nil
+}
+
+// Save saves a piece of `Data`
+// key is the identification of a piece of `Data`, and it's decided by end-users
+// value contains content of a piece of `Data`
+// returns index, which is the same with key
+func (s *Storage) Save(key string, value io.Reader) (string, error) {
+	exist, err := s.c.headObject(s.objectKey(key))
+	if err != nil {
+		return "", errorx.Wrap(err, "failed to determine data is existing or not")
+	}
+	if exist {
+		return "", errorx.New(errorx.ErrCodeAlreadyExists, "key already exist")
+	}
+	if err := s.put(key, value); err != nil {
+		return "", errorx.Wrap(err, "failed to save data")
+	}
+
+	logger.WithField("key", key).Debug("successfully saved")
+	return key, nil
+}
+
+// Load loads a piece of `Data`
+// key is the identification of a piece of `Data`, and it's decided by end-users
+// index is the index of stored data, the same with key
+func (s *Storage) Load(key string, index string) (io.ReadCloser, error) {
+	if key != index {
+		return nil, errorx.New(errorx.ErrCodeParam, "invalid key or index: %s, %s", key, index)
+	}
+	r, err := s.c.getObject(s.objectKey(key))
+	if err != nil {
+		return nil, errorx.Wrap(err, "failed to load data")
+	}
+
+	logger.WithField("key", key).Debug("successfully loaded")
+	return r, nil
+}
+
+// Exist checks existence of a piece of `Data`
+// key is the identification of a piece of `Data`, and it's decided by end-users
+// index is the index of stored data, the same with key
+func (s *Storage) Exist(key string, index string) (bool, error) {
+	if key != index {
+		return false, errorx.New(errorx.ErrCodeParam, "invalid key or index: %s, %s", key, index)
+	}
+	exist, err := s.c.headObject(s.objectKey(key))
+	if err != nil {
+		return false, errorx.Wrap(err, "failed to determine data is existing or not")
+	}
+	return exist, nil
+}
+
+// Delete deletes a piece of `Data`
+// key is the identification of a piece of `Data`, and it's decided by end-users
+// index is the index of stored data, the same with key
+func (s *Storage) Delete(key string, index string) error {
+	if key != index {
+		return errorx.New(errorx.ErrCodeParam, "invalid key or index: %s, %s", key, index)
+	}
+	if err := s.c.deleteObject(s.objectKey(key)); err != nil {
+		return errorx.Wrap(err, "failed to delete data")
+	}
+
+	logger.WithField("key", key).Debug("successfully deleted")
+	return nil
+}
+
+// Update updates a piece of `Data`
+// key is the identification of a piece of `Data`, and it's decided by end-users
+// index is the index of stored data, the same with key
+// returns new index, which is the same with key
+func (s *Storage) Update(key string, index string, value io.Reader) (string, error) {
+	if key != index {
+		return "", errorx.New(errorx.ErrCodeParam, "invalid key or index: %s, %s", key, index)
+	}
+	if err := s.put(key, value); err != nil {
+		return "", errorx.Wrap(err, "failed to update data")
+	}
+
+	logger.WithField("key", key).Debug("successfully updated")
+	return key, nil
+}
+
+// put uploads value as the object of key, the object is overwritten if exists.
+// Value no larger than part size is uploaded at once, otherwise it is uploaded in parts
+func (s *Storage) put(key string, value io.Reader) error {
+	objectKey := s.objectKey(key)
+	first, err := readPart(value, s.partSize+1)
+	if err != nil {
+		return err
+	}
+	if len(first) <= s.partSize {
+		return s.c.putObject(objectKey, first)
+	}
+
+	uploadID, err := s.c.createMultipartUpload(objectKey)
+	if err != nil {
+		return err
+	}
+	parts, err := s.putParts(objectKey, uploadID, first, value)
+	if err == nil {
+		err = s.c.completeMultipartUpload(objectKey, uploadID, parts)
+	}
+	if err != nil {
+		if abortErr := s.c.abortMultipartUpload(objectKey, uploadID); abortErr != nil {
+			logger.WithField("key", key).WithError(abortErr).Warn("failed to abort multipart upload")
+		}
+		return err
+	}
+	return nil
+}
+
+// putParts uploads parts of partSize bytes, the first one is read ahead already
+func (s *Storage) putParts(objectKey, uploadID string, first []byte, value io.Reader) ([]completedPart, error) {
+	var parts []completedPart
+	// the byte read ahead to find out large value belongs to the next part
+	next := first[s.partSize:]
+	data := first[:s.partSize]
+	for number := 1; len(data) > 0; number++ {
+		part, err := s.c.uploadPart(objectKey, uploadID, number, data)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+
+		rest, err := readPart(value, s.partSize-len(next))
+		if err != nil {
+			return nil, err
+		}
+		data = append(next, rest...)
+		next = nil
+	}
+	return parts, nil
+}
+
+// objectKey returns key of the object storing data of key
+func (s *Storage) objectKey(key string) string {
+	return s.prefix + key
+}
+
+// readPart reads at most n bytes from r
+func readPart(r io.Reader, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	l, err := io.ReadFull(r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, errorx.NewCode(err, errorx.ErrCodeInternal, "failed to read data")
+	}
+	return buf[:l], nil
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PaddlePaddle/PaddleDTX/xdb/config"
+	"github.com/PaddlePaddle/PaddleDTX/xdb/errorx"
+)
+
+// fakeS3 is an in-process stand-in of S3, serving objects in path style
+type fakeS3 struct {
+	lock      sync.Mutex
+	accessKey string
+	objects   map[string][]byte
+	uploads   map[string]map[int][]byte
+	completed int // number of multipart uploads completed
+}
+
+func newFakeS3(accessKey string) *fakeS3 {
+	return &fakeS3{
+		accessKey: accessKey,
+		objects:   make(map[string][]byte),
+		uploads:   make(map[string]map[int][]byte),
+	}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	if !strings.HasPrefix(r.Header.Get("Authorization"), signAlgorithm+" Credential="+f.accessKey+"/") ||
+		r.Header.Get("X-Amz-Content-Sha256") != hexSha256(body) {
+		writeError(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+
+	query := r.URL.Query()
+	key := r.URL.Path
+	switch {
+	case r.Method == http.MethodPost && query.Get("uploads") == "" && len(query["uploads"]) == 1:
+		id := strconv.Itoa(len(f.uploads) + 1)
+		f.uploads[id] = make(map[int][]byte)
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == http.MethodPut && query.Get("uploadId") != "":
+		parts, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		n, _ := strconv.Atoi(query.Get("partNumber"))
+		parts[n] = body
+		w.Header().Set("ETag", fmt.Sprintf("\"%d\"", n))
+	case r.Method == http.MethodPost && query.Get("uploadId") != "":
+		parts, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		var req completeMultipartUpload
+		if err := xml.Unmarshal(body, &req); err != nil || len(req.Parts) != len(parts) {
+			writeError(w, http.StatusBadRequest, "InvalidPart")
+			return
+		}
+		var content []byte
+		for i, p := range req.Parts {
+			if p.PartNumber != i+1 || (i < len(req.Parts)-1 && len(parts[p.PartNumber]) < MinPartSize) {
+				// errors in completing are returned with status 200
+				fmt.Fprint(w, "<Error><Code>EntityTooSmall</Code><Message>part too small</Message></Error>")
+				return
+			}
+			content = append(content, parts[p.PartNumber]...)
+		}
+		f.objects[key] = content
+		delete(f.uploads, query.Get("uploadId"))
+		f.completed++
+		fmt.Fprint(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+	case r.Method == http.MethodDelete && query.Get("uploadId") != "":
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		content, ok := f.objects[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if r.Method == http.MethodGet {
+			w.Write(content)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func newTestStorage(t *testing.T, fake *fakeS3, partSize int64) (*Storage, func()) {
+	server := httptest.NewServer(fake)
+	s, err := New(&config.S3Conf{
+		Endpoint:  server.URL,
+		Bucket:    "xdb",
+		Prefix:    "/slices/",
+		AccessKey: "access",
+		SecretKey: "secret",
+		PartSize:  partSize,
+		Timeout:   5000,
+	})
+	require.NoError(t, err)
+	return s.(*Storage), server.Close
+}
+
+func TestStorage(t *testing.T) {
+	fake := newFakeS3("access")
+	s, closeServer := newTestStorage(t, fake, 0)
+	defer closeServer()
+
+	key := "18f168b6-2ef2-491e-8b26-4aa6df18378a"
+	index, err := s.Save(key, strings.NewReader("Hello world!"))
+	require.NoError(t, err)
+	require.Equal(t, key, index)
+	require.Equal(t, []byte("Hello world!"), fake.objects["/xdb/slices/"+key])
+
+	_, err = s.Save(key, strings.NewReader("Hello again!"))
+	require.True(t, errorx.Is(err, errorx.ErrCodeAlreadyExists))
+
+	exist, err := s.Exist(key, index)
+	require.NoError(t, err)
+	require.True(t, exist)
+
+	r, err := s.Load(key, index)
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(r)
+	r.Close()
+	require.NoError(t, err)
+	require.Equal(t, "Hello world!", string(content))
+
+	index, err = s.Update(key, index, strings.NewReader("Hello again!"))
+	require.NoError(t, err)
+	require.Equal(t, key, index)
+	require.Equal(t, []byte("Hello again!"), fake.objects["/xdb/slices/"+key])
+
+	_, err = s.Load(key, "bad index")
+	require.Error(t, err)
+
+	require.NoError(t, s.Delete(key, index))
+	exist, err = s.Exist(key, index)
+	require.NoError(t, err)
+	require.False(t, exist)
+	_, err = s.Load(key, index)
+	require.True(t, errorx.Is(err, errorx.ErrCodeNotFound))
+
+	// bad credentials
+	s.c.signer.accessKey = "bad"
+	_, err = s.Exist(key, index)
+	require.Error(t, err)
+}
+
+func TestMultipart(t *testing.T) {
+	fake := newFakeS3("access")
+	s, closeServer := newTestStorage(t, fake, MinPartSize)
+	defer closeServer()
+
+	for i, size := range []int{MinPartSize, MinPartSize + 1, 2 * MinPartSize, 2*MinPartSize + 1024} {
+		key := fmt.Sprintf("slice-%d", i)
+		data := make([]byte, size)
+		rand.Read(data)
+		_, err := s.Save(key, bytes.NewReader(data))
+		require.NoError(t, err)
+
+		r, err := s.Load(key, key)
+		require.NoError(t, err)
+		content, err := ioutil.ReadAll(r)
+		r.Close()
+		require.NoError(t, err)
+		require.Equal(t, data, content)
+	}
+	// values larger than the part size are uploaded in parts
+	require.Equal(t, 3, fake.completed)
+	require.Equal(t, 0, len(fake.uploads))
+}
+
+func TestNew(t *testing.T) {
+	conf := config.S3Conf{
+		Endpoint:  "http://127.0.0.1:9000",
+		Bucket:    "xdb",
+		AccessKey: "access",
+		SecretKey: "secret",
+	}
+	s, err := New(&conf)
+	require.NoError(t, err)
+	require.Equal(t, "http://127.0.0.1:9000/xdb/a%20b", s.(*Storage).c.objectURL("a b", nil).String())
+
+	vconf := conf
+	vconf.VirtualHostedStyle = true
+	s, err = New(&vconf)
+	require.NoError(t, err)
+	require.Equal(t, "http://xdb.127.0.0.1:9000/a%20b?uploads=",
+		s.(*Storage).c.objectURL("a b", map[string][]string{"uploads": {""}}).String())
+
+	for _, bad := range []config.S3Conf{
+		{Endpoint: "127.0.0.1:9000", Bucket: "xdb", AccessKey: "access", SecretKey: "secret"},
+		{Endpoint: "http://127.0.0.1:9000", AccessKey: "access", SecretKey: "secret"},
+		{Endpoint: "http://127.0.0.1:9000", Bucket: "xdb", AccessKey: "access"},
+		{Endpoint: "http://127.0.0.1:9000", Bucket: "xdb", AccessKey: "access", SecretKey: "secret", PartSize: 1024},
+	} {
+		_, err := New(&bad)
+		require.Error(t, err)
+	}
+}
@@ -40,11 +40,21 @@ type BasicStorage interface {
 	Update(key string, index string, value io.Reader) (string, error)
 }
 
+// CapacityStorage is implemented by BasicStorage knowing its capacity,
+// such as those storing data in local file system
+type CapacityStorage interface {
+	// Capacity returns total and free bytes of the storage
+	Capacity() (uint64, uint64, error)
+}
+
 type Storage interface {
 	BasicStorage
 
 	//LoadStr loads a piece of `Data`, and convert it to a string
 	LoadStr(key string, index string) (string, error)
+
+	// Capacity returns total and free bytes of the storage, zero if unknown
+	Capacity() (uint64, uint64, error)
 }
 
 type storage struct {
@@ -65,6 +75,13 @@ func (s *storage) LoadStr(key string, index string) (string, error) {
 	return string(content), nil
 }
 
+func (s *storage) Capacity() (uint64, uint64, error) {
+	if c, ok := s.BasicStorage.(CapacityStorage); ok {
+		return c.Capacity()
+	}
+	return 0, 0, nil
+}
+
 func NewStorage(s BasicStorage) Storage {
 	return &storage{s}
 }